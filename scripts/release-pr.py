@@ -0,0 +1,96 @@
+#!/usr/bin/env python3
+"""Open the release pull request (main → production) through the GitHub API.
+
+Usage: release-pr.py VERSION
+
+Closes any release PR still open from main, then opens a new one titled
+"Release VERSION". Talks to the API directly, so the gh CLI is not needed.
+
+Token, first one set:
+  GITHUB_TOKEN / GH_TOKEN – a token allowed to open pull requests
+  `gh auth token`         – only when the gh CLI is installed and logged in
+
+Optional:
+  GITHUB_REPOSITORY – owner/repo (default: read from the origin remote)
+"""
+
+import json
+import os
+import re
+import shutil
+import subprocess
+import sys
+from urllib.error import HTTPError, URLError
+from urllib.request import Request, urlopen
+
+API_URL = "https://api.github.com"
+BASE, HEAD = "production", "main"
+
+
+def fail(message: str) -> None:
+    print(f"release-pr: {message}", file=sys.stderr)
+    sys.exit(1)
+
+
+def get_token() -> str:
+    for name in ("GITHUB_TOKEN", "GH_TOKEN"):
+        if os.environ.get(name):
+            return os.environ[name]
+    if shutil.which("gh"):
+        result = subprocess.run(["gh", "auth", "token"], capture_output=True, text=True)
+        if result.returncode == 0 and result.stdout.strip():
+            return result.stdout.strip()
+    fail("set GITHUB_TOKEN or GH_TOKEN (or log in with gh)")
+
+
+def get_repo() -> str:
+    if os.environ.get("GITHUB_REPOSITORY"):
+        return os.environ["GITHUB_REPOSITORY"]
+    remote = subprocess.run(["git", "remote", "get-url", "origin"], capture_output=True, text=True).stdout.strip()
+    match = re.search(r"github\.com[:/](.+?/.+?)(?:\.git)?$", remote)
+    if not match:
+        fail(f"origin ({remote or 'none'}) is not a GitHub remote; set GITHUB_REPOSITORY")
+    return match.group(1)
+
+
+def api(method: str, path: str, token: str, body: dict | None = None):
+    """Make an authenticated API request and return parsed JSON."""
+    headers = {
+        "Authorization": f"Bearer {token}",
+        "Accept": "application/vnd.github+json",
+        "User-Agent": "release-pr",
+    }
+    data = json.dumps(body).encode() if body is not None else None
+    req = Request(f"{API_URL}{path}", data=data, headers=headers, method=method)
+    try:
+        with urlopen(req, timeout=30) as resp:
+            return json.loads(resp.read() or b"null")
+    except HTTPError as e:
+        detail = e.read().decode("utf-8", "replace")
+        fail(f"{method} {path}: HTTP {e.code} {detail}")
+    except URLError as e:
+        fail(f"{method} {path}: connection error: {e.reason}")
+
+
+def main() -> None:
+    if len(sys.argv) != 2:
+        fail("usage: release-pr.py VERSION")
+    version = sys.argv[1]
+    token, repo = get_token(), get_repo()
+    owner = repo.split("/")[0]
+
+    for pr in api("GET", f"/repos/{repo}/pulls?state=open&base={BASE}&head={owner}:{HEAD}", token):
+        api("PATCH", f"/repos/{repo}/pulls/{pr['number']}", token, {"state": "closed"})
+        print(f"release-pr: closed #{pr['number']}")
+
+    pr = api("POST", f"/repos/{repo}/pulls", token, {
+        "title": f"Release {version}",
+        "head": HEAD,
+        "base": BASE,
+        "body": f"Automated release PR for v{version}",
+    })
+    print(f"release-pr: opened {pr['html_url']}")
+
+
+if __name__ == "__main__":
+    main()
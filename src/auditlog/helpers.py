@@ -2,6 +2,8 @@
 
 import logging
 
+from django.conf import settings
+
 from .models import AuditLog
 
 logger = logging.getLogger(__name__)
@@ -15,6 +17,7 @@ _CATEGORY_MAP = {
     "User": AuditLog.Category.USER,
     "Invitation": AuditLog.Category.USER,
     "RegisterDefinition": AuditLog.Category.DEVICE,
+    "DeviceDraft": AuditLog.Category.DEVICE,
     "GatewayAssignment": AuditLog.Category.GATEWAY,
 }
 
@@ -66,3 +69,25 @@ def log_action(request, action, target, category="", details=""):
     except Exception:
         logger.exception("Failed to write audit log")
         return None
+
+
+def log_command_action(action, category, target_label, details=None):
+    """Record an action run from a management command (no request, no user).
+
+    Opt-in: does nothing unless ``settings.USAGE_TRACKING`` is on. Entries
+    stay in the local audit log; they feed ``usage_report`` and are never
+    sent anywhere.
+    """
+    if not getattr(settings, "USAGE_TRACKING", False):
+        return None
+    try:
+        return AuditLog.objects.create(
+            category=category,
+            action=action,
+            target_type="Command",
+            target_label=str(target_label)[:255],
+            details=details or {},
+        )
+    except Exception:
+        logger.exception("Failed to write audit log")
+        return None
@@ -0,0 +1,18 @@
+# Generated by Django 6.0.4 on 2026-10-16 11:20
+
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ('auditlog', '0002_gateway_category'),
+    ]
+
+    operations = [
+        migrations.AlterField(
+            model_name='auditlog',
+            name='category',
+            field=models.CharField(choices=[('device', 'Device'), ('vendor', 'Vendor'), ('version', 'Version'), ('apikey', 'API Key'), ('gateway', 'Gateway'), ('import', 'Import'), ('export', 'Export'), ('validation', 'Validation'), ('user', 'User')], max_length=20),
+        ),
+    ]
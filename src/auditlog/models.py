@@ -18,6 +18,7 @@ class AuditLog(TimeStampedModel):
         GATEWAY = "gateway", "Gateway"
         IMPORT = "import", "Import"
         EXPORT = "export", "Export"
+        VALIDATION = "validation", "Validation"
         USER = "user", "User"
 
     id = models.UUIDField(primary_key=True, default=uuid.uuid4, editable=False)
@@ -130,6 +130,8 @@ TEMPLATES = [
                 "core.context_processors.app_version",
                 "core.context_processors.auto_logout",
                 "library.context_processors.unpublished_changes",
+                "library.context_processors.undo_stack",
+                "library.context_processors.status_history",
             ],
         },
     },
@@ -188,6 +190,20 @@ LOGGING = {
     },
 }
 
+# Status messages (``library.status_log``) also written to this file.
+STATUS_LOG_FILE = env("STATUS_LOG_FILE", default="")
+if STATUS_LOG_FILE:
+    LOGGING["handlers"]["status_file"] = {
+        "level": "INFO",
+        "class": "logging.FileHandler",
+        "filename": STATUS_LOG_FILE,
+        "formatter": "verbose",
+    }
+    LOGGING["loggers"]["library.status"] = {"handlers": ["status_file"], "level": "INFO", "propagate": True}
+
+# Messages are kept in the session's status log as well (``L`` shows it).
+MESSAGE_STORAGE = "library.status_log.StatusLogStorage"
+
 # DJANGO REST FRAMEWORK
 # ------------------------------------------------------------------------------
 REST_FRAMEWORK = {
@@ -211,6 +227,29 @@ REST_FRAMEWORK = {
 # ------------------------------------------------------------------------------
 SERVICE_TOKEN = env("SERVICE_TOKEN", default="")
 
+# USAGE TRACKING
+# ------------------------------------------------------------------------------
+# Opt-in: also audit-log CLI lint/export runs so ``manage.py usage_report``
+# can count them. Local only — nothing is sent outside this database.
+USAGE_TRACKING = env.bool("USAGE_TRACKING", default=False)
+
+# LINT
+# ------------------------------------------------------------------------------
+# The ``.sparklint.yaml`` rule set ``lint_library`` and the web UI apply when
+# no config is named explicitly — one file, wherever the server was started.
+SPARKLINT_CONFIG = env.path("SPARKLINT_CONFIG", default=BASE_DIR.parent / ".sparklint.yaml")
+
+# REGISTER SNIPPETS
+# ------------------------------------------------------------------------------
+# Reusable register blocks (``library.snippets``), kept in the repository.
+SNIPPETS_DIR = env.path("SNIPPETS_DIR", default=BASE_DIR.parent / "snippets")
+
+# DEVICE TEMPLATES
+# ------------------------------------------------------------------------------
+# Repository device templates (``library.device_templates``), next to the
+# built-in ones shipped with the app.
+DEVICE_TEMPLATES_DIR = env.path("DEVICE_TEMPLATES_DIR", default=BASE_DIR.parent / "templates")
+
 # DRF SPECTACULAR
 # ------------------------------------------------------------------------------
 SPECTACULAR_SETTINGS = {
@@ -219,3 +258,12 @@ SPECTACULAR_SETTINGS = {
     "VERSION": "1.0.0",
     "SERVE_INCLUDE_SCHEMA": False,
 }
+
+# NUMBER FORMAT
+# ------------------------------------------------------------------------------
+# Display formatting for detail views; see library/templatetags/number_format.py
+# for the defaults each key overrides.
+NUMBER_FORMAT = {
+    "thousands_separator": env("NUMBER_THOUSANDS_SEPARATOR", default="\u202f"),
+    "show_hex_addresses": env.bool("NUMBER_SHOW_HEX_ADDRESSES", default=True),
+}
@@ -0,0 +1,79 @@
+"""Read-only access to the Spark device library for backend services.
+
+``devicelib`` depends on PyYAML only — no Django, no database — so a
+service can import the library definitions directly::
+
+    import devicelib
+
+    library = devicelib.load("path/to/library")    # manifest.yaml + devices/
+    meter = library.device("Acme", "PM-1")
+    for reg in meter.registers:
+        print(reg.address, reg.field_name, reg.unit)
+
+    index = devicelib.Index(library)                # O(1) lookups per telegram
+    index.wmbus("KAM", "1b", 7)
+    index.match("KAM", 7, 0x1b)                     # one device, or an error
+
+    plan = devicelib.read_plan(meter)               # Modbus block reads
+
+    devicelib.fields.validate("power", "W")         # canonical field names / units
+    devicelib.units.convert(1500, "Wh", "kWh")      # 1.5
+
+    library = devicelib.load_release("1.4.0")     # pinned GitHub release, cached
+    library = devicelib.load("fork/", recover=True) # missing / stale manifest → library.problems
+
+The web application (``library``) is the source of truth and writes the
+tree this package reads; see ``library.exporters``.
+"""
+
+from . import fields, units
+from .fields import FieldSpec
+from .index import AmbiguousMatchError, Duplicate, DuplicateKeyError, Index, MatchError, NoMatchError
+from .loader import LibraryLoadError, from_bundle, load, load_fs, propose_manifest
+from .models import Device, DeviceTypeProfile, FieldMapping, Library, Metric, Register, Vendor
+from .readplan import ReadPlan, ReadPlanError, ReadRequest, read_plan
+from .release import Release, ReleaseFetchError, fetch_release, load_release
+from .technology import (
+    LoRaWANConfig,
+    ModbusConfig,
+    TechnologyConfigError,
+    WMBusConfig,
+    decode_technology_config,
+)
+
+__all__ = [
+    "AmbiguousMatchError",
+    "Device",
+    "DeviceTypeProfile",
+    "Duplicate",
+    "DuplicateKeyError",
+    "FieldMapping",
+    "FieldSpec",
+    "Index",
+    "Library",
+    "LibraryLoadError",
+    "LoRaWANConfig",
+    "MatchError",
+    "Metric",
+    "ModbusConfig",
+    "NoMatchError",
+    "ReadPlan",
+    "ReadPlanError",
+    "ReadRequest",
+    "Register",
+    "Release",
+    "ReleaseFetchError",
+    "TechnologyConfigError",
+    "Vendor",
+    "WMBusConfig",
+    "decode_technology_config",
+    "fetch_release",
+    "fields",
+    "from_bundle",
+    "load",
+    "load_fs",
+    "load_release",
+    "propose_manifest",
+    "read_plan",
+    "units",
+]
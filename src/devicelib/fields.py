@@ -0,0 +1,151 @@
+"""Canonical register field names and their units.
+
+Register maps name the same quantity a dozen ways (``power``, ``p_total``,
+``activePower``); every consumer then keeps its own alias table. This
+catalogue is the shared vocabulary: one snake_case name per quantity,
+the units it may be expressed in (canonical unit first) and the L1
+metric it usually maps onto.
+
+Per-phase and per-tariff variants don't need entries of their own —
+``voltage_l1`` is ``voltage`` on phase 1, ``energy_import_t2`` is
+``energy_import`` on tariff 2 — for the quantities where that makes
+sense (``phased`` / ``tariffed``). Common non-canonical spellings are
+listed as ``aliases`` so ``suggest`` can point at the right name.
+
+The ``canonical-field`` lint rule and the register editor validate
+against this catalogue; ``lookup``, ``suggest`` and ``validate`` are the
+same checks for services. Converting between a field's units is
+``devicelib.units``.
+"""
+
+from __future__ import annotations
+
+import difflib
+import re
+from dataclasses import dataclass
+
+PHASE_SUFFIX = re.compile(r"_(l[123]|n)$")
+TARIFF_SUFFIX = re.compile(r"_t[1-9]$")
+
+
+@dataclass(frozen=True)
+class FieldSpec:
+    name: str
+    units: tuple[str, ...]  # accepted units, canonical first; () = dimensionless
+    description: str
+    metric: str = ""  # L1 metric key the field usually maps onto
+    phased: bool = False  # _l1 / _l2 / _l3 (/ _n) variants
+    tariffed: bool = False  # _t1 … _t9 variants
+    aliases: tuple[str, ...] = ()
+
+    @property
+    def unit(self) -> str:
+        """The canonical unit ("" when dimensionless)."""
+        return self.units[0] if self.units else ""
+
+
+_SPECS = (
+    # Electrical — instantaneous
+    FieldSpec("active_power", ("W", "kW", "MW"), "Active power", "elec:active_power", phased=True,
+              aliases=("power", "p", "p_total", "total_power", "active_power_total")),
+    FieldSpec("reactive_power", ("var", "kvar"), "Reactive power", "elec:reactive_power", phased=True,
+              aliases=("q", "q_total")),
+    FieldSpec("apparent_power", ("VA", "kVA"), "Apparent power", "elec:apparent_power", phased=True,
+              aliases=("s", "s_total")),
+    FieldSpec("power_factor", ("", "ratio"), "Power factor", "elec:power_factor", phased=True,
+              aliases=("pf", "cos_phi")),
+    FieldSpec("voltage", ("V", "kV", "mV"), "Phase-to-neutral voltage", "elec:voltage", phased=True,
+              aliases=("volt", "u")),
+    FieldSpec("current", ("A", "mA"), "Current", "elec:current", phased=True, aliases=("i", "amps")),
+    FieldSpec("frequency", ("Hz",), "Grid frequency", "elec:frequency", aliases=("freq", "f")),
+    # Electrical — counters
+    FieldSpec("energy_import", ("kWh", "Wh", "MWh"), "Active energy imported (consumed)", "elec:total_energy",
+              phased=True, tariffed=True,
+              aliases=("energy", "energy_total", "total_energy", "active_energy", "energy_consumed")),
+    FieldSpec("energy_export", ("kWh", "Wh", "MWh"), "Active energy exported (produced)", phased=True,
+              tariffed=True, aliases=("energy_produced", "export_energy")),
+    FieldSpec("reactive_energy_import", ("kvarh", "varh"), "Reactive energy imported", phased=True, tariffed=True,
+              aliases=("reactive_energy",)),
+    FieldSpec("reactive_energy_export", ("kvarh", "varh"), "Reactive energy exported", phased=True, tariffed=True),
+    FieldSpec("apparent_energy", ("kVAh", "VAh"), "Apparent energy", phased=True, tariffed=True),
+    # Heat / water / gas
+    FieldSpec("heat_energy", ("kWh", "MWh", "GJ", "MJ"), "Heat energy delivered", "heat:total_energy",
+              tariffed=True, aliases=("thermal_energy",)),
+    FieldSpec("heat_power", ("kW", "W"), "Heat power", aliases=("thermal_power",)),
+    FieldSpec("volume", ("m³", "L"), "Total volume", tariffed=True, aliases=("total_volume", "volume_total")),
+    FieldSpec("flow_rate", ("m³/h", "L/h"), "Volume flow", aliases=("flow", "volume_flow")),
+    FieldSpec("flow_temperature", ("°C",), "Supply (flow) temperature", "heat:flow_temperature",
+              aliases=("supply_temperature", "t_flow", "t1")),
+    FieldSpec("return_temperature", ("°C",), "Return temperature", "heat:return_temperature",
+              aliases=("t_return", "t2")),
+    FieldSpec("temperature_difference", ("K",), "Flow − return temperature", aliases=("delta_t", "dt")),
+    FieldSpec("heat_cost_units", ("HCA", ""), "Heat cost allocator units", aliases=("hca", "units")),
+    # Environment
+    FieldSpec("temperature", ("°C",), "Ambient temperature", "env:temperature", aliases=("temp", "t")),
+    FieldSpec("humidity", ("%",), "Relative humidity", "env:humidity", aliases=("rh", "relative_humidity")),
+    FieldSpec("pressure", ("hPa", "Pa", "kPa", "bar"), "Pressure", "env:pressure"),
+    FieldSpec("co2", ("ppm",), "CO₂ concentration", "env:co2", aliases=("co2_ppm",)),
+    FieldSpec("illuminance", ("lx",), "Illuminance", aliases=("lux", "light")),
+    # Device health
+    FieldSpec("battery", ("ratio", "%"), "Battery level", "device:battery", aliases=("battery_level", "bat")),
+    FieldSpec("battery_voltage", ("V", "mV"), "Battery voltage", "device:battery_voltage", aliases=("vbat",)),
+    FieldSpec("rssi", ("dBm",), "Received signal strength", "device:rssi"),
+    FieldSpec("snr", ("dB",), "Signal-to-noise ratio", "device:snr"),
+    FieldSpec("uptime", ("s", "min", "h"), "Time since start", "device:uptime"),
+    FieldSpec("status", (), "Device status / error flags", "device:status", aliases=("error_flags", "errors")),
+)
+
+FIELDS: dict[str, FieldSpec] = {spec.name: spec for spec in _SPECS}
+ALIASES: dict[str, str] = {alias: spec.name for spec in _SPECS for alias in spec.aliases}
+
+
+def _split(name: str) -> tuple[str, str, str]:
+    """``name`` → (base, phase suffix, tariff suffix), e.g.
+    ``energy_import_l1_t2`` → (``energy_import``, ``_l1``, ``_t2``)."""
+    phase = tariff = ""
+    if match := TARIFF_SUFFIX.search(name):
+        tariff, name = match.group(), name[:match.start()]
+    if match := PHASE_SUFFIX.search(name):
+        phase, name = match.group(), name[:match.start()]
+    return name, phase, tariff
+
+
+def lookup(name: str) -> FieldSpec | None:
+    """The spec for canonical ``name`` (including phase / tariff variants)."""
+    if name in FIELDS:
+        return FIELDS[name]
+    base, phase, tariff = _split(name)
+    spec = FIELDS.get(base)
+    if spec is None or (phase and not spec.phased) or (tariff and not spec.tariffed):
+        return None
+    return spec
+
+
+def _with_suffixes(base: str, phase: str, tariff: str) -> str:
+    candidate = base + phase + tariff
+    return candidate if lookup(candidate) else base
+
+
+def suggest(name: str) -> str | None:
+    """The canonical name ``name`` probably means: an alias's target
+    (keeping any phase / tariff suffix) or a close spelling match."""
+    lowered = name.lower()
+    if lowered in ALIASES:
+        return ALIASES[lowered]
+    base, phase, tariff = _split(lowered)
+    if base in FIELDS or base in ALIASES:
+        return _with_suffixes(ALIASES.get(base, base), phase, tariff)
+    match = difflib.get_close_matches(base, FIELDS, n=1, cutoff=0.8)
+    return _with_suffixes(match[0], phase, tariff) if match else None
+
+
+def validate(name: str, unit: str = "") -> list[str]:
+    """Problems with a register field ``name`` / ``unit`` (empty when fine)."""
+    spec = lookup(name)
+    if spec is None:
+        hint = suggest(name)
+        return [f"{name!r} is not a canonical field name" + (f" (did you mean {hint!r}?)" if hint else "")]
+    if unit not in spec.units and not (unit == "" and not spec.units):
+        expected = ", ".join(repr(u) for u in spec.units) or "no unit"
+        return [f"Unit {unit!r} doesn't fit {spec.name} (expected {expected})"]
+    return []
@@ -0,0 +1,172 @@
+"""Constant-time device lookups for services resolving incoming telegrams.
+
+``Index`` hashes a ``Library`` once instead of scanning every device per
+message:
+
+- by vendor + model number (vendor name or slug, model number case- and
+  whitespace-insensitive — the same rule as the ``duplicate-model-number``
+  lint);
+- by wM-Bus header: manufacturer code, optionally narrowed by version and
+  device type — the triple is what identifies a meter model on the air.
+  ``match`` resolves a received telegram header to exactly one device,
+  treating a definition without version or device type as a wildcard and
+  preferring the most specific definition;
+- by ``processor_config.decoder_type`` (several LoRaWAN devices commonly
+  share a decoder, so this returns a list).
+
+Keys that should be unique but aren't are collected in ``duplicates`` on
+every ``rebuild``; with ``strict`` they raise ``DuplicateKeyError``. The
+first device in library order wins a clashing key.
+"""
+
+from __future__ import annotations
+
+import re
+from dataclasses import dataclass
+
+from .models import Device, Library, slugify
+
+
+@dataclass(frozen=True)
+class Duplicate:
+    kind: str  # "model" | "wmbus"
+    key: tuple
+    devices: tuple[Device, ...]
+
+    def __str__(self) -> str:
+        return f"duplicate {self.kind} key {self.key}: {', '.join(d.label for d in self.devices)}"
+
+
+class DuplicateKeyError(ValueError):
+    def __init__(self, duplicates: list[Duplicate]):
+        super().__init__("; ".join(str(d) for d in duplicates))
+        self.duplicates = duplicates
+
+
+class MatchError(LookupError):
+    pass
+
+
+class NoMatchError(MatchError):
+    pass
+
+
+class AmbiguousMatchError(MatchError):
+    def __init__(self, header: tuple, candidates: list[Device]):
+        super().__init__(
+            f"wM-Bus header {header} matches {len(candidates)} devices: {', '.join(d.label for d in candidates)}"
+        )
+        self.header = header
+        self.candidates = candidates
+
+
+def _version_text(version: int | str | None) -> str:
+    # Telegrams carry the version as a byte; definitions as two hex digits.
+    if isinstance(version, int):
+        return f"{version:02x}"
+    return str(version or "").lower()
+
+
+def _model_key(vendor: str, model_number: str) -> tuple[str, str]:
+    return slugify(vendor), re.sub(r"\s+", "", model_number or "").lower()
+
+
+def _wmbus_key(manufacturer_code, version, device_type) -> tuple:
+    return (str(manufacturer_code or "").upper(), _version_text(version), device_type)
+
+
+class Index:
+    def __init__(self, library: Library, strict: bool = False):
+        self.library = library
+        self.strict = strict
+        self.duplicates: list[Duplicate] = []
+        self.rebuild()
+
+    def rebuild(self, library: Library | None = None) -> list[Duplicate]:
+        """Re-hash ``library`` (or the current one, e.g. after a reload).
+
+        Returns the duplicate keys found; raises ``DuplicateKeyError``
+        instead when the index is strict.
+        """
+        if library is not None:
+            self.library = library
+        by_model: dict[tuple, list[Device]] = {}
+        by_wmbus: dict[tuple, list[Device]] = {}
+        by_manufacturer: dict[str, list[Device]] = {}
+        by_decoder: dict[str, list[Device]] = {}
+
+        for device in self.library.devices():
+            by_model.setdefault(_model_key(device.vendor_name, device.model_number), []).append(device)
+            tech = device.technology_config_for("wmbus")
+            if tech.get("manufacturer_code"):
+                key = _wmbus_key(tech["manufacturer_code"], tech.get("wmbus_version"), tech.get("wmbus_device_type"))
+                by_wmbus.setdefault(key, []).append(device)
+                by_manufacturer.setdefault(key[0], []).append(device)
+            decoder_type = device.processor_config.get("decoder_type")
+            if decoder_type:
+                by_decoder.setdefault(decoder_type, []).append(device)
+
+        self.duplicates = [
+            Duplicate(kind, key, tuple(devices))
+            for kind, table in (("model", by_model), ("wmbus", by_wmbus))
+            for key, devices in table.items()
+            if len(devices) > 1
+        ]
+        self._by_model = {key: devices[0] for key, devices in by_model.items()}
+        self._by_wmbus = {key: devices[0] for key, devices in by_wmbus.items()}
+        self._by_manufacturer = by_manufacturer
+        self._by_decoder = by_decoder
+        if self.strict and self.duplicates:
+            raise DuplicateKeyError(self.duplicates)
+        return self.duplicates
+
+    def device(self, vendor: str, model_number: str) -> Device | None:
+        return self._by_model.get(_model_key(vendor, model_number))
+
+    def wmbus(self, manufacturer_code: str, version: str | None = None, device_type: int | None = None) -> list[Device]:
+        """Devices matching a wM-Bus header; ``version`` / ``device_type``
+        narrow the match when given (an exact triple is a single device)."""
+        if version is not None and device_type is not None:
+            found = self._by_wmbus.get(_wmbus_key(manufacturer_code, version, device_type))
+            return [found] if found else []
+        candidates = self._by_manufacturer.get(str(manufacturer_code or "").upper(), [])
+        found = []
+        for d in candidates:
+            tech = d.technology_config_for("wmbus")
+            if (version is None or _version_text(tech.get("wmbus_version")) == _version_text(version)) and (
+                device_type is None or tech.get("wmbus_device_type") == device_type
+            ):
+                found.append(d)
+        return found
+
+    def match(self, manufacturer_code: str, device_type: int, version: int | str) -> Device:
+        """The device a telegram with this header comes from.
+
+        ``version`` is the header byte (``0x1b``) or its hex text
+        (``"1b"``). A definition without ``wmbus_version`` or
+        ``wmbus_device_type`` matches any value there; among the matches
+        the one fixing the most header fields wins. Raises
+        ``NoMatchError``, or ``AmbiguousMatchError`` (with the
+        ``candidates``) when several definitions are equally specific.
+        """
+        header = (str(manufacturer_code or "").upper(), _version_text(version), device_type)
+        scored: dict[int, list[Device]] = {}
+        for device in self._by_manufacturer.get(header[0], []):
+            tech = device.technology_config_for("wmbus")
+            dev_version = _version_text(tech.get("wmbus_version"))
+            dev_type = tech.get("wmbus_device_type")
+            if (dev_version and dev_version != header[1]) or (dev_type is not None and dev_type != device_type):
+                continue
+            scored.setdefault(bool(dev_version) + (dev_type is not None), []).append(device)
+        if not scored:
+            raise NoMatchError(f"No device matches wM-Bus header {header}")
+        best = scored[max(scored)]
+        if len(best) > 1:
+            raise AmbiguousMatchError(header, best)
+        return best[0]
+
+    def by_decoder_type(self, decoder_type: str) -> list[Device]:
+        return list(self._by_decoder.get(decoder_type, []))
+
+    def __len__(self) -> int:
+        return len(self._by_model)
@@ -0,0 +1,198 @@
+"""Load a device library from an exported YAML tree or a JSON bundle.
+
+``load_fs`` reads from any ``importlib.resources`` traversable — a
+``pathlib.Path``, a ``zipfile.Path`` into a release archive, or package
+data shipped inside a service's wheel — so services don't need the tree
+unpacked on disk. ``load`` is the filesystem shortcut and also accepts
+the ``export_json`` bundle.
+
+Forks and work-in-progress branches often carry a manifest that is
+missing or out of step with ``devices/``. ``recover=True`` loads them
+anyway: the vendor list comes from ``propose_manifest`` (a scan of
+``devices/`` reusing the manifest entries that still fit) and what had
+to be patched up is reported in ``Library.problems``.
+"""
+
+from __future__ import annotations
+
+import json
+from importlib.resources.abc import Traversable
+from pathlib import Path
+
+import yaml
+
+from .models import Device, DeviceTypeProfile, Library, Metric, Vendor
+
+MANIFEST_NAME = "manifest.yaml"
+DEVICES_DIR = "devices"
+VENDOR_FILE_SUFFIXES = (".yaml", ".yml")
+
+
+class LibraryLoadError(Exception):
+    pass
+
+
+def load(path: str | Path, recover: bool = False) -> Library:
+    """Load the library at ``path``.
+
+    ``path`` is the directory holding ``manifest.yaml`` and ``devices/``,
+    the manifest file itself, or a ``.json`` bundle from ``export_json``.
+    ``recover`` tolerates a missing or partial manifest (see above).
+    """
+    path = Path(path)
+    if path.suffix == ".json":
+        try:
+            return from_bundle(json.loads(path.read_text()))
+        except (OSError, ValueError) as e:
+            raise LibraryLoadError(f"{path}: {e}") from e
+    if path.is_file():
+        return _load_tree(path, path.parent / DEVICES_DIR, recover)
+    return load_fs(path, recover)
+
+
+def load_fs(root: Traversable, recover: bool = False) -> Library:
+    """Load the tree whose root (``manifest.yaml`` + ``devices/``) is ``root``."""
+    return _load_tree(root / MANIFEST_NAME, root / DEVICES_DIR, recover)
+
+
+def _read_yaml(node: Traversable):
+    try:
+        return yaml.safe_load(node.read_text(encoding="utf-8"))
+    except (OSError, yaml.YAMLError) as e:
+        raise LibraryLoadError(f"{node}: {e}") from e
+
+
+def _devices_in(data) -> list[dict] | None:
+    """The device list of a vendor file document (None when it isn't one)."""
+    if not isinstance(data, dict):
+        return None
+    devices = data.get("models" if "models" in data else "device_types")
+    return devices if isinstance(devices, list) else None
+
+
+def propose_manifest(
+    devices_node: Traversable, manifest: dict | None = None, schema_version: int | None = None,
+) -> tuple[dict, list[str]]:
+    """A manifest matching the vendor files in ``devices_node``, and notes
+    on what differs from ``manifest`` (None: there is none).
+
+    Entries whose file exists are kept as they are, entries pointing at
+    missing files are dropped, and every vendor file the manifest doesn't
+    list gets an entry named after its devices' ``vendor_name`` (else the
+    file name). The metric / device type catalogues are carried over;
+    ``schema_version`` fills in a missing one.
+    """
+    notes = [] if manifest is not None else ["No manifest; vendor list rebuilt from devices/"]
+    manifest = manifest or {}
+    files = sorted(
+        node.name for node in devices_node.iterdir()
+        if node.is_file() and node.name.endswith(VENDOR_FILE_SUFFIXES)
+    ) if devices_node.is_dir() else []
+
+    entries, listed = [], set()
+    for entry in manifest.get("vendors", []) or []:
+        file_name = (entry or {}).get("file") if isinstance(entry, dict) else None
+        if file_name in files:
+            entries.append(entry)
+            listed.add(file_name)
+        else:
+            name = entry.get("name", "?") if isinstance(entry, dict) else "?"
+            notes.append(f"Dropped vendor {name}: {file_name or 'no file'} not found")
+
+    for file_name in files:
+        if file_name in listed:
+            continue
+        try:
+            devices = _devices_in(_read_yaml(devices_node / file_name))
+        except LibraryLoadError as e:
+            notes.append(f"Skipped unreadable {file_name}: {e}")
+            continue
+        if devices is None:
+            notes.append(f"Skipped {file_name}: not a vendor file")
+            continue
+        parsed = [Device.from_dict(d) for d in devices if isinstance(d, dict)]
+        stem = file_name.rsplit(".", 1)[0]
+        name = next((d.vendor_name for d in parsed if d.vendor_name), stem.replace("-", " ").title())
+        entries.append({
+            "name": name,
+            "file": file_name,
+            "technologies": sorted({t for d in parsed for t in d.technologies}),
+        })
+        notes.append(f"Added vendor {name} from unlisted {file_name}")
+
+    proposal = {
+        "version": manifest.get("version", "0.0.0"),
+        "schema_version": manifest.get("schema_version", schema_version),
+        **{k: v for k, v in manifest.items() if k not in ("version", "schema_version", "vendors")},
+        "vendors": entries,
+    }
+    if proposal["schema_version"] is None:
+        del proposal["schema_version"]
+    return proposal, notes
+
+
+def _load_tree(manifest_node: Traversable, devices_node: Traversable, recover: bool = False) -> Library:
+    problems = []
+    if not manifest_node.is_file():
+        if not recover:
+            raise LibraryLoadError(f"Manifest not found: {manifest_node}")
+        manifest = None
+    else:
+        try:
+            manifest = _read_yaml(manifest_node) or {}
+        except LibraryLoadError as e:
+            if not recover:
+                raise
+            problems.append(f"Ignored unreadable manifest: {e}")
+            manifest = None
+    if recover:
+        manifest, notes = propose_manifest(devices_node, manifest)
+        problems += notes
+
+    vendors = []
+    for entry in manifest.get("vendors", []) or []:
+        file_node = devices_node / entry["file"]
+        if not file_node.is_file():
+            raise LibraryLoadError(f"Vendor file not found: {file_node}")
+        data = _read_yaml(file_node) or {}
+        vendors.append(_vendor(entry, _devices_in(data) or []))
+    return _library(manifest, vendors, tuple(problems))
+
+
+def from_bundle(bundle: dict) -> Library:
+    """Build a ``Library`` from an ``export_json`` bundle (already parsed)."""
+    vendors = [_vendor(entry, entry.get("models") or []) for entry in bundle.get("vendors", []) or []]
+    return _library(bundle, vendors)
+
+
+def _vendor(entry: dict, devices: list[dict]) -> Vendor:
+    # The manifest may list a vendor once per file (split vendors).
+    return Vendor(
+        name=entry["name"],
+        devices=tuple(Device.from_dict(d, vendor_name=entry["name"]) for d in devices),
+        technologies=tuple(entry.get("technologies") or ()),
+        file=entry.get("file", ""),
+    )
+
+
+def _library(manifest: dict, vendors: list[Vendor], problems: tuple[str, ...] = ()) -> Library:
+    merged: dict[str, Vendor] = {}
+    for vendor in vendors:
+        first = merged.get(vendor.slug)
+        if first is None:
+            merged[vendor.slug] = vendor
+            continue
+        merged[vendor.slug] = Vendor(
+            name=first.name,
+            devices=first.devices + vendor.devices,
+            technologies=tuple(sorted(set(first.technologies) | set(vendor.technologies))),
+            file=first.file,
+        )
+    return Library(
+        version=str(manifest.get("version", "0.0.0")),
+        schema_version=int(manifest.get("schema_version") or 0),
+        vendors=tuple(merged.values()),
+        metrics=tuple(Metric.from_dict(m) for m in manifest.get("metrics", []) or []),
+        device_types=tuple(DeviceTypeProfile.from_dict(t) for t in manifest.get("device_types", []) or []),
+        problems=problems,
+    )
@@ -0,0 +1,279 @@
+"""Typed, read-only views of the device library.
+
+The classes mirror the exported YAML schema (see ``library.exporters``)
+one-to-one; fields the schema doesn't guarantee are optional. Every
+object keeps the document it was built from in ``raw`` so consumers can
+reach keys this package doesn't model yet.
+"""
+
+from __future__ import annotations
+
+import re
+import unicodedata
+from dataclasses import dataclass, field
+
+
+def slugify(value: str) -> str:
+    """Same slugs as the library's vendor slugs (Django's ``slugify``)."""
+    value = unicodedata.normalize("NFKD", str(value)).encode("ascii", "ignore").decode("ascii")
+    value = re.sub(r"[^\w\s-]", "", value.lower())
+    return re.sub(r"[-\s]+", "-", value).strip("-_")
+
+
+@dataclass(frozen=True)
+class Register:
+    address: int
+    data_type: str
+    field_name: str
+    unit: str = ""
+    scale: float = 1.0
+    offset: float = 0.0
+    display: dict = field(default_factory=dict, hash=False)
+    description: str = ""  # what the field means, e.g. "T3 supply temperature"
+
+    @classmethod
+    def from_dict(cls, data: dict) -> Register:
+        reg_field = data.get("field") or {}
+        return cls(
+            address=data.get("address", 0),
+            data_type=data.get("data_type", "uint16"),
+            field_name=reg_field.get("name", ""),
+            unit=reg_field.get("unit", "") or "",
+            description=reg_field.get("description", "") or "",
+            scale=data.get("scale", 1.0),
+            offset=data.get("offset", 0.0),
+            display=data.get("display") or {},
+        )
+
+    def to_dict(self) -> dict:
+        """The register entry in the exported YAML layout."""
+        out = {
+            "field": {"name": self.field_name, "unit": self.unit},
+            "scale": self.scale,
+            "offset": self.offset,
+            "address": self.address,
+            "data_type": self.data_type,
+        }
+        if self.description:
+            out["field"]["description"] = self.description
+        if self.display:
+            out["display"] = dict(self.display)
+        return out
+
+    def decode(self, raw_value: float) -> float:
+        """Engineering value for a raw register reading."""
+        return raw_value * self.scale + self.offset
+
+
+@dataclass(frozen=True)
+class FieldMapping:
+    source: str
+    target: str
+    scale: float = 1.0
+    offset: float = 0.0
+    raw: dict = field(default_factory=dict, repr=False, compare=False)
+
+    @classmethod
+    def from_dict(cls, data: dict) -> FieldMapping:
+        return cls(
+            source=data.get("source", ""),
+            target=data.get("target", ""),
+            scale=data.get("scale", 1.0),
+            offset=data.get("offset", 0.0),
+            raw=data,
+        )
+
+
+@dataclass(frozen=True)
+class Device:
+    vendor_name: str
+    model_number: str
+    name: str
+    device_type: str
+    technology: str
+    description: str = ""
+    device_type_key: str = ""
+    raw: dict = field(default_factory=dict, repr=False, compare=False)
+
+    @classmethod
+    def from_dict(cls, data: dict, vendor_name: str = "") -> Device:
+        tech = data.get("technology_config") or {}
+        return cls(
+            vendor_name=data.get("vendor_name") or vendor_name,
+            model_number=data.get("model_number", ""),
+            name=data.get("name", ""),
+            device_type=data.get("device_type", ""),
+            technology=tech.get("technology", ""),
+            description=data.get("description", "") or "",
+            device_type_key=data.get("device_type_key", "") or "",
+            raw=data,
+        )
+
+    @property
+    def label(self) -> str:
+        return f"{self.vendor_name} {self.model_number}"
+
+    @property
+    def technology_config(self) -> dict:
+        return self.raw.get("technology_config") or {}
+
+    @property
+    def additional_technology_configs(self) -> list[dict]:
+        """Configs of a hybrid device's other transports (empty for most devices)."""
+        return [c for c in self.raw.get("additional_technology_configs") or [] if isinstance(c, dict)]
+
+    @property
+    def technologies(self) -> tuple[str, ...]:
+        """Primary technology followed by the additional ones."""
+        return tuple(
+            t for t in (self.technology, *(c.get("technology") for c in self.additional_technology_configs)) if t
+        )
+
+    def technology_config_for(self, technology: str) -> dict:
+        """The config for one transport (``{}`` when the device doesn't speak it)."""
+        configs = (self.technology_config, *self.additional_technology_configs)
+        return next((c for c in configs if c.get("technology") == technology), {})
+
+    @property
+    def config(self):
+        """Typed technology config (``devicelib.technology``); raises
+        ``TechnologyConfigError`` when the document doesn't type-check."""
+        from .technology import decode_technology_config
+
+        return decode_technology_config(self.technology_config)
+
+    def config_for(self, technology: str):
+        """Typed config for one transport, e.g. ``device.config_for("modbus")``
+        of a hybrid meter; ``None`` when the device doesn't speak it."""
+        from .technology import decode_technology_config
+
+        return decode_technology_config(self.technology_config_for(technology))
+
+    @property
+    def control_config(self) -> dict:
+        return self.raw.get("control_config") or {}
+
+    @property
+    def processor_config(self) -> dict:
+        return self.raw.get("processor_config") or {}
+
+    @property
+    def alarm_mappings(self) -> list[dict]:
+        return (self.raw.get("alarm_config") or {}).get("mappings") or []
+
+    @property
+    def links(self) -> list[dict]:
+        """Documentation links (``{kind, url, title?}``) in file order."""
+        return [link for link in self.raw.get("links") or [] if isinstance(link, dict)]
+
+    def link(self, kind: str) -> str | None:
+        """URL of the first link of ``kind`` (``datasheet``, ``manual``, ``vendor_page``)."""
+        return next((link.get("url") for link in self.links if link.get("kind") == kind), None)
+
+    @property
+    def registers(self) -> list[Register]:
+        """Modbus register map in file order (empty for devices without Modbus)."""
+        return [Register.from_dict(r) for r in self.technology_config_for("modbus").get("register_definitions") or []]
+
+    def register(self, field_name: str) -> Register | None:
+        return next((r for r in self.registers if r.field_name == field_name), None)
+
+    @property
+    def field_mappings(self) -> list[FieldMapping]:
+        """Base ``field_mappings`` followed by ``extra_mappings``."""
+        proc = self.processor_config
+        return [
+            FieldMapping.from_dict(m)
+            for m in (proc.get("field_mappings") or []) + (proc.get("extra_mappings") or [])
+        ]
+
+    @property
+    def controllable(self) -> bool:
+        return bool(self.control_config.get("controllable"))
+
+
+@dataclass(frozen=True)
+class Vendor:
+    name: str
+    devices: tuple[Device, ...] = ()
+    technologies: tuple[str, ...] = ()
+    file: str = ""
+
+    @property
+    def slug(self) -> str:
+        return slugify(self.name)
+
+    def device(self, model_number: str) -> Device | None:
+        wanted = model_number.strip().lower()
+        return next((d for d in self.devices if d.model_number.strip().lower() == wanted), None)
+
+
+@dataclass(frozen=True)
+class Metric:
+    key: str
+    label: str = ""
+    unit: str = ""
+    data_type: str = "decimal"
+    kind: str = "measurement"
+    raw: dict = field(default_factory=dict, repr=False, compare=False)
+
+    @classmethod
+    def from_dict(cls, data: dict) -> Metric:
+        return cls(
+            key=data.get("key", ""),
+            label=data.get("label", ""),
+            unit=data.get("unit", "") or "",
+            data_type=data.get("data_type", "decimal"),
+            kind=data.get("kind") or "measurement",
+            raw=data,
+        )
+
+
+@dataclass(frozen=True)
+class DeviceTypeProfile:
+    code: str
+    key: str = ""
+    label: str = ""
+    metrics: tuple[dict, ...] = field(default=(), hash=False)
+
+    @classmethod
+    def from_dict(cls, data: dict) -> DeviceTypeProfile:
+        return cls(
+            code=data.get("code", ""),
+            key=data.get("key", "") or "",
+            label=data.get("label", ""),
+            metrics=tuple(data.get("metrics") or []),
+        )
+
+
+@dataclass(frozen=True)
+class Library:
+    version: str
+    schema_version: int
+    vendors: tuple[Vendor, ...] = ()
+    metrics: tuple[Metric, ...] = ()
+    device_types: tuple[DeviceTypeProfile, ...] = ()
+    problems: tuple[str, ...] = ()  # what a ``recover=True`` load had to patch up
+
+    def vendor(self, name_or_slug: str) -> Vendor | None:
+        slug = slugify(name_or_slug)
+        return next((v for v in self.vendors if v.slug == slug), None)
+
+    def device(self, vendor: str, model_number: str) -> Device | None:
+        found = self.vendor(vendor)
+        return found.device(model_number) if found else None
+
+    def devices(self, technology: str | None = None, device_type: str | None = None) -> list[Device]:
+        """Every device, optionally narrowed to one technology (any of a hybrid
+        device's) / device type."""
+        return [
+            d for v in self.vendors for d in v.devices
+            if (technology is None or technology in d.technologies)
+            and (device_type is None or d.device_type == device_type)
+        ]
+
+    def metric(self, key: str) -> Metric | None:
+        return next((m for m in self.metrics if m.key == key), None)
+
+    def device_type(self, code_or_key: str) -> DeviceTypeProfile | None:
+        return next((t for t in self.device_types if code_or_key in (t.code, t.key)), None)
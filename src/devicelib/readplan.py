@@ -0,0 +1,145 @@
+"""Modbus polling plans built from a device's register map.
+
+Gateways poll a meter by reading blocks of registers; one request per
+register is slow and some devices rate-limit. ``read_plan`` sorts the
+register map and packs it into as few read requests as the limits allow:
+
+- a request never exceeds ``max_registers`` (125, the Modbus limit for
+  function codes 3 and 4, unless the device is known to need less);
+- a request only spans addresses the map defines, unless ``max_gap``
+  allows reading (and discarding) up to that many undefined registers
+  between two fields — some devices answer a read touching an unmapped
+  address with an exception, so the default is 0;
+- a multi-register value (int32, float32, ...) is never split across
+  requests;
+- a request never reaches past the last address, 65535 — a register
+  that would (a 32-bit value at 65535) is rejected.
+
+``ReadPlan.decode`` turns the raw 16-bit words a gateway got back into
+engineering values, applying the device's byte and word order, data
+types, scale and offset.
+"""
+
+from __future__ import annotations
+
+import struct
+from dataclasses import dataclass
+
+from .models import Device, Register
+from .technology import ModbusConfig
+
+REGISTER_WIDTHS = {
+    "int16": 1, "uint16": 1, "int32": 2, "uint32": 2, "float32": 2, "int64": 4, "uint64": 4,
+}
+STRUCT_FORMATS = {
+    "int16": ">h", "uint16": ">H", "int32": ">i", "uint32": ">I", "float32": ">f", "int64": ">q", "uint64": ">Q",
+}
+FUNCTION_CODES = {"holding": 3, "input": 4}
+MAX_READ_REGISTERS = 125
+MAX_ADDRESS = 0xFFFF
+
+
+class ReadPlanError(ValueError):
+    pass
+
+
+def register_width(register: Register) -> int:
+    try:
+        return REGISTER_WIDTHS[register.data_type]
+    except KeyError:
+        raise ReadPlanError(f"{register.field_name}: unknown data type {register.data_type!r}") from None
+
+
+@dataclass(frozen=True)
+class ReadRequest:
+    function_code: int
+    start: int
+    count: int
+    registers: tuple[Register, ...]
+
+    @property
+    def end(self) -> int:
+        """First address after the request."""
+        return self.start + self.count
+
+
+@dataclass(frozen=True)
+class ReadPlan:
+    function: str  # "holding" | "input"
+    byte_order: str
+    word_order: str
+    requests: tuple[ReadRequest, ...]
+
+    def __len__(self) -> int:
+        return len(self.requests)
+
+    def _raw_value(self, register: Register, words: list[int]):
+        words = [w & 0xFFFF for w in words]
+        if self.word_order == "low_first":
+            words.reverse()
+        if self.byte_order == "little_endian":
+            words = [((w & 0xFF) << 8) | (w >> 8) for w in words]
+        data = b"".join(w.to_bytes(2, "big") for w in words)
+        return struct.unpack(STRUCT_FORMATS[register.data_type], data)[0]
+
+    def decode(self, responses: list[list[int]]) -> dict[str, float]:
+        """Field name → engineering value, from one word list per request
+        (in plan order, as returned by the device)."""
+        if len(responses) != len(self.requests):
+            raise ReadPlanError(f"Expected {len(self.requests)} responses, got {len(responses)}")
+        values = {}
+        for request, words in zip(self.requests, responses, strict=True):
+            if len(words) < request.count:
+                raise ReadPlanError(
+                    f"Request at {request.start} returned {len(words)} registers, expected {request.count}"
+                )
+            for register in request.registers:
+                offset = register.address - request.start
+                raw = self._raw_value(register, list(words[offset:offset + register_width(register)]))
+                values[register.field_name] = register.decode(raw)
+        return values
+
+
+def read_plan(
+    source: Device | ModbusConfig, max_registers: int = MAX_READ_REGISTERS, max_gap: int = 0,
+) -> ReadPlan:
+    """The polling plan for a Modbus device (or its typed config)."""
+    config = source.config_for("modbus") if isinstance(source, Device) else source
+    if not isinstance(config, ModbusConfig):
+        raise ReadPlanError("Read plans need a Modbus device")
+    if max_registers < 1 or max_gap < 0:
+        raise ValueError("max_registers must be positive and max_gap not negative")
+    function = config.function or "holding"
+    if function not in FUNCTION_CODES:
+        raise ReadPlanError(f"Unknown register function {function!r}")
+    code = FUNCTION_CODES[function]
+
+    requests: list[ReadRequest] = []
+    start = end = None
+    members: list[Register] = []
+    for register in sorted(config.registers, key=lambda r: (r.address, r.field_name)):
+        width = register_width(register)
+        if width > max_registers:
+            raise ReadPlanError(f"{register.field_name} needs {width} registers, more than max_registers")
+        reg_end = register.address + width
+        if register.address < 0 or reg_end - 1 > MAX_ADDRESS:
+            raise ReadPlanError(
+                f"{register.field_name} at {register.address} ({width} registers) is outside "
+                f"the Modbus address range 0–{MAX_ADDRESS}"
+            )
+        if start is not None and register.address - end <= max_gap and max(end, reg_end) - start <= max_registers:
+            end = max(end, reg_end)
+            members.append(register)
+            continue
+        if start is not None:
+            requests.append(ReadRequest(code, start, end - start, tuple(members)))
+        start, end, members = register.address, reg_end, [register]
+    if start is not None:
+        requests.append(ReadRequest(code, start, end - start, tuple(members)))
+
+    return ReadPlan(
+        function=function,
+        byte_order=config.byte_order or "big_endian",
+        word_order=config.word_order or "high_first",
+        requests=tuple(requests),
+    )
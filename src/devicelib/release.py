@@ -0,0 +1,248 @@
+"""Fetch a published library release from GitHub and cache it locally.
+
+Services pin a library version instead of tracking git::
+
+    release = devicelib.fetch_release("1.4.0")
+    library = release.load()
+
+A release carries the library as a JSON bundle (``*.json``, the
+``export_json`` layout) and/or a tarball of the YAML tree (``*.tar.gz``
+holding ``manifest.yaml`` + ``devices/``). The download is checked
+against, in order of preference: the ``sha256`` the caller pinned, the
+release's ``SHA256SUMS`` asset, or the digest GitHub records for the
+asset. A release with none of these is refused rather than trusted.
+
+Verified artifacts are kept under ``cache_dir/<owner>__<repo>/<tag>/``
+with their checksum next to them; a later call for the same version is
+served from the cache (re-hashed, so a corrupted cache is re-fetched)
+without touching the network. Upgrading is fetching another version.
+
+Requests are plain HTTPS against the GitHub API; the ``gh`` CLI is never
+required. ``github_token`` only asks it for its stored login when no
+token is in the environment and the binary happens to be installed.
+"""
+
+from __future__ import annotations
+
+import hashlib
+import json
+import os
+import shutil
+import subprocess
+import tarfile
+import tempfile
+import urllib.error
+import urllib.request
+from dataclasses import dataclass
+from pathlib import Path
+
+from .loader import MANIFEST_NAME, load
+from .models import Library
+
+DEFAULT_REPO = "hardwario/enerooo-spark-device-library"
+API_URL = "https://api.github.com"
+CHECKSUMS_ASSET = "SHA256SUMS"
+KINDS = {"json": (".json",), "tarball": (".tar.gz", ".tgz")}
+CHUNK = 64 * 1024
+
+
+class ReleaseFetchError(Exception):
+    pass
+
+
+@dataclass(frozen=True)
+class Release:
+    version: str
+    repo: str
+    asset: str
+    sha256: str
+    path: Path  # the JSON bundle, or the unpacked tree's root
+
+    def load(self) -> Library:
+        return load(self.path)
+
+
+def default_cache_dir() -> Path:
+    """``$DEVICELIB_CACHE``, else ``$XDG_CACHE_HOME/devicelib``, else ``~/.cache/devicelib``."""
+    if os.environ.get("DEVICELIB_CACHE"):
+        return Path(os.environ["DEVICELIB_CACHE"])
+    return Path(os.environ.get("XDG_CACHE_HOME") or Path.home() / ".cache") / "devicelib"
+
+
+def github_token() -> str | None:
+    """``$GITHUB_TOKEN``, else ``$GH_TOKEN``, else ``gh auth token`` if gh is installed."""
+    for name in ("GITHUB_TOKEN", "GH_TOKEN"):
+        if os.environ.get(name):
+            return os.environ[name]
+    gh = shutil.which("gh")
+    if gh is None:
+        return None
+    try:
+        result = subprocess.run([gh, "auth", "token"], capture_output=True, text=True, timeout=10)
+    except (OSError, subprocess.SubprocessError):
+        return None
+    if result.returncode != 0:
+        return None
+    return result.stdout.strip() or None
+
+
+def _tag(version: str) -> str:
+    return version if version.startswith("v") else f"v{version}"
+
+
+def _sha256(path: Path) -> str:
+    digest = hashlib.sha256()
+    with path.open("rb") as f:
+        for chunk in iter(lambda: f.read(CHUNK), b""):
+            digest.update(chunk)
+    return digest.hexdigest()
+
+
+def _asset_url(asset: dict) -> str:
+    """The API URL of ``asset``: served with ``Accept: application/octet-stream``, and for private repos too."""
+    return asset.get("url") or asset["browser_download_url"]
+
+
+def _open(url: str, token: str | None, timeout: float, accept: str):
+    request = urllib.request.Request(url, headers={"Accept": accept, "User-Agent": "devicelib"})
+    if token:
+        # Asset downloads redirect to a storage host; the token must not follow them there.
+        request.add_unredirected_header("Authorization", f"Bearer {token}")
+    try:
+        return urllib.request.urlopen(request, timeout=timeout)
+    except urllib.error.HTTPError as e:
+        raise ReleaseFetchError(f"{url}: HTTP {e.code}") from e
+    except (urllib.error.URLError, TimeoutError) as e:
+        raise ReleaseFetchError(f"{url}: {e}") from e
+
+
+def _pick_asset(assets: list[dict], kind: str, tag: str) -> dict:
+    suffixes = KINDS[kind]
+    matches = [a for a in assets if a.get("name", "").endswith(suffixes)]
+    if not matches:
+        raise ReleaseFetchError(f"Release {tag} has no {kind} asset ({', '.join('*' + s for s in suffixes)})")
+    return matches[0]
+
+
+def _parse_sums(text: str) -> dict[str, str]:
+    """``sha256sum`` output → {file name: hex digest}."""
+    sums = {}
+    for line in text.splitlines():
+        parts = line.split()
+        if len(parts) == 2:
+            sums[parts[1].lstrip("*")] = parts[0].lower()
+    return sums
+
+
+def _expected_sha256(asset: dict, assets: list[dict], pinned: str | None, token, timeout) -> str:
+    if pinned:
+        return pinned.lower()
+    sums_asset = next((a for a in assets if a.get("name") == CHECKSUMS_ASSET), None)
+    if sums_asset is not None:
+        with _open(_asset_url(sums_asset), token, timeout, "application/octet-stream") as response:
+            sums = _parse_sums(response.read().decode("utf-8", "replace"))
+        if asset["name"] not in sums:
+            raise ReleaseFetchError(f"{CHECKSUMS_ASSET} does not list {asset['name']}")
+        return sums[asset["name"]]
+    digest = asset.get("digest") or ""
+    if digest.startswith("sha256:"):
+        return digest.removeprefix("sha256:").lower()
+    raise ReleaseFetchError(f"No checksum published for {asset['name']}; pass sha256= to pin one")
+
+
+def _tree_root(directory: Path) -> Path:
+    if (directory / MANIFEST_NAME).is_file():
+        return directory
+    # Tarballs usually wrap the tree in one top-level directory.
+    for child in directory.iterdir():
+        if child.is_dir() and (child / MANIFEST_NAME).is_file():
+            return child
+    raise ReleaseFetchError(f"No {MANIFEST_NAME} in the release tarball")
+
+
+def _unpack(archive: Path, target: Path) -> Path:
+    try:
+        with tarfile.open(archive) as tar:
+            tar.extractall(target, filter="data")
+    except (tarfile.TarError, OSError) as e:
+        raise ReleaseFetchError(f"{archive.name}: {e}") from e
+    return _tree_root(target)
+
+
+def _cached(directory: Path, version: str, repo: str, pinned: str | None) -> Release | None:
+    marker = directory / "asset.json"
+    if not marker.is_file():
+        return None
+    try:
+        meta = json.loads(marker.read_text())
+        artifact = directory / meta["asset"]
+        if not artifact.is_file() or _sha256(artifact) != meta["sha256"]:
+            return None
+    except (OSError, ValueError, KeyError):
+        return None
+    if pinned and pinned.lower() != meta["sha256"]:
+        return None
+    path = artifact if artifact.suffix == ".json" else _tree_root(directory / "tree")
+    return Release(version=version, repo=repo, asset=meta["asset"], sha256=meta["sha256"], path=path)
+
+
+def fetch_release(
+    version: str,
+    *,
+    repo: str = DEFAULT_REPO,
+    kind: str = "json",
+    sha256: str | None = None,
+    cache_dir: str | Path | None = None,
+    token: str | None = None,
+    timeout: float = 30,
+) -> Release:
+    """Download (or reuse from the cache) release ``version`` of ``repo``.
+
+    ``kind`` is ``"json"`` (bundle) or ``"tarball"`` (YAML tree).
+    ``token`` defaults to ``github_token()`` — needed for private repos
+    and to avoid the anonymous API rate limit; without one the release
+    is fetched anonymously. Raises
+    ``ReleaseFetchError`` when the release, asset or checksum is missing
+    or the download doesn't match.
+    """
+    if kind not in KINDS:
+        raise ValueError(f"kind must be one of {', '.join(KINDS)}")
+    tag = _tag(version)
+    directory = Path(cache_dir or default_cache_dir()) / repo.replace("/", "__") / tag / kind
+    cached = _cached(directory, version, repo, sha256)
+    if cached is not None:
+        return cached
+
+    token = token or github_token()
+    with _open(f"{API_URL}/repos/{repo}/releases/tags/{tag}", token, timeout, "application/vnd.github+json") as r:
+        assets = json.loads(r.read()).get("assets") or []
+    asset = _pick_asset(assets, kind, tag)
+    expected = _expected_sha256(asset, assets, sha256, token, timeout)
+
+    directory.parent.mkdir(parents=True, exist_ok=True)
+    staging = Path(tempfile.mkdtemp(dir=directory.parent, prefix=f".{kind}-"))
+    try:
+        artifact = staging / asset["name"]
+        with _open(_asset_url(asset), token, timeout, "application/octet-stream") as response:
+            with artifact.open("wb") as f:
+                shutil.copyfileobj(response, f, CHUNK)
+        actual = _sha256(artifact)
+        if actual != expected:
+            raise ReleaseFetchError(f"Checksum mismatch for {asset['name']}: expected {expected}, got {actual}")
+        if kind == "tarball":
+            _unpack(artifact, staging / "tree")
+        (staging / "asset.json").write_text(json.dumps({"asset": asset["name"], "sha256": actual}))
+        shutil.rmtree(directory, ignore_errors=True)
+        staging.rename(directory)
+    finally:
+        shutil.rmtree(staging, ignore_errors=True)
+
+    release = _cached(directory, version, repo, sha256)
+    if release is None:  # pragma: no cover - just written and verified
+        raise ReleaseFetchError(f"Could not cache {asset['name']} in {directory}")
+    return release
+
+
+def load_release(version: str, **kwargs) -> Library:
+    """``fetch_release(version, **kwargs).load()``."""
+    return fetch_release(version, **kwargs).load()
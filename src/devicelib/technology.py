@@ -0,0 +1,229 @@
+"""Typed ``technology_config`` decoding and encoding.
+
+``decode_technology_config`` picks the class from the ``technology`` key
+and checks every value's type on the way in — a ``scale`` written as
+``"0.1"`` or an ``address`` as ``1.5`` raises ``TechnologyConfigError``
+naming the path instead of surfacing as a wrong reading later. Integers
+are accepted where a float is expected (``scale: 1``) and come back as
+floats. ``to_dict`` produces the exporter's layout again, so decode →
+encode round-trips an exported config.
+"""
+
+from __future__ import annotations
+
+from dataclasses import dataclass, field
+
+from .models import Register
+
+REGISTER_DATA_TYPES = ("int16", "uint16", "int32", "uint32", "int64", "uint64", "float32")
+
+
+class TechnologyConfigError(ValueError):
+    pass
+
+
+def _mapping(data, path: str) -> dict:
+    if data is None:
+        return {}
+    if not isinstance(data, dict):
+        raise TechnologyConfigError(f"{path}: expected a mapping, got {type(data).__name__}")
+    return data
+
+
+def _str(data: dict, key: str, path: str, default: str = "") -> str:
+    value = data.get(key)
+    if value is None:
+        return default
+    if not isinstance(value, str):
+        raise TechnologyConfigError(f"{path}.{key}: expected a string, got {value!r}")
+    return value
+
+
+def _bool(data: dict, key: str, path: str, default: bool) -> bool:
+    value = data.get(key)
+    if value is None:
+        return default
+    if not isinstance(value, bool):
+        raise TechnologyConfigError(f"{path}.{key}: expected true/false, got {value!r}")
+    return value
+
+
+def _int(data: dict, key: str, path: str, default: int | None = None) -> int | None:
+    value = data.get(key)
+    if value is None:
+        return default
+    if isinstance(value, bool) or not isinstance(value, int):
+        raise TechnologyConfigError(f"{path}.{key}: expected an integer, got {value!r}")
+    return value
+
+
+def _float(data: dict, key: str, path: str, default: float) -> float:
+    value = data.get(key)
+    if value is None:
+        return default
+    if isinstance(value, bool) or not isinstance(value, int | float):
+        raise TechnologyConfigError(f"{path}.{key}: expected a number, got {value!r}")
+    return float(value)
+
+
+def _register(data, path: str) -> Register:
+    data = _mapping(data, path)
+    reg_field = _mapping(data.get("field"), f"{path}.field")
+    data_type = _str(data, "data_type", path, "uint16")
+    if data_type not in REGISTER_DATA_TYPES:
+        raise TechnologyConfigError(f"{path}.data_type: unknown data type {data_type!r}")
+    return Register(
+        address=_int(data, "address", path, 0),
+        data_type=data_type,
+        field_name=_str(reg_field, "name", f"{path}.field"),
+        unit=_str(reg_field, "unit", f"{path}.field"),
+        scale=_float(data, "scale", path, 1.0),
+        offset=_float(data, "offset", path, 0.0),
+        display=_mapping(data.get("display"), f"{path}.display"),
+        description=_str(reg_field, "description", f"{path}.field"),
+    )
+
+
+@dataclass(frozen=True)
+class ModbusConfig:
+    function: str = ""
+    byte_order: str = ""
+    word_order: str = ""
+    registers: tuple[Register, ...] = ()
+
+    technology = "modbus"
+
+    @classmethod
+    def from_dict(cls, data: dict, path: str = "technology_config") -> ModbusConfig:
+        regs = data.get("register_definitions") or []
+        if not isinstance(regs, list):
+            raise TechnologyConfigError(f"{path}.register_definitions: expected a list")
+        return cls(
+            function=_str(data, "function", path),
+            byte_order=_str(data, "byte_order", path),
+            word_order=_str(data, "word_order", path),
+            registers=tuple(_register(r, f"{path}.register_definitions[{i}]") for i, r in enumerate(regs)),
+        )
+
+    def to_dict(self) -> dict:
+        out = {"technology": self.technology}
+        for key in ("function", "byte_order", "word_order"):
+            if getattr(self, key):
+                out[key] = getattr(self, key)
+        if self.registers:
+            out["register_definitions"] = [reg.to_dict() for reg in self.registers]
+        return out
+
+
+@dataclass(frozen=True)
+class LoRaWANConfig:
+    device_class: str = ""
+    lorawan_version: str = ""
+    lorawan_phy_version: str = ""
+    frequency_plan_id: str = ""
+    join_eui_default: str = ""
+    supports_join: bool = True
+    downlink_f_port: int | None = None
+    codec_format: str = ""
+    codec_script: str = ""
+    codec_source: dict = field(default_factory=dict, hash=False)  # {url, sha256, fetched_at}
+
+    technology = "lorawan"
+
+    @classmethod
+    def from_dict(cls, data: dict, path: str = "technology_config") -> LoRaWANConfig:
+        codec = data.get("payload_codec")
+        if isinstance(codec, str):
+            # Older trees carry the script directly.
+            codec = {"script": codec}
+        codec = _mapping(codec, f"{path}.payload_codec")
+        return cls(
+            device_class=_str(data, "device_class", path),
+            lorawan_version=_str(data, "lorawan_version", path),
+            lorawan_phy_version=_str(data, "lorawan_phy_version", path),
+            frequency_plan_id=_str(data, "frequency_plan_id", path),
+            join_eui_default=_str(data, "join_eui_default", path),
+            supports_join=_bool(data, "supports_join", path, True),
+            downlink_f_port=_int(data, "downlink_f_port", path),
+            codec_format=_str(codec, "format", f"{path}.payload_codec"),
+            codec_script=_str(codec, "script", f"{path}.payload_codec"),
+            codec_source=_mapping(codec.get("source"), f"{path}.payload_codec.source"),
+        )
+
+    def to_dict(self) -> dict:
+        out = {"technology": self.technology}
+        for key in ("device_class", "lorawan_version", "lorawan_phy_version", "frequency_plan_id", "join_eui_default"):
+            if getattr(self, key):
+                out[key] = getattr(self, key)
+        if not self.supports_join:
+            out["supports_join"] = False
+        if self.downlink_f_port is not None:
+            out["downlink_f_port"] = self.downlink_f_port
+        if self.codec_script:
+            out["payload_codec"] = {"format": self.codec_format or "ttn_v3", "script": self.codec_script}
+            if self.codec_source:
+                out["payload_codec"]["source"] = dict(self.codec_source)
+        return out
+
+
+@dataclass(frozen=True)
+class WMBusConfig:
+    manufacturer_code: str = ""
+    wmbus_version: str = ""
+    wmbus_device_type: int | None = None
+    encryption_required: bool = False
+    shared_encryption_key: str = ""
+    wmbusmeters_driver: str = ""
+    is_mvt_default: bool = False
+
+    technology = "wmbus"
+
+    @classmethod
+    def from_dict(cls, data: dict, path: str = "technology_config") -> WMBusConfig:
+        return cls(
+            manufacturer_code=_str(data, "manufacturer_code", path),
+            wmbus_version=_str(data, "wmbus_version", path),
+            wmbus_device_type=_int(data, "wmbus_device_type", path),
+            encryption_required=_bool(data, "encryption_required", path, False),
+            shared_encryption_key=_str(data, "shared_encryption_key", path),
+            wmbusmeters_driver=_str(data, "wmbusmeters_driver", path),
+            is_mvt_default=_bool(data, "is_mvt_default", path, False),
+        )
+
+    def to_dict(self) -> dict:
+        out = {
+            "technology": self.technology,
+            "manufacturer_code": self.manufacturer_code,
+        }
+        if self.wmbus_version:
+            out["wmbus_version"] = self.wmbus_version
+        out["wmbus_device_type"] = self.wmbus_device_type
+        out["encryption_required"] = self.encryption_required
+        if self.shared_encryption_key:
+            out["shared_encryption_key"] = self.shared_encryption_key
+        if self.wmbusmeters_driver:
+            out["wmbusmeters_driver"] = self.wmbusmeters_driver
+        if self.is_mvt_default:
+            out["is_mvt_default"] = True
+        return out
+
+
+TechnologyConfig = ModbusConfig | LoRaWANConfig | WMBusConfig
+
+DECODERS: dict[str, type[ModbusConfig] | type[LoRaWANConfig] | type[WMBusConfig]] = {
+    cls.technology: cls for cls in (ModbusConfig, LoRaWANConfig, WMBusConfig)
+}
+
+
+def decode_technology_config(data, path: str = "technology_config") -> TechnologyConfig | None:
+    """The typed config for ``data``; ``None`` when no technology is set."""
+    data = _mapping(data, path)
+    technology = _str(data, "technology", path)
+    if not technology:
+        return None
+    decoder = DECODERS.get(technology)
+    if decoder is None:
+        raise TechnologyConfigError(
+            f"{path}.technology: unknown technology {technology!r} (expected one of {', '.join(DECODERS)})"
+        )
+    return decoder.from_dict(data, path)
@@ -0,0 +1,163 @@
+"""Unit conversion between the units register maps declare.
+
+Meters report in whatever unit their firmware uses — Wh or kWh, litres
+or m³, K or °C — while consumers want the canonical unit of the field
+(``devicelib.fields``) or of the L1 metric a field maps onto. This module
+knows how the units of one quantity relate::
+
+    units.convert(1500, "Wh", "kWh")            # 1.5
+    units.convert(293.15, "K", "°C")            # 20.0
+    units.conversion("L", "m³")                 # (0.001, 0.0): scale, offset
+    units.to_canonical(12.5, "MWh", "energy_import")
+
+A device's pipeline is raw → register ``scale``/``offset`` (in the
+register's unit) → field mapping ``scale``/``offset`` (in the target
+metric's unit). ``check_device`` verifies that the mapping's scale and
+offset are exactly the conversion between those two units, and that
+unmapped registers at least declare a unit convertible to their
+canonical field's.
+"""
+
+from __future__ import annotations
+
+import math
+
+from . import fields
+from .models import Device, Library, Register
+
+# unit → (dimension, factor, offset): value in the dimension's base unit
+# is ``value * factor + offset``.
+UNITS: dict[str, tuple[str, float, float]] = {
+    # Active / reactive / apparent energy (base: Wh, varh, VAh)
+    "Wh": ("energy", 1.0, 0.0), "kWh": ("energy", 1e3, 0.0), "MWh": ("energy", 1e6, 0.0),
+    "J": ("energy", 1 / 3600, 0.0), "MJ": ("energy", 1e6 / 3600, 0.0), "GJ": ("energy", 1e9 / 3600, 0.0),
+    "varh": ("reactive_energy", 1.0, 0.0), "kvarh": ("reactive_energy", 1e3, 0.0),
+    "VAh": ("apparent_energy", 1.0, 0.0), "kVAh": ("apparent_energy", 1e3, 0.0),
+    # Power (base: W, var, VA)
+    "W": ("power", 1.0, 0.0), "kW": ("power", 1e3, 0.0), "MW": ("power", 1e6, 0.0),
+    "var": ("reactive_power", 1.0, 0.0), "kvar": ("reactive_power", 1e3, 0.0),
+    "VA": ("apparent_power", 1.0, 0.0), "kVA": ("apparent_power", 1e3, 0.0),
+    # Electrical
+    "V": ("voltage", 1.0, 0.0), "mV": ("voltage", 1e-3, 0.0), "kV": ("voltage", 1e3, 0.0),
+    "A": ("current", 1.0, 0.0), "mA": ("current", 1e-3, 0.0), "kA": ("current", 1e3, 0.0),
+    "Hz": ("frequency", 1.0, 0.0),
+    # Volume and flow (base: m³, m³/h)
+    "m³": ("volume", 1.0, 0.0), "L": ("volume", 1e-3, 0.0),
+    "m³/h": ("flow", 1.0, 0.0), "L/h": ("flow", 1e-3, 0.0), "L/min": ("flow", 0.06, 0.0),
+    "L/s": ("flow", 3.6, 0.0), "m³/s": ("flow", 3600.0, 0.0),
+    # Temperature (base: °C)
+    "°C": ("temperature", 1.0, 0.0), "K": ("temperature", 1.0, -273.15),
+    "°F": ("temperature", 5 / 9, -160 / 9),
+    # Pressure (base: Pa)
+    "Pa": ("pressure", 1.0, 0.0), "hPa": ("pressure", 1e2, 0.0), "mbar": ("pressure", 1e2, 0.0),
+    "kPa": ("pressure", 1e3, 0.0), "bar": ("pressure", 1e5, 0.0), "MPa": ("pressure", 1e6, 0.0),
+    # Time (base: s)
+    "s": ("time", 1.0, 0.0), "min": ("time", 60.0, 0.0), "h": ("time", 3600.0, 0.0), "d": ("time", 86400.0, 0.0),
+    # Dimensionless (base: ratio)
+    "": ("ratio", 1.0, 0.0), "ratio": ("ratio", 1.0, 0.0), "%": ("ratio", 1e-2, 0.0),
+    # Quantities with a single unit
+    "ppm": ("concentration", 1.0, 0.0), "lx": ("illuminance", 1.0, 0.0),
+    "dBm": ("signal_power", 1.0, 0.0), "dB": ("signal_ratio", 1.0, 0.0), "HCA": ("heat_cost", 1.0, 0.0),
+}
+
+# Fields whose values are differences: 5 K of spread is 5 °C of spread,
+# so conversions ignore the offsets.
+DIFFERENCE_FIELDS = frozenset({"temperature_difference"})
+
+
+class UnitError(ValueError):
+    pass
+
+
+def _unit(unit: str) -> tuple[str, float, float]:
+    try:
+        return UNITS[unit]
+    except KeyError:
+        raise UnitError(f"Unknown unit {unit!r}") from None
+
+
+def dimension(unit: str) -> str | None:
+    """The quantity ``unit`` measures ("energy", "temperature", …), or None."""
+    return UNITS[unit][0] if unit in UNITS else None
+
+
+def compatible(a: str, b: str) -> bool:
+    return a in UNITS and b in UNITS and UNITS[a][0] == UNITS[b][0]
+
+
+def conversion(from_unit: str, to_unit: str, difference: bool = False) -> tuple[float, float]:
+    """``(scale, offset)`` with ``value_in_to = value_in_from * scale + offset``.
+
+    ``difference`` converts a difference of two values (offsets cancel).
+    """
+    from_dim, from_factor, from_offset = _unit(from_unit)
+    to_dim, to_factor, to_offset = _unit(to_unit)
+    if from_dim != to_dim:
+        raise UnitError(f"Cannot convert {from_unit!r} ({from_dim}) to {to_unit!r} ({to_dim})")
+    offset = 0.0 if difference else (from_offset - to_offset) / to_factor
+    return from_factor / to_factor, offset
+
+
+def convert(value: float, from_unit: str, to_unit: str, difference: bool = False) -> float:
+    scale, offset = conversion(from_unit, to_unit, difference)
+    return value * scale + offset
+
+
+def to_canonical(value: float, unit: str, field_name: str) -> float:
+    """``value`` (in ``unit``) in the canonical unit of ``field_name``."""
+    spec = fields.lookup(field_name)
+    if spec is None:
+        raise UnitError(f"{field_name!r} is not a canonical field")
+    return convert(value, unit, spec.unit, difference=spec.name in DIFFERENCE_FIELDS)
+
+
+def _close(a: float, b: float) -> bool:
+    return math.isclose(a, b, rel_tol=1e-6, abs_tol=1e-9)
+
+
+def check_register(register: Register, target_unit: str, scale: float = 1.0, offset: float = 0.0) -> str | None:
+    """Why ``scale``/``offset`` applied to ``register`` doesn't give
+    ``target_unit`` (None when it does, or the register declares no unit)."""
+    if not register.unit:
+        return None
+    spec = fields.lookup(register.field_name)
+    difference = spec is not None and spec.name in DIFFERENCE_FIELDS
+    try:
+        want_scale, want_offset = conversion(register.unit, target_unit, difference)
+    except UnitError as e:
+        return f"{register.field_name}: {e}"
+    if _close(scale, want_scale) and _close(offset, want_offset):
+        return None
+    return (
+        f"{register.field_name}: {register.unit!r} → {target_unit!r} needs scale {want_scale:g}"
+        f" offset {want_offset:g}, got scale {scale:g} offset {offset:g}"
+    )
+
+
+def check_device(device: Device, library: Library | None = None) -> list[str]:
+    """Unit problems in ``device``'s register → metric pipeline.
+
+    Mapped registers are checked against the target metric's unit (from
+    ``library``'s metric catalogue, else the canonical unit when the target
+    is the field's usual metric); unmapped registers with a canonical
+    field name against the field's canonical unit.
+    """
+    problems = []
+    mappings = device.field_mappings
+    for register in device.registers:
+        mapped = [m for m in mappings if m.source == register.field_name]
+        spec = fields.lookup(register.field_name)
+        for mapping in mapped:
+            metric = library.metric(mapping.target) if library else None
+            if metric is not None:
+                target_unit = metric.unit
+            elif spec is not None and spec.metric == mapping.target:
+                target_unit = spec.unit  # no catalogue at hand; the field's usual metric shares its unit
+            else:
+                continue
+            problem = check_register(register, target_unit, mapping.scale, mapping.offset)
+            if problem:
+                problems.append(f"{problem} (mapping to {mapping.target})")
+        if not mapped and spec is not None and register.unit and not compatible(register.unit, spec.unit):
+            problems.append(f"{register.field_name}: unit {register.unit!r} cannot be converted to {spec.unit!r}")
+    return problems
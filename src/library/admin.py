@@ -7,6 +7,7 @@ from .forms import PrettyJSONWidget
 from .models import (
     APIKey,
     ControlConfig,
+    DeviceDraft,
     DeviceType,
     GatewayAssignment,
     LibraryVersion,
@@ -271,6 +272,12 @@ class GatewayAssignmentAdmin(admin.ModelAdmin):
     search_fields = ["serial_number", "spark_url", "assigned_by"]
 
 
+@admin.register(DeviceDraft)
+class DeviceDraftAdmin(admin.ModelAdmin):
+    list_display = ["title", "created_by", "modified"]
+    search_fields = ["title", "notes"]
+
+
 @admin.register(APIKey)
 class APIKeyAdmin(admin.ModelAdmin):
     list_display = ["name", "is_active", "last_used_at", "created_by", "created"]
@@ -51,7 +51,18 @@ class RegisterDefinitionSerializer(serializers.ModelSerializer):
         fields = ["field", "scale", "offset", "address", "data_type"]
 
     def get_field(self, obj):
-        return {"name": obj.field_name, "unit": obj.field_unit}
+        field = {"name": obj.field_name, "unit": obj.field_unit}
+        if obj.field_description:
+            field["description"] = obj.field_description
+        return field
+
+    def to_representation(self, obj):
+        data = super().to_representation(obj)
+        # Display hints are optional; omit the key entirely when unset so
+        # existing payloads (and their hashes) don't change.
+        if obj.display:
+            data["display"] = obj.display
+        return data
 
 
 # NOTE: per-technology config is serialized by DeviceTechnologyConfigSerializer
@@ -90,9 +101,13 @@ class DeviceTechnologyConfigSerializer(serializers.Serializer):
     technology = serializers.CharField()
 
     def to_representation(self, device):
-        data = {"technology": device.technology}
+        return self.technology_config(device, device.technology)
+
+    @staticmethod
+    def technology_config(device, technology):
+        data = {"technology": technology}
 
-        if device.technology == "modbus":
+        if technology == "modbus":
             try:
                 modbus = device.modbus_config
                 if modbus.function:
@@ -107,7 +122,7 @@ class DeviceTechnologyConfigSerializer(serializers.Serializer):
             except ModbusConfig.DoesNotExist:
                 pass
 
-        elif device.technology == "lorawan":
+        elif technology == "lorawan":
             try:
                 lorawan = device.lorawan_config
                 if lorawan.device_class:
@@ -132,7 +147,7 @@ class DeviceTechnologyConfigSerializer(serializers.Serializer):
             except LoRaWANConfig.DoesNotExist:
                 pass
 
-        elif device.technology == "wmbus":
+        elif technology == "wmbus":
             try:
                 wmbus = device.wmbus_config
                 data["manufacturer_code"] = wmbus.manufacturer_code
@@ -204,6 +219,7 @@ class VendorModelDetailSerializer(serializers.ModelSerializer):
     vendor_name = serializers.CharField(source="vendor.name", read_only=True)
     device_type_key = serializers.UUIDField(source="device_type_fk.key", read_only=True, allow_null=True)
     technology_config = DeviceTechnologyConfigSerializer(source="*", read_only=True)
+    additional_technology_configs = serializers.SerializerMethodField()
     control_config = serializers.SerializerMethodField()
     processor_config = serializers.SerializerMethodField()
     alarm_config = serializers.SerializerMethodField()
@@ -222,13 +238,21 @@ class VendorModelDetailSerializer(serializers.ModelSerializer):
             "device_type_key",
             "description",
             "technology_config",
+            "additional_technology_configs",
             "control_config",
             "processor_config",
             "alarm_config",
+            "links",
             "effective_field_mappings",
             "declared_metrics",
         ]
 
+    def get_additional_technology_configs(self, obj):
+        return [
+            DeviceTechnologyConfigSerializer.technology_config(obj, technology)
+            for technology in obj.additional_technologies
+        ]
+
     def get_control_config(self, obj):
         try:
             return ControlConfigSerializer(obj.control_config).data
@@ -0,0 +1,121 @@
+"""Human-readable release notes between two published library versions.
+
+Built from the same structured comparison as ``diff_versions``
+(``version_diff.compare_snapshot_maps``), then sorted into three buckets
+per vendor or technology:
+
+- **New devices** — present only in the newer version.
+- **Improved definitions** — additive or cosmetic changes: new registers,
+  descriptions, units, mappings, codecs.
+- **Breaking changes** — anything that can invalidate data or integrations
+  already in the field: removed devices, renamed vendor/model, changed
+  technology, and removed or re-typed/re-scaled/renamed registers.
+"""
+
+from __future__ import annotations
+
+from collections import defaultdict
+
+from .models import DeviceHistory, LibraryVersion, LibraryVersionDevice
+from .version_diff import compare_snapshot_maps, version_snapshot_map
+
+GROUP_BY = ("vendor", "technology")
+SECTIONS = (("new", "New devices"), ("improved", "Improved definitions"), ("breaking", "Breaking changes"))
+
+# Register attributes whose change alters how existing raw values decode.
+_BREAKING_REGISTER_FIELDS = ("field_name", "data_type", "scale", "offset")
+_UNGROUPED = "Other"
+
+
+def _describe_changes(diff: dict) -> tuple[list[str], list[str]]:
+    """Split a ``diff_snapshots`` result into (improvements, breaking) phrases."""
+    improved, breaking = [], []
+    for key in ("vendor", "model_number"):
+        if key in diff:
+            breaking.append(f"{key.replace('_', ' ')} renamed {diff[key]['old']!r} → {diff[key]['new']!r}")
+    if "technology" in diff:
+        breaking.append(f"technology changed {diff['technology']['old']} → {diff['technology']['new']}")
+
+    regs = diff.get("registers") or {}
+    if regs.get("added"):
+        improved.append(f"{len(regs['added'])} register(s) added")
+    for reg in regs.get("removed", []):
+        breaking.append(f"register {reg['address']} ({reg['field_name']}) removed")
+    for mod in regs.get("modified", []):
+        changed = [f for f in _BREAKING_REGISTER_FIELDS if mod["old"].get(f) != mod["new"].get(f)]
+        if changed:
+            breaking.append(f"register {mod['address']} {', '.join(changed)} changed")
+        else:
+            improved.append(f"register {mod['address']} unit changed")
+
+    other = sorted(
+        {k.split(".")[0].replace("_", " ") for k in diff if k not in ("vendor", "model_number", "technology", "registers")}
+    )
+    improved.extend(f"{name} updated" for name in other)
+    return improved, breaking
+
+
+def _group_key(snapshot: dict | None, group_by: str) -> str:
+    return (snapshot or {}).get(group_by) or _UNGROUPED
+
+
+def _deleted_snapshot(label: str) -> dict | None:
+    """Last snapshot of a deleted device — its history outlives it, unlinked."""
+    return (
+        DeviceHistory.objects.filter(device__isnull=True, device_label=label)
+        .order_by("-created").values_list("snapshot", flat=True).first()
+    )
+
+
+def build_changelog(since: LibraryVersion, until: LibraryVersion, group_by: str = "vendor") -> dict:
+    """``{group: {"new": [...], "improved": [...], "breaking": [...]}}``
+    with one Markdown-ready line per device."""
+    result = compare_snapshot_maps(version_snapshot_map(since), version_snapshot_map(until))
+    groups: dict[str, dict[str, list[str]]] = defaultdict(lambda: {key: [] for key, _ in SECTIONS})
+
+    for entry in result["added"]:
+        groups[_group_key(entry["snapshot"], group_by)]["new"].append(f"**{entry['label']}**")
+    for entry in result["modified"]:
+        improved, breaking = _describe_changes(entry["diff"])
+        group = groups[_group_key(entry["snapshot"], group_by)]
+        if breaking:
+            group["breaking"].append(f"**{entry['label']}** — {'; '.join(breaking)}")
+        if improved:
+            group["improved"].append(f"**{entry['label']}** — {', '.join(improved)}")
+
+    # Devices deleted outright lose their FK and drop out of the snapshot
+    # maps; the REMOVED rows recorded at publish time keep the label, and
+    # the device's history its vendor and technology.
+    removed = {e["label"]: e["snapshot"] for e in result["removed"]}
+    for entry in LibraryVersionDevice.objects.filter(
+        library_version__version__gt=since.version,
+        library_version__version__lte=until.version,
+        change_type=LibraryVersionDevice.ChangeType.REMOVED,
+    ):
+        if entry.device_label not in removed:
+            removed[entry.device_label] = _deleted_snapshot(entry.device_label)
+    for label, snapshot in sorted(removed.items()):
+        groups[_group_key(snapshot, group_by)]["breaking"].append(f"**{label}** — removed from the library")
+
+    return dict(sorted(groups.items(), key=lambda kv: (kv[0] == _UNGROUPED, kv[0].lower())))
+
+
+def render_markdown(since: LibraryVersion, until: LibraryVersion, groups: dict) -> str:
+    counts = {key: sum(len(g[key]) for g in groups.values()) for key, _ in SECTIONS}
+    lines = [
+        f"# Device library v{until.version}",
+        "",
+        f"Changes since v{since.version}: {counts['new']} new device(s), "
+        f"{counts['improved']} improved definition(s), {counts['breaking']} breaking change(s).",
+    ]
+    if until.notes:
+        lines += ["", until.notes.strip()]
+    if not groups:
+        lines += ["", "No device changes."]
+    for name, sections in groups.items():
+        lines += ["", f"## {name}"]
+        for key, title in SECTIONS:
+            if sections[key]:
+                lines += ["", f"### {title}", ""]
+                lines += [f"- {line}" for line in sections[key]]
+    return "\n".join(lines) + "\n"
@@ -0,0 +1,76 @@
+"""The LoRaWAN codec editor — decoder, fPorts and expected outputs on one page.
+
+A LoRaWAN device is decoded either by its JavaScript payload codec
+(``decoder_type`` ``js_codec``) or, without one, by the network server's
+field map (``lorawan_field_map``). What the decoder is expected to emit
+lives in the processor config's ``field_mappings`` (metrics declared on
+the device type) and ``extra_mappings`` (everything else); ``f_port`` on
+an entry records the uplink port that carries it. The codec editor
+shows all of it as one table of outputs (``codec_outputs``) and files
+each row back into the list it belongs to (``split_outputs``), keeping
+the keys the table doesn't edit (an extra's tier, label and unit).
+
+No JavaScript engine ships with the library, so ``missing_sources`` can
+only check that each output's field is mentioned in the codec at all —
+a renamed field shows up there before the first uplink fails to map.
+"""
+
+from __future__ import annotations
+
+import re
+
+# Keys the outputs table edits; anything else on an entry rides along.
+OUTPUT_KEYS = ("source", "f_port", "target", "scale", "offset")
+EXTRA_DEFAULT_TIER = "secondary"
+
+
+def codec_outputs(processor_config) -> list[dict]:
+    """Rows for the outputs table: ``field_mappings`` then ``extra_mappings``.
+
+    Each row has the ``OUTPUT_KEYS`` and ``rest``, the entry's other keys.
+    """
+    if processor_config is None:
+        return []
+    rows = []
+    for entry in [*(processor_config.field_mappings or []), *(processor_config.extra_mappings or [])]:
+        if not isinstance(entry, dict):
+            continue
+        row = {key: entry.get(key) for key in OUTPUT_KEYS}
+        row["rest"] = {k: v for k, v in entry.items() if k not in OUTPUT_KEYS}
+        rows.append(row)
+    return rows
+
+
+def split_outputs(rows: list[dict], declared: set[str]) -> tuple[list[dict], list[dict]]:
+    """``(field_mappings, extra_mappings)`` from table rows.
+
+    A row whose metric is declared on the device type is a field mapping;
+    any other is an extra (tier ``secondary`` unless it had one). Scale 1,
+    offset 0 and an empty fPort are left out, as the mapping editors do.
+    """
+    field_mappings, extra_mappings = [], []
+    for row in rows:
+        entry = {"source": row["source"], "target": row["target"]}
+        if row.get("f_port") is not None:
+            entry["f_port"] = row["f_port"]
+        if row.get("scale") not in (None, 1):
+            entry["scale"] = row["scale"]
+        if row.get("offset") not in (None, 0):
+            entry["offset"] = row["offset"]
+        rest = dict(row.get("rest") or {})
+        if row["target"] in declared:
+            rest.pop("tier", None)
+            field_mappings.append({**entry, **rest})
+        else:
+            extra_mappings.append({**entry, "tier": rest.pop("tier", EXTRA_DEFAULT_TIER), **rest})
+    return field_mappings, extra_mappings
+
+
+def missing_sources(script: str, rows: list[dict]) -> list[str]:
+    """Output fields the codec never mentions (``data.temperature`` looks for ``temperature``)."""
+    missing = []
+    for row in rows:
+        name = str(row["source"]).rsplit(".", 1)[-1]
+        if name and not re.search(rf"(?<![\w$]){re.escape(name)}(?![\w$])", script):
+            missing.append(row["source"])
+    return missing
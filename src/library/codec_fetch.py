@@ -0,0 +1,205 @@
+"""Fetch a LoRaWAN payload codec from a vendor URL, with provenance.
+
+Vendors publish decoders on GitHub, in the TTN device repository or on
+their own sites. ``fetch_codec`` downloads one and ``apply_fetched_codec``
+stores it on the device's ``LoRaWANConfig`` together with where it came
+from, the SHA-256 of the downloaded bytes and when — exported next to the
+script (``payload_codec.source``) so licensing questions and upstream
+update checks can be answered from the tree.
+
+``check_upstream`` re-fetches a recorded source and compares hashes; it
+backs the ``check_upstreams`` command. Codecs from the TTN device
+repository are ordinary GitHub URLs here and need nothing special.
+
+The server fetches whatever URL an editor pastes, and the body can be read
+back as the codec, so only public addresses are reachable: every
+connection (redirects included) checks the address it is about to connect
+to and refuses loopback, private, link-local and other non-global ones.
+Proxies from the environment are not used.
+"""
+
+from __future__ import annotations
+
+import hashlib
+import http.client
+import ipaddress
+import socket
+import urllib.error
+import urllib.request
+from dataclasses import dataclass
+from datetime import datetime
+from urllib.parse import urlparse
+
+from django.utils import timezone
+
+MAX_CODEC_BYTES = 512 * 1024
+FETCH_TIMEOUT = 15  # seconds
+
+
+class CodecFetchError(Exception):
+    pass
+
+
+class BlockedAddress(OSError):
+    pass
+
+
+def check_public(ip: str) -> None:
+    """Raise ``BlockedAddress`` unless ``ip`` is a global (public internet) address."""
+    address = ipaddress.ip_address(ip.split("%", 1)[0])
+    if address.version == 6 and address.ipv4_mapped:
+        address = address.ipv4_mapped
+    if not address.is_global or address.is_multicast:
+        raise BlockedAddress(f"{ip} is not a public address")
+
+
+def _public_connection(address, timeout=socket._GLOBAL_DEFAULT_TIMEOUT, source_address=None):
+    """``socket.create_connection`` that only connects to public addresses.
+
+    The host is resolved once and the connection made to the checked
+    address, so a second lookup can't answer differently.
+    """
+    host, port = address
+    infos = socket.getaddrinfo(host, port, type=socket.SOCK_STREAM)
+    for *_, sockaddr in infos:
+        check_public(sockaddr[0])
+    error = None
+    for family, type_, proto, _, sockaddr in infos:
+        sock = socket.socket(family, type_, proto)
+        try:
+            if timeout is not socket._GLOBAL_DEFAULT_TIMEOUT:
+                sock.settimeout(timeout)
+            if source_address:
+                sock.bind(source_address)
+            sock.connect(sockaddr)
+            return sock
+        except OSError as e:
+            sock.close()
+            error = e
+    raise error or OSError(f"{host} did not resolve")
+
+
+class _PublicHTTPConnection(http.client.HTTPConnection):
+    def __init__(self, *args, **kwargs):
+        super().__init__(*args, **kwargs)
+        self._create_connection = _public_connection
+
+
+class _PublicHTTPSConnection(http.client.HTTPSConnection):
+    def __init__(self, *args, **kwargs):
+        super().__init__(*args, **kwargs)
+        self._create_connection = _public_connection
+
+
+class _PublicHTTPHandler(urllib.request.HTTPHandler):
+    def http_open(self, req):
+        return self.do_open(_PublicHTTPConnection, req)
+
+
+class _PublicHTTPSHandler(urllib.request.HTTPSHandler):
+    def https_open(self, req):
+        return self.do_open(_PublicHTTPSConnection, req, context=self._context)
+
+
+def _open(request, timeout):
+    opener = urllib.request.build_opener(urllib.request.ProxyHandler({}), _PublicHTTPHandler, _PublicHTTPSHandler)
+    return opener.open(request, timeout=timeout)
+
+
+@dataclass
+class FetchedCodec:
+    url: str
+    script: str
+    sha256: str
+    fetched_at: datetime
+
+
+def raw_url(url: str) -> str:
+    """GitHub "blob" page URLs → the raw file (users paste what the browser shows)."""
+    parsed = urlparse(url)
+    if parsed.netloc == "github.com" and "/blob/" in parsed.path:
+        return f"https://raw.githubusercontent.com{parsed.path.replace('/blob/', '/', 1)}"
+    return url
+
+
+def fetch_codec(url: str, timeout: int = FETCH_TIMEOUT) -> FetchedCodec:
+    """Download the codec at ``url`` (http/https, at most ``MAX_CODEC_BYTES``)."""
+    url = raw_url(url.strip())
+    if urlparse(url).scheme not in ("http", "https"):
+        raise CodecFetchError(f"Only http(s) URLs can be fetched: {url}")
+    request = urllib.request.Request(url, headers={"User-Agent": "spark-device-library"})
+    try:
+        with _open(request, timeout) as response:
+            body = response.read(MAX_CODEC_BYTES + 1)
+    except urllib.error.URLError as e:
+        if isinstance(e.reason, BlockedAddress):
+            raise CodecFetchError(f"Refusing to fetch {url}: {e.reason}") from e
+        raise CodecFetchError(f"Could not fetch {url}: {e}") from e
+    except (TimeoutError, ValueError) as e:
+        raise CodecFetchError(f"Could not fetch {url}: {e}") from e
+    if len(body) > MAX_CODEC_BYTES:
+        raise CodecFetchError(f"{url} is larger than {MAX_CODEC_BYTES // 1024} KiB — not a codec script?")
+    try:
+        script = body.decode("utf-8")
+    except UnicodeDecodeError as e:
+        raise CodecFetchError(f"{url} is not UTF-8 text") from e
+    if not script.strip():
+        raise CodecFetchError(f"{url} is empty")
+    return FetchedCodec(url=url, script=script, sha256=hashlib.sha256(body).hexdigest(), fetched_at=timezone.now())
+
+
+def apply_fetched_codec(config, fetched: FetchedCodec, codec_format: str | None = None) -> None:
+    """Store ``fetched`` on a ``LoRaWANConfig`` (saved) with its provenance."""
+    config.payload_codec = fetched.script
+    if codec_format:
+        config.codec_format = codec_format
+    config.codec_source_url = fetched.url
+    config.codec_source_sha256 = fetched.sha256
+    config.codec_fetched_at = fetched.fetched_at
+    config.save()
+
+
+def codec_source(config) -> dict:
+    """The exported ``payload_codec.source`` block (empty without provenance)."""
+    if not config.codec_source_url:
+        return {}
+    source = {"url": config.codec_source_url, "sha256": config.codec_source_sha256}
+    if config.codec_fetched_at:
+        source["fetched_at"] = config.codec_fetched_at.isoformat()
+    return source
+
+
+@dataclass
+class UpstreamCheck:
+    config: object  # LoRaWANConfig
+    status: str  # "current" | "updated" | "error"
+    fetched: FetchedCodec | None = None
+    error: str = ""
+    locally_modified: bool = False  # the stored codec was edited after the fetch
+
+    def as_dict(self) -> dict:
+        device = self.config.device_type
+        out = {
+            "vendor": device.vendor.name,
+            "model_number": device.model_number,
+            "url": self.config.codec_source_url,
+            "status": self.status,
+            "recorded_sha256": self.config.codec_source_sha256,
+            "locally_modified": self.locally_modified,
+        }
+        if self.fetched:
+            out["upstream_sha256"] = self.fetched.sha256
+        if self.error:
+            out["error"] = self.error
+        return out
+
+
+def check_upstream(config, timeout: int = FETCH_TIMEOUT) -> UpstreamCheck:
+    """Re-fetch ``config``'s recorded codec source and compare hashes."""
+    modified = config.codec_modified_since_fetch
+    try:
+        fetched = fetch_codec(config.codec_source_url, timeout=timeout)
+    except CodecFetchError as e:
+        return UpstreamCheck(config, "error", error=str(e), locally_modified=modified)
+    status = "current" if fetched.sha256 == config.codec_source_sha256 else "updated"
+    return UpstreamCheck(config, status, fetched, locally_modified=modified)
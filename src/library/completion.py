@@ -0,0 +1,159 @@
+"""Shell completion scripts for the library management commands.
+
+``render_script`` emits a bash, zsh or fish script that completes the
+``library`` app's command names and each command's own options. Values for
+``--vendor``/``--vendors`` and ``--model`` are completed dynamically: the
+script calls back into ``manage.py completion --values ...`` which reads the
+database, or a YAML tree when ``SPARK_MANIFEST`` is set in the shell.
+"""
+
+from __future__ import annotations
+
+from pathlib import Path
+
+import yaml
+from django.core.management import get_commands, load_command_class
+from django.core.management.base import BaseCommand
+from django.utils.text import slugify
+
+SHELLS = ("bash", "zsh", "fish")
+VENDOR_OPTIONS = ("--vendor", "--vendors")
+MODEL_OPTIONS = ("--model",)
+PATH_OPTIONS = ("--path", "--manifest", "--output", "-o", "--config", "--plugin-dir")
+
+
+def _option_strings(parser) -> set[str]:
+    return {opt for action in parser._actions for opt in action.option_strings}
+
+
+def command_options() -> dict[str, list[str]]:
+    """Map each ``library`` command to its own (non-generic) option flags."""
+    generic = _option_strings(BaseCommand().create_parser("manage.py", "base"))
+    commands = {}
+    for name, app in sorted(get_commands().items()):
+        if app != "library":
+            continue
+        parser = load_command_class(app, name).create_parser("manage.py", name)
+        commands[name] = sorted(_option_strings(parser) - generic)
+    return commands
+
+
+def vendor_values(manifest_path: str | Path | None = None) -> list[str]:
+    if manifest_path:
+        manifest = yaml.safe_load(Path(manifest_path).read_text()) or {}
+        return sorted(slugify(Path(e["file"]).stem) for e in manifest.get("vendors", []) or [] if e.get("file"))
+    from .models import Vendor
+
+    return list(Vendor.objects.order_by("slug").values_list("slug", flat=True))
+
+
+def model_values(vendor: str | None = None, manifest_path: str | Path | None = None) -> list[str]:
+    if manifest_path:
+        manifest_path = Path(manifest_path)
+        models = set()
+        for entry in yaml.safe_load(manifest_path.read_text()).get("vendors", []) or []:
+            file_path = manifest_path.parent / "devices" / entry.get("file", "")
+            if (vendor and slugify(Path(file_path).stem) != vendor) or not file_path.is_file():
+                continue
+            data = yaml.safe_load(file_path.read_text()) or {}
+            models |= {d.get("model_number", "") for d in data.get("models", data.get("device_types", [])) or []}
+        return sorted(models - {""})
+    from .models import VendorModel
+
+    qs = VendorModel.objects.all()
+    if vendor:
+        qs = qs.filter(vendor__slug=vendor)
+    return sorted(set(qs.values_list("model_number", flat=True)))
+
+
+def render_script(shell: str, prog: str = "manage.py") -> str:
+    commands = command_options()
+    return {"bash": _bash, "zsh": _zsh, "fish": _fish}[shell](commands, prog)
+
+
+def _bash(commands: dict[str, list[str]], prog: str) -> str:
+    cases = "\n".join(f'        {name}) opts="{" ".join(opts)}" ;;' for name, opts in commands.items())
+    return f"""# bash completion for {prog} (library commands)
+_spark_values() {{
+    {prog} completion --values "$@" 2>/dev/null
+}}
+
+_spark_complete() {{
+    local cur prev cmd opts vendor i
+    cur="${{COMP_WORDS[COMP_CWORD]}}"
+    prev="${{COMP_WORDS[COMP_CWORD-1]}}"
+    cmd="${{COMP_WORDS[1]}}"
+    if [[ $COMP_CWORD -eq 1 ]]; then
+        COMPREPLY=($(compgen -W "{" ".join(commands)}" -- "$cur"))
+        return
+    fi
+    for ((i = 2; i < COMP_CWORD; i++)); do
+        [[ "${{COMP_WORDS[i]}}" == "--vendor" ]] && vendor="${{COMP_WORDS[i+1]}}"
+    done
+    case "$prev" in
+        {"|".join(VENDOR_OPTIONS)})
+            COMPREPLY=($(compgen -W "$(_spark_values vendors)" -- "$cur")); return ;;
+        {"|".join(MODEL_OPTIONS)})
+            COMPREPLY=($(compgen -W "$(_spark_values models ${{vendor:+--vendor "$vendor"}})" -- "$cur")); return ;;
+        {"|".join(PATH_OPTIONS)})
+            COMPREPLY=($(compgen -f -- "$cur")); return ;;
+    esac
+    case "$cmd" in
+{cases}
+        *) opts="" ;;
+    esac
+    COMPREPLY=($(compgen -W "$opts" -- "$cur"))
+}}
+complete -o default -F _spark_complete {prog}
+"""
+
+
+def _zsh(commands: dict[str, list[str]], prog: str) -> str:
+    cases = "\n".join(f"        {name}) opts=({' '.join(opts)}) ;;" for name, opts in commands.items())
+    return f"""#compdef {prog}
+# zsh completion for {prog} (library commands)
+_spark_complete() {{
+    local -a opts
+    local vendor
+    if (( CURRENT == 2 )); then
+        compadd -- {" ".join(commands)}
+        return
+    fi
+    (( ${{words[(I)--vendor]}} )) && vendor=${{words[${{words[(I)--vendor]}}+1]}}
+    case "${{words[CURRENT-1]}}" in
+        {"|".join(VENDOR_OPTIONS)})
+            compadd -- ${{(f)"$({prog} completion --values vendors 2>/dev/null)"}}; return ;;
+        {"|".join(MODEL_OPTIONS)})
+            compadd -- ${{(f)"$({prog} completion --values models ${{vendor:+--vendor $vendor}} 2>/dev/null)"}}
+            return ;;
+        {"|".join(PATH_OPTIONS)})
+            _files; return ;;
+    esac
+    case "${{words[2]}}" in
+{cases}
+    esac
+    compadd -- $opts
+}}
+compdef _spark_complete {prog}
+"""
+
+
+def _fish(commands: dict[str, list[str]], prog: str) -> str:
+    lines = [
+        f"# fish completion for {prog} (library commands)",
+        f"complete -c {prog} -f",
+        f"complete -c {prog} -n __fish_use_subcommand -a '{' '.join(commands)}'",
+    ]
+    for name, opts in commands.items():
+        for opt in opts:
+            if not opt.startswith("--"):
+                continue
+            flag = f"-l {opt[2:]}"
+            if opt in VENDOR_OPTIONS:
+                flag += f" -x -a '({prog} completion --values vendors 2>/dev/null)'"
+            elif opt in MODEL_OPTIONS:
+                flag += f" -x -a '({prog} completion --values models 2>/dev/null)'"
+            elif opt in PATH_OPTIONS:
+                flag += " -r -F"
+            lines.append(f"complete -c {prog} -n '__fish_seen_subcommand_from {name}' {flag}")
+    return "\n".join(lines) + "\n"
@@ -4,6 +4,7 @@ from __future__ import annotations
 
 import logging
 
+from . import status_log, undo
 from .unpublished import unpublished_changes_summary
 
 logger = logging.getLogger(__name__)
@@ -24,3 +25,18 @@ def unpublished_changes(request) -> dict:
         logger.exception("unpublished_changes_summary failed")
         return {}
     return {"unpublished_changes": summary.as_template_context()}
+
+
+def undo_stack(request) -> dict:
+    """Labels of the session's next undo / redo, for the header buttons."""
+    if not getattr(request.user, "is_authenticated", False) or not hasattr(request, "session"):
+        return {}
+    undo_label, redo_label = undo.peek(request)
+    return {"undo_label": undo_label, "redo_label": redo_label}
+
+
+def status_history(request) -> dict:
+    """The session's status log, for the panel ``L`` toggles."""
+    if not getattr(request.user, "is_authenticated", False):
+        return {}
+    return {"status_log": status_log.entries(request)}
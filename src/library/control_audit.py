@@ -0,0 +1,104 @@
+"""Consistency audit for device control configuration.
+
+Legacy entries predate the typed ``ControlConfig.controls`` list and often
+disagree with themselves — ``controllable`` left at its default while
+controls exist, LoRaWAN downlink ports configured for devices with no
+control channel, Modbus controls writing to registers the register map
+doesn't define, and so on. ``audit_control_configs`` reports those
+mismatches; issues with an unambiguous remedy carry a ``fix`` callable
+that applies it and records device history.
+"""
+
+from __future__ import annotations
+
+from collections.abc import Callable
+from dataclasses import dataclass
+
+from .control_editor import register_addresses, unknown_registers
+from .history import record_history, snapshot_device
+from .models import ControlConfig, DeviceHistory, ModbusConfig, VendorModel
+
+
+@dataclass
+class ControlIssue:
+    device: VendorModel
+    code: str
+    message: str
+    fix_description: str = ""
+    fix: Callable[[object], None] | None = None  # called with the acting user (or None)
+
+
+def _set_controllable(device: VendorModel, value: bool) -> Callable[[object], None]:
+    def apply(user):
+        previous = snapshot_device(device)
+        ControlConfig.objects.update_or_create(device_type=device, defaults={"controllable": value})
+        # ``device`` may hold the control config it was audited with; history needs the new one.
+        record_history(VendorModel.objects.get(pk=device.pk), DeviceHistory.Action.UPDATED, user, previous)
+
+    return apply
+
+
+def audit_device(device: VendorModel) -> list[ControlIssue]:
+    """Return the control-configuration issues for one device."""
+    issues: list[ControlIssue] = []
+
+    try:
+        ctrl = device.control_config
+        controllable, controls = ctrl.controllable, ctrl.controls or []
+    except VendorModel.control_config.RelatedObjectDoesNotExist:
+        controllable, controls = False, []
+
+    if controls and not controllable:
+        issues.append(ControlIssue(
+            device, "controls-not-controllable",
+            f"{len(controls)} control(s) defined but controllable=false",
+            fix_description="set controllable=true",
+            fix=_set_controllable(device, True),
+        ))
+    elif controllable and not controls:
+        issues.append(ControlIssue(
+            device, "controllable-without-controls",
+            "controllable=true but no control channels are defined",
+            fix_description="set controllable=false",
+            fix=_set_controllable(device, False),
+        ))
+
+    if device.technology == VendorModel.Technology.LORAWAN:
+        try:
+            downlink_port = device.lorawan_config.downlink_f_port
+        except VendorModel.lorawan_config.RelatedObjectDoesNotExist:
+            downlink_port = None
+        if downlink_port is not None and not controls:
+            issues.append(ControlIssue(
+                device, "downlink-without-controls",
+                f"LoRaWAN downlink f_port {downlink_port} is set but no control channels are defined",
+            ))
+
+    addresses = register_addresses(device) if controls else None
+    if addresses is not None:
+        for problem in unknown_registers(controls, addresses):
+            issues.append(ControlIssue(device, "control-register-missing", problem))
+
+    if device.technology == VendorModel.Technology.MODBUS and not controllable:
+        try:
+            function = device.modbus_config.function
+        except VendorModel.modbus_config.RelatedObjectDoesNotExist:
+            function = ""
+        if function == ModbusConfig.Function.HOLDING and device.modbus_config.register_definitions.exists():
+            issues.append(ControlIssue(
+                device, "writable-registers-not-controllable",
+                "Holding (writable) registers are defined but controllable=false",
+            ))
+
+    return issues
+
+
+def audit_control_configs(queryset=None) -> list[ControlIssue]:
+    """Audit every device in ``queryset`` (default: all devices)."""
+    if queryset is None:
+        queryset = VendorModel.objects.all()
+    queryset = queryset.select_related("vendor", "control_config", "lorawan_config", "modbus_config")
+    issues: list[ControlIssue] = []
+    for device in queryset.order_by("vendor__name", "model_number"):
+        issues.extend(audit_device(device))
+    return issues
@@ -0,0 +1,189 @@
+"""The structured control editor — relays, setpoints and commands as typed rows.
+
+``ControlConfig.controls`` is a list of widget descriptors whose ``wire``
+blocks follow the device's technology (see
+``docs/architecture/controls-architecture.md``). Most controllable devices
+need three shapes, and the control editor shows each as a row of fields:
+
+- a *relay* is a ``toggle`` with ``on`` and ``off`` states,
+- a *setpoint* is a ``slider``; its ``min``/``max`` are the safety limits
+  and its wire carries the value template,
+- a *command* is a ``button`` writing one fixed value.
+
+``control_rows`` turns entries into rows and ``build_control`` turns a row
+back into an entry on top of the one it came from, so keys the row doesn't
+edit (a toggle's third state, a wire's ``scale``) survive the round trip.
+Entries of any other shape (enums, relays whose states address different
+registers) are returned as kept; the editor lists them and saves them
+untouched.
+
+``unknown_registers`` is the Modbus check shared with the YAML editor and
+the control audit: every ``register`` a wire names must be the address of
+one of the device's register definitions.
+"""
+
+from __future__ import annotations
+
+from .models import RegisterDefinition, VendorModel
+
+KINDS = {"relay": "toggle", "setpoint": "slider", "command": "button"}
+KIND_CHOICES = [("relay", "Relay (on/off)"), ("setpoint", "Setpoint"), ("command", "Command")]
+
+# Per technology: the wire key addressing the command, the one carrying a
+# fixed payload and the one carrying a setpoint's value template.
+WIRE_KEYS = {
+    VendorModel.Technology.MODBUS: ("register", "value", "value_template"),
+    VendorModel.Technology.LORAWAN: ("f_port", "payload_hex", "payload_template"),
+}
+MODBUS_FUNCTIONS = ["write_single_register", "write_single_coil", "write_multiple_registers"]
+
+# Keys each widget owns; changing a row's kind drops the old widget's.
+WIDGET_KEYS = {
+    "toggle": {"states"},
+    "enum": {"options"},
+    "slider": {"min", "max", "step", "unit", "default", "wire"},
+    "button": {"wire"},
+}
+LIMIT_KEYS = ("min", "max", "step", "default")
+
+
+def wire_keys(technology: str) -> tuple[str, str, str]:
+    """``(target, payload, template)`` wire keys; LoRaWAN's for anything but Modbus."""
+    return WIRE_KEYS.get(technology, WIRE_KEYS[VendorModel.Technology.LORAWAN])
+
+
+def _wire(value) -> dict:
+    return value if isinstance(value, dict) else {}
+
+
+def _row(entry: dict, technology: str) -> dict | None:
+    """The editor row for ``entry``, or None when it has another shape."""
+    target_key, payload_key, template_key = wire_keys(technology)
+    kind = {widget: kind for kind, widget in KINDS.items()}.get(entry.get("widget"))
+    row = {
+        "id": entry.get("id", ""),
+        "label": entry.get("label", ""),
+        "kind": kind,
+        "feedback_metric": entry.get("feedback_metric", ""),
+        "group": entry.get("group", ""),
+        "requires_confirmation": bool(entry.get("requires_confirmation")),
+        "rest": entry,
+    }
+    if kind == "relay":
+        states = entry.get("states")
+        if not isinstance(states, dict):
+            return None
+        on, off = (_wire((states.get(name) or {}).get("wire")) for name in ("on", "off"))
+        if not on or not off or on.get(target_key) != off.get(target_key):
+            return None
+        wire = on
+        row.update(on=on.get(payload_key), off=off.get(payload_key))
+    elif kind == "setpoint":
+        wire = _wire(entry.get("wire"))
+        row.update({key: entry.get(key) for key in LIMIT_KEYS}, unit=entry.get("unit", ""))
+        row["value"] = wire.get(template_key)
+    elif kind == "command":
+        wire = _wire(entry.get("wire"))
+        row["value"] = wire.get(payload_key)
+    else:
+        return None
+    row.update(target=wire.get(target_key), function=wire.get("function", ""))
+    return row
+
+
+def control_rows(controls, technology: str) -> tuple[list[dict], list[dict]]:
+    """``(rows, kept)``: editor rows, and the entries the editor can't show."""
+    rows, kept = [], []
+    for entry in controls or []:
+        row = _row(entry, technology) if isinstance(entry, dict) else None
+        if row is None:
+            kept.append(entry)
+        else:
+            rows.append(row)
+    return rows, kept
+
+
+def _number(value):
+    """A float that is a whole number as an int, so YAML reads ``30`` not ``30.0``."""
+    if isinstance(value, float) and value.is_integer():
+        return int(value)
+    return value
+
+
+def _put(target: dict, key: str, value) -> None:
+    """Set ``key``, or drop it when the value is empty (the schema's default)."""
+    if value in (None, "", False):
+        target.pop(key, None)
+    else:
+        target[key] = value
+
+
+def build_control(row: dict, technology: str) -> dict:
+    """The ``controls`` entry for an editor row, over the entry it was read from."""
+    target_key, payload_key, template_key = wire_keys(technology)
+    widget = KINDS[row["kind"]]
+    entry = dict(row.get("rest") or {})
+    if entry.get("widget") != widget:
+        for key in set().union(*WIDGET_KEYS.values()) - WIDGET_KEYS[widget]:
+            entry.pop(key, None)
+    entry.update(id=row["id"], label=row["label"] or row["id"], widget=widget)
+    for key in ("feedback_metric", "group", "requires_confirmation"):
+        _put(entry, key, row.get(key))
+
+    def wire(old, key, value):
+        other = template_key if key == payload_key else payload_key
+        new = {k: v for k, v in _wire(old).items() if k != other}
+        new.update({target_key: row["target"], key: value})
+        _put(new, "function", row.get("function"))
+        return new
+
+    if widget == "toggle":
+        states = dict(entry.get("states") or {})
+        for name in ("on", "off"):
+            state = dict(states.get(name) or {})
+            state["wire"] = wire(state.get("wire"), payload_key, row[name])
+            states[name] = state
+        entry["states"] = states
+    elif widget == "slider":
+        for key in LIMIT_KEYS:
+            _put(entry, key, _number(row.get(key)))
+        _put(entry, "unit", row.get("unit"))
+        entry["wire"] = wire(entry.get("wire"), template_key, row["value"])
+    else:
+        entry["wire"] = wire(entry.get("wire"), payload_key, row["value"])
+    return entry
+
+
+def register_addresses(device: VendorModel) -> set[int] | None:
+    """Addresses of a Modbus device's register definitions; None for other technologies."""
+    if device.technology != VendorModel.Technology.MODBUS:
+        return None
+    return set(
+        RegisterDefinition.objects.filter(modbus_config__device_type=device).values_list("address", flat=True)
+    )
+
+
+def wire_registers(entry: dict) -> list:
+    """Every ``register`` named by the entry's wires (its own, its states', its options')."""
+    wires = [entry.get("wire")]
+    states = entry.get("states")
+    if isinstance(states, dict):
+        wires += [state.get("wire") for state in states.values() if isinstance(state, dict)]
+    options = entry.get("options")
+    if isinstance(options, list):
+        wires += [option.get("wire") for option in options if isinstance(option, dict)]
+    return [wire["register"] for wire in wires if isinstance(wire, dict) and "register" in wire]
+
+
+def unknown_registers(controls, addresses: set[int]) -> list[str]:
+    """Problems for wires writing to registers that aren't in ``addresses``."""
+    problems = []
+    for entry in controls or []:
+        if not isinstance(entry, dict):
+            continue
+        for register in dict.fromkeys(wire_registers(entry)):
+            if isinstance(register, bool) or not isinstance(register, int):
+                problems.append(f"{entry.get('id')}: register {register!r} is not an address.")
+            elif register not in addresses:
+                problems.append(f"{entry.get('id')}: register {register} is not in the register map.")
+    return problems
@@ -0,0 +1,45 @@
+"""Paste a device definition copied as YAML into the library.
+
+The device YAML view copies an entry the way the export writes it
+(``models:`` with one item), which is also what ends up in chats and
+issues. ``parse_pasted`` accepts that, a bare list item or a bare device
+mapping; ``paste_device`` files it like a draft — vendor and model number
+from the YAML unless given, lint errors refuse it.
+"""
+
+from __future__ import annotations
+
+import yaml
+
+from .drafts import DraftError, file_device
+from .models import Vendor, VendorModel
+
+
+def parse_pasted(text: str) -> dict:
+    try:
+        data = yaml.safe_load(text or "")
+    except yaml.YAMLError as e:
+        raise DraftError(f"Invalid YAML: {e}") from e
+    if isinstance(data, dict) and isinstance(data.get("models"), list):
+        data = data["models"]
+    if isinstance(data, list):
+        if len(data) != 1:
+            raise DraftError(f"Paste one device, not {len(data)}")
+        data = data[0]
+    if not isinstance(data, dict) or not data:
+        raise DraftError("Expected a device: a YAML mapping with model_number and technology_config")
+    return data
+
+
+def paste_device(text: str, vendor: Vendor | None = None, model_number: str = "") -> tuple[VendorModel, list]:
+    """Create a device from pasted YAML; returns ``(device, lint findings)``."""
+    device = parse_pasted(text)
+    if vendor is None:
+        name = str(device.get("vendor_name") or "").strip()
+        vendor = Vendor.objects.filter(name__iexact=name).first() if name else None
+        if vendor is None:
+            raise DraftError(f"Unknown vendor {name!r} — choose one" if name else "No vendor_name — choose a vendor")
+    model_number = model_number or str(device.get("model_number") or "").strip()
+    if not model_number:
+        raise DraftError("No model_number — enter one")
+    return file_device(device, vendor, model_number)
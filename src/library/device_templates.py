@@ -0,0 +1,150 @@
+"""Device templates — starting points for new device definitions.
+
+A new device rarely starts from nothing: most are another three-phase
+power meter, another class A LoRaWAN sensor, another wM-Bus water meter.
+A template is such a device, kept as one YAML file with a name, a
+description and the device document minus its identity::
+
+    name: Three-phase Modbus power meter
+    description: Float32 input registers, the common DIN-rail layout.
+    device:
+      device_type: power_meter
+      technology_config:
+        technology: modbus
+        register_definitions: [...]
+
+The built-in templates ship in ``builtin_templates/`` next to this module;
+repositories add their own under ``templates/`` at the repository root
+(``settings.DEVICE_TEMPLATES_DIR``), where a file with a built-in's key
+replaces it. Templates are picked when starting a draft in the UI and
+with ``manage.py create_device --template``.
+"""
+
+from __future__ import annotations
+
+import copy
+from dataclasses import dataclass, field
+from pathlib import Path
+
+import yaml
+from django.conf import settings
+from django.db import transaction
+from django.utils.text import slugify
+
+from .importers import _import_device
+from .models import Vendor, VendorModel
+from .snippets import KEY_PATTERN
+from .strict import unknown_keys
+
+TEMPLATE_SUFFIX = ".yaml"
+TEMPLATE_KEYS = {"name", "description", "device"}
+BUILTIN_DIR = Path(__file__).resolve().parent / "builtin_templates"
+# Identity belongs to the new device, never to the template.
+IDENTITY_KEYS = ("vendor_name", "model_number", "name", "device_type_key")
+
+
+class TemplateError(ValueError):
+    pass
+
+
+@dataclass(frozen=True)
+class DeviceTemplate:
+    key: str  # file stem, e.g. "modbus-power-meter-3ph"
+    name: str
+    description: str = ""
+    device: dict = field(default_factory=dict)
+    builtin: bool = False
+
+    @property
+    def technology(self) -> str:
+        return self.device["technology_config"]["technology"]
+
+
+def templates_dir() -> Path:
+    return Path(settings.DEVICE_TEMPLATES_DIR)
+
+
+def parse_template(key: str, data, builtin: bool = False) -> DeviceTemplate:
+    """Validate a template document; ``TemplateError`` lists every problem."""
+    if not isinstance(data, dict):
+        raise TemplateError(f"{key}: a template is a mapping with name and device")
+    problems = [f"Unknown key {k!r}" for k in data if k not in TEMPLATE_KEYS]
+    if not data.get("name"):
+        problems.append("name is required")
+    device = data.get("device")
+    if not isinstance(device, dict):
+        problems.append("device must be a mapping")
+        device = {}
+    problems += [f"device.{k} is set when the template is used" for k in IDENTITY_KEYS if k in device]
+    technologies = VendorModel.Technology.values
+    if (device.get("technology_config") or {}).get("technology") not in technologies:
+        problems.append(f"device.technology_config.technology must be one of {', '.join(technologies)}")
+    problems += [f"device.{path}: {message}" for path, message in unknown_keys(device)]
+    if problems:
+        raise TemplateError(f"{key}: " + "; ".join(problems))
+    return DeviceTemplate(
+        key=key,
+        name=str(data["name"]),
+        description=str(data.get("description") or ""),
+        device=device,
+        builtin=builtin,
+    )
+
+
+def _load(path: Path, builtin: bool) -> DeviceTemplate:
+    try:
+        data = yaml.safe_load(path.read_text(encoding="utf-8"))
+    except yaml.YAMLError as e:
+        raise TemplateError(f"{path.name}: {e}") from e
+    return parse_template(path.stem, data, builtin=builtin)
+
+
+def _paths(directory: Path) -> list[Path]:
+    if not directory.is_dir():
+        return []
+    return [p for p in sorted(directory.glob(f"*{TEMPLATE_SUFFIX}")) if KEY_PATTERN.match(p.stem)]
+
+
+def load_templates(directory: str | Path | None = None) -> list[DeviceTemplate]:
+    """Built-in and repository templates, by name; repository files win on key."""
+    templates = {path.stem: _load(path, builtin=True) for path in _paths(BUILTIN_DIR)}
+    templates.update({path.stem: _load(path, builtin=False) for path in _paths(Path(directory or templates_dir()))})
+    return sorted(templates.values(), key=lambda t: t.name.lower())
+
+
+def load_template(key: str, directory: str | Path | None = None) -> DeviceTemplate:
+    templates = {t.key: t for t in load_templates(directory)}
+    if key not in templates:
+        raise TemplateError(f"No device template {key!r} (available: {', '.join(sorted(templates)) or 'none'})")
+    return templates[key]
+
+
+def template_device(template: DeviceTemplate, vendor_name: str, model_number: str, name: str = "") -> dict:
+    """A new device document from ``template`` (a copy; the template is untouched)."""
+    return {
+        "vendor_name": vendor_name,
+        "model_number": model_number,
+        "name": name or model_number,
+        **copy.deepcopy(template.device),
+    }
+
+
+def template_content(template: DeviceTemplate) -> str:
+    """``template``'s device as draft YAML (no vendor or model number yet)."""
+    device = {"name": "", **copy.deepcopy(template.device)}
+    return yaml.safe_dump(device, sort_keys=False, allow_unicode=True)
+
+
+def create_from_template(template: DeviceTemplate, vendor_name: str, model_number: str, name: str = ""):
+    """Create a library device from ``template`` (vendor created if missing).
+
+    Unlike filing a draft this doesn't refuse lint errors: a template is
+    incomplete on purpose (a wM-Bus meter has no manufacturer code yet),
+    and lint lists what is left to fill in.
+    """
+    device = template_device(template, vendor_name, model_number, name)
+    with transaction.atomic():
+        vendor, _ = Vendor.objects.get_or_create(slug=slugify(vendor_name), defaults={"name": vendor_name})
+        if VendorModel.objects.filter(vendor=vendor, model_number__iexact=model_number).exists():
+            raise TemplateError(f"{vendor.name} {model_number} already exists")
+        return _import_device(vendor, device, {"devices_created": 0, "devices_updated": 0})
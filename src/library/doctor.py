@@ -0,0 +1,291 @@
+"""Health checks for the server environment and an exported YAML tree.
+
+Each check returns a ``Check`` with a status and, when something is wrong,
+a concrete suggestion for fixing it. ``check_environment`` covers what the
+management commands need from the server (database, migrations);
+``check_github`` the GitHub token ``export_yaml --submit`` and release
+downloads use — set, or from a logged-in gh CLI, and accepted by GitHub;
+``check_tree`` covers the repository layout a vendor keeps under version
+control: manifest present and parseable, ``schema_version`` understood by
+this importer, and — via ``check_manifest``, shared with ``verify_manifest``
+— vendor files referenced by the manifest actually existing, no device
+files lying around that the manifest forgot about, and each entry's
+``technologies`` list matching its file. ``check_yaml_content`` flags
+files that aren't UTF-8, contain control characters, or hold values YAML
+would misread (see ``yaml_content``). ``rebuild_manifest`` repairs a
+missing or partial manifest from the vendor files on disk.
+"""
+
+from __future__ import annotations
+
+import os
+import shutil
+from dataclasses import dataclass
+from pathlib import Path
+
+import yaml
+
+from devicelib import propose_manifest
+from devicelib.release import github_token
+
+from .models import DEFAULT_SCHEMA_VERSION
+from .safe_write import TreeWriter
+from .yaml_content import check_file
+from .yaml_format import canonical_manifest, device_technologies, dump_yaml
+
+# Oldest manifest layout the importer still migrates on the fly (v2/v3
+# processor mappings are translated in ``importers``).
+MIN_SCHEMA_VERSION = 2
+
+OK, WARNING, ERROR = "ok", "warning", "error"
+
+# Names of the ``check_environment`` checks — a failure there is the server's
+# problem, not the tree's.
+ENVIRONMENT_CHECKS = ("database", "migrations")
+
+
+@dataclass
+class Check:
+    name: str
+    status: str  # "ok" | "warning" | "error"
+    message: str
+    fix: str = ""
+
+    def as_dict(self) -> dict:
+        return {"name": self.name, "status": self.status, "message": self.message, "fix": self.fix}
+
+
+def check_environment() -> list[Check]:
+    """Database reachable and all migrations applied."""
+    from django.db import DatabaseError, connection
+    from django.db.migrations.executor import MigrationExecutor
+
+    try:
+        connection.ensure_connection()
+    except DatabaseError as e:
+        return [Check("database", ERROR, f"Cannot connect to the database: {e}",
+                      "Check the DATABASE_* settings / that the database container is running")]
+
+    checks = [Check("database", OK, f"Connected ({connection.vendor})")]
+    executor = MigrationExecutor(connection)
+    pending = executor.migration_plan(executor.loader.graph.leaf_nodes())
+    if pending:
+        checks.append(Check("migrations", ERROR, f"{len(pending)} unapplied migration(s)",
+                            "Run: python manage.py migrate"))
+    else:
+        checks.append(Check("migrations", OK, "All migrations applied"))
+    return checks
+
+
+def check_github(github=None) -> list[Check]:
+    """A GitHub token is available and GitHub accepts it."""
+    from .submit import GitHub, SubmitError, Unauthorized
+
+    token = github_token()
+    if token is None:
+        if shutil.which("gh"):
+            return [Check("github", ERROR, "No GitHub token: the gh CLI is installed but not logged in",
+                          "Run: gh auth login (or set GITHUB_TOKEN)")]
+        return [Check("github", ERROR, "No GitHub token: GITHUB_TOKEN and GH_TOKEN are unset and gh is not installed",
+                      "Set GITHUB_TOKEN to a token that can open pull requests, or install gh and run: gh auth login")]
+    source = next((name for name in ("GITHUB_TOKEN", "GH_TOKEN") if os.environ.get(name)), "gh auth token")
+    try:
+        login = (github or GitHub(token)).get("/user")["login"]
+    except Unauthorized as e:
+        return [Check("github", ERROR, f"GitHub refused the token from {source}: {e}",
+                      "Replace the token (or run: gh auth refresh)")]
+    except SubmitError as e:
+        return [Check("github", WARNING, f"Could not verify the token from {source}: {e}",
+                      "Check the network connection to api.github.com")]
+    return [Check("github", OK, f"Authenticated as {login} (token from {source})")]
+
+
+def check_tree(devices_path: str | Path, manifest_path: str | Path) -> list[Check]:
+    """Validate layout, schema version and manifest ↔ file consistency."""
+    devices_path = Path(devices_path)
+    manifest_path = Path(manifest_path)
+    checks: list[Check] = []
+
+    if not devices_path.is_dir():
+        checks.append(Check("layout", ERROR, f"Devices directory not found: {devices_path}",
+                            "Pass --path pointing at the devices/ directory, or run export_yaml to create one"))
+    if not manifest_path.is_file():
+        checks.append(Check("layout", ERROR, f"Manifest not found: {manifest_path}",
+                            "Pass --manifest, or rebuild it from the device files: "
+                            "python manage.py verify_manifest --rebuild"))
+    if checks:
+        return checks
+    checks.append(Check("layout", OK, f"{manifest_path} and {devices_path}/"))
+    checks.append(check_yaml_content([manifest_path, *sorted(devices_path.glob("*.y*ml"))], devices_path))
+
+    try:
+        manifest = yaml.safe_load(manifest_path.read_text()) or {}
+    except (yaml.YAMLError, UnicodeDecodeError) as e:
+        checks.append(Check("manifest", ERROR, f"Manifest is not valid YAML: {e}",
+                            "Fix the syntax error, then run fmt_yaml to normalise the file"))
+        return checks
+    if not isinstance(manifest, dict) or not isinstance(manifest.get("vendors", []), list):
+        checks.append(Check("manifest", ERROR, "Manifest must be a mapping with a 'vendors' list",
+                            "Regenerate it with export_yaml"))
+        return checks
+
+    checks.append(_check_schema_version(manifest.get("schema_version")))
+
+    checks.extend(check_manifest(devices_path, manifest))
+    return checks
+
+
+def _load_devices(path: Path) -> list[dict] | None:
+    try:
+        data = yaml.safe_load(path.read_text()) or {}
+    except (yaml.YAMLError, UnicodeDecodeError):
+        return None
+    if not isinstance(data, dict):
+        return None
+    return data.get("models" if "models" in data else "device_types") or []
+
+
+def _listed(items: list[str], limit: int = 5) -> str:
+    more = f" (+{len(items) - limit} more)" if len(items) > limit else ""
+    return "; ".join(items[:limit]) + more
+
+
+def check_yaml_content(paths: list[Path], devices_path: Path) -> Check:
+    """Encoding, control characters and misread scalars across ``paths``."""
+    broken, misread = [], []
+    for path in paths:
+        for problem in check_file(path):
+            (misread if problem.fixable else broken).append(f"{path.name} {problem}")
+    if broken:
+        return Check("yaml-content", ERROR, f"Unreadable YAML content: {_listed(broken)}",
+                     "Re-save the file(s) as UTF-8 and remove the control characters at the listed positions")
+    if misread:
+        return Check("yaml-content", WARNING, f"Values YAML reads as another type: {_listed(misread)}",
+                     f"Run: python manage.py fmt_yaml --path {devices_path} (quotes them)")
+    return Check("yaml-content", OK, "UTF-8, no control characters or misread values")
+
+
+def check_manifest(devices_path: str | Path, manifest: dict) -> list[Check]:
+    """Cross-reference manifest entries with the vendor files on disk:
+    every entry's file exists, every file has an entry, and each entry's
+    ``technologies`` list matches what its file actually uses."""
+    devices_path = Path(devices_path)
+    checks: list[Check] = []
+    referenced = set()
+    dangling = []
+    mismatched, undeclared = [], []
+    for entry in manifest.get("vendors") or []:
+        file_name = (entry or {}).get("file")
+        if not file_name:
+            dangling.append(f"{(entry or {}).get('name', '?')} (no 'file' key)")
+            continue
+        referenced.add((devices_path / file_name).resolve())
+        if not (devices_path / file_name).is_file():
+            dangling.append(file_name)
+            continue
+        devices = _load_devices(devices_path / file_name)
+        if devices is None:
+            continue
+        actual = device_technologies(devices)
+        declared = entry.get("technologies")
+        if declared is None:
+            undeclared.append(file_name)
+        elif sorted(declared) != actual:
+            mismatched.append(f"{file_name} (declared {', '.join(sorted(declared)) or 'none'}; "
+                              f"used {', '.join(actual) or 'none'})")
+    if dangling:
+        checks.append(Check("dangling-entries", ERROR,
+                            f"Manifest lists missing vendor file(s): {', '.join(dangling)}",
+                            "Restore the file(s), or drop the entries: python manage.py verify_manifest --rebuild"))
+    else:
+        checks.append(Check("dangling-entries", OK, "Every manifest entry points to an existing file"))
+
+    orphans = sorted(
+        p.name for p in devices_path.iterdir()
+        if p.suffix in (".yaml", ".yml") and p.resolve() not in referenced
+    ) if devices_path.is_dir() else []
+    if orphans:
+        checks.append(Check("orphaned-files", WARNING,
+                            f"Device file(s) not referenced by the manifest: {', '.join(orphans)}",
+                            "Delete the file(s), or add entries for them: python manage.py verify_manifest --rebuild"))
+    else:
+        checks.append(Check("orphaned-files", OK, "No unreferenced device files"))
+
+    if mismatched:
+        checks.append(Check("technologies", ERROR,
+                            f"Manifest technologies don't match the device files: {'; '.join(mismatched)}",
+                            "Run: python manage.py verify_manifest --fix"))
+    elif undeclared:
+        checks.append(Check("technologies", WARNING,
+                            f"Manifest entries without a technologies list: {', '.join(undeclared)}",
+                            "Run: python manage.py verify_manifest --fix"))
+    else:
+        checks.append(Check("technologies", OK, "Technologies lists match the device files"))
+    return checks
+
+
+def fix_technologies(devices_path: str | Path, manifest_path: str | Path, writer: TreeWriter | None = None) -> list[str]:
+    """Rewrite each entry's ``technologies`` from its file; returns the
+    files whose entry changed."""
+    devices_path = Path(devices_path)
+    writer = writer or TreeWriter()
+    manifest = yaml.safe_load(Path(manifest_path).read_text()) or {}
+    changed = []
+    for entry in manifest.get("vendors") or []:
+        path = devices_path / (entry or {}).get("file", "")
+        devices = _load_devices(path) if path.is_file() else None
+        if devices is None:
+            continue
+        actual = device_technologies(devices)
+        if entry.get("technologies") != actual:
+            entry["technologies"] = actual
+            changed.append(entry["file"])
+    if changed:
+        writer.write(manifest_path, dump_yaml(canonical_manifest(manifest)))
+    return changed
+
+
+def _check_schema_version(value) -> Check:
+    if value is None:
+        return Check("schema-version", WARNING, "Manifest has no schema_version",
+                     f"Add 'schema_version: {DEFAULT_SCHEMA_VERSION}' (or re-export with export_yaml)")
+    try:
+        version = int(value)
+    except (TypeError, ValueError):
+        return Check("schema-version", ERROR, f"schema_version {value!r} is not an integer",
+                     f"Set 'schema_version: {DEFAULT_SCHEMA_VERSION}'")
+    if version > DEFAULT_SCHEMA_VERSION:
+        return Check("schema-version", ERROR,
+                     f"schema_version {version} is newer than this server supports ({DEFAULT_SCHEMA_VERSION})",
+                     "Upgrade the device library server before importing this tree")
+    if version < MIN_SCHEMA_VERSION:
+        return Check("schema-version", ERROR,
+                     f"schema_version {version} is older than the oldest supported ({MIN_SCHEMA_VERSION})",
+                     "Re-export the tree from a newer server")
+    if version < DEFAULT_SCHEMA_VERSION:
+        return Check("schema-version", WARNING,
+                     f"schema_version {version} is migrated on import (current is {DEFAULT_SCHEMA_VERSION})",
+                     "Upgrade the tree with migrate_schema")
+    return Check("schema-version", OK, f"schema_version {version}")
+
+
+def write_manifest(manifest_path: str | Path, manifest: dict, writer: TreeWriter | None = None) -> bool:
+    """Write ``manifest`` (e.g. a recovered one) canonically; False when unchanged."""
+    writer = writer or TreeWriter()
+    return writer.write(manifest_path, dump_yaml(canonical_manifest(manifest)))
+
+
+def rebuild_manifest(
+    devices_path: str | Path, manifest_path: str | Path, writer: TreeWriter | None = None,
+) -> list[str]:
+    """Bring the manifest in line with the vendor files on disk — drop
+    entries for missing files, add the unlisted ones, create it when
+    missing (see ``devicelib.propose_manifest``). Returns what changed."""
+    manifest_path = Path(manifest_path)
+    manifest = None
+    if manifest_path.is_file():
+        manifest = yaml.safe_load(manifest_path.read_text()) or {}
+    proposal, notes = propose_manifest(Path(devices_path), manifest, DEFAULT_SCHEMA_VERSION)
+    if notes:
+        write_manifest(manifest_path, proposal, writer)
+    return notes
@@ -0,0 +1,85 @@
+"""Scratchpad drafts: device definitions kept outside the library.
+
+A ``DeviceDraft`` holds a device document as free YAML text — vendor and
+model number may be placeholders, fields may be missing. Nothing reads
+drafts except the drafts UI; filing one turns it into a regular
+``VendorModel`` through the YAML importer and deletes the draft.
+"""
+
+from __future__ import annotations
+
+import yaml
+from django.db import transaction
+
+from .importers import _import_device
+from .lint import LintDevice, lint_devices
+from .models import DeviceDraft, Vendor, VendorModel
+from .scaffold import skeleton_device
+
+
+class DraftError(ValueError):
+    pass
+
+
+def initial_content(technology: str = "modbus") -> str:
+    """Starting YAML for a new draft (the ``create_device`` skeleton)."""
+    device = skeleton_device("", "", technology, "")
+    for key in ("vendor_name", "model_number"):
+        device.pop(key)
+    return yaml.safe_dump(device, sort_keys=False, allow_unicode=True)
+
+
+def parse_draft(content: str) -> dict:
+    try:
+        device = yaml.safe_load(content or "") or {}
+    except yaml.YAMLError as e:
+        raise DraftError(f"Invalid YAML: {e}") from e
+    if not isinstance(device, dict):
+        raise DraftError("The draft must be a YAML mapping (one device)")
+    return device
+
+
+def set_technology_config(draft: DeviceDraft, config: dict) -> None:
+    """Replace the draft's ``technology_config`` and save it.
+
+    The content is dumped again, so comments in the draft's YAML are lost.
+    """
+    device = parse_draft(draft.content)
+    device["technology_config"] = config
+    draft.content = yaml.safe_dump(device, sort_keys=False, allow_unicode=True)
+    draft.save()
+
+
+def file_draft(draft: DeviceDraft, vendor: Vendor, model_number: str) -> tuple[VendorModel, list]:
+    """Create the device under ``vendor`` and delete the draft.
+
+    Returns ``(device, lint findings)``; lint errors abort filing.
+    """
+    return file_device(parse_draft(draft.content), vendor, model_number, on_created=draft.delete)
+
+
+def file_device(device: dict, vendor: Vendor, model_number: str, on_created=None) -> tuple[VendorModel, list]:
+    """Create a new library device from a device document (``file_draft``
+    and the pasted-YAML import). ``on_created`` runs in the same
+    transaction."""
+    device["vendor_name"] = vendor.name
+    device["model_number"] = model_number
+    device.setdefault("name", model_number)
+    if not (device.get("technology_config") or {}).get("technology"):
+        raise DraftError("technology_config.technology is required before filing")
+    if VendorModel.objects.filter(vendor=vendor, model_number__iexact=model_number).exists():
+        raise DraftError(f"{vendor.name} {model_number} already exists")
+
+    findings = lint_devices([LintDevice(data=device)])
+    errors = [f for f in findings if f.severity == "error"]
+    if errors:
+        raise DraftError("; ".join(f"{f.path or f.rule}: {f.message}" for f in errors))
+
+    try:
+        with transaction.atomic():
+            vm = _import_device(vendor, device, {"devices_created": 0, "devices_updated": 0})
+            if on_created:
+                on_created()
+    except (KeyError, TypeError, ValueError) as e:
+        raise DraftError(f"Cannot import device: {e}") from e
+    return vm, findings
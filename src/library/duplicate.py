@@ -0,0 +1,74 @@
+"""Copy a device's configs and registers onto another device, or file a
+device under another vendor.
+
+Most new devices in a family differ from an existing one by a handful of
+registers, so the editor starts them from a copy: ``VendorModelDuplicateView``
+saves the new device from the source's form fields (model number left
+blank) and ``copy_configs`` then gives it its own copy of every config the
+source has — technology, processor, control and alarm — and of every
+Modbus register. Nothing is shared: editing the copy never touches the
+original.
+
+``move_to_vendor`` re-files a device under another vendor in the database
+(``move_device`` for the command line; ``vendor_files.move_device`` does
+the same in an exported tree).
+"""
+
+from __future__ import annotations
+
+import uuid
+
+from django.db import transaction
+
+from .history import record_history, snapshot_device
+from .models import DeviceHistory, Vendor, VendorModel
+from .undo import CONFIGS
+
+
+class DuplicateConflict(Exception):
+    """The target vendor already has a device with that model number."""
+
+
+def copy_configs(source: VendorModel, target: VendorModel) -> int:
+    """Deep-copy ``source``'s configs and registers onto ``target`` (which
+    has none yet); returns the number of registers copied."""
+    registers = 0
+    for name in CONFIGS:
+        config = getattr(source, name, None)
+        if config is None:
+            continue
+        rows = list(config.register_definitions.all()) if name == "modbus_config" else []
+        _insert_copy(config, device_type=target)
+        for register in rows:
+            _insert_copy(register, modbus_config=config)
+        registers += len(rows)
+    return registers
+
+
+@transaction.atomic
+def move_to_vendor(device: VendorModel, vendor: Vendor, copy: bool = False, user=None) -> VendorModel:
+    """File ``device`` under ``vendor`` — or, with ``copy``, a deep copy of
+    it, leaving the original where it is. Returns the moved or new device."""
+    if vendor.device_types.filter(model_number__iexact=device.model_number).exists():
+        raise DuplicateConflict(f"{vendor.name} already has {device.model_number}")
+    if copy:
+        source = VendorModel.objects.get(pk=device.pk)
+        new = VendorModel.objects.get(pk=device.pk)
+        _insert_copy(new, vendor=vendor, key=uuid.uuid4())
+        copy_configs(source, new)
+        record_history(new, DeviceHistory.Action.CREATED, user)
+        return new
+    old_snapshot = snapshot_device(device)
+    device.vendor = vendor
+    device.save()
+    record_history(device, DeviceHistory.Action.UPDATED, user, old_snapshot)
+    return device
+
+
+def _insert_copy(obj, **fields) -> None:
+    """Save ``obj`` as a new row (fresh primary key) with ``fields`` set."""
+    obj.pk = None
+    obj._state.adding = True
+    for name, value in fields.items():
+        setattr(obj, name, value)
+    obj.save()
@@ -0,0 +1,354 @@
+"""Offline preview of an export before it goes to the library repository.
+
+``export_yaml --preview`` renders everything a submission would contain
+without writing to the target tree or talking to GitHub: the exact files
+of the commit, the branch name, commit message, pull request title and
+body, unified diffs against the tree on disk, and the validation report
+(``doctor``'s tree checks plus lint, schema included) run on the exported files. The result
+goes to a local directory for a final review, or to a pager.
+
+The same validation gates a real export: ``blocking`` lists the tree check
+errors and the lint errors in the files the export would change, by file
+and device, and ``export_yaml`` refuses to write while there are any
+(unless overridden with ``--force``). Errors in files the export leaves
+as they are don't block it.
+
+Directory layout::
+
+    preview.json        summary: branch, title, changed files, error counts
+    pr.md               pull request title (first heading) and body
+    commit-message.txt
+    changes.diff        git-style unified diff against the current tree
+    changes.html        the same diff colourized, one scrollable panel per file
+    validation.json     tree checks and lint findings
+    tree/               the exported tree exactly as it would be committed
+"""
+
+from __future__ import annotations
+
+import difflib
+import hashlib
+import html
+import json
+import shutil
+import tempfile
+from dataclasses import dataclass, field
+from datetime import UTC, datetime
+from pathlib import Path
+
+from .doctor import Check, check_tree
+from .exporters import export_to_yaml
+from .lint import Finding, LintConfig, devices_from_yaml, lint_devices, tree_schema_findings
+from .safe_write import TreeWriter
+
+MANIFEST_NAME = "manifest.yaml"
+SUMMARY_NAME = "preview.json"
+BRANCH_PREFIX = "library/export-"
+MAX_LISTED_FINDINGS = 20
+
+# Diff line colours, as git shows them: ANSI for the pager, CSS for changes.html.
+ANSI = {"header": "\033[1m", "+": "\033[32m", "-": "\033[31m", "@": "\033[36m"}
+ANSI_RESET = "\033[0m"
+CSS = {"header": "font-weight:bold", "+": "color:#1a7f37;background:#e6ffec", "-": "color:#cf222e;background:#ffebe9",
+       "@": "color:#0969da"}
+
+
+class PreviewError(Exception):
+    pass
+
+
+@dataclass
+class FileChange:
+    path: str  # relative to the repository root, e.g. "devices/acme.yaml"
+    old: str | None  # None for a new file
+    new: str | None  # None for a deleted file
+
+    @property
+    def diff(self) -> str:
+        return "".join(difflib.unified_diff(
+            (self.old or "").splitlines(keepends=True),
+            (self.new or "").splitlines(keepends=True),
+            fromfile=f"a/{self.path}" if self.old is not None else "/dev/null",
+            tofile=f"b/{self.path}" if self.new is not None else "/dev/null",
+        ))
+
+    @property
+    def status(self) -> str:
+        if self.old is None:
+            return "new"
+        if self.new is None:
+            return "deleted"
+        added, removed = self.line_counts
+        return f"+{added} −{removed}"
+
+    @property
+    def line_counts(self) -> tuple[int, int]:
+        """``(added, removed)`` lines."""
+        lines = self.diff.splitlines()
+        added = sum(1 for line in lines if line.startswith("+") and not line.startswith("+++"))
+        removed = sum(1 for line in lines if line.startswith("-") and not line.startswith("---"))
+        return added, removed
+
+
+@dataclass
+class ExportPreview:
+    branch: str
+    title: str
+    changes: list[FileChange]
+    checks: list[Check]
+    findings: list[Finding]
+    stats: dict
+    tree: dict[str, str] = field(default_factory=dict)  # every exported file, relative path → content
+    devices_dir: str = "devices"
+
+    @property
+    def errors(self) -> int:
+        return sum(c.status == "error" for c in self.checks) + sum(f.severity == "error" for f in self.findings)
+
+    @property
+    def warnings(self) -> int:
+        return sum(c.status == "warning" for c in self.checks) + sum(f.severity == "warning" for f in self.findings)
+
+    def file_of(self, finding: Finding) -> str:
+        """The exported file ``finding`` is about, relative to the repository root."""
+        if finding.source == MANIFEST_NAME:
+            return MANIFEST_NAME
+        return f"{self.devices_dir}/{finding.source}"
+
+    @property
+    def blocking(self) -> dict[str, dict[str, list[Finding]]]:
+        """Lint errors in the changed files, by file and device."""
+        changed = {change.path for change in self.changes}
+        grouped: dict[str, dict[str, list[Finding]]] = {}
+        for finding in self.findings:
+            if finding.severity == "error" and self.file_of(finding) in changed:
+                grouped.setdefault(self.file_of(finding), {}).setdefault(finding.device, []).append(finding)
+        return grouped
+
+    @property
+    def failed_checks(self) -> list[Check]:
+        return [c for c in self.checks if c.status == "error"]
+
+    @property
+    def blocking_count(self) -> int:
+        return len(self.failed_checks) + sum(len(f) for devices in self.blocking.values() for f in devices.values())
+
+    @property
+    def blocking_report(self) -> str:
+        """``blocking`` and the failed tree checks as an indented list."""
+        lines = [f"tree check {c.name}: {c.message}" for c in self.failed_checks]
+        for path, devices in self.blocking.items():
+            lines.append(path)
+            for device, findings in devices.items():
+                lines.append(f"  {device}")
+                for finding in findings:
+                    where = f" [{finding.path}]" if finding.path else ""
+                    lines.append(f"    {finding.rule}{where}: {finding.message}")
+        return "\n".join(lines)
+
+    @property
+    def commit_message(self) -> str:
+        lines = [self.title, ""]
+        lines += [f"- {change.path}" for change in self.changes]
+        return "\n".join(lines) + "\n"
+
+    @property
+    def validation_summary(self) -> str:
+        lines = []
+        problems = [c for c in self.checks if c.status != "ok"]
+        if problems:
+            lines += [f"- **{c.status}** {c.name}: {c.message}" for c in problems]
+        else:
+            lines.append(f"- Tree checks: {len(self.checks)} passed")
+        errors = sum(f.severity == "error" for f in self.findings)
+        lines.append(f"- Lint: {errors} error(s), {len(self.findings) - errors} warning(s)")
+        for finding in self.findings[:MAX_LISTED_FINDINGS]:
+            where = f" `{finding.path}`" if finding.path else ""
+            lines.append(f"  - {finding.severity} [{finding.rule}] {finding.device}{where}: {finding.message}")
+        if len(self.findings) > MAX_LISTED_FINDINGS:
+            lines.append(f"  - … and {len(self.findings) - MAX_LISTED_FINDINGS} more (see validation.json)")
+        return "\n".join(lines)
+
+    @property
+    def body(self) -> str:
+        changes = []
+        for change in self.changes:
+            changes.append(f"- `{change.path}` ({change.status})")
+        return "\n".join([
+            "## Changes",
+            "",
+            *(changes or ["No changes against the current tree."]),
+            "",
+            f"{self.stats['devices_exported']} devices from {self.stats['vendors_exported']} vendors exported.",
+            "",
+            "## Validation",
+            "",
+            self.validation_summary,
+            "",
+        ])
+
+    @property
+    def diff(self) -> str:
+        return "".join(change.diff for change in self.changes)
+
+    def as_dict(self) -> dict:
+        return {
+            "branch": self.branch,
+            "title": self.title,
+            "changed_files": [change.path for change in self.changes],
+            "errors": self.errors,
+            "warnings": self.warnings,
+            "blocking_errors": self.blocking_count,
+            "stats": self.stats,
+        }
+
+    def render(self, color: bool = False) -> str:
+        """Everything in one text, for a pager; ``color`` adds ANSI colours
+        to the diffs (the pager must pass them through, like ``less -R``)."""
+        blocking = []
+        if self.blocking_count:
+            blocking = [f"=== {self.blocking_count} error(s) block this export ===", self.blocking_report, ""]
+        diffs = []
+        for change in self.changes:
+            header = f"=== {change.path} ({change.status}) ==="
+            if color:
+                header, diff = _ansi(header, "header"), colorize_diff(change.diff)
+            else:
+                diff = change.diff
+            diffs += [header, diff]
+        return "\n".join([
+            f"Branch:  {self.branch}",
+            f"Title:   {self.title}",
+            "",
+            *blocking,
+            "=== Commit message ===",
+            self.commit_message,
+            "=== Pull request body ===",
+            self.body,
+            f"=== Diff: {len(self.changes)} file(s) ===",
+            *(diffs or ["(no changes)\n"]),
+        ])
+
+    def render_html(self) -> str:
+        """The diffs as a standalone HTML page: a file list, then each file's
+        colourized diff in its own scrollable panel."""
+        items, panels = [], []
+        for index, change in enumerate(self.changes):
+            path = html.escape(change.path)
+            items.append(f'<li><a href="#file-{index}">{path}</a> ({change.status})</li>')
+            lines = []
+            for line in change.diff.splitlines():
+                style = CSS.get(_kind(line))
+                escaped = html.escape(line) or " "
+                lines.append(f'<span style="{style}">{escaped}</span>' if style else escaped)
+            panels.append(
+                f'<h2 id="file-{index}">{path} <small>({change.status})</small></h2>\n'
+                f'<pre style="max-height:32em;overflow:auto;border:1px solid #d0d7de;padding:.5em">'
+                + "\n".join(lines) + "</pre>"
+            )
+        body = f"<ul>{''.join(items)}</ul>\n" + "\n".join(panels) if self.changes else "<p>No changes.</p>"
+        return (
+            f'<!DOCTYPE html>\n<html><head><meta charset="utf-8"><title>{html.escape(self.title)}</title></head>\n'
+            f'<body style="font-family:sans-serif"><h1>{html.escape(self.title)}</h1>\n{body}\n</body></html>\n'
+        )
+
+    def write(self, directory: str | Path) -> Path:
+        """Write the preview files into ``directory`` (replacing an earlier preview)."""
+        directory = Path(directory)
+        if directory.exists() and any(directory.iterdir()):
+            if not (directory / SUMMARY_NAME).is_file():
+                raise PreviewError(f"{directory} is not empty and holds no earlier preview; pick another directory")
+            shutil.rmtree(directory)
+        directory.mkdir(parents=True, exist_ok=True)
+        (directory / SUMMARY_NAME).write_text(json.dumps(self.as_dict(), indent=2, ensure_ascii=False) + "\n")
+        (directory / "pr.md").write_text(f"# {self.title}\n\n{self.body}", encoding="utf-8")
+        (directory / "commit-message.txt").write_text(self.commit_message, encoding="utf-8")
+        (directory / "changes.diff").write_text(self.diff, encoding="utf-8")
+        (directory / "changes.html").write_text(self.render_html(), encoding="utf-8")
+        (directory / "validation.json").write_text(json.dumps({
+            "checks": [c.as_dict() for c in self.checks],
+            "findings": [f.as_dict() for f in self.findings],
+        }, indent=2, ensure_ascii=False) + "\n", encoding="utf-8")
+        for path, content in self.tree.items():
+            target = directory / "tree" / path
+            target.parent.mkdir(parents=True, exist_ok=True)
+            target.write_text(content, encoding="utf-8")
+        return directory
+
+
+def _kind(line: str) -> str | None:
+    """Which ``ANSI`` / ``CSS`` colour a unified diff line takes."""
+    if line.startswith(("+++", "---")):
+        return "header"
+    return line[:1] if line[:1] in ("+", "-", "@") else None
+
+
+def _ansi(text: str, kind: str) -> str:
+    return f"{ANSI[kind]}{text}{ANSI_RESET}"
+
+
+def colorize_diff(diff: str) -> str:
+    """``diff`` with ANSI colours on its added, removed, hunk and file lines."""
+    lines = []
+    for line in diff.splitlines(keepends=True):
+        text = line.rstrip("\n")
+        kind = _kind(text)
+        lines.append((_ansi(text, kind) if kind else text) + line[len(text):])
+    return "".join(lines)
+
+
+def change_title(changes: list[FileChange], devices_dir: str) -> str:
+    """The commit / pull request title: the vendors whose files change."""
+    vendors = [Path(c.path).stem for c in changes if c.path.startswith(f"{devices_dir}/")]
+    if not vendors:
+        return "Update device library manifest" if changes else "Device library export (no changes)"
+    listed = ", ".join(vendors[:3]) + (f" and {len(vendors) - 3} more" if len(vendors) > 3 else "")
+    return f"Update device library: {listed}"
+
+
+def _branch(tree: dict[str, str], now: datetime) -> str:
+    """``library/export-<UTC time to the second>-<tree hash>``: a retry or
+    a second editor in the same minute doesn't collide with an existing ref."""
+    digest = hashlib.sha1()
+    for path, content in sorted(tree.items()):
+        digest.update(f"{path}\0{content}\0".encode())
+    return f"{BRANCH_PREFIX}{now:%Y%m%d-%H%M%S}-{digest.hexdigest()[:7]}"
+
+
+def build_preview(
+    output_dir: str | Path, lint_config: LintConfig | None = None, now: datetime | None = None,
+) -> ExportPreview:
+    """Export into a scratch directory and compare it with ``output_dir``
+    (the ``devices/`` directory; the manifest lives next to it)."""
+    output_dir = Path(output_dir)
+    root = output_dir.parent
+    now = now or datetime.now(UTC)
+    with tempfile.TemporaryDirectory(prefix="export-preview-") as scratch:
+        staged = Path(scratch) / output_dir.name
+        stats = export_to_yaml(staged, writer=TreeWriter(backup=False))
+        manifest = staged.parent / MANIFEST_NAME
+        checks = check_tree(staged, manifest)
+        findings = tree_schema_findings(staged, manifest, lint_config) + lint_devices(
+            devices_from_yaml(staged, manifest), lint_config,
+        )
+        tree = {
+            str(path.relative_to(staged.parent)): path.read_text(encoding="utf-8")
+            for path in sorted([manifest, *staged.glob("*.yaml")])
+        }
+
+    changes = []
+    for rel, content in tree.items():
+        current = root / rel
+        old = current.read_text(encoding="utf-8") if current.is_file() else None
+        if old != content:
+            changes.append(FileChange(rel, old, content))
+    return ExportPreview(
+        branch=_branch(tree, now),
+        title=change_title(changes, output_dir.name),
+        changes=changes,
+        checks=checks,
+        findings=findings,
+        stats=stats,
+        tree=tree,
+        devices_dir=output_dir.name,
+    )
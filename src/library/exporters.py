@@ -1,22 +1,30 @@
-"""YAML export logic for device definitions."""
+"""YAML / JSON export logic for device definitions."""
 
+import csv
+import json
 import logging
 from pathlib import Path
 
-import yaml
+from django.db.models import Prefetch
 
+from .codec_fetch import codec_source
 from .models import DEFAULT_SCHEMA_VERSION, DeviceType, Vendor, VendorModel
+from .safe_write import TreeWriter
+from .yaml_format import device_technologies, dump_yaml
 
 logger = logging.getLogger(__name__)
 
 
-def export_to_yaml(output_dir: str | Path) -> dict:
+def export_to_yaml(output_dir: str | Path, writer: TreeWriter | None = None) -> dict:
     """Export all device definitions to YAML files.
 
+    Files go through ``writer`` (atomic, with ``.bak`` backups by default);
+    pass ``TreeWriter(dry_run=True)`` to collect the diffs without writing.
+
     Returns a dict with export statistics.
     """
     output_dir = Path(output_dir)
-    output_dir.mkdir(parents=True, exist_ok=True)
+    writer = writer or TreeWriter()
 
     stats = {
         "vendors_exported": 0,
@@ -38,48 +46,122 @@ def export_to_yaml(output_dir: str | Path) -> dict:
         vendor_data = {"models": device_types}
 
         filename = f"{vendor.slug}.yaml"
-        file_path = output_dir / filename
-        with open(file_path, "w") as f:
-            yaml.dump(vendor_data, f, default_flow_style=False, sort_keys=False, allow_unicode=True)
+        writer.write(output_dir / filename, dump_yaml(vendor_data))
 
         manifest_vendors.append({
             "name": vendor.name,
             "file": filename,
+            "technologies": device_technologies(device_types),
         })
 
         stats["vendors_exported"] += 1
         stats["devices_exported"] += len(device_types)
         logger.info("Exported %d devices for %s", len(device_types), vendor.name)
 
+    manifest = _build_manifest(manifest_vendors, stats)
+
+    writer.write(output_dir.parent / "manifest.yaml", dump_yaml(manifest))
+
+    return stats
+
+
+# Bumped only on breaking changes to the bundle layout; additive fields
+# (new device keys, new manifest sections) keep the same version.
+JSON_BUNDLE_FORMAT_VERSION = 1
+
+
+def export_to_json(output_path: str | Path | None = None) -> tuple[dict, dict]:
+    """Export the whole library as a single normalized JSON document.
+
+    The bundle is the manifest with each vendor entry carrying its models
+    inline instead of a ``file`` reference, so consumers that ingest JSON
+    don't have to walk the YAML folder layout. Vendors and models are
+    ordered by slug / model number for stable diffs. When ``output_path``
+    is given the document is written there; it is always returned along
+    with export statistics.
+    """
+    stats = {
+        "vendors_exported": 0,
+        "devices_exported": 0,
+        "device_types_exported": 0,
+    }
+
+    vendors = []
+    models = Prefetch("device_types", queryset=VendorModel.objects.order_by("model_number"))
+    for vendor in Vendor.objects.prefetch_related(models).order_by("slug"):
+        devices = [_export_device(device) for device in vendor.device_types.all()]
+        if not devices:
+            continue
+        vendors.append({
+            "name": vendor.name,
+            "slug": vendor.slug,
+            "technologies": device_technologies(devices),
+            "models": devices,
+        })
+        stats["vendors_exported"] += 1
+        stats["devices_exported"] += len(devices)
+
+    bundle = {"format_version": JSON_BUNDLE_FORMAT_VERSION, **_build_manifest(vendors, stats)}
+
+    if output_path is not None:
+        output_path = Path(output_path)
+        output_path.parent.mkdir(parents=True, exist_ok=True)
+        with open(output_path, "w") as f:
+            json.dump(bundle, f, indent=2, ensure_ascii=False)
+            f.write("\n")
+
+    return bundle, stats
+
+
+REGISTER_CSV_COLUMNS = ("address", "data_type", "scale", "offset", "field_name", "unit", "description")
+
+
+def export_registers_csv(device: VendorModel, stream, delimiter: str = ",") -> int:
+    """Write a Modbus device's register map as CSV to ``stream``.
+
+    Intended for printing / sharing with installers, so columns are the
+    human-facing subset of ``RegisterDefinition`` in address order.
+    Returns the number of rows written (0 for devices without a Modbus
+    config).
+    """
+    writer = csv.writer(stream, delimiter=delimiter)
+    writer.writerow(REGISTER_CSV_COLUMNS)
+    try:
+        registers = device.modbus_config.register_definitions.all()
+    except VendorModel.modbus_config.RelatedObjectDoesNotExist:
+        return 0
+
+    count = 0
+    for reg in registers:
+        writer.writerow([
+            reg.address, reg.data_type, reg.scale, reg.offset, reg.field_name, reg.field_unit, reg.field_description,
+        ])
+        count += 1
+    return count
+
+
+def _build_manifest(vendor_entries: list[dict], stats: dict) -> dict:
+    """Assemble the manifest document around ``vendor_entries``."""
     # Schema-v4: manifest carries both the L1 Metric catalogue (the global
     # vocabulary of canonical metrics) and the L2 device_types section
     # (per-type semantic profile of declared metrics + tier). Older importers
     # without v4 awareness ignore unknown top-level keys.
-    from .models import Metric
+    from .models import LibraryVersion, Metric
 
     device_type_entries = [_export_device_type(dt) for dt in DeviceType.objects.all()]
     stats["device_types_exported"] = len(device_type_entries)
     metric_entries = [_export_metric(m) for m in Metric.objects.all()]
     stats["metrics_exported"] = len(metric_entries)
 
-    # Export manifest
-    from .models import LibraryVersion
-
     current_version = LibraryVersion.objects.filter(is_current=True).first()
-    manifest = {
+    return {
         "version": current_version.version if current_version else "0.0.0",
         "schema_version": current_version.schema_version if current_version else DEFAULT_SCHEMA_VERSION,
         "metrics": metric_entries,
         "device_types": device_type_entries,
-        "vendors": manifest_vendors,
+        "vendors": vendor_entries,
     }
 
-    manifest_path = output_dir.parent / "manifest.yaml"
-    with open(manifest_path, "w") as f:
-        yaml.dump(manifest, f, default_flow_style=False, sort_keys=False, allow_unicode=True)
-
-    return stats
-
 
 def _export_metric(m) -> dict:
     """Export a single L1 Metric row to a YAML-compatible dict.
@@ -123,6 +205,11 @@ def _export_device_type(dt: DeviceType) -> dict:
     }
 
 
+def export_device_yaml(device: VendorModel) -> str:
+    """The device's entry as ``export_to_yaml`` writes it into its vendor file."""
+    return dump_yaml({"models": [_export_device(device)]})
+
+
 def _export_device(device: VendorModel) -> dict:
     """Export a single device type to a YAML-compatible dict."""
     data = {
@@ -145,14 +232,27 @@ def _export_device(device: VendorModel) -> dict:
     if alarm_config:
         data["alarm_config"] = alarm_config
 
+    if device.additional_technologies:
+        data["additional_technology_configs"] = [
+            _export_tech_config(device, technology) for technology in device.additional_technologies
+        ]
+
+    if device.links:
+        data["links"] = [dict(link) for link in device.links]
+
+    if device.suppressed_rules:
+        data["validation"] = {"suppress": list(device.suppressed_rules)}
+
     return data
 
 
-def _export_tech_config(device: VendorModel) -> dict:
-    """Export technology-specific config."""
-    config = {"technology": device.technology}
+def _export_tech_config(device: VendorModel, technology: str | None = None) -> dict:
+    """Export technology-specific config (the primary technology's unless
+    ``technology`` names one of a hybrid device's additional ones)."""
+    technology = technology or device.technology
+    config = {"technology": technology}
 
-    if device.technology == "modbus":
+    if technology == "modbus":
         try:
             modbus = device.modbus_config
             if modbus.function:
@@ -164,22 +264,26 @@ def _export_tech_config(device: VendorModel) -> dict:
 
             registers = []
             for reg in modbus.register_definitions.all():
-                registers.append({
+                entry = {
                     "field": {
                         "name": reg.field_name,
                         "unit": reg.field_unit,
+                        **({"description": reg.field_description} if reg.field_description else {}),
                     },
                     "scale": reg.scale,
                     "offset": reg.offset,
                     "address": reg.address,
                     "data_type": reg.data_type,
-                })
+                }
+                if reg.display:
+                    entry["display"] = reg.display
+                registers.append(entry)
             if registers:
                 config["register_definitions"] = registers
         except VendorModel.modbus_config.RelatedObjectDoesNotExist:
             pass
 
-    elif device.technology == "lorawan":
+    elif technology == "lorawan":
         try:
             lorawan = device.lorawan_config
             if lorawan.device_class:
@@ -202,10 +306,12 @@ def _export_tech_config(device: VendorModel) -> dict:
                     "format": lorawan.codec_format or "ttn_v3",
                     "script": lorawan.payload_codec,
                 }
+                if source := codec_source(lorawan):
+                    config["payload_codec"]["source"] = source
         except VendorModel.lorawan_config.RelatedObjectDoesNotExist:
             pass
 
-    elif device.technology == "wmbus":
+    elif technology == "wmbus":
         try:
             wmbus = device.wmbus_config
             config["manufacturer_code"] = wmbus.manufacturer_code
@@ -270,10 +376,7 @@ def _export_alarm_config(device: VendorModel) -> dict:
     return {}
 
 
-def snapshot_to_schema(snapshot: dict) -> dict:
-    """Convert a DeviceHistory snapshot dict to the YAML device schema format."""
-    technology = snapshot.get("technology", "")
-
+def _snapshot_tech_config(snapshot: dict, technology: str) -> dict:
     tech_config = {"technology": technology}
     if technology == "modbus":
         mc = snapshot.get("modbus_config", {})
@@ -287,11 +390,16 @@ def snapshot_to_schema(snapshot: dict) -> dict:
         if registers:
             tech_config["register_definitions"] = [
                 {
-                    "field": {"name": r["field_name"], "unit": r.get("field_unit", "")},
+                    "field": {
+                        "name": r["field_name"],
+                        "unit": r.get("field_unit", ""),
+                        **({"description": r["field_description"]} if r.get("field_description") else {}),
+                    },
                     "scale": r.get("scale", 1.0),
                     "offset": r.get("offset", 0.0),
                     "address": r["address"],
                     "data_type": r.get("data_type", "uint16"),
+                    **({"display": r["display"]} if r.get("display") else {}),
                 }
                 for r in registers
             ]
@@ -306,6 +414,8 @@ def snapshot_to_schema(snapshot: dict) -> dict:
                 "format": lc.get("codec_format", "ttn_v3"),
                 "script": lc["payload_codec"],
             }
+            if lc.get("codec_source"):
+                tech_config["payload_codec"]["source"] = lc["codec_source"]
     elif technology == "wmbus":
         wc = snapshot.get("wmbus_config", {})
         tech_config["manufacturer_code"] = wc.get("manufacturer_code", "")
@@ -319,7 +429,11 @@ def snapshot_to_schema(snapshot: dict) -> dict:
             tech_config["wmbusmeters_driver"] = wc["wmbusmeters_driver"]
         if wc.get("is_mvt_default"):
             tech_config["is_mvt_default"] = wc["is_mvt_default"]
+    return tech_config
 
+
+def snapshot_to_schema(snapshot: dict) -> dict:
+    """Convert a DeviceHistory snapshot dict to the YAML device schema format."""
     device = {
         "key": snapshot.get("key", ""),
         "vendor_name": snapshot.get("vendor", ""),
@@ -327,8 +441,12 @@ def snapshot_to_schema(snapshot: dict) -> dict:
         "name": snapshot.get("name", ""),
         "device_type": snapshot.get("device_type", ""),
         "description": snapshot.get("description", ""),
-        "technology_config": tech_config,
+        "technology_config": _snapshot_tech_config(snapshot, snapshot.get("technology", "")),
     }
+    if snapshot.get("additional_technologies"):
+        device["additional_technology_configs"] = [
+            _snapshot_tech_config(snapshot, technology) for technology in snapshot["additional_technologies"]
+        ]
 
     ctrl = snapshot.get("control_config", {})
     if ctrl and (ctrl.get("controllable") or ctrl.get("controls")):
@@ -351,6 +469,12 @@ def snapshot_to_schema(snapshot: dict) -> dict:
     if alarm and alarm.get("mappings"):
         device["alarm_config"] = alarm
 
+    if snapshot.get("links"):
+        device["links"] = snapshot["links"]
+
+    if snapshot.get("suppressed_rules"):
+        device["validation"] = {"suppress": snapshot["suppressed_rules"]}
+
     return device
 
 
@@ -0,0 +1,96 @@
+"""Edit a device — or one of its configs — in the user's own editor.
+
+Configs longer than a screen are painful in a form's textarea. The
+``edit_device`` command writes the device in its YAML export shape (or just one section of it, such
+as ``processor_config``) to a temporary file, runs ``$VISUAL`` / ``$EDITOR``
+on it and waits, then parses what was saved. The edited document is
+checked against the device schema — only problems the edit introduced
+count — and re-imported through the YAML importer, which records history.
+As with ``import_yaml``, deleting a whole config section doesn't remove
+the config; empty its entries instead.
+
+A document that doesn't parse or validate raises ``EditError`` carrying the
+edited text, so the caller can reopen the editor on it rather than losing
+the edit.
+"""
+
+from __future__ import annotations
+
+import copy
+import os
+import shlex
+import subprocess
+import tempfile
+from pathlib import Path
+
+import yaml
+from django.db import transaction
+
+from .exporters import _export_device
+from .importers import _import_device
+from .schema import validate_device
+from .yaml_format import canonical_device, dump_yaml
+
+SECTIONS = ("technology_config", "processor_config", "control_config", "alarm_config")
+
+
+class EditError(ValueError):
+    def __init__(self, message: str, text: str = ""):
+        super().__init__(message)
+        self.text = text
+
+
+def editor_command() -> list[str]:
+    return shlex.split(os.environ.get("VISUAL") or os.environ.get("EDITOR") or "vi")
+
+
+def run_editor(text: str, command: list[str] | None = None) -> str:
+    """``text`` as saved by the editor (blocks until it exits)."""
+    fd, name = tempfile.mkstemp(suffix=".yaml", prefix="device-")
+    path = Path(name)
+    try:
+        with os.fdopen(fd, "w") as f:
+            f.write(text)
+        result = subprocess.run([*(command or editor_command()), str(path)])
+        if result.returncode:
+            raise EditError(f"Editor exited with status {result.returncode}; nothing saved", text)
+        return path.read_text()
+    finally:
+        path.unlink(missing_ok=True)
+
+
+def document(device, section: str | None = None) -> str:
+    """The YAML the editor opens: the whole device or one ``section``."""
+    data = canonical_device(_export_device(device))
+    return dump_yaml(data if section is None else data.get(section) or {})
+
+
+def apply_edit(device, text: str, section: str | None = None) -> bool:
+    """Save the edited ``text`` (see ``document``) to ``device``; returns
+    False when nothing changed."""
+    original = _export_device(device)
+    try:
+        edited = yaml.safe_load(text)
+    except yaml.YAMLError as e:
+        raise EditError(f"Not valid YAML: {e}", text) from e
+    if not isinstance(edited, dict):
+        raise EditError("The document must be a mapping", text)
+
+    data = copy.deepcopy(original)
+    if section is None:
+        data = edited
+    else:
+        data[section] = edited
+    for key in ("vendor_name", "model_number"):
+        if data.get(key) != original.get(key):
+            raise EditError(f"{key} can't be changed here (use move_device or the model form)", text)
+    if data == original:
+        return False
+
+    known = {str(e) for e in validate_device(original)}
+    errors = [str(e) for e in validate_device(data) if str(e) not in known]
+    if errors:
+        raise EditError("; ".join(errors), text)
+    with transaction.atomic():
+        _import_device(device.vendor, data, {"devices_created": 0, "devices_updated": 0})
+    return True
@@ -1,13 +1,25 @@
 """Library forms."""
 
+import dataclasses
 import re
 
+import yaml
 from django import forms
+from django.core.exceptions import FieldDoesNotExist
 
+from devicelib import fields as canonical_fields
+from devicelib.technology import DECODERS, TechnologyConfigError, decode_technology_config
+
+from .control_editor import KIND_CHOICES, MODBUS_FUNCTIONS, register_addresses, unknown_registers
+from .drafts import DraftError, parse_draft
+from .js_check import check_script
+from .lint import RULES, LintConfig, approved_units, suggest_unit
 from .models import (
     AlarmConfig,
     APIKey,
     ControlConfig,
+    DeviceDraft,
+    DeviceType,
     LoRaWANConfig,
     Metric,
     ModbusConfig,
@@ -17,6 +29,8 @@ from .models import (
     VendorModel,
     WMBusConfig,
 )
+from .replace import TARGETS, find_hits
+from .yaml_format import dump_yaml
 
 
 class PrettyJSONWidget(forms.Textarea):
@@ -35,6 +49,80 @@ class PrettyJSONWidget(forms.Textarea):
         return super().format_value(value)
 
 
+class YAMLEditorWidget(forms.Textarea):
+    """Config editor: YAML highlighting, live parse errors with line and
+    column, and a Format / reformat-on-save toggle (CodeMirror, loaded as a
+    progressive enhancement over the plain textarea)."""
+
+    template_name = "library/widgets/yaml_editor.html"
+
+    def __init__(self, attrs=None):
+        super().__init__({"rows": 20, "spellcheck": "false", "class": "w-full font-mono text-sm", **(attrs or {})})
+
+
+class YAMLConfigField(forms.JSONField):
+    """A JSON value edited as YAML (JSON text is valid YAML too).
+
+    Parse errors name the line and column, like the editor's live check."""
+
+    widget = YAMLEditorWidget
+
+    def to_python(self, value):
+        if self.disabled:
+            return value
+        if value in self.empty_values:
+            return None
+        if isinstance(value, str):
+            try:
+                return yaml.safe_load(value)
+            except yaml.MarkedYAMLError as e:
+                mark = e.problem_mark or e.context_mark
+                where = f"Line {mark.line + 1}, column {mark.column + 1}: " if mark else ""
+                raise forms.ValidationError(f"{where}{e.problem or e.context}", code="invalid") from e
+            except yaml.YAMLError as e:
+                raise forms.ValidationError(str(e), code="invalid") from e
+        return value
+
+    def bound_data(self, data, initial):
+        return initial if self.disabled else data
+
+    def prepare_value(self, value):
+        if isinstance(value, str) or value is None:
+            return value or ""
+        return dump_yaml(value)
+
+
+ADDRESS_PATTERN = r"0[xX][0-9A-Fa-f]+|[0-9]+"
+
+
+class RegisterAddressField(forms.IntegerField):
+    """A register address typed as decimal or ``0x`` hex (datasheets use
+    both); hex is stored as its decimal value."""
+
+    # A text input: a number input refuses "0x" before the form sees it.
+    widget = forms.TextInput
+    default_error_messages = {"invalid": "Enter a register address: a whole number, or hex like 0x1A."}
+
+    def __init__(self, **kwargs):
+        # Model form fields bring the database column's range; addresses start at 0.
+        kwargs["min_value"] = max(kwargs.get("min_value") or 0, 0)
+        super().__init__(**kwargs)
+
+    def widget_attrs(self, widget):
+        return {
+            **super().widget_attrs(widget),
+            "inputmode": "numeric",
+            "autocomplete": "off",
+            "pattern": ADDRESS_PATTERN,
+            "title": "Decimal, or hex like 0x1A",
+        }
+
+    def to_python(self, value):
+        if isinstance(value, str) and re.fullmatch(r"\s*0[xX][0-9A-Fa-f]+\s*", value):
+            return int(value.strip(), 16)
+        return super().to_python(value)
+
+
 class FieldMappingsWidget(forms.Textarea):
     """Tabular editor for L2-scaffolded ``ProcessorConfig.field_mappings``.
 
@@ -194,7 +282,64 @@ class VendorForm(forms.ModelForm):
         fields = ["name", "slug"]
 
 
+class DeviceLinksWidget(forms.Textarea):
+    """Tabular editor for ``VendorModel.links`` (Table / JSON toggle).
+
+    One row per {kind, url, title} entry with a kind dropdown, plus a raw
+    JSON view. Entries are checked by ``VendorModel.clean``
+    (``link_errors``).
+    """
+
+    template_name = "library/widgets/device_links.html"
+
+    def format_value(self, value):
+        import json
+
+        if isinstance(value, str):
+            try:
+                parsed = json.loads(value) if value else []
+            except json.JSONDecodeError:
+                parsed = []
+        elif value is None:
+            parsed = []
+        else:
+            parsed = value
+        return json.dumps(parsed, indent=2, ensure_ascii=False)
+
+    def get_context(self, name, value, attrs):
+        context = super().get_context(name, value, attrs)
+        context["link_kinds"] = VendorModel.LinkKind.choices
+        return context
+
+
+class LintRulesWidget(forms.CheckboxSelectMultiple):
+    """Checklist of lint rules for ``validation.suppress``: each rule with
+    its severity and description, filterable by typing."""
+
+    template_name = "library/widgets/lint_rules.html"
+
+    def create_option(self, name, value, label, selected, index, subindex=None, attrs=None):
+        option = super().create_option(name, value, label, selected, index, subindex, attrs)
+        option["rule"] = RULES.get(value)
+        return option
+
+
 class VendorModelForm(forms.ModelForm):
+    additional_technologies = forms.MultipleChoiceField(
+        choices=VendorModel.Technology.choices,
+        required=False,
+        widget=forms.CheckboxSelectMultiple,
+        help_text="For hybrid devices: other transports the device also speaks, each with its own configuration.",
+    )
+    suppressed_rules = forms.MultipleChoiceField(
+        choices=(),
+        required=False,
+        label="Suppressed lint rules",
+        widget=LintRulesWidget,
+        help_text="Rules not reported for this device — for findings that are known and accepted. "
+                  "Exported as validation.suppress.",
+    )
+
     class Meta:
         model = VendorModel
         fields = [
@@ -204,11 +349,87 @@ class VendorModelForm(forms.ModelForm):
             "device_type",
             "device_type_fk",
             "technology",
+            "additional_technologies",
             "description",
+            "links",
+            "suppressed_rules",
         ]
         widgets = {
             "description": forms.Textarea(attrs={"rows": 3}),
+            "links": DeviceLinksWidget(),
+        }
+        labels = {
+            "links": "Documentation links",
         }
+        help_texts = {
+            "links": "Datasheet, manual and vendor page URLs. Listed on the model page and checked by "
+                     "lint_library --check-links.",
+        }
+
+    def __init__(self, *args, **kwargs):
+        super().__init__(*args, **kwargs)
+        # Plugin rules register at runtime; keep unknown stored ids selectable.
+        rule_ids = set(RULES) | set(self.instance.suppressed_rules or [])
+        self.fields["suppressed_rules"].choices = [(rule_id, rule_id) for rule_id in sorted(rule_ids)]
+
+    def clean_links(self):
+        return self.cleaned_data.get("links") or []
+
+
+class BulkModelForm(forms.Form):
+    """Models marked in the model list and the action to apply to all of them."""
+
+    ACTIONS = [
+        ("delete", "Delete"),
+        ("device_type", "Set device type"),
+        ("suppress", "Suppress a lint rule"),
+        ("unsuppress", "Stop suppressing a lint rule"),
+    ]
+
+    models = forms.ModelMultipleChoiceField(
+        queryset=VendorModel.objects.select_related("vendor", "device_type_fk"),
+        error_messages={"required": "Mark at least one model."},
+    )
+    action = forms.ChoiceField(choices=ACTIONS)
+    device_type_fk = forms.ModelChoiceField(queryset=DeviceType.objects.all(), required=False, label="Device type")
+    rule = forms.ChoiceField(choices=(), required=False, label="Lint rule")
+
+    def __init__(self, *args, **kwargs):
+        super().__init__(*args, **kwargs)
+        self.fields["rule"].choices = [("", "---------")] + [(rule_id, rule_id) for rule_id in sorted(RULES)]
+
+    def clean(self):
+        cleaned = super().clean()
+        action = cleaned.get("action")
+        if action == "device_type" and not cleaned.get("device_type_fk"):
+            self.add_error("device_type_fk", "Pick the device type to set.")
+        if action in ("suppress", "unsuppress") and not cleaned.get("rule"):
+            self.add_error("rule", "Pick a lint rule.")
+        return cleaned
+
+
+class FindReplaceForm(forms.Form):
+    """What to find and replace, and where (``library.replace``)."""
+
+    find = forms.CharField(max_length=255, strip=False)
+    replace = forms.CharField(max_length=255, required=False, strip=False, label="Replace with")
+    targets = forms.MultipleChoiceField(
+        choices=TARGETS, initial=[key for key, _ in TARGETS], widget=forms.CheckboxSelectMultiple, label="In",
+        error_messages={"required": "Pick at least one of field names, units or descriptions."},
+    )
+    vendor = forms.ModelChoiceField(
+        queryset=Vendor.objects.all(), to_field_name="slug", required=False, empty_label="All vendor files",
+        label="Scope",
+    )
+    match_case = forms.BooleanField(required=False)
+    whole_value = forms.BooleanField(required=False, help_text="Only values that are exactly the text found.")
+
+    def hits(self):
+        data = self.cleaned_data
+        return find_hits(
+            data["find"], data["replace"], data["targets"], vendor=data["vendor"],
+            match_case=data["match_case"], whole_value=data["whole_value"],
+        )
 
 
 class DeviceTypeForm(forms.ModelForm):
@@ -232,6 +453,19 @@ class DeviceTypeForm(forms.ModelForm):
         return val if val is not None else []
 
 
+class ProfileMetricForm(forms.Form):
+    """One entry of a device type's metrics profile."""
+
+    metric = forms.ChoiceField(help_text="L1 metric key from the catalogue.")
+    tier = forms.ChoiceField(choices=DeviceType.Tier.choices, initial=DeviceType.Tier.SECONDARY)
+
+    def __init__(self, *args, **kwargs):
+        super().__init__(*args, **kwargs)
+        self.fields["metric"].choices = [
+            (key, f"{key} — {label}") for key, label in Metric.objects.values_list("key", "label")
+        ]
+
+
 class ModbusConfigForm(forms.ModelForm):
     class Meta:
         model = ModbusConfig
@@ -241,7 +475,102 @@ class ModbusConfigForm(forms.ModelForm):
 class RegisterDefinitionForm(forms.ModelForm):
     class Meta:
         model = RegisterDefinition
-        fields = ["field_name", "field_unit", "address", "data_type", "scale", "offset"]
+        fields = [
+            "field_name",
+            "field_unit",
+            "field_description",
+            "address",
+            "data_type",
+            "scale",
+            "offset",
+            "display_name",
+            "display_precision",
+            "display_icon",
+            "display_category",
+        ]
+        field_classes = {"address": RegisterAddressField}
+        widgets = {
+            "field_name": forms.TextInput(attrs={"list": "canonical-fields", "autocomplete": "off"}),
+            "field_unit": forms.TextInput(attrs={"list": "approved-units", "autocomplete": "off"}),
+        }
+        help_texts = {
+            "field_name": "Prefer a name from the canonical field catalogue (suggested while typing).",
+            "field_unit": "Tab cycles through the approved units that start with what you typed.",
+        }
+
+    def __init__(self, *args, device=None, **kwargs):
+        super().__init__(*args, **kwargs)
+        self.device = device
+        # The unit-whitelist list, including .sparklint.yaml's extra_units.
+        try:
+            config = LintConfig.load()
+        except (FileNotFoundError, ValueError):
+            config = LintConfig()
+        self.approved_units = approved_units(config.options("unit-whitelist"))
+
+    def unit_warnings(self) -> list[str]:
+        """Where the cleaned unit is not an approved one (advisory, like
+        the ``unit-whitelist`` lint rule)."""
+        unit = self.cleaned_data.get("field_unit") or ""
+        if not unit or unit in self.approved_units:
+            return []
+        message = f"Unit {unit!r} is not in the approved unit list"
+        suggestion = suggest_unit(unit, self.approved_units)
+        return [message + (f" (did you mean {suggestion!r}?)" if suggestion else "")]
+
+    def canonical_warnings(self) -> list[str]:
+        """Where the cleaned field name / unit leave the canonical catalogue.
+
+        Advisory only — vendor-specific quantities have no canonical name.
+        """
+        return canonical_fields.validate(self.cleaned_data["field_name"], self.cleaned_data.get("field_unit") or "")
+
+    def clean_address(self):
+        # Refuse to create a second register at an occupied address; existing
+        # duplicates (imports, merges) are resolved on the duplicates page.
+        address = self.cleaned_data["address"]
+        if self.device is None or (self.instance.pk and "address" not in self.changed_data):
+            return address
+        taken = (
+            RegisterDefinition.objects.filter(modbus_config__device_type=self.device, address=address)
+            .exclude(pk=self.instance.pk)
+            .first()
+        )
+        if taken:
+            raise forms.ValidationError(
+                f"Address {address} is already used by {taken.field_name} — edit that register instead."
+            )
+        return address
+
+
+class RegisterCSVImportForm(forms.Form):
+    """Upload for merging a CSV register map into a device."""
+
+    csv_file = forms.FileField(
+        label="CSV file",
+        help_text="Columns: address, data_type, scale, offset, field_name, unit, description (the Export CSV layout)",
+    )
+
+
+class RegisterSnippetForm(forms.Form):
+    """Pick a register snippet and where its block starts."""
+
+    snippet = forms.ChoiceField()
+    base_address = RegisterAddressField(
+        help_text="Address of the block's first register (decimal or 0x hex); the rest keep their relative offsets.",
+    )
+
+    def __init__(self, *args, snippets=(), **kwargs):
+        super().__init__(*args, **kwargs)
+        self.fields["snippet"].choices = [(s.key, f"{s.name} ({len(s.registers)} registers)") for s in snippets]
+
+
+class RegisterPasteForm(forms.Form):
+    """Where copied registers land: their source addresses plus an offset."""
+
+    offset = forms.IntegerField(
+        initial=0, help_text="Added to every copied address; 0 keeps the addresses of the source device.",
+    )
 
 
 class LoRaWANConfigForm(forms.ModelForm):
@@ -255,20 +584,91 @@ class LoRaWANConfigForm(forms.ModelForm):
             "join_eui_default",
             "supports_join",
             "downlink_f_port",
-            "codec_format",
-            "payload_codec",
         ]
         widgets = {
-            "payload_codec": forms.Textarea(attrs={
-                "id": "script-textarea",
-                "rows": 25,
-                "style": "font-family: 'SF Mono', Monaco, 'Cascadia Code', monospace; width: 100%; tab-size: 2;",
-                "spellcheck": "false",
-            }),
             "join_eui_default": forms.TextInput(attrs={"placeholder": "e.g. 04B6480000000000", "style": "font-family: monospace;"}),
         }
 
 
+class CodecFetchForm(forms.Form):
+    """URL of a vendor-published codec to download into the LoRaWAN config."""
+
+    url = forms.URLField(
+        label="Codec URL",
+        max_length=500,
+        assume_scheme="https",
+        help_text="Raw file or GitHub page URL; the download's SHA-256 is recorded with the URL",
+        widget=forms.URLInput(attrs={"placeholder": "https://github.com/vendor/decoders/blob/main/device.js"}),
+    )
+    codec_format = forms.ChoiceField(
+        label="Codec format",
+        choices=LoRaWANConfig.CodecFormat.choices,
+        initial=LoRaWANConfig.CodecFormat.TTN_V3,
+    )
+
+
+class LoRaWANCodecForm(forms.Form):
+    """Decoder and downlink port of a LoRaWAN device (``library.codec_editor``)."""
+
+    DECODER_CHOICES = [
+        (ProcessorConfig.DecoderType.JS_CODEC.value, "JavaScript payload codec"),
+        (ProcessorConfig.DecoderType.LORAWAN_FIELD_MAP.value, "Network server field map (no codec)"),
+    ]
+
+    decoder = forms.ChoiceField(choices=DECODER_CHOICES, widget=forms.RadioSelect)
+    codec_format = forms.ChoiceField(label="Codec format", choices=LoRaWANConfig.CodecFormat.choices)
+    payload_codec = forms.CharField(
+        label="Codec source",
+        required=False,
+        strip=False,
+        widget=forms.Textarea(attrs={
+            "id": "script-textarea",
+            "rows": 25,
+            "style": "font-family: 'SF Mono', Monaco, 'Cascadia Code', monospace; width: 100%; tab-size: 2;",
+            "spellcheck": "false",
+        }),
+    )
+    downlink_f_port = forms.IntegerField(
+        label="Downlink fPort", required=False, min_value=1, max_value=223,
+        help_text="Port commands are sent on; empty if the device takes none.",
+    )
+
+    def clean(self):
+        cleaned = super().clean()
+        codec = cleaned.get("payload_codec", "")
+        if cleaned.get("decoder") == ProcessorConfig.DecoderType.JS_CODEC and not codec.strip():
+            self.add_error("payload_codec", "Paste the codec source, or pick the field map decoder.")
+        return cleaned
+
+    def script_errors(self) -> list[str]:
+        """Syntax problems of the cleaned codec (advisory, like the ``codec-syntax`` lint rule)."""
+        if self.cleaned_data["decoder"] != ProcessorConfig.DecoderType.JS_CODEC:
+            return []
+        return [str(e) for e in check_script(self.cleaned_data["payload_codec"], self.cleaned_data["codec_format"])]
+
+
+class CodecOutputForm(forms.Form):
+    """One expected output of a LoRaWAN decoder; a row left empty is dropped."""
+
+    source = forms.CharField(max_length=255, required=False)
+    f_port = forms.IntegerField(required=False, min_value=1, max_value=223)
+    target = forms.CharField(
+        max_length=255, required=False, widget=forms.TextInput(attrs={"list": "codec-metrics", "autocomplete": "off"}),
+    )
+    scale = forms.FloatField(required=False)
+    offset = forms.FloatField(required=False)
+    rest = forms.JSONField(required=False, widget=forms.HiddenInput)
+
+    def clean(self):
+        cleaned = super().clean()
+        if bool(cleaned.get("source")) != bool(cleaned.get("target")):
+            raise forms.ValidationError("Give both the decoded field and the metric it maps to.")
+        return cleaned
+
+
+CodecOutputFormSet = forms.formset_factory(CodecOutputForm, extra=3)
+
+
 class WMBusConfigForm(forms.ModelForm):
     class Meta:
         model = WMBusConfig
@@ -296,18 +696,162 @@ class WMBusConfigForm(forms.ModelForm):
 
 
 class ControlConfigForm(forms.ModelForm):
+    controls = YAMLConfigField(required=False, help_text=ControlConfig._meta.get_field("controls").help_text)
+
     class Meta:
         model = ControlConfig
         fields = ["controllable", "controls"]
-        widgets = {
-            "controls": PrettyJSONWidget(attrs={"rows": 20, "cols": 80, "style": "font-family: monospace; width: 100%;"}),
-        }
 
     def clean_controls(self):
         val = self.cleaned_data.get("controls")
+        if val and self.instance.device_type_id:
+            addresses = register_addresses(self.instance.device_type)
+            if addresses is not None and (problems := unknown_registers(val, addresses)):
+                raise forms.ValidationError(problems)
         return val if val is not None else []
 
 
+class ControlEditorForm(forms.Form):
+    controllable = forms.BooleanField(
+        required=False, help_text="Spark offers the controls only for a device marked controllable.",
+    )
+
+
+class ControlEntryForm(forms.Form):
+    """One relay, setpoint or command of the control editor
+    (``library.control_editor``); a row left empty is dropped.
+
+    The wire fields follow the device's technology: a Modbus control
+    writes to a register of the device's register map with integer
+    values, a LoRaWAN one is a downlink on an fPort with hex payloads.
+    ``limits`` maps metric keys to their ``(min_value, max_value)`` so a
+    setpoint can't be allowed past what its feedback metric accepts.
+    """
+
+    id = forms.CharField(label="ID", max_length=64, required=False)
+    label = forms.CharField(max_length=255, required=False)
+    kind = forms.ChoiceField(choices=KIND_CHOICES)
+    function = forms.ChoiceField(
+        required=False, choices=[("", f"{MODBUS_FUNCTIONS[0]} (default)")] + [(f, f) for f in MODBUS_FUNCTIONS[1:]],
+    )
+    on = forms.CharField(max_length=255, required=False)
+    off = forms.CharField(max_length=255, required=False)
+    value = forms.CharField(max_length=255, required=False)
+    min = forms.FloatField(label="Lower limit", required=False)
+    max = forms.FloatField(label="Upper limit", required=False)
+    step = forms.FloatField(required=False, min_value=0)
+    default = forms.FloatField(required=False)
+    unit = forms.CharField(max_length=50, required=False)
+    feedback_metric = forms.CharField(
+        max_length=255,
+        required=False,
+        widget=forms.TextInput(attrs={"list": "control-metrics", "autocomplete": "off"}),
+    )
+    group = forms.CharField(max_length=100, required=False)
+    requires_confirmation = forms.BooleanField(required=False)
+    rest = forms.JSONField(required=False, widget=forms.HiddenInput)
+
+    def __init__(self, *args, technology="", addresses=None, limits=None, **kwargs):
+        super().__init__(*args, **kwargs)
+        self.modbus = technology == VendorModel.Technology.MODBUS
+        self.addresses = addresses
+        self.limits = limits or {}
+        if self.modbus:
+            self.fields["target"] = RegisterAddressField(label="Register", required=False)
+        else:
+            self.fields["target"] = forms.IntegerField(label="fPort", required=False, min_value=1, max_value=223)
+            del self.fields["function"]
+        self.order_fields(["id", "label", "kind", "target", "function"])
+
+    def clean(self):
+        cleaned = super().clean()
+        if not cleaned.get("id") and not cleaned.get("label") and cleaned.get("target") is None:
+            return cleaned
+        if not cleaned.get("id"):
+            self.add_error("id", "Give the control an id.")
+        target = cleaned.get("target")
+        if target is None:
+            if "target" not in self.errors:
+                self.add_error("target", "Give the register it writes to." if self.modbus else "Give its fPort.")
+        elif self.addresses is not None and target not in self.addresses:
+            self.add_error("target", f"Register {target} is not in the register map.")
+
+        kind = cleaned.get("kind")
+        if kind == "relay":
+            self._payload("on")
+            self._payload("off")
+        elif kind == "command":
+            self._payload("value")
+        elif kind == "setpoint":
+            self._setpoint(cleaned)
+        return cleaned
+
+    def _payload(self, name):
+        """Parse a fixed payload: an integer for Modbus, hex bytes for LoRaWAN."""
+        text = (self.cleaned_data.get(name) or "").strip()
+        if not text:
+            self.add_error(name, "Give the value to write." if self.modbus else "Give the payload to send.")
+        elif self.modbus:
+            try:
+                self.cleaned_data[name] = int(text, 16) if re.fullmatch(r"0[xX][0-9A-Fa-f]+", text) else int(text)
+            except ValueError:
+                self.add_error(name, "Enter a whole number, or hex like 0xFF00.")
+        else:
+            payload = re.sub(r"\s+", "", text).upper()
+            if not re.fullmatch(r"(?:[0-9A-F]{2})+", payload):
+                self.add_error(name, "Enter the payload as hex bytes, like 01 or FF00.")
+            else:
+                self.cleaned_data[name] = payload
+
+    def _setpoint(self, cleaned):
+        """A value template and safety limits inside what the feedback metric accepts."""
+        if not (cleaned.get("value") or "").strip():
+            if self.modbus:
+                cleaned["value"] = "{value}"
+            else:
+                self.add_error("value", "Give the payload template, like 01{value:02X}.")
+        low, high = cleaned.get("min"), cleaned.get("max")
+        for name, value in (("min", low), ("max", high)):
+            if value is None and name not in self.errors:
+                self.add_error(name, "A setpoint needs both limits.")
+        if low is None or high is None:
+            return
+        if low > high:
+            self.add_error("max", "The upper limit is below the lower one.")
+            return
+        default = cleaned.get("default")
+        if default is not None and not low <= default <= high:
+            self.add_error("default", "The default is outside the limits.")
+        metric = cleaned.get("feedback_metric")
+        metric_low, metric_high = self.limits.get(metric, (None, None))
+        if metric_low is not None and low < metric_low:
+            self.add_error("min", f"{metric} doesn't go below {metric_low:g}.")
+        if metric_high is not None and high > metric_high:
+            self.add_error("max", f"{metric} doesn't go above {metric_high:g}.")
+
+
+class BaseControlEntryFormSet(forms.BaseFormSet):
+    """Control rows; ids must be unique among them and the entries kept as they are."""
+
+    def __init__(self, *args, kept_ids=(), **kwargs):
+        self.kept_ids = set(kept_ids)
+        super().__init__(*args, **kwargs)
+
+    def clean(self):
+        if any(self.errors):
+            return
+        seen = set(self.kept_ids)
+        for form in self.forms:
+            cid = form.cleaned_data.get("id")
+            if cid in seen:
+                raise forms.ValidationError(f"Two controls are called {cid}.")
+            if cid:
+                seen.add(cid)
+
+
+ControlEntryFormSet = forms.formset_factory(ControlEntryForm, formset=BaseControlEntryFormSet, extra=2)
+
+
 class ProcessorConfigForm(forms.ModelForm):
     class Meta:
         model = ProcessorConfig
@@ -398,6 +942,144 @@ class AlarmConfigForm(forms.ModelForm):
         return val
 
 
+class DeviceDraftForm(forms.ModelForm):
+    class Meta:
+        model = DeviceDraft
+        fields = ["title", "content", "notes"]
+        widgets = {
+            "content": forms.Textarea(attrs={"rows": 24, "class": "font-mono text-sm", "spellcheck": "false"}),
+            "notes": forms.Textarea(attrs={"rows": 4}),
+        }
+
+    def clean_content(self):
+        content = self.cleaned_data.get("content", "")
+        try:
+            parse_draft(content)
+        except DraftError as e:
+            raise forms.ValidationError(str(e)) from e
+        return content
+
+
+class DraftFileForm(forms.Form):
+    """Target vendor and final model number for filing a draft."""
+
+    vendor = forms.ModelChoiceField(queryset=Vendor.objects.order_by("name"))
+    model_number = forms.CharField(max_length=255)
+
+
+class DevicePasteForm(forms.Form):
+    """A device copied as YAML, filed as a new model."""
+
+    content = forms.CharField(
+        label="Device YAML",
+        widget=forms.Textarea(attrs={"rows": 20, "class": "font-mono text-xs", "spellcheck": "false"}),
+        help_text="One device, as copied from a device's YAML view: a models: list with one entry or the bare mapping.",
+    )
+    vendor = forms.ModelChoiceField(
+        queryset=Vendor.objects.order_by("name"), required=False,
+        help_text="Leave empty to use vendor_name from the YAML.",
+    )
+    model_number = forms.CharField(
+        max_length=255, required=False, help_text="Leave empty to use model_number from the YAML.",
+    )
+
+
+# Library model whose field of the same name supplies a struct key's
+# choices, help text and length (``function``, ``device_class``, ...).
+TECHNOLOGY_MODELS = {"modbus": ModbusConfig, "lorawan": LoRaWANConfig, "wmbus": WMBusConfig}
+
+# Struct fields kept below a nested key (``payload_codec``), not at the top
+# level of ``technology_config``.
+_NESTED_STRUCT_FIELDS = {"codec_format", "codec_script", "codec_source"}
+_SCALAR_TYPES = {"str": str, "bool": bool, "int | None": int}
+
+
+def technology_keys(technology: str) -> list[dataclasses.Field]:
+    """Struct fields of ``technology`` that are top-level scalar keys."""
+    return [
+        f for f in dataclasses.fields(DECODERS[technology])
+        if f.type in _SCALAR_TYPES and f.name not in _NESTED_STRUCT_FIELDS
+    ]
+
+
+def _technology_formfield(technology: str, key: dataclasses.Field) -> forms.Field:
+    kind = _SCALAR_TYPES[key.type]
+    try:
+        model_field = TECHNOLOGY_MODELS[technology]._meta.get_field(key.name)
+    except FieldDoesNotExist:
+        model_field = None
+    if kind is bool:
+        return forms.BooleanField(required=False, help_text=getattr(model_field, "help_text", ""))
+    if model_field is None:
+        return forms.IntegerField(required=False) if kind is int else forms.CharField(required=False)
+    field = model_field.formfield()
+    field.required = False
+    return field
+
+
+class TechnologyConfigForm(forms.Form):
+    """``technology_config`` of a known technology as typed inputs.
+
+    The fields are generated from the ``devicelib.technology`` struct; keys
+    it doesn't type as a scalar — register definitions, the payload codec,
+    anything unknown — are edited as YAML in ``other``. The merged config
+    is decoded again on save, so a bad value there names its path.
+    """
+
+    other = YAMLConfigField(
+        required=False,
+        label="Other keys",
+        help_text="Keys without a field above (register_definitions, payload_codec, ...) as a YAML mapping.",
+    )
+
+    def __init__(self, config: dict, *args, **kwargs):
+        self.technology = config["technology"]
+        self.keys = technology_keys(self.technology)
+        names = {key.name for key in self.keys}
+        initial = {key.name: config.get(key.name, key.default) for key in self.keys}
+        initial["other"] = {k: v for k, v in config.items() if k != "technology" and k not in names} or None
+        self.original = config
+        kwargs["initial"] = {**initial, **(kwargs.get("initial") or {})}
+        super().__init__(*args, **kwargs)
+        for key in self.keys:
+            field = _technology_formfield(self.technology, key)
+            value = initial[key.name]
+            if isinstance(field, forms.ChoiceField) and value and value not in dict(field.choices):
+                # Keep a value the library doesn't list rather than blanking it on save.
+                field.choices = [*field.choices, (value, f"{value} (not in the library's list)")]
+            self.fields[key.name] = field
+        self.order_fields([key.name for key in self.keys] + ["other"])
+
+    def clean(self):
+        cleaned = super().clean()
+        if self.errors:
+            return cleaned
+        other = cleaned.get("other") or {}
+        if not isinstance(other, dict):
+            self.add_error("other", "Other keys must be a YAML mapping.")
+            return cleaned
+        clash = sorted(set(other) & ({"technology"} | {key.name for key in self.keys}))
+        if clash:
+            self.add_error("other", f"Set {', '.join(clash)} in the fields above, not here.")
+            return cleaned
+
+        config = {"technology": self.technology}
+        for key in self.keys:
+            value = cleaned.get(key.name)
+            if value in ("", None):
+                continue
+            if isinstance(value, bool) and value == key.default and key.name not in self.original:
+                continue
+            config[key.name] = value
+        config.update(other)
+        try:
+            decode_technology_config(config)
+        except TechnologyConfigError as e:
+            raise forms.ValidationError(str(e)) from e
+        self.technology_config = config
+        return cleaned
+
+
 class APIKeyForm(forms.ModelForm):
     class Meta:
         model = APIKey
@@ -420,3 +1102,8 @@ class YAMLImportForm(forms.Form):
         label="Clear existing data",
         help_text="Delete all existing vendors and devices before importing",
     )
+    write_manifest = forms.BooleanField(
+        required=False,
+        label="Write rebuilt manifest",
+        help_text="If the manifest is missing or lists the wrong files, save the one rebuilt from devices/",
+    )
@@ -10,6 +10,7 @@ snapshots and computed diffs against the previous version.
 
 import logging
 
+from .codec_fetch import codec_source
 from .models import DeviceHistory, DeviceTypeHistory, MetricHistory
 
 logger = logging.getLogger(__name__)
@@ -27,6 +28,14 @@ def snapshot_device(device):
         "technology": device.technology,
         "description": device.description,
     }
+    # Only when set, so snapshots of single-technology devices taken before
+    # hybrid devices existed still compare equal.
+    if device.additional_technologies:
+        data["additional_technologies"] = list(device.additional_technologies)
+    if device.links:
+        data["links"] = [dict(link) for link in device.links]
+    if device.suppressed_rules:
+        data["suppressed_rules"] = list(device.suppressed_rules)
 
     # Modbus config
     try:
@@ -40,10 +49,14 @@ def snapshot_device(device):
             {
                 "field_name": r.field_name,
                 "field_unit": r.field_unit,
+                **({"field_description": r.field_description} if r.field_description else {}),
                 "address": r.address,
                 "data_type": r.data_type,
                 "scale": r.scale,
                 "offset": r.offset,
+                # Only when set, so snapshots taken before display hints
+                # existed still compare equal.
+                **({"display": r.display} if r.display else {}),
             }
             for r in mc.register_definitions.all().order_by("address")
         ]
@@ -59,6 +72,8 @@ def snapshot_device(device):
             "codec_format": lc.codec_format,
             "payload_codec": lc.payload_codec,
         }
+        if source := codec_source(lc):
+            data["lorawan_config"]["codec_source"] = source
     except Exception:
         pass
 
@@ -4,8 +4,12 @@ import logging
 from pathlib import Path
 
 import yaml
+from django.db.models import Q
+from django.utils.dateparse import parse_datetime
 from django.utils.text import slugify
 
+from devicelib import propose_manifest
+
 from .history import (
     record_device_type_history,
     record_history,
@@ -15,6 +19,7 @@ from .history import (
     snapshot_metric,
 )
 from .models import (
+    DEFAULT_SCHEMA_VERSION,
     AlarmConfig,
     ControlConfig,
     DeviceHistory,
@@ -30,25 +35,59 @@ from .models import (
     VendorModel,
     WMBusConfig,
 )
+from .schema_migrations.v4 import convert_default_field_mappings, convert_field_mappings
+from .strict import check_known_keys
 
 logger = logging.getLogger(__name__)
 
 
-def import_from_yaml(devices_path: str | Path, manifest_path: str | Path, clear: bool = False) -> dict:
+def import_from_yaml(
+    devices_path: str | Path,
+    manifest_path: str | Path,
+    clear: bool = False,
+    vendors: list[str] | None = None,
+    strict: bool = False,
+    recover: bool = False,
+) -> dict:
     """Import device definitions from YAML files.
 
+    ``vendors`` restricts the import to the listed vendors (matched by
+    slug, name or file stem) — maintainers of one or two vendors don't
+    need the rest of the tree on disk. Manifest entries outside the
+    selection are not read and are reported in ``vendors_skipped``; with
+    ``clear`` only the selected vendors are wiped. The shared metric and
+    device-type catalogues are always imported.
+
+    With ``strict`` a device carrying keys the importer doesn't know (see
+    ``library.strict``) is not imported; the keys are reported in
+    ``errors``.
+
+    With ``recover`` a missing manifest, or one listing missing files or
+    missing some of the vendor files, doesn't stop the import: the tree is
+    imported as ``devicelib.propose_manifest`` reconstructs it from
+    ``devices_path``. What was patched up is listed in ``recovery`` and
+    the reconstructed manifest returned as ``recovered_manifest``, for
+    the caller to offer writing (``doctor.write_manifest``).
+
     Returns a dict with import statistics.
     """
     devices_path = Path(devices_path)
     manifest_path = Path(manifest_path)
 
-    if not manifest_path.exists():
+    if not manifest_path.exists() and not recover:
         raise FileNotFoundError(f"Manifest file not found: {manifest_path}")
     if not devices_path.exists():
         raise FileNotFoundError(f"Devices directory not found: {devices_path}")
 
-    with open(manifest_path) as f:
-        manifest = yaml.safe_load(f)
+    manifest = None
+    if manifest_path.exists():
+        with open(manifest_path) as f:
+            manifest = yaml.safe_load(f) or {}
+    recovery = []
+    if recover:
+        proposal, recovery = propose_manifest(devices_path, manifest, DEFAULT_SCHEMA_VERSION)
+        if recovery:
+            manifest = proposal
 
     stats = {
         "vendors_created": 0,
@@ -57,13 +96,33 @@ def import_from_yaml(devices_path: str | Path, manifest_path: str | Path, clear:
         "devices_updated": 0,
         "device_types_created": 0,
         "device_types_updated": 0,
+        "vendors_skipped": [],
         "errors": [],
     }
+    if recovery:
+        stats["recovery"] = recovery
+        stats["recovered_manifest"] = manifest
+
+    selected = None
+    if vendors is not None:
+        selected = {slugify(v) for v in vendors if v.strip()}
+        known = set()
+        for entry in manifest.get("vendors", []) or []:
+            known |= _vendor_entry_keys(entry)
+        for missing in sorted(selected - known):
+            stats["errors"].append(f"Vendor not in manifest: {missing}")
 
     if clear:
-        VendorModel.objects.all().delete()
-        Vendor.objects.all().delete()
-        logger.info("Cleared existing vendors and devices")
+        if selected is None:
+            VendorModel.objects.all().delete()
+            Vendor.objects.all().delete()
+            logger.info("Cleared existing vendors and devices")
+        else:
+            entries = [e for e in manifest.get("vendors", []) or [] if _vendor_entry_keys(e) & selected]
+            cleared = _entry_vendors(entries)
+            names = sorted(cleared.values_list("name", flat=True))
+            cleared.delete()
+            logger.info("Cleared vendors: %s", ", ".join(names))
 
     # Schema-v4: import the L1 Metric catalogue first (vocabulary of metrics
     # referenced by every L4 mapping). Then L2 device_types, then vendors +
@@ -93,15 +152,23 @@ def import_from_yaml(devices_path: str | Path, manifest_path: str | Path, clear:
         vendor_file = vendor_entry["file"]
         file_path = devices_path / vendor_file
 
+        if selected is not None and not (_vendor_entry_keys(vendor_entry) & selected):
+            stats["vendors_skipped"].append(vendor_name)
+            continue
+
         if not file_path.exists():
             stats["errors"].append(f"File not found: {file_path}")
             logger.warning("File not found: %s", file_path)
             continue
 
-        vendor, created = Vendor.objects.get_or_create(
-            slug=slugify(vendor_name),
-            defaults={"name": vendor_name},
-        )
+        vendor = _vendor_by_alias(vendor_entry)
+        if vendor is not None:
+            created = False
+        else:
+            vendor, created = Vendor.objects.get_or_create(
+                slug=slugify(vendor_name),
+                defaults={"name": vendor_name},
+            )
         if created:
             stats["vendors_created"] += 1
             logger.info("Created vendor: %s", vendor_name)
@@ -118,6 +185,8 @@ def import_from_yaml(devices_path: str | Path, manifest_path: str | Path, clear:
 
         for device_data in data[devices_key]:
             try:
+                if strict:
+                    check_known_keys(device_data)
                 _import_device(vendor, device_data, stats)
             except Exception as e:
                 error_msg = f"Error importing {device_data.get('model_number', '?')} from {vendor_name}: {e}"
@@ -127,6 +196,44 @@ def import_from_yaml(devices_path: str | Path, manifest_path: str | Path, clear:
     return stats
 
 
+def _vendor_entry_keys(entry: dict) -> set[str]:
+    """Slugs a manifest vendor entry answers to (name, file stem, aliases)."""
+    keys = {slugify(entry.get("name", "")), slugify(Path(entry.get("file", "")).stem)}
+    keys |= {slugify(alias) for alias in entry.get("aliases") or []}
+    return keys - {""}
+
+
+def _entry_vendors(entries: list[dict]):
+    """Vendor rows the manifest ``entries`` import into — stored under an
+    entry's name or one of its aliases, by slug or verbatim (as
+    ``move_device`` finds vendors)."""
+    query = Q(pk__in=[])
+    for entry in entries:
+        for name in [entry["name"], *(entry.get("aliases") or [])]:
+            query |= Q(slug=slugify(name)) | Q(name=name)
+    return Vendor.objects.filter(query)
+
+
+def _vendor_by_alias(entry: dict) -> Vendor | None:
+    """Existing vendor still stored under one of the entry's former names.
+
+    ``rename_vendor --alias`` leaves the old name in ``aliases``; importing
+    such a tree renames the vendor row in place, so its models keep their
+    keys instead of being re-created under a new vendor.
+    """
+    slug = slugify(entry["name"])
+    if Vendor.objects.filter(slug=slug).exists():
+        return None
+    for alias in entry.get("aliases") or []:
+        vendor = Vendor.objects.filter(slug=slugify(alias)).first()
+        if vendor is not None:
+            vendor.name, vendor.slug = entry["name"], slug
+            vendor.save(update_fields=["name", "slug", "modified"])
+            logger.info("Renamed vendor %s -> %s", alias, entry["name"])
+            return vendor
+    return None
+
+
 def _import_metric(data: dict) -> Metric:
     """Upsert an L1 Metric row from YAML.
 
@@ -176,62 +283,26 @@ def _import_metric(data: dict) -> Metric:
 def _convert_legacy_field_mappings(base: list[dict], extras: list[dict]) -> list[dict]:
     """Translate schema-v3 ProcessorConfig mappings into the v4 single-slot shape.
 
-    - Concatenates ``base`` + ``extras`` (extras win on same-source collision,
-      preserving the historical effective-list order).
-    - Keeps per-entry ``target`` (now points at an L1 Metric.key).
-    - Drops per-entry ``unit`` (resolved from L1) and ``primary`` (resolved
-      from L2).
-    - Drops per-entry ``transform`` — production data only carried type
-      coercion values like ``to_float`` which the new model doesn't track.
-      Unit conversion is now expressed via ``scale``/``offset``.
-    - Auto-creates missing L1 Metric rows so downstream lookups don't fail.
+    The reshaping is ``schema_migrations.v4.convert_field_mappings`` (extras
+    win on a source collision; ``unit`` / ``primary`` / ``transform`` are
+    dropped). On top of it, missing L1 Metric rows are auto-created so
+    downstream lookups don't fail.
     """
-    if extras:
-        extra_sources = {e.get("source") for e in extras if e.get("source")}
-        merged = [m for m in base if m.get("source") not in extra_sources] + list(extras)
-    else:
-        merged = list(base)
-
-    out: list[dict] = []
-    for entry in merged:
-        target = entry.get("target") or entry.get("metric")
-        if not target:
-            continue
-        new_entry = {"source": entry.get("source"), "target": target}
+    units = {
+        entry.get("target") or entry.get("metric"): entry.get("unit", "") or ""
+        for entry in [*base, *extras]
+    }
+    out = convert_field_mappings(base, extras)
+    for entry in out:
+        target = entry["target"]
         Metric.objects.get_or_create(
             key=target,
             defaults={
                 "label": target.split(":", 1)[-1].replace("_", " ").title(),
-                "unit": entry.get("unit", "") or "",
+                "unit": units.get(target, ""),
                 "data_type": "decimal",
             },
         )
-        if entry.get("scale") not in (None, 1):
-            new_entry["scale"] = entry["scale"]
-        if entry.get("offset") not in (None, 0):
-            new_entry["offset"] = entry["offset"]
-        out.append(new_entry)
-    return out
-
-
-def _convert_legacy_default_field_mappings(legacy: list[dict]) -> list[dict]:
-    """Translate schema-v3 ``default_field_mappings`` entries to v4 ``metrics``.
-
-    Old shape: ``[{source, target, transform?, primary?}]``
-    New shape: ``[{metric, tier}]``
-    Source/transform are dropped (they were decoder concerns that don't
-    belong on the type). ``primary`` flag promotes to tier=primary,
-    otherwise tier=secondary.
-    """
-    seen = set()
-    out: list[dict] = []
-    for entry in legacy or []:
-        target = entry.get("target")
-        if not target or target in seen:
-            continue
-        seen.add(target)
-        tier = "primary" if entry.get("primary") else "secondary"
-        out.append({"metric": target, "tier": tier})
     return out
 
 
@@ -245,9 +316,7 @@ def _import_device_type(data: dict, stats: dict) -> DeviceType:
     if "metrics" in data:
         metrics = data.get("metrics") or []
     else:
-        metrics = _convert_legacy_default_field_mappings(
-            data.get("default_field_mappings") or [],
-        )
+        metrics = convert_default_field_mappings(data.get("default_field_mappings") or [])
     # Auto-create any L1 Metric rows referenced from the profile but missing
     # from the catalogue (tolerant import; operator tidies in admin).
     for entry in metrics:
@@ -307,10 +376,17 @@ def _resolve_device_type_fk(data: dict) -> DeviceType | None:
     return None
 
 
-def _import_device(vendor: Vendor, data: dict, stats: dict) -> VendorModel:
+def import_device(vendor: Vendor, data: dict, user=None) -> VendorModel:
+    """Create or update one device of ``vendor`` from its YAML definition
+    (a ``models`` entry), recording the history entry under ``user``."""
+    return _import_device(vendor, data, {"devices_created": 0, "devices_updated": 0}, user)
+
+
+def _import_device(vendor: Vendor, data: dict, stats: dict, user=None) -> VendorModel:
     """Import a single device type from YAML data."""
     tech_config = data.get("technology_config", {})
     technology = tech_config.get("technology", "")
+    additional_configs = data.get("additional_technology_configs") or []
     device_type_fk = _resolve_device_type_fk(data)
 
     # Check if device already exists so we can capture a pre-update snapshot
@@ -325,7 +401,10 @@ def _import_device(vendor: Vendor, data: dict, stats: dict) -> VendorModel:
             "device_type": data.get("device_type", ""),
             "device_type_fk": device_type_fk,
             "technology": technology,
+            "additional_technologies": [config.get("technology", "") for config in additional_configs],
             "description": data.get("description", "") or "",
+            "links": data.get("links") or [],
+            "suppressed_rules": list((data.get("validation") or {}).get("suppress") or []),
         },
     )
 
@@ -336,13 +415,9 @@ def _import_device(vendor: Vendor, data: dict, stats: dict) -> VendorModel:
         stats["devices_updated"] += 1
         logger.info("Updated device: %s", device)
 
-    # Import technology-specific config
-    if technology == "modbus":
-        _import_modbus_config(device, tech_config)
-    elif technology == "lorawan":
-        _import_lorawan_config(device, tech_config)
-    elif technology == "wmbus":
-        _import_wmbus_config(device, tech_config)
+    # Import technology-specific config, one per technology of a hybrid device
+    for config in (tech_config, *additional_configs):
+        _import_tech_config(device, config)
 
     # Import control config (only if meaningful data present). Older
     # manifests may still ship a ``capabilities`` blob — we accept it
@@ -396,13 +471,23 @@ def _import_device(vendor: Vendor, data: dict, stats: dict) -> VendorModel:
 
     # Record device history
     if created:
-        record_history(device, DeviceHistory.Action.CREATED, user=None)
+        record_history(device, DeviceHistory.Action.CREATED, user=user)
     else:
-        record_history(device, DeviceHistory.Action.UPDATED, user=None, previous_snapshot=old_snapshot)
+        record_history(device, DeviceHistory.Action.UPDATED, user=user, previous_snapshot=old_snapshot)
 
     return device
 
 
+def _import_tech_config(device: VendorModel, tech_config: dict):
+    technology = tech_config.get("technology", "")
+    if technology == "modbus":
+        _import_modbus_config(device, tech_config)
+    elif technology == "lorawan":
+        _import_lorawan_config(device, tech_config)
+    elif technology == "wmbus":
+        _import_wmbus_config(device, tech_config)
+
+
 def _import_modbus_config(device: VendorModel, tech_config: dict):
     """Import Modbus-specific configuration."""
     modbus_config, _ = ModbusConfig.objects.update_or_create(
@@ -419,14 +504,20 @@ def _import_modbus_config(device: VendorModel, tech_config: dict):
 
     for reg_data in tech_config.get("register_definitions", []):
         field = reg_data.get("field", {})
+        display = reg_data.get("display") or {}
         RegisterDefinition.objects.create(
             modbus_config=modbus_config,
             field_name=field.get("name", ""),
             field_unit=field.get("unit", "") or "",
+            field_description=field.get("description", "") or "",
             address=reg_data.get("address", 0),
             data_type=reg_data.get("data_type", "uint16"),
             scale=reg_data.get("scale", 1.0),
             offset=reg_data.get("offset", 0.0),
+            display_name=display.get("name", "") or "",
+            display_precision=display.get("precision"),
+            display_icon=display.get("icon", "") or "",
+            display_category=display.get("category", "") or "",
         )
 
 
@@ -434,9 +525,11 @@ def _import_lorawan_config(device: VendorModel, tech_config: dict):
     """Import LoRaWAN-specific configuration."""
     # payload_codec can be a structured dict {format, script} or a plain string (legacy)
     raw_codec = tech_config.get("payload_codec", "")
+    source = {}
     if isinstance(raw_codec, dict):
         codec_format = raw_codec.get("format", "ttn_v3")
         codec_script = raw_codec.get("script", "")
+        source = raw_codec.get("source") or {}
     else:
         codec_format = "ttn_v3"
         codec_script = str(raw_codec) if raw_codec else ""
@@ -453,6 +546,9 @@ def _import_lorawan_config(device: VendorModel, tech_config: dict):
             "downlink_f_port": tech_config.get("downlink_f_port"),
             "codec_format": codec_format,
             "payload_codec": codec_script,
+            "codec_source_url": source.get("url", ""),
+            "codec_source_sha256": source.get("sha256", ""),
+            "codec_fetched_at": parse_datetime(source["fetched_at"]) if source.get("fetched_at") else None,
         },
     )
 
@@ -0,0 +1,226 @@
+"""Syntax checks for embedded JavaScript payload codecs.
+
+A codec with a syntax error is otherwise only discovered on the gateway,
+when the first uplink fails to decode. No JavaScript engine ships with
+the library, so ``check_script`` is a lexer rather than a full parser: it
+walks the source the way a JavaScript tokenizer does — strings, template
+literals (``${…}`` nesting included), comments, regular expression
+literals — and reports what stops a script from compiling at all:
+
+- an unterminated string, template literal, block comment or regex,
+- a closing bracket that doesn't match the open one, or one too many,
+- brackets still open at the end of the script (a truncated paste),
+
+each with the line and column it starts at. It also checks that the
+script defines the entry point its codec format calls: ``decodeUplink``
+for TTN v3 and ChirpStack v4, ``Decoder`` for TTN v2.
+"""
+
+from __future__ import annotations
+
+import re
+from dataclasses import dataclass
+
+ENTRY_POINTS = {
+    "ttn_v3": "decodeUplink",
+    "chirpstack": "decodeUplink",
+    "ttn_v2": "Decoder",
+}
+
+CLOSING = {")": "(", "]": "[", "}": "{"}
+
+# After these keywords a ``/`` starts a regular expression, not a division.
+_REGEX_KEYWORDS = {
+    "return", "typeof", "case", "do", "else", "in", "instanceof", "new", "delete", "void", "throw", "yield", "await",
+}
+_IDENTIFIER = re.compile(r"[A-Za-z_$][\w$]*")
+_NUMBER = re.compile(r"\d[\w.]*|\.\d[\w]*")
+
+
+@dataclass(frozen=True)
+class ScriptError:
+    line: int  # 0 for problems of the script as a whole
+    column: int
+    message: str
+
+    def __str__(self) -> str:
+        return f"line {self.line}, column {self.column}: {self.message}" if self.line else self.message
+
+
+class _Lexer:
+    def __init__(self, source: str):
+        self.src = source
+        self.pos = 0
+        self.line = 1
+        self.col = 1
+        # Open brackets with their position; "${" with its template literal's.
+        self.stack: list[tuple[str, int, int]] = []
+        self.code: list[str] = []  # the source with strings, comments and regexes blanked
+        self.previous = ""  # last significant token, for the regex/division decision
+
+    def error(self, message: str, line: int | None = None, col: int | None = None) -> ScriptError:
+        return ScriptError(line or self.line, col or self.col, message)
+
+    def advance(self, count: int = 1, keep: bool = False) -> str:
+        text = self.src[self.pos:self.pos + count]
+        for char in text:
+            if char == "\n":
+                self.line += 1
+                self.col = 1
+            else:
+                self.col += 1
+        self.pos += count
+        self.code.append(text if keep else re.sub(r"[^\n]", " ", text))
+        return text
+
+    def run(self) -> ScriptError | None:
+        src = self.src
+        while self.pos < len(src):
+            char = src[self.pos]
+            rest = src[self.pos:self.pos + 2]
+            if char in " \t\r\n":
+                self.advance(keep=True)
+            elif rest == "//":
+                end = src.find("\n", self.pos)
+                self.advance((end if end != -1 else len(src)) - self.pos)
+            elif rest == "/*":
+                line, col = self.line, self.col
+                end = src.find("*/", self.pos + 2)
+                if end == -1:
+                    return self.error("unterminated comment", line, col)
+                self.advance(end + 2 - self.pos)
+            elif char in "'\"":
+                if error := self.string(char):
+                    return error
+                self.previous = "string"
+            elif char == "`":
+                if error := self.template():
+                    return error
+            elif rest in ("++", "--"):
+                # Postfix after an operand (``i++ / 2``): what follows divides.
+                self.previous = "postfix" if not self.regex_allowed() else rest
+                self.advance(2, keep=True)
+            elif char == "/" and self.regex_allowed():
+                if error := self.regex():
+                    return error
+                self.previous = "regex"
+            elif char in "([{":
+                self.stack.append((char, self.line, self.col))
+                self.previous = char
+                self.advance(keep=True)
+            elif char in ")]}":
+                if not self.stack:
+                    return self.error(f"unexpected {char!r}")
+                opened, line, col = self.stack.pop()
+                if opened == "${" and char == "}":
+                    self.advance(keep=True)
+                    if error := self.template(start=(line, col)):
+                        return error
+                    continue
+                expected = CLOSING[char]
+                if opened != expected:
+                    closer = "}" if opened == "${" else {v: k for k, v in CLOSING.items()}[opened]
+                    return self.error(f"expected {closer!r} to close {opened!r} from line {line}, found {char!r}")
+                self.previous = char
+                self.advance(keep=True)
+            elif match := _IDENTIFIER.match(src, self.pos):
+                self.previous = match.group()
+                self.advance(len(match.group()), keep=True)
+            elif match := _NUMBER.match(src, self.pos):
+                self.previous = "number"
+                self.advance(len(match.group()), keep=True)
+            else:
+                self.previous = char
+                self.advance(keep=True)
+        if self.stack:
+            opened, line, col = self.stack[-1]
+            what = "template literal" if opened == "${" else repr(opened)
+            return self.error(f"{what} is never closed", line, col)
+        return None
+
+    def regex_allowed(self) -> bool:
+        prev = self.previous
+        if prev in ("", "(", "[", "{") or prev in _REGEX_KEYWORDS:
+            return True
+        if prev in ("string", "regex", "number", "template", "postfix", ")", "]", "}"):
+            return False
+        return not _IDENTIFIER.fullmatch(prev)
+
+    def string(self, quote: str) -> ScriptError | None:
+        line, col = self.line, self.col
+        self.advance()
+        while self.pos < len(self.src):
+            char = self.src[self.pos]
+            if char == "\\":
+                self.advance(2)
+            elif char == quote:
+                self.advance()
+                return None
+            elif char == "\n":
+                break
+            else:
+                self.advance()
+        return self.error("unterminated string", line, col)
+
+    def template(self, start: tuple[int, int] | None = None) -> ScriptError | None:
+        """Lex a template literal up to its end or its next ``${``;
+        ``start`` is the literal's opening backtick when resuming after one."""
+        line, col = start or (self.line, self.col)
+        if not start:
+            self.advance()
+        while self.pos < len(self.src):
+            char = self.src[self.pos]
+            if char == "\\":
+                self.advance(2)
+            elif char == "`":
+                self.advance()
+                self.previous = "template"
+                return None
+            elif self.src.startswith("${", self.pos):
+                self.stack.append(("${", line, col))
+                self.advance(2)
+                self.previous = "{"
+                return None
+            else:
+                self.advance()
+        return self.error("unterminated template literal", line, col)
+
+    def regex(self) -> ScriptError | None:
+        line, col = self.line, self.col
+        self.advance()
+        in_class = False
+        while self.pos < len(self.src):
+            char = self.src[self.pos]
+            if char == "\\":
+                self.advance(2)
+            elif char == "\n":
+                break
+            elif char == "[":
+                in_class = True
+                self.advance()
+            elif char == "]":
+                in_class = False
+                self.advance()
+            elif char == "/" and not in_class:
+                self.advance()
+                flags = _IDENTIFIER.match(self.src, self.pos)
+                if flags:
+                    self.advance(len(flags.group()))
+                return None
+            else:
+                self.advance()
+        return self.error("unterminated regular expression", line, col)
+
+
+def check_script(script: str, codec_format: str = "") -> list[ScriptError]:
+    """Syntax problems of ``script``, plus a missing entry point for
+    ``codec_format`` (one of ``ENTRY_POINTS``; others skip that check)."""
+    lexer = _Lexer(script)
+    error = lexer.run()
+    if error:
+        return [error]  # what follows an unterminated token is noise
+    entry = ENTRY_POINTS.get(codec_format)
+    code = "".join(lexer.code)
+    if entry and not re.search(rf"\bfunction\s+{entry}\s*\(|\b{entry}\s*=[^=]", code):
+        return [ScriptError(0, 0, f"no {entry} function, which {codec_format} codecs must define")]
+    return []
@@ -0,0 +1,45 @@
+"""Reachability checks for device documentation links.
+
+``check_url`` requests a URL with HEAD and, when the server answers HEAD
+with an error (some CDNs and vendor portals refuse it), once more with
+GET before calling it broken. ``check_urls`` checks many in parallel,
+each URL once. They back the opt-in ``link-unreachable`` lint rule
+(``lint_library --check-links``).
+"""
+
+from __future__ import annotations
+
+import urllib.error
+import urllib.request
+from collections.abc import Iterable
+from concurrent.futures import ThreadPoolExecutor
+
+LINK_TIMEOUT = 10  # seconds
+MAX_WORKERS = 8
+
+
+def check_url(url: str, timeout: float = LINK_TIMEOUT) -> str | None:
+    """``None`` when ``url`` answers without an HTTP error, else why not."""
+    problem = None
+    for method in ("HEAD", "GET"):
+        try:
+            request = urllib.request.Request(url, headers={"User-Agent": "spark-device-library"}, method=method)
+            with urllib.request.urlopen(request, timeout=timeout):
+                return None
+        except urllib.error.HTTPError as e:
+            problem = f"HTTP {e.code}"
+        except urllib.error.URLError as e:
+            return str(e.reason)
+        except (TimeoutError, ValueError) as e:
+            return str(e) or type(e).__name__
+    return problem
+
+
+def check_urls(urls: Iterable[str], timeout: float = LINK_TIMEOUT) -> dict[str, str]:
+    """``{url: problem}`` for the unreachable ones among ``urls``."""
+    unique = sorted(set(urls))
+    if not unique:
+        return {}
+    with ThreadPoolExecutor(max_workers=min(MAX_WORKERS, len(unique))) as pool:
+        results = pool.map(lambda url: check_url(url, timeout), unique)
+        return {url: problem for url, problem in zip(unique, results, strict=True) if problem}
@@ -0,0 +1,868 @@
+"""Library lint — configurable rule checks over device definitions.
+
+Rules operate on the YAML device schema (the shape ``_export_device`` and
+``snapshot_to_schema`` emit), so the same checks run against the live
+database and against an exported ``devices/`` + ``manifest.yaml`` tree a
+vendor keeps in their own repository. Each rule has a stable id, a default
+severity, and optional per-rule options; a ``.sparklint.yaml`` file at the
+repository root enables/disables rules and overrides their options::
+
+    rules:
+      missing-description: false        # disable a rule
+      unit-whitelist:
+        extra_units: [GJ, MJ]           # rule-specific options
+      field-naming:
+        severity: error                 # promote a warning
+      missing-unit: ignore              # shorthand: error | warning | ignore
+      max-devices-per-file:
+        max: 80                         # growth guardrail limits
+      canonical-field: true             # opt-in rules run only when listed
+
+Device-scope rules receive one device dict at a time; library-scope rules
+(duplicate model numbers, …) receive the whole list.
+
+A device can suppress rules for itself — findings that are known and
+accepted — with an inline ``validation`` block; every lint consumer
+(``lint_library``, the add_device wizard, the editor's Problems panel)
+honors it::
+
+    validation:
+      suppress: [missing-description, field-naming]
+
+Organisations add their own rules without forking through plugins —
+Python files (or importable modules) that register rules with the same
+``@rule`` decorator, listed in the config or dropped into a plugin
+directory::
+
+    plugins:
+      - lint_plugins/acme_rules.py
+      - acme.sparklint.rules
+
+Plugins are ordinary Python and run with the linter's permissions, so
+they are never loaded on their own: a config's ``plugins`` only run when
+the caller asks for it (``lint_library --config FILE --load-plugins``),
+and never from the default config (``settings.SPARKLINT_CONFIG``) —
+linting an untrusted vendor tree must not execute it.
+Without them, settings for the plugins' rule ids are kept but unused.
+"""
+
+from __future__ import annotations
+
+import difflib
+import importlib
+import importlib.util
+import re
+from collections.abc import Callable, Iterable
+from dataclasses import asdict, dataclass, field
+from pathlib import Path
+from typing import Any
+
+import yaml
+
+from devicelib import fields, units
+from devicelib.models import Device
+
+from .js_check import check_script
+from .link_check import LINK_TIMEOUT, check_urls
+from .register_map import SUSPICIOUS_GAP, analyze_registers
+from .schema import validate_device, validate_manifest, validate_vendor_file
+from .strict import unknown_keys
+
+SEVERITIES = ("error", "warning")
+IGNORE = "ignore"  # config value that turns a rule off
+
+
+# Units accepted by ``unit-whitelist`` out of the box — the L1 catalogue
+# units (migration 0022) plus the common SI prefixes vendors use in
+# register maps. Repositories extend this via ``extra_units`` or replace
+# it wholesale via ``units``.
+DEFAULT_UNITS = (
+    # Electrical
+    "W", "kW", "MW", "Wh", "kWh", "MWh",
+    "var", "kvar", "varh", "kvarh",
+    "VA", "kVA", "VAh", "kVAh",
+    "V", "kV", "mV", "A", "mA", "Hz",
+    # Thermal / flow
+    "°C", "K", "m³", "m³/h", "L", "L/h", "GJ", "MJ", "HCA",
+    # Environment
+    "%", "hPa", "Pa", "kPa", "bar", "ppm", "lx",
+    # Radio / device health
+    "dBm", "dB", "ratio", "s", "min", "h",
+)
+
+# ASCII spellings of approved units, as typed from vendor PDFs.
+UNIT_ALIASES = {
+    "m3": "m³", "m^3": "m³", "m3/h": "m³/h", "m^3/h": "m³/h",
+    "degC": "°C", "deg C": "°C", "C": "°C", "oC": "°C", "Celsius": "°C",
+    "l": "L", "l/h": "L/h", "mbar": "hPa",
+    "VAr": "var", "kVAr": "kvar", "VArh": "varh", "kVArh": "kvarh",
+}
+
+
+def approved_units(options: dict) -> list[str]:
+    """The ``unit-whitelist`` list for the rule ``options``, in catalogue order."""
+    units = list(options.get("units") or [])
+    return units + [u for u in options.get("extra_units") or [] if u not in units]
+
+
+def suggest_unit(unit: str, allowed: Iterable[str]) -> str | None:
+    """The approved unit ``unit`` most likely means: its ASCII alias, a
+    case-insensitive match, else the closest spelling."""
+    allowed = list(allowed)
+    if UNIT_ALIASES.get(unit) in allowed:
+        return UNIT_ALIASES[unit]
+    folded = [u for u in allowed if u.casefold() == unit.casefold()]
+    if len(folded) == 1:
+        return folded[0]
+    match = difflib.get_close_matches(unit, allowed, n=1, cutoff=0.5)
+    return match[0] if match else None
+
+
+@dataclass
+class LintDevice:
+    """One device definition under lint, plus where it came from."""
+
+    data: dict
+    source: str = ""  # vendor file name for YAML sources, "" for the database
+    source_bytes: int = 0  # size of that file on disk
+    # Keys of the manifest's device_types section, for YAML sources whose
+    # manifest has one; None where references can't dangle (the database).
+    device_type_keys: frozenset[str] | None = None
+
+    @property
+    def label(self) -> str:
+        return f"{self.data.get('vendor_name', '?')} {self.data.get('model_number', '?')}"
+
+
+@dataclass
+class Finding:
+    rule: str
+    severity: str
+    device: str
+    message: str
+    path: str = ""
+    source: str = ""
+
+    def as_dict(self) -> dict:
+        return asdict(self)
+
+
+@dataclass
+class Rule:
+    id: str
+    description: str
+    severity: str
+    scope: str  # "device" | "library"
+    check: Callable[..., Iterable]
+    defaults: dict = field(default_factory=dict)
+    enabled: bool = True  # False: opt-in, runs only when the config mentions it
+
+
+RULES: dict[str, Rule] = {}
+
+
+def rule(
+    rule_id: str, *, description: str, severity: str = "warning", scope: str = "device", enabled: bool = True,
+    **defaults,
+):
+    """Register a lint rule.
+
+    Device-scope checks are called as ``check(device_dict, options)`` and
+    yield ``(path, message)`` tuples. Library-scope checks are called as
+    ``check(devices, options)`` with a list of :class:`LintDevice` and
+    yield ``(device, path, message)`` tuples. ``enabled=False`` makes the
+    rule opt-in: it only runs when ``.sparklint.yaml`` lists it.
+    """
+
+    def decorator(fn):
+        RULES[rule_id] = Rule(
+            id=rule_id,
+            description=description,
+            severity=severity,
+            scope=scope,
+            check=fn,
+            defaults=defaults,
+            enabled=enabled,
+        )
+        return fn
+
+    return decorator
+
+
+# -----------------------------------------------------------------------------
+# Configuration
+# -----------------------------------------------------------------------------
+
+
+@dataclass
+class LintConfig:
+    """Per-repository rule selection parsed from ``.sparklint.yaml``.
+
+    ``rules`` maps rule id → settings dict. A rule missing from the map
+    runs with its defaults (opt-in rules don't run); ``false`` disables
+    it, ``true`` or a dict enables it; a dict may carry ``enabled`` /
+    ``severity`` plus any rule-specific options. A severity string is
+    shorthand for ``{severity: ...}``, and ``ignore`` for ``false``.
+    """
+
+    rules: dict[str, Any] = field(default_factory=dict)
+    plugins: list[str] = field(default_factory=list)
+
+    @classmethod
+    def load(cls, path: str | Path | None = None, load_plugins: bool = False) -> LintConfig:
+        """Read ``path`` (or ``settings.SPARKLINT_CONFIG`` when it exists).
+
+        A missing default file yields the all-defaults config; an
+        explicitly requested file that doesn't exist is an error.
+        ``load_plugins`` runs the plugins the file lists and needs an
+        explicit ``path``.
+        """
+        if path is None:
+            from django.conf import settings
+
+            if load_plugins:
+                raise ValueError("Lint plugins are only loaded from a config file named explicitly")
+            default = Path(settings.SPARKLINT_CONFIG)
+            if not default.exists():
+                return cls()
+            path = default
+        path = Path(path)
+        if not path.exists():
+            raise FileNotFoundError(f"Lint config not found: {path}")
+        with open(path) as f:
+            data = yaml.safe_load(f) or {}
+        return cls.from_dict(data, base_dir=path.parent, load_plugins=load_plugins)
+
+    @classmethod
+    def from_dict(cls, data: dict, base_dir: str | Path | None = None, load_plugins: bool = False) -> LintConfig:
+        rules = data.get("rules") or {}
+        if not isinstance(rules, dict):
+            raise ValueError("'rules' must be a mapping of rule id to settings")
+        plugins = data.get("plugins") or []
+        if not isinstance(plugins, list):
+            raise ValueError("'plugins' must be a list of plugin files or modules")
+        # Plugin rules must be registered before unknown ids are rejected.
+        if load_plugins:
+            for plugin in plugins:
+                load_plugin(plugin, base_dir=base_dir)
+        unknown = sorted(set(rules) - set(RULES))
+        if unknown and (load_plugins or not plugins):
+            raise ValueError(f"Unknown lint rule(s): {', '.join(unknown)}")
+        for rule_id, raw in rules.items():
+            severity = raw if isinstance(raw, str) else raw.get("severity") if isinstance(raw, dict) else None
+            if severity is not None and severity not in (*SEVERITIES, IGNORE):
+                raise ValueError(f"Rule {rule_id}: severity must be one of {', '.join((*SEVERITIES, IGNORE))}")
+        return cls(rules=rules, plugins=plugins)
+
+    def _settings(self, rule_id: str) -> dict:
+        raw = self.rules.get(rule_id, True)
+        if isinstance(raw, bool):
+            return {"enabled": raw}
+        if raw is None:
+            return {}
+        if isinstance(raw, str):
+            raw = {"severity": raw}
+        settings = dict(raw)
+        if settings.get("severity") == IGNORE:
+            settings["enabled"] = False
+            del settings["severity"]
+        return settings
+
+    def is_enabled(self, rule_id: str) -> bool:
+        if rule_id not in self.rules:
+            return RULES[rule_id].enabled
+        return bool(self._settings(rule_id).get("enabled", True))
+
+    def severity(self, rule_id: str) -> str:
+        severity = self._settings(rule_id).get("severity") or RULES[rule_id].severity
+        if severity not in SEVERITIES:
+            raise ValueError(f"Rule {rule_id}: severity must be one of {', '.join(SEVERITIES)}")
+        return severity
+
+    def options(self, rule_id: str) -> dict:
+        opts = dict(RULES[rule_id].defaults)
+        opts.update({k: v for k, v in self._settings(rule_id).items() if k not in ("enabled", "severity")})
+        return opts
+
+
+# -----------------------------------------------------------------------------
+# Plugins
+# -----------------------------------------------------------------------------
+
+
+def load_plugin(spec: str, base_dir: str | Path | None = None) -> None:
+    """Import a rule plugin so its ``@rule`` registrations take effect.
+
+    ``spec`` is a ``.py`` path (relative paths resolve against
+    ``base_dir``, normally the config file's directory) or a dotted
+    module name. Loading the same plugin twice is harmless — the
+    decorator re-registers the same ids.
+    """
+    if spec.endswith(".py"):
+        path = Path(spec)
+        if not path.is_absolute() and base_dir is not None:
+            path = Path(base_dir) / path
+        if not path.exists():
+            raise ValueError(f"Lint plugin not found: {path}")
+        module_spec = importlib.util.spec_from_file_location(f"sparklint_plugin_{path.stem}", path)
+        module = importlib.util.module_from_spec(module_spec)
+        try:
+            module_spec.loader.exec_module(module)
+        except Exception as e:
+            raise ValueError(f"Lint plugin {path} failed to load: {e}") from e
+        return
+    try:
+        importlib.import_module(spec)
+    except ImportError as e:
+        raise ValueError(f"Lint plugin module {spec!r} could not be imported: {e}") from e
+
+
+def load_plugin_dir(directory: str | Path) -> list[Path]:
+    """Load every ``*.py`` file in ``directory`` (sorted, non-recursive)."""
+    directory = Path(directory)
+    if not directory.is_dir():
+        raise ValueError(f"Lint plugin directory not found: {directory}")
+    paths = sorted(p for p in directory.glob("*.py") if not p.name.startswith("_"))
+    for path in paths:
+        load_plugin(str(path))
+    return paths
+
+
+# -----------------------------------------------------------------------------
+# Rules
+# -----------------------------------------------------------------------------
+
+
+def _registers(device: dict) -> list[dict]:
+    return (device.get("technology_config") or {}).get("register_definitions") or []
+
+
+def _mapping_entries(device: dict) -> Iterable[tuple[str, dict]]:
+    proc = device.get("processor_config") or {}
+    for slot in ("field_mappings", "extra_mappings"):
+        for idx, entry in enumerate(proc.get(slot) or []):
+            yield f"processor_config.{slot}[{idx}]", entry
+
+
+@rule(
+    "field-naming",
+    description="Register field names and mapping sources must be snake_case.",
+    pattern=r"^[a-z][a-z0-9_]*$",
+)
+def _check_field_naming(device: dict, options: dict):
+    pattern = re.compile(options["pattern"])
+    for idx, reg in enumerate(_registers(device)):
+        name = (reg.get("field") or {}).get("name") or ""
+        if name and not pattern.match(name):
+            yield (
+                f"technology_config.register_definitions[{idx}].field.name",
+                f"Field name {name!r} does not match {options['pattern']}",
+            )
+    for path, entry in _mapping_entries(device):
+        source = entry.get("source") or ""
+        if source and not pattern.match(source):
+            yield f"{path}.source", f"Mapping source {source!r} does not match {options['pattern']}"
+
+
+@rule(
+    "unit-whitelist",
+    description="Register units must come from the approved unit list.",
+    units=list(DEFAULT_UNITS),
+    extra_units=[],
+)
+def _check_unit_whitelist(device: dict, options: dict):
+    allowed = approved_units(options)
+    for idx, reg in enumerate(_registers(device)):
+        unit = (reg.get("field") or {}).get("unit") or ""
+        if unit and unit not in allowed:
+            message = f"Unit {unit!r} is not in the approved unit list"
+            suggestion = suggest_unit(unit, allowed)
+            if suggestion:
+                message += f" (did you mean {suggestion!r}?)"
+            yield f"technology_config.register_definitions[{idx}].field.unit", message
+
+
+@rule(
+    "canonical-field",
+    description="Register field names and units should come from the canonical field catalogue (opt-in).",
+    enabled=False,
+    extra_fields=[],
+    check_units=True,
+)
+def _check_canonical_field(device: dict, options: dict):
+    extra = set(options.get("extra_fields") or [])
+    for idx, reg in enumerate(_registers(device)):
+        spec = reg.get("field") or {}
+        name = spec.get("name") or ""
+        if not name or name in extra:
+            continue
+        problems = fields.validate(name, spec.get("unit") or "")
+        if problems and (options.get("check_units") or fields.lookup(name) is None):
+            yield f"technology_config.register_definitions[{idx}].field", problems[0]
+
+
+@rule(
+    "unit-conversion",
+    description="Mapping scale/offset must convert a register's unit into its target's canonical unit (opt-in).",
+    enabled=False,
+)
+def _check_unit_conversion(device: dict, options: dict):
+    for problem in units.check_device(Device.from_dict(device)):
+        yield "processor_config", problem
+
+
+@rule("missing-description", description="Devices should carry a description.")
+def _check_missing_description(device: dict, options: dict):
+    if not (device.get("description") or "").strip():
+        yield "description", "Device has no description"
+
+
+@rule(
+    "empty-processor-config",
+    description="Devices should map decoded fields onto L1 metrics.",
+)
+def _check_empty_processor_config(device: dict, options: dict):
+    proc = device.get("processor_config") or {}
+    if not proc.get("field_mappings") and not proc.get("extra_mappings"):
+        yield "processor_config", "processor_config has no field_mappings or extra_mappings"
+
+
+@rule(
+    "unknown-key",
+    description="Keys the importer doesn't know (typos like registr_definitions) are errors.",
+    severity="error",
+)
+def _check_unknown_key(device: dict, options: dict):
+    yield from unknown_keys(device)
+
+
+@rule(
+    "unknown-suppression",
+    description="validation.suppress must name existing rules (a typo suppresses nothing).",
+)
+def _check_unknown_suppression(device: dict, options: dict):
+    validation = device.get("validation")
+    suppress = validation.get("suppress") if isinstance(validation, dict) else None
+    for idx, rule_id in enumerate(suppress if isinstance(suppress, list) else []):
+        if isinstance(rule_id, str) and rule_id not in RULES:
+            message = f"Unknown rule {rule_id!r}"
+            suggestion = difflib.get_close_matches(rule_id, sorted(RULES), n=1)
+            if suggestion:
+                message += f" (did you mean {suggestion[0]!r}?)"
+            yield f"validation.suppress[{idx}]", message
+
+
+@rule(
+    "schema",
+    description="Devices must match device.schema.json (types, enums, required keys).",
+    severity="error",
+)
+def _check_schema(device: dict, options: dict):
+    for error in validate_device(device):
+        if error.keyword != "additionalProperties":  # unknown-key reports these, with a suggestion
+            yield error.path, error.message
+
+
+_URL = re.compile(r"https?://[^\s)>\]]+")
+
+
+def _link_urls(device: dict) -> Iterable[tuple[str, str]]:
+    """``(path, url)`` of every http(s) link; malformed ones are the ``schema`` rule's."""
+    for idx, link in enumerate(device.get("links") or []):
+        url = link.get("url") if isinstance(link, dict) else None
+        if isinstance(url, str) and url.startswith(("http://", "https://")):
+            yield f"links[{idx}].url", url
+
+
+@rule(
+    "description-url",
+    description="URLs belong in links: (datasheet, manual, vendor_page), not pasted into the description.",
+)
+def _check_description_url(device: dict, options: dict):
+    for match in _URL.findall(device.get("description") or ""):
+        url = match.rstrip(".,;:")
+        yield "description", f"URL {url} in description; list it under links instead"
+
+
+@rule(
+    "link-unreachable",
+    description="Documentation links must resolve (one HTTP request per URL; opt-in, or lint_library --check-links).",
+    scope="library",
+    enabled=False,
+    timeout=LINK_TIMEOUT,
+)
+def _check_link_unreachable(devices: list[LintDevice], options: dict):
+    problems = check_urls((url for dev in devices for _, url in _link_urls(dev.data)), timeout=options["timeout"])
+    for dev in devices:
+        for path, url in _link_urls(dev.data):
+            if url in problems:
+                yield dev, path, f"{url} is unreachable ({problems[url]})"
+
+
+# Settings a gateway can't do without, per technology. LoRaWAN also needs a
+# decoder — see ``_check_technology_required``.
+REQUIRED_TECHNOLOGY_KEYS = {
+    "modbus": ("function", "byte_order", "word_order", "register_definitions"),
+    "lorawan": ("device_class",),
+    "wmbus": ("manufacturer_code", "wmbus_device_type"),
+}
+
+
+def _technology_configs(device: dict) -> Iterable[tuple[str, dict]]:
+    yield "technology_config", device.get("technology_config") or {}
+    for idx, config in enumerate(device.get("additional_technology_configs") or []):
+        if isinstance(config, dict):
+            yield f"additional_technology_configs[{idx}]", config
+
+
+@rule(
+    "technology-required",
+    description="Each technology declares what a gateway needs: Modbus registers and connection parameters, "
+                "LoRaWAN device class and a decoder, wM-Bus manufacturer code and device type.",
+    severity="error",
+)
+def _check_technology_required(device: dict, options: dict):
+    seen = set()
+    for path, config in _technology_configs(device):
+        technology = config.get("technology")
+        if technology in seen:
+            continue  # duplicate-technology reports it
+        seen.add(technology)
+        for key in REQUIRED_TECHNOLOGY_KEYS.get(technology, ()):
+            if config.get(key) in (None, "", []):
+                yield f"{path}.{key}", f"{technology} devices must declare {key}"
+        if technology == "lorawan":
+            codec = config.get("payload_codec")
+            decoder_type = (device.get("processor_config") or {}).get("decoder_type")
+            if not (isinstance(codec, dict) and codec.get("script")) and decoder_type != "lorawan_field_map":
+                yield (
+                    f"{path}.payload_codec",
+                    "lorawan devices must declare a decoder: a payload_codec script, "
+                    "or processor_config.decoder_type lorawan_field_map",
+                )
+
+
+@rule(
+    "codec-syntax",
+    description="Embedded JavaScript codecs must be syntactically complete and define their format's entry point.",
+    severity="error",
+)
+def _check_codec_syntax(device: dict, options: dict):
+    for path, config in _technology_configs(device):
+        codec = config.get("payload_codec")
+        if config.get("technology") != "lorawan" or not isinstance(codec, dict):
+            continue
+        script = codec.get("script")
+        if isinstance(script, str) and script.strip():
+            for error in check_script(script, codec.get("format") or ""):
+                yield f"{path}.payload_codec.script", str(error)
+
+
+@rule(
+    "duplicate-technology",
+    description="A hybrid device lists each technology once across technology_config and its additional configs.",
+    severity="error",
+)
+def _check_duplicate_technology(device: dict, options: dict):
+    primary = (device.get("technology_config") or {}).get("technology")
+    seen = {primary} if primary else set()
+    for idx, config in enumerate(device.get("additional_technology_configs") or []):
+        technology = (config or {}).get("technology") if isinstance(config, dict) else None
+        path = f"additional_technology_configs[{idx}].technology"
+        if not technology:
+            yield path, "Additional technology config has no technology"
+        elif technology in seen:
+            yield path, f"Technology {technology!r} already has a config"
+        else:
+            seen.add(technology)
+
+
+@rule(
+    "duplicate-register-address",
+    description="Each Modbus register address appears once per device (duplicates are usually merge leftovers).",
+    severity="error",
+)
+def _check_duplicate_register_address(device: dict, options: dict):
+    first: dict = {}
+    for idx, reg in enumerate(_registers(device)):
+        address = reg.get("address")
+        if address is None:
+            continue
+        if address in first:
+            yield (
+                f"technology_config.register_definitions[{idx}].address",
+                f"Address {address} is also used by register_definitions[{first[address]}]",
+            )
+        else:
+            first[address] = idx
+
+
+def _register_issues(device: dict, kind: str, max_gap: int = SUSPICIOUS_GAP):
+    for issue in analyze_registers(_registers(device), max_gap=max_gap):
+        if issue.kind == kind:
+            yield f"technology_config.register_definitions[{issue.index}]", issue.message
+
+
+@rule(
+    "register-overlap",
+    description="Registers must not overlap, counting each data type's width (uint32/float32 take two).",
+    severity="error",
+)
+def _check_register_overlap(device: dict, options: dict):
+    yield from _register_issues(device, "overlap")
+
+
+@rule(
+    "duplicate-register-field",
+    description="Each register has its own field name.",
+    severity="error",
+)
+def _check_duplicate_register_field(device: dict, options: dict):
+    yield from _register_issues(device, "duplicate-field")
+
+
+@rule(
+    "register-gap",
+    description="Small holes between registers usually mean a data type that is too narrow or a missing field.",
+    max_gap=SUSPICIOUS_GAP,
+)
+def _check_register_gap(device: dict, options: dict):
+    yield from _register_issues(device, "gap", max_gap=options["max_gap"])
+
+
+@rule(
+    "duplicate-model-number",
+    description="Model numbers must be unique per vendor (case- and whitespace-insensitive).",
+    severity="error",
+    scope="library",
+)
+def _check_duplicate_model_number(devices: list[LintDevice], options: dict):
+    seen: dict[tuple[str, str], LintDevice] = {}
+    for dev in devices:
+        vendor = (dev.data.get("vendor_name") or "").strip().lower()
+        model = re.sub(r"\s+", "", (dev.data.get("model_number") or "")).lower()
+        if not model:
+            continue
+        first = seen.setdefault((vendor, model), dev)
+        if first is not dev:
+            yield dev, "model_number", f"Duplicates model number of {first.label}"
+
+
+@rule(
+    "unresolved-reference",
+    description="device_type_key must name an entry of the manifest's device_types section.",
+    severity="error",
+    scope="library",
+)
+def _check_unresolved_reference(devices: list[LintDevice], options: dict):
+    # The importer silently falls back to matching device_type by code when
+    # the key doesn't resolve, so a dangling key would otherwise go unnoticed.
+    for dev in devices:
+        key = dev.data.get("device_type_key")
+        if dev.device_type_keys is not None and key and str(key) not in dev.device_type_keys:
+            yield dev, "device_type_key", f"device_type_key {key} is not in the manifest's device_types"
+
+
+# Growth guardrails: limits that keep files and devices reviewable in a pull
+# request. Repositories tune them per rule in ``.sparklint.yaml``.
+
+
+@rule(
+    "max-registers",
+    description="A device's register map stays below a reviewable size.",
+    max=250,
+)
+def _check_max_registers(device: dict, options: dict):
+    count = len(_registers(device))
+    if count > options["max"]:
+        yield (
+            "technology_config.register_definitions",
+            f"{count} registers (limit {options['max']}) — split rarely used registers into a separate "
+            "device variant, or raise max-registers.max for this repository",
+        )
+
+
+def _per_file(devices: list[LintDevice]) -> dict[str, list[LintDevice]]:
+    files: dict[str, list[LintDevice]] = {}
+    for dev in devices:
+        if dev.source:
+            files.setdefault(dev.source, []).append(dev)
+    return files
+
+
+@rule(
+    "max-devices-per-file",
+    description="A vendor file holds a reviewable number of devices.",
+    scope="library",
+    max=60,
+)
+def _check_max_devices_per_file(devices: list[LintDevice], options: dict):
+    for source, devs in _per_file(devices).items():
+        if len(devs) > options["max"]:
+            yield (
+                devs[0],
+                "",
+                f"{source} holds {len(devs)} devices (limit {options['max']}) — split it with "
+                f"`manage.py split_vendor \"{devs[0].data.get('vendor_name', '')}\" --by device_type`",
+            )
+
+
+@rule(
+    "max-file-size",
+    description="A vendor file stays below a reviewable size on disk.",
+    scope="library",
+    max_kb=512,
+)
+def _check_max_file_size(devices: list[LintDevice], options: dict):
+    for source, devs in _per_file(devices).items():
+        size_kb = devs[0].source_bytes / 1024
+        if size_kb > options["max_kb"]:
+            yield (
+                devs[0],
+                "",
+                f"{source} is {size_kb:.0f} KiB (limit {options['max_kb']} KiB) — split it with "
+                f"`manage.py split_vendor \"{devs[0].data.get('vendor_name', '')}\" --by technology` "
+                "or `--by device_type`",
+            )
+
+
+# -----------------------------------------------------------------------------
+# Sources
+# -----------------------------------------------------------------------------
+
+
+def devices_from_database(vendor_slug: str | None = None) -> list[LintDevice]:
+    """Load every VendorModel in its exported schema shape."""
+    from .exporters import _export_device
+    from .models import VendorModel
+
+    qs = VendorModel.objects.select_related("vendor", "device_type_fk")
+    if vendor_slug:
+        qs = qs.filter(vendor__slug=vendor_slug)
+    return [LintDevice(data=_export_device(device)) for device in qs]
+
+
+def device_statuses(vendor_models, config: LintConfig | None = None) -> dict[str, dict]:
+    """Quick per-device lint summary for list views:
+    ``{pk: {"status": "ok" | "warning" | "error", "errors": n, "warnings": n}}``.
+
+    Library-scope rules only see ``vendor_models`` (typically one page), so
+    ``lint_library`` over the whole library stays the authority.
+    """
+    from .exporters import _export_device
+
+    devices = {str(vm.pk): LintDevice(data=_export_device(vm)) for vm in vendor_models}
+    pk_by_label = {dev.label: pk for pk, dev in devices.items()}
+    statuses = {pk: {"status": "ok", "errors": 0, "warnings": 0} for pk in devices}
+    for finding in lint_devices(list(devices.values()), config):
+        entry = statuses[pk_by_label[finding.device]]
+        entry["errors" if finding.severity == "error" else "warnings"] += 1
+    for entry in statuses.values():
+        if entry["errors"]:
+            entry["status"] = "error"
+        elif entry["warnings"]:
+            entry["status"] = "warning"
+    return statuses
+
+
+def devices_from_yaml(devices_path: str | Path, manifest_path: str | Path) -> list[LintDevice]:
+    """Load device definitions from an exported YAML tree.
+
+    Files referenced by the manifest but missing on disk are skipped — that
+    is a manifest consistency problem, not a device lint finding.
+    """
+    devices_path = Path(devices_path)
+    with open(manifest_path) as f:
+        manifest = yaml.safe_load(f) or {}
+
+    device_type_keys = None
+    if isinstance(manifest.get("device_types"), list):
+        device_type_keys = frozenset(
+            str(entry["key"]) for entry in manifest["device_types"] if isinstance(entry, dict) and entry.get("key")
+        )
+
+    result: list[LintDevice] = []
+    for vendor_entry in manifest.get("vendors", []) or []:
+        file_path = devices_path / vendor_entry["file"]
+        if not file_path.exists():
+            continue
+        with open(file_path) as f:
+            data = yaml.safe_load(f) or {}
+        size = file_path.stat().st_size
+        devices_key = "models" if "models" in data else "device_types"
+        for device in data.get(devices_key) or []:
+            device.setdefault("vendor_name", vendor_entry.get("name", ""))
+            result.append(LintDevice(
+                data=device, source=vendor_entry["file"], source_bytes=size, device_type_keys=device_type_keys,
+            ))
+    return result
+
+
+def tree_schema_findings(
+    devices_path: str | Path, manifest_path: str | Path, config: LintConfig | None = None,
+) -> list[Finding]:
+    """``schema`` findings for the manifest and the vendor files themselves
+    (their devices are checked by the ``schema`` rule in ``lint_devices``)."""
+    config = config or LintConfig()
+    if not config.is_enabled("schema"):
+        return []
+    severity = config.severity("schema")
+    devices_path = Path(devices_path)
+    with open(manifest_path) as f:
+        manifest = yaml.safe_load(f) or {}
+
+    name = Path(manifest_path).name
+    findings = [Finding("schema", severity, name, e.message, e.path, name) for e in validate_manifest(manifest)]
+    for vendor_entry in manifest.get("vendors", []) or []:
+        file_name = vendor_entry.get("file") if isinstance(vendor_entry, dict) else None
+        if not file_name or not (devices_path / file_name).exists():
+            continue
+        with open(devices_path / file_name) as f:
+            data = yaml.safe_load(f) or {}
+        findings += [
+            Finding("schema", severity, file_name, e.message, e.path, file_name)
+            for e in validate_vendor_file(data) if not e.path.startswith("models[")
+        ]
+    return findings
+
+
+# -----------------------------------------------------------------------------
+# Runner
+# -----------------------------------------------------------------------------
+
+
+def suppressed_rules(device: dict) -> set[str]:
+    """Rule ids ``device`` suppresses in its ``validation`` block."""
+    validation = device.get("validation")
+    suppress = validation.get("suppress") if isinstance(validation, dict) else None
+    return {r for r in suppress if isinstance(r, str)} if isinstance(suppress, list) else set()
+
+
+def lint_devices(devices: list[LintDevice], config: LintConfig | None = None) -> list[Finding]:
+    """Run every enabled rule over ``devices`` and return the findings,
+    ordered by severity (errors first), then device label. Rules a device
+    suppresses aren't reported for it."""
+    config = config or LintConfig()
+    findings: list[Finding] = []
+    suppressed = {id(dev): suppressed_rules(dev.data) for dev in devices}
+
+    for rule_id, r in RULES.items():
+        if not config.is_enabled(rule_id):
+            continue
+        severity = config.severity(rule_id)
+        options = config.options(rule_id)
+
+        if r.scope == "library":
+            for dev, path, message in r.check(devices, options):
+                if rule_id not in suppressed.get(id(dev), ()):
+                    findings.append(Finding(rule_id, severity, dev.label, message, path, dev.source))
+            continue
+
+        for dev in devices:
+            if rule_id in suppressed[id(dev)]:
+                continue
+            for path, message in r.check(dev.data, options):
+                findings.append(Finding(rule_id, severity, dev.label, message, path, dev.source))
+
+    findings.sort(key=lambda f: (SEVERITIES.index(f.severity), f.device.lower(), f.rule))
+    return findings
@@ -0,0 +1,98 @@
+"""Base class for the library management commands.
+
+``LibraryCommand`` adds ``--json-errors`` and classifies failures the
+commands themselves don't (a database that can't be reached becomes an
+``EnvironmentFailure``) — see ``library.management.errors`` for the
+taxonomy and exit codes. ``--log-file`` appends the run to a file: the
+``library`` log (imports, fetches, writes) plus the command's start,
+finish or failure — the stream the web app's status log writes to
+``STATUS_LOG_FILE``.
+"""
+
+from __future__ import annotations
+
+import json
+import logging
+import sys
+
+from django.core.exceptions import ImproperlyConfigured
+from django.core.management.base import BaseCommand, CommandError, handle_default_options
+from django.db import OperationalError, connections
+
+from .errors import EnvironmentFailure, UsageError, error_payload
+
+
+class LibraryCommand(BaseCommand):
+    def create_parser(self, prog_name, subcommand, **kwargs):
+        parser = super().create_parser(prog_name, subcommand, **kwargs)
+        parser.add_argument(
+            "--json-errors",
+            action="store_true",
+            help="On failure, print a JSON error document on stderr (code, exit_code, message)",
+        )
+        parser.add_argument(
+            "--log-file",
+            default=None,
+            help="Append the run's log (library messages, start, finish, errors) to this file",
+        )
+        return parser
+
+    def execute(self, *args, **options):
+        if not options.get("log_file"):
+            return self._execute(*args, **options)
+        handler = logging.FileHandler(options["log_file"], encoding="utf-8")
+        handler.setFormatter(logging.Formatter("%(levelname)s %(asctime)s %(name)s %(message)s"))
+        library = logging.getLogger("library")
+        library.addHandler(handler)
+        name = self.__module__.rsplit(".", 1)[-1]
+        status = logging.getLogger("library.status")
+        status.info("manage.py %s started", name)
+        try:
+            result = self._execute(*args, **options)
+        except Exception as e:
+            status.error("manage.py %s failed: %s", name, e)
+            raise
+        else:
+            status.info("manage.py %s finished", name)
+            return result
+        finally:
+            library.removeHandler(handler)
+            handler.close()
+
+    def _execute(self, *args, **options):
+        try:
+            return super().execute(*args, **options)
+        except OperationalError as e:
+            raise EnvironmentFailure(f"Database unavailable: {e}") from e
+
+    def run_from_argv(self, argv):
+        if "--json-errors" not in argv:
+            return super().run_from_argv(argv)
+
+        # Same flow as BaseCommand.run_from_argv, but every failure —
+        # argument errors included — is reported as JSON.
+        parser = self.create_parser(argv[0], argv[1])
+        try:
+            options = parser.parse_args(argv[2:])
+        except CommandError as e:
+            self._exit_with(UsageError(str(e).removeprefix("Error: ")))
+        self._called_from_command_line = True
+        cmd_options = vars(options)
+        args = cmd_options.pop("args", ())
+        handle_default_options(options)
+        try:
+            self.execute(*args, **cmd_options)
+        except Exception as e:
+            if options.traceback:
+                raise
+            self._exit_with(e)
+        finally:
+            try:
+                connections.close_all()
+            except ImproperlyConfigured:
+                pass
+
+    def _exit_with(self, error: Exception):
+        payload = error_payload(error)
+        self.stderr.write(json.dumps(payload, ensure_ascii=False), style_func=lambda x: x)
+        sys.exit(payload["error"]["exit_code"])
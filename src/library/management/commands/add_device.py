@@ -0,0 +1,76 @@
+"""Management command running the interactive add-a-device wizard.
+
+Asks for vendor, model, technology and the technology-specific settings
+(registers for Modbus, class/FPort for LoRaWAN, header fields for wM-Bus),
+shows the resulting definition with any lint findings, and on confirmation
+saves it to the database or — with ``--path`` — appends it to an exported
+YAML tree. A definition that doesn't match the device JSON Schema or lacks
+a setting its technology requires is never saved. A device saved to the
+database is audited like one created in the editor, under ``--user``.
+"""
+
+from auditlog.models import AuditLog
+from core.models import User
+from library.management.base import LibraryCommand
+from library.management.errors import Conflict, LibraryError, NotFound, ValidationFailed
+from library.management.tree import add_tree_arguments, tree_paths
+from library.scaffold import ScaffoldError, append_to_tree
+from library.wizard import DeviceWizard, blocking_findings, save_to_database, validate
+from library.yaml_format import canonical_device, dump_yaml
+
+
+class Command(LibraryCommand):
+    help = "Interactively build a complete device definition step by step"
+
+    def add_arguments(self, parser):
+        add_tree_arguments(parser, "Append to a YAML devices directory instead of the database")
+        parser.add_argument(
+            "--user", default=None, help="Username the device history and audit entry are recorded under",
+        )
+
+    def handle(self, *args, **options):
+        tree = tree_paths(options)
+        user = None
+        if options["user"] and not tree:
+            user = User.objects.filter(username=options["user"]).first()
+            if user is None:
+                raise NotFound(f"User {options['user']} not found")
+        wizard = DeviceWizard(ask=input, say=self.stdout.write)
+        try:
+            device = wizard.run()
+            self.stdout.write("\n" + dump_yaml(canonical_device(device)))
+
+            findings = validate(device)
+            for f in findings:
+                style = self.style.ERROR if f.severity == "error" else self.style.WARNING
+                path = f"{f.path}: " if f.path else ""
+                self.stdout.write(style(f"{f.severity.upper()}: {path}{f.message}"))
+            blocking = blocking_findings(findings)
+            if blocking:
+                raise ValidationFailed(
+                    f"{len(blocking)} blocking error(s), nothing saved", [f.as_dict() for f in blocking],
+                )
+            if not wizard.confirm("Save this device?", default=not findings):
+                self.stdout.write("Nothing saved.")
+                return
+        except (EOFError, KeyboardInterrupt) as e:
+            raise LibraryError("Aborted, nothing saved") from e
+
+        try:
+            if tree:
+                file_path = append_to_tree(*tree, device)
+                self.stdout.write(self.style.SUCCESS(f"Added {device['vendor_name']} {device['model_number']} to {file_path}"))
+            else:
+                vm = save_to_database(device, user)
+                AuditLog.objects.create(
+                    user=user,
+                    category=AuditLog.Category.DEVICE,
+                    action="created",
+                    target_type="VendorModel",
+                    target_id=vm.pk,
+                    target_label=str(vm)[:255],
+                    details={"message": "Added with the add_device wizard"},
+                )
+                self.stdout.write(self.style.SUCCESS(f"Created {vm} ({vm.pk})"))
+        except ScaffoldError as e:
+            raise Conflict(str(e)) from e
@@ -0,0 +1,73 @@
+"""Management command to start a vendor in an exported YAML tree.
+
+``add_vendor "Acme Metering" --technology modbus --path devices`` creates
+an empty ``acme-metering.yaml`` and appends the vendor's entry to the
+manifest, so ``create_device --path`` can add its first device right
+away. Run on a terminal without a name, it prompts for the name, the file
+name and the technologies.
+"""
+
+import sys
+
+from django.utils.text import slugify
+
+from library.management.base import LibraryCommand
+from library.management.errors import Conflict, UsageError
+from library.management.tree import add_tree_arguments, tree_paths
+from library.models import VendorModel
+from library.safe_write import TreeWriter
+from library.vendor_files import VendorFileConflict, VendorFileError, add_vendor
+
+TECHNOLOGIES = [t.value for t in VendorModel.Technology]
+
+
+class Command(LibraryCommand):
+    help = "Add a new vendor (empty vendor file and manifest entry) to a YAML tree"
+
+    def add_arguments(self, parser):
+        parser.add_argument("name", nargs="?", default=None, help="Vendor name (default: prompt)")
+        parser.add_argument("--file", default=None, help="Vendor file name (default: <slug>.yaml)")
+        parser.add_argument(
+            "--technology",
+            action="append",
+            choices=TECHNOLOGIES,
+            default=[],
+            help="Technology the vendor's devices use (repeatable)",
+        )
+        add_tree_arguments(parser, "YAML devices directory", required=True)
+        parser.add_argument("--dry-run", action="store_true", help="Print the diff instead of writing the files")
+
+    def handle(self, *args, **options):
+        tree = tree_paths(options)
+        name, file, technologies = options["name"], options["file"], options["technology"]
+        if not name:
+            name, file, technologies = self._prompt(file, technologies)
+
+        writer = TreeWriter(dry_run=options["dry_run"])
+        try:
+            file = add_vendor(*tree, name, file, technologies, writer)
+        except VendorFileConflict as e:
+            raise Conflict(str(e)) from e
+        except VendorFileError as e:
+            raise UsageError(str(e)) from e
+        if options["dry_run"]:
+            for diff in writer.diffs:
+                self.stdout.write(diff, ending="")
+            return
+        self.stdout.write(self.style.SUCCESS(f"Added {name} ({file}); manifest updated"))
+
+    def _prompt(self, file, technologies):
+        if not sys.stdin.isatty():
+            raise UsageError("A vendor name is required when not running interactively")
+        name = input("Vendor name: ").strip()
+        if not name:
+            raise UsageError("No vendor name given")
+        default = file or f"{slugify(name)}.yaml"
+        file = input(f"File name [{default}]: ").strip() or default
+        answer = input(f"Technologies ({', '.join(TECHNOLOGIES)}; comma-separated) [{','.join(technologies)}]: ")
+        if answer.strip():
+            technologies = [t.strip() for t in answer.split(",") if t.strip()]
+            unknown = sorted(set(technologies) - set(TECHNOLOGIES))
+            if unknown:
+                raise UsageError(f"Unknown technology: {', '.join(unknown)}")
+        return name, file, technologies
@@ -0,0 +1,52 @@
+"""Management command to apply a declarative patch file to many devices.
+
+See ``library.patching`` for the patch format. Applies to the database by
+default (each changed device is re-imported, so history is recorded) or,
+with ``--path``, to an exported YAML tree. ``--dry-run`` prints the
+per-device diff without saving.
+"""
+
+from library.management.base import LibraryCommand
+from library.management.errors import InvalidInput
+from library.management.tree import add_tree_arguments, tree_paths
+from library.patching import PatchError, apply_to_database, apply_to_tree, load_patches
+from library.safe_write import TreeWriter
+
+
+class Command(LibraryCommand):
+    help = "Apply declarative edits from a patch file to all matching devices"
+
+    def add_arguments(self, parser):
+        parser.add_argument("patch_file", help="YAML patch file")
+        add_tree_arguments(parser, "Patch a YAML devices directory instead of the database")
+        parser.add_argument(
+            "--dry-run",
+            action="store_true",
+            help="Show what would change without saving",
+        )
+
+    def handle(self, *args, **options):
+        try:
+            patches = load_patches(options["patch_file"])
+        except (OSError, PatchError) as e:
+            raise InvalidInput(str(e)) from e
+
+        tree = tree_paths(options)
+        try:
+            if tree:
+                results = apply_to_tree(patches, *tree, writer=TreeWriter(dry_run=options["dry_run"]))
+            else:
+                results = apply_to_database(patches, dry_run=options["dry_run"])
+        except PatchError as e:
+            raise InvalidInput(str(e)) from e
+
+        for change in results:
+            if options["dry_run"]:
+                self.stdout.write(change.diff(), ending="")
+                continue
+            self.stdout.write(self.style.WARNING(change.label))
+            for line in change.changes:
+                self.stdout.write(f"    {line}")
+
+        verb = "would change" if options["dry_run"] else "changed"
+        self.stdout.write(self.style.SUCCESS(f"{len(results)} device(s) {verb}"))
@@ -0,0 +1,62 @@
+"""Management command to audit control configuration consistency.
+
+Lists devices whose ``ControlConfig`` disagrees with the rest of their
+definition (see ``library.control_audit``). With ``--fix`` each fixable
+issue is offered interactively; ``--fix --yes`` applies all of them
+without prompting.
+"""
+
+from library.control_audit import audit_control_configs
+from library.management.base import LibraryCommand
+from library.models import VendorModel
+
+
+class Command(LibraryCommand):
+    help = "List devices with inconsistent control configuration and optionally fix them"
+
+    def add_arguments(self, parser):
+        parser.add_argument(
+            "--vendor",
+            default=None,
+            help="Limit the audit to one vendor slug",
+        )
+        parser.add_argument(
+            "--fix",
+            action="store_true",
+            help="Offer to apply the suggested fix for each fixable issue",
+        )
+        parser.add_argument(
+            "--yes",
+            action="store_true",
+            help="With --fix, apply every fix without prompting",
+        )
+
+    def handle(self, *args, **options):
+        qs = VendorModel.objects.all()
+        if options["vendor"]:
+            qs = qs.filter(vendor__slug=options["vendor"])
+
+        issues = audit_control_configs(qs)
+        if not issues:
+            self.stdout.write(self.style.SUCCESS("No control configuration issues found"))
+            return
+
+        fixed = 0
+        for issue in issues:
+            line = f"{issue.device.vendor.name} {issue.device.model_number}: [{issue.code}] {issue.message}"
+            self.stdout.write(self.style.WARNING(line))
+            if not (options["fix"] and issue.fix):
+                continue
+            if not options["yes"]:
+                answer = input(f"  Fix ({issue.fix_description})? [y/N] ").strip().lower()
+                if answer not in ("y", "yes"):
+                    continue
+            issue.fix(None)
+            fixed += 1
+            self.stdout.write(f"  fixed: {issue.fix_description}")
+
+        fixable = sum(1 for i in issues if i.fix)
+        summary = f"{len(issues)} issues found ({fixable} fixable)"
+        if options["fix"]:
+            summary += f", {fixed} fixed"
+        self.stdout.write(summary)
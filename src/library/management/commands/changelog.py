@@ -0,0 +1,62 @@
+"""Management command to generate Markdown release notes.
+
+Compares two published library versions and groups new devices, improved
+definitions and breaking changes by vendor or technology — for product
+announcements, derived from the structured device diff rather than raw
+edit history.
+"""
+
+from pathlib import Path
+
+from library.changelog import GROUP_BY, build_changelog, render_markdown
+from library.management.base import LibraryCommand
+from library.management.errors import NotFound, UsageError
+from library.models import LibraryVersion
+
+
+class Command(LibraryCommand):
+    help = "Generate Markdown release notes between two published library versions"
+
+    def add_arguments(self, parser):
+        parser.add_argument("--since", required=True, help="Base published version (e.g. 3 or v3)")
+        parser.add_argument(
+            "--until",
+            default=None,
+            help="Target published version (default: the current version)",
+        )
+        parser.add_argument(
+            "--group-by",
+            choices=GROUP_BY,
+            default="vendor",
+            help="Group changes by vendor or technology",
+        )
+        parser.add_argument("-o", "--output", default=None, help="Write to this file instead of stdout")
+
+    def handle(self, *args, **options):
+        if options["until"]:
+            until = self._version(options["until"])
+        else:
+            until = (
+                LibraryVersion.objects.filter(is_current=True).first()
+                or LibraryVersion.objects.order_by("-version").first()
+            )
+            if until is None:
+                raise NotFound("No library version has been published yet: nothing to compare")
+        since = self._version(options["since"])
+        if until.version <= since.version:
+            raise UsageError(f"--until (v{until.version}) must be newer than --since (v{since.version})")
+
+        markdown = render_markdown(since, until, build_changelog(since, until, options["group_by"]))
+
+        if options["output"]:
+            Path(options["output"]).write_text(markdown)
+            self.stdout.write(self.style.SUCCESS(f"Release notes written to {options['output']}"))
+        else:
+            self.stdout.write(markdown, ending="")
+
+    def _version(self, ref: str) -> LibraryVersion:
+        number = ref[1:] if ref.startswith("v") else ref
+        version = LibraryVersion.objects.filter(version=int(number)).first() if number.isdigit() else None
+        if version is None:
+            raise NotFound(f"Library version {ref} not found")
+        return version
@@ -0,0 +1,103 @@
+"""Management command to check fetched LoRaWAN codecs against their upstream.
+
+Every codec imported from a URL (see ``library.codec_fetch``) is
+re-fetched and its SHA-256 compared with the one recorded at fetch time.
+The report lists codecs with a newer upstream version, unreachable
+sources, and codecs edited locally since the fetch — updating those would
+discard the local changes.
+
+``--apply`` stores the newer upstream versions (with a history entry per
+device); locally edited codecs are only replaced with ``--force``.
+``--fail-on-update`` exits non-zero while updates are pending, for CI.
+"""
+
+import json
+
+from django.db.models import Q
+
+from library.codec_fetch import apply_fetched_codec, check_upstream
+from library.history import record_history, snapshot_device
+from library.management.base import LibraryCommand
+from library.management.errors import UsageError, ValidationFailed
+from library.models import DeviceHistory, LoRaWANConfig
+
+
+class Command(LibraryCommand):
+    help = "Report LoRaWAN codecs whose upstream source has changed since they were fetched"
+
+    def add_arguments(self, parser):
+        parser.add_argument("--vendor", help="Only check this vendor's devices (name or slug)")
+        parser.add_argument("--apply", action="store_true", help="Store the newer upstream codecs")
+        parser.add_argument(
+            "--force",
+            action="store_true",
+            help="With --apply, also replace codecs edited locally since they were fetched",
+        )
+        parser.add_argument("--timeout", type=int, default=15, help="Per-request timeout in seconds")
+        parser.add_argument(
+            "--fail-on-update",
+            action="store_true",
+            help="Exit non-zero when updates are available (and not applied) or a source is unreachable",
+        )
+        parser.add_argument("--format", choices=["text", "json"], default="text", help="Output format")
+
+    def handle(self, *args, **options):
+        if options["force"] and not options["apply"]:
+            raise UsageError("--force only applies together with --apply")
+
+        configs = (
+            LoRaWANConfig.objects.exclude(codec_source_url="")
+            .select_related("device_type__vendor")
+            .order_by("device_type__vendor__name", "device_type__model_number")
+        )
+        if options["vendor"]:
+            vendor = options["vendor"]
+            configs = configs.filter(Q(device_type__vendor__slug=vendor) | Q(device_type__vendor__name__iexact=vendor))
+
+        results = [check_upstream(config, timeout=options["timeout"]) for config in configs]
+        applied = []
+        if options["apply"]:
+            for result in results:
+                if result.status != "updated" or (result.locally_modified and not options["force"]):
+                    continue
+                device = result.config.device_type
+                old_snapshot = snapshot_device(device)
+                apply_fetched_codec(result.config, result.fetched)
+                record_history(device, DeviceHistory.Action.UPDATED, user=None, previous_snapshot=old_snapshot)
+                applied.append(result)
+
+        if options["format"] == "json":
+            rows = [dict(r.as_dict(), applied=r in applied) for r in results]
+            self.stdout.write(json.dumps(rows, indent=2, ensure_ascii=False))
+        else:
+            self._write_text(results, applied)
+
+        pending = [r for r in results if r.status == "updated" and r not in applied]
+        errors = [r for r in results if r.status == "error"]
+        if options["fail_on_update"] and (pending or errors):
+            raise ValidationFailed(
+                f"{len(pending)} codec update(s) pending, {len(errors)} source(s) unreachable",
+                [r.as_dict() for r in pending + errors],
+            )
+
+    def _write_text(self, results, applied):
+        if not results:
+            self.stdout.write("No codecs with a recorded source URL.")
+            return
+        for r in results:
+            device = r.config.device_type
+            label = f"{device.vendor.name} {device.model_number}"
+            edited = " (edited locally since fetch)" if r.locally_modified else ""
+            if r.status == "current":
+                self.stdout.write(self.style.SUCCESS(f"[current] {label}{edited}"))
+            elif r.status == "error":
+                self.stdout.write(self.style.ERROR(f"[error]   {label}: {r.error}"))
+            elif r in applied:
+                self.stdout.write(self.style.SUCCESS(f"[applied] {label}: {r.config.codec_source_url}{edited}"))
+            else:
+                self.stdout.write(self.style.WARNING(f"[updated] {label}: {r.config.codec_source_url}{edited}"))
+        counts = {status: sum(1 for r in results if r.status == status) for status in ("current", "updated", "error")}
+        self.stdout.write(
+            f"{len(results)} checked: {counts['current']} current, {counts['updated']} with upstream updates "
+            f"({len(applied)} applied), {counts['error']} unreachable"
+        )
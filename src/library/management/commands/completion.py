@@ -0,0 +1,60 @@
+"""Management command to print shell completion for the library commands.
+
+Install with e.g. ``python manage.py completion bash > /etc/bash_completion.d/spark``
+or ``python manage.py completion fish > ~/.config/fish/completions/manage.py.fish``.
+The generated script calls ``completion --values`` to complete vendor slugs
+and model numbers; set ``SPARK_MANIFEST`` to complete from a YAML tree
+instead of the database.
+"""
+
+import os
+
+from library.completion import SHELLS, model_values, render_script, vendor_values
+from library.management.base import LibraryCommand
+from library.management.errors import InvalidInput, UsageError
+
+
+class Command(LibraryCommand):
+    help = "Print a bash/zsh/fish completion script for the library management commands"
+
+    def add_arguments(self, parser):
+        parser.add_argument(
+            "shell",
+            nargs="?",
+            choices=SHELLS,
+            help="Shell to generate the completion script for",
+        )
+        parser.add_argument(
+            "--prog",
+            default="manage.py",
+            help="Command name the completion is registered for (default: manage.py)",
+        )
+        parser.add_argument(
+            "--values",
+            choices=["vendors", "models"],
+            default=None,
+            help="Print completion candidates, one per line (used by the generated scripts)",
+        )
+        parser.add_argument(
+            "--vendor",
+            default=None,
+            help="With --values models, only list models of this vendor slug",
+        )
+
+    def handle(self, *args, **options):
+        if options["values"]:
+            manifest = os.environ.get("SPARK_MANIFEST") or None
+            try:
+                if options["values"] == "vendors":
+                    values = vendor_values(manifest)
+                else:
+                    values = model_values(options["vendor"], manifest)
+            except (OSError, AttributeError) as e:
+                raise InvalidInput(f"Cannot read {manifest}: {e}") from e
+            for value in values:
+                self.stdout.write(value)
+            return
+
+        if not options["shell"]:
+            raise UsageError(f"Specify a shell: {', '.join(SHELLS)}")
+        self.stdout.write(render_script(options["shell"], options["prog"]), ending="")
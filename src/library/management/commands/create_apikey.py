@@ -1,11 +1,10 @@
 """Management command to create an API key."""
 
-from django.core.management.base import BaseCommand
-
+from library.management.base import LibraryCommand
 from library.models import APIKey
 
 
-class Command(BaseCommand):
+class Command(LibraryCommand):
     help = "Create an API key and print its credentials for use with sync scripts"
 
     def add_arguments(self, parser):
@@ -0,0 +1,87 @@
+"""Management command to scaffold a new device definition.
+
+Creates a skeleton device (empty register map / mappings) either in the
+database or — with ``--path`` — appended to the vendor file of an exported
+YAML tree, creating the vendor file and manifest entry when needed. Meant
+for scripted onboarding of whole device families. ``--template`` starts
+from a device template (``library.device_templates``) instead of the
+skeleton.
+"""
+
+from library.device_templates import TemplateError, create_from_template, load_template, template_device
+from library.management.base import LibraryCommand
+from library.management.errors import Conflict, NotFound, UsageError
+from library.management.tree import add_tree_arguments, tree_paths
+from library.models import VendorModel
+from library.safe_write import TreeWriter
+from library.scaffold import ScaffoldError, append_to_tree, create_in_database, skeleton_device
+
+
+class Command(LibraryCommand):
+    help = "Create a skeleton device definition (database or YAML tree)"
+
+    def add_arguments(self, parser):
+        parser.add_argument("--vendor", required=True, help="Vendor name (created if missing)")
+        parser.add_argument("--model", required=True, help="Model number")
+        parser.add_argument(
+            "--technology",
+            choices=[t.value for t in VendorModel.Technology],
+            help="Technology (required without --template)",
+        )
+        parser.add_argument(
+            "--type",
+            dest="device_type",
+            choices=[c.value for c in VendorModel.DeviceCategory],
+            help="Device category (required without --template)",
+        )
+        parser.add_argument(
+            "--template",
+            default="",
+            help="Start from this device template (file stem, e.g. modbus-power-meter-3ph) instead of a skeleton",
+        )
+        parser.add_argument("--name", default="", help="Display name (default: model number)")
+        add_tree_arguments(parser, "Append to a YAML devices directory instead of the database")
+        parser.add_argument(
+            "--dry-run",
+            action="store_true",
+            help="With --path, print the diff instead of writing the files",
+        )
+
+    def handle(self, *args, **options):
+        template = None
+        if options["template"]:
+            try:
+                template = load_template(options["template"])
+            except TemplateError as e:
+                raise NotFound(str(e)) from e
+            device = template_device(template, options["vendor"], options["model"], options["name"])
+        elif not (options["technology"] and options["device_type"]):
+            raise UsageError("--technology and --type are required without --template")
+        else:
+            device = skeleton_device(
+                vendor_name=options["vendor"],
+                model_number=options["model"],
+                technology=options["technology"],
+                device_type=options["device_type"],
+                name=options["name"],
+            )
+
+        tree = tree_paths(options, must_exist=False)
+        try:
+            if tree:
+                devices_path, manifest_path = tree
+                writer = TreeWriter(dry_run=options["dry_run"])
+                file_path = append_to_tree(devices_path, manifest_path, device, writer=writer)
+                if options["dry_run"]:
+                    for diff in writer.diffs:
+                        self.stdout.write(diff, ending="")
+                    return
+                self.stdout.write(self.style.SUCCESS(f"Added {options['vendor']} {options['model']} to {file_path}"))
+            else:
+                if template:
+                    vm = create_from_template(template, options["vendor"], options["model"], options["name"])
+                else:
+                    vm = create_in_database(device)
+                self.stdout.write(self.style.SUCCESS(f"Created {vm} ({vm.pk})"))
+        except (ScaffoldError, TemplateError) as e:
+            raise Conflict(str(e)) from e
@@ -0,0 +1,84 @@
+"""Management command to diff two library states.
+
+Each side is either a published ``LibraryVersion`` number or a path to an
+exported YAML tree (a directory with ``manifest.yaml`` + ``devices/``, or
+the ``devices/`` directory itself). To compare git refs, export or check
+them out into worktrees and pass the paths.
+"""
+
+import json
+from pathlib import Path
+
+from library.management.base import LibraryCommand
+from library.management.errors import NotFound
+from library.models import LibraryVersion
+from library.version_diff import compare_snapshot_maps, version_snapshot_map, yaml_snapshot_map
+
+
+class Command(LibraryCommand):
+    help = "Report added/removed/changed devices (with per-register changes) between two library versions"
+
+    def add_arguments(self, parser):
+        parser.add_argument("old", help="Published version number (e.g. 3 or v3) or path to an exported tree")
+        parser.add_argument("new", help="Published version number (e.g. 4 or v4) or path to an exported tree")
+        parser.add_argument(
+            "--format",
+            choices=["text", "json"],
+            default="text",
+            help="Output format",
+        )
+
+    def handle(self, *args, **options):
+        result = compare_snapshot_maps(self._load(options["old"]), self._load(options["new"]))
+
+        if options["format"] == "json":
+            self.stdout.write(json.dumps({
+                "added": [e["label"] for e in result["added"]],
+                "removed": [e["label"] for e in result["removed"]],
+                "modified": [{"label": e["label"], "changes": e["diff"]} for e in result["modified"]],
+                "unchanged_count": result["unchanged_count"],
+            }, indent=2, ensure_ascii=False, default=str))
+            return
+
+        for entry in result["added"]:
+            self.stdout.write(self.style.SUCCESS(f"+ {entry['label']}"))
+        for entry in result["removed"]:
+            self.stdout.write(self.style.ERROR(f"- {entry['label']}"))
+        for entry in result["modified"]:
+            self.stdout.write(self.style.WARNING(f"~ {entry['label']}"))
+            for field, change in sorted(entry["diff"].items()):
+                if field == "registers":
+                    self._write_register_changes(change)
+                else:
+                    self.stdout.write(f"    {field}: {change['old']!r} → {change['new']!r}")
+
+        self.stdout.write(
+            f"\n{len(result['added'])} added, {len(result['removed'])} removed, "
+            f"{len(result['modified'])} modified, {result['unchanged_count']} unchanged"
+        )
+
+    def _write_register_changes(self, change):
+        for reg in change.get("added", []):
+            self.stdout.write(f"    register {reg['address']} added: {reg['field_name']} ({reg['data_type']})")
+        for reg in change.get("removed", []):
+            self.stdout.write(f"    register {reg['address']} removed: {reg['field_name']}")
+        for mod in change.get("modified", []):
+            fields = sorted(k for k in mod["new"] if mod["old"].get(k) != mod["new"].get(k))
+            details = ", ".join(f"{k}: {mod['old'].get(k)!r} → {mod['new'].get(k)!r}" for k in fields)
+            self.stdout.write(f"    register {mod['address']} changed: {details}")
+
+    def _load(self, ref: str) -> dict:
+        number = ref[1:] if ref.startswith("v") else ref
+        if number.isdigit() and not Path(ref).exists():
+            version = LibraryVersion.objects.filter(version=int(number)).first()
+            if version is None:
+                raise NotFound(f"Library version {ref} not found")
+            return version_snapshot_map(version)
+
+        path = Path(ref)
+        if not path.exists():
+            raise NotFound(f"Neither a published version nor an existing path: {ref}")
+        try:
+            return yaml_snapshot_map(path)
+        except FileNotFoundError as e:
+            raise NotFound(str(e)) from e
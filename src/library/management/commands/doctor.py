@@ -0,0 +1,73 @@
+"""Management command to check server and YAML-tree health.
+
+Without ``--path`` only the environment (database, migrations, the
+GitHub token unless ``--skip-github``) is checked. With ``--path`` the exported tree is checked as well — layout,
+manifest ``schema_version`` compatibility, dangling manifest entries,
+orphaned device files and stale ``technologies`` lists. Every problem comes with a suggested fix; exits
+non-zero when any check fails.
+"""
+
+import json
+
+from library.doctor import ENVIRONMENT_CHECKS, ERROR, OK, check_environment, check_github, check_tree
+from library.management.base import LibraryCommand
+from library.management.errors import AuthFailure, EnvironmentFailure, UsageError, ValidationFailed
+from library.management.tree import add_tree_arguments, tree_paths
+
+
+class Command(LibraryCommand):
+    help = "Check environment and YAML tree health, with suggested fixes"
+
+    def add_arguments(self, parser):
+        add_tree_arguments(parser, "Path to the devices/ directory to check")
+        parser.add_argument(
+            "--skip-environment",
+            action="store_true",
+            help="Only check the YAML tree (no database access)",
+        )
+        parser.add_argument(
+            "--skip-github",
+            action="store_true",
+            help="Don't check the GitHub token (for servers that never submit exports)",
+        )
+        parser.add_argument(
+            "--format",
+            choices=["text", "json"],
+            default="text",
+            help="Output format",
+        )
+
+    def handle(self, *args, **options):
+        checks = [] if options["skip_environment"] else check_environment()
+        if not options["skip_environment"] and not options["skip_github"]:
+            checks += check_github()
+        tree = tree_paths(options, must_exist=False)
+        if tree:
+            checks += check_tree(*tree)
+        elif options["skip_environment"]:
+            raise UsageError("Nothing to check: pass --path or drop --skip-environment")
+
+        failed = sum(1 for c in checks if c.status == ERROR)
+
+        if options["format"] == "json":
+            self.stdout.write(json.dumps([c.as_dict() for c in checks], indent=2, ensure_ascii=False))
+        else:
+            for c in checks:
+                if c.status == OK:
+                    self.stdout.write(self.style.SUCCESS(f"[ok]    {c.name}: {c.message}"))
+                    continue
+                style = self.style.ERROR if c.status == ERROR else self.style.WARNING
+                self.stdout.write(style(f"[{c.status}] {c.name}: {c.message}"))
+                if c.fix:
+                    self.stdout.write(f"        fix: {c.fix}")
+
+        if failed:
+            details = [c.as_dict() for c in checks if c.status == ERROR]
+            # A server problem outranks tree problems: the tree may be fine.
+            if any(c["name"] in ENVIRONMENT_CHECKS for c in details):
+                error = EnvironmentFailure
+            elif any(c["name"] == "github" for c in details):
+                error = AuthFailure
+            else:
+                error = ValidationFailed
+            raise error(f"{failed} check(s) failed", details)
@@ -0,0 +1,60 @@
+"""Management command to edit a device in ``$VISUAL`` / ``$EDITOR``.
+
+``edit_device EM340 --section processor_config`` opens the device's
+processor config as YAML in the editor and saves it back when the editor
+exits; without ``--section`` the whole device document is opened. When the
+saved text doesn't parse or validate, the problem is shown and — on a
+terminal — the editor can be reopened on the edited text. See
+``library.external_edit``.
+"""
+
+import shlex
+import sys
+
+from library.external_edit import SECTIONS, EditError, apply_edit, document, run_editor
+from library.management.base import LibraryCommand
+from library.management.errors import InvalidInput, NotFound, UsageError
+from library.models import VendorModel
+
+
+class Command(LibraryCommand):
+    help = "Edit a device (or one of its configs) as YAML in $VISUAL / $EDITOR"
+
+    def add_arguments(self, parser):
+        parser.add_argument("model_number", help="Model number of the device")
+        parser.add_argument("--vendor", default=None, help="Vendor slug, when the model number isn't unique")
+        parser.add_argument("--section", choices=SECTIONS, default=None, help="Edit only this config")
+        parser.add_argument("--editor", default=None, help="Editor command (default: $VISUAL, $EDITOR, vi)")
+
+    def handle(self, *args, **options):
+        devices = VendorModel.objects.select_related("vendor").filter(model_number__iexact=options["model_number"])
+        if options["vendor"]:
+            devices = devices.filter(vendor__slug=options["vendor"])
+        devices = list(devices)
+        if not devices:
+            raise NotFound(f"Device not found: {options['model_number']}")
+        if len(devices) > 1:
+            vendors = ", ".join(sorted(d.vendor.slug for d in devices))
+            raise UsageError(f"{options['model_number']} exists under several vendors ({vendors}); use --vendor")
+        device, section = devices[0], options["section"]
+        command = shlex.split(options["editor"]) if options["editor"] else None
+
+        text = document(device, section)
+        while True:
+            try:
+                text = run_editor(text, command)
+                changed = apply_edit(device, text, section)
+                break
+            except EditError as e:
+                self.stderr.write(self.style.ERROR(str(e)))
+                if not e.text or not sys.stdin.isatty():
+                    raise InvalidInput(str(e)) from e
+                if input("  Reopen the editor? [Y/n] ").strip().lower() in ("n", "no"):
+                    raise InvalidInput(str(e)) from e
+                text = e.text
+
+        what = f"{section} of {device}" if section else str(device)
+        if not changed:
+            self.stdout.write(f"No changes to {what}")
+            return
+        self.stdout.write(self.style.SUCCESS(f"Updated {what}"))
@@ -0,0 +1,30 @@
+"""Management command to export the library as a single JSON bundle."""
+
+from auditlog.helpers import log_command_action
+from auditlog.models import AuditLog
+from library.exporters import export_to_json
+from library.management.base import LibraryCommand
+
+
+class Command(LibraryCommand):
+    help = "Export manifest and all vendor device definitions as one normalized JSON document"
+
+    def add_arguments(self, parser):
+        parser.add_argument(
+            "-o",
+            "--output",
+            required=True,
+            help="Output JSON file path",
+        )
+
+    def handle(self, *args, **options):
+        self.stdout.write(f"Exporting to {options['output']}...")
+
+        _, stats = export_to_json(output_path=options["output"])
+        log_command_action("exported", AuditLog.Category.EXPORT, f"export_json {options['output']}", stats)
+
+        self.stdout.write(self.style.SUCCESS(
+            f"Export complete: "
+            f"{stats['vendors_exported']} vendors, "
+            f"{stats['devices_exported']} devices exported"
+        ))
@@ -0,0 +1,58 @@
+"""Management command to export a Modbus device's register map as CSV."""
+
+from library.exporters import export_registers_csv
+from library.management.base import LibraryCommand
+from library.management.errors import NotFound, UsageError
+from library.models import VendorModel
+
+
+class Command(LibraryCommand):
+    help = "Export a Modbus device's register definitions as CSV (for printing / sharing)"
+
+    def add_arguments(self, parser):
+        parser.add_argument(
+            "--vendor",
+            required=True,
+            help="Vendor slug or name",
+        )
+        parser.add_argument(
+            "--model",
+            required=True,
+            help="Model number",
+        )
+        parser.add_argument(
+            "--format",
+            choices=["csv", "excel"],
+            default="csv",
+            help="'excel' writes a UTF-8 BOM so Excel renders unit symbols (°C, m³) correctly",
+        )
+        parser.add_argument(
+            "-o",
+            "--output",
+            default=None,
+            help="Output file (default: stdout)",
+        )
+
+    def handle(self, *args, **options):
+        device = (
+            VendorModel.objects.select_related("vendor")
+            .filter(model_number=options["model"])
+            .filter(vendor__slug=options["vendor"])
+            .first()
+        ) or (
+            VendorModel.objects.select_related("vendor")
+            .filter(model_number=options["model"], vendor__name__iexact=options["vendor"])
+            .first()
+        )
+        if device is None:
+            raise NotFound(f"Device not found: {options['vendor']} {options['model']}")
+        if device.technology != VendorModel.Technology.MODBUS:
+            raise UsageError(f"{device} is not a Modbus device")
+
+        encoding = "utf-8-sig" if options["format"] == "excel" else "utf-8"
+        if options["output"]:
+            with open(options["output"], "w", newline="", encoding=encoding) as f:
+                count = export_registers_csv(device, f)
+            self.stderr.write(self.style.SUCCESS(f"Exported {count} registers to {options['output']}"))
+        else:
+            export_registers_csv(device, self.stdout)
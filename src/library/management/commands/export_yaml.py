@@ -1,27 +1,282 @@
-"""Management command to export device definitions to YAML files."""
+"""Management command to export device definitions to YAML files.
 
-from django.core.management.base import BaseCommand
+``--preview DIR`` renders the would-be submission (commit content, branch,
+commit message, PR title / body, diffs and validation report — see
+``library.export_preview``) into ``DIR`` without touching the tree;
+``--preview`` alone shows it in a pager, each changed file's diff
+colourized (``--no-color`` turns that off).
 
+Before writing, the export is validated the same way: tree check errors
+and lint errors in the files it would change are listed per file and
+device, and nothing is written unless ``--force`` overrides them (the
+override is recorded in the audit log).
+
+``--submit`` sends the export to the library repository (``--repo``) as a
+pull request instead of writing the tree, after the same validation:
+the files that differ from the repository's default branch (vendor
+files it no longer exports deleted) are committed on the preview's
+branch through the GitHub API (token from ``GITHUB_TOKEN`` / ``GH_TOKEN``). Without push access the
+branch goes to the contributor's fork, created if needed, and the pull
+request is opened from there — see ``library.submit``.
+
+Review rounds go onto the same pull request: ``--list-submissions``
+shows your open export pull requests, ``--checkout PR`` writes one's
+branch into the tree (for ``import_yaml`` to load and edit), and
+``--update PR`` pushes the next export to its branch as another commit.
+"""
+
+import pydoc
+import shutil
+from pathlib import Path
+
+from auditlog.helpers import log_command_action
+from auditlog.models import AuditLog
+from library.export_preview import PreviewError, build_preview
 from library.exporters import export_to_yaml
+from library.management.base import LibraryCommand
+from library.management.errors import (
+    AuthFailure,
+    Conflict,
+    LibraryError,
+    NetworkFailure,
+    NotFound,
+    UsageError,
+    ValidationFailed,
+)
+from library.safe_write import TreeWriter
+from library.submit import (
+    DEFAULT_REPO,
+    SubmitError,
+    Unauthorized,
+    Unreachable,
+    branch_files,
+    compare,
+    open_submissions,
+    submit,
+    update,
+)
+
 
+def _github_failure(error: SubmitError) -> LibraryError:
+    if isinstance(error, Unauthorized):
+        return AuthFailure(str(error))
+    if isinstance(error, Unreachable):
+        return NetworkFailure(str(error))
+    if error.status == 404:
+        return NotFound(str(error))
+    if error.status in (409, 422):  # e.g. the branch exists, or moved since it was read
+        return Conflict(str(error))
+    return LibraryError(str(error))
 
-class Command(BaseCommand):
+
+class Command(LibraryCommand):
     help = "Export device definitions from the database to YAML files"
 
     def add_arguments(self, parser):
         parser.add_argument(
             "--output-dir",
-            required=True,
-            help="Output directory for YAML files",
+            help="Output directory for YAML files (required except with --list-submissions)",
+        )
+        parser.add_argument(
+            "--dry-run",
+            action="store_true",
+            help="Print the diff against the files on disk without writing anything",
+        )
+        parser.add_argument(
+            "--no-backup",
+            action="store_true",
+            help="Don't keep a .bak copy of overwritten files",
+        )
+        parser.add_argument(
+            "--preview",
+            nargs="?",
+            const="-",
+            default=None,
+            metavar="DIR",
+            help="Write branch, commit, PR text, diffs and validation report to DIR (or a pager) instead of exporting",
+        )
+        parser.add_argument(
+            "--force",
+            action="store_true",
+            help="Export even if the changed files have validation errors",
+        )
+        parser.add_argument(
+            "--submit",
+            action="store_true",
+            help="Open a pull request with the export on --repo (through a fork without push access)",
+        )
+        parser.add_argument(
+            "--repo",
+            default=DEFAULT_REPO,
+            help=f"GitHub repository --submit opens the pull request on (default: {DEFAULT_REPO})",
+        )
+        parser.add_argument(
+            "--list-submissions",
+            action="store_true",
+            help="List your open export pull requests on --repo",
+        )
+        parser.add_argument(
+            "--checkout",
+            type=int,
+            metavar="PR",
+            help="Write the exported tree on pull request PR's branch into the output directory",
+        )
+        parser.add_argument(
+            "--update",
+            type=int,
+            metavar="PR",
+            help="Push the export to pull request PR's branch as another commit instead of opening a new one",
         )
 
     def handle(self, *args, **options):
+        if options["list_submissions"]:
+            return self._list_submissions(options)
+        if not options["output_dir"]:
+            raise UsageError("--output-dir is required")
+        if options["checkout"]:
+            return self._checkout(options)
+        if options["submit"] or options["update"]:
+            return self._submit(options)
+        if options["preview"]:
+            return self._preview(options)
         self.stdout.write(f"Exporting to {options['output_dir']}...")
+        overridden = 0 if options["dry_run"] else self._validate(options)
+
+        writer = TreeWriter(dry_run=options["dry_run"], backup=not options["no_backup"])
+        stats = export_to_yaml(output_dir=options["output_dir"], writer=writer)
 
-        stats = export_to_yaml(output_dir=options["output_dir"])
+        if options["dry_run"]:
+            for diff in writer.diffs:
+                self.stdout.write(diff, ending="")
+            self.stdout.write(self.style.WARNING(f"Dry run: {len(writer.changed)} file(s) would change"))
+            return
 
+        self._log_export(f"export_yaml {options['output_dir']}", stats, overridden)
         self.stdout.write(self.style.SUCCESS(
             f"Export complete: "
             f"{stats['vendors_exported']} vendors, "
             f"{stats['devices_exported']} devices exported"
         ))
+
+    def _validate(self, options, preview=None) -> int:
+        """Validate the would-be export; returns the number of errors
+        ``--force`` overrode. Raises ``ValidationFailed`` otherwise."""
+        preview = preview or build_preview(options["output_dir"])
+        if not preview.blocking_count:
+            return 0
+        self.stdout.write(self.style.ERROR(preview.blocking_report))
+        if not options["force"]:
+            raise ValidationFailed(
+                f"{preview.blocking_count} validation error(s) in the changed files, nothing exported "
+                f"(fix them, or pass --force to export anyway)",
+                {path: {device: [f.as_dict() for f in findings] for device, findings in devices.items()}
+                 for path, devices in preview.blocking.items()},
+            )
+        self.stdout.write(self.style.WARNING(f"--force: exporting despite {preview.blocking_count} error(s)"))
+        return preview.blocking_count
+
+    def _log_export(self, target_label, details, overridden):
+        """Usage tracking is opt-in, but an export that ``--force`` pushed
+        past validation errors is always audited."""
+        if not overridden:
+            log_command_action("exported", AuditLog.Category.EXPORT, target_label, details)
+            return
+        AuditLog.objects.create(
+            category=AuditLog.Category.EXPORT,
+            action="exported",
+            target_type="Command",
+            target_label=target_label[:255],
+            details={**details, "validation_errors_overridden": overridden},
+        )
+
+    def _submit(self, options):
+        if options["dry_run"] or options["preview"]:
+            raise UsageError("--submit and --update can't be combined with --dry-run or --preview")
+        number = options["update"]
+        preview = build_preview(options["output_dir"])
+        # What is submitted is what differs from the library repository (or,
+        # for an update, from the pull request's branch) — not from the tree on disk.
+        if not number:
+            try:
+                preview = compare(preview, options["repo"])
+            except SubmitError as e:
+                raise _github_failure(e) from e
+            if not preview.changes:
+                self.stdout.write(self.style.WARNING(f"Nothing to submit: the export matches {options['repo']}."))
+                return
+        overridden = self._validate(options, preview)
+        try:
+            submission = update(preview, number, options["repo"]) if number else submit(preview, options["repo"])
+        except SubmitError as e:
+            raise _github_failure(e) from e
+        if not submission.commit:
+            self.stdout.write(self.style.WARNING(f"Nothing to push: #{number} already has this export."))
+            return
+
+        details = {**preview.stats, "pull_request": submission.url, "pushed_to": submission.repo}
+        command = f"export_yaml --update {number}" if number else "export_yaml --submit"
+        self._log_export(f"{command} {options['repo']}", details, overridden)
+        if number:
+            self.stdout.write(self.style.SUCCESS(
+                f"Pushed {submission.commit[:7]} to {submission.branch} of {submission.repo}: {submission.url}"
+            ))
+            return
+        if submission.forked:
+            self.stdout.write(f"No push access to {options['repo']}; pushed {submission.branch} to {submission.repo}")
+        self.stdout.write(self.style.SUCCESS(f"Pull request #{submission.number} opened: {submission.url}"))
+
+    def _list_submissions(self, options):
+        try:
+            submissions = open_submissions(options["repo"])
+        except SubmitError as e:
+            raise _github_failure(e) from e
+        if not submissions:
+            self.stdout.write(f"No open export pull requests on {options['repo']}.")
+        for submission in submissions:
+            fork = f" (fork {submission.repo})" if submission.forked else ""
+            self.stdout.write(
+                f"#{submission.number}  {submission.title}  {submission.branch}{fork}  {submission.url}"
+            )
+
+    def _checkout(self, options):
+        output_dir = Path(options["output_dir"])
+        try:
+            files = branch_files(options["checkout"], options["repo"], output_dir.name)
+        except SubmitError as e:
+            raise _github_failure(e) from e
+        writer = TreeWriter(dry_run=options["dry_run"], backup=not options["no_backup"])
+        for path, content in files.items():
+            writer.write(output_dir.parent / path, content)
+        if options["dry_run"]:
+            for diff in writer.diffs:
+                self.stdout.write(diff, ending="")
+            self.stdout.write(self.style.WARNING(f"Dry run: {len(writer.changed)} file(s) would change"))
+            return
+        self.stdout.write(self.style.SUCCESS(
+            f"Checked out #{options['checkout']}: {len(writer.changed)} file(s) changed; "
+            f"load them with import_yaml --path {output_dir}"
+        ))
+
+    def _preview(self, options):
+        if options["dry_run"]:
+            raise UsageError("--preview and --dry-run are alternatives; pick one")
+        preview = build_preview(options["output_dir"])
+        summary = (
+            f"{len(preview.changes)} file(s) would change; validation: "
+            f"{preview.errors} error(s), {preview.warnings} warning(s)"
+        )
+        if options["preview"] == "-":
+            color = options["force_color"] or (self.stdout.isatty() and not options["no_color"])
+            if self.stdout.isatty() and shutil.which("less"):
+                pydoc.pipepager(preview.render(color=color), "less -R")
+            elif self.stdout.isatty():
+                pydoc.pager(preview.render())
+            else:
+                self.stdout.write(preview.render(color=color), ending="")
+            self.stderr.write(summary)
+            return
+        try:
+            directory = preview.write(options["preview"])
+        except PreviewError as e:
+            raise Conflict(str(e)) from e
+        self.stdout.write(self.style.SUCCESS(f"Preview written to {directory}: {summary}"))
@@ -0,0 +1,68 @@
+"""Management command to rewrite an exported YAML tree in canonical style.
+
+``--check`` only reports files that would change and exits non-zero,
+for use in CI on repositories that keep the tree under version control;
+``--dry-run`` prints the would-be diff instead. Files are replaced
+atomically with a ``.bak`` of the previous content.
+
+Unquoted values YAML would misread under string keys (``description: no``,
+``wmbus_version: 1.10``) are written back quoted. Files that aren't UTF-8
+or contain control characters are refused with the line and column of
+each problem.
+"""
+
+from library.management.base import LibraryCommand
+from library.management.errors import InvalidInput, ValidationFailed
+from library.management.tree import add_tree_arguments, tree_paths
+from library.safe_write import TreeWriter
+from library.yaml_content import YAMLContentError
+from library.yaml_format import format_tree
+
+
+class Command(LibraryCommand):
+    help = "Canonically format manifest.yaml and all vendor device files"
+
+    def add_arguments(self, parser):
+        add_tree_arguments(parser, "Path to the devices/ directory containing YAML files", required=True)
+        parser.add_argument(
+            "--check",
+            action="store_true",
+            help="Don't write; fail if any file is not canonically formatted",
+        )
+        parser.add_argument(
+            "--dry-run",
+            action="store_true",
+            help="Don't write; print the diff of every file that would be reformatted",
+        )
+        parser.add_argument(
+            "--no-backup",
+            action="store_true",
+            help="Don't keep a .bak copy of reformatted files",
+        )
+
+    def handle(self, *args, **options):
+        devices_path, manifest_path = tree_paths(options)
+
+        dry_run = options["check"] or options["dry_run"]
+        writer = TreeWriter(dry_run=dry_run, backup=not options["no_backup"])
+        try:
+            changed = format_tree(devices_path, manifest_path, writer=writer)
+        except YAMLContentError as e:
+            raise InvalidInput(
+                f"Can't format {e}",
+                [{"file": str(e.path), "line": p.line, "column": p.column, "message": p.message} for p in e.problems],
+            ) from e
+
+        if options["dry_run"]:
+            for diff in writer.diffs:
+                self.stdout.write(diff, ending="")
+        for path in changed:
+            self.stdout.write(f"{'would reformat' if dry_run else 'reformatted'} {path}")
+
+        if options["check"] and changed:
+            raise ValidationFailed(f"{len(changed)} file(s) not canonically formatted", [str(p) for p in changed])
+        if options["dry_run"] and changed:
+            return
+        self.stdout.write(self.style.SUCCESS(
+            f"{len(changed)} file(s) reformatted" if changed else "All files canonically formatted"
+        ))
@@ -1,39 +1,68 @@
-"""Management command to import device definitions from YAML files."""
+"""Management command to import device definitions from YAML files.
 
-from django.core.management.base import BaseCommand
+A missing manifest, or one out of step with ``devices/`` (common on forks
+and work-in-progress branches), doesn't stop the import: the vendor list
+is rebuilt from the files on disk, what was patched up is printed, and the
+rebuilt manifest is offered for writing (``--write-manifest`` writes it
+without asking).
+"""
 
+import sys
+
+from library.doctor import write_manifest
 from library.importers import import_from_yaml
+from library.management.base import LibraryCommand
+from library.management.errors import NotFound
+from library.management.tree import add_tree_arguments, tree_paths
+from library.safe_write import TreeWriter
 
 
-class Command(BaseCommand):
+class Command(LibraryCommand):
     help = "Import device definitions from YAML files into the database"
 
     def add_arguments(self, parser):
+        add_tree_arguments(parser, "Path to the devices/ directory containing YAML files", required=True)
         parser.add_argument(
-            "--path",
-            required=True,
-            help="Path to the devices/ directory containing YAML files",
+            "--clear",
+            action="store_true",
+            help="Clear existing vendors and devices before importing",
         )
         parser.add_argument(
-            "--manifest",
-            required=True,
-            help="Path to manifest.yaml",
+            "--vendors",
+            default=None,
+            help="Comma-separated vendor slugs/names to import; other manifest vendors are skipped",
         )
         parser.add_argument(
-            "--clear",
+            "--strict",
             action="store_true",
-            help="Clear existing vendors and devices before importing",
+            help="Reject devices with unknown keys (typos) instead of ignoring those keys",
+        )
+        parser.add_argument(
+            "--write-manifest",
+            action="store_true",
+            help="Write the rebuilt manifest when the existing one was missing or incomplete",
         )
 
     def handle(self, *args, **options):
-        self.stdout.write(f"Importing from {options['path']}...")
+        devices_path, manifest_path = tree_paths(options, must_exist=False)
+        if not devices_path.is_dir():
+            raise NotFound(f"Devices directory not found: {devices_path}")
+        self.stdout.write(f"Importing from {devices_path}...")
 
         stats = import_from_yaml(
-            devices_path=options["path"],
-            manifest_path=options["manifest"],
+            devices_path=devices_path,
+            manifest_path=manifest_path,
             clear=options["clear"],
+            vendors=options["vendors"].split(",") if options["vendors"] else None,
+            strict=options["strict"],
+            recover=True,
         )
 
+        if stats.get("recovery"):
+            self.stdout.write(self.style.WARNING(f"Manifest {manifest_path} is missing or incomplete; recovered:"))
+            for note in stats["recovery"]:
+                self.stdout.write(self.style.WARNING(f"  - {note}"))
+
         self.stdout.write(self.style.SUCCESS(
             f"Import complete: "
             f"{stats['vendors_created']} vendors created, "
@@ -42,7 +71,26 @@ class Command(BaseCommand):
             f"{stats['devices_updated']} devices updated"
         ))
 
+        if stats["vendors_skipped"]:
+            self.stdout.write(
+                f"{len(stats['vendors_skipped'])} vendors not loaded: {', '.join(stats['vendors_skipped'])}"
+            )
+
         if stats["errors"]:
             self.stdout.write(self.style.WARNING(f"\n{len(stats['errors'])} errors:"))
             for error in stats["errors"]:
                 self.stdout.write(self.style.ERROR(f"  - {error}"))
+
+        if stats.get("recovery"):
+            self._offer_manifest(manifest_path, stats["recovered_manifest"], options["write_manifest"])
+
+    def _offer_manifest(self, manifest_path, manifest, write):
+        if not write:
+            if not sys.stdin.isatty():
+                self.stdout.write(f"Re-run with --write-manifest to save the rebuilt {manifest_path}")
+                return
+            answer = input(f"  Write the rebuilt manifest to {manifest_path}? [y/N] ").strip().lower()
+            if answer not in ("y", "yes"):
+                return
+        write_manifest(manifest_path, manifest, TreeWriter())
+        self.stdout.write(self.style.SUCCESS(f"Wrote {manifest_path}"))
@@ -0,0 +1,141 @@
+"""Management command to lint device definitions against a configurable rule set.
+
+Lints the database by default; ``--path``/``--manifest`` lint an exported
+YAML tree instead, so vendors can run the same checks in CI against their
+own repository — including the manifest and vendor files themselves
+against the shipped JSON Schemas (``library.schema``). Rule selection and
+options come from ``.sparklint.yaml`` (see ``library.lint``). Rule plugins
+run only when asked for: ``--plugin-dir`` loads a directory of them, and
+``--load-plugins`` the ones an explicit ``--config`` file lists.
+``--check-links`` also requests every documentation link (the opt-in
+``link-unreachable`` rule). Exits non-zero when any error-severity
+finding is reported.
+"""
+
+import json
+
+from auditlog.helpers import log_command_action
+from auditlog.models import AuditLog
+from library.lint import (
+    IGNORE,
+    RULES,
+    SEVERITIES,
+    LintConfig,
+    devices_from_database,
+    devices_from_yaml,
+    lint_devices,
+    load_plugin_dir,
+    tree_schema_findings,
+)
+from library.management.base import LibraryCommand
+from library.management.errors import InvalidInput, UsageError, ValidationFailed
+from library.management.tree import add_tree_arguments, tree_paths
+
+
+class Command(LibraryCommand):
+    help = "Lint device definitions (database or YAML tree) against the configured rule set"
+
+    def add_arguments(self, parser):
+        parser.add_argument(
+            "--config",
+            default=None,
+            help="Path to the lint config (default: settings.SPARKLINT_CONFIG when present)",
+        )
+        add_tree_arguments(parser, "Lint a YAML devices directory instead of the database")
+        parser.add_argument(
+            "--vendor",
+            default=None,
+            help="Limit database lint to one vendor slug",
+        )
+        parser.add_argument(
+            "--format",
+            choices=["text", "json"],
+            default="text",
+            help="Output format",
+        )
+        parser.add_argument(
+            "--plugin-dir",
+            default=None,
+            help="Load every *.py rule plugin in this directory before linting",
+        )
+        parser.add_argument(
+            "--load-plugins",
+            action="store_true",
+            help="Run the rule plugins the --config file lists (plugins are Python code; trusted configs only)",
+        )
+        parser.add_argument(
+            "--check-links",
+            action="store_true",
+            help="Check that every documentation link resolves (makes one HTTP request per URL)",
+        )
+        parser.add_argument(
+            "--list-rules",
+            action="store_true",
+            help="List available rules and exit",
+        )
+
+    def handle(self, *args, **options):
+        try:
+            if options["plugin_dir"]:
+                load_plugin_dir(options["plugin_dir"])
+            if options["load_plugins"] and not options["config"]:
+                raise UsageError("--load-plugins needs --config naming the (trusted) config file")
+            config = LintConfig.load(options["config"], load_plugins=options["load_plugins"])
+        except (FileNotFoundError, ValueError) as e:
+            raise InvalidInput(str(e)) from e
+        if options["check_links"]:
+            settings = config.rules.get("link-unreachable")
+            if isinstance(settings, str):
+                settings = {} if settings == IGNORE else {"severity": settings}
+            config.rules["link-unreachable"] = {**settings, "enabled": True} if isinstance(settings, dict) else True
+
+        if options["list_rules"]:
+            # Severity as configured for this repository; "off" when disabled.
+            for r in RULES.values():
+                severity = config.severity(r.id) if config.is_enabled(r.id) else "off"
+                self.stdout.write(f"{r.id:<26} {severity:<8} {r.description}")
+            return
+
+        tree = tree_paths(options)
+        if tree:
+            devices = devices_from_yaml(*tree)
+        else:
+            devices = devices_from_database(vendor_slug=options["vendor"])
+
+        try:
+            findings = lint_devices(devices, config)
+        except ValueError as e:
+            raise InvalidInput(str(e)) from e
+        if tree:
+            findings = sorted(
+                tree_schema_findings(*tree, config) + findings, key=lambda f: SEVERITIES.index(f.severity),
+            )
+
+        errors = sum(1 for f in findings if f.severity == "error")
+        warnings = len(findings) - errors
+        log_command_action("validated", AuditLog.Category.VALIDATION, "lint_library", {
+            "source": str(tree[0]) if tree else "database",
+            "devices": [[d.data.get("vendor_name", ""), d.data.get("model_number", "")] for d in devices],
+            "errors": errors,
+            "warnings": warnings,
+        })
+
+        if options["format"] == "json":
+            self.stdout.write(json.dumps({
+                "devices_checked": len(devices),
+                "errors": errors,
+                "warnings": warnings,
+                "findings": [f.as_dict() for f in findings],
+            }, indent=2, ensure_ascii=False))
+        else:
+            for f in findings:
+                style = self.style.ERROR if f.severity == "error" else self.style.WARNING
+                location = f"{f.source}: " if f.source else ""
+                path = f" [{f.path}]" if f.path else ""
+                self.stdout.write(style(f"{f.severity.upper():<7} {f.rule:<24} {location}{f.device}{path}: {f.message}"))
+
+            summary = f"Checked {len(devices)} devices: {errors} errors, {warnings} warnings"
+            self.stdout.write(self.style.SUCCESS(summary) if not findings else summary)
+
+        if errors:
+            raise ValidationFailed(f"Lint failed with {errors} error(s)", {"errors": errors, "warnings": warnings})
@@ -0,0 +1,85 @@
+"""Management command to enumerate devices for scripts and support tooling."""
+
+import json
+
+import yaml
+
+from library.management.base import LibraryCommand
+from library.models import VendorModel
+
+
+COLUMNS = ("vendor", "model_number", "name", "device_type", "technology", "controllable")
+
+
+class Command(LibraryCommand):
+    help = "List devices with optional filters as a table, JSON or YAML"
+
+    def add_arguments(self, parser):
+        parser.add_argument("--vendor", default=None, help="Vendor slug")
+        parser.add_argument(
+            "--technology",
+            choices=[t.value for t in VendorModel.Technology],
+            default=None,
+        )
+        parser.add_argument(
+            "--type",
+            dest="device_type",
+            choices=[c.value for c in VendorModel.DeviceCategory],
+            default=None,
+        )
+        parser.add_argument(
+            "--controllable",
+            action="store_true",
+            help="Only devices with controllable=true",
+        )
+        parser.add_argument(
+            "--format",
+            choices=["table", "json", "yaml"],
+            default="table",
+        )
+
+    def handle(self, *args, **options):
+        qs = VendorModel.objects.select_related("vendor", "control_config").order_by("vendor__name", "model_number")
+        if options["vendor"]:
+            qs = qs.filter(vendor__slug=options["vendor"])
+        if options["technology"]:
+            qs = qs.filter(technology=options["technology"])
+        if options["device_type"]:
+            qs = qs.filter(device_type=options["device_type"])
+        if options["controllable"]:
+            qs = qs.filter(control_config__controllable=True)
+
+        rows = [
+            {
+                "key": str(device.key),
+                "vendor": device.vendor.name,
+                "model_number": device.model_number,
+                "name": device.name,
+                "device_type": device.device_type,
+                "technology": device.technology,
+                "controllable": _controllable(device),
+            }
+            for device in qs
+        ]
+
+        if options["format"] == "json":
+            self.stdout.write(json.dumps(rows, indent=2, ensure_ascii=False))
+        elif options["format"] == "yaml":
+            self.stdout.write(yaml.dump(rows, default_flow_style=False, sort_keys=False, allow_unicode=True))
+        else:
+            self._write_table(rows)
+
+    def _write_table(self, rows):
+        cells = [[str(row[c]).lower() if c == "controllable" else str(row[c]) for c in COLUMNS] for row in rows]
+        widths = [max([len(c)] + [len(r[i]) for r in cells]) for i, c in enumerate(COLUMNS)]
+        self.stdout.write("  ".join(c.upper().ljust(w) for c, w in zip(COLUMNS, widths, strict=True)))
+        for r in cells:
+            self.stdout.write("  ".join(v.ljust(w) for v, w in zip(r, widths, strict=True)).rstrip())
+        self.stdout.write(f"\n{len(rows)} devices")
+
+
+def _controllable(device: VendorModel) -> bool:
+    try:
+        return device.control_config.controllable
+    except VendorModel.control_config.RelatedObjectDoesNotExist:
+        return False
@@ -0,0 +1,44 @@
+"""Management command to merge several files of one vendor into one.
+
+The reverse of ``split_vendor``: devices from all given files are written
+to the first file (or ``--into``), the other files and their manifest
+entries are removed. Duplicate model numbers abort the merge. Devices
+are moved verbatim, comments included.
+"""
+
+from library.management.base import LibraryCommand
+from library.management.errors import Conflict, NotFound, UsageError
+from library.management.tree import add_tree_arguments, tree_paths
+from library.safe_write import TreeWriter
+from library.vendor_files import VendorFileConflict, VendorFileError, VendorFileNotFound, merge_vendor_files
+
+
+class Command(LibraryCommand):
+    help = "Merge several YAML files of the same vendor into one"
+
+    def add_arguments(self, parser):
+        parser.add_argument("files", nargs="+", help="Vendor files as listed in the manifest")
+        parser.add_argument("--into", default=None, help="Target file name (default: the first file)")
+        add_tree_arguments(parser, "Path to the YAML devices directory", required=True)
+        parser.add_argument(
+            "--dry-run",
+            action="store_true",
+            help="Print the diff instead of writing the files",
+        )
+
+    def handle(self, *args, **options):
+        writer = TreeWriter(dry_run=options["dry_run"])
+        try:
+            target, count = merge_vendor_files(*tree_paths(options), options["files"], options["into"], writer)
+        except VendorFileConflict as e:
+            raise Conflict(str(e)) from e
+        except VendorFileNotFound as e:
+            raise NotFound(str(e)) from e
+        except VendorFileError as e:
+            raise UsageError(str(e)) from e
+
+        if options["dry_run"]:
+            for diff in writer.diffs:
+                self.stdout.write(diff, ending="")
+            return
+        self.stdout.write(self.style.SUCCESS(f"Merged {len(options['files'])} file(s) into {target} ({count} device(s))"))
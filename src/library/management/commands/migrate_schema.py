@@ -0,0 +1,88 @@
+"""Management command to upgrade an exported YAML tree to a newer schema_version.
+
+Runs the ordered steps in ``library.schema_migrations`` from the
+manifest's ``schema_version`` up to ``--to`` (default: the latest) and
+rewrites the manifest and vendor files in place, atomically and with a
+``.bak`` of each replaced file. ``--dry-run`` prints the diff instead;
+``--list`` shows the available steps.
+"""
+
+from library.management.base import LibraryCommand
+from library.management.errors import InvalidInput, UsageError
+from library.management.tree import add_tree_arguments, tree_paths
+from library.safe_write import TreeWriter
+from library.schema_migrations import LATEST_SCHEMA_VERSION, MIGRATIONS, SchemaMigrationError, migrate_tree
+from library.yaml_content import YAMLContentError
+
+
+class Command(LibraryCommand):
+    help = "Upgrade manifest.yaml and all vendor device files to a newer schema_version"
+
+    def add_arguments(self, parser):
+        add_tree_arguments(parser, "Path to the devices/ directory containing YAML files")
+        parser.add_argument(
+            "--to",
+            type=int,
+            default=LATEST_SCHEMA_VERSION,
+            help=f"Target schema_version (default: {LATEST_SCHEMA_VERSION})",
+        )
+        parser.add_argument(
+            "--from",
+            dest="from_version",
+            type=int,
+            default=None,
+            help="schema_version the tree is at, when its manifest doesn't say",
+        )
+        parser.add_argument(
+            "--dry-run",
+            action="store_true",
+            help="Don't write; print the diff of every file that would change",
+        )
+        parser.add_argument(
+            "--no-backup",
+            action="store_true",
+            help="Don't keep a .bak copy of migrated files",
+        )
+        parser.add_argument(
+            "--list",
+            action="store_true",
+            help="List the available migrations and exit",
+        )
+
+    def handle(self, *args, **options):
+        if options["list"]:
+            for migration in MIGRATIONS:
+                self.stdout.write(f"{migration.version - 1} → {migration.version}  {migration.description}")
+            return
+        paths = tree_paths(options)
+        if paths is None:
+            raise UsageError("--path is required")
+        devices_path, manifest_path = paths
+
+        writer = TreeWriter(dry_run=options["dry_run"], backup=not options["no_backup"])
+        try:
+            result = migrate_tree(
+                devices_path, manifest_path, options["to"], from_version=options["from_version"], writer=writer,
+            )
+        except SchemaMigrationError as e:
+            raise UsageError(str(e)) from e
+        except YAMLContentError as e:
+            raise InvalidInput(f"Can't migrate {e}") from e
+
+        for note in result.notes:
+            self.stdout.write(f"  {note}")
+        if options["dry_run"]:
+            for diff in writer.diffs:
+                self.stdout.write(diff, ending="")
+            self.stdout.write(self.style.WARNING(
+                f"Dry run: {len(result.changed)} file(s) would change "
+                f"(schema_version {result.from_version} → {result.to_version})"
+            ))
+            return
+        if not result.applied and not result.changed:
+            self.stdout.write(self.style.SUCCESS(f"Tree is already at schema_version {result.to_version}"))
+            return
+        self.stdout.write(self.style.SUCCESS(
+            f"Migrated schema_version {result.from_version} → {result.to_version}: "
+            f"{len(result.applied)} step(s), {len(result.changed)} file(s) rewritten"
+        ))
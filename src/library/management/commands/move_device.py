@@ -0,0 +1,116 @@
+"""Management command to file a device under another vendor.
+
+``move_device EM340 --to carlo-gavazzi`` moves the device in the database;
+with ``--path`` it moves the device's block from one vendor file into the
+other in the exported tree (``vendor_name`` rewritten, both files and the
+manifest's technology lists updated). ``--copy`` keeps the original and
+adds an independent copy under the target vendor. Without ``--to`` the
+target is picked from a numbered vendor list.
+"""
+
+import sys
+
+import yaml
+from django.utils.text import slugify
+
+from library.duplicate import DuplicateConflict, move_to_vendor
+from library.management.base import LibraryCommand
+from library.management.errors import Conflict, NotFound, UsageError
+from library.management.tree import add_tree_arguments, tree_paths
+from library.models import Vendor, VendorModel
+from library.safe_write import TreeWriter
+from library.vendor_files import VendorFileConflict, VendorFileError, VendorFileNotFound, move_device
+
+
+class Command(LibraryCommand):
+    help = "Move (or copy) a device to another vendor (database, or vendor files with --path)"
+
+    def add_arguments(self, parser):
+        parser.add_argument("model_number", help="Model number of the device")
+        parser.add_argument("--to", default=None, help="Target vendor name or slug (default: pick from a list)")
+        parser.add_argument(
+            "--from",
+            dest="from_vendor",
+            default=None,
+            help="Current vendor, when the model number exists under several",
+        )
+        parser.add_argument("--copy", action="store_true", help="Copy the device instead of moving it")
+        add_tree_arguments(parser, "Move within a YAML devices directory instead of the database")
+        parser.add_argument(
+            "--dry-run",
+            action="store_true",
+            help="With --path, print the diff instead of writing the files",
+        )
+
+    def handle(self, *args, **options):
+        tree = tree_paths(options)
+        if not tree and options["dry_run"]:
+            raise UsageError("--dry-run only applies together with --path")
+        verb = "Copied" if options["copy"] else "Moved"
+
+        if tree:
+            manifest = yaml.safe_load(tree[1].read_text()) or {}
+            names = list(dict.fromkeys(v["name"] for v in manifest.get("vendors") or []))
+            target = options["to"] or self._pick_vendor(names)
+            writer = TreeWriter(dry_run=options["dry_run"])
+            try:
+                source_file, target_file = move_device(
+                    *tree, options["model_number"], target, options["from_vendor"], options["copy"], writer,
+                )
+            except VendorFileConflict as e:
+                raise Conflict(str(e)) from e
+            except VendorFileNotFound as e:
+                raise NotFound(str(e)) from e
+            except VendorFileError as e:
+                raise UsageError(str(e)) from e
+            if options["dry_run"]:
+                for diff in writer.diffs:
+                    self.stdout.write(diff, ending="")
+                return
+            self.stdout.write(self.style.SUCCESS(
+                f"{verb} {options['model_number']}: {source_file} → {target_file}"
+            ))
+            return
+
+        device = self._find_device(options["model_number"], options["from_vendor"])
+        target = options["to"] or self._pick_vendor(list(Vendor.objects.values_list("name", flat=True)))
+        vendor = _find_vendor(target)
+        if vendor is None:
+            raise NotFound(f"Vendor not found: {target}")
+        if vendor == device.vendor:
+            raise UsageError(f"{device.model_number} is already filed under {vendor.name}")
+        source = device.vendor.name
+        try:
+            moved = move_to_vendor(device, vendor, copy=options["copy"])
+        except DuplicateConflict as e:
+            raise Conflict(str(e)) from e
+        self.stdout.write(self.style.SUCCESS(f"{verb} {moved.model_number}: {source} → {vendor.name}"))
+
+    def _find_device(self, model_number, from_vendor):
+        devices = VendorModel.objects.select_related("vendor").filter(model_number__iexact=model_number)
+        if from_vendor:
+            vendor = _find_vendor(from_vendor)
+            if vendor is None:
+                raise NotFound(f"Vendor not found: {from_vendor}")
+            devices = devices.filter(vendor=vendor)
+        devices = list(devices)
+        if not devices:
+            raise NotFound(f"Device not found: {model_number}")
+        if len(devices) > 1:
+            vendors = ", ".join(sorted(d.vendor.name for d in devices))
+            raise UsageError(f"{model_number} exists under several vendors ({vendors}); say which with --from")
+        return devices[0]
+
+    def _pick_vendor(self, names):
+        if not sys.stdin.isatty():
+            raise UsageError("--to is required when not running interactively")
+        for number, name in enumerate(names, 1):
+            self.stdout.write(f"  {number:>3}  {name}")
+        answer = input(f"Target vendor [1-{len(names)}]: ").strip()
+        if not answer.isdigit() or not 1 <= int(answer) <= len(names):
+            raise UsageError(f"No such vendor: {answer!r}")
+        return names[int(answer) - 1]
+
+
+def _find_vendor(name):
+    return Vendor.objects.filter(slug=slugify(name)).first() or Vendor.objects.filter(name=name).first()
@@ -0,0 +1,46 @@
+"""Management command to run a jq-style query over the merged library.
+
+    manage.py query_library '.models[] | select(.technology_config.technology == "wmbus") | .model_number'
+
+The query runs against the ``export_json`` bundle plus a flat ``models``
+list (see ``library.query``), from the database or, with ``--path``, from
+an exported YAML tree. Each result is printed as JSON, one per line with
+``--compact``; ``--raw`` prints strings without quotes.
+"""
+
+import json
+
+from library.management.base import LibraryCommand
+from library.management.errors import InvalidInput
+from library.management.tree import add_tree_arguments, tree_paths
+from library.query import QueryError, compile_query, library_document, run_query
+
+
+class Command(LibraryCommand):
+    help = "Query the device library with a jq-style expression"
+
+    def add_arguments(self, parser):
+        parser.add_argument("expression", help="jq-style filter, e.g. '.models[] | .model_number'")
+        add_tree_arguments(parser, "Query a YAML devices directory instead of the database")
+        parser.add_argument("-r", "--raw", action="store_true", help="Print string results without JSON quotes")
+        parser.add_argument("-c", "--compact", action="store_true", help="One line per result")
+
+    def handle(self, *args, **options):
+        try:
+            compile_query(options["expression"])
+        except QueryError as e:
+            raise InvalidInput(f"Invalid query: {e}") from e
+
+        tree = tree_paths(options)
+        document = library_document(*tree) if tree else library_document()
+        try:
+            results = run_query(options["expression"], document)
+        except QueryError as e:
+            raise InvalidInput(str(e)) from e
+
+        indent = None if options["compact"] else 2
+        for result in results:
+            if options["raw"] and isinstance(result, str):
+                self.stdout.write(result)
+            else:
+                self.stdout.write(json.dumps(result, indent=indent, ensure_ascii=False, default=str))
@@ -0,0 +1,59 @@
+"""Management command to rename a vendor everywhere it is referenced.
+
+In the database this renames the vendor row (name and slug). With
+``--path`` it rewrites the exported tree: manifest entry, vendor file name
+and every ``vendor_name`` inside it; ``--alias`` keeps the old name as a
+deprecation alias on the manifest entry.
+"""
+
+from library.management.base import LibraryCommand
+from library.management.errors import Conflict, NotFound, UsageError
+from library.management.tree import add_tree_arguments, tree_paths
+from library.rename import RenameConflict, RenameError, rename_vendor_in_database, rename_vendor_in_tree
+from library.safe_write import TreeWriter
+
+
+class Command(LibraryCommand):
+    help = "Rename a vendor consistently (database, or manifest + device files)"
+
+    def add_arguments(self, parser):
+        parser.add_argument("old", help="Current vendor name or slug")
+        parser.add_argument("new", help="New vendor name")
+        add_tree_arguments(parser, "Rename in a YAML devices directory instead of the database")
+        parser.add_argument(
+            "--alias",
+            action="store_true",
+            help="With --path, keep the old name as an alias on the manifest entry",
+        )
+        parser.add_argument(
+            "--dry-run",
+            action="store_true",
+            help="With --path, print the diff instead of writing the files",
+        )
+
+    def handle(self, *args, **options):
+        tree = tree_paths(options)
+        if not tree and (options["alias"] or options["dry_run"]):
+            raise UsageError("--alias and --dry-run only apply together with --path")
+
+        try:
+            if tree:
+                writer = TreeWriter(dry_run=options["dry_run"])
+                result = rename_vendor_in_tree(*tree, options["old"], options["new"], options["alias"], writer)
+                if options["dry_run"]:
+                    for diff in writer.diffs:
+                        self.stdout.write(diff, ending="")
+                    return
+                self.stdout.write(self.style.SUCCESS(
+                    f"Renamed {options['old']} → {options['new']}: {result['old_file']} → {result['new_file']}, "
+                    f"{result['devices']} device(s) updated"
+                ))
+            else:
+                vendor = rename_vendor_in_database(options["old"], options["new"])
+                self.stdout.write(self.style.SUCCESS(
+                    f"Renamed vendor to {vendor.name} ({vendor.slug}), {vendor.device_types.count()} device(s)"
+                ))
+        except RenameConflict as e:
+            raise Conflict(str(e)) from e
+        except RenameError as e:
+            raise NotFound(str(e)) from e
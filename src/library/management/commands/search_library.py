@@ -0,0 +1,53 @@
+"""Management command for full-text search across the device library."""
+
+from django.urls import reverse
+
+from library.management.base import LibraryCommand
+from library.management.errors import UsageError
+from library.management.tree import add_tree_arguments, tree_paths
+from library.models import VendorModel
+from library.search import search_queryset, search_terms, search_yaml
+
+
+class Command(LibraryCommand):
+    help = "Search vendor names, device names, model numbers, descriptions and register field names"
+
+    def add_arguments(self, parser):
+        parser.add_argument("query", help="Search terms (all must match)")
+        parser.add_argument(
+            "--technology",
+            choices=[t.value for t in VendorModel.Technology],
+            default=None,
+            help="Restrict to one technology",
+        )
+        add_tree_arguments(parser, "Search a YAML devices directory (reports file:line) instead of the database")
+
+    def handle(self, *args, **options):
+        if not search_terms(options["query"]):
+            raise UsageError("Empty search query")
+
+        tree = tree_paths(options)
+        if tree:
+            self._search_yaml(options, *tree)
+        else:
+            self._search_database(options)
+
+    def _search_yaml(self, options, devices_path, manifest_path):
+        hits = search_yaml(devices_path, manifest_path, options["query"], options["technology"])
+        for hit in hits:
+            self.stdout.write(f"{hit.location}: {hit.device}: {hit.field}: {hit.value}")
+        devices = len({(hit.file, hit.device) for hit in hits})
+        self.stdout.write(self.style.SUCCESS(f"{devices} devices matched ({len(hits)} fields)"))
+
+    def _search_database(self, options):
+        qs = VendorModel.objects.select_related("vendor")
+        if options["technology"]:
+            qs = qs.filter(technology=options["technology"])
+        qs = search_queryset(qs, options["query"]).order_by("vendor__name", "model_number")
+
+        for device in qs:
+            self.stdout.write(
+                f"{device.vendor.name} {device.model_number}: {device.name} "
+                f"[{device.technology}] {reverse('library:model-detail', args=[device.pk])}"
+            )
+        self.stdout.write(self.style.SUCCESS(f"{qs.count()} devices matched"))
@@ -22,11 +22,12 @@ import json
 import re
 from pathlib import Path
 
-from django.core.management.base import BaseCommand, CommandError
-
 from library.history import record_history, snapshot_device
+from library.management.base import LibraryCommand
+from library.management.errors import InvalidInput
 from library.models import AlarmConfig, DeviceHistory, VendorModel
 
+
 DATA_DIR = Path(__file__).resolve().parents[2] / "data"
 
 _XMQ_DRIVER_RE = re.compile(r"driver\s*\{\s*name\s*=\s*(\w+)")
@@ -101,7 +102,7 @@ def extract_driver_flags(wmbusmeters_path: Path) -> dict[str, dict[str, set[str]
     return result
 
 
-class Command(BaseCommand):
+class Command(LibraryCommand):
     help = "Seed AlarmConfig.mappings for wM-Bus models from wmbusmeters drivers + curated severities."
 
     def add_arguments(self, parser):
@@ -128,7 +129,7 @@ class Command(BaseCommand):
         if options["wmbusmeters"]:
             root = Path(options["wmbusmeters"]).expanduser()
             if not (root / "src").is_dir():
-                raise CommandError(f"{root} does not look like a wmbusmeters checkout")
+                raise InvalidInput(f"{root} does not look like a wmbusmeters checkout")
             extracted = extract_driver_flags(root)
             self.stdout.write(
                 f"Extracted flags for {len(extracted)} drivers from {root}"
@@ -0,0 +1,154 @@
+"""Management command to maintain the register snippets library.
+
+Snippets are reusable register blocks kept as YAML under ``snippets/``
+(see ``library.snippets``)::
+
+    manage.py snippets list
+    manage.py snippets show heat-meter
+    manage.py snippets create acme-pm-block --vendor acme --model PM-1 --start 0 --end 40 --name "Acme PM block"
+    manage.py snippets insert three-phase-electricity --vendor acme --model PM-2 --base-address 100
+    manage.py snippets delete acme-pm-block
+    manage.py snippets check
+
+``create`` copies a device's registers (rebased to address 0) into a new
+snippet; ``check`` validates every file and fails CI on a broken one.
+"""
+
+import json
+from pathlib import Path
+
+from django.conf import settings
+
+from library.management.base import LibraryCommand
+from library.management.errors import Conflict, InvalidInput, NotFound, UsageError, ValidationFailed
+from library.models import VendorModel
+from library.snippets import (
+    SNIPPET_SUFFIX,
+    SnippetError,
+    delete_snippet,
+    insert_snippet,
+    load_snippet,
+    load_snippets,
+    save_snippet,
+    snippet_from_device,
+)
+from library.yaml_format import dump_yaml
+
+ACTIONS = ("list", "show", "create", "insert", "delete", "check")
+
+
+class Command(LibraryCommand):
+    help = "List, show, create, insert, delete and check register snippets (snippets/*.yaml)"
+
+    def add_arguments(self, parser):
+        parser.add_argument("action", choices=ACTIONS, help="What to do")
+        parser.add_argument("key", nargs="?", help="Snippet key (file name without .yaml)")
+        parser.add_argument("--dir", default=None, help="Snippets directory (default: settings.SNIPPETS_DIR)")
+        parser.add_argument("--vendor", help="Vendor slug or name (create / insert)")
+        parser.add_argument("--model", help="Model number (create / insert)")
+        parser.add_argument("--start", type=int, default=None, help="create: first register address to copy")
+        parser.add_argument("--end", type=int, default=None, help="create: last register address to copy")
+        parser.add_argument("--name", help="create: human-readable snippet name")
+        parser.add_argument("--description", default="", help="create: what the block contains")
+        parser.add_argument("--force", action="store_true", help="create: overwrite an existing snippet")
+        parser.add_argument("--base-address", type=int, default=None, help="insert: address of the first register")
+        parser.add_argument("--format", choices=["text", "json"], default="text", help="Output format (list / show)")
+
+    def handle(self, *args, **options):
+        directory = Path(options["dir"] or settings.SNIPPETS_DIR)
+        action = options["action"]
+        if action in ("show", "create", "insert", "delete") and not options["key"]:
+            raise UsageError(f"snippets {action} needs a snippet key")
+        getattr(self, f"_{action}")(directory, options)
+
+    def _load(self, directory: Path, key: str):
+        if not (directory / f"{key}{SNIPPET_SUFFIX}").is_file():
+            raise NotFound(f"No snippet {key!r} in {directory}")
+        try:
+            return load_snippet(key, directory)
+        except SnippetError as e:
+            raise InvalidInput(str(e)) from e
+
+    def _device(self, options) -> VendorModel:
+        if not options["vendor"] or not options["model"]:
+            raise UsageError("--vendor and --model are required")
+        devices = VendorModel.objects.select_related("vendor").filter(model_number=options["model"])
+        device = (
+            devices.filter(vendor__slug=options["vendor"]).first()
+            or devices.filter(vendor__name__iexact=options["vendor"]).first()
+        )
+        if device is None:
+            raise NotFound(f"Device not found: {options['vendor']} {options['model']}")
+        if device.technology != VendorModel.Technology.MODBUS:
+            raise UsageError(f"{device} is not a Modbus device")
+        return device
+
+    def _list(self, directory: Path, options):
+        try:
+            snippets = load_snippets(directory)
+        except SnippetError as e:
+            raise InvalidInput(str(e)) from e
+        if options["format"] == "json":
+            rows = [
+                {"key": s.key, "name": s.name, "description": s.description, "registers": len(s.registers),
+                 "span": s.span}
+                for s in snippets
+            ]
+            self.stdout.write(json.dumps(rows, indent=2, ensure_ascii=False))
+            return
+        for snippet in snippets:
+            self.stdout.write(f"{snippet.key:<28} {len(snippet.registers):>3} registers  {snippet.name}")
+
+    def _show(self, directory: Path, options):
+        snippet = self._load(directory, options["key"])
+        if options["format"] == "json":
+            self.stdout.write(json.dumps({"key": snippet.key, **snippet.as_dict()}, indent=2, ensure_ascii=False))
+        else:
+            self.stdout.write(dump_yaml(snippet.as_dict()), ending="")
+
+    def _create(self, directory: Path, options):
+        key = options["key"]
+        if (directory / f"{key}{SNIPPET_SUFFIX}").exists() and not options["force"]:
+            raise Conflict(f"Snippet {key!r} already exists; pass --force to overwrite it")
+        device = self._device(options)
+        try:
+            snippet = snippet_from_device(
+                device, key, options["name"] or key.replace("-", " ").capitalize(), options["description"],
+                start=options["start"], end=options["end"],
+            )
+            path = save_snippet(snippet, directory)
+        except SnippetError as e:
+            raise InvalidInput(str(e)) from e
+        self.stderr.write(self.style.SUCCESS(f"Wrote {len(snippet.registers)} registers to {path}"))
+
+    def _insert(self, directory: Path, options):
+        if options["base_address"] is None:
+            raise UsageError("snippets insert needs --base-address")
+        snippet = self._load(directory, options["key"])
+        device = self._device(options)
+        try:
+            created = insert_snippet(device, snippet, options["base_address"])
+        except SnippetError as e:
+            raise Conflict(str(e)) from e
+        self.stderr.write(self.style.SUCCESS(f"Added {len(created)} registers from {snippet.name} to {device}"))
+
+    def _delete(self, directory: Path, options):
+        try:
+            delete_snippet(options["key"], directory)
+        except SnippetError as e:
+            raise NotFound(str(e)) from e
+        self.stderr.write(self.style.SUCCESS(f"Deleted snippet {options['key']}"))
+
+    def _check(self, directory: Path, options):
+        problems = []
+        paths = sorted(directory.glob(f"*{SNIPPET_SUFFIX}")) if directory.is_dir() else []
+        for path in paths:
+            try:
+                load_snippet(path.stem, directory)
+            except SnippetError as e:
+                problems.append(str(e))
+        for problem in problems:
+            self.stderr.write(problem)
+        if problems:
+            raise ValidationFailed(f"{len(problems)} of {len(paths)} snippet(s) invalid", problems)
+        self.stdout.write(f"{len(paths)} snippet(s) OK")
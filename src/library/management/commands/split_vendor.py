@@ -0,0 +1,48 @@
+"""Management command to split a vendor file into one file per group.
+
+``split_vendor acme --by device_type --path devices/`` replaces
+``acme.yaml`` with ``acme-power-meter.yaml``, ``acme-heat-meter.yaml``, …
+and lists each of them in the manifest under the same vendor name.
+``--by family`` groups by model family (the model number's leading
+letters: ``EM340`` and ``EM112`` land in ``acme-em.yaml``). Devices are
+moved verbatim, comments included.
+"""
+
+from library.management.base import LibraryCommand
+from library.management.errors import Conflict, NotFound, UsageError
+from library.management.tree import add_tree_arguments, tree_paths
+from library.safe_write import TreeWriter
+from library.vendor_files import SPLIT_BY, VendorFileConflict, VendorFileError, VendorFileNotFound, split_vendor_file
+
+
+class Command(LibraryCommand):
+    help = "Split a vendor's YAML file into one file per device type, technology or model family"
+
+    def add_arguments(self, parser):
+        parser.add_argument("vendor", help="Vendor name or slug, or one of its files (acme.yaml)")
+        parser.add_argument("--by", choices=SPLIT_BY, default="device_type", help="Grouping key")
+        add_tree_arguments(parser, "Path to the YAML devices directory", required=True)
+        parser.add_argument(
+            "--dry-run",
+            action="store_true",
+            help="Print the diff instead of writing the files",
+        )
+
+    def handle(self, *args, **options):
+        writer = TreeWriter(dry_run=options["dry_run"])
+        try:
+            files = split_vendor_file(*tree_paths(options), options["vendor"], options["by"], writer)
+        except VendorFileConflict as e:
+            raise Conflict(str(e)) from e
+        except VendorFileNotFound as e:
+            raise NotFound(str(e)) from e
+        except VendorFileError as e:
+            raise UsageError(str(e)) from e
+
+        if options["dry_run"]:
+            for diff in writer.diffs:
+                self.stdout.write(diff, ending="")
+            return
+        for name, count in files.items():
+            self.stdout.write(f"  {name}: {count} device(s)")
+        self.stdout.write(self.style.SUCCESS(f"Split {options['vendor']} into {len(files)} file(s)"))
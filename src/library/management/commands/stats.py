@@ -0,0 +1,51 @@
+"""Management command to summarise library coverage.
+
+Counts per vendor, technology and device type, the controllable share,
+devices lacking a description or metric mappings, and the average register
+count of Modbus devices. ``--format json`` is meant for dashboards that
+track library growth.
+"""
+
+import json
+
+from library.management.base import LibraryCommand
+from library.stats import library_stats
+
+
+class Command(LibraryCommand):
+    help = "Summarise device library coverage (text or JSON)"
+
+    def add_arguments(self, parser):
+        parser.add_argument(
+            "--format",
+            choices=["text", "json"],
+            default="text",
+            help="Output format",
+        )
+
+    def handle(self, *args, **options):
+        stats = library_stats()
+
+        if options["format"] == "json":
+            self.stdout.write(json.dumps(stats, indent=2, ensure_ascii=False))
+            return
+
+        self.stdout.write(self.style.SUCCESS(f"{stats['devices']} devices from {stats['vendors']} vendors"))
+        for title, key, label in (
+            ("Per vendor", "per_vendor", "vendor"),
+            ("Per technology", "per_technology", "technology"),
+            ("Per device type", "per_device_type", "device_type"),
+        ):
+            self.stdout.write(f"\n{title}:")
+            for row in stats[key]:
+                self.stdout.write(f"  {row[label]:<32} {row['count']:>5}")
+
+        self.stdout.write(
+            f"\nControllable: {stats['controllable']} ({stats['controllable_share']:.1%})"
+            f"\nAverage registers per Modbus device: {stats['avg_registers_per_modbus_device']}"
+        )
+        for title, key in (("Missing description", "missing_description"), ("Missing metrics", "missing_metrics")):
+            devices = stats[key]
+            self.stdout.write(f"\n{title}: {len(devices)}")
+            for label in devices:
+                self.stdout.write(f"  {label}")
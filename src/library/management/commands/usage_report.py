@@ -0,0 +1,62 @@
+"""Management command to print a local usage report from the audit trail.
+
+Edits, validations, exports and imports over a period (default: the last
+30 days), per vendor and per device — see ``library.usage``. Set
+``USAGE_TRACKING=true`` to also count command-line lint and export runs.
+"""
+
+import json
+from datetime import datetime, time, timedelta
+
+from django.utils import timezone
+from django.utils.dateparse import parse_date
+
+from library.management.base import LibraryCommand
+from library.management.errors import UsageError
+from library.usage import usage_report
+
+
+def _day_start(value: str) -> datetime:
+    day = parse_date(value) if value else None
+    if day is None:
+        raise UsageError(f"Invalid date {value!r}, expected YYYY-MM-DD")
+    return timezone.make_aware(datetime.combine(day, time.min))
+
+
+class Command(LibraryCommand):
+    help = "Summarise which devices were edited, validated and exported over a period"
+
+    def add_arguments(self, parser):
+        parser.add_argument("--days", type=int, default=30, help="Report on the last N days (default: 30)")
+        parser.add_argument("--since", default=None, help="Start date YYYY-MM-DD (overrides --days)")
+        parser.add_argument("--until", default=None, help="End date YYYY-MM-DD, exclusive (default: now)")
+        parser.add_argument("--top", type=int, default=20, help="Number of devices to list (0 = all)")
+        parser.add_argument("--format", choices=["text", "json"], default="text", help="Output format")
+
+    def handle(self, *args, **options):
+        until = _day_start(options["until"]) if options["until"] else timezone.now()
+        since = _day_start(options["since"]) if options["since"] else until - timedelta(days=options["days"])
+        if since >= until:
+            raise UsageError("--since must be before --until")
+
+        report = usage_report(since, until, top=options["top"] or None)
+
+        if options["format"] == "json":
+            self.stdout.write(json.dumps(report, indent=2, ensure_ascii=False))
+            return
+
+        totals = report["totals"]
+        self.stdout.write(self.style.SUCCESS(f"Usage {since:%Y-%m-%d} – {until:%Y-%m-%d}"))
+        self.stdout.write(
+            f"{totals['edits']} edits on {totals['devices_edited']} devices, "
+            f"{totals['validation_runs']} validation runs, {totals['exports']} exports, {totals['imports']} imports"
+        )
+        self.stdout.write("\nPer vendor:                        edits  devices  validated")
+        for v in report["vendors"]:
+            self.stdout.write(f"  {v['vendor']:<32} {v['edits']:>5} {v['devices_edited']:>8} {v['validations']:>10}")
+        self.stdout.write("\nMost active devices:               edits  editors  validated  last edited")
+        for d in report["devices"]:
+            last = (d["last_edited"] or "—")[:10]
+            self.stdout.write(
+                f"  {d['device']:<32} {d['edits']:>5} {d['editors']:>8} {d['validations']:>10}  {last}"
+            )
@@ -0,0 +1,70 @@
+"""Management command to cross-check manifest.yaml against the device files.
+
+Runs the manifest part of ``doctor``: every vendor entry's file exists,
+no device file on disk is missing from the manifest, and each entry's
+``technologies`` list matches the technologies its file actually uses.
+``--fix`` rewrites stale or missing ``technologies`` lists; ``--rebuild``
+drops entries for missing files, adds the unlisted ones and creates the
+manifest when there is none. Exits non-zero on errors so it can gate CI.
+"""
+
+import yaml
+
+from library.doctor import ERROR, OK, check_manifest, fix_technologies, rebuild_manifest
+from library.management.base import LibraryCommand
+from library.management.errors import InvalidInput, ValidationFailed
+from library.management.tree import add_tree_arguments, tree_paths
+from library.safe_write import TreeWriter
+
+
+class Command(LibraryCommand):
+    help = "Verify manifest entries, vendor files and technologies lists agree"
+
+    def add_arguments(self, parser):
+        add_tree_arguments(parser, "Path to the devices/ directory", required=True)
+        parser.add_argument(
+            "--fix",
+            action="store_true",
+            help="Rewrite each entry's technologies list from its device file",
+        )
+        parser.add_argument(
+            "--rebuild",
+            action="store_true",
+            help="Rebuild the vendor list from the files in devices/ (creates a missing manifest)",
+        )
+
+    def handle(self, *args, **options):
+        devices_path, manifest_path = tree_paths(options, must_exist=not options["rebuild"])
+        if options["rebuild"]:
+            try:
+                notes = rebuild_manifest(devices_path, manifest_path, TreeWriter())
+            except yaml.YAMLError as e:
+                raise InvalidInput(f"Manifest is not valid YAML: {e}") from e
+            for note in notes:
+                self.stdout.write(f"  {note}")
+        try:
+            manifest = yaml.safe_load(manifest_path.read_text()) or {}
+        except yaml.YAMLError as e:
+            raise InvalidInput(f"Manifest is not valid YAML: {e}") from e
+
+        if options["fix"]:
+            changed = fix_technologies(devices_path, manifest_path, TreeWriter())
+            for file_name in changed:
+                self.stdout.write(f"  updated technologies for {file_name}")
+            manifest = yaml.safe_load(manifest_path.read_text()) or {}
+
+        checks = check_manifest(devices_path, manifest)
+        for c in checks:
+            if c.status == OK:
+                self.stdout.write(self.style.SUCCESS(f"[ok]    {c.name}: {c.message}"))
+                continue
+            style = self.style.ERROR if c.status == ERROR else self.style.WARNING
+            self.stdout.write(style(f"[{c.status}] {c.name}: {c.message}"))
+            if c.fix:
+                self.stdout.write(f"        fix: {c.fix}")
+
+        failed = sum(1 for c in checks if c.status == ERROR)
+        if failed:
+            raise ValidationFailed(
+                f"{failed} check(s) failed", [c.as_dict() for c in checks if c.status == ERROR],
+            )
@@ -0,0 +1,94 @@
+"""Error taxonomy and exit codes shared by the library commands.
+
+Automation (CI jobs, release scripts) needs to tell "the library content
+is broken" apart from "the database was unreachable", "GitHub refused the
+token" or "you called it wrong" without parsing messages. Every library command fails with one of
+the ``LibraryError`` subclasses below, each carrying a stable ``code``
+string and an ``ExitCode``; ``--json-errors`` (see ``LibraryCommand``)
+prints the same information as JSON on stderr.
+
+A plain ``CommandError`` still works and maps to ``failure`` / its own
+return code.
+"""
+
+from __future__ import annotations
+
+from enum import IntEnum
+
+from django.core.management.base import CommandError
+
+
+class ExitCode(IntEnum):
+    OK = 0
+    FAILURE = 1  # unexpected / unclassified
+    USAGE = 2  # bad arguments or option combination
+    VALIDATION = 3  # library content fails a check (lint, doctor, verify_manifest, fmt_yaml --check)
+    INPUT = 4  # an input file, patch or query can't be read or parsed
+    NOT_FOUND = 5  # referenced vendor, device, version or file doesn't exist
+    CONFLICT = 6  # target already exists / duplicate definition
+    ENVIRONMENT = 7  # database unreachable or not migrated — infrastructure, not the library
+    AUTH = 8  # no GitHub token, or GitHub refused it (401/403)
+    NETWORK = 9  # GitHub couldn't be reached
+
+
+class LibraryError(CommandError):
+    code = "failure"
+    exit_code = ExitCode.FAILURE
+
+    def __init__(self, message: str, details: dict | list | None = None):
+        super().__init__(message, returncode=int(self.exit_code))
+        self.details = details
+
+
+class UsageError(LibraryError):
+    code = "usage"
+    exit_code = ExitCode.USAGE
+
+
+class ValidationFailed(LibraryError):
+    code = "validation"
+    exit_code = ExitCode.VALIDATION
+
+
+class InvalidInput(LibraryError):
+    code = "input"
+    exit_code = ExitCode.INPUT
+
+
+class NotFound(LibraryError):
+    code = "not_found"
+    exit_code = ExitCode.NOT_FOUND
+
+
+class Conflict(LibraryError):
+    code = "conflict"
+    exit_code = ExitCode.CONFLICT
+
+
+class EnvironmentFailure(LibraryError):
+    code = "environment"
+    exit_code = ExitCode.ENVIRONMENT
+
+
+class AuthFailure(LibraryError):
+    code = "auth"
+    exit_code = ExitCode.AUTH
+
+
+class NetworkFailure(LibraryError):
+    code = "network"
+    exit_code = ExitCode.NETWORK
+
+
+def error_payload(error: Exception) -> dict:
+    """The ``--json-errors`` document for ``error``."""
+    if isinstance(error, LibraryError):
+        code, exit_code = error.code, int(error.exit_code)
+    elif isinstance(error, CommandError):
+        code, exit_code = LibraryError.code, error.returncode
+    else:
+        code, exit_code = "internal", int(ExitCode.FAILURE)
+    payload = {"code": code, "exit_code": exit_code, "message": str(error)}
+    if getattr(error, "details", None):
+        payload["details"] = error.details
+    return {"error": payload}
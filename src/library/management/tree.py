@@ -0,0 +1,41 @@
+"""Shared ``--path`` / ``--manifest`` handling for the library commands.
+
+Most tooling commands work either on the database or on an exported YAML
+tree (``manifest.yaml`` next to a ``devices/`` directory). They register
+the same two flags through ``add_tree_arguments`` and resolve them through
+``tree_paths`` so defaults, help text and error messages stay identical
+across commands.
+"""
+
+from __future__ import annotations
+
+from pathlib import Path
+
+from .errors import NotFound
+
+MANIFEST_NAME = "manifest.yaml"
+
+
+def add_tree_arguments(parser, path_help: str, required: bool = False) -> None:
+    """Register ``--path`` (devices directory) and ``--manifest``."""
+    parser.add_argument("--path", required=required, default=None, help=path_help)
+    parser.add_argument(
+        "--manifest",
+        default=None,
+        help=f"Path to {MANIFEST_NAME} (default: <path>/../{MANIFEST_NAME})",
+    )
+
+
+def tree_paths(options: dict, must_exist: bool = True) -> tuple[Path, Path] | None:
+    """``(devices_path, manifest_path)`` from parsed options, or ``None``
+    when ``--path`` wasn't given (i.e. the command works on the database).
+
+    With ``must_exist`` a missing manifest raises ``NotFound``.
+    """
+    if not options.get("path"):
+        return None
+    devices_path = Path(options["path"])
+    manifest_path = Path(options["manifest"]) if options.get("manifest") else devices_path.parent / MANIFEST_NAME
+    if must_exist and not manifest_path.exists():
+        raise NotFound(f"Manifest not found: {manifest_path}")
+    return devices_path, manifest_path
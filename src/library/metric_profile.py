@@ -0,0 +1,94 @@
+"""Typed entries of a device type's metrics profile (``DeviceType.metrics``).
+
+The profile is stored as a JSON list of ``{metric, tier}``. The metrics
+editor works on ``ProfileMetric`` rows instead, each joined with its L1
+``Metric`` (label, unit, aggregation) and with the source fields the
+type's vendor models map onto it. ``save_profile`` writes the list back
+and records history; it refuses unknown tiers, repeated metrics and keys
+missing from the L1 catalogue, which the free-form JSON never checked.
+"""
+
+from __future__ import annotations
+
+from dataclasses import dataclass, field
+
+from .history import record_device_type_history, snapshot_device_type
+from .models import DeviceType, DeviceTypeHistory, Metric, ProcessorConfig
+
+TIERS = tuple(DeviceType.Tier.values)
+
+
+class ProfileError(ValueError):
+    pass
+
+
+@dataclass(frozen=True)
+class ProfileMetric:
+    metric: str
+    tier: str = DeviceType.Tier.SECONDARY
+    extra: dict = field(default_factory=dict, compare=False)  # other keys, kept as they are
+    catalogue: Metric | None = field(default=None, compare=False)
+    sources: tuple[str, ...] = field(default=(), compare=False)
+
+    @classmethod
+    def from_dict(cls, data: dict, **kwargs) -> ProfileMetric:
+        extra = {k: v for k, v in data.items() if k not in ("metric", "tier")}
+        return cls(
+            metric=data.get("metric") or "", tier=data.get("tier") or DeviceType.Tier.SECONDARY, extra=extra, **kwargs,
+        )
+
+    def to_dict(self) -> dict:
+        return {"metric": self.metric, "tier": self.tier, **self.extra}
+
+    @property
+    def label(self) -> str:
+        return self.catalogue.label if self.catalogue else ""
+
+    @property
+    def unit(self) -> str:
+        return self.catalogue.unit if self.catalogue else ""
+
+    @property
+    def aggregation(self) -> str:
+        return self.catalogue.get_aggregation_display() if self.catalogue else ""
+
+
+def _sources(device_type: DeviceType) -> dict[str, set[str]]:
+    """Source field names mapped onto each metric by the type's models."""
+    sources: dict[str, set[str]] = {}
+    for proc in ProcessorConfig.objects.filter(device_type__device_type_fk=device_type):
+        for entry in proc.field_mappings or []:
+            if isinstance(entry, dict) and entry.get("target") and entry.get("source"):
+                sources.setdefault(entry["target"], set()).add(str(entry["source"]))
+    return sources
+
+
+def load_profile(device_type: DeviceType) -> list[ProfileMetric]:
+    entries = [e for e in device_type.metrics or [] if isinstance(e, dict)]
+    catalogue = Metric.objects.in_bulk([e.get("metric") for e in entries if e.get("metric")], field_name="key")
+    sources = _sources(device_type)
+    return [
+        ProfileMetric.from_dict(
+            e, catalogue=catalogue.get(e.get("metric")), sources=tuple(sorted(sources.get(e.get("metric"), ()))),
+        )
+        for e in entries
+    ]
+
+
+def save_profile(device_type: DeviceType, entries: list[ProfileMetric], user=None) -> None:
+    """Store ``entries`` as the type's profile (raises ``ProfileError``)."""
+    seen = set()
+    for entry in entries:
+        if entry.tier not in TIERS:
+            raise ProfileError(f"{entry.metric}: tier must be one of {', '.join(TIERS)}")
+        if entry.metric in seen:
+            raise ProfileError(f"{entry.metric} is already in the profile")
+        seen.add(entry.metric)
+    unknown = sorted(seen - set(Metric.objects.filter(key__in=seen).values_list("key", flat=True)))
+    if unknown:
+        raise ProfileError(f"Not in the metric catalogue: {', '.join(unknown)}")
+
+    before = snapshot_device_type(device_type)
+    device_type.metrics = [entry.to_dict() for entry in entries]
+    device_type.save()
+    record_device_type_history(device_type, DeviceTypeHistory.Action.UPDATED, user, previous_snapshot=before)
@@ -0,0 +1,33 @@
+# Generated by Django 6.0.4 on 2026-10-16 09:12
+
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ('library', '0042_remove_lorawanconfig_field_map_and_more'),
+    ]
+
+    operations = [
+        migrations.AddField(
+            model_name='registerdefinition',
+            name='display_name',
+            field=models.CharField(blank=True, default='', help_text="Human-readable label, e.g. 'Voltage L1'.", max_length=128),
+        ),
+        migrations.AddField(
+            model_name='registerdefinition',
+            name='display_precision',
+            field=models.PositiveSmallIntegerField(blank=True, help_text='Decimal places to show; empty = client default.', null=True),
+        ),
+        migrations.AddField(
+            model_name='registerdefinition',
+            name='display_icon',
+            field=models.CharField(blank=True, default='', help_text="Lucide icon name, e.g. 'zap', 'thermometer'.", max_length=64),
+        ),
+        migrations.AddField(
+            model_name='registerdefinition',
+            name='display_category',
+            field=models.CharField(blank=True, default='', help_text="Grouping in the UI, e.g. 'energy', 'power quality'.", max_length=64),
+        ),
+    ]
@@ -0,0 +1,28 @@
+# Generated by Django 6.0.4 on 2026-10-16 12:05
+
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ("library", "0044_devicedraft"),
+    ]
+
+    operations = [
+        migrations.AddField(
+            model_name="lorawanconfig",
+            name="codec_source_url",
+            field=models.URLField(blank=True, default="", max_length=500),
+        ),
+        migrations.AddField(
+            model_name="lorawanconfig",
+            name="codec_source_sha256",
+            field=models.CharField(blank=True, default="", max_length=64),
+        ),
+        migrations.AddField(
+            model_name="lorawanconfig",
+            name="codec_fetched_at",
+            field=models.DateTimeField(blank=True, null=True),
+        ),
+    ]
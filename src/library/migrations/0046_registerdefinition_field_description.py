@@ -0,0 +1,23 @@
+# Generated by Django 6.0.4 on 2026-10-16 14:20
+
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ("library", "0045_lorawanconfig_codec_source"),
+    ]
+
+    operations = [
+        migrations.AddField(
+            model_name="registerdefinition",
+            name="field_description",
+            field=models.CharField(
+                blank=True,
+                default="",
+                help_text="What the value means, e.g. 'T3 supply temperature'. Exported as field.description.",
+                max_length=255,
+            ),
+        ),
+    ]
@@ -0,0 +1,18 @@
+# Generated by Django 6.0.4 on 2026-10-16 15:05
+
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ("library", "0046_registerdefinition_field_description"),
+    ]
+
+    operations = [
+        migrations.AddField(
+            model_name="vendormodel",
+            name="additional_technologies",
+            field=models.JSONField(blank=True, default=list),
+        ),
+    ]
@@ -0,0 +1,18 @@
+# Generated by Django 6.0.4 on 2026-10-16 16:20
+
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ("library", "0047_vendormodel_additional_technologies"),
+    ]
+
+    operations = [
+        migrations.AddField(
+            model_name="vendormodel",
+            name="links",
+            field=models.JSONField(blank=True, default=list),
+        ),
+    ]
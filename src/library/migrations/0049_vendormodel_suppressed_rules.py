@@ -0,0 +1,18 @@
+# Generated by Django 6.0.4 on 2026-10-16 17:05
+
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ("library", "0048_vendormodel_links"),
+    ]
+
+    operations = [
+        migrations.AddField(
+            model_name="vendormodel",
+            name="suppressed_rules",
+            field=models.JSONField(blank=True, default=list),
+        ),
+    ]
@@ -1,7 +1,9 @@
 """Library models — device definitions and metadata."""
 
+import hashlib
 import secrets
 import uuid
+from urllib.parse import urlsplit
 
 from django.conf import settings
 from django.core.exceptions import ValidationError
@@ -263,6 +265,11 @@ class VendorModel(TimeStampedModel):
         LORAWAN = "lorawan", "LoRaWAN"
         WMBUS = "wmbus", "wM-Bus"
 
+    class LinkKind(models.TextChoices):
+        DATASHEET = "datasheet", "Datasheet"
+        MANUAL = "manual", "Manual"
+        VENDOR_PAGE = "vendor_page", "Vendor page"
+
     id = models.UUIDField(primary_key=True, default=uuid.uuid4, editable=False)
     key = models.UUIDField(default=uuid.uuid4, null=True, blank=True, unique=True)
     vendor = models.ForeignKey(
@@ -283,7 +290,21 @@ class VendorModel(TimeStampedModel):
         related_name="vendor_models",
     )
     technology = models.CharField(max_length=20, choices=Technology.choices)
+    # Hybrid devices (e.g. a heat meter readable over Modbus and wM-Bus)
+    # carry one config per transport. ``technology`` stays the primary one
+    # — what list views, filters and ``decoder_type`` go by — and the rest
+    # are listed here, each backed by its own *Config row.
+    additional_technologies = models.JSONField(default=list, blank=True)
     description = models.TextField(blank=True, default="")
+    # Documentation links: ``[{kind, url, title?}]`` with ``kind`` one of
+    # ``LinkKind``. Kept out of ``description`` so they can be validated,
+    # checked for reachability (``lint_library --check-links``) and listed
+    # on the device page.
+    links = models.JSONField(default=list, blank=True)
+    # Lint rule ids not reported for this device (``validation.suppress``
+    # in YAML) — for findings that are known and accepted, such as a
+    # vendor field name that doesn't follow the naming convention.
+    suppressed_rules = models.JSONField(default=list, blank=True)
 
     class Meta:
         ordering = ["vendor__name", "model_number"]
@@ -292,6 +313,70 @@ class VendorModel(TimeStampedModel):
     def __str__(self):
         return f"{self.vendor.name} {self.model_number}"
 
+    @property
+    def technologies(self) -> list[str]:
+        """Primary technology followed by the additional ones."""
+        return [self.technology, *(self.additional_technologies or [])]
+
+    def get_additional_technologies_display(self) -> list[str]:
+        labels = dict(self.Technology.choices)
+        return [labels.get(t, t) for t in self.additional_technologies or []]
+
+    def get_links_display(self) -> list[dict]:
+        """``links`` with a ``label`` per entry: its title, else the kind's label."""
+        labels = dict(self.LinkKind.choices)
+        return [
+            {**link, "label": link.get("title") or labels.get(link.get("kind"), link.get("kind"))}
+            for link in self.links or [] if isinstance(link, dict)
+        ]
+
+    def clean(self):
+        super().clean()
+        additional = self.additional_technologies or []
+        unknown = [t for t in additional if t not in self.Technology.values]
+        if unknown:
+            raise ValidationError({"additional_technologies": f"Unknown technology: {', '.join(map(str, unknown))}"})
+        if len(set(additional)) != len(additional):
+            raise ValidationError({"additional_technologies": "Each technology can be listed once."})
+        if self.technology in additional:
+            raise ValidationError({
+                "additional_technologies": f"{self.get_technology_display()} is already the primary technology."
+            })
+        errors = self.link_errors(self.links)
+        if errors:
+            raise ValidationError({"links": errors})
+
+    @classmethod
+    def link_errors(cls, links) -> list[str]:
+        """Problems with a ``links`` value, one message per bad entry."""
+        if not isinstance(links, list):
+            return ["Must be a list of links."]
+        errors = []
+        seen = set()
+        for idx, link in enumerate(links, start=1):
+            if not isinstance(link, dict):
+                errors.append(f"Link {idx}: must be an object with kind and url.")
+                continue
+            unknown = set(link) - {"kind", "url", "title"}
+            if unknown:
+                errors.append(f"Link {idx}: unknown key(s) {', '.join(sorted(map(str, unknown)))}.")
+            if link.get("kind") not in cls.LinkKind.values:
+                errors.append(f"Link {idx}: kind must be one of {', '.join(cls.LinkKind.values)}.")
+            url = link.get("url")
+            try:
+                parts = urlsplit(url) if isinstance(url, str) else None
+            except ValueError:
+                parts = None
+            if not parts or parts.scheme not in ("http", "https") or not parts.netloc or " " in url:
+                errors.append(f"Link {idx}: {url!r} is not an http(s) URL.")
+            elif url in seen:
+                errors.append(f"Link {idx}: {url} is listed twice.")
+            else:
+                seen.add(url)
+            if not isinstance(link.get("title", ""), str):
+                errors.append(f"Link {idx}: title must be text.")
+        return errors
+
     @property
     def effective_field_mappings(self) -> list[dict]:
         """Resolved + merged view of L4 ``field_mappings`` and
@@ -447,10 +532,30 @@ class RegisterDefinition(TimeStampedModel):
     modbus_config = models.ForeignKey(ModbusConfig, on_delete=models.CASCADE, related_name="register_definitions")
     field_name = models.CharField(max_length=255)
     field_unit = models.CharField(max_length=50, blank=True, default="")
+    field_description = models.CharField(
+        max_length=255,
+        blank=True,
+        default="",
+        help_text="What the value means, e.g. 'T3 supply temperature'. Exported as field.description.",
+    )
     address = models.IntegerField()
     data_type = models.CharField(max_length=20, choices=DataType.choices)
     scale = models.FloatField(default=1.0)
     offset = models.FloatField(default=0.0)
+    # Presentation hints for UI consumers (Spark UI). Optional — clients
+    # fall back to the field name / metric defaults when unset.
+    display_name = models.CharField(
+        max_length=128, blank=True, default="", help_text="Human-readable label, e.g. 'Voltage L1'.",
+    )
+    display_precision = models.PositiveSmallIntegerField(
+        null=True, blank=True, help_text="Decimal places to show; empty = client default.",
+    )
+    display_icon = models.CharField(
+        max_length=64, blank=True, default="", help_text="Lucide icon name, e.g. 'zap', 'thermometer'.",
+    )
+    display_category = models.CharField(
+        max_length=64, blank=True, default="", help_text="Grouping in the UI, e.g. 'energy', 'power quality'.",
+    )
 
     class Meta:
         ordering = ["address"]
@@ -458,6 +563,17 @@ class RegisterDefinition(TimeStampedModel):
     def __str__(self):
         return f"{self.field_name} @ {self.address}"
 
+    @property
+    def display(self) -> dict:
+        """Set display hints as ``{name, precision, icon, category}`` (unset keys omitted)."""
+        hints = {
+            "name": self.display_name,
+            "precision": self.display_precision,
+            "icon": self.display_icon,
+            "category": self.display_category,
+        }
+        return {k: v for k, v in hints.items() if v not in ("", None)}
+
 
 class LoRaWANConfig(TimeStampedModel):
     """LoRaWAN-specific configuration for a device type."""
@@ -511,6 +627,11 @@ class LoRaWANConfig(TimeStampedModel):
         default="",
         help_text="JavaScript source implementing decodeUplink/encodeDownlink (TTN v3/ChirpStack) or Decoder/Encoder (TTN v2).",
     )
+    # Provenance of a codec fetched from a vendor URL (``library.codec_fetch``):
+    # where it came from, the SHA-256 of what was downloaded, and when.
+    codec_source_url = models.URLField(max_length=500, blank=True, default="")
+    codec_source_sha256 = models.CharField(max_length=64, blank=True, default="")
+    codec_fetched_at = models.DateTimeField(null=True, blank=True)
 
     # --- TTN registration profile (blank = registrar falls back to its default) ---
     lorawan_version = models.CharField(
@@ -548,6 +669,13 @@ class LoRaWANConfig(TimeStampedModel):
     def __str__(self):
         return f"LoRaWANConfig for {self.device_type}"
 
+    @property
+    def codec_modified_since_fetch(self) -> bool:
+        """The stored codec no longer matches the fetched upstream file."""
+        if not self.codec_source_sha256:
+            return False
+        return hashlib.sha256(self.payload_codec.encode("utf-8")).hexdigest() != self.codec_source_sha256
+
 
 class WMBusConfig(TimeStampedModel):
     """wM-Bus-specific configuration for a device type."""
@@ -1215,6 +1343,34 @@ class GatewayAssignment(TimeStampedModel):
         return f"{self.serial_number} -> {self.spark_url}"
 
 
+class DeviceDraft(TimeStampedModel):
+    """Scratchpad device definition not yet filed under a vendor.
+
+    ``content`` is a device document in the YAML export shape, kept as
+    free text so it may be incomplete or invalid while the device is being
+    reverse-engineered. Drafts are not part of the library: exports,
+    versions and the sync API never see them until they are filed.
+    """
+
+    id = models.UUIDField(primary_key=True, default=uuid.uuid4, editable=False)
+    title = models.CharField(max_length=255)
+    content = models.TextField(blank=True, default="", help_text="Device definition as YAML (export shape).")
+    notes = models.TextField(blank=True, default="")
+    created_by = models.ForeignKey(
+        settings.AUTH_USER_MODEL,
+        on_delete=models.SET_NULL,
+        null=True,
+        blank=True,
+        related_name="device_drafts",
+    )
+
+    class Meta:
+        ordering = ["-modified"]
+
+    def __str__(self):
+        return self.title
+
+
 class APIKey(TimeStampedModel):
     """API key for external services to access the sync API."""
 
@@ -0,0 +1,213 @@
+"""What changed on a device since its published original, field by field.
+
+The current ``LibraryVersion`` pins one ``DeviceHistory`` snapshot per
+device — the *original* Spark instances are served until the next
+publish. ``device_changes`` diffs the device as it is now against that
+snapshot (``diff_snapshots``) and keys the result the way the pages
+mark it: fields by snapshot key (``name``, ``lorawan_config.device_class``)
+and registers by address. A device added since the last publish has no
+original, so nothing on it is marked.
+
+``revert_field`` and ``revert_register`` put one field or one register
+back the way the original has it and leave every other edit alone.
+Fields that don't map onto a column (the vendor, a codec's download
+provenance, the derived decoder type) are marked but not revertable.
+"""
+
+from __future__ import annotations
+
+import copy
+import json
+from dataclasses import dataclass, field
+from typing import Any
+
+from .history import diff_snapshots, snapshot_device
+from .models import (
+    AlarmConfig,
+    ControlConfig,
+    DeviceHistory,
+    LibraryVersion,
+    LibraryVersionDevice,
+    LoRaWANConfig,
+    ModbusConfig,
+    ProcessorConfig,
+    RegisterDefinition,
+    VendorModel,
+    WMBusConfig,
+)
+
+# Device columns in the snapshot, with the value a key left out means.
+DEVICE_FIELDS = {
+    "model_number": "",
+    "name": "",
+    "device_type": "",
+    "technology": "",
+    "description": "",
+    "additional_technologies": [],
+    "links": [],
+    "suppressed_rules": [],
+}
+# Per config: its model, its title, and the snapshot keys that are columns.
+CONFIG_FIELDS = {
+    "modbus_config": (ModbusConfig, "Modbus", ("function", "byte_order", "word_order")),
+    "lorawan_config": (LoRaWANConfig, "LoRaWAN", ("device_class", "downlink_f_port", "codec_format", "payload_codec")),
+    "wmbus_config": (WMBusConfig, "wM-Bus", (
+        "manufacturer_code", "wmbus_version", "wmbus_device_type", "encryption_required",
+        "shared_encryption_key", "wmbusmeters_driver", "is_mvt_default",
+    )),
+    "control_config": (ControlConfig, "Control", ("controllable", "controls")),
+    "processor_config": (ProcessorConfig, "Processor", ("field_mappings", "extra_mappings")),
+    "alarm_config": (AlarmConfig, "Alarm", ("mappings",)),
+}
+
+
+class RevertError(ValueError):
+    pass
+
+
+@dataclass
+class FieldChange:
+    key: str  # snapshot key: "name", "lorawan_config.device_class"
+    original: Any
+    current: Any
+
+    @property
+    def label(self) -> str:
+        head, _, sub = self.key.partition(".")
+        if head not in CONFIG_FIELDS:
+            return head.replace("_", " ").capitalize()
+        return f"{CONFIG_FIELDS[head][1]} {sub.replace('_', ' ') if sub else 'configuration'}"
+
+    @property
+    def original_text(self) -> str:
+        """The published value as the pages show it."""
+        if self.original in (None, "", [], {}):
+            return "(empty)"
+        return self.original if isinstance(self.original, str) else json.dumps(self.original, ensure_ascii=False)
+
+    @property
+    def revertable(self) -> bool:
+        head, _, sub = self.key.partition(".")
+        if not sub:
+            return head in DEVICE_FIELDS or head in CONFIG_FIELDS
+        return head in CONFIG_FIELDS and sub in CONFIG_FIELDS[head][2]
+
+
+@dataclass
+class RegisterChange:
+    address: int
+    change: str  # "added" | "modified" | "removed"
+    original: dict | None = None
+    fields: list[str] = field(default_factory=list)  # snapshot keys that differ (modified)
+
+
+@dataclass
+class Changes:
+    version: int  # the LibraryVersion the original was published in
+    fields: dict[str, FieldChange] = field(default_factory=dict)
+    registers: dict[int, RegisterChange] = field(default_factory=dict)
+
+    def __len__(self) -> int:
+        return len(self.fields) + len(self.registers)
+
+    @property
+    def removed_registers(self) -> list[RegisterChange]:
+        return [r for r in self.registers.values() if r.change == "removed"]
+
+
+def original_snapshot(device: VendorModel) -> tuple[int, dict] | None:
+    """``(library version, snapshot)`` the current version serves for ``device``."""
+    current = LibraryVersion.objects.filter(is_current=True).first()
+    if current is None:
+        return None
+    pinned = (
+        current.device_changes.filter(device_type=device)
+        .exclude(change_type=LibraryVersionDevice.ChangeType.REMOVED)
+        .values_list("device_version", flat=True).first()
+    )
+    snapshot = (
+        DeviceHistory.objects.filter(device=device, version=pinned).values_list("snapshot", flat=True).first()
+        if pinned is not None else None
+    )
+    return (current.version, snapshot) if snapshot else None
+
+
+def device_changes(device: VendorModel) -> Changes | None:
+    """How ``device`` differs from its published original; None without one."""
+    original = original_snapshot(device)
+    if original is None:
+        return None
+    version, snapshot = original
+    changes = Changes(version)
+    for key, diff in diff_snapshots(snapshot, snapshot_device(device)).items():
+        if key != "registers":
+            changes.fields[key] = FieldChange(key, diff["old"], diff["new"])
+            continue
+        for reg in diff.get("added", []):
+            changes.registers[reg["address"]] = RegisterChange(reg["address"], "added")
+        for reg in diff.get("removed", []):
+            changes.registers[reg["address"]] = RegisterChange(reg["address"], "removed", reg)
+        for entry in diff.get("modified", []):
+            old, new = entry["old"], entry["new"]
+            changes.registers[entry["address"]] = RegisterChange(
+                entry["address"], "modified", old, sorted(k for k in {*old, *new} if old.get(k) != new.get(k)),
+            )
+    return changes
+
+
+def revert_field(device: VendorModel, key: str) -> FieldChange:
+    """Set one field back to its published value."""
+    changes = device_changes(device)
+    change = changes.fields.get(key) if changes else None
+    if change is None or not change.revertable:
+        raise RevertError(f"{key} has no change that can be reverted.")
+    head, _, sub = key.partition(".")
+    if head in DEVICE_FIELDS:
+        setattr(device, key, copy.deepcopy(DEVICE_FIELDS[key] if change.original is None else change.original))
+        device.save()
+        return change
+    model, _, columns = CONFIG_FIELDS[head]
+    if sub:
+        model.objects.update_or_create(device_type=device, defaults={sub: change.original})
+    elif change.original is None:
+        # The config didn't exist when published.
+        model.objects.filter(device_type=device).delete()
+    else:
+        defaults = {column: change.original[column] for column in columns if column in change.original}
+        model.objects.update_or_create(device_type=device, defaults=defaults)
+    return change
+
+
+def register_values(original: dict) -> dict:
+    """``RegisterDefinition`` columns for a register entry of a snapshot."""
+    display = original.get("display") or {}
+    return {
+        "field_name": original["field_name"],
+        "field_unit": original.get("field_unit", ""),
+        "field_description": original.get("field_description", ""),
+        "data_type": original["data_type"],
+        "scale": original.get("scale", 1.0),
+        "offset": original.get("offset", 0.0),
+        "display_name": display.get("name", ""),
+        "display_precision": display.get("precision"),
+        "display_icon": display.get("icon", ""),
+        "display_category": display.get("category", ""),
+    }
+
+
+def revert_register(device: VendorModel, address: int) -> RegisterChange:
+    """Put the register at ``address`` back as published: restore it, or remove it if it was added."""
+    changes = device_changes(device)
+    change = changes.registers.get(address) if changes else None
+    if change is None:
+        raise RevertError(f"Register {address} has no change to revert.")
+    modbus, _ = ModbusConfig.objects.get_or_create(device_type=device)
+    registers = modbus.register_definitions.filter(address=address)
+    if change.original is None:
+        registers.delete()
+        return change
+    register = registers.first() or RegisterDefinition(modbus_config=modbus, address=address)
+    for name, value in register_values(change.original).items():
+        setattr(register, name, value)
+    register.save()
+    return change
@@ -0,0 +1,221 @@
+"""Declarative batch edits across many device definitions.
+
+A patch file lists edits, each with a ``match`` selector and operations::
+
+    patches:
+      - match: {vendor: acme, technology: modbus}
+        set:
+          processor_config.decoder_type: acme_v2
+      - match: {vendor: acme, model_number: "PM-*"}
+        registers:
+          where: {field.unit: kWh}
+          multiply: {scale: 0.1}
+
+``match`` keys are ``vendor`` (slug or name), ``model_number``,
+``technology`` and ``device_type``; values are shell-style globs, matched
+case-insensitively. ``set`` assigns dotted paths in the device document
+(the YAML export shape). ``registers`` edits Modbus register entries
+selected by ``where`` (dotted paths into the register entry, globs) with
+``set``, ``multiply`` and ``add``.
+
+Edits run against the device document, so the same patch applies to the
+database (re-imported through the YAML importer, which records history)
+and to an exported YAML tree.
+"""
+
+from __future__ import annotations
+
+import copy
+import difflib
+from dataclasses import dataclass, field
+from fnmatch import fnmatchcase
+from pathlib import Path
+
+import yaml
+from django.db import transaction
+from django.utils.text import slugify
+
+from .exporters import _export_device
+from .importers import _import_device
+from .models import VendorModel
+from .safe_write import TreeWriter
+from .yaml_format import canonical_vendor_file, dump_yaml
+
+MATCH_KEYS = ("vendor", "model_number", "technology", "device_type")
+PATCH_KEYS = ("match", "set", "registers", "description")
+REGISTER_OPS = ("where", "set", "multiply", "add")
+
+
+class PatchError(ValueError):
+    pass
+
+
+@dataclass
+class DeviceChange:
+    label: str
+    changes: list[str] = field(default_factory=list)
+    before: dict | None = None
+    after: dict | None = None
+
+    def diff(self) -> str:
+        return "".join(difflib.unified_diff(
+            dump_yaml(self.before).splitlines(keepends=True),
+            dump_yaml(self.after).splitlines(keepends=True),
+            fromfile=self.label,
+            tofile=self.label,
+        ))
+
+
+def load_patches(path: str | Path) -> list[dict]:
+    """Read and validate a patch file."""
+    try:
+        data = yaml.safe_load(Path(path).read_text()) or {}
+    except yaml.YAMLError as e:
+        raise PatchError(f"{path}: invalid YAML: {e}") from e
+    patches = data.get("patches") if isinstance(data, dict) else None
+    if not isinstance(patches, list) or not patches:
+        raise PatchError(f"{path}: expected a non-empty 'patches' list")
+    for i, patch in enumerate(patches):
+        _validate(patch, f"patches[{i}]")
+    return patches
+
+
+def _validate(patch, where: str):
+    if not isinstance(patch, dict):
+        raise PatchError(f"{where}: must be a mapping")
+    unknown = set(patch) - set(PATCH_KEYS)
+    if unknown:
+        raise PatchError(f"{where}: unknown key(s) {', '.join(sorted(unknown))}")
+    match = patch.get("match") or {}
+    if set(match) - set(MATCH_KEYS):
+        raise PatchError(f"{where}.match: unknown key(s) {', '.join(sorted(set(match) - set(MATCH_KEYS)))}")
+    if not patch.get("set") and not patch.get("registers"):
+        raise PatchError(f"{where}: nothing to do (needs 'set' or 'registers')")
+    regs = patch.get("registers")
+    if regs is not None:
+        if not isinstance(regs, dict) or set(regs) - set(REGISTER_OPS):
+            raise PatchError(f"{where}.registers: allowed keys are {', '.join(REGISTER_OPS)}")
+        for op in ("multiply", "add"):
+            for key, value in (regs.get(op) or {}).items():
+                if not isinstance(value, int | float) or isinstance(value, bool):
+                    raise PatchError(f"{where}.registers.{op}.{key}: must be a number")
+
+
+def _glob(pattern, value) -> bool:
+    return fnmatchcase(str(value or "").lower(), str(pattern).lower())
+
+
+def device_matches(device: dict, match: dict) -> bool:
+    tech = (device.get("technology_config") or {}).get("technology", "")
+    values = {
+        "vendor": [device.get("vendor_name", ""), slugify(device.get("vendor_name", ""))],
+        "model_number": [device.get("model_number", "")],
+        "technology": [tech],
+        "device_type": [device.get("device_type", "")],
+    }
+    return all(any(_glob(pattern, v) for v in values[key]) for key, pattern in (match or {}).items())
+
+
+def _get(doc: dict, path: str):
+    for part in path.split("."):
+        if not isinstance(doc, dict):
+            return None
+        doc = doc.get(part)
+    return doc
+
+
+def _set(doc: dict, path: str, value):
+    *parents, leaf = path.split(".")
+    for part in parents:
+        doc = doc.setdefault(part, {})
+        if not isinstance(doc, dict):
+            raise PatchError(f"cannot set {path}: {part} is not a mapping")
+    doc[leaf] = value
+
+
+def apply_to_device(device: dict, patch: dict) -> list[str]:
+    """Apply one patch to ``device`` in place; returns human-readable changes."""
+    changes = []
+    for path, value in (patch.get("set") or {}).items():
+        old = _get(device, path)
+        if old != value:
+            _set(device, path, value)
+            changes.append(f"{path}: {old!r} → {value!r}")
+
+    reg_ops = patch.get("registers")
+    if reg_ops:
+        where = reg_ops.get("where") or {}
+        registers = (device.get("technology_config") or {}).get("register_definitions") or []
+        for reg in registers:
+            if not all(_glob(pattern, _get(reg, key)) for key, pattern in where.items()):
+                continue
+            label = f"register {reg.get('address')}"
+            for path, value in (reg_ops.get("set") or {}).items():
+                old = _get(reg, path)
+                if old != value:
+                    _set(reg, path, value)
+                    changes.append(f"{label} {path}: {old!r} → {value!r}")
+            for op, fn in (("multiply", lambda a, b: a * b), ("add", lambda a, b: a + b)):
+                for path, operand in (reg_ops.get(op) or {}).items():
+                    old = _get(reg, path)
+                    if not isinstance(old, int | float):
+                        raise PatchError(f"{label} {path}: {old!r} is not a number")
+                    new = round(fn(old, operand), 12)
+                    if new != old:
+                        _set(reg, path, new)
+                        changes.append(f"{label} {path}: {old!r} → {new!r}")
+    return changes
+
+
+def _apply_all(device: dict, patches: list[dict], label: str) -> DeviceChange | None:
+    before = copy.deepcopy(device)
+    change = DeviceChange(label=label, before=before)
+    for patch in patches:
+        if device_matches(device, patch.get("match")):
+            change.changes += apply_to_device(device, patch)
+    if not change.changes:
+        return None
+    change.after = device
+    return change
+
+
+def apply_to_database(patches: list[dict], dry_run: bool = False) -> list[DeviceChange]:
+    """Patch matching devices in the database, re-importing each changed one."""
+    results = []
+    with transaction.atomic():
+        for vm in VendorModel.objects.select_related("vendor").order_by("vendor__name", "model_number"):
+            change = _apply_all(_export_device(vm), patches, str(vm))
+            if change is None:
+                continue
+            results.append(change)
+            if not dry_run:
+                _import_device(vm.vendor, change.after, {"devices_created": 0, "devices_updated": 0})
+    return results
+
+
+def apply_to_tree(
+    patches: list[dict], devices_path: str | Path, manifest_path: str | Path, writer: TreeWriter | None = None,
+) -> list[DeviceChange]:
+    """Patch matching devices in an exported YAML tree."""
+    devices_path = Path(devices_path)
+    writer = writer or TreeWriter()
+    manifest = yaml.safe_load(Path(manifest_path).read_text()) or {}
+
+    results = []
+    for entry in manifest.get("vendors", []) or []:
+        file_path = devices_path / entry["file"]
+        if not file_path.exists():
+            continue
+        data = yaml.safe_load(file_path.read_text()) or {}
+        devices_key = "models" if "models" in data else "device_types"
+        file_changed = False
+        for device in data.get(devices_key) or []:
+            device.setdefault("vendor_name", entry.get("name", ""))
+            label = f"{device['vendor_name']} {device.get('model_number', '')}"
+            change = _apply_all(device, patches, f"{entry['file']}: {label}")
+            if change:
+                results.append(change)
+                file_changed = True
+        if file_changed:
+            writer.write(file_path, dump_yaml(canonical_vendor_file(data)))
+    return results
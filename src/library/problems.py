@@ -0,0 +1,143 @@
+"""Live validation for the device editor.
+
+``editor_problems`` lints the device the editor form would save — the
+posted model fields on top of the stored technology, processor, control
+and alarm configs — and attributes each problem to the place it can be
+fixed: a field of the form (``field``), or the editor page of the config
+it sits in (``url``; a register's own edit page for register findings).
+Form validation errors come first, as ``form`` problems.
+
+Library-scope rules are left out: they need the whole library to be
+meaningful (``lint_library`` runs them), and ``link-unreachable`` would
+make network requests on every keystroke.
+"""
+
+from __future__ import annotations
+
+import re
+from dataclasses import asdict, dataclass
+
+from django.urls import reverse
+
+from .exporters import _export_device
+from .lint import RULES, LintConfig, LintDevice, lint_devices
+from .scaffold import skeleton_device
+
+# Top-level device keys edited directly in ``VendorModelForm``.
+FORM_FIELDS = {
+    "vendor_name": "vendor",
+    "model_number": "model_number",
+    "name": "name",
+    "device_type": "device_type",
+    "device_type_key": "device_type_fk",
+    "description": "description",
+    "links": "links",
+    "validation": "suppressed_rules",
+}
+CONFIG_PAGES = {
+    "processor_config": "library:processor-config-edit",
+    "control_config": "library:control-config-edit",
+    "alarm_config": "library:alarm-config-edit",
+}
+TECHNOLOGY_PAGES = {
+    "modbus": "library:modbus-config-edit",
+    "lorawan": "library:lorawan-config-edit",
+    "wmbus": "library:wmbus-config-edit",
+}
+
+_TECH_PATH = re.compile(r"(technology_config|additional_technology_configs\[(\d+)\])(?:\.(.*))?$")
+_REGISTER = re.compile(r"register_definitions\[(\d+)\]")
+
+
+@dataclass(frozen=True)
+class Problem:
+    severity: str
+    rule: str
+    message: str
+    path: str = ""  # lint path, "" for form errors
+    field: str = ""  # form field to jump to
+    url: str = ""  # editor page to open when the field isn't on the form
+
+    def as_dict(self) -> dict:
+        return asdict(self)
+
+
+def editor_problems(form, config: LintConfig | None = None) -> list[Problem]:
+    """Problems of the device a bound ``VendorModelForm`` would save.
+
+    Validating the form updates ``form.instance`` in memory (nothing is
+    saved), which is what gets linted."""
+    problems = [
+        Problem("error", "form", message, field="" if name == "__all__" else name)
+        for name, messages in form.errors.items() for message in messages
+    ]
+    device = form.instance
+    data = _device_data(device)
+    config = config or LintConfig()
+    live = LintConfig(
+        rules={**config.rules, **{rule_id: False for rule_id, r in RULES.items() if r.scope == "library"}},
+        plugins=config.plugins,
+    )
+    for finding in lint_devices([LintDevice(data=data)], live):
+        field, url = _locate(finding.path, device, data)
+        problems.append(Problem(finding.severity, finding.rule, finding.message, finding.path, field, url))
+    return problems
+
+
+def _device_data(device) -> dict:
+    if device.pk:
+        return _export_device(device)
+    # A new device has no configs yet; lint the shape it would be created with.
+    vendor = device.vendor.name if device.vendor_id else ""
+    data = skeleton_device(vendor, device.model_number, device.technology, device.device_type, device.name)
+    data["description"] = device.description or ""
+    if device.links:
+        data["links"] = list(device.links)
+    if device.additional_technologies:
+        data["additional_technology_configs"] = [{"technology": t} for t in device.additional_technologies]
+    if device.suppressed_rules:
+        data["validation"] = {"suppress": list(device.suppressed_rules)}
+    return data
+
+
+def _locate(path: str, device, data: dict) -> tuple[str, str]:
+    """``(form field, editor url)`` for the lint ``path``."""
+    head = re.split(r"[.\[]", path, maxsplit=1)[0]
+    if head in FORM_FIELDS:
+        return FORM_FIELDS[head], ""
+    match = _TECH_PATH.match(path)
+    if match:
+        rest = match.group(3) or ""
+        if rest == "technology":
+            return ("technology" if match.group(2) is None else "additional_technologies"), ""
+        if not device.pk:
+            return "", ""
+        if match.group(2) is None:
+            technology = device.technology
+        else:
+            configs = data.get("additional_technology_configs") or []
+            index = int(match.group(2))
+            technology = configs[index].get("technology") if index < len(configs) else None
+        register = _REGISTER.match(rest)
+        if technology == "modbus" and register:
+            url = _register_url(device, int(register.group(1)))
+            if url:
+                return "", url
+        page = TECHNOLOGY_PAGES.get(technology)
+        return "", reverse(page, kwargs={"device_pk": device.pk}) if page else ""
+    if head in CONFIG_PAGES and device.pk:
+        return "", reverse(CONFIG_PAGES[head], kwargs={"device_pk": device.pk})
+    return "", ""
+
+
+def _register_url(device, index: int) -> str:
+    """Edit page of the ``index``-th exported register (export order)."""
+    from .models import VendorModel
+
+    try:
+        registers = list(device.modbus_config.register_definitions.all())
+    except VendorModel.modbus_config.RelatedObjectDoesNotExist:
+        return ""
+    if index >= len(registers):
+        return ""
+    return reverse("library:register-edit", kwargs={"pk": registers[index].pk})
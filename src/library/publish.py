@@ -0,0 +1,245 @@
+"""Publishing a library version, one reported step at a time.
+
+A publish pins the latest history version of every model, metric and
+device type into a new ``LibraryVersion`` — on a large library that is
+thousands of rows. ``publish_steps`` does it as a generator of
+``Progress`` (``"Model 12/340"``), the last one carrying the version, so
+the UI can stream it as a progress bar (``VersionCreateView``).
+
+Everything happens in one transaction: a failure half-way, or a consumer
+that stops iterating (the browser cancelled, the connection dropped),
+rolls the whole publish back — no half-written version, and the previous
+one stays current. ``publish_version`` runs it to the end.
+"""
+
+from __future__ import annotations
+
+from collections.abc import Iterator
+from dataclasses import dataclass
+
+from django.db import transaction
+from django.db.models import Max
+
+from .history import record_device_type_history, record_history, record_metric_history
+from .models import (
+    DeviceHistory,
+    DeviceType,
+    DeviceTypeHistory,
+    LibraryVersion,
+    LibraryVersionDevice,
+    LibraryVersionDeviceType,
+    LibraryVersionMetric,
+    Metric,
+    MetricHistory,
+    VendorModel,
+)
+
+
+@dataclass(frozen=True)
+class Progress:
+    message: str
+    done: int = 0
+    total: int = 0
+    version: LibraryVersion | None = None  # set on the last step
+
+    def as_dict(self) -> dict:
+        return {"message": self.message, "done": self.done, "total": self.total}
+
+
+def publish_version(user) -> LibraryVersion:
+    """Publish the next version; returns it."""
+    version = None
+    for step in publish_steps(user):
+        version = step.version or version
+    return version
+
+
+def publish_steps(user) -> Iterator[Progress]:
+    """Publish the next version, yielding progress; see the module docstring."""
+    with transaction.atomic():
+        new_version = (LibraryVersion.objects.aggregate(v=Max("version"))["v"] or 0) + 1
+        yield Progress(f"Preparing v{new_version}")
+
+        # Backfill: ensure every VendorModel has at least one DeviceHistory entry
+        for device in VendorModel.objects.filter(history__isnull=True):
+            record_history(device, DeviceHistory.Action.CREATED, user=None)
+
+        # Mark previous current version as not current
+        LibraryVersion.objects.filter(is_current=True).update(is_current=False)
+
+        # Create the new library version
+        lib_version = LibraryVersion.objects.create(version=new_version, published_by=user, is_current=True)
+
+        # Build previous version's manifest for comparison
+        prev_version = LibraryVersion.objects.filter(version__lt=new_version).order_by("-version").first()
+        prev_manifest = {}
+        if prev_version:
+            for entry in prev_version.device_changes.all():
+                if entry.device_type_id and entry.change_type != LibraryVersionDevice.ChangeType.REMOVED:
+                    prev_manifest[entry.device_type_id] = entry.device_version
+
+        # Snapshot all current devices
+        devices = list(VendorModel.objects.select_related("vendor"))
+        current_device_ids = set()
+        for done, device in enumerate(devices, 1):
+            current_device_ids.add(device.pk)
+            # Get latest DeviceHistory version for this device
+            latest_version = (
+                DeviceHistory.objects.filter(device=device)
+                .order_by("-version")
+                .values_list("version", flat=True)
+                .first()
+            ) or 1
+
+            # Determine change_type
+            if device.pk in prev_manifest:
+                if prev_manifest[device.pk] == latest_version:
+                    change_type = LibraryVersionDevice.ChangeType.UNCHANGED
+                else:
+                    change_type = LibraryVersionDevice.ChangeType.MODIFIED
+            else:
+                change_type = LibraryVersionDevice.ChangeType.ADDED
+
+            LibraryVersionDevice.objects.create(
+                library_version=lib_version,
+                device_type=device,
+                device_version=latest_version,
+                device_label=str(device),
+                change_type=change_type,
+            )
+            yield Progress(f"Model {done}/{len(devices)}: {device}", done, len(devices))
+
+        # Detect removed devices (in previous but not in current)
+        if prev_version:
+            for prev_device_id, prev_device_version in prev_manifest.items():
+                if prev_device_id not in current_device_ids:
+                    # Get label from previous manifest entry
+                    prev_entry = prev_version.device_changes.filter(device_type_id=prev_device_id).first()
+                    label = prev_entry.device_label if prev_entry else "Deleted device"
+                    LibraryVersionDevice.objects.create(
+                        library_version=lib_version,
+                        device_type=None,
+                        device_version=prev_device_version,
+                        device_label=label,
+                        change_type=LibraryVersionDevice.ChangeType.REMOVED,
+                    )
+
+        # -----------------------------------------------------------------
+        # L1 Metric + L2 DeviceType manifest entries — same publish flow
+        # as VendorModel above, applied to the two other versioned entity
+        # types. Without these, retrieve(version=N) would have to fall
+        # back to ``Metric.objects.all()`` and serve the *current* state
+        # rather than the v=N snapshot — see ``LibraryContentViewSet``.
+        # -----------------------------------------------------------------
+        yield from _publish_entities(
+            lib_version,
+            prev_version,
+            Metric, MetricHistory,
+            LibraryVersionMetric,
+            noun="Metric",
+            link_attr="metric",
+            label_attr="key",
+            version_attr="metric_version",
+            label_field="metric_key",
+            prev_relation="metric_changes",
+        )
+        yield from _publish_entities(
+            lib_version,
+            prev_version,
+            DeviceType, DeviceTypeHistory,
+            LibraryVersionDeviceType,
+            noun="Device type",
+            link_attr="device_type",
+            label_attr="code",
+            version_attr="device_type_version",
+            label_field="device_type_code",
+            prev_relation="device_type_changes",
+        )
+    # Only once committed: a consumer that stops at the last step must not roll it back.
+    yield Progress(f"Library version v{new_version} created.", version=lib_version)
+
+
+def _publish_entities(
+    lib_version,
+    prev_version,
+    entity_model,
+    history_model,
+    link_model,
+    *,
+    noun,              # progress label ("Metric" / "Device type")
+    link_attr,         # FK name on link_model ("metric" / "device_type")
+    label_attr,        # field on entity_model used as label ("key" / "code")
+    version_attr,      # version field on link_model ("metric_version" / …)
+    label_field,       # label field on link_model ("metric_key" / "device_type_code")
+    prev_relation,     # reverse FK on LibraryVersion ("metric_changes" / …)
+) -> Iterator[Progress]:
+    """Generic publish step for an L1/L2 entity that mirrors the
+    VendorModel block above. Factored out to keep the two new
+    entity types from duplicating ~30 lines each."""
+    # Backfill: any entity row without a history entry gets a v1
+    # CREATED snapshot so the publish flow can reference it.
+    record_fn = (
+        record_metric_history if entity_model is Metric else record_device_type_history
+    )
+    action_created = (
+        MetricHistory.Action.CREATED
+        if entity_model is Metric
+        else DeviceTypeHistory.Action.CREATED
+    )
+    for ent in entity_model.objects.filter(history__isnull=True):
+        record_fn(ent, action_created, user=None)
+
+    # Previous manifest: which entity → which history version was pinned
+    prev_manifest: dict = {}
+    if prev_version:
+        for entry in getattr(prev_version, prev_relation).all():
+            fk_id = getattr(entry, f"{link_attr}_id")
+            if fk_id and entry.change_type != link_model.ChangeType.REMOVED:
+                prev_manifest[fk_id] = getattr(entry, version_attr)
+
+    entities = list(entity_model.objects.all())
+    current_ids: set = set()
+    for done, ent in enumerate(entities, 1):
+        current_ids.add(ent.pk)
+        latest_version = (
+            history_model.objects.filter(**{link_attr: ent})
+            .order_by("-version")
+            .values_list("version", flat=True)
+            .first()
+        ) or 1
+
+        if ent.pk in prev_manifest:
+            change_type = (
+                link_model.ChangeType.UNCHANGED
+                if prev_manifest[ent.pk] == latest_version
+                else link_model.ChangeType.MODIFIED
+            )
+        else:
+            change_type = link_model.ChangeType.ADDED
+
+        link_model.objects.create(
+            library_version=lib_version,
+            **{link_attr: ent},
+            **{version_attr: latest_version},
+            **{label_field: getattr(ent, label_attr)},
+            change_type=change_type,
+        )
+        yield Progress(f"{noun} {done}/{len(entities)}: {getattr(ent, label_attr)}", done, len(entities))
+
+    if prev_version:
+        for prev_id, prev_ver in prev_manifest.items():
+            if prev_id in current_ids:
+                continue
+            prev_entry = (
+                getattr(prev_version, prev_relation)
+                .filter(**{f"{link_attr}_id": prev_id})
+                .first()
+            )
+            label = getattr(prev_entry, label_field) if prev_entry else f"Deleted {label_attr}"
+            link_model.objects.create(
+                library_version=lib_version,
+                **{link_attr: None},
+                **{version_attr: prev_ver},
+                **{label_field: label},
+                change_type=link_model.ChangeType.REMOVED,
+            )
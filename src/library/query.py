@@ -0,0 +1,532 @@
+"""jq-style queries over the merged device library.
+
+``run_query(expression, document)`` evaluates a practical subset of jq
+against ``library_document()`` — the JSON bundle (manifest with vendors and
+their models inline) plus a flat top-level ``models`` list of every device,
+so most questions start at ``.models[]``::
+
+    .models[] | select(.technology_config.technology == "wmbus") | .model_number
+    [.models[] | select(.vendor_name == "Kamstrup")] | length
+    .models[] | {vendor_name, model_number, registers: (.technology_config.register_definitions // [] | length)}
+
+Supported: ``.``, ``.field``, ``."field"``, ``.[]``, ``.[n]``, ``.["k"]``,
+``?``, ``|``, ``,``, ``//``, ``==`` ``!=`` ``<`` ``<=`` ``>`` ``>=``,
+``and``/``or``, parentheses, literals, ``[...]`` and ``{...}``
+construction, and the functions in ``FUNCTIONS``.
+"""
+
+from __future__ import annotations
+
+import json
+import re
+from collections.abc import Iterator
+from itertools import product
+from pathlib import Path
+
+import yaml
+
+from .exporters import export_to_json
+
+
+class QueryError(ValueError):
+    pass
+
+
+# -----------------------------------------------------------------------------
+# Tokenizer
+# -----------------------------------------------------------------------------
+
+_TOKEN_RE = re.compile(
+    r"""
+    (?P<ws>\s+)
+  | (?P<string>"(?:[^"\\]|\\.)*")
+  | (?P<number>-?\d+(?:\.\d+)?(?:[eE][+-]?\d+)?)
+  | (?P<field>\.[A-Za-z_][A-Za-z0-9_]*)
+  | (?P<ident>[A-Za-z_][A-Za-z0-9_]*)
+  | (?P<op>==|!=|<=|>=|//|[<>|,.\[\](){}:;?])
+    """,
+    re.VERBOSE,
+)
+
+
+def _tokenize(expression: str) -> list[tuple[str, object, int]]:
+    tokens, pos = [], 0
+    while pos < len(expression):
+        m = _TOKEN_RE.match(expression, pos)
+        if not m:
+            raise QueryError(f"Unexpected character {expression[pos]!r} at position {pos}")
+        kind = m.lastgroup
+        text = m.group()
+        if kind == "string":
+            tokens.append(("literal", json.loads(text), pos))
+        elif kind == "number":
+            tokens.append(("literal", float(text) if any(c in text for c in ".eE") else int(text), pos))
+        elif kind == "field":
+            tokens.append(("field", text[1:], pos))
+        elif kind == "ident":
+            tokens.append(("ident", text, pos))
+        elif kind == "op":
+            tokens.append((text, text, pos))
+        pos = m.end()
+    tokens.append(("end", None, pos))
+    return tokens
+
+
+# -----------------------------------------------------------------------------
+# Parser — nodes are tuples ``(kind, *children)``
+# -----------------------------------------------------------------------------
+
+_COMPARISONS = ("==", "!=", "<", "<=", ">", ">=")
+_KEYWORD_LITERALS = {"true": True, "false": False, "null": None}
+
+
+class _Parser:
+    def __init__(self, expression: str):
+        self.tokens = _tokenize(expression)
+        self.i = 0
+
+    @property
+    def kind(self) -> str:
+        return self.tokens[self.i][0]
+
+    def _next(self):
+        token = self.tokens[self.i]
+        self.i += 1
+        return token
+
+    def _unexpected(self, token) -> QueryError:
+        kind, value, pos = token
+        if kind == "end":
+            return QueryError("Unexpected end of expression")
+        return QueryError(f"Unexpected {value!r} at position {pos}")
+
+    def _expect(self, kind: str):
+        if self.kind != kind:
+            raise QueryError(f"Expected {kind!r} at position {self.tokens[self.i][2]}")
+        return self._next()
+
+    def parse(self):
+        node = self.pipe()
+        if self.kind != "end":
+            raise self._unexpected(self.tokens[self.i])
+        return node
+
+    def pipe(self):
+        node = self.comma()
+        while self.kind == "|":
+            self._next()
+            node = ("pipe", node, self.comma())
+        return node
+
+    def comma(self):
+        node = self.alternative()
+        while self.kind == ",":
+            self._next()
+            node = ("comma", node, self.alternative())
+        return node
+
+    def alternative(self):
+        node = self.or_()
+        while self.kind == "//":
+            self._next()
+            node = ("alt", node, self.or_())
+        return node
+
+    def or_(self):
+        node = self.and_()
+        while self.kind == "ident" and self.tokens[self.i][1] == "or":
+            self._next()
+            node = ("or", node, self.and_())
+        return node
+
+    def and_(self):
+        node = self.compare()
+        while self.kind == "ident" and self.tokens[self.i][1] == "and":
+            self._next()
+            node = ("and", node, self.compare())
+        return node
+
+    def compare(self):
+        node = self.postfix()
+        if self.kind in _COMPARISONS:
+            op = self._next()[0]
+            node = ("compare", op, node, self.postfix())
+        return node
+
+    def postfix(self):
+        node = self.primary()
+        while True:
+            if self.kind == "field":
+                node = ("field", node, self._next()[1])
+            elif self.kind == "." and isinstance(self.tokens[self.i + 1][1], str) and self.tokens[self.i + 1][0] == "literal":
+                self._next()
+                node = ("field", node, self._next()[1])
+            elif self.kind == "." and self.tokens[self.i + 1][0] == "[":
+                self._next()
+            elif self.kind == "[":
+                self._next()
+                if self.kind == "]":
+                    self._next()
+                    node = ("iterate", node)
+                else:
+                    node = ("index", node, self.pipe())
+                    self._expect("]")
+            elif self.kind == "?":
+                self._next()
+                node = ("try", node)
+            else:
+                return node
+
+    def primary(self):
+        token = self._next()
+        kind, value, pos = token
+        if kind == "field":
+            return ("field", ("identity",), value)
+        if kind == ".":
+            if self.kind == "literal" and isinstance(self.tokens[self.i][1], str):
+                return ("field", ("identity",), self._next()[1])
+            return ("identity",)
+        if kind == "literal":
+            return ("literal", value)
+        if kind == "(":
+            node = self.pipe()
+            self._expect(")")
+            return node
+        if kind == "[":
+            if self.kind == "]":
+                self._next()
+                return ("literal", [])
+            node = self.pipe()
+            self._expect("]")
+            return ("collect", node)
+        if kind == "{":
+            return self._object()
+        if kind == "ident":
+            if value in _KEYWORD_LITERALS:
+                return ("literal", _KEYWORD_LITERALS[value])
+            if value not in FUNCTIONS:
+                raise QueryError(f"Unknown function {value!r} at position {pos}")
+            args = []
+            if self.kind == "(":
+                self._next()
+                args.append(self.pipe())
+                while self.kind == ";":
+                    self._next()
+                    args.append(self.pipe())
+                self._expect(")")
+            arity = FUNCTIONS[value][0]
+            if len(args) != arity:
+                raise QueryError(f"{value} takes {arity} argument(s), got {len(args)}")
+            return ("call", value, args)
+        raise self._unexpected(token)
+
+    def _object(self):
+        entries = []
+        while self.kind != "}":
+            kind, key, pos = self._next()
+            if kind not in ("ident", "literal") or not isinstance(key, str):
+                raise QueryError(f"Expected an object key at position {pos}")
+            if self.kind == ":":
+                self._next()
+                value = self.alternative()
+            else:
+                value = ("field", ("identity",), key)
+            entries.append((key, value))
+            if self.kind == ",":
+                self._next()
+            elif self.kind != "}":
+                raise QueryError(f"Expected ',' or '}}' at position {self.tokens[self.i][2]}")
+        self._next()
+        return ("object", entries)
+
+
+# -----------------------------------------------------------------------------
+# Evaluation
+# -----------------------------------------------------------------------------
+
+
+def _truthy(value) -> bool:
+    return value is not None and value is not False
+
+
+def _type_name(value) -> str:
+    if value is None:
+        return "null"
+    if isinstance(value, bool):
+        return "boolean"
+    if isinstance(value, int | float):
+        return "number"
+    if isinstance(value, str):
+        return "string"
+    if isinstance(value, list):
+        return "array"
+    return "object"
+
+
+_TYPE_ORDER = ("null", "boolean", "number", "string", "array", "object")
+
+
+def _sort_key(value):
+    """jq's total order: null < false < true < numbers < strings < arrays < objects."""
+    name = _type_name(value)
+    if name == "array":
+        return (_TYPE_ORDER.index(name), [_sort_key(v) for v in value])
+    if name == "object":
+        return (_TYPE_ORDER.index(name), sorted((k, _sort_key(v)) for k, v in value.items()))
+    return (_TYPE_ORDER.index(name), value if value is not None else 0)
+
+
+def _compare(op: str, left, right) -> bool:
+    if op == "==":
+        return left == right
+    if op == "!=":
+        return left != right
+    a, b = _sort_key(left), _sort_key(right)
+    return {"<": a < b, "<=": a <= b, ">": a > b, ">=": a >= b}[op]
+
+
+def _iterate(value) -> Iterator:
+    if isinstance(value, list):
+        return iter(value)
+    if isinstance(value, dict):
+        return iter(value.values())
+    raise QueryError(f"Cannot iterate over {_type_name(value)}")
+
+
+def _contains(a, b) -> bool:
+    if isinstance(a, dict) and isinstance(b, dict):
+        return all(k in a and _contains(a[k], v) for k, v in b.items())
+    if isinstance(a, list) and isinstance(b, list):
+        return all(any(_contains(x, y) for x in a) for y in b)
+    if isinstance(a, str) and isinstance(b, str):
+        return b in a
+    if _type_name(a) != _type_name(b):
+        raise QueryError(f"{_type_name(a)} and {_type_name(b)} cannot have their containment checked")
+    return a == b
+
+
+def _length(value):
+    if value is None:
+        return 0
+    if isinstance(value, bool):
+        raise QueryError("boolean has no length")
+    if isinstance(value, int | float):
+        return abs(value)
+    return len(value)
+
+
+def _add(values):
+    values = [v for v in values if v is not None]
+    if not values:
+        return None
+    result = values[0]
+    for v in values[1:]:
+        if isinstance(result, dict) and isinstance(v, dict):
+            result = {**result, **v}
+        else:
+            try:
+                result = result + v
+            except TypeError as e:
+                raise QueryError(f"Cannot add {_type_name(result)} and {_type_name(v)}") from e
+    return result
+
+
+def _string_arg(name: str, value) -> str:
+    if not isinstance(value, str):
+        raise QueryError(f"{name} requires string input and argument")
+    return value
+
+
+def _call(name: str, args: list, value) -> Iterator:
+    if name == "select":
+        for cond in _eval(args[0], value):
+            if _truthy(cond):
+                yield value
+    elif name == "map":
+        yield [out for item in _iterate(value) for out in _eval(args[0], item)]
+    elif name == "empty":
+        return
+    elif name == "not":
+        yield not _truthy(value)
+    elif name == "length":
+        yield _length(value)
+    elif name == "keys":
+        if isinstance(value, dict):
+            yield sorted(value)
+        elif isinstance(value, list):
+            yield list(range(len(value)))
+        else:
+            raise QueryError(f"{_type_name(value)} has no keys")
+    elif name == "values":
+        yield list(_iterate(value))
+    elif name == "has":
+        for key in _eval(args[0], value):
+            if isinstance(value, dict):
+                yield key in value
+            elif isinstance(value, list) and isinstance(key, int):
+                yield 0 <= key < len(value)
+            else:
+                raise QueryError(f"Cannot check whether {_type_name(value)} has a {_type_name(key)} key")
+    elif name == "contains":
+        for other in _eval(args[0], value):
+            yield _contains(value, other)
+    elif name == "test":
+        for pattern in _eval(args[0], value):
+            yield re.search(_string_arg(name, pattern), _string_arg(name, value)) is not None
+    elif name in ("startswith", "endswith"):
+        for other in _eval(args[0], value):
+            yield getattr(_string_arg(name, value), name)(_string_arg(name, other))
+    elif name == "ascii_downcase":
+        yield _string_arg(name, value).lower()
+    elif name == "ascii_upcase":
+        yield _string_arg(name, value).upper()
+    elif name in ("sort", "unique"):
+        items = sorted(_iterate(value), key=_sort_key)
+        if name == "unique":
+            items = [v for i, v in enumerate(items) if i == 0 or v != items[i - 1]]
+        yield items
+    elif name == "add":
+        yield _add(list(_iterate(value)))
+    elif name == "first":
+        items = list(_iterate(value))
+        yield items[0] if items else None
+    elif name == "last":
+        items = list(_iterate(value))
+        yield items[-1] if items else None
+    elif name == "type":
+        yield _type_name(value)
+
+
+# name -> (arity, one-line help); dispatch lives in ``_call``.
+FUNCTIONS = {
+    "select": (1, "keep the input when the condition is true"),
+    "map": (1, "apply to every element of an array"),
+    "empty": (0, "produce no output"),
+    "not": (0, "boolean negation"),
+    "length": (0, "length of a string, array or object"),
+    "keys": (0, "sorted object keys / array indices"),
+    "values": (0, "object values / array elements as an array"),
+    "has": (1, "whether the object/array has the key/index"),
+    "contains": (1, "recursive containment"),
+    "test": (1, "regex search on a string"),
+    "startswith": (1, "string prefix test"),
+    "endswith": (1, "string suffix test"),
+    "ascii_downcase": (0, "lowercase a string"),
+    "ascii_upcase": (0, "uppercase a string"),
+    "sort": (0, "sort an array"),
+    "unique": (0, "sort an array and drop duplicates"),
+    "add": (0, "sum / concatenate / merge array elements"),
+    "first": (0, "first array element"),
+    "last": (0, "last array element"),
+    "type": (0, "JSON type name"),
+}
+
+
+def _eval(node, value) -> Iterator:
+    kind = node[0]
+    if kind == "identity":
+        yield value
+    elif kind == "literal":
+        yield node[1]
+    elif kind == "field":
+        for base in _eval(node[1], value):
+            if base is None:
+                yield None
+            elif isinstance(base, dict):
+                yield base.get(node[2])
+            else:
+                raise QueryError(f"Cannot index {_type_name(base)} with {node[2]!r}")
+    elif kind == "index":
+        for key in _eval(node[2], value):
+            for base in _eval(node[1], value):
+                if base is None:
+                    yield None
+                elif isinstance(base, list) and isinstance(key, int) and not isinstance(key, bool):
+                    yield base[key] if -len(base) <= key < len(base) else None
+                elif isinstance(base, dict) and isinstance(key, str):
+                    yield base.get(key)
+                else:
+                    raise QueryError(f"Cannot index {_type_name(base)} with {_type_name(key)}")
+    elif kind == "iterate":
+        for base in _eval(node[1], value):
+            yield from _iterate(base)
+    elif kind == "try":
+        try:
+            results = list(_eval(node[1], value))
+        except QueryError:
+            return
+        yield from results
+    elif kind == "pipe":
+        for intermediate in _eval(node[1], value):
+            yield from _eval(node[2], intermediate)
+    elif kind == "comma":
+        yield from _eval(node[1], value)
+        yield from _eval(node[2], value)
+    elif kind == "alt":
+        try:
+            results = [v for v in _eval(node[1], value) if _truthy(v)]
+        except QueryError:
+            results = []
+        yield from results or _eval(node[2], value)
+    elif kind in ("and", "or"):
+        for left in _eval(node[1], value):
+            if kind == "and" and not _truthy(left):
+                yield False
+            elif kind == "or" and _truthy(left):
+                yield True
+            else:
+                for right in _eval(node[2], value):
+                    yield _truthy(right)
+    elif kind == "compare":
+        for right in _eval(node[3], value):
+            for left in _eval(node[2], value):
+                yield _compare(node[1], left, right)
+    elif kind == "collect":
+        yield list(_eval(node[1], value))
+    elif kind == "object":
+        keys = [key for key, _ in node[1]]
+        for combo in product(*(list(_eval(expr, value)) for _, expr in node[1])):
+            yield dict(zip(keys, combo, strict=True))
+    elif kind == "call":
+        yield from _call(node[1], node[2], value)
+
+
+def compile_query(expression: str):
+    """Parse ``expression``; raises ``QueryError`` on syntax errors."""
+    return _Parser(expression).parse()
+
+
+def run_query(expression: str, document) -> list:
+    """All outputs of ``expression`` evaluated against ``document``."""
+    return list(_eval(compile_query(expression), document))
+
+
+# -----------------------------------------------------------------------------
+# Input document
+# -----------------------------------------------------------------------------
+
+
+def library_document(devices_path: str | Path | None = None, manifest_path: str | Path | None = None) -> dict:
+    """The merged library the queries run against.
+
+    From the database by default (the ``export_json`` bundle), or from an
+    exported YAML tree. Either way ``models`` lists every device, each
+    carrying ``vendor_name``; ``device_types``, the name older vendor
+    files use for the list, is the same list.
+    """
+    if devices_path is None:
+        document, _ = export_to_json()
+    else:
+        devices_path = Path(devices_path)
+        document = yaml.safe_load(Path(manifest_path).read_text()) or {}
+        for entry in document.get("vendors") or []:
+            file_path = devices_path / entry.get("file", "")
+            data = (yaml.safe_load(file_path.read_text()) or {}) if file_path.is_file() else {}
+            devices = data.get("models" if "models" in data else "device_types") or []
+            for device in devices:
+                device.setdefault("vendor_name", entry.get("name", ""))
+            entry["models"] = devices
+    document["models"] = document["device_types"] = [
+        m for v in document.get("vendors") or [] for m in v.get("models") or []
+    ]
+    return document
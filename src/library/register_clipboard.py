@@ -0,0 +1,55 @@
+"""Copy registers from one Modbus device and paste them into another.
+
+Meter families share most of their register map, so an editor selects
+registers on one device (*yank*), opens another and pastes them there,
+shifted by an address offset (0 keeps the source's addresses). The
+clipboard is a snippet (``library.snippets``) kept in the session with
+the address it was rebased from; pasting goes through ``insert_snippet``
+and is refused as a whole when an address or field name is taken. It
+stays until the next copy, so one block can be pasted into several
+devices.
+"""
+
+from __future__ import annotations
+
+from dataclasses import dataclass
+
+from .snippets import Snippet, SnippetError, insert_snippet, parse_snippet, snippet_from_registers
+
+CLIPBOARD_KEY = "register_clipboard"
+
+
+@dataclass(frozen=True)
+class Clipboard:
+    source: str  # "Vendor MODEL" the registers were copied from
+    base: int  # the lowest copied address; the snippet starts at 0
+    snippet: Snippet
+
+
+def yank(request, device, registers) -> Clipboard:
+    """Put ``registers`` of ``device`` on the user's clipboard."""
+    registers = list(registers)
+    if not registers:
+        raise SnippetError("No registers selected")
+    snippet = snippet_from_registers(registers, "clipboard", f"registers copied from {device}")
+    clipboard = Clipboard(str(device), min(reg.address for reg in registers), snippet)
+    request.session[CLIPBOARD_KEY] = {"source": clipboard.source, "base": clipboard.base, **snippet.as_dict()}
+    return clipboard
+
+
+def clipboard(request) -> Clipboard | None:
+    data = request.session.get(CLIPBOARD_KEY)
+    if not data:
+        return None
+    data = dict(data)
+    source, base = data.pop("source"), data.pop("base")
+    return Clipboard(source, base, parse_snippet("clipboard", data))
+
+
+def paste(request, device, offset: int = 0, user=None) -> list:
+    """Insert the clipboard's registers into ``device`` at their copied
+    addresses plus ``offset``."""
+    current = clipboard(request)
+    if current is None:
+        raise SnippetError("The register clipboard is empty")
+    return insert_snippet(device, current.snippet, current.base + offset, user)
@@ -0,0 +1,95 @@
+"""Consistency checks for a Modbus register map.
+
+A register map is easy to get subtly wrong when it is typed in from a
+vendor PDF: a ``float32`` at 100 and a ``uint16`` at 101 read overlapping
+words, a field name pasted twice makes one of them unreachable by name,
+and a one-register hole between two fields usually means a data type
+that is one register too narrow rather than a reserved address.
+``analyze_registers`` reports all three, taking each data type's width
+(``devicelib.readplan.REGISTER_WIDTHS``) into account:
+
+- ``overlap`` — a register starts inside the words of an earlier one.
+  Registers at the *same* address are the ``duplicate-register-address``
+  lint rule's (and ``register_merge.find_duplicates``'s) business.
+- ``duplicate-field`` — a field name used by more than one register.
+- ``gap`` — up to ``max_gap`` unmapped registers between two fields.
+  Larger gaps are normal (separate blocks) and not reported.
+
+It works on the exported register shape (``{field: {name}, address,
+data_type}``), so the lint rules and the register table on the device
+page share it.
+"""
+
+from __future__ import annotations
+
+from dataclasses import dataclass
+
+from devicelib.readplan import REGISTER_WIDTHS
+
+SUSPICIOUS_GAP = 2  # unmapped registers between two fields worth a second look
+
+
+@dataclass(frozen=True)
+class RegisterIssue:
+    kind: str  # "overlap" | "duplicate-field" | "gap"
+    index: int  # position in the register list of the register it's reported on
+    message: str
+
+
+def _name(reg: dict) -> str:
+    field = reg.get("field")
+    return (field.get("name") if isinstance(field, dict) else None) or "?"
+
+
+def _width(reg: dict) -> int:
+    return REGISTER_WIDTHS.get(reg.get("data_type"), 1)
+
+
+def _span(reg: dict) -> str:
+    start, width = reg["address"], _width(reg)
+    return str(start) if width == 1 else f"{start}–{start + width - 1}"
+
+
+def analyze_registers(registers: list[dict], max_gap: int = SUSPICIOUS_GAP) -> list[RegisterIssue]:
+    """Overlaps, duplicate field names and suspicious gaps in ``registers``,
+    ordered by address. Registers without an integer address are skipped."""
+    issues: list[RegisterIssue] = []
+    placed = sorted(
+        (
+            (reg["address"], idx, reg) for idx, reg in enumerate(registers)
+            if isinstance(reg, dict) and isinstance(reg.get("address"), int) and not isinstance(reg["address"], bool)
+        ),
+        key=lambda item: item[:2],
+    )
+
+    widest: tuple[int, dict] | None = None  # (end, register) reaching furthest so far
+    first_by_name: dict[str, dict] = {}
+    for address, idx, reg in placed:
+        name = _name(reg)
+        if widest is not None and address != widest[1]["address"]:
+            end, other = widest
+            if address < end:
+                issues.append(RegisterIssue(
+                    "overlap", idx,
+                    f"{name} ({reg.get('data_type')} at {_span(reg)}) overlaps {_name(other)} "
+                    f"({other.get('data_type')} at {_span(other)})",
+                ))
+            elif 0 < address - end <= max_gap:
+                gap = address - end
+                issues.append(RegisterIssue(
+                    "gap", idx,
+                    f"{gap} unmapped register{'s' if gap > 1 else ''} between {_name(other)} (ends at {end - 1}) "
+                    f"and {name} — a data type too narrow, or a missing field?",
+                ))
+        if name != "?":
+            if name in first_by_name:
+                issues.append(RegisterIssue(
+                    "duplicate-field", idx,
+                    f"Field name {name!r} is also used at address {first_by_name[name]['address']}",
+                ))
+            else:
+                first_by_name[name] = reg
+        end = address + _width(reg)
+        if widest is None or end > widest[0]:
+            widest = (end, reg)
+    return issues
@@ -0,0 +1,206 @@
+"""Reconcile an imported register map with a device's existing registers.
+
+Imports (CSV today) used to be all-or-nothing: either append — creating
+duplicates at the same address — or wipe and replace. ``reconcile`` matches
+incoming rows to existing ``RegisterDefinition`` rows by address and
+classifies each as new / changed / unchanged / removed, so the editor can
+accept changes row by row; ``apply_plan`` then applies only the accepted
+rows.
+
+``find_duplicates`` covers the other half: two registers at the same
+address within one device (typically a rebase artifact where both sides
+of a conflict survived). The editor sees them side by side and keeps one
+or merges them; saving a register at an address already taken is
+refused by ``RegisterDefinitionForm``.
+"""
+
+from __future__ import annotations
+
+import csv
+import io
+from dataclasses import dataclass, field
+
+from .exporters import REGISTER_CSV_COLUMNS
+from .models import RegisterDefinition
+
+COMPARED_FIELDS = ("field_name", "field_unit", "data_type", "scale", "offset")
+DISPLAY_FIELDS = ("display_name", "display_precision", "display_icon", "display_category")
+# Everything a duplicate-address merge reconciles.
+MERGED_FIELDS = COMPARED_FIELDS + ("field_description",) + DISPLAY_FIELDS
+
+
+@dataclass
+class RegisterRow:
+    address: int
+    status: str  # "new" | "changed" | "unchanged" | "removed"
+    old: dict | None = None
+    new: dict | None = None
+    changed_fields: list[str] = field(default_factory=list)
+
+    @property
+    def default_accept(self) -> bool:
+        # Removals are opt-in: a partial CSV shouldn't silently drop registers.
+        return self.status in ("new", "changed")
+
+
+def parse_register_csv(text: str) -> list[dict]:
+    """Parse CSV in the ``export_registers`` column layout.
+
+    ``unit`` maps onto ``field_unit``; ``scale``/``offset`` default to 1/0
+    when blank. ``description`` is optional — CSVs exported before it
+    existed leave the registers' descriptions alone. Raises ``ValueError``
+    naming the offending line.
+    """
+    reader = csv.DictReader(io.StringIO(text.lstrip("\ufeff")))
+    missing = {"address", "data_type", "field_name"} - set(reader.fieldnames or [])
+    if missing:
+        raise ValueError(
+            f"CSV is missing column(s): {', '.join(sorted(missing))} "
+            f"(expected {', '.join(REGISTER_CSV_COLUMNS)})"
+        )
+
+    has_description = "description" in (reader.fieldnames or [])
+    valid_types = {choice.value for choice in RegisterDefinition.DataType}
+    rows, seen = [], set()
+    for line, raw in enumerate(reader, start=2):
+        try:
+            address = int(raw["address"])
+            scale = float(raw.get("scale") or 1.0)
+            offset = float(raw.get("offset") or 0.0)
+        except ValueError as e:
+            raise ValueError(f"Line {line}: {e}") from e
+        data_type = (raw.get("data_type") or "").strip()
+        if data_type not in valid_types:
+            raise ValueError(f"Line {line}: unknown data type {data_type!r}")
+        name = (raw.get("field_name") or "").strip()
+        if not name:
+            raise ValueError(f"Line {line}: field_name is empty")
+        if address in seen:
+            raise ValueError(f"Line {line}: duplicate address {address}")
+        seen.add(address)
+        row = {
+            "address": address,
+            "field_name": name,
+            "field_unit": (raw.get("unit") or "").strip(),
+            "data_type": data_type,
+            "scale": scale,
+            "offset": offset,
+        }
+        if has_description:
+            row["field_description"] = (raw.get("description") or "").strip()
+        rows.append(row)
+    return rows
+
+
+def _as_dict(reg: RegisterDefinition) -> dict:
+    return {
+        "address": reg.address,
+        "field_name": reg.field_name,
+        "field_unit": reg.field_unit,
+        "field_description": reg.field_description,
+        "data_type": reg.data_type,
+        "scale": reg.scale,
+        "offset": reg.offset,
+    }
+
+
+def _compared(row: dict) -> tuple[str, ...]:
+    return COMPARED_FIELDS + (("field_description",) if "field_description" in row else ())
+
+
+def reconcile(existing, incoming: list[dict]) -> list[RegisterRow]:
+    """Classify incoming rows against ``existing`` RegisterDefinitions."""
+    old_by_addr = {reg.address: _as_dict(reg) for reg in existing}
+    new_by_addr = {row["address"]: row for row in incoming}
+
+    rows = []
+    for address in sorted(set(old_by_addr) | set(new_by_addr)):
+        old, new = old_by_addr.get(address), new_by_addr.get(address)
+        if old is None:
+            rows.append(RegisterRow(address, "new", new=new))
+        elif new is None:
+            rows.append(RegisterRow(address, "removed", old=old))
+        else:
+            changed = [f for f in _compared(new) if old[f] != new[f]]
+            rows.append(RegisterRow(address, "changed" if changed else "unchanged", old, new, changed))
+    return rows
+
+
+def apply_plan(modbus_config, rows: list[RegisterRow], accepted: set[int]) -> dict:
+    """Apply accepted rows (by address). Returns counts per action."""
+    counts = {"created": 0, "updated": 0, "deleted": 0}
+    for row in rows:
+        if row.address not in accepted:
+            continue
+        if row.status == "new":
+            RegisterDefinition.objects.create(modbus_config=modbus_config, **row.new)
+            counts["created"] += 1
+        elif row.status == "changed":
+            modbus_config.register_definitions.filter(address=row.address).update(
+                **{f: row.new[f] for f in _compared(row.new)}
+            )
+            counts["updated"] += 1
+        elif row.status == "removed":
+            modbus_config.register_definitions.filter(address=row.address).delete()
+            counts["deleted"] += 1
+    return counts
+
+
+@dataclass
+class DuplicateGroup:
+    address: int
+    registers: list  # RegisterDefinition rows sharing the address, oldest first
+    differing_fields: list[str] = field(default_factory=list)
+
+    @property
+    def merged(self) -> dict:
+        """The oldest register's values with blanks filled in from the others."""
+        values = {}
+        for f in MERGED_FIELDS:
+            candidates = [getattr(reg, f) for reg in self.registers]
+            values[f] = next((v for v in candidates if v not in ("", None)), candidates[0])
+        return values
+
+    @property
+    def rows(self) -> list[tuple[str, list, object, bool]]:
+        """``(field, value per register, merged value, differs)`` for the side-by-side view."""
+        merged = self.merged
+        return [
+            (f, [getattr(reg, f) for reg in self.registers], merged[f], f in self.differing_fields)
+            for f in MERGED_FIELDS
+        ]
+
+
+def find_duplicates(registers) -> list[DuplicateGroup]:
+    """Group ``registers`` (one device's) that share an address."""
+    by_addr: dict[int, list] = {}
+    for reg in sorted(registers, key=lambda r: (r.address, r.created)):
+        by_addr.setdefault(reg.address, []).append(reg)
+
+    groups = []
+    for address, regs in sorted(by_addr.items()):
+        if len(regs) < 2:
+            continue
+        differing = [
+            f for f in MERGED_FIELDS
+            if len({getattr(reg, f) for reg in regs}) > 1
+        ]
+        groups.append(DuplicateGroup(address, regs, differing))
+    return groups
+
+
+def resolve_duplicates(group: DuplicateGroup, keep=None) -> RegisterDefinition:
+    """Keep one register of ``group`` and delete the rest.
+
+    ``keep`` is the register to keep as-is; ``None`` merges — the oldest
+    register takes ``group.merged`` values. Returns the surviving register.
+    """
+    survivor = keep if keep is not None else group.registers[0]
+    if keep is None:
+        for f, value in group.merged.items():
+            setattr(survivor, f, value)
+        survivor.save()
+    for reg in group.registers:
+        if reg.pk != survivor.pk:
+            reg.delete()
+    return survivor
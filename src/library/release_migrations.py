@@ -0,0 +1,135 @@
+"""Machine-readable rename/removal manifest between published versions.
+
+Platforms store references into the library — device identities (vendor +
+model number) and decoded field names. When a release renames or removes
+those, ``build_migrations`` describes the change so the platform can remap
+stored references instead of breaking:
+
+- **devices.renamed** — same device ``key`` (stable UUID), different
+  vendor name and/or model number.
+- **devices.removed** — present in the previous release, gone in this one.
+- **fields.renamed** — a Modbus register at the same address whose field
+  name changed, or a processor mapping whose ``target`` stayed the same
+  while its ``source`` changed.
+- **metrics.renamed** / **metrics.removed** — L1 catalogue keys, matched
+  through ``MetricHistory`` row identity.
+
+Everything is derived from the history snapshots pinned by each
+``LibraryVersion``, so no annotations are needed for the common cases.
+"""
+
+from __future__ import annotations
+
+from .models import LibraryVersion, LibraryVersionDevice, LibraryVersionMetric, MetricHistory
+from .version_diff import version_snapshot_map
+
+MIGRATIONS_FORMAT_VERSION = 1
+
+
+def previous_version(lib_version: LibraryVersion) -> LibraryVersion | None:
+    return LibraryVersion.objects.filter(version__lt=lib_version.version).order_by("-version").first()
+
+
+def _identity(snapshot: dict) -> dict:
+    return {"vendor": snapshot.get("vendor", ""), "model_number": snapshot.get("model_number", "")}
+
+
+def _field_renames(old: dict, new: dict) -> list[dict]:
+    renames = []
+    old_regs = {r["address"]: r for r in old.get("registers") or []}
+    for reg in new.get("registers") or []:
+        prev = old_regs.get(reg["address"])
+        if prev and prev["field_name"] != reg["field_name"]:
+            renames.append({
+                "old": prev["field_name"],
+                "new": reg["field_name"],
+                "reason": f"register {reg['address']}",
+            })
+
+    def by_target(snapshot):
+        proc = snapshot.get("processor_config") or {}
+        return {
+            m["target"]: m["source"]
+            for m in [*(proc.get("field_mappings") or []), *(proc.get("extra_mappings") or [])]
+            if m.get("target") and m.get("source")
+        }
+
+    old_targets, new_targets = by_target(old), by_target(new)
+    seen = {(r["old"], r["new"]) for r in renames}
+    for target, source in sorted(new_targets.items()):
+        prev = old_targets.get(target)
+        if prev and prev != source and (prev, source) not in seen:
+            renames.append({"old": prev, "new": source, "reason": f"mapping → {target}"})
+    return renames
+
+
+def _metric_key_map(lib_version: LibraryVersion) -> dict:
+    """{metric pk: key} as pinned by ``lib_version``."""
+    result = {}
+    entries = lib_version.metric_changes.exclude(change_type=LibraryVersionMetric.ChangeType.REMOVED)
+    for entry in entries:
+        if not entry.metric_id:
+            continue
+        snapshot = (
+            MetricHistory.objects.filter(metric_id=entry.metric_id, version=entry.metric_version)
+            .values_list("snapshot", flat=True)
+            .first()
+        )
+        result[entry.metric_id] = (snapshot or {}).get("key") or entry.metric_key
+    return result
+
+
+def build_migrations(lib_version: LibraryVersion, base: LibraryVersion | None = None) -> dict:
+    """Describe renames/removals from ``base`` (default: the previous
+    published version) to ``lib_version``."""
+    base = base or previous_version(lib_version)
+    document = {
+        "format_version": MIGRATIONS_FORMAT_VERSION,
+        "from_version": base.version if base else None,
+        "to_version": lib_version.version,
+        "devices": {"renamed": [], "removed": []},
+        "fields": {"renamed": []},
+        "metrics": {"renamed": [], "removed": []},
+    }
+    if base is None:
+        return document
+
+    old_map, new_map = version_snapshot_map(base), version_snapshot_map(lib_version)
+
+    for device_id in sorted(set(old_map) | set(new_map), key=lambda d: (old_map.get(d) or new_map.get(d))["label"]):
+        old = old_map.get(device_id)
+        new = new_map.get(device_id)
+        if old and not new:
+            document["devices"]["removed"].append({"key": old["snapshot"].get("key"), "label": old["label"]})
+            continue
+        if not old or not new:
+            continue
+        old_snap, new_snap = old["snapshot"], new["snapshot"]
+        if _identity(old_snap) != _identity(new_snap):
+            document["devices"]["renamed"].append({
+                "key": new_snap.get("key"),
+                "old": _identity(old_snap),
+                "new": _identity(new_snap),
+            })
+        for rename in _field_renames(old_snap, new_snap):
+            document["fields"]["renamed"].append({"key": new_snap.get("key"), **_identity(new_snap), **rename})
+
+    # Devices deleted outright lose their FK (SET_NULL) and drop out of the
+    # snapshot maps; the REMOVED entries recorded at publish keep the label.
+    known = {d["label"] for d in document["devices"]["removed"]}
+    for entry in lib_version.device_changes.filter(change_type=LibraryVersionDevice.ChangeType.REMOVED):
+        if entry.device_label not in known:
+            document["devices"]["removed"].append({"key": None, "label": entry.device_label})
+
+    old_metrics, new_metrics = _metric_key_map(base), _metric_key_map(lib_version)
+    for metric_id, old_key in sorted(old_metrics.items(), key=lambda kv: kv[1]):
+        new_key = new_metrics.get(metric_id)
+        if new_key is None:
+            document["metrics"]["removed"].append(old_key)
+        elif new_key != old_key:
+            document["metrics"]["renamed"].append({"old": old_key, "new": new_key})
+    for entry in lib_version.metric_changes.filter(change_type=LibraryVersionMetric.ChangeType.REMOVED):
+        if entry.metric_key and entry.metric_key not in document["metrics"]["removed"]:
+            document["metrics"]["removed"].append(entry.metric_key)
+
+    return document
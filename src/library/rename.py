@@ -0,0 +1,100 @@
+"""Rename a vendor consistently — database or exported YAML tree.
+
+In a tree the vendor name lives in three places that must agree: the
+manifest entry (``name`` + ``file``), the vendor file's name, and
+``vendor_name`` on every device in it. Renaming by hand tends to miss one
+of them. ``alias`` keeps the old name in the manifest entry's ``aliases``
+so ``import_yaml`` renames the existing vendor row instead of creating a
+second vendor, and ``--vendors <old>`` selections keep working.
+"""
+
+from __future__ import annotations
+
+from pathlib import Path
+
+import yaml
+from django.db import transaction
+from django.utils.text import slugify
+
+from .history import record_history, snapshot_device
+from .models import DeviceHistory, Vendor
+from .safe_write import TreeWriter
+from .yaml_format import canonical_manifest, canonical_vendor_file, dump_yaml
+
+
+class RenameError(Exception):
+    pass
+
+
+class RenameConflict(RenameError):
+    """The new name (or its file) is already taken."""
+
+
+def rename_vendor_in_tree(
+    devices_path: str | Path,
+    manifest_path: str | Path,
+    old: str,
+    new: str,
+    alias: bool = False,
+    writer: TreeWriter | None = None,
+) -> dict:
+    """Rewrite manifest entry, vendor file name and ``vendor_name`` fields.
+
+    Returns ``{"old_file", "new_file", "devices"}``.
+    """
+    devices_path, manifest_path = Path(devices_path), Path(manifest_path)
+    writer = writer or TreeWriter()
+    manifest = yaml.safe_load(manifest_path.read_text()) or {}
+    vendors = manifest.get("vendors") or []
+
+    old_slug, new_slug = slugify(old), slugify(new)
+    entry = next(
+        (v for v in vendors if old_slug in (slugify(v.get("name", "")), slugify(Path(v.get("file", "")).stem))),
+        None,
+    )
+    if entry is None:
+        raise RenameError(f"Vendor not in manifest: {old}")
+    if any(v is not entry and slugify(v.get("name", "")) == new_slug for v in vendors):
+        raise RenameConflict(f"Vendor already exists: {new}")
+
+    old_name, old_file = entry["name"], entry["file"]
+    new_file = f"{new_slug}{Path(old_file).suffix or '.yaml'}"
+    if new_file != old_file and (devices_path / new_file).exists():
+        raise RenameConflict(f"File already exists: {devices_path / new_file}")
+
+    data = yaml.safe_load((devices_path / old_file).read_text()) or {}
+    devices_key = "models" if "models" in data else "device_types"
+    for device in data.get(devices_key) or []:
+        device["vendor_name"] = new
+
+    entry["name"], entry["file"] = new, new_file
+    if alias:
+        aliases = [a for a in entry.get("aliases") or [] if slugify(a) != new_slug]
+        if old_name not in aliases:
+            aliases.append(old_name)
+        entry["aliases"] = aliases
+
+    writer.write(devices_path / new_file, dump_yaml(canonical_vendor_file(data)))
+    if new_file != old_file:
+        writer.remove(devices_path / old_file)
+    writer.write(manifest_path, dump_yaml(canonical_manifest(manifest)))
+    return {"old_file": old_file, "new_file": new_file, "devices": len(data.get(devices_key) or [])}
+
+
+def rename_vendor_in_database(old: str, new: str, user=None) -> Vendor:
+    """Rename the vendor row (name + slug), recording history per model."""
+    vendor = Vendor.objects.filter(slug=slugify(old)).first() or Vendor.objects.filter(name=old).first()
+    if vendor is None:
+        raise RenameError(f"Vendor not found: {old}")
+    if Vendor.objects.filter(slug=slugify(new)).exclude(pk=vendor.pk).exists():
+        raise RenameConflict(f"Vendor already exists: {new}")
+
+    with transaction.atomic():
+        devices = list(vendor.device_types.all())
+        snapshots = {d.pk: snapshot_device(d) for d in devices}
+        vendor.name, vendor.slug = new, slugify(new)
+        vendor.save()
+        for device in devices:
+            device.vendor = vendor
+            record_history(device, DeviceHistory.Action.UPDATED, user, snapshots[device.pk])
+    return vendor
@@ -0,0 +1,116 @@
+"""Find and replace across devices.
+
+Renaming a quantity (``active_power_total`` → ``power_active_total``),
+fixing a unit spelling (``KWh`` → ``kWh``) or a recurring typo in the
+descriptions means the same edit on dozens of devices. ``find_hits``
+finds every occurrence in one vendor's file (``vendor``) or in all of
+them, and says what each would become; nothing is written until the
+hits picked from that preview are applied (``FindReplaceView``).
+
+What can be rewritten (``TARGETS``):
+
+- ``field_name`` — register field names,
+- ``unit`` — register units,
+- ``description`` — device descriptions and register descriptions.
+
+Matching is literal, case-insensitive unless ``match_case``;
+``whole_value`` only matches a value that is exactly ``find`` (so
+``W`` → ``kW`` leaves ``Wh`` alone). A replacement that would leave a
+required value empty or overflow its column is reported on the hit and
+never applied.
+"""
+
+from __future__ import annotations
+
+import re
+from dataclasses import dataclass
+
+from .models import RegisterDefinition, VendorModel
+
+TARGETS = [
+    ("field_name", "Register field names"),
+    ("unit", "Units"),
+    ("description", "Descriptions"),
+]
+
+# target -> (model, attribute, label) for every column it covers.
+COLUMNS = {
+    "field_name": [(RegisterDefinition, "field_name", "field name")],
+    "unit": [(RegisterDefinition, "field_unit", "unit")],
+    "description": [
+        (VendorModel, "description", "description"),
+        (RegisterDefinition, "field_description", "description"),
+    ],
+}
+
+# Columns that must not end up empty.
+REQUIRED = {(RegisterDefinition, "field_name")}
+
+
+@dataclass(frozen=True)
+class Hit:
+    key: str  # "<model>:<pk>:<attribute>", names the hit in the preview form
+    device: VendorModel
+    obj: object  # the VendorModel or RegisterDefinition holding the value
+    attr: str
+    where: str  # "register 40001 (voltage_l1) unit"
+    old: str
+    new: str
+    problem: str = ""  # why this hit can't be applied
+
+
+def replace_value(value: str, find: str, replace: str, match_case: bool = False, whole_value: bool = False):
+    """``value`` with ``find`` replaced, or ``None`` when it doesn't occur."""
+    flags = 0 if match_case else re.IGNORECASE
+    pattern = re.escape(find)
+    if whole_value:
+        pattern = rf"\A{pattern}\Z"
+    new, count = re.subn(pattern, lambda _: replace, value, flags=flags)
+    return new if count else None
+
+
+def find_hits(
+    find: str,
+    replace: str,
+    targets,
+    vendor=None,
+    match_case: bool = False,
+    whole_value: bool = False,
+) -> list[Hit]:
+    """Every occurrence of ``find`` in ``targets``, in one vendor's devices or all."""
+    if not find:
+        return []
+    lookup = "" if match_case else "i"
+    hits = []
+    for target in targets:
+        for model, attr, label in COLUMNS[target]:
+            field = model._meta.get_field(attr)
+            filters = {f"{attr}__{lookup}exact" if whole_value else f"{attr}__{lookup}contains": find}
+            if model is VendorModel:
+                queryset = model.objects.select_related("vendor").filter(**filters)
+                if vendor is not None:
+                    queryset = queryset.filter(vendor=vendor)
+            else:
+                queryset = model.objects.select_related("modbus_config__device_type__vendor").filter(**filters)
+                if vendor is not None:
+                    queryset = queryset.filter(modbus_config__device_type__vendor=vendor)
+            for obj in queryset:
+                old = getattr(obj, attr)
+                new = replace_value(old, find, replace, match_case, whole_value)
+                if new is None or new == old:
+                    continue
+                if model is VendorModel:
+                    device, where = obj, label
+                else:
+                    device, where = obj.modbus_config.device_type, f"register {obj.address} ({obj.field_name}) {label}"
+                problem = ""
+                if not new and (model, attr) in REQUIRED:
+                    problem = f"The {label} can't be empty."
+                elif field.max_length and len(new) > field.max_length:
+                    problem = f"Longer than the {field.max_length} characters a {label} can have."
+                key = f"{model._meta.model_name}:{obj.pk}:{attr}"
+                hits.append(Hit(key, device, obj, attr, where, old, new, problem))
+    # Device by device, the device's own description before its registers by address.
+    return sorted(hits, key=lambda h: (
+        h.device.vendor.name, h.device.model_number, getattr(h.obj, "address", -1), h.attr,
+    ))
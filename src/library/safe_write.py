@@ -0,0 +1,83 @@
+"""Crash-safe writes for the exported YAML tree.
+
+Vendor files are rewritten by several tools (export, ``fmt_yaml``,
+scaffolding). Content is always serialized in full before the target is
+touched, written to a temp file in the same directory and moved into place
+with ``os.replace`` — so a serialization error or a killed process leaves
+the previous file intact instead of a truncated one. The previous content
+is kept next to the file as ``<name>.bak``.
+"""
+
+from __future__ import annotations
+
+import difflib
+import os
+import shutil
+import tempfile
+from pathlib import Path
+
+
+def atomic_write(path: str | Path, content: str, backup: bool = True) -> None:
+    """Replace ``path`` with ``content`` atomically, keeping ``<path>.bak``."""
+    path = Path(path)
+    path.parent.mkdir(parents=True, exist_ok=True)
+    fd, tmp_name = tempfile.mkstemp(dir=path.parent, prefix=f".{path.name}.", suffix=".tmp")
+    try:
+        with os.fdopen(fd, "w", encoding="utf-8") as f:
+            f.write(content)
+            f.flush()
+            os.fsync(f.fileno())
+        if path.exists():
+            shutil.copymode(path, tmp_name)
+            if backup:
+                shutil.copy2(path, path.with_name(path.name + ".bak"))
+        os.replace(tmp_name, path)
+    except BaseException:
+        Path(tmp_name).unlink(missing_ok=True)
+        raise
+
+
+class TreeWriter:
+    """Write (or, with ``dry_run``, only diff) files of the YAML tree.
+
+    ``write`` skips files whose content is unchanged; every changed file is
+    recorded in ``changed`` and its unified diff in ``diffs``.
+    """
+
+    def __init__(self, dry_run: bool = False, backup: bool = True):
+        self.dry_run = dry_run
+        self.backup = backup
+        self.changed: list[Path] = []
+        self.diffs: list[str] = []
+
+    def write(self, path: str | Path, content: str) -> bool:
+        path = Path(path)
+        old = path.read_text(encoding="utf-8") if path.exists() else ""
+        if path.exists() and old == content:
+            return False
+        self.changed.append(path)
+        self.diffs.append("".join(difflib.unified_diff(
+            old.splitlines(keepends=True),
+            content.splitlines(keepends=True),
+            fromfile=str(path) if old else "/dev/null",
+            tofile=str(path),
+        )))
+        if not self.dry_run:
+            atomic_write(path, content, backup=self.backup)
+        return True
+
+    def remove(self, path: str | Path) -> bool:
+        """Delete ``path`` (moved to ``<name>.bak`` when backups are on)."""
+        path = Path(path)
+        if not path.exists():
+            return False
+        self.changed.append(path)
+        self.diffs.append("".join(difflib.unified_diff(
+            path.read_text(encoding="utf-8").splitlines(keepends=True), [], fromfile=str(path), tofile="/dev/null",
+        )))
+        if not self.dry_run:
+            if self.backup:
+                os.replace(path, path.with_name(path.name + ".bak"))
+            else:
+                path.unlink()
+        return True
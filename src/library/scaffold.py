@@ -0,0 +1,114 @@
+"""Skeleton device definitions for scripted onboarding.
+
+``skeleton_device`` returns a well-formed schema-v4 device dict with the
+technology block pre-shaped (empty ``register_definitions`` for Modbus,
+required wM-Bus keys present) so it imports cleanly and lints with only
+the expected "fill me in" findings. ``append_to_tree`` writes it into an
+exported YAML tree, creating the vendor file and manifest entry when the
+vendor is new.
+"""
+
+from __future__ import annotations
+
+from pathlib import Path
+
+import yaml
+from django.utils.text import slugify
+
+from .safe_write import TreeWriter
+from .yaml_format import canonical_manifest, canonical_vendor_file, device_technologies, dump_yaml
+
+
+class ScaffoldError(Exception):
+    pass
+
+
+def skeleton_device(vendor_name: str, model_number: str, technology: str, device_type: str, name: str = "") -> dict:
+    tech_config: dict = {"technology": technology}
+    if technology == "modbus":
+        tech_config["register_definitions"] = []
+    elif technology == "wmbus":
+        tech_config.update({"manufacturer_code": "", "wmbus_device_type": None, "encryption_required": False})
+
+    return {
+        "vendor_name": vendor_name,
+        "model_number": model_number,
+        "name": name or model_number,
+        "device_type": device_type,
+        "description": "",
+        "technology_config": tech_config,
+        "control_config": {},
+        "processor_config": {"field_mappings": []},
+    }
+
+
+def append_to_tree(
+    devices_path: str | Path, manifest_path: str | Path, device: dict, writer: TreeWriter | None = None,
+) -> Path:
+    """Append ``device`` to its vendor file; returns the file written.
+
+    Raises ``ScaffoldError`` when the model number already exists for the
+    vendor. Files go through ``writer`` (a ``safe_write.TreeWriter``).
+    """
+    writer = writer or TreeWriter()
+    devices_path = Path(devices_path)
+    manifest_path = Path(manifest_path)
+    if not manifest_path.exists():
+        raise ScaffoldError(f"Manifest not found: {manifest_path}")
+
+    with open(manifest_path) as f:
+        manifest = yaml.safe_load(f) or {}
+    vendors = manifest.setdefault("vendors", [])
+
+    vendor_name = device["vendor_name"]
+    entry = next((v for v in vendors if slugify(v.get("name", "")) == slugify(vendor_name)), None)
+    new_vendor = entry is None
+    if new_vendor:
+        entry = {"name": vendor_name, "file": f"{slugify(vendor_name)}.yaml"}
+        vendors.append(entry)
+
+    file_path = devices_path / entry["file"]
+    data = {}
+    if file_path.exists():
+        with open(file_path) as f:
+            data = yaml.safe_load(f) or {}
+    devices_key = "device_types" if "device_types" in data and "models" not in data else "models"
+    models = data.setdefault(devices_key, [])
+
+    if any((m.get("model_number") or "").strip().lower() == device["model_number"].strip().lower() for m in models):
+        raise ScaffoldError(f"{vendor_name} {device['model_number']} already exists in {entry['file']}")
+
+    models.append(device)
+    writer.write(file_path, dump_yaml(canonical_vendor_file(data)))
+    technologies = device_technologies(models)
+    if new_vendor or entry.get("technologies", technologies) != technologies:
+        entry["technologies"] = technologies
+        writer.write(manifest_path, dump_yaml(canonical_manifest(manifest)))
+    return file_path
+
+
+def create_in_database(device: dict, user=None):
+    """Create the skeleton as a ``VendorModel`` (plus empty technology config)."""
+    from .history import record_history
+    from .importers import _resolve_device_type_fk
+    from .models import DeviceHistory, ModbusConfig, Vendor, VendorModel
+
+    vendor, _ = Vendor.objects.get_or_create(
+        slug=slugify(device["vendor_name"]),
+        defaults={"name": device["vendor_name"]},
+    )
+    if VendorModel.objects.filter(vendor=vendor, model_number__iexact=device["model_number"]).exists():
+        raise ScaffoldError(f"{vendor.name} {device['model_number']} already exists")
+
+    vm = VendorModel.objects.create(
+        vendor=vendor,
+        model_number=device["model_number"],
+        name=device["name"],
+        device_type=device["device_type"],
+        device_type_fk=_resolve_device_type_fk(device),
+        technology=device["technology_config"]["technology"],
+    )
+    if vm.technology == VendorModel.Technology.MODBUS:
+        ModbusConfig.objects.create(device_type=vm)
+    record_history(vm, DeviceHistory.Action.CREATED, user)
+    return vm
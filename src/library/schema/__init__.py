@@ -0,0 +1,245 @@
+"""JSON Schema documents for the exported YAML tree, and a validator.
+
+The ``*.schema.json`` files next to this module describe the current
+schema_version: ``manifest.schema.json``, ``vendor-file.schema.json``
+(a file under ``devices/``), ``device.schema.json`` (one entry of its
+``models`` list) and one sub-schema per technology under
+``technologies/``, selected by ``technology_config.technology``. They are
+plain JSON Schema (draft 2020-12), so vendors can point their editor or CI
+at them; ``validate`` checks parsed documents against them here without a
+third-party dependency.
+
+The validator implements the keywords the shipped schemas use (see
+``KEYWORDS``); a schema using any other keyword is rejected on load
+rather than silently not enforced. ``$ref`` resolves ``#/$defs/...``
+within a document and file names relative to it.
+
+Unknown keys show up as ``additionalProperties`` errors; ``lint``'s
+``schema`` rule leaves those to ``unknown-key``, which suggests the key
+that was probably meant.
+"""
+
+from __future__ import annotations
+
+import json
+import re
+from dataclasses import dataclass
+from functools import cache
+from pathlib import Path, PurePosixPath
+
+SCHEMA_DIR = Path(__file__).parent
+SCHEMAS = {
+    "manifest": "manifest.schema.json",
+    "vendor-file": "vendor-file.schema.json",
+    "device": "device.schema.json",
+}
+
+# Validation keywords ``validate`` understands; annotations are ignored.
+KEYWORDS = {
+    "type", "enum", "const", "properties", "required", "additionalProperties", "items", "minItems",
+    "uniqueItems", "minimum", "maximum", "minLength", "maxLength", "pattern", "$ref", "allOf", "if", "then",
+}
+ANNOTATIONS = {"$schema", "$id", "$defs", "title", "description", "default", "examples", "$comment"}
+
+_TYPES = {
+    "object": lambda v: isinstance(v, dict),
+    "array": lambda v: isinstance(v, list),
+    "string": lambda v: isinstance(v, str),
+    "integer": lambda v: isinstance(v, int) and not isinstance(v, bool),
+    "number": lambda v: isinstance(v, int | float) and not isinstance(v, bool),
+    "boolean": lambda v: isinstance(v, bool),
+    "null": lambda v: v is None,
+}
+
+
+class SchemaDefinitionError(ValueError):
+    """A shipped schema is broken (bad ``$ref``, unsupported keyword)."""
+
+
+@dataclass(frozen=True)
+class SchemaError:
+    path: str  # "technology_config.register_definitions[0].address"; "" for the document
+    message: str
+    keyword: str
+
+    def __str__(self) -> str:
+        return f"{self.path}: {self.message}" if self.path else self.message
+
+
+@cache
+def load_schema(file_name: str) -> dict:
+    """The schema document ``file_name`` (relative to ``SCHEMA_DIR``)."""
+    path = SCHEMA_DIR / file_name
+    try:
+        schema = json.loads(path.read_text())
+    except (OSError, ValueError) as e:
+        raise SchemaDefinitionError(f"Can't load schema {file_name}: {e}") from e
+    _check_keywords(schema, file_name)
+    return schema
+
+
+def _check_keywords(schema, where: str) -> None:
+    if isinstance(schema, bool):
+        return
+    unknown = set(schema) - KEYWORDS - ANNOTATIONS
+    if unknown:
+        raise SchemaDefinitionError(f"{where}: unsupported keyword(s) {', '.join(sorted(unknown))}")
+    for key in ("items", "additionalProperties", "if", "then"):
+        if isinstance(schema.get(key), dict):
+            _check_keywords(schema[key], where)
+    for sub in [*(schema.get("properties") or {}).values(), *(schema.get("$defs") or {}).values()]:
+        _check_keywords(sub, where)
+    for sub in schema.get("allOf") or []:
+        _check_keywords(sub, where)
+
+
+def validate(document, name: str) -> list[SchemaError]:
+    """Errors of ``document`` against the ``SCHEMAS[name]`` schema
+    (``manifest``, ``vendor-file`` or ``device``), in document order."""
+    try:
+        file_name = SCHEMAS[name]
+    except KeyError:
+        raise ValueError(f"Unknown schema {name!r} (expected one of {', '.join(SCHEMAS)})") from None
+    errors: list[SchemaError] = []
+    _Validator(file_name, errors).check(document, load_schema(file_name), "")
+    return errors
+
+
+def validate_manifest(manifest) -> list[SchemaError]:
+    return validate(manifest, "manifest")
+
+
+def validate_vendor_file(data) -> list[SchemaError]:
+    return validate(data, "vendor-file")
+
+
+def validate_device(device) -> list[SchemaError]:
+    return validate(device, "device")
+
+
+def _join(path: str, key) -> str:
+    if isinstance(key, int):
+        return f"{path}[{key}]"
+    return f"{path}.{key}" if path else str(key)
+
+
+def _describe(value) -> str:
+    for name, matches in _TYPES.items():
+        if name != "number" and matches(value):
+            return name
+    return "number" if _TYPES["number"](value) else type(value).__name__
+
+
+def _equal(a, b) -> bool:
+    """JSON equality for ``const``, ``enum`` and ``uniqueItems``: like
+    ``==``, except a boolean only equals a boolean (``1 != True``)."""
+    if isinstance(a, bool) or isinstance(b, bool):
+        return a is b
+    if isinstance(a, list) and isinstance(b, list):
+        return len(a) == len(b) and all(_equal(x, y) for x, y in zip(a, b))
+    if isinstance(a, dict) and isinstance(b, dict):
+        return a.keys() == b.keys() and all(_equal(a[k], b[k]) for k in a)
+    return a == b
+
+
+class _Validator:
+    def __init__(self, file_name: str, errors: list[SchemaError]):
+        self.file_name = file_name
+        self.errors = errors
+
+    def error(self, path: str, message: str, keyword: str) -> None:
+        self.errors.append(SchemaError(path, message, keyword))
+
+    def resolve(self, ref: str) -> tuple[str, dict]:
+        """``(file name, schema)`` a ``$ref`` points at."""
+        target, _, fragment = ref.partition("#")
+        file_name = self.file_name
+        if target:
+            file_name = str(PurePosixPath(self.file_name).parent / target)
+        schema = load_schema(file_name)
+        for part in [p for p in fragment.split("/") if p]:
+            try:
+                schema = schema[part]
+            except (KeyError, TypeError):
+                raise SchemaDefinitionError(f"{self.file_name}: unresolvable $ref {ref!r}") from None
+        return file_name, schema
+
+    def matches(self, value, schema: dict) -> bool:
+        """Whether ``value`` validates against ``schema`` (for ``if``)."""
+        probe = _Validator(self.file_name, [])
+        probe.check(value, schema, "")
+        return not probe.errors
+
+    def check(self, value, schema, path: str) -> None:
+        if schema is True:
+            return
+        if schema is False:
+            self.error(path, "Not allowed here", "false")
+            return
+
+        if "$ref" in schema:
+            file_name, target = self.resolve(schema["$ref"])
+            _Validator(file_name, self.errors).check(value, target, path)
+
+        if "type" in schema:
+            types = schema["type"] if isinstance(schema["type"], list) else [schema["type"]]
+            if not any(_TYPES[t](value) for t in types):
+                self.error(path, f"Expected {' or '.join(types)}, got {_describe(value)}", "type")
+                return  # the remaining keywords assume the right type
+        if "const" in schema and not _equal(value, schema["const"]):
+            self.error(path, f"Must be {schema['const']!r}", "const")
+        if "enum" in schema and not any(_equal(value, option) for option in schema["enum"]):
+            self.error(path, f"{value!r} is not one of {', '.join(map(str, schema['enum']))}", "enum")
+
+        for sub in schema.get("allOf") or []:
+            self.check(value, sub, path)
+        if "if" in schema and "then" in schema and self.matches(value, schema["if"]):
+            self.check(value, schema["then"], path)
+
+        if isinstance(value, dict):
+            self.check_object(value, schema, path)
+        elif isinstance(value, list):
+            self.check_array(value, schema, path)
+        elif isinstance(value, str):
+            self.check_string(value, schema, path)
+        elif _TYPES["number"](value):
+            if "minimum" in schema and value < schema["minimum"]:
+                self.error(path, f"{value} is less than {schema['minimum']}", "minimum")
+            if "maximum" in schema and value > schema["maximum"]:
+                self.error(path, f"{value} is greater than {schema['maximum']}", "maximum")
+
+    def check_object(self, value: dict, schema: dict, path: str) -> None:
+        for key in schema.get("required") or []:
+            if key not in value:
+                self.error(_join(path, key), "Required key is missing", "required")
+        properties = schema.get("properties") or {}
+        additional = schema.get("additionalProperties", True)
+        for key, item in value.items():
+            if key in properties:
+                self.check(item, properties[key], _join(path, key))
+            elif additional is False:
+                self.error(_join(path, key), f"Unknown key {key!r}", "additionalProperties")
+            elif isinstance(additional, dict):
+                self.check(item, additional, _join(path, key))
+
+    def check_array(self, value: list, schema: dict, path: str) -> None:
+        if "minItems" in schema and len(value) < schema["minItems"]:
+            self.error(path, f"Needs at least {schema['minItems']} item(s)", "minItems")
+        if schema.get("uniqueItems"):
+            seen = []
+            for idx, item in enumerate(value):
+                if any(_equal(item, other) for other in seen):
+                    self.error(_join(path, idx), f"Duplicate item {item!r}", "uniqueItems")
+                seen.append(item)
+        if "items" in schema:
+            for idx, item in enumerate(value):
+                self.check(item, schema["items"], _join(path, idx))
+
+    def check_string(self, value: str, schema: dict, path: str) -> None:
+        if "minLength" in schema and len(value) < schema["minLength"]:
+            self.error(path, "Must not be empty" if schema["minLength"] == 1 else
+                       f"Shorter than {schema['minLength']} characters", "minLength")
+        if "maxLength" in schema and len(value) > schema["maxLength"]:
+            self.error(path, f"Longer than {schema['maxLength']} characters", "maxLength")
+        if "pattern" in schema and not re.search(schema["pattern"], value):
+            self.error(path, f"{value!r} does not match {schema['pattern']}", "pattern")
@@ -0,0 +1,136 @@
+"""Schema-version migrations for an exported YAML tree.
+
+Each step in ``MIGRATIONS`` upgrades a whole tree — the manifest plus
+every vendor file it lists — from ``version - 1`` to ``version``, in
+order. ``migrate_tree`` reads the tree's ``schema_version``, runs the
+steps up to the requested version and rewrites the changed files in
+place (atomically, with ``.bak`` backups); ``manage.py migrate_schema``
+is the command-line front end.
+
+The importer still understands older files, but consumers that read the
+tree directly (``devicelib``, Spark) only understand the current schema.
+A format change therefore ships as a new step here together with the
+``DEFAULT_SCHEMA_VERSION`` bump, so existing trees can be upgraded
+instead of silently going stale. Steps are pure functions of the
+documents; they must leave an already-migrated tree unchanged.
+
+Downgrades are not supported.
+"""
+
+from __future__ import annotations
+
+import copy
+from collections.abc import Callable
+from dataclasses import dataclass, field
+from pathlib import Path
+
+from ..safe_write import TreeWriter
+from ..yaml_content import read_yaml
+from ..yaml_format import canonical_manifest, canonical_vendor_file, dump_yaml
+from . import v3, v4
+
+
+class SchemaMigrationError(ValueError):
+    pass
+
+
+@dataclass(frozen=True)
+class SchemaMigration:
+    version: int  # schema_version the step produces
+    description: str
+    # (manifest, {file name: vendor file document}) → notes; mutates in place
+    apply: Callable[[dict, dict[str, dict]], list[str]]
+
+
+MIGRATIONS: tuple[SchemaMigration, ...] = (
+    SchemaMigration(3, v3.DESCRIPTION, v3.migrate),
+    SchemaMigration(4, v4.DESCRIPTION, v4.migrate),
+)
+OLDEST_SCHEMA_VERSION = MIGRATIONS[0].version - 1
+LATEST_SCHEMA_VERSION = MIGRATIONS[-1].version
+
+
+@dataclass
+class MigrationResult:
+    from_version: int
+    to_version: int
+    applied: list[SchemaMigration] = field(default_factory=list)
+    notes: list[str] = field(default_factory=list)
+    changed: list[Path] = field(default_factory=list)
+
+
+def schema_version(manifest: dict) -> int | None:
+    value = manifest.get("schema_version")
+    if value is None:
+        return None
+    try:
+        return int(value)
+    except (TypeError, ValueError):
+        raise SchemaMigrationError(f"schema_version {value!r} is not an integer") from None
+
+
+def plan(from_version: int, to_version: int) -> list[SchemaMigration]:
+    """The steps taking a tree from ``from_version`` to ``to_version``."""
+    if to_version > LATEST_SCHEMA_VERSION:
+        raise SchemaMigrationError(f"No migration to schema_version {to_version} (latest is {LATEST_SCHEMA_VERSION})")
+    if from_version < OLDEST_SCHEMA_VERSION:
+        raise SchemaMigrationError(
+            f"schema_version {from_version} is older than the oldest migratable ({OLDEST_SCHEMA_VERSION})"
+        )
+    if to_version < from_version:
+        raise SchemaMigrationError(
+            f"Tree is at schema_version {from_version}; downgrading to {to_version} is not supported"
+        )
+    return [m for m in MIGRATIONS if from_version < m.version <= to_version]
+
+
+def migrate_documents(
+    manifest: dict, vendor_files: dict[str, dict], to_version: int = LATEST_SCHEMA_VERSION,
+    from_version: int | None = None,
+) -> MigrationResult:
+    """Upgrade ``manifest`` and ``vendor_files`` in place.
+
+    ``from_version`` overrides the manifest's ``schema_version`` (needed
+    when the manifest carries none).
+    """
+    if from_version is None:
+        from_version = schema_version(manifest)
+    if from_version is None:
+        raise SchemaMigrationError("Manifest has no schema_version; say which version the tree is at")
+    result = MigrationResult(from_version, to_version)
+    for migration in plan(from_version, to_version):
+        notes = migration.apply(manifest, vendor_files)
+        result.applied.append(migration)
+        result.notes += [f"v{migration.version}: {note}" for note in notes]
+    if result.applied or manifest.get("schema_version") != to_version:
+        manifest["schema_version"] = to_version
+    return result
+
+
+def migrate_tree(
+    devices_path: str | Path, manifest_path: str | Path, to_version: int = LATEST_SCHEMA_VERSION,
+    from_version: int | None = None, writer: TreeWriter | None = None,
+) -> MigrationResult:
+    """Upgrade the tree on disk; files whose documents changed are
+    rewritten through ``writer`` (a dry-run ``TreeWriter`` collects diffs)."""
+    devices_path = Path(devices_path)
+    manifest_path = Path(manifest_path)
+    writer = writer or TreeWriter()
+
+    manifest = read_yaml(manifest_path) or {}
+    vendor_files = {}
+    for entry in manifest.get("vendors", []) or []:
+        path = devices_path / entry["file"]
+        if entry["file"] not in vendor_files and path.is_file():
+            vendor_files[entry["file"]] = read_yaml(path) or {}
+    before = copy.deepcopy((manifest, vendor_files))
+
+    result = migrate_documents(manifest, vendor_files, to_version, from_version)
+
+    if manifest != before[0]:
+        writer.write(manifest_path, dump_yaml(canonical_manifest(manifest)))
+    for name, data in vendor_files.items():
+        if data != before[1][name]:
+            writer.write(devices_path / name, dump_yaml(canonical_vendor_file(data)))
+    result.changed = list(writer.changed)
+    return result
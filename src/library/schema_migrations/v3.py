@@ -0,0 +1,26 @@
+"""Schema 2 → 3: the L2 device type catalogue.
+
+v3 added the manifest's top-level ``device_types`` section (devices point
+into it with ``device_type_key``) and renamed the vendor files' device
+list from ``device_types`` to ``models`` to free the name.
+"""
+
+from __future__ import annotations
+
+DESCRIPTION = "Add the manifest device_types section; vendor files list devices under models"
+
+
+def migrate(manifest: dict, vendor_files: dict[str, dict]) -> list[str]:
+    notes = []
+    if "device_types" not in manifest:
+        # The importer seeds the well-known types; an empty catalogue keeps
+        # resolving devices by their ``device_type`` code.
+        manifest["device_types"] = []
+        notes.append("manifest: added empty device_types section")
+    for name, data in vendor_files.items():
+        if "models" not in data and isinstance(data.get("device_types"), list):
+            vendor_files[name] = {
+                ("models" if key == "device_types" else key): value for key, value in data.items()
+            }
+            notes.append(f"{name}: device_types renamed to models")
+    return notes
@@ -0,0 +1,120 @@
+"""Schema 3 → 4: the L1 metric catalogue and single-slot mappings.
+
+- The manifest gains a ``metrics`` catalogue; every metric a mapping or
+  device type refers to gets an entry (a stub for keys it lacks).
+- Device types declare ``metrics: [{metric, tier}]`` instead of
+  ``default_field_mappings`` (``primary`` becomes tier ``primary``).
+- ``processor_config`` merges ``extra_field_mappings`` into
+  ``field_mappings`` and drops the per-entry ``unit`` / ``primary`` /
+  ``transform`` (resolved from L1 / L2 now).
+- ``control_config.capabilities`` is gone (typed ``controls`` replaced it).
+
+The importer applies the same conversions to v3 files it reads.
+"""
+
+from __future__ import annotations
+
+DESCRIPTION = "Add the metrics catalogue; merge field mapping slots; device types declare metrics"
+
+LEGACY_ENTRY_KEYS = ("unit", "primary", "transform", "metric")
+
+
+def convert_field_mappings(base: list[dict], extras: list[dict]) -> list[dict]:
+    """v3 ``field_mappings`` + ``extra_field_mappings`` → v4 ``field_mappings``.
+
+    Extras win on a source collision (the historical effective-list
+    order); entries keep ``source`` / ``target`` and non-default
+    ``scale`` / ``offset``. Entries without a target are dropped.
+    """
+    if extras:
+        extra_sources = {e.get("source") for e in extras if e.get("source")}
+        merged = [m for m in base if m.get("source") not in extra_sources] + list(extras)
+    else:
+        merged = list(base)
+
+    out: list[dict] = []
+    for entry in merged:
+        target = entry.get("target") or entry.get("metric")
+        if not target:
+            continue
+        new_entry = {"source": entry.get("source"), "target": target}
+        if entry.get("scale") not in (None, 1):
+            new_entry["scale"] = entry["scale"]
+        if entry.get("offset") not in (None, 0):
+            new_entry["offset"] = entry["offset"]
+        out.append(new_entry)
+    return out
+
+
+def convert_default_field_mappings(legacy: list[dict]) -> list[dict]:
+    """v3 ``default_field_mappings`` (``[{source, target, primary?}]``) →
+    v4 ``metrics`` (``[{metric, tier}]``), first occurrence per target."""
+    seen = set()
+    out: list[dict] = []
+    for entry in legacy or []:
+        target = entry.get("target")
+        if not target or target in seen:
+            continue
+        seen.add(target)
+        out.append({"metric": target, "tier": "primary" if entry.get("primary") else "secondary"})
+    return out
+
+
+def stub_metric(key: str, unit: str = "") -> dict:
+    """The catalogue entry for a metric only known by its key."""
+    return {
+        "key": key,
+        "label": key.split(":", 1)[-1].replace("_", " ").title(),
+        "unit": unit or "",
+        "data_type": "decimal",
+        "description": "",
+    }
+
+
+def _is_legacy(proc: dict) -> bool:
+    return "extra_field_mappings" in proc or any(
+        isinstance(entry, dict) and any(key in entry for key in LEGACY_ENTRY_KEYS)
+        for entry in proc.get("field_mappings") or []
+    )
+
+
+def migrate(manifest: dict, vendor_files: dict[str, dict]) -> list[str]:
+    notes = []
+    referenced: dict[str, str] = {}  # metric key → unit seen on a v3 entry
+
+    for device_type in manifest.get("device_types") or []:
+        if "metrics" not in device_type:
+            legacy = device_type.pop("default_field_mappings", None) or []
+            device_type["metrics"] = convert_default_field_mappings(legacy)
+            notes.append(f"device type {device_type.get('code', '?')}: default_field_mappings → metrics")
+        for entry in device_type["metrics"]:
+            referenced.setdefault(entry.get("metric"), "")
+
+    for name, data in vendor_files.items():
+        for device in data.get("models") or []:
+            label = f"{name}: {device.get('model_number', '?')}"
+            proc = device.get("processor_config")
+            if isinstance(proc, dict) and _is_legacy(proc):
+                base, extras = proc.get("field_mappings") or [], proc.pop("extra_field_mappings", None) or []
+                for entry in [*base, *extras]:
+                    if target := entry.get("target") or entry.get("metric"):
+                        referenced[target] = entry.get("unit") or referenced.get(target, "")
+                proc["field_mappings"] = convert_field_mappings(base, extras)
+                notes.append(f"{label}: field mappings merged into one slot")
+            elif isinstance(proc, dict):
+                for entry in [*(proc.get("field_mappings") or []), *(proc.get("extra_mappings") or [])]:
+                    if entry.get("target"):
+                        referenced.setdefault(entry["target"], "")
+            control = device.get("control_config")
+            if isinstance(control, dict) and "capabilities" in control:
+                del control["capabilities"]
+                notes.append(f"{label}: dropped control_config.capabilities")
+
+    if not isinstance(manifest.get("metrics"), list):
+        manifest["metrics"] = []
+    catalogue = manifest["metrics"]
+    known = {m.get("key") for m in catalogue}
+    for key in sorted(k for k in referenced if k and k not in known):
+        catalogue.append(stub_metric(key, referenced[key]))
+        notes.append(f"metrics: added stub entry for {key}")
+    return notes
@@ -0,0 +1,243 @@
+"""Full-text search across the device library.
+
+Queries are split on whitespace and every term must match somewhere on the
+device (vendor name, device name, model number, description or a register
+field name) — ``"schneider pm"`` finds Schneider's PM-series meters even
+though no single field contains both words.
+
+``search_queryset`` filters the database (used by the model list and the
+``search_library`` command); ``search_yaml`` scans an exported YAML tree
+and reports file/line locations for each matching field.
+
+``jump_candidates`` backs the ``g m`` jump prompt, which goes straight to
+a device by model number or part of its name, whatever its vendor.
+
+``quick_search`` backs the ``/`` finder overlay: it matches vendors,
+devices and register field names *fuzzily* — each term only has to occur
+as a subsequence (``"schn pm51"`` finds the Schneider PM5110) — and ranks
+contiguous, word-start matches first.
+"""
+
+from __future__ import annotations
+
+import re
+from dataclasses import asdict, dataclass
+from pathlib import Path
+
+import yaml
+
+# Device-level keys searched in YAML sources, mirroring the DB lookups below.
+SEARCH_KEYS = ("vendor_name", "name", "model_number", "description")
+
+
+def search_terms(query: str) -> list[str]:
+    return [t.lower() for t in query.split() if t]
+
+
+def search_queryset(queryset, query: str):
+    """Narrow a VendorModel queryset to devices matching every term."""
+    from django.db.models import Q
+
+    for term in search_terms(query):
+        queryset = queryset.filter(
+            Q(vendor__name__icontains=term)
+            | Q(name__icontains=term)
+            | Q(model_number__icontains=term)
+            | Q(description__icontains=term)
+            | Q(modbus_config__register_definitions__field_name__icontains=term)
+        )
+    return queryset.distinct()
+
+
+def jump_candidates(query: str) -> list:
+    """Devices a ``g m`` jump for ``query`` could mean: those whose model
+    number is exactly ``query`` when there are any, otherwise every device
+    whose model number or name contains it."""
+    from django.db.models import Q
+
+    from .models import VendorModel
+
+    query = query.strip()
+    if not query:
+        return []
+    devices = VendorModel.objects.select_related("vendor")
+    exact = list(devices.filter(model_number__iexact=query))
+    return exact or list(devices.filter(Q(model_number__icontains=query) | Q(name__icontains=query)))
+
+
+@dataclass
+class SearchHit:
+    device: str
+    field: str
+    value: str
+    file: str = ""
+    line: int = 0
+
+    @property
+    def location(self) -> str:
+        return f"{self.file}:{self.line}" if self.file else ""
+
+
+def _scalar(node) -> str:
+    return node.value if isinstance(node, yaml.ScalarNode) else ""
+
+
+def _get(mapping_node, key: str):
+    for k, v in mapping_node.value:
+        if _scalar(k) == key:
+            return v
+    return None
+
+
+def _device_fields(device_node) -> list[tuple[str, str, int]]:
+    """(field, value, 1-based line) for every searchable scalar of a device."""
+    fields = []
+    for key in SEARCH_KEYS:
+        node = _get(device_node, key)
+        if node is not None and _scalar(node):
+            fields.append((key, _scalar(node), node.start_mark.line + 1))
+
+    tech = _get(device_node, "technology_config")
+    regs = _get(tech, "register_definitions") if isinstance(tech, yaml.MappingNode) else None
+    if isinstance(regs, yaml.SequenceNode):
+        for reg in regs.value:
+            field = _get(reg, "field") if isinstance(reg, yaml.MappingNode) else None
+            name = _get(field, "name") if isinstance(field, yaml.MappingNode) else None
+            if name is not None and _scalar(name):
+                fields.append(("field_name", _scalar(name), name.start_mark.line + 1))
+    return fields
+
+
+def _technology(device_node) -> str:
+    tech = _get(device_node, "technology_config")
+    return _scalar(_get(tech, "technology")) if isinstance(tech, yaml.MappingNode) else ""
+
+
+def search_yaml(
+    devices_path: str | Path,
+    manifest_path: str | Path,
+    query: str,
+    technology: str | None = None,
+) -> list[SearchHit]:
+    """Search vendor files listed in the manifest, keeping line numbers.
+
+    Uses the YAML node graph rather than the loaded dicts so each hit can
+    point at the exact line in the source file.
+    """
+    terms = search_terms(query)
+    devices_path = Path(devices_path)
+    with open(manifest_path) as f:
+        manifest = yaml.safe_load(f) or {}
+
+    hits: list[SearchHit] = []
+    for vendor_entry in manifest.get("vendors", []) or []:
+        file_path = devices_path / vendor_entry["file"]
+        if not file_path.exists():
+            continue
+        with open(file_path) as f:
+            root = yaml.compose(f)
+        if not isinstance(root, yaml.MappingNode):
+            continue
+        devices = _get(root, "models") or _get(root, "device_types")
+        if not isinstance(devices, yaml.SequenceNode):
+            continue
+
+        for device_node in devices.value:
+            if not isinstance(device_node, yaml.MappingNode):
+                continue
+            if technology and _technology(device_node) != technology:
+                continue
+            fields = _device_fields(device_node)
+            vendor_name = _scalar(_get(device_node, "vendor_name")) or vendor_entry.get("name", "")
+            # Vendor name from the manifest counts for matching even when
+            # the device entry omits it.
+            haystack = " ".join([vendor_name, *(value for _, value, _ in fields)]).lower()
+            if not all(term in haystack for term in terms):
+                continue
+            label = f"{vendor_name} {_scalar(_get(device_node, 'model_number'))}"
+            for field, value, line in fields:
+                if any(term in value.lower() for term in terms):
+                    hits.append(SearchHit(label, field, value, vendor_entry["file"], line))
+    return hits
+
+
+# -----------------------------------------------------------------------------
+# Fuzzy finder
+# -----------------------------------------------------------------------------
+
+QUICK_LIMIT = 20
+_WORD_START = re.compile(r"(?:^|[\s_\-./])(\w)")
+
+
+def fuzzy_score(term: str, text: str) -> int | None:
+    """How well ``term`` matches ``text`` (higher is better), or None when
+    its characters don't all occur in ``text`` in order."""
+    term, text = term.lower(), text.lower()
+    if not term:
+        return 0
+    index = text.find(term)
+    if index != -1:
+        starts = {m.start(1) for m in _WORD_START.finditer(text)}
+        return 100 + (50 if index == 0 else 25 if index in starts else 0) - min(index, 20)
+    score, pos, previous = 50, 0, -1
+    for char in term:
+        pos = text.find(char, pos)
+        if pos == -1:
+            return None
+        if previous != -1:
+            score -= min(pos - previous - 1, 5)  # gaps between matched characters
+        previous, pos = pos, pos + 1
+    return score
+
+
+def _match(terms: list[str], text: str) -> int | None:
+    scores = [fuzzy_score(term, text) for term in terms]
+    return None if None in scores else sum(scores)
+
+
+@dataclass(frozen=True)
+class QuickMatch:
+    kind: str  # "vendor" | "device" | "register"
+    label: str
+    detail: str
+    url: str
+    score: int
+
+    def as_dict(self) -> dict:
+        return asdict(self)
+
+
+def quick_search(query: str, limit: int = QUICK_LIMIT) -> list[QuickMatch]:
+    """Best fuzzy matches for ``query`` among vendors, devices (vendor,
+    name and model number together) and register field names."""
+    from django.urls import reverse
+
+    from .models import RegisterDefinition, Vendor, VendorModel
+
+    terms = search_terms(query)
+    if not terms:
+        return []
+    matches: list[QuickMatch] = []
+    for slug, name in Vendor.objects.values_list("slug", "name"):
+        score = _match(terms, name)
+        if score is not None:
+            url = reverse("library:vendor-detail", kwargs={"slug": slug})
+            matches.append(QuickMatch("vendor", name, "Vendor", url, score))
+    for device in VendorModel.objects.select_related("vendor"):
+        score = _match(terms, f"{device.vendor.name} {device.model_number} {device.name}")
+        if score is not None:
+            url = reverse("library:model-detail", kwargs={"pk": device.pk})
+            label = f"{device.vendor.name} {device.model_number}"
+            matches.append(QuickMatch("device", label, device.name, url, score))
+    registers = RegisterDefinition.objects.values_list(
+        "pk", "field_name", "address", "modbus_config__device_type__model_number",
+        "modbus_config__device_type__vendor__name",
+    )
+    for pk, field_name, address, model_number, vendor_name in registers:
+        score = _match(terms, field_name)
+        if score is not None:
+            url = reverse("library:register-edit", kwargs={"pk": pk})
+            detail = f"Register {address} · {vendor_name} {model_number}"
+            matches.append(QuickMatch("register", field_name, detail, url, score))
+    matches.sort(key=lambda m: (-m.score, m.label.lower()))
+    return matches[:limit]
@@ -0,0 +1,240 @@
+"""Register snippets — reusable register blocks for new Modbus definitions.
+
+Most register maps repeat a handful of blocks: the three-phase voltage /
+current / power / energy set of an electricity meter, the energy, volume
+and temperature records of a heat meter behind a gateway. A snippet is
+such a block, kept as one YAML file under ``snippets/`` at the repository
+root (``settings.SNIPPETS_DIR``)::
+
+    name: Three-phase electricity
+    description: Per-phase voltage, current and power plus total energy.
+    register_definitions:
+      - field: {name: voltage_l1, unit: V}
+        address: 0
+        data_type: float32
+
+Registers use the exported ``register_definitions`` schema with addresses
+relative to the start of the block; ``insert_snippet`` shifts them to a
+base address when the block is added to a device. Snippets are picked
+from a device's register list in the UI and maintained with
+``manage.py snippets``.
+"""
+
+from __future__ import annotations
+
+import re
+from dataclasses import dataclass, field
+from pathlib import Path
+
+import yaml
+from django.conf import settings
+from django.db import transaction
+
+from devicelib.readplan import REGISTER_WIDTHS
+
+from .history import record_history, snapshot_device
+from .models import DeviceHistory, ModbusConfig, RegisterDefinition
+from .safe_write import atomic_write
+from .strict import unknown_keys
+from .yaml_format import _canonical_register, dump_yaml
+
+SNIPPET_SUFFIX = ".yaml"
+KEY_PATTERN = re.compile(r"^[a-z0-9][a-z0-9-]*$")
+SNIPPET_KEYS = {"name", "description", "register_definitions"}
+
+
+class SnippetError(ValueError):
+    pass
+
+
+@dataclass(frozen=True)
+class Snippet:
+    key: str  # file stem, e.g. "three-phase-electricity"
+    name: str
+    description: str = ""
+    registers: tuple[dict, ...] = field(default_factory=tuple)
+
+    @property
+    def span(self) -> int:
+        """Registers the block occupies, from its first address to past its last value."""
+        if not self.registers:
+            return 0
+        return max(r["address"] + REGISTER_WIDTHS.get(r.get("data_type", "uint16"), 1) for r in self.registers)
+
+    def as_dict(self) -> dict:
+        return {
+            "name": self.name,
+            **({"description": self.description} if self.description else {}),
+            "register_definitions": [_canonical_register(dict(r)) for r in self.registers],
+        }
+
+
+def snippets_dir() -> Path:
+    return Path(settings.SNIPPETS_DIR)
+
+
+def _path(key: str, directory: str | Path | None) -> Path:
+    if not KEY_PATTERN.match(key):
+        raise SnippetError(f"Snippet key {key!r} must be lowercase letters, digits and dashes")
+    return Path(directory or snippets_dir()) / f"{key}{SNIPPET_SUFFIX}"
+
+
+def parse_snippet(key: str, data) -> Snippet:
+    """Validate a snippet document; ``SnippetError`` lists every problem."""
+    if not isinstance(data, dict):
+        raise SnippetError(f"{key}: a snippet is a mapping with name and register_definitions")
+    problems = [f"Unknown key {k!r}" for k in data if k not in SNIPPET_KEYS]
+    registers = data.get("register_definitions")
+    if not isinstance(registers, list) or not registers:
+        problems.append("register_definitions must be a non-empty list")
+        registers = []
+    problems += [f"{path}: {message}" for path, message in unknown_keys(
+        {"technology_config": {"technology": "modbus", "register_definitions": registers}}
+    )]
+    seen = {}
+    for idx, reg in enumerate(registers):
+        if not isinstance(reg, dict):
+            problems.append(f"register_definitions[{idx}]: not a mapping")
+            continue
+        name = reg["field"].get("name") if isinstance(reg.get("field"), dict) else None
+        address = reg.get("address")
+        if not name:
+            problems.append(f"register_definitions[{idx}]: field.name is required")
+        if not isinstance(address, int) or isinstance(address, bool) or address < 0:
+            problems.append(f"register_definitions[{idx}]: address must be a non-negative integer")
+        elif address in seen:
+            problems.append(f"register_definitions[{idx}]: address {address} is also used by {seen[address]}")
+        else:
+            seen[address] = name
+        if reg.get("data_type", "uint16") not in RegisterDefinition.DataType.values:
+            problems.append(f"register_definitions[{idx}]: unknown data_type {reg.get('data_type')!r}")
+    if not data.get("name"):
+        problems.append("name is required")
+    if problems:
+        raise SnippetError(f"{key}: " + "; ".join(problems))
+    return Snippet(
+        key=key,
+        name=str(data["name"]),
+        description=str(data.get("description") or ""),
+        registers=tuple(sorted(registers, key=lambda r: r["address"])),
+    )
+
+
+def load_snippet(key: str, directory: str | Path | None = None) -> Snippet:
+    path = _path(key, directory)
+    if not path.is_file():
+        raise SnippetError(f"No snippet {key!r} in {path.parent}")
+    try:
+        data = yaml.safe_load(path.read_text(encoding="utf-8"))
+    except yaml.YAMLError as e:
+        raise SnippetError(f"{path.name}: {e}") from e
+    return parse_snippet(key, data)
+
+
+def load_snippets(directory: str | Path | None = None) -> list[Snippet]:
+    """Every snippet in ``directory``, by name; a missing directory is empty."""
+    directory = Path(directory or snippets_dir())
+    if not directory.is_dir():
+        return []
+    snippets = [load_snippet(path.stem, directory) for path in sorted(directory.glob(f"*{SNIPPET_SUFFIX}"))]
+    return sorted(snippets, key=lambda s: s.name.lower())
+
+
+def save_snippet(snippet: Snippet, directory: str | Path | None = None) -> Path:
+    path = _path(snippet.key, directory)
+    parse_snippet(snippet.key, snippet.as_dict())
+    atomic_write(path, dump_yaml(snippet.as_dict()), backup=False)
+    return path
+
+
+def delete_snippet(key: str, directory: str | Path | None = None) -> None:
+    path = _path(key, directory)
+    if not path.is_file():
+        raise SnippetError(f"No snippet {key!r} in {path.parent}")
+    path.unlink()
+
+
+def snippet_from_device(
+    device, key: str, name: str, description: str = "", start: int | None = None, end: int | None = None,
+) -> Snippet:
+    """A snippet of ``device``'s registers in ``[start, end]``, rebased to 0."""
+    registers = RegisterDefinition.objects.filter(modbus_config__device_type=device).order_by("address")
+    if start is not None:
+        registers = registers.filter(address__gte=start)
+    if end is not None:
+        registers = registers.filter(address__lte=end)
+    registers = list(registers)
+    if not registers:
+        raise SnippetError(f"{device} has no registers in that range")
+    return snippet_from_registers(registers, key, name, description)
+
+
+def snippet_from_registers(registers, key: str, name: str, description: str = "") -> Snippet:
+    """A snippet of ``registers`` (``RegisterDefinition`` rows), rebased so
+    the lowest address is 0."""
+    registers = sorted(registers, key=lambda reg: reg.address)
+    base = registers[0].address
+    return parse_snippet(key, {
+        "name": name,
+        "description": description,
+        "register_definitions": [
+            {
+                "field": {
+                    "name": reg.field_name,
+                    "unit": reg.field_unit,
+                    **({"description": reg.field_description} if reg.field_description else {}),
+                },
+                "scale": reg.scale,
+                "offset": reg.offset,
+                "address": reg.address - base,
+                "data_type": reg.data_type,
+                **({"display": reg.display} if reg.display else {}),
+            }
+            for reg in registers
+        ],
+    })
+
+
+def insert_snippet(device, snippet: Snippet, base_address: int, user=None) -> list[RegisterDefinition]:
+    """Add ``snippet``'s registers to ``device`` starting at ``base_address``.
+
+    Refuses (``SnippetError``) when an address or field name is already
+    taken, so a block is inserted whole or not at all.
+    """
+    if base_address < 0:
+        raise SnippetError("Base address must not be negative")
+    existing = RegisterDefinition.objects.filter(modbus_config__device_type=device)
+    taken_addresses = dict(existing.values_list("address", "field_name"))
+    taken_names = set(taken_addresses.values())
+    clashes = []
+    for reg in snippet.registers:
+        address, name = base_address + reg["address"], reg["field"]["name"]
+        if address in taken_addresses:
+            clashes.append(f"address {address} is used by {taken_addresses[address]}")
+        if name in taken_names:
+            clashes.append(f"field {name} already exists")
+    if clashes:
+        raise SnippetError(f"Cannot insert {snippet.name}: " + "; ".join(clashes))
+
+    with transaction.atomic():
+        old_snapshot = snapshot_device(device)
+        modbus_config, _ = ModbusConfig.objects.get_or_create(device_type=device)
+        created = []
+        for reg in snippet.registers:
+            spec, display = reg["field"], reg.get("display") or {}
+            created.append(RegisterDefinition.objects.create(
+                modbus_config=modbus_config,
+                field_name=spec["name"],
+                field_unit=spec.get("unit", "") or "",
+                field_description=spec.get("description", "") or "",
+                address=base_address + reg["address"],
+                data_type=reg.get("data_type", "uint16"),
+                scale=reg.get("scale", 1.0),
+                offset=reg.get("offset", 0.0),
+                display_name=display.get("name", "") or "",
+                display_precision=display.get("precision"),
+                display_icon=display.get("icon", "") or "",
+                display_category=display.get("category", "") or "",
+            ))
+        record_history(device, DeviceHistory.Action.UPDATED, user, old_snapshot)
+    return created
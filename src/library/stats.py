@@ -0,0 +1,60 @@
+"""Coverage statistics for the device library.
+
+``library_stats`` aggregates the catalogue into plain dicts/lists so the
+``stats`` command can print them as text or JSON for dashboards that
+track library growth over time.
+"""
+
+from __future__ import annotations
+
+from django.db.models import Count, Q
+
+from .models import VendorModel
+
+
+def library_stats() -> dict:
+    devices = VendorModel.objects.all()
+    total = devices.count()
+
+    per_vendor = list(
+        devices.values("vendor__name").annotate(count=Count("id")).order_by("-count", "vendor__name")
+    )
+    per_technology = list(devices.values("technology").annotate(count=Count("id")).order_by("-count", "technology"))
+    per_type = list(devices.values("device_type").annotate(count=Count("id")).order_by("-count", "device_type"))
+
+    controllable = devices.filter(control_config__controllable=True).count()
+    missing_description = devices.filter(Q(description="") | Q(description__isnull=True)).select_related("vendor")
+    # A device "has metrics" once its processor config maps at least one
+    # field — otherwise nothing it reports reaches the platform.
+    missing_metrics = [
+        d for d in devices.select_related("vendor", "processor_config") if not _has_mappings(d)
+    ]
+
+    register_counts = list(
+        devices.filter(technology=VendorModel.Technology.MODBUS)
+        .annotate(register_count=Count("modbus_config__register_definitions"))
+        .values_list("register_count", flat=True)
+    )
+
+    return {
+        "devices": total,
+        "vendors": len(per_vendor),
+        "per_vendor": [{"vendor": r["vendor__name"], "count": r["count"]} for r in per_vendor],
+        "per_technology": [{"technology": r["technology"], "count": r["count"]} for r in per_technology],
+        "per_device_type": [{"device_type": r["device_type"], "count": r["count"]} for r in per_type],
+        "controllable": controllable,
+        "controllable_share": round(controllable / total, 4) if total else 0.0,
+        "missing_description": sorted(str(d) for d in missing_description),
+        "missing_metrics": sorted(str(d) for d in missing_metrics),
+        "avg_registers_per_modbus_device": (
+            round(sum(register_counts) / len(register_counts), 2) if register_counts else 0.0
+        ),
+    }
+
+
+def _has_mappings(device: VendorModel) -> bool:
+    try:
+        proc = device.processor_config
+    except VendorModel.processor_config.RelatedObjectDoesNotExist:
+        return False
+    return bool(proc.field_mappings or proc.extra_mappings)
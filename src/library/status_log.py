@@ -0,0 +1,61 @@
+"""Status log — the history of the messages the app has shown.
+
+A status message (a save, a codec fetch, a publish, an error) is shown
+once, at the top of the next page, and is gone after that. The status log
+keeps them: ``StatusLogStorage`` is the ``MESSAGE_STORAGE`` and records
+every message it is given in the session — the last ``STATUS_LOG_LIMIT``,
+with time, level and the page it came from — for the log panel that
+``L`` toggles.
+
+Each entry also goes to the ``library.status`` logger, so
+``STATUS_LOG_FILE`` writes the same stream to disk; management commands
+take ``--log-file`` for theirs (``library.management.base``).
+"""
+
+from __future__ import annotations
+
+import logging
+
+from django.contrib.messages import constants
+from django.contrib.messages.storage.fallback import FallbackStorage
+from django.contrib.messages.utils import get_level_tags
+from django.utils import timezone
+
+STATUS_LOG_LIMIT = 200
+STATUS_LOG_KEY = "status_log"
+
+logger = logging.getLogger("library.status")
+
+
+def record(request, level: int, message: str) -> None:
+    """Append ``message`` to the session's status log and the ``library.status`` logger."""
+    user = getattr(request, "user", None)
+    logger.log(
+        logging.INFO if level == constants.SUCCESS else level, "%s %s: %s",
+        getattr(user, "username", "") or "-", request.path, message,
+    )
+    if not hasattr(request, "session"):
+        return
+    entry = {
+        "time": timezone.now().isoformat(timespec="seconds"),
+        "level": get_level_tags().get(level, ""),
+        "message": message,
+        "path": request.path,
+    }
+    request.session[STATUS_LOG_KEY] = [*request.session.get(STATUS_LOG_KEY, []), entry][-STATUS_LOG_LIMIT:]
+
+
+def entries(request) -> list[dict]:
+    """The session's status log, newest first."""
+    if not hasattr(request, "session"):
+        return []
+    return list(reversed(request.session.get(STATUS_LOG_KEY, [])))
+
+
+class StatusLogStorage(FallbackStorage):
+    """Django's default message storage, recording each message it accepts."""
+
+    def add(self, level, message, extra_tags=""):
+        super().add(level, message, extra_tags)
+        if message and level >= self.level:
+            record(self.request, level, str(message))
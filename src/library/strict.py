@@ -0,0 +1,106 @@
+"""Known keys of the YAML device schema, for strict decoding.
+
+The importer reads the keys it knows and ignores the rest, so a typo such
+as ``registr_definitions`` silently becomes dead data. ``unknown_keys``
+walks a device definition and reports every key the importer would
+ignore, with a close-match suggestion where one exists. It backs the
+``unknown-key`` lint rule (an error by default, so ``lint_library`` in CI
+catches typos) and ``import_from_yaml(strict=True)``.
+
+Keys older importers accepted (``extra_field_mappings``, ``capabilities``)
+are still known — they're migrated or discarded on import, not typos.
+"""
+
+from __future__ import annotations
+
+import difflib
+
+DEVICE_KEYS = {
+    "vendor_name", "model_number", "name", "device_type", "description",
+    "technology_config", "additional_technology_configs", "control_config", "processor_config",
+    "device_type_key", "alarm_config", "links", "validation",
+}
+TECHNOLOGY_KEYS = {
+    "modbus": {"technology", "function", "byte_order", "word_order", "register_definitions"},
+    "lorawan": {
+        "technology", "device_class", "lorawan_version", "lorawan_phy_version", "frequency_plan_id",
+        "join_eui_default", "supports_join", "downlink_f_port", "payload_codec",
+    },
+    "wmbus": {
+        "technology", "manufacturer_code", "wmbus_version", "wmbus_device_type", "encryption_required",
+        "shared_encryption_key", "wmbusmeters_driver", "is_mvt_default",
+    },
+}
+REGISTER_KEYS = {"field", "scale", "offset", "address", "data_type", "display"}
+REGISTER_FIELD_KEYS = {"name", "unit", "description"}
+DISPLAY_KEYS = {"name", "precision", "icon", "category"}
+PAYLOAD_CODEC_KEYS = {"format", "script", "source"}
+CODEC_SOURCE_KEYS = {"url", "sha256", "fetched_at"}
+CONTROL_KEYS = {"controllable", "controls", "capabilities"}
+PROCESSOR_KEYS = {"decoder_type", "field_mappings", "extra_mappings", "extra_field_mappings"}
+ALARM_KEYS = {"mappings"}
+LINK_KEYS = {"kind", "url", "title"}
+VALIDATION_KEYS = {"suppress"}
+
+
+class UnknownKeyError(ValueError):
+    pass
+
+
+def _unknown(data, known: set[str], path: str):
+    if not isinstance(data, dict):
+        return
+    for key in data:
+        if key in known:
+            continue
+        where = f"{path}.{key}" if path else str(key)
+        message = f"Unknown key {key!r}"
+        suggestion = difflib.get_close_matches(str(key), sorted(known), n=1)
+        if suggestion:
+            message += f" (did you mean {suggestion[0]!r}?)"
+        yield where, message
+
+
+def _unknown_tech(tech, path: str):
+    known_tech = TECHNOLOGY_KEYS.get(tech.get("technology") if isinstance(tech, dict) else None)
+    if not known_tech:
+        return
+    yield from _unknown(tech, known_tech, path)
+    for idx, reg in enumerate(tech.get("register_definitions") or []):
+        reg_path = f"{path}.register_definitions[{idx}]"
+        yield from _unknown(reg, REGISTER_KEYS, reg_path)
+        if isinstance(reg, dict):
+            yield from _unknown(reg.get("field"), REGISTER_FIELD_KEYS, f"{reg_path}.field")
+            yield from _unknown(reg.get("display"), DISPLAY_KEYS, f"{reg_path}.display")
+    codec = tech.get("payload_codec")
+    yield from _unknown(codec, PAYLOAD_CODEC_KEYS, f"{path}.payload_codec")
+    if isinstance(codec, dict):
+        yield from _unknown(codec.get("source"), CODEC_SOURCE_KEYS, f"{path}.payload_codec.source")
+
+
+def unknown_keys(device: dict) -> list[tuple[str, str]]:
+    """``(path, message)`` for every key in ``device`` the importer ignores.
+
+    Technology configs are only checked for the technologies the importer
+    knows; an unknown technology is a different problem.
+    """
+    found = list(_unknown(device, DEVICE_KEYS, ""))
+
+    found += _unknown_tech(device.get("technology_config"), "technology_config")
+    for idx, tech in enumerate(device.get("additional_technology_configs") or []):
+        found += _unknown_tech(tech, f"additional_technology_configs[{idx}]")
+
+    found += _unknown(device.get("control_config"), CONTROL_KEYS, "control_config")
+    found += _unknown(device.get("processor_config"), PROCESSOR_KEYS, "processor_config")
+    found += _unknown(device.get("alarm_config"), ALARM_KEYS, "alarm_config")
+    for idx, link in enumerate(device.get("links") or []):
+        found += _unknown(link, LINK_KEYS, f"links[{idx}]")
+    found += _unknown(device.get("validation"), VALIDATION_KEYS, "validation")
+    return found
+
+
+def check_known_keys(device: dict) -> None:
+    """Raise ``UnknownKeyError`` listing every unknown key in ``device``."""
+    found = unknown_keys(device)
+    if found:
+        raise UnknownKeyError("; ".join(f"{path}: {message}" for path, message in found))
@@ -0,0 +1,324 @@
+"""Submit an export to the library repository as a pull request.
+
+``export_yaml --submit`` builds the same ``ExportPreview`` as ``--preview``
+and commits it onto the preview's branch through the GitHub API — no
+clone, no git or gh binary — then opens a pull request against the
+repository's default branch. What goes into the commit is decided against
+that branch, not the local tree (``compare``): the files that differ from
+it, and the vendor files it has that the export no longer does, deleted.
+
+Contributors without push access to the library repository (external
+vendors) go through a fork: the token user's existing fork is reused, or
+one is created, the branch is pushed there, and the pull request is
+opened cross-repo (``owner:branch``) with maintainer edits allowed.
+The commit is made on the upstream default branch's head either way;
+a fork shares its parent's objects, so a fork that is behind doesn't
+need syncing first.
+
+A submission is resumed rather than resubmitted for each review round:
+``open_submissions`` lists the token user's open export pull requests
+(branches starting with ``BRANCH_PREFIX``), ``branch_files`` reads the
+exported tree back from one so it can be imported and edited, and
+``update`` pushes the next export onto the same branch as one more
+commit — only the files that differ from the branch, and nothing when
+none do — and only onto one of those pull requests.
+"""
+
+from __future__ import annotations
+
+import base64
+import hashlib
+import json
+import logging
+import time
+import urllib.error
+import urllib.request
+from dataclasses import dataclass, replace
+
+from devicelib.release import API_URL, DEFAULT_REPO, github_token
+
+from .export_preview import BRANCH_PREFIX, MANIFEST_NAME, ExportPreview, FileChange, change_title
+
+logger = logging.getLogger(__name__)
+
+# Forking is asynchronous: wait up to FORK_WAIT_TRIES × FORK_WAIT_SECONDS for the copy.
+FORK_WAIT_TRIES = 15
+FORK_WAIT_SECONDS = 2
+
+
+class SubmitError(Exception):
+    def __init__(self, message: str, status: int | None = None):
+        super().__init__(message)
+        self.status = status  # the HTTP status, when GitHub answered
+
+
+class Unauthorized(SubmitError):
+    """No token, or GitHub refused it (401/403)."""
+
+
+class Unreachable(SubmitError):
+    """GitHub didn't answer at all."""
+
+
+@dataclass
+class Submission:
+    url: str
+    number: int
+    repo: str  # where the branch was pushed: the library repository or a fork of it
+    branch: str
+    forked: bool = False
+    title: str = ""
+    commit: str = ""  # the commit pushed; empty when an update had nothing to push
+
+
+class GitHub:
+    """Just enough of the REST API to commit files and open a pull request."""
+
+    def __init__(self, token: str, api_url: str = API_URL, timeout: float = 30):
+        self.token = token
+        self.api_url = api_url
+        self.timeout = timeout
+
+    def request(self, method: str, path: str, body: dict | None = None):
+        headers = {
+            "Authorization": f"Bearer {self.token}",
+            "Accept": "application/vnd.github+json",
+            "User-Agent": "devicelib",
+        }
+        data = json.dumps(body).encode() if body is not None else None
+        request = urllib.request.Request(f"{self.api_url}{path}", data=data, headers=headers, method=method)
+        try:
+            with urllib.request.urlopen(request, timeout=self.timeout) as response:
+                return json.loads(response.read() or b"null")
+        except urllib.error.HTTPError as e:
+            try:
+                detail = json.loads(e.read()).get("message", "")
+            except (ValueError, AttributeError):
+                detail = ""
+            error = Unauthorized if e.code in (401, 403) else SubmitError
+            raise error(f"{method} {path}: HTTP {e.code}{f' {detail}' if detail else ''}", e.code) from e
+        except (urllib.error.URLError, TimeoutError) as e:
+            raise Unreachable(f"{method} {path}: {e}") from e
+
+    def get(self, path: str):
+        return self.request("GET", path)
+
+    def post(self, path: str, body: dict):
+        return self.request("POST", path, body)
+
+    def patch(self, path: str, body: dict):
+        return self.request("PATCH", path, body)
+
+
+def _client() -> GitHub:
+    token = github_token()
+    if not token:
+        raise Unauthorized("Submitting needs a GitHub token: set GITHUB_TOKEN or GH_TOKEN (or log in with gh).")
+    return GitHub(token)
+
+
+def blob_sha(content: str) -> str:
+    """The git blob id of ``content``, to compare a file with a branch without downloading it."""
+    data = content.encode("utf-8")
+    return hashlib.sha1(b"blob %d\0" % len(data) + data).hexdigest()
+
+
+def _vendor_file(path: str, devices_dir: str) -> bool:
+    directory, _, name = path.rpartition("/")
+    return directory == devices_dir and name.endswith(".yaml")
+
+
+def _blob(github: GitHub, repo: str, sha: str) -> str:
+    return base64.b64decode(github.get(f"/repos/{repo}/git/blobs/{sha}")["content"]).decode("utf-8")
+
+
+def _changes(github: GitHub, repo: str, blobs: dict[str, str], preview: ExportPreview) -> list[FileChange]:
+    """``preview``'s files that differ from ``blobs`` (a tree of ``repo``), and
+    the vendor files there that the export doesn't have."""
+    changes = [
+        FileChange(path, _blob(github, repo, blobs[path]) if path in blobs else None, content)
+        for path, content in preview.tree.items() if blobs.get(path) != blob_sha(content)
+    ]
+    changes += [
+        FileChange(path, _blob(github, repo, sha), None) for path, sha in sorted(blobs.items())
+        if _vendor_file(path, preview.devices_dir) and path not in preview.tree
+    ]
+    return changes
+
+
+def _tree_entries(changes: list[FileChange]) -> list[dict]:
+    # A null sha deletes the path from the base tree.
+    return [
+        {"path": change.path, "mode": "100644", "type": "blob",
+         **({"sha": None} if change.new is None else {"content": change.new})}
+        for change in changes
+    ]
+
+
+def _upstream_head(github: GitHub, repo: str) -> tuple[dict, str]:
+    upstream = github.get(f"/repos/{repo}")
+    return upstream, github.get(f"/repos/{repo}/git/ref/heads/{upstream['default_branch']}")["object"]["sha"]
+
+
+def compare(preview: ExportPreview, repo: str = DEFAULT_REPO, github: GitHub | None = None) -> ExportPreview:
+    """``preview`` with its changes taken against ``repo``'s default branch instead of the local tree."""
+    github = github or _client()
+    _, head = _upstream_head(github, repo)
+    _, blobs = _branch_tree(github, repo, head)
+    changes = _changes(github, repo, blobs, preview)
+    return replace(preview, changes=changes, title=change_title(changes, preview.devices_dir))
+
+
+def _fork_ready(github: GitHub, fork: str, branch: str) -> bool:
+    try:
+        github.get(f"/repos/{fork}/git/ref/heads/{branch}")
+    except SubmitError as e:
+        if e.status not in (404, 409):
+            raise
+        return False
+    return True
+
+
+def fork_of(github: GitHub, upstream: dict, sleep=time.sleep) -> str:
+    """Full name of the token user's fork of ``upstream``, forking it if there is none."""
+    login = github.get("/user")["login"]
+    try:
+        existing = github.get(f"/repos/{login}/{upstream['name']}")
+    except SubmitError as e:
+        if e.status != 404:
+            raise
+        existing = None
+    if existing and existing.get("fork") and (existing.get("parent") or {}).get("full_name") == upstream["full_name"]:
+        logger.info("Reusing fork %s", existing["full_name"])
+        return existing["full_name"]
+
+    # A same-named repository that isn't the fork makes GitHub pick another name.
+    fork = github.post(f"/repos/{upstream['full_name']}/forks", {})["full_name"]
+    logger.info("Forked %s to %s", upstream["full_name"], fork)
+    for _ in range(FORK_WAIT_TRIES):
+        if _fork_ready(github, fork, upstream["default_branch"]):
+            return fork
+        sleep(FORK_WAIT_SECONDS)
+    raise SubmitError(f"Fork {fork} is not ready yet; run the submission again in a minute to reuse it.")
+
+
+def submit(
+    preview: ExportPreview, repo: str = DEFAULT_REPO, github: GitHub | None = None, sleep=time.sleep,
+) -> Submission:
+    """Commit ``preview``'s tree to a new branch and open a pull request on ``repo``.
+
+    Raises ``SubmitError`` without a token, when the tree matches ``repo``'s
+    default branch, or when GitHub refuses a step.
+    """
+    github = github or _client()
+    upstream, head = _upstream_head(github, repo)
+    base = upstream["default_branch"]
+    base_tree, blobs = _branch_tree(github, repo, head)
+    changes = _changes(github, repo, blobs, preview)
+    if not changes:
+        raise SubmitError(f"Nothing to submit: the export matches {repo}.")
+    preview = replace(preview, changes=changes, title=change_title(changes, preview.devices_dir))
+    forked = not (upstream.get("permissions") or {}).get("push")
+    target = fork_of(github, upstream, sleep) if forked else upstream["full_name"]
+
+    tree = github.post(f"/repos/{target}/git/trees", {"base_tree": base_tree, "tree": _tree_entries(changes)})["sha"]
+    commit = github.post(f"/repos/{target}/git/commits", {
+        "message": preview.commit_message, "tree": tree, "parents": [head],
+    })["sha"]
+    github.post(f"/repos/{target}/git/refs", {"ref": f"refs/heads/{preview.branch}", "sha": commit})
+    logger.info("Pushed %s to %s", preview.branch, target)
+
+    pull = {"title": preview.title, "body": preview.body, "base": base, "head": preview.branch}
+    if forked:
+        pull.update(head=f"{target.split('/')[0]}:{preview.branch}", maintainer_can_modify=True)
+    pr = github.post(f"/repos/{repo}/pulls", pull)
+    return Submission(
+        url=pr["html_url"], number=pr["number"], repo=target, branch=preview.branch, forked=forked,
+        title=preview.title, commit=commit,
+    )
+
+
+def _submission(pr: dict, repo: str) -> Submission:
+    head_repo = (pr["head"].get("repo") or {}).get("full_name")
+    if head_repo is None:
+        raise SubmitError(f"The branch of pull request #{pr['number']} is gone (its fork was deleted).")
+    return Submission(
+        url=pr["html_url"], number=pr["number"], repo=head_repo, branch=pr["head"]["ref"],
+        forked=head_repo != repo, title=pr["title"],
+    )
+
+
+def _open_pull(github: GitHub, repo: str, number: int) -> dict:
+    pr = github.get(f"/repos/{repo}/pulls/{number}")
+    if pr["state"] != "open":
+        raise SubmitError(f"Pull request #{number} is {'merged' if pr.get('merged_at') else pr['state']}.")
+    return pr
+
+
+def _branch_tree(github: GitHub, repo: str, commit: str) -> tuple[str, dict[str, str]]:
+    """``(tree, {path: blob})`` of ``commit``."""
+    tree = github.get(f"/repos/{repo}/git/commits/{commit}")["tree"]["sha"]
+    listing = github.get(f"/repos/{repo}/git/trees/{tree}?recursive=1")
+    if listing.get("truncated"):
+        raise SubmitError(f"The tree of {repo}@{commit[:7]} is too large to list.")
+    return tree, {entry["path"]: entry["sha"] for entry in listing["tree"] if entry["type"] == "blob"}
+
+
+def open_submissions(repo: str = DEFAULT_REPO, github: GitHub | None = None) -> list[Submission]:
+    """The token user's open export pull requests on ``repo``, newest first."""
+    github = github or _client()
+    login = github.get("/user")["login"]
+    pulls = github.get(f"/repos/{repo}/pulls?state=open&sort=created&direction=desc&per_page=100")
+    return [
+        _submission(pr, repo) for pr in pulls
+        if pr["user"]["login"] == login and pr["head"]["ref"].startswith(BRANCH_PREFIX)
+    ]
+
+
+def branch_files(
+    number: int, repo: str = DEFAULT_REPO, devices_dir: str = "devices", github: GitHub | None = None,
+) -> dict[str, str]:
+    """The exported tree on pull request ``number``'s branch: relative path → content."""
+    github = github or _client()
+    pr = _open_pull(github, repo, number)
+    submission = _submission(pr, repo)
+    _, blobs = _branch_tree(github, submission.repo, pr["head"]["sha"])
+    return {
+        path: _blob(github, submission.repo, sha) for path, sha in blobs.items()
+        if path == MANIFEST_NAME or _vendor_file(path, devices_dir)
+    }
+
+
+def update(preview: ExportPreview, number: int, repo: str = DEFAULT_REPO, github: GitHub | None = None) -> Submission:
+    """Push ``preview``'s tree onto pull request ``number``'s branch as one more commit.
+
+    Only files that differ from the branch go into the commit (vendor files
+    the export no longer has are deleted); without any, nothing is pushed
+    and ``commit`` stays empty. Only the token user's own export pull
+    requests are updated, never someone else's or a hand-made branch.
+    """
+    github = github or _client()
+    pr = _open_pull(github, repo, number)
+    if pr["user"]["login"] != github.get("/user")["login"]:
+        raise SubmitError(f"Pull request #{number} was opened by {pr['user']['login']}, not by you.")
+    if not pr["head"]["ref"].startswith(BRANCH_PREFIX):
+        raise SubmitError(f"Pull request #{number} is not an export: its branch {pr['head']['ref']} "
+                          f"doesn't start with {BRANCH_PREFIX}.")
+    submission = _submission(pr, repo)
+    head = pr["head"]["sha"]
+    base_tree, blobs = _branch_tree(github, submission.repo, head)
+    changed = _changes(github, submission.repo, blobs, preview)
+    if not changed:
+        return submission
+
+    tree = github.post(f"/repos/{submission.repo}/git/trees", {
+        "base_tree": base_tree, "tree": _tree_entries(changed),
+    })["sha"]
+    message = "\n".join([change_title(changed, preview.devices_dir), "", *(f"- {c.path}" for c in changed)]) + "\n"
+    submission.commit = github.post(f"/repos/{submission.repo}/git/commits", {
+        "message": message, "tree": tree, "parents": [head],
+    })["sha"]
+    # Not forced: if the branch moved since it was read, GitHub refuses the update.
+    github.patch(f"/repos/{submission.repo}/git/refs/heads/{submission.branch}", {"sha": submission.commit})
+    logger.info("Pushed %s onto %s of %s", submission.commit[:7], submission.branch, submission.repo)
+    return submission
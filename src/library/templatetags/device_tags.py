@@ -35,3 +35,14 @@ def device_type_badge(device_type, label=None):
         colors,
         label,
     )
+
+
+@register.simple_tag
+def field_change(changes, key):
+    """The ``library.original.FieldChange`` of a field that differs from
+    its published value, or None.
+
+    Usage:
+        {% field_change changes field.name as change %}
+    """
+    return changes.fields.get(key) if changes else None
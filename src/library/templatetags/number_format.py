@@ -0,0 +1,131 @@
+"""Template filters for numeric display in detail views.
+
+Float fields (register scale/offset) otherwise render via Python's
+``repr`` — ``1e-05``, ``0.30000000000000004`` — which field users can't
+compare against a meter display. Formatting is configured through the
+``NUMBER_FORMAT`` setting::
+
+    NUMBER_FORMAT = {
+        "thousands_separator": ",",
+        "decimal_places_by_unit": {"kWh": 3, "°C": 1},
+        "show_hex_addresses": True,
+    }
+
+Units missing from ``decimal_places_by_unit`` render with trailing zeros
+trimmed. Configured places pad short values (``5`` kWh → ``5.000``) but
+never truncate a more precise one.
+"""
+
+from decimal import Decimal, InvalidOperation
+
+from django import template
+from django.conf import settings
+
+register = template.Library()
+
+DEFAULT_NUMBER_FORMAT = {
+    "thousands_separator": "\u202f",  # narrow no-break space — unambiguous across locales
+    "decimal_places_by_unit": {
+        "kWh": 3,
+        "MWh": 3,
+        "m³": 3,
+        "m³/h": 3,
+        "°C": 1,
+        "V": 1,
+        "A": 2,
+        "W": 0,
+        "var": 0,
+        "VA": 0,
+        "Hz": 2,
+        "%": 1,
+        "hPa": 1,
+        "ppm": 0,
+        "dBm": 0,
+        "dB": 1,
+    },
+    "show_hex_addresses": True,
+}
+
+
+def number_format_settings() -> dict:
+    """Defaults overlaid with ``settings.NUMBER_FORMAT``."""
+    conf = {**DEFAULT_NUMBER_FORMAT, **getattr(settings, "NUMBER_FORMAT", {})}
+    conf["decimal_places_by_unit"] = {
+        **DEFAULT_NUMBER_FORMAT["decimal_places_by_unit"],
+        **getattr(settings, "NUMBER_FORMAT", {}).get("decimal_places_by_unit", {}),
+    }
+    return conf
+
+
+def _to_decimal(value) -> Decimal | None:
+    if value is None or value == "" or isinstance(value, bool):
+        return None
+    try:
+        # ``str(float)`` gives the shortest round-tripping repr, so 0.1 stays
+        # 0.1 rather than expanding to its binary approximation.
+        return Decimal(str(value))
+    except (InvalidOperation, ValueError):
+        return None
+
+
+def _group(integer_part: str, separator: str) -> str:
+    if not separator or len(integer_part) <= 3:
+        return integer_part
+    head = len(integer_part) % 3 or 3
+    parts = [integer_part[:head]] + [integer_part[i:i + 3] for i in range(head, len(integer_part), 3)]
+    return separator.join(parts)
+
+
+def format_decimal(value, places: int | None = None, separator: str = "") -> str:
+    """Render ``value`` in plain (never scientific) notation.
+
+    ``places`` pads to that many decimals; ``None`` trims trailing zeros.
+    Significant digits are never rounded away — a bound of 0.05 °C must
+    not display as 0.1.
+    """
+    dec = _to_decimal(value)
+    if dec is None:
+        return "" if value is None else str(value)
+    normalized = dec.normalize()
+    if places is not None and -normalized.as_tuple().exponent <= places:
+        text = format(dec.quantize(Decimal(1).scaleb(-places)), "f")
+    else:
+        text = format(normalized, "f")
+    sign = ""
+    if text.startswith("-"):
+        sign, text = "-", text[1:]
+    integer_part, _, fraction = text.partition(".")
+    grouped = _group(integer_part, separator)
+    return f"{sign}{grouped}.{fraction}" if fraction else f"{sign}{grouped}"
+
+
+@register.filter
+def fmt_number(value, unit=""):
+    """Format a measured value using the unit's configured decimal places.
+
+    Usage: ``{{ metric.max_value|fmt_number:metric.unit }}``
+    """
+    conf = number_format_settings()
+    places = conf["decimal_places_by_unit"].get(unit or "")
+    return format_decimal(value, places, conf["thousands_separator"])
+
+
+@register.filter
+def fmt_plain(value):
+    """Format a coefficient (scale, offset) exactly, without scientific notation
+    or float noise: ``1e-05`` → ``0.00001``, ``1.0`` → ``1``."""
+    return format_decimal(value)
+
+
+@register.filter
+def hex_addr(value, width=4):
+    """Render a register address as zero-padded hex (``10`` → ``0x000A``)."""
+    try:
+        return f"0x{int(value):0{int(width)}X}"
+    except (TypeError, ValueError):
+        return value
+
+
+@register.simple_tag
+def show_hex_addresses():
+    return number_format_settings()["show_hex_addresses"]
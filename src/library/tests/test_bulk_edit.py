@@ -0,0 +1,76 @@
+"""Bulk actions on models marked in the model list, behind a confirmation page."""
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.test import Client
+from django.urls import reverse
+
+from library.models import DeviceHistory, DeviceType, Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def client():
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(username="bulk-editor", password="x", role="editor"))
+    return client
+
+
+@pytest.fixture
+def variants():
+    vendor = Vendor.objects.create(name="Bulk Vendor", slug="bulk-vendor")
+    return [
+        VendorModel.objects.create(
+            vendor=vendor, model_number=f"BV-{n}", name=f"Variant {n}", device_type="power_meter", technology="modbus",
+        )
+        for n in range(3)
+    ]
+
+
+def _post(client, variants, **data):
+    return client.post(reverse("library:model-bulk"), {"models": [v.pk for v in variants], **data})
+
+
+def test_list_offers_marking_to_editors(client, variants):
+    body = client.get(reverse("library:model-list")).content.decode()
+    assert body.count("data-bulk-select") == len(variants)
+
+
+def test_first_post_only_asks_for_confirmation(client, variants):
+    response = _post(client, variants[:2], action="delete")
+    assert response.status_code == 200
+    assert "Delete 2 models?" in response.content.decode()
+    assert VendorModel.objects.count() == 3
+
+
+def test_confirmed_delete(client, variants):
+    response = _post(client, variants[:2], action="delete", confirm="1", query="vendor=bulk-vendor")
+    assert response.url == "/models/?vendor=bulk-vendor"
+    assert list(VendorModel.objects.values_list("model_number", flat=True)) == ["BV-2"]
+    assert DeviceHistory.objects.filter(action=DeviceHistory.Action.DELETED).count() == 2
+
+
+def test_set_device_type(client, variants):
+    water = DeviceType.objects.create(code="bulk_water", label="Bulk Water")
+    _post(client, variants, action="device_type", device_type_fk=water.pk, confirm="1")
+    assert set(VendorModel.objects.values_list("device_type_fk", flat=True)) == {water.pk}
+    assert DeviceHistory.objects.filter(action=DeviceHistory.Action.UPDATED).count() == 3
+
+
+def test_suppress_and_unsuppress_rule(client, variants):
+    variants[0].suppressed_rules = ["missing-description"]
+    variants[0].save()
+    _post(client, variants, action="suppress", rule="missing-description", confirm="1")
+    assert all(m.suppressed_rules == ["missing-description"] for m in VendorModel.objects.all())
+    _post(client, variants[1:], action="unsuppress", rule="missing-description", confirm="1")
+    assert VendorModel.objects.get(pk=variants[0].pk).suppressed_rules == ["missing-description"]
+    assert VendorModel.objects.get(pk=variants[1].pk).suppressed_rules == []
+
+
+def test_incomplete_action_is_refused(client, variants):
+    response = _post(client, variants, action="device_type", confirm="1")
+    assert response.status_code == 302
+    assert not DeviceHistory.objects.exists()
+    response = client.post(reverse("library:model-bulk"), {"action": "delete"}, follow=True)
+    assert "Mark at least one model." in response.content.decode()
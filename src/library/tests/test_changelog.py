@@ -0,0 +1,101 @@
+"""Markdown release notes between published versions."""
+
+import io
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.core.management import call_command
+from django.core.management.base import CommandError
+from django.test import Client
+
+from library.changelog import build_changelog
+from library.history import record_history, snapshot_device
+from library.models import DeviceHistory, LibraryVersion, ModbusConfig, RegisterDefinition, Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+User = get_user_model()
+
+
+@pytest.fixture
+def admin_client(db):
+    u = User.objects.create_user(
+        username="changelog-admin", password="x",
+        is_staff=True, is_superuser=True, role="admin",
+    )
+    c = Client()
+    c.force_login(u)
+    return c
+
+
+def _publish(client):
+    assert client.post("/versions/create/").status_code == 302
+    return LibraryVersion.objects.order_by("-version").first()
+
+
+@pytest.fixture
+def versions(admin_client):
+    acme = Vendor.objects.create(name="Notes Acme", slug="notes-acme")
+    meter = VendorModel.objects.create(
+        vendor=acme, model_number="NA-1", name="Meter", device_type="power_meter", technology="modbus",
+    )
+    modbus = ModbusConfig.objects.create(device_type=meter)
+    volt = RegisterDefinition.objects.create(modbus_config=modbus, field_name="volt", address=0, data_type="float32")
+    doomed = VendorModel.objects.create(
+        vendor=acme, model_number="NA-OLD", name="Old", device_type="power_meter", technology="modbus",
+    )
+    record_history(meter, DeviceHistory.Action.CREATED, None)
+    record_history(doomed, DeviceHistory.Action.CREATED, None)
+    v1 = _publish(admin_client)
+
+    prev = snapshot_device(meter)
+    volt.scale = 0.1
+    volt.save()
+    RegisterDefinition.objects.create(modbus_config=modbus, field_name="current", address=2, data_type="float32")
+    meter.description = "Three-phase meter"
+    meter.save()
+    record_history(meter, DeviceHistory.Action.UPDATED, None, prev)
+    doomed.delete()
+    other = Vendor.objects.create(name="Notes Other", slug="notes-other")
+    sensor = VendorModel.objects.create(
+        vendor=other, model_number="NO-1", name="Sensor", device_type="environment_sensor", technology="lorawan",
+    )
+    record_history(sensor, DeviceHistory.Action.CREATED, None)
+    v2 = _publish(admin_client)
+    return v1, v2
+
+
+def test_grouped_by_vendor(versions):
+    groups = build_changelog(*versions)
+    assert list(groups) == ["Notes Acme", "Notes Other"]
+    acme = groups["Notes Acme"]
+    assert acme["breaking"] == [
+        "**Notes Acme NA-1** — register 0 scale changed",
+        "**Notes Acme NA-OLD** — removed from the library",
+    ]
+    assert acme["improved"] == ["**Notes Acme NA-1** — 1 register(s) added, description updated"]
+    assert groups["Notes Other"]["new"] == ["**Notes Other NO-1**"]
+
+
+def test_grouped_by_technology(versions):
+    groups = build_changelog(*versions, group_by="technology")
+    assert groups["lorawan"]["new"] == ["**Notes Other NO-1**"]
+    assert groups["modbus"]["improved"]
+    assert "**Notes Acme NA-OLD** — removed from the library" in groups["modbus"]["breaking"]
+
+
+def test_command_renders_markdown(versions):
+    v1, v2 = versions
+    out = io.StringIO()
+    call_command("changelog", "--since", f"v{v1.version}", stdout=out)
+    text = out.getvalue()
+    assert text.startswith(f"# Device library v{v2.version}")
+    assert "## Notes Acme" in text
+    assert "### Breaking changes" in text
+
+    with pytest.raises(CommandError, match="must be newer"):
+        call_command("changelog", "--since", str(v2.version), "--until", str(v1.version))
+
+
+def test_command_without_published_versions():
+    with pytest.raises(CommandError, match="No library version has been published yet"):
+        call_command("changelog", "--since", "1")
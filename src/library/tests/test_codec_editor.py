@@ -0,0 +1,121 @@
+"""The LoRaWAN codec editor: decoder, fPorts and expected outputs on one page."""
+
+import re
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.test import Client
+
+from library.codec_editor import codec_outputs, missing_sources, split_outputs
+from library.models import DeviceHistory, DeviceType, LoRaWANConfig, ProcessorConfig, Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+SCRIPT = "function decodeUplink(input) {\n  return { data: { temperature: input.bytes[0] / 2 } };\n}\n"
+
+
+@pytest.fixture
+def client():
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(username="codec-editor", password="x", role="editor"))
+    return client
+
+
+@pytest.fixture
+def sensor():
+    sensor_type = DeviceType.objects.create(
+        code="codec_sensor", label="Codec Sensor", metrics=[{"metric": "env:temperature", "tier": "primary"}],
+    )
+    vendor = Vendor.objects.create(name="Codec Vendor", slug="codec-vendor")
+    return VendorModel.objects.create(
+        vendor=vendor, model_number="CE-1", name="Codec Sensor", device_type="environment_sensor",
+        technology="lorawan", device_type_fk=sensor_type,
+    )
+
+
+def _post(client, sensor, outputs=(), **data):
+    body = {
+        "decoder": "js_codec", "codec_format": "ttn_v3", "payload_codec": SCRIPT, "downlink_f_port": "",
+        "outputs-TOTAL_FORMS": str(len(outputs)), "outputs-INITIAL_FORMS": "0",
+        **data,
+    }
+    for n, row in enumerate(outputs):
+        body.update({f"outputs-{n}-{key}": value for key, value in row.items()})
+    return client.post(f"/models/{sensor.pk}/lorawan-config/codec/", body)
+
+
+def test_outputs_split_into_declared_and_extra_mappings():
+    processor = ProcessorConfig(
+        field_mappings=[{"source": "temperature", "target": "env:temperature", "f_port": 2}],
+        extra_mappings=[{"source": "rssi", "target": "radio:rssi", "tier": "diagnostic", "unit": "dBm"}],
+    )
+    rows = codec_outputs(processor)
+    assert rows[1]["rest"] == {"tier": "diagnostic", "unit": "dBm"}
+    assert split_outputs(rows, {"env:temperature"}) == (
+        [{"source": "temperature", "target": "env:temperature", "f_port": 2}],
+        [{"source": "rssi", "target": "radio:rssi", "tier": "diagnostic", "unit": "dBm"}],
+    )
+    # Without the type declaring it, the same row becomes an extra.
+    assert split_outputs(rows[:1], set())[1] == [
+        {"source": "temperature", "target": "env:temperature", "f_port": 2, "tier": "secondary"},
+    ]
+
+
+def test_missing_sources():
+    rows = [{"source": "data.temperature"}, {"source": "humidity"}, {"source": "temp"}]
+    assert missing_sources(SCRIPT, rows) == ["humidity", "temp"]
+
+
+def test_page_shows_codec_and_outputs(client, sensor):
+    LoRaWANConfig.objects.create(device_type=sensor, payload_codec=SCRIPT)
+    ProcessorConfig.objects.create(
+        device_type=sensor, field_mappings=[{"source": "temperature", "target": "env:temperature"}],
+    )
+    body = client.get(f"/models/{sensor.pk}/lorawan-config/codec/").content.decode()
+    assert re.search(r'value="js_codec"[^>]*checked', body)
+    assert 'value="temperature"' in body
+    assert "decodeUplink" in body
+
+
+def test_save_codec_ports_and_outputs(client, sensor):
+    response = _post(client, sensor, downlink_f_port="10", outputs=[
+        {"source": "temperature", "f_port": "2", "target": "env:temperature", "scale": "", "offset": ""},
+        {"source": "battery", "f_port": "", "target": "power:battery", "scale": "0.01", "offset": ""},
+    ])
+    assert response.status_code == 302
+    config = LoRaWANConfig.objects.get(device_type=sensor)
+    assert (config.payload_codec, config.downlink_f_port) == (SCRIPT, 10)
+    processor = ProcessorConfig.objects.get(device_type=sensor)
+    assert processor.field_mappings == [{"source": "temperature", "target": "env:temperature", "f_port": 2}]
+    assert processor.extra_mappings == [
+        {"source": "battery", "target": "power:battery", "scale": 0.01, "tier": "secondary"},
+    ]
+    assert DeviceHistory.objects.filter(device=sensor, action=DeviceHistory.Action.UPDATED).count() == 1
+
+
+def test_outputs_the_codec_never_mentions_are_warned_about(client, sensor):
+    response = _post(client, sensor, outputs=[
+        {"source": "humidity", "target": "env:humidity"},
+    ])
+    messages = [str(m) for m in response.wsgi_request._messages]
+    assert messages == ["Not mentioned in the codec: humidity"]
+
+
+def test_field_map_decoder_drops_the_codec(client, sensor):
+    LoRaWANConfig.objects.create(
+        device_type=sensor, payload_codec=SCRIPT,
+        codec_source_url="https://example.com/ce1.js", codec_source_sha256="ab",
+    )
+    _post(client, sensor, decoder="lorawan_field_map")
+    config = LoRaWANConfig.objects.get(device_type=sensor)
+    assert (config.payload_codec, config.codec_source_url) == ("", "")
+    assert not ProcessorConfig.objects.filter(device_type=sensor).exists()
+
+
+def test_invalid_input_is_shown_not_saved(client, sensor):
+    response = _post(client, sensor, payload_codec=" ", outputs=[{"source": "temperature", "f_port": "300"}])
+    assert response.status_code == 200
+    body = response.content.decode()
+    assert "Paste the codec source, or pick the field map decoder." in body
+    assert "Give both the decoded field and the metric it maps to." in body
+    assert not DeviceHistory.objects.exists()
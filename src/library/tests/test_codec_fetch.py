@@ -0,0 +1,241 @@
+"""Fetching LoRaWAN codecs from a URL: download, provenance, export and the view."""
+
+import io
+import json
+from datetime import UTC, datetime
+
+import pytest
+import yaml
+from django.contrib.auth import get_user_model
+from django.core.management import call_command
+from django.test import Client
+
+from library import codec_fetch
+from library.codec_fetch import CodecFetchError, FetchedCodec, apply_fetched_codec, fetch_codec, raw_url
+from library.exporters import export_to_yaml
+from library.importers import import_from_yaml
+from library.management.errors import UsageError, ValidationFailed
+from library.models import DeviceHistory, LoRaWANConfig, Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+User = get_user_model()
+
+SCRIPT = "function decodeUplink(input) { return { data: {} }; }\n"
+
+
+class FakeResponse(io.BytesIO):
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc):
+        return False
+
+
+@pytest.fixture
+def serve(monkeypatch):
+    """Answer every fetch with ``body``; the requested URLs are collected."""
+    requested = []
+
+    def install(body: bytes):
+        def urlopen(request, timeout=None):
+            requested.append(request.full_url)
+            return FakeResponse(body)
+
+        monkeypatch.setattr(codec_fetch, "_open", urlopen)
+        return requested
+
+    return install
+
+
+@pytest.fixture
+def lorawan_device():
+    vendor = Vendor.objects.create(name="Codec Vendor", slug="codec-vendor")
+    return VendorModel.objects.create(
+        vendor=vendor, model_number="CV-1", name="Codec Sensor", device_type="environment_sensor", technology="lorawan",
+    )
+
+
+class TestFetch:
+    def test_github_blob_url_is_rewritten_to_raw(self):
+        assert raw_url("https://github.com/acme/codecs/blob/main/cv1.js") == (
+            "https://raw.githubusercontent.com/acme/codecs/main/cv1.js"
+        )
+        assert raw_url("https://example.com/cv1.js") == "https://example.com/cv1.js"
+
+    def test_fetch_records_hash(self, serve):
+        requested = serve(SCRIPT.encode())
+        fetched = fetch_codec("https://github.com/acme/codecs/blob/main/cv1.js")
+        assert requested == ["https://raw.githubusercontent.com/acme/codecs/main/cv1.js"]
+        assert fetched.script == SCRIPT
+        assert len(fetched.sha256) == 64
+
+    def test_rejects_non_http_scheme(self):
+        with pytest.raises(CodecFetchError, match="Only http"):
+            fetch_codec("file:///etc/passwd")
+
+    def test_rejects_oversized_body(self, serve):
+        serve(b"x" * (codec_fetch.MAX_CODEC_BYTES + 1))
+        with pytest.raises(CodecFetchError, match="larger than"):
+            fetch_codec("https://example.com/big.js")
+
+    def test_rejects_binary_body(self, serve):
+        serve(b"\xff\xfe\x00")
+        with pytest.raises(CodecFetchError, match="not UTF-8"):
+            fetch_codec("https://example.com/codec.bin")
+
+    @pytest.mark.parametrize("url", [
+        "http://127.0.0.1:8000/admin/",
+        "http://localhost/codec.js",
+        "http://169.254.169.254/latest/meta-data/",
+        "https://10.0.0.5/codec.js",
+        "http://192.168.1.1/codec.js",
+        "http://[::1]/codec.js",
+        "http://[::ffff:127.0.0.1]/codec.js",
+    ])
+    def test_refuses_local_and_private_addresses(self, url):
+        with pytest.raises(CodecFetchError, match="Refusing to fetch .* is not a public address"):
+            fetch_codec(url)
+
+
+class TestProvenance:
+    def test_edit_after_fetch_is_detected(self, serve, lorawan_device):
+        serve(SCRIPT.encode())
+        config = LoRaWANConfig.objects.create(device_type=lorawan_device)
+        apply_fetched_codec(config, fetch_codec("https://example.com/cv1.js"))
+        assert not config.codec_modified_since_fetch
+
+        config.payload_codec += "// local fix\n"
+        assert config.codec_modified_since_fetch
+
+    def test_source_round_trips_through_yaml(self, tmp_path, lorawan_device):
+        config = LoRaWANConfig.objects.create(device_type=lorawan_device)
+        fetched_at = datetime(2026, 3, 1, 12, 0, tzinfo=UTC)
+        fetched = FetchedCodec("https://example.com/cv1.js", SCRIPT, "ab" * 32, fetched_at)
+        apply_fetched_codec(config, fetched, codec_format="chirpstack")
+
+        export_to_yaml(tmp_path / "devices")
+        exported = yaml.safe_load((tmp_path / "devices" / "codec-vendor.yaml").read_text())
+        codec = exported["models"][0]["technology_config"]["payload_codec"]
+        assert codec["source"] == {
+            "url": "https://example.com/cv1.js", "sha256": "ab" * 32, "fetched_at": fetched_at.isoformat(),
+        }
+
+        Vendor.objects.all().delete()
+        stats = import_from_yaml(tmp_path / "devices", tmp_path / "manifest.yaml", strict=True)
+        assert not stats["errors"]
+        config = LoRaWANConfig.objects.get(device_type__model_number="CV-1")
+        assert config.codec_source_url == "https://example.com/cv1.js"
+        assert config.codec_source_sha256 == "ab" * 32
+        assert config.codec_fetched_at == fetched_at
+
+
+class TestView:
+    @pytest.fixture
+    def client(self):
+        user = User.objects.create_user(username="codec-editor", password="x", role="editor")
+        client = Client()
+        client.force_login(user)
+        return client
+
+    def test_fetch_stores_codec_and_history(self, client, serve, lorawan_device):
+        serve(SCRIPT.encode())
+        response = client.post(
+            f"/models/{lorawan_device.pk}/lorawan-config/fetch-codec/",
+            {"url": "https://example.com/cv1.js", "codec_format": "ttn_v3"},
+        )
+        assert response.status_code == 302
+        config = LoRaWANConfig.objects.get(device_type=lorawan_device)
+        assert config.payload_codec == SCRIPT
+        assert config.codec_source_url == "https://example.com/cv1.js"
+        history = DeviceHistory.objects.get(device=lorawan_device, action="updated")
+        assert history.changes["lorawan_config"]["old"] is None
+        assert history.changes["lorawan_config"]["new"]["payload_codec"] == SCRIPT
+
+        serve(b"// v2\n" + SCRIPT.encode())
+        client.post(
+            f"/models/{lorawan_device.pk}/lorawan-config/fetch-codec/",
+            {"url": "https://example.com/cv1.js", "codec_format": "ttn_v3"},
+        )
+        latest = DeviceHistory.objects.filter(device=lorawan_device).order_by("-version").first()
+        assert latest.changes["lorawan_config.payload_codec"] == {"old": SCRIPT, "new": "// v2\n" + SCRIPT}
+
+    def test_fetch_error_leaves_codec_alone(self, client, monkeypatch, lorawan_device):
+        def fail(url):
+            raise CodecFetchError("Could not fetch")
+
+        monkeypatch.setattr("library.views.fetch_codec", fail)
+        response = client.post(
+            f"/models/{lorawan_device.pk}/lorawan-config/fetch-codec/",
+            {"url": "https://example.com/missing.js", "codec_format": "ttn_v3"},
+            follow=True,
+        )
+        assert "Could not fetch" in response.content.decode()
+        assert not LoRaWANConfig.objects.filter(device_type=lorawan_device).exists()
+        assert not DeviceHistory.objects.filter(device=lorawan_device).exists()
+
+    def test_fetch_refused_on_other_technologies(self, client, serve, lorawan_device):
+        requested = serve(SCRIPT.encode())
+        lorawan_device.technology = "modbus"
+        lorawan_device.save()
+        response = client.post(
+            f"/models/{lorawan_device.pk}/lorawan-config/fetch-codec/",
+            {"url": "https://example.com/cv1.js", "codec_format": "ttn_v3"},
+            follow=True,
+        )
+        assert "is not a LoRaWAN device" in response.content.decode()
+        assert requested == []
+        assert not LoRaWANConfig.objects.filter(device_type=lorawan_device).exists()
+
+
+class TestCheckUpstreams:
+    @pytest.fixture
+    def fetched_config(self, serve, lorawan_device):
+        serve(SCRIPT.encode())
+        config = LoRaWANConfig.objects.create(device_type=lorawan_device)
+        apply_fetched_codec(config, fetch_codec("https://example.com/cv1.js"))
+        return config
+
+    def _run(self, *args):
+        out = io.StringIO()
+        call_command("check_upstreams", "--format", "json", *args, stdout=out)
+        return json.loads(out.getvalue())
+
+    def test_unchanged_upstream_is_current(self, fetched_config):
+        [row] = self._run("--fail-on-update")
+        assert row["status"] == "current"
+        assert not row["locally_modified"]
+
+    def test_reports_and_applies_updates(self, serve, fetched_config):
+        serve(b"// v2\n" + SCRIPT.encode())
+        [row] = self._run()
+        assert (row["status"], row["applied"]) == ("updated", False)
+        with pytest.raises(ValidationFailed, match="1 codec update"):
+            self._run("--fail-on-update")
+
+        [row] = self._run("--apply")
+        assert row["applied"]
+        fetched_config.refresh_from_db()
+        assert fetched_config.payload_codec.startswith("// v2")
+        assert DeviceHistory.objects.filter(device=fetched_config.device_type).exists()
+
+    def test_local_edits_need_force(self, serve, fetched_config):
+        fetched_config.payload_codec += "// local fix\n"
+        fetched_config.save()
+        serve(b"// v2\n" + SCRIPT.encode())
+
+        [row] = self._run("--apply")
+        assert row["locally_modified"] and not row["applied"]
+        [row] = self._run("--apply", "--force")
+        assert row["applied"]
+
+        with pytest.raises(UsageError):
+            self._run("--force")
+
+    def test_unreachable_source(self, monkeypatch, fetched_config):
+        def fail(request, timeout=None):
+            raise codec_fetch.urllib.error.URLError("connection refused")
+
+        monkeypatch.setattr(codec_fetch, "_open", fail)
+        [row] = self._run()
+        assert row["status"] == "error"
+        assert "connection refused" in row["error"]
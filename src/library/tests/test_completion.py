@@ -0,0 +1,57 @@
+"""Shell completion: script generation and dynamic value lookup."""
+
+import io
+
+import pytest
+import yaml
+from django.core.management import call_command
+
+from library.completion import command_options, model_values, render_script, vendor_values
+from library.models import Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def devices():
+    vendor = Vendor.objects.create(name="Comp Vendor", slug="comp-vendor")
+    VendorModel.objects.create(
+        vendor=vendor, model_number="CV-1", name="A", device_type="power_meter", technology="modbus",
+    )
+    other = Vendor.objects.create(name="Other", slug="other")
+    VendorModel.objects.create(
+        vendor=other, model_number="OT-9", name="B", device_type="power_meter", technology="modbus",
+    )
+
+
+def test_command_options_are_command_specific():
+    options = command_options()
+    assert "--check" in options["fmt_yaml"]
+    assert "--verbosity" not in options["fmt_yaml"]
+    assert "completion" in options
+
+
+@pytest.mark.parametrize("shell", ["bash", "zsh", "fish"])
+def test_scripts_mention_commands(shell):
+    script = render_script(shell, prog="sparkctl")
+    assert "lint_library" in script
+    assert "completion --values vendors" in script
+
+
+def test_values_from_database(devices):
+    assert vendor_values() == ["comp-vendor", "other"]
+    assert model_values("comp-vendor") == ["CV-1"]
+
+    out = io.StringIO()
+    call_command("completion", "--values", "models", stdout=out)
+    assert out.getvalue().split() == ["CV-1", "OT-9"]
+
+
+def test_values_from_manifest(tmp_path):
+    (tmp_path / "devices").mkdir()
+    (tmp_path / "devices" / "acme.yaml").write_text(yaml.dump({"models": [{"model_number": "AC-1"}]}))
+    manifest = tmp_path / "manifest.yaml"
+    manifest.write_text(yaml.dump({"vendors": [{"name": "Acme", "file": "acme.yaml"}]}))
+    assert vendor_values(manifest) == ["acme"]
+    assert model_values("acme", manifest) == ["AC-1"]
+    assert model_values("other", manifest) == []
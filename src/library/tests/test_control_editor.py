@@ -0,0 +1,161 @@
+"""The structured control editor: relays, setpoints and commands as typed rows."""
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.test import Client
+
+from library.control_audit import audit_device
+from library.control_editor import build_control, control_rows, unknown_registers
+from library.forms import ControlConfigForm
+from library.models import (
+    ControlConfig, DeviceHistory, Metric, ModbusConfig, RegisterDefinition, Vendor, VendorModel,
+)
+
+pytestmark = pytest.mark.django_db
+
+RELAY = {
+    "id": "power", "label": "Power", "widget": "toggle", "feedback_metric": "device:relay_state",
+    "states": {
+        "on": {"wire": {"register": 10, "value": 1}},
+        "off": {"wire": {"register": 10, "value": 0}},
+        "toggle": {"wire": {"register": 10, "value": 2}},
+    },
+}
+MODE = {
+    "id": "mode", "label": "Mode", "widget": "enum",
+    "options": [{"value": "heat", "wire": {"register": 12, "value": 1}}],
+}
+
+
+@pytest.fixture
+def client():
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(username="control-editor", password="x", role="editor"))
+    return client
+
+
+@pytest.fixture
+def heater():
+    vendor = Vendor.objects.create(name="Control Vendor", slug="control-vendor")
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number="HC-1", name="Heater controller", device_type="controller", technology="modbus",
+    )
+    modbus = ModbusConfig.objects.create(device_type=device, function="holding")
+    for address, name in ((10, "relay"), (11, "setpoint"), (12, "mode"), (13, "reset")):
+        RegisterDefinition.objects.create(modbus_config=modbus, field_name=name, address=address, data_type="uint16")
+    return device
+
+
+def _post(client, device, rows=(), **data):
+    body = {"controls-TOTAL_FORMS": str(len(rows)), "controls-INITIAL_FORMS": "0", **data}
+    for n, row in enumerate(rows):
+        body.update({f"controls-{n}-{key}": value for key, value in row.items()})
+    return client.post(f"/models/{device.pk}/control-config/controls/", body)
+
+
+def test_rows_round_trip_keeping_what_they_dont_edit():
+    rows, kept = control_rows([RELAY, MODE], "modbus")
+    assert kept == [MODE]
+    [row] = rows
+    assert (row["kind"], row["target"], row["on"], row["off"]) == ("relay", 10, 1, 0)
+    assert build_control(row, "modbus") == RELAY
+
+
+def test_changing_kind_drops_the_old_widgets_keys():
+    [row] = control_rows([RELAY], "modbus")[0]
+    row.update(kind="command", value=5, function="write_single_coil")
+    assert build_control(row, "modbus") == {
+        "id": "power", "label": "Power", "widget": "button", "feedback_metric": "device:relay_state",
+        "wire": {"register": 10, "value": 5, "function": "write_single_coil"},
+    }
+
+
+def test_relays_with_states_on_different_targets_are_kept():
+    split = {**RELAY, "states": {"on": {"wire": {"f_port": 85}}, "off": {"wire": {"f_port": 86}}}}
+    assert control_rows([split], "lorawan") == ([], [split])
+
+
+def test_unknown_registers():
+    assert unknown_registers([RELAY, MODE], {10, 12}) == []
+    assert unknown_registers([RELAY, MODE], {12}) == ["power: register 10 is not in the register map."]
+    not_an_address = [{"id": "x", "wire": {"register": "ten"}}]
+    assert unknown_registers(not_an_address, set()) == ["x: register 'ten' is not an address."]
+
+
+def test_save_relay_setpoint_and_command(client, heater):
+    ControlConfig.objects.create(device_type=heater, controls=[MODE])
+    response = _post(client, heater, controllable="on", rows=[
+        {"id": "power", "label": "Power", "kind": "relay", "target": "0x0A", "on": "1", "off": "0"},
+        {"id": "target_temp", "label": "Target", "kind": "setpoint", "target": "11", "min": "5", "max": "30",
+         "step": "0.5", "unit": "°C"},
+        {"id": "reset", "kind": "command", "target": "13", "value": "0xFF00", "requires_confirmation": "on"},
+        {"kind": "relay"},
+    ])
+    assert response.status_code == 302
+    config = ControlConfig.objects.get(device_type=heater)
+    assert config.controllable
+    assert config.controls == [
+        {"id": "power", "label": "Power", "widget": "toggle", "states": {
+            "on": {"wire": {"register": 10, "value": 1}}, "off": {"wire": {"register": 10, "value": 0}},
+        }},
+        {"id": "target_temp", "label": "Target", "widget": "slider", "min": 5, "max": 30, "step": 0.5, "unit": "°C",
+         "wire": {"register": 11, "value_template": "{value}"}},
+        {"id": "reset", "label": "reset", "widget": "button", "requires_confirmation": True,
+         "wire": {"register": 13, "value": 0xFF00}},
+        MODE,
+    ]
+    assert DeviceHistory.objects.filter(device=heater, action=DeviceHistory.Action.UPDATED).count() == 1
+
+
+def test_registers_outside_the_map_are_refused(client, heater):
+    response = _post(client, heater, rows=[
+        {"id": "power", "kind": "relay", "target": "40", "on": "1", "off": "0"},
+    ])
+    assert response.status_code == 200
+    assert "Register 40 is not in the register map." in response.content.decode()
+    assert ControlConfig.objects.get(device_type=heater).controls == []
+
+
+def test_setpoint_limits_stay_inside_the_feedback_metric(client, heater):
+    Metric.objects.update_or_create(
+        key="heat:setpoint", defaults={"label": "Setpoint", "min_value": 5, "max_value": 30},
+    )
+    response = _post(client, heater, rows=[
+        {"id": "target_temp", "kind": "setpoint", "target": "11", "min": "0", "max": "40", "default": "50",
+         "feedback_metric": "heat:setpoint"},
+    ])
+    body = response.content.decode()
+    assert "heat:setpoint doesn&#x27;t go below 5." in body
+    assert "heat:setpoint doesn&#x27;t go above 30." in body
+    assert "The default is outside the limits." in body
+
+
+def test_lorawan_payloads_are_hex(client, heater):
+    heater.technology = "lorawan"
+    heater.save()
+    response = _post(client, heater, rows=[
+        {"id": "power", "kind": "relay", "target": "85", "on": "0 1", "off": "zz"},
+    ])
+    assert "Enter the payload as hex bytes, like 01 or FF00." in response.content.decode()
+    _post(client, heater, rows=[{"id": "identify", "kind": "command", "target": "90", "value": "ff"}])
+    assert ControlConfig.objects.get(device_type=heater).controls == [
+        {"id": "identify", "label": "identify", "widget": "button", "wire": {"f_port": 90, "payload_hex": "FF"}},
+    ]
+
+
+def test_duplicate_ids_are_refused(client, heater):
+    ControlConfig.objects.create(device_type=heater, controls=[MODE])
+    response = _post(client, heater, rows=[{"id": "mode", "kind": "command", "target": "13", "value": "1"}])
+    assert "Two controls are called mode." in response.content.decode()
+
+
+def test_yaml_editor_and_audit_check_registers_too(heater):
+    config = ControlConfig.objects.create(device_type=heater, controllable=True, controls=[RELAY])
+    form = ControlConfigForm({"controllable": "on", "controls": '[{"id": "x", "widget": "button", '
+                             '"wire": {"register": 99, "value": 1}}]'}, instance=config)
+    assert form.errors["controls"] == ["x: register 99 is not in the register map."]
+    assert audit_device(heater) == []
+    RegisterDefinition.objects.filter(address=10).delete()
+    [issue] = audit_device(heater)
+    assert issue.code == "control-register-missing"
+    assert issue.message == "power: register 10 is not in the register map."
@@ -6,7 +6,8 @@ from __future__ import annotations
 import pytest
 from django.core.exceptions import ValidationError
 
-from library.models import ControlConfig, Metric, Vendor, VendorModel
+from library.control_audit import audit_control_configs
+from library.models import ControlConfig, DeviceHistory, LoRaWANConfig, Metric, Vendor, VendorModel
 
 pytestmark = pytest.mark.django_db
 
@@ -400,3 +401,45 @@ class TestSyncEndpointControls:
         assert len(ctrl_block["controls"]) == 1
         assert ctrl_block["controls"][0]["widget"] == "toggle"
         assert ctrl_block["controls"][0]["feedback_metric"] == "device:relay_state"
+
+
+# -----------------------------------------------------------------------------
+# Control audit
+# -----------------------------------------------------------------------------
+
+
+class TestControlAudit:
+    """``audit_control_configs`` flags legacy entries whose control flags
+    disagree with the rest of the definition."""
+
+    TOGGLE = {
+        "id": "power",
+        "label": "Power",
+        "widget": "toggle",
+        "states": {"on": {"wire": {"f_port": 85, "payload_hex": "01"}}},
+    }
+
+    def _codes(self, device):
+        return {i.code for i in audit_control_configs(VendorModel.objects.filter(pk=device.pk))}
+
+    def test_consistent_device_is_clean(self, smart_plug_vm):
+        ControlConfig.objects.create(device_type=smart_plug_vm, controllable=True, controls=[self.TOGGLE])
+        assert self._codes(smart_plug_vm) == set()
+
+    def test_controls_without_flag_is_fixable(self, smart_plug_vm):
+        ControlConfig.objects.create(device_type=smart_plug_vm, controllable=False, controls=[self.TOGGLE])
+        [issue] = audit_control_configs(VendorModel.objects.filter(pk=smart_plug_vm.pk))
+        assert issue.code == "controls-not-controllable"
+
+        issue.fix(None)
+        assert ControlConfig.objects.get(device_type=smart_plug_vm).controllable is True
+        history = DeviceHistory.objects.filter(device=smart_plug_vm).order_by("-version").first()
+        assert history.changes["control_config.controllable"] == {"old": False, "new": True}
+
+    def test_flag_without_controls(self, smart_plug_vm):
+        ControlConfig.objects.create(device_type=smart_plug_vm, controllable=True)
+        assert self._codes(smart_plug_vm) == {"controllable-without-controls"}
+
+    def test_downlink_without_controls(self, smart_plug_vm):
+        LoRaWANConfig.objects.create(device_type=smart_plug_vm, downlink_f_port=85)
+        assert self._codes(smart_plug_vm) == {"downlink-without-controls"}
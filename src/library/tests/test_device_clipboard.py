@@ -0,0 +1,65 @@
+"""Copying a device as YAML and pasting it back as a new model."""
+
+import pytest
+import yaml
+from django.contrib.auth import get_user_model
+from django.test import Client
+
+from library.device_clipboard import paste_device
+from library.drafts import DraftError, file_device, initial_content
+from library.exporters import export_device_yaml
+from library.models import Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def meter():
+    vendor = Vendor.objects.create(name="Clip Vendor", slug="clip-vendor")
+    device = yaml.safe_load(initial_content("modbus"))
+    device["device_type"] = "power_meter"
+    device["description"] = "Copied around in chat"
+    device["technology_config"].update({
+        "function": "holding", "byte_order": "big_endian", "word_order": "high_first",
+        "register_definitions": [{"field": {"name": "energy", "unit": "kWh"}, "address": 0, "data_type": "uint32"}],
+    })
+    return file_device(device, vendor, "CV-1")[0]
+
+
+def test_copied_yaml_pastes_as_a_new_model(meter):
+    copied = export_device_yaml(meter)
+    with pytest.raises(DraftError, match="already exists"):
+        paste_device(copied)
+
+    device, _ = paste_device(copied, model_number="CV-2")
+    assert device.vendor == meter.vendor
+    assert device.description == "Copied around in chat"
+    assert device.modbus_config.register_definitions.get().field_name == "energy"
+
+    bare = yaml.safe_load(copied)["models"][0]
+    bare["model_number"] = "CV-3"
+    assert paste_device(yaml.safe_dump(bare))[0].model_number == "CV-3"
+
+
+def test_paste_refuses_what_it_cannot_file(meter):
+    with pytest.raises(DraftError, match="Invalid YAML"):
+        paste_device("a: [unclosed")
+    with pytest.raises(DraftError, match="not 2"):
+        paste_device(yaml.safe_dump({"models": [{"model_number": "A"}, {"model_number": "B"}]}))
+    with pytest.raises(DraftError, match="Unknown vendor"):
+        paste_device(export_device_yaml(meter).replace("Clip Vendor", "Nobody"))
+    assert VendorModel.objects.count() == 1
+
+
+def test_paste_view_and_raw_yaml(meter):
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(username="clip-editor", password="x", role="editor"))
+    copied = client.get(f"/models/{meter.pk}/yaml/", {"raw": "1"}).content.decode()
+    assert copied == export_device_yaml(meter)
+
+    response = client.post("/models/paste/", {"content": copied, "model_number": "CV-9"})
+    device = VendorModel.objects.get(model_number="CV-9")
+    assert response["Location"] == f"/models/{device.pk}/"
+
+    response = client.post("/models/paste/", {"content": copied})
+    assert "already exists" in response.content.decode()
@@ -0,0 +1,126 @@
+"""Documentation links: validation, the editor, YAML round trip and the link lint rules."""
+
+import io
+import urllib.error
+
+import pytest
+import yaml
+from django.core.management import call_command
+
+import devicelib
+from library import link_check
+from library.exporters import export_to_yaml, snapshot_to_schema
+from library.forms import VendorModelForm
+from library.history import snapshot_device
+from library.importers import import_from_yaml
+from library.lint import LintDevice, lint_devices
+from library.models import Vendor, VendorModel, WMBusConfig
+from library.schema import validate_device
+
+pytestmark = pytest.mark.django_db
+
+LINKS = [
+    {"kind": "datasheet", "url": "https://acme.example/docs/lm-1.pdf", "title": "Datasheet rev. 3"},
+    {"kind": "vendor_page", "url": "https://acme.example/products/lm-1"},
+]
+
+
+@pytest.fixture
+def device():
+    vendor = Vendor.objects.create(name="Link Vendor", slug="link-vendor")
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number="LM-1", name="Linked Meter", device_type="water_meter", technology="wmbus",
+        description="Water meter", links=LINKS,
+    )
+    WMBusConfig.objects.create(device_type=device, manufacturer_code="ACM", wmbus_device_type=7)
+    return device
+
+
+def test_link_errors():
+    assert VendorModel.link_errors(LINKS) == []
+    assert VendorModel.link_errors([
+        {"kind": "brochure", "url": "https://acme.example/a"},
+        {"kind": "manual", "url": "ftp://acme.example/manual.pdf"},
+        {"kind": "manual", "url": "https://acme.example/a", "href": "x"},
+        "https://acme.example/b",
+    ]) == [
+        "Link 1: kind must be one of datasheet, manual, vendor_page.",
+        "Link 2: 'ftp://acme.example/manual.pdf' is not an http(s) URL.",
+        "Link 3: unknown key(s) href.",
+        "Link 3: https://acme.example/a is listed twice.",
+        "Link 4: must be an object with kind and url.",
+    ]
+
+
+def test_form_validates_links(device):
+    data = {
+        "vendor": device.vendor.pk, "model_number": "LM-1", "name": "Linked Meter", "device_type": "water_meter",
+        "technology": "wmbus", "description": "", "links": '[{"kind": "manual", "url": "acme.example/manual"}]',
+    }
+    form = VendorModelForm(data=data, instance=device)
+    assert not form.is_valid()
+    assert "not an http(s) URL" in form.errors["links"][0]
+
+    data["links"] = '[{"kind": "manual", "url": "https://acme.example/manual.pdf"}]'
+    form = VendorModelForm(data=data, instance=device)
+    assert form.is_valid(), form.errors
+    assert form.save().links == [{"kind": "manual", "url": "https://acme.example/manual.pdf"}]
+
+
+def test_links_round_trip_through_yaml_and_history(device, tmp_path):
+    export_to_yaml(tmp_path / "devices")
+    exported = yaml.safe_load((tmp_path / "devices" / "link-vendor.yaml").read_text())["models"][0]
+    assert exported["links"] == LINKS
+    assert validate_device(exported) == []
+    assert devicelib.load(tmp_path).device("link-vendor", "LM-1").link("datasheet") == LINKS[0]["url"]
+    assert snapshot_to_schema(snapshot_device(device))["links"] == LINKS
+
+    VendorModel.objects.all().delete()
+    import_from_yaml(tmp_path / "devices", tmp_path / "manifest.yaml")
+    assert VendorModel.objects.get(model_number="LM-1").links == LINKS
+
+
+def test_description_url_rule():
+    device = {
+        "vendor_name": "Acme", "model_number": "X", "description": "See https://acme.example/x.pdf (datasheet)",
+        "technology_config": {"technology": "wmbus", "manufacturer_code": "ACM", "wmbus_device_type": 7},
+        "processor_config": {"field_mappings": [{"source": "volume", "target": "water:total_volume"}]},
+    }
+    findings = lint_devices([LintDevice(data=device)])
+    assert [(f.rule, f.message) for f in findings] == [
+        ("description-url", "URL https://acme.example/x.pdf in description; list it under links instead"),
+    ]
+
+
+class FakeResponse(io.BytesIO):
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc):
+        return False
+
+
+def test_check_links(device, tmp_path, monkeypatch, capsys):
+    requested = []
+
+    def urlopen(request, timeout=None):
+        requested.append((request.get_method(), request.full_url))
+        if request.full_url.endswith(".pdf"):
+            raise urllib.error.HTTPError(request.full_url, 404, "Not Found", {}, None)
+        if request.get_method() == "HEAD":  # the vendor page refuses HEAD but serves GET
+            raise urllib.error.HTTPError(request.full_url, 405, "Method Not Allowed", {}, None)
+        return FakeResponse(b"<html></html>")
+
+    monkeypatch.setattr(link_check.urllib.request, "urlopen", urlopen)
+    export_to_yaml(tmp_path / "devices")
+
+    call_command("lint_library", path=str(tmp_path / "devices"))
+    assert requested == []  # opt-in
+
+    call_command("lint_library", path=str(tmp_path / "devices"), check_links=True)
+    out = capsys.readouterr().out
+    assert "link-unreachable" in out and "[links[0].url]" in out and "(HTTP 404)" in out
+    assert "products/lm-1 is unreachable" not in out
+    assert sorted(requested) == [
+        ("GET", LINKS[0]["url"]), ("GET", LINKS[1]["url"]), ("HEAD", LINKS[0]["url"]), ("HEAD", LINKS[1]["url"]),
+    ]
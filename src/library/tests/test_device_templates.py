@@ -0,0 +1,111 @@
+"""Device templates: built-in and repository starting points for new devices."""
+
+import pytest
+import yaml
+from django.contrib.auth import get_user_model
+from django.core.management import call_command
+from django.core.management.base import CommandError
+from django.test import Client
+
+from library.device_templates import TemplateError, load_template, load_templates, parse_template, template_device
+from library.lint import LintDevice, lint_devices
+from library.models import DeviceHistory, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture(autouse=True)
+def repo_templates(tmp_path, settings):
+    settings.DEVICE_TEMPLATES_DIR = tmp_path / "templates"
+    settings.DEVICE_TEMPLATES_DIR.mkdir()
+    return settings.DEVICE_TEMPLATES_DIR
+
+
+def test_builtin_templates_only_leave_the_fill_in_findings():
+    templates = {t.key: t for t in load_templates()}
+    assert set(templates) >= {"modbus-power-meter-3ph", "lorawan-class-a-sensor", "wmbus-water-meter"}
+    for template in templates.values():
+        assert template.builtin
+        device = template_device(template, "Acme", "T-1")
+        findings = {(f.rule, f.path) for f in lint_devices([LintDevice(data=device)])}
+        expected = {("missing-description", "description")}
+        if template.technology == "wmbus":
+            expected.add(("technology-required", "technology_config.manufacturer_code"))
+        assert findings == expected, template.key
+
+
+def test_template_device_is_a_copy():
+    template = load_template("modbus-power-meter-3ph")
+    device = template_device(template, "Acme", "PM-3", name="Power Meter 3")
+    assert (device["vendor_name"], device["model_number"], device["name"]) == ("Acme", "PM-3", "Power Meter 3")
+    device["technology_config"]["register_definitions"].clear()
+    assert template.device["technology_config"]["register_definitions"]
+
+
+def test_repository_templates_add_and_override(repo_templates):
+    (repo_templates / "wmbus-water-meter.yaml").write_text(yaml.safe_dump({
+        "name": "Our water meter",
+        "device": {
+            "device_type": "water_meter",
+            "technology_config": {"technology": "wmbus", "manufacturer_code": "ACM"},
+        },
+    }))
+    (repo_templates / "README.md").write_text("not a template")
+    template = load_template("wmbus-water-meter")
+    assert template.name == "Our water meter" and not template.builtin
+    with pytest.raises(TemplateError, match="available: lorawan-class-a-sensor"):
+        load_template("nope")
+
+
+def test_invalid_template_lists_every_problem():
+    with pytest.raises(TemplateError) as exc:
+        parse_template("bad", {
+            "device": {"model_number": "X", "technology_config": {"technology": "zigbee"}, "registr": []},
+            "tags": [],
+        })
+    message = str(exc.value)
+    for problem in ("Unknown key 'tags'", "name is required", "device.model_number is set when",
+                    "technology must be one of", "registr"):
+        assert problem in message
+
+
+def _create(model, *extra):
+    call_command("create_device", "--vendor", "Template Vendor", "--model", model, *extra)
+
+
+def test_create_device_from_template():
+    _create("TV-1", "--template", "modbus-power-meter-3ph")
+    vm = VendorModel.objects.get(model_number="TV-1")
+    assert vm.device_type == "power_meter"
+    assert vm.modbus_config.register_definitions.count() == 11
+    assert DeviceHistory.objects.filter(device=vm, action=DeviceHistory.Action.CREATED).exists()
+
+    with pytest.raises(CommandError, match="already exists"):
+        _create("tv-1", "--template", "wmbus-water-meter")
+    with pytest.raises(CommandError, match="No device template"):
+        _create("TV-2", "--template", "nope")
+    with pytest.raises(CommandError, match="required without --template"):
+        _create("TV-2")
+
+
+def test_create_device_from_template_in_tree(tmp_path):
+    (tmp_path / "devices").mkdir()
+    (tmp_path / "manifest.yaml").write_text(yaml.dump({"schema_version": 4, "vendors": []}))
+    call_command(
+        "create_device", "--vendor", "Tree Vendor", "--model", "LS-1", "--template", "lorawan-class-a-sensor",
+        "--path", str(tmp_path / "devices"),
+    )
+    [device] = yaml.safe_load((tmp_path / "devices" / "tree-vendor.yaml").read_text())["models"]
+    assert device["technology_config"]["device_class"] == "A"
+    assert "decodeUplink" in device["technology_config"]["payload_codec"]["script"]
+
+
+def test_new_draft_starts_from_a_template():
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(username="template-editor", password="x", role="editor"))
+    body = client.get("/drafts/create/").content.decode()
+    assert body.count("data-device-template") == len(load_templates())
+
+    body = client.get("/drafts/create/", {"template": "wmbus-water-meter"}).content.decode()
+    assert 'value="wM-Bus water meter"' in body
+    assert "wmbus_device_type: 7" in body
@@ -0,0 +1,400 @@
+"""devicelib: the standalone reader sees what the exporters write."""
+
+import hashlib
+import io
+import json
+import struct
+import tarfile
+import zipfile
+
+import pytest
+from django.core.exceptions import ValidationError
+
+import devicelib
+from library.exporters import _export_tech_config, export_to_json, export_to_yaml
+from library.importers import import_from_yaml
+from library.models import LoRaWANConfig, ModbusConfig, RegisterDefinition, Vendor, VendorModel, WMBusConfig
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def library_tree(tmp_path):
+    vendor = Vendor.objects.create(name="SDK Vendor", slug="sdk-vendor")
+    meter = VendorModel.objects.create(
+        vendor=vendor, model_number="SDK-1", name="Meter", device_type="power_meter", technology="modbus",
+    )
+    modbus = ModbusConfig.objects.create(device_type=meter)
+    RegisterDefinition.objects.create(
+        modbus_config=modbus, field_name="energy", field_unit="kWh", address=10, data_type="uint32", scale=0.1,
+    )
+    VendorModel.objects.create(
+        vendor=vendor, model_number="SDK-2", name="Sensor", device_type="environment_sensor", technology="lorawan",
+    )
+    export_to_yaml(tmp_path / "devices")
+    return tmp_path
+
+
+def _check(library):
+    vendor = library.vendor("SDK Vendor")
+    assert vendor.technologies == ("lorawan", "modbus")
+    meter = library.device("sdk-vendor", "sdk-1")
+    assert (meter.name, meter.technology) == ("Meter", "modbus")
+    reg = meter.register("energy")
+    assert (reg.address, reg.unit, reg.data_type) == (10, "kWh", "uint32")
+    assert reg.decode(25) == pytest.approx(2.5)
+    assert [d.model_number for d in library.devices(technology="lorawan")] == ["SDK-2"]
+
+
+def test_load_tree(library_tree):
+    _check(devicelib.load(library_tree))
+    _check(devicelib.load(library_tree / "manifest.yaml"))
+
+
+def test_load_from_zip_and_bundle(library_tree, tmp_path):
+    archive = tmp_path / "library.zip"
+    with zipfile.ZipFile(archive, "w") as zf:
+        zf.write(library_tree / "manifest.yaml", "manifest.yaml")
+        zf.write(library_tree / "devices" / "sdk-vendor.yaml", "devices/sdk-vendor.yaml")
+    _check(devicelib.load_fs(zipfile.Path(archive)))
+
+    export_to_json(tmp_path / "library.json")
+    _check(devicelib.load(tmp_path / "library.json"))
+
+
+def test_split_vendor_files_merge_into_one_vendor(library_tree):
+    manifest = library_tree / "manifest.yaml"
+    manifest.write_text(manifest.read_text().replace(
+        "- name: SDK Vendor\n", "- name: SDK Vendor\n  file: sdk-vendor.yaml\n- name: SDK Vendor\n", 1,
+    ))
+    library = devicelib.load(library_tree)
+    assert len(library.vendors) == 1
+    assert len(library.vendor("sdk-vendor").devices) == 4
+
+
+def test_missing_files_raise(library_tree):
+    (library_tree / "devices" / "sdk-vendor.yaml").unlink()
+    with pytest.raises(devicelib.LibraryLoadError, match="Vendor file not found"):
+        devicelib.load(library_tree)
+    with pytest.raises(devicelib.LibraryLoadError, match="Manifest not found"):
+        devicelib.load(library_tree / "devices")
+
+
+def test_recover_rebuilds_missing_or_stale_manifest(library_tree):
+    devices = library_tree / "devices"
+    (devices / "sdk-vendor.yaml").rename(devices / "renamed.yaml")
+    library = devicelib.load(library_tree, recover=True)
+    _check(library)
+    assert library.problems == (
+        "Dropped vendor SDK Vendor: sdk-vendor.yaml not found",
+        "Added vendor SDK Vendor from unlisted renamed.yaml",
+    )
+
+    (library_tree / "manifest.yaml").unlink()
+    library = devicelib.load(library_tree, recover=True)
+    _check(library)
+    assert library.problems[0] == "No manifest; vendor list rebuilt from devices/"
+
+
+def test_typed_technology_configs_round_trip(library_tree):
+    vendor = Vendor.objects.get(slug="sdk-vendor")
+    sensor = VendorModel.objects.get(model_number="SDK-2")
+    LoRaWANConfig.objects.create(device_type=sensor, device_class="A", downlink_f_port=10, payload_codec="function x() {}")
+    water = VendorModel.objects.create(
+        vendor=vendor, model_number="SDK-3", name="Water", device_type="water_meter", technology="wmbus",
+    )
+    WMBusConfig.objects.create(device_type=water, manufacturer_code="KAM", wmbus_device_type=7)
+
+    for device in VendorModel.objects.filter(vendor=vendor):
+        exported = _export_tech_config(device)
+        config = devicelib.decode_technology_config(exported)
+        assert config.technology == device.technology
+        assert config.to_dict() == exported
+
+    meter = devicelib.load(library_tree).device("sdk-vendor", "SDK-1")
+    assert isinstance(meter.config, devicelib.ModbusConfig)
+    assert meter.config.registers[0].scale == pytest.approx(0.1)
+
+
+@pytest.mark.parametrize("config, message", [
+    ({"technology": "modbus", "register_definitions": [{"scale": "0.1"}]}, r"\[0\]\.scale: expected a number"),
+    ({"technology": "modbus", "register_definitions": [{"address": 1.5}]}, r"address: expected an integer"),
+    ({"technology": "wmbus", "encryption_required": "yes"}, r"encryption_required: expected true/false"),
+    ({"technology": "zigbee"}, r"unknown technology 'zigbee'"),
+])
+def test_typed_config_rejects_wrong_types(config, message):
+    with pytest.raises(devicelib.TechnologyConfigError, match=message):
+        devicelib.decode_technology_config(config)
+
+
+def _bundle(*models):
+    return devicelib.from_bundle({"version": "1.0.0", "schema_version": 4, "vendors": [
+        {"name": "Index Vendor", "models": list(models)},
+    ]})
+
+
+def _wmbus(model, code="KAM", version="1b", device_type=7):
+    return {"model_number": model, "technology_config": {
+        "technology": "wmbus", "manufacturer_code": code, "wmbus_version": version, "wmbus_device_type": device_type,
+    }}
+
+
+def test_index_lookups(library_tree):
+    index = devicelib.Index(devicelib.load(library_tree))
+    assert index.device("SDK Vendor", " sdk-1 ").name == "Meter"
+    assert index.device("sdk-vendor", "nope") is None
+
+    library = _bundle(
+        _wmbus("W-1"), _wmbus("W-2", device_type=6),
+        {"model_number": "L-1", "technology_config": {"technology": "lorawan"},
+         "processor_config": {"decoder_type": "acme_v2"}},
+    )
+    index.rebuild(library)
+    assert [d.model_number for d in index.wmbus("kam")] == ["W-1", "W-2"]
+    assert [d.model_number for d in index.wmbus("KAM", "1B", 6)] == ["W-2"]
+    assert [d.model_number for d in index.by_decoder_type("acme_v2")] == ["L-1"]
+    assert index.duplicates == []
+
+
+def test_match_wmbus_header():
+    library = _bundle(
+        _wmbus("Heat", device_type=4), _wmbus("Water", device_type=7),
+        _wmbus("Any-Kam", version=None, device_type=None),
+        _wmbus("A", code="ABC", version="10", device_type=7), _wmbus("B", code="ABC", version="10", device_type=7),
+    )
+    index = devicelib.Index(library)
+    assert index.match("kam", 7, 0x1b).model_number == "Water"
+    assert index.match("KAM", 7, "1B").model_number == "Water"
+    assert index.match("KAM", 3, 0x1b).model_number == "Any-Kam"  # wildcard definition
+
+    with pytest.raises(devicelib.NoMatchError):
+        index.match("XYZ", 7, 0x1b)
+    with pytest.raises(devicelib.AmbiguousMatchError) as exc:
+        index.match("ABC", 7, 0x10)
+    assert [d.model_number for d in exc.value.candidates] == ["A", "B"]
+
+
+def _modbus(*registers, **config):
+    return {"model_number": "RP-1", "technology_config": {"technology": "modbus", **config, "register_definitions": [
+        {"address": address, "data_type": data_type, "field": {"name": name}} for address, data_type, name in registers
+    ]}}
+
+
+def test_read_plan_groups_contiguous_registers():
+    device = _bundle(_modbus(
+        (10, "float32", "power"), (0, "uint32", "energy"), (2, "int16", "temp"), (12, "uint16", "status"),
+        (200, "uint16", "far"), function="input", word_order="low_first",
+    )).device("index-vendor", "RP-1")
+
+    plan = devicelib.read_plan(device)
+    assert [(r.function_code, r.start, r.count) for r in plan.requests] == [(4, 0, 3), (4, 10, 3), (4, 200, 1)]
+    assert [(r.start, r.count) for r in devicelib.read_plan(device, max_gap=8).requests] == [(0, 13), (200, 1)]
+    # A 32-bit value is never split, even when the limit would allow a partial read.
+    assert [(r.start, r.count) for r in devicelib.read_plan(device, max_registers=2).requests] == [
+        (0, 2), (2, 1), (10, 2), (12, 1), (200, 1),
+    ]
+
+    hi, lo = struct.unpack(">HH", struct.pack(">f", 2.5))
+    values = plan.decode([[0x0001, 0x0002, 0xFFFF], [lo, hi, 7], [9]])
+    assert values == {"energy": 0x00020001, "temp": -1, "power": 2.5, "status": 7, "far": 9}
+
+
+def test_read_plan_address_range():
+    last = _bundle(_modbus((65534, "uint16", "a"), (65535, "uint16", "b"))).device("index-vendor", "RP-1")
+    assert [(r.start, r.count) for r in devicelib.read_plan(last).requests] == [(65534, 2)]
+    past = _bundle(_modbus((65535, "uint32", "wide"))).device("index-vendor", "RP-1")
+    with pytest.raises(devicelib.ReadPlanError, match="wide at 65535 \\(2 registers\\) is outside"):
+        devicelib.read_plan(past)
+
+
+def test_read_plan_rejects_other_technologies():
+    with pytest.raises(devicelib.ReadPlanError, match="Modbus"):
+        devicelib.read_plan(_bundle(_wmbus("W-1")).device("index-vendor", "W-1"))
+
+
+def test_hybrid_device_profiles(library_tree):
+    meter = VendorModel.objects.get(model_number="SDK-1")
+    meter.additional_technologies = ["wmbus"]
+    meter.full_clean()
+    meter.save()
+    WMBusConfig.objects.create(device_type=meter, manufacturer_code="KAM", wmbus_version="1b", wmbus_device_type=4)
+    export_to_yaml(library_tree / "devices")
+
+    library = devicelib.load(library_tree)
+    hybrid = library.device("sdk-vendor", "SDK-1")
+    assert hybrid.technologies == ("modbus", "wmbus")
+    assert hybrid.config_for("wmbus").manufacturer_code == "KAM"
+    assert hybrid.config_for("lorawan") is None
+    assert [d.model_number for d in library.devices(technology="wmbus")] == ["SDK-1"]
+    assert library.vendor("SDK Vendor").technologies == ("lorawan", "modbus", "wmbus")
+    assert devicelib.Index(library).match("KAM", 4, 0x1b).model_number == "SDK-1"
+    assert [(r.start, r.count) for r in devicelib.read_plan(hybrid).requests] == [(10, 2)]
+
+    # Both profiles survive a round trip through the YAML tree.
+    WMBusConfig.objects.filter(device_type=meter).delete()
+    VendorModel.objects.filter(pk=meter.pk).update(additional_technologies=[])
+    import_from_yaml(library_tree / "devices", library_tree / "manifest.yaml", strict=True)
+    meter.refresh_from_db()
+    assert meter.technologies == ["modbus", "wmbus"]
+    assert meter.wmbus_config.wmbus_device_type == 4
+
+    meter.additional_technologies = ["modbus"]
+    with pytest.raises(ValidationError, match="already the primary technology"):
+        meter.full_clean()
+
+
+def test_field_catalogue_lookup_and_validation():
+    fields = devicelib.fields
+    assert fields.lookup("energy_import_l2_t1").unit == "kWh"
+    assert fields.lookup("frequency_l1") is None  # frequency isn't per phase
+    assert fields.validate("voltage_l3", "V") == []
+    assert fields.validate("temperature", "K") == ["Unit 'K' doesn't fit temperature (expected '°C')"]
+    assert fields.suggest("total_energy_t2") == "energy_import_t2"
+    assert fields.suggest("activ_power") == "active_power"
+    assert "did you mean 'active_power'" in fields.validate("power", "W")[0]
+    assert fields.suggest("vendor_specific_thing") is None
+
+
+def test_unit_conversion():
+    units = devicelib.units
+    assert units.convert(1500, "Wh", "kWh") == 1.5
+    assert units.convert(293.15, "K", "°C") == pytest.approx(20.0)
+    assert units.convert(250, "L", "m³") == 0.25
+    assert units.to_canonical(5, "K", "temperature_difference") == 5  # a spread has no offset
+    with pytest.raises(units.UnitError, match="Cannot convert"):
+        units.conversion("kWh", "m³")
+
+
+def test_unit_check_follows_register_and_mapping_scaling():
+    def device(register_unit, mapping_scale):
+        return devicelib.Device.from_dict({
+            "technology_config": {"technology": "modbus", "register_definitions": [
+                {"address": 0, "data_type": "uint32", "field": {"name": "energy_import", "unit": register_unit}},
+            ]},
+            "processor_config": {"field_mappings": [
+                {"source": "energy_import", "target": "elec:total_energy", "scale": mapping_scale},
+            ]},
+        })
+
+    metric = devicelib.Metric("elec:total_energy", unit="kWh")
+    library = devicelib.Library(version="1", schema_version=4, metrics=(metric,))
+    assert devicelib.units.check_device(device("Wh", 0.001), library) == []
+    [problem] = devicelib.units.check_device(device("Wh", 1), library)
+    assert "needs scale 0.001" in problem
+    [problem] = devicelib.units.check_device(device("m³", 1))  # catalogue fallback without a library
+    assert "Cannot convert 'm³'" in problem
+
+
+def test_index_reports_duplicate_keys():
+    library = _bundle(_wmbus("W-1"), _wmbus("w 1"))
+    index = devicelib.Index(library)
+    assert sorted(d.kind for d in index.duplicates) == ["model", "wmbus"]
+    assert index.device("index-vendor", "W-1").model_number == "W-1"
+    with pytest.raises(devicelib.DuplicateKeyError, match="duplicate model key"):
+        devicelib.Index(library, strict=True)
+
+
+class FakeGitHub:
+    """``urlopen`` stand-in serving one release; counts requests."""
+
+    def __init__(self, monkeypatch, assets: dict[str, bytes], sums: bool = True):
+        self.requests = []
+        self.tokens = set()
+        self.redirected_headers = set()  # headers that would follow a redirect off the API host
+        self.files = dict(assets)
+        if sums:
+            self.files["SHA256SUMS"] = "".join(
+                f"{hashlib.sha256(body).hexdigest()}  {name}\n" for name, body in assets.items()
+            ).encode()
+        monkeypatch.setattr(devicelib.release.urllib.request, "urlopen", self.urlopen)
+
+    def urlopen(self, request, timeout=None):
+        url = request.full_url
+        self.requests.append(url)
+        self.tokens.add(request.get_header("Authorization"))
+        self.redirected_headers.update(request.headers)
+        if url.endswith("/releases/tags/v1.2.0"):
+            body = json.dumps({"assets": [
+                {"name": name, "url": f"{devicelib.release.API_URL}/repos/r/releases/assets/{name}",
+                 "browser_download_url": f"https://dl.example/{name}"}
+                for name in self.files
+            ]}).encode()
+        else:
+            body = self.files[url.rsplit("/", 1)[1]]
+        return FakeResponse(body)
+
+
+class FakeResponse(io.BytesIO):
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc):
+        return False
+
+
+def _tarball(library_tree, tmp_path):
+    archive = tmp_path / "library-1.2.0.tar.gz"
+    with tarfile.open(archive, "w:gz") as tar:
+        tar.add(library_tree / "manifest.yaml", "library-1.2.0/manifest.yaml")
+        tar.add(library_tree / "devices", "library-1.2.0/devices")
+    return archive.read_bytes()
+
+
+def test_fetch_release_verifies_and_caches(library_tree, tmp_path, monkeypatch):
+    export_to_json(tmp_path / "bundle.json")
+    github = FakeGitHub(monkeypatch, {
+        "library-1.2.0.json": (tmp_path / "bundle.json").read_bytes(),
+        "library-1.2.0.tar.gz": _tarball(library_tree, tmp_path),
+    })
+    cache = tmp_path / "cache"
+
+    release = devicelib.fetch_release("1.2.0", cache_dir=cache)
+    assert release.path.suffix == ".json"
+    _check(release.load())
+    _check(devicelib.load_release("v1.2.0", kind="tarball", cache_dir=cache))
+
+    fetched = len(github.requests)
+    assert devicelib.fetch_release("1.2.0", cache_dir=cache).sha256 == release.sha256
+    assert len(github.requests) == fetched  # served from the cache
+
+    release.path.write_text("{}")  # a corrupted cache is fetched again
+    _check(devicelib.load_release("1.2.0", cache_dir=cache))
+    assert len(github.requests) > fetched
+
+
+def test_fetch_release_rejects_bad_checksums(tmp_path, monkeypatch):
+    FakeGitHub(monkeypatch, {"library-1.2.0.json": b'{"vendors": []}'})
+    with pytest.raises(devicelib.ReleaseFetchError, match="Checksum mismatch"):
+        devicelib.fetch_release("1.2.0", sha256="0" * 64, cache_dir=tmp_path)
+    assert not any(tmp_path.rglob("*.json"))
+
+    FakeGitHub(monkeypatch, {"library-1.2.0.json": b"{}"}, sums=False)
+    with pytest.raises(devicelib.ReleaseFetchError, match="No checksum published"):
+        devicelib.fetch_release("1.2.0", cache_dir=tmp_path)
+    with pytest.raises(devicelib.ReleaseFetchError, match="no tarball asset"):
+        devicelib.fetch_release("1.2.0", kind="tarball", cache_dir=tmp_path)
+
+
+def test_github_token_without_the_gh_cli(tmp_path, monkeypatch):
+    monkeypatch.delenv("GITHUB_TOKEN", raising=False)
+    monkeypatch.setenv("GH_TOKEN", "gh-env")
+    github = FakeGitHub(monkeypatch, {"library-1.2.0.json": b"{}"})
+    devicelib.fetch_release("1.2.0", cache_dir=tmp_path)
+    assert github.tokens == {"Bearer gh-env"}
+    assert "Authorization" not in github.redirected_headers
+    assert not any(url.startswith("https://dl.example/") for url in github.requests)
+    monkeypatch.setenv("GITHUB_TOKEN", "github-env")
+    assert devicelib.release.github_token() == "github-env"
+
+    monkeypatch.delenv("GITHUB_TOKEN")
+    monkeypatch.delenv("GH_TOKEN")
+    monkeypatch.setattr(devicelib.release.shutil, "which", lambda name: None)
+    assert devicelib.release.github_token() is None
+
+    def gh_auth_token(args, **kwargs):
+        assert args == ["/usr/bin/gh", "auth", "token"]
+        return devicelib.release.subprocess.CompletedProcess(args, 0, stdout="gho_stored\n")
+
+    monkeypatch.setattr(devicelib.release.shutil, "which", lambda name: "/usr/bin/gh")
+    monkeypatch.setattr(devicelib.release.subprocess, "run", gh_auth_token)
+    assert devicelib.release.github_token() == "gho_stored"
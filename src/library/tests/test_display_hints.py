@@ -0,0 +1,60 @@
+"""Per-register display hints: model, API, history and YAML round-trip."""
+
+import pytest
+import yaml
+
+from library.api.serializers import RegisterDefinitionSerializer
+from library.exporters import export_to_yaml
+from library.history import snapshot_device
+from library.importers import import_from_yaml
+from library.models import ModbusConfig, RegisterDefinition, Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def registers():
+    vendor = Vendor.objects.create(name="Hint Vendor", slug="hint-vendor")
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number="HV-1", name="Hint Meter", device_type="power_meter", technology="modbus",
+    )
+    modbus = ModbusConfig.objects.create(device_type=device)
+    hinted = RegisterDefinition.objects.create(
+        modbus_config=modbus, field_name="voltage_l1", field_unit="V", address=0, data_type="float32",
+        display_name="Voltage L1", display_precision=1, display_icon="zap", display_category="power quality",
+    )
+    plain = RegisterDefinition.objects.create(
+        modbus_config=modbus, field_name="energy_total", field_unit="kWh", address=2, data_type="uint32",
+    )
+    return hinted, plain
+
+
+def test_display_omits_unset_hints(registers):
+    hinted, plain = registers
+    assert hinted.display == {"name": "Voltage L1", "precision": 1, "icon": "zap", "category": "power quality"}
+    assert plain.display == {}
+
+    plain.display_precision = 0
+    assert plain.display == {"precision": 0}
+
+
+def test_api_includes_display_only_when_set(registers):
+    hinted, plain = registers
+    assert RegisterDefinitionSerializer(hinted).data["display"]["icon"] == "zap"
+    assert "display" not in RegisterDefinitionSerializer(plain).data
+
+
+def test_snapshot_and_yaml_round_trip(registers, tmp_path):
+    hinted, plain = registers
+    snapshot = snapshot_device(hinted.modbus_config.device_type)
+    assert "display" in snapshot["registers"][0]
+    assert "display" not in snapshot["registers"][1]
+
+    export_to_yaml(tmp_path / "devices")
+    data = yaml.safe_load((tmp_path / "devices" / "hint-vendor.yaml").read_text())
+    assert data["models"][0]["technology_config"]["register_definitions"][0]["display"]["name"] == "Voltage L1"
+
+    RegisterDefinition.objects.all().delete()
+    import_from_yaml(tmp_path / "devices", tmp_path / "manifest.yaml")
+    restored = RegisterDefinition.objects.get(address=0)
+    assert restored.display == hinted.display
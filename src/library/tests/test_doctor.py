@@ -0,0 +1,131 @@
+"""doctor: environment and YAML tree health checks."""
+
+import io
+
+import pytest
+import yaml
+from django.core.management import call_command
+from django.core.management.base import CommandError
+
+from library import doctor
+from library.doctor import check_environment, check_github, check_tree
+from library.management.errors import AuthFailure
+from library.models import DEFAULT_SCHEMA_VERSION
+from library.submit import SubmitError, Unauthorized, Unreachable
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def tree(tmp_path):
+    devices = tmp_path / "devices"
+    devices.mkdir()
+    (devices / "acme.yaml").write_text(yaml.dump({"models": []}))
+    (tmp_path / "manifest.yaml").write_text(yaml.dump({
+        "schema_version": DEFAULT_SCHEMA_VERSION,
+        "vendors": [{"name": "Acme", "file": "acme.yaml", "technologies": []}],
+    }))
+    return devices
+
+
+def _status(checks):
+    return {c.name: c.status for c in checks}
+
+
+def test_environment_ok():
+    assert _status(check_environment()) == {"database": "ok", "migrations": "ok"}
+
+
+class FakeGitHub:
+    def __init__(self, error: SubmitError | None = None):
+        self.error = error
+
+    def get(self, path):
+        if self.error:
+            raise self.error
+        return {"login": "vendor"}
+
+
+@pytest.fixture
+def no_token(monkeypatch):
+    monkeypatch.setattr(doctor, "github_token", lambda: None)
+
+
+def test_github_token(monkeypatch):
+    monkeypatch.setenv("GITHUB_TOKEN", "token")
+    monkeypatch.setattr(doctor, "github_token", lambda: "token")
+    [check] = check_github(FakeGitHub())
+    assert (check.status, check.message) == ("ok", "Authenticated as vendor (token from GITHUB_TOKEN)")
+
+    [check] = check_github(FakeGitHub(Unauthorized("GET /user: HTTP 401 Bad credentials", 401)))
+    assert check.status == "error"
+    assert "gh auth refresh" in check.fix
+    assert check_github(FakeGitHub(Unreachable("GET /user: timed out")))[0].status == "warning"
+
+
+def test_github_without_token(no_token, monkeypatch):
+    monkeypatch.setattr(doctor.shutil, "which", lambda name: None)
+    [check] = check_github()
+    assert (check.status, check.fix) == (
+        "error", "Set GITHUB_TOKEN to a token that can open pull requests, or install gh and run: gh auth login",
+    )
+    monkeypatch.setattr(doctor.shutil, "which", lambda name: f"/usr/bin/{name}")
+    [check] = check_github()
+    assert (check.message, check.fix) == (
+        "No GitHub token: the gh CLI is installed but not logged in", "Run: gh auth login (or set GITHUB_TOKEN)",
+    )
+
+    with pytest.raises(AuthFailure, match="1 check"):
+        call_command("doctor", stdout=io.StringIO())
+    call_command("doctor", "--skip-github", stdout=io.StringIO())
+
+
+def test_healthy_tree(tree):
+    statuses = _status(check_tree(tree, tree.parent / "manifest.yaml"))
+    assert set(statuses.values()) == {"ok"}
+
+
+def test_dangling_and_orphaned(tree):
+    (tree / "acme.yaml").rename(tree / "other.yaml")
+    checks = check_tree(tree, tree.parent / "manifest.yaml")
+    statuses = _status(checks)
+    assert statuses["dangling-entries"] == "error"
+    assert statuses["orphaned-files"] == "warning"
+    assert all(c.fix for c in checks if c.status != "ok")
+
+
+@pytest.mark.parametrize("version,status", [
+    (DEFAULT_SCHEMA_VERSION + 1, "error"),
+    (DEFAULT_SCHEMA_VERSION - 1, "warning"),
+    (1, "error"),
+    ("four", "error"),
+])
+def test_schema_version(tree, version, status):
+    manifest = tree.parent / "manifest.yaml"
+    data = yaml.safe_load(manifest.read_text())
+    data["schema_version"] = version
+    manifest.write_text(yaml.dump(data))
+    assert _status(check_tree(tree, manifest))["schema-version"] == status
+
+
+def test_missing_layout(tmp_path):
+    checks = check_tree(tmp_path / "devices", tmp_path / "manifest.yaml")
+    assert [c.status for c in checks] == ["error", "error"]
+
+
+def test_command_fails_on_errors(tree):
+    (tree / "acme.yaml").unlink()
+    with pytest.raises(CommandError, match="1 check"):
+        call_command("doctor", "--path", str(tree), "--skip-github")
+
+
+def test_yaml_content(tree):
+    manifest = tree.parent / "manifest.yaml"
+    (tree / "acme.yaml").write_text("models:\n- model_number: PM-1\n  description: on\n")
+    check = next(c for c in check_tree(tree, manifest) if c.name == "yaml-content")
+    assert check.status == "warning"
+    assert "acme.yaml line 3, column 16: description" in check.message
+    assert "fmt_yaml" in check.fix
+
+    (tree / "acme.yaml").write_bytes(b"models:\n- model_number: PM-\xff\n")
+    assert _status(check_tree(tree, manifest))["yaml-content"] == "error"
@@ -0,0 +1,113 @@
+"""Scratchpad drafts: editing outside the library and filing under a vendor."""
+
+import pytest
+import yaml
+from django.contrib.auth import get_user_model
+from django.test import Client
+
+from library.drafts import DraftError, file_draft, initial_content
+from library.exporters import export_to_yaml
+from library.forms import TechnologyConfigForm
+from library.models import DeviceDraft, Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+User = get_user_model()
+
+
+@pytest.fixture
+def vendor():
+    return Vendor.objects.create(name="Draft Vendor", slug="draft-vendor")
+
+
+@pytest.fixture
+def draft():
+    device = yaml.safe_load(initial_content("modbus"))
+    device["device_type"] = "power_meter"
+    device["description"] = "Unknown meter found on site"
+    device["technology_config"].update({
+        "function": "holding", "byte_order": "big_endian", "word_order": "high_first",
+        "register_definitions": [{"field": {"name": "energy", "unit": "kWh"}, "address": 0, "data_type": "uint32"}],
+    })
+    return DeviceDraft.objects.create(title="Mystery meter", content=yaml.safe_dump(device))
+
+
+@pytest.fixture
+def client():
+    user = User.objects.create_user(username="draft-editor", password="x", role="editor")
+    client = Client()
+    client.force_login(user)
+    return client
+
+
+def test_drafts_are_not_exported(draft, tmp_path):
+    stats = export_to_yaml(tmp_path / "devices")
+    assert stats["devices_exported"] == 0
+
+
+def test_file_draft_creates_model_and_removes_draft(draft, vendor):
+    device, _ = file_draft(draft, vendor, "MM-1")
+    assert device.vendor == vendor
+    assert device.model_number == "MM-1"
+    assert device.modbus_config.register_definitions.get().field_name == "energy"
+    assert not DeviceDraft.objects.exists()
+
+
+def test_file_draft_refuses_duplicates_and_missing_technology(draft, vendor):
+    VendorModel.objects.create(vendor=vendor, model_number="MM-1", name="MM-1", device_type="power_meter")
+    with pytest.raises(DraftError, match="already exists"):
+        file_draft(draft, vendor, "mm-1")
+
+    draft.content = "name: half-done\n"
+    with pytest.raises(DraftError, match="technology"):
+        file_draft(draft, vendor, "MM-2")
+    assert DeviceDraft.objects.filter(pk=draft.pk).exists()
+
+
+def test_invalid_yaml_is_rejected_on_save(client):
+    response = client.post("/drafts/create/", {"title": "Broken", "content": "a: [unclosed"})
+    assert response.status_code == 200
+    assert "Invalid YAML" in response.content.decode()
+    assert not DeviceDraft.objects.exists()
+
+
+def test_file_view_redirects_to_model(client, draft, vendor):
+    response = client.post(f"/drafts/{draft.pk}/file/", {"vendor": vendor.pk, "model_number": "MM-1"})
+    device = VendorModel.objects.get(vendor=vendor, model_number="MM-1")
+    assert response.status_code == 302
+    assert response["Location"] == f"/models/{device.pk}/"
+
+
+def test_technology_form_fields_come_from_the_struct():
+    form = TechnologyConfigForm({"technology": "lorawan", "device_class": "A", "payload_codec": {"script": "x"}})
+    assert list(form.fields) == [
+        "device_class", "lorawan_version", "lorawan_phy_version", "frequency_plan_id", "join_eui_default",
+        "supports_join", "downlink_f_port", "other",
+    ]
+    assert ("C", "Class C") in form.fields["device_class"].choices
+    assert form.initial["supports_join"] is True
+    assert form.initial["other"] == {"payload_codec": {"script": "x"}}
+
+
+def test_technology_form_edits_draft(client, draft):
+    response = client.get(f"/drafts/{draft.pk}/technology/")
+    assert response.context["form"].initial["function"] == "holding"
+    registers = yaml.safe_load(draft.content)["technology_config"]["register_definitions"]
+    other = yaml.safe_dump({"register_definitions": registers})
+    response = client.post(f"/drafts/{draft.pk}/technology/", {
+        "function": "input", "byte_order": "big_endian", "word_order": "low_first", "other": other,
+    })
+    assert response.status_code == 302
+    draft.refresh_from_db()
+    config = yaml.safe_load(draft.content)["technology_config"]
+    assert (config["function"], config["word_order"]) == ("input", "low_first")
+    assert config["register_definitions"][0]["address"] == 0
+
+
+def test_technology_form_checks_other_keys(client, draft):
+    before = draft.content
+    response = client.post(f"/drafts/{draft.pk}/technology/", {"function": "input", "other": "function: holding"})
+    assert "Set function in the fields above" in response.content.decode()
+    response = client.post(f"/drafts/{draft.pk}/technology/", {"other": "register_definitions: nope"})
+    assert "register_definitions: expected a list" in response.content.decode()
+    draft.refresh_from_db()
+    assert draft.content == before
@@ -0,0 +1,63 @@
+"""Duplicating a device: the form starts from the source, configs and registers are copied."""
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.test import Client
+
+from library.models import DeviceHistory, ModbusConfig, ProcessorConfig, RegisterDefinition, Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def source():
+    vendor = Vendor.objects.create(name="Copy Vendor", slug="copy-vendor")
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number="CP-1", name="Meter", device_type="power_meter", technology="modbus",
+        description="Three-phase meter",
+    )
+    modbus = ModbusConfig.objects.create(device_type=device, function="holding", byte_order="big_endian")
+    RegisterDefinition.objects.create(modbus_config=modbus, field_name="energy", address=0, data_type="uint32")
+    RegisterDefinition.objects.create(modbus_config=modbus, field_name="power", address=2, data_type="int32")
+    ProcessorConfig.objects.create(device_type=device)
+    return device
+
+
+@pytest.fixture
+def client():
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(username="copy-editor", password="x", role="editor"))
+    return client
+
+
+def test_form_starts_from_source_without_model_number(client, source):
+    form = client.get(f"/models/{source.pk}/duplicate/").context["form"]
+    assert form.initial["name"] == "Meter"
+    assert form.initial["technology"] == "modbus"
+    assert "model_number" not in form.initial
+    assert 'data-duplicate="page"' in client.get(f"/models/{source.pk}/").content.decode()
+
+
+def test_duplicate_copies_configs_and_registers(client, source):
+    response = client.post(f"/models/{source.pk}/duplicate/", {
+        "vendor": source.vendor.pk, "model_number": "CP-2", "name": "Meter", "device_type": "power_meter",
+        "technology": "modbus", "description": "Three-phase meter", "links": "[]",
+    })
+    copy = VendorModel.objects.get(model_number="CP-2")
+    assert response.url == f"/models/{copy.pk}/"
+    assert copy.key != source.key
+    assert copy.modbus_config.pk != source.modbus_config.pk
+    assert copy.modbus_config.byte_order == "big_endian"
+    assert list(copy.modbus_config.register_definitions.values_list("field_name", "address")) == [
+        ("energy", 0), ("power", 2),
+    ]
+    assert ProcessorConfig.objects.filter(device_type=copy).exists()
+    assert DeviceHistory.objects.get(device=copy).action == DeviceHistory.Action.CREATED
+
+    # Deep copies: editing the duplicate leaves the source alone.
+    copy.modbus_config.register_definitions.filter(field_name="power").update(address=4)
+    assert source.modbus_config.register_definitions.get(field_name="power").address == 2
+
+    client.post("/undo/")
+    assert not VendorModel.objects.filter(pk=copy.pk).exists()
+    assert source.modbus_config.register_definitions.count() == 2
@@ -0,0 +1,87 @@
+"""Error taxonomy: distinct exit codes per failure class and --json-errors."""
+
+import json
+
+import pytest
+import yaml
+from django.core.management import call_command
+from django.core.management.base import CommandError
+
+from library.exporters import export_to_yaml
+from library.management.commands.lint_library import Command as LintCommand
+from library.management.commands.rename_vendor import Command as RenameCommand
+from library.management.errors import (
+    Conflict,
+    ExitCode,
+    NotFound,
+    UsageError,
+    ValidationFailed,
+    error_payload,
+)
+from library.models import Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def tree(tmp_path):
+    vendor = Vendor.objects.create(name="Exit Vendor", slug="exit-vendor")
+    VendorModel.objects.create(
+        vendor=vendor, model_number="EX-1", name="EX-1", device_type="power_meter", technology="modbus",
+    )
+    Vendor.objects.create(name="Other Vendor", slug="other-vendor")
+    export_to_yaml(tmp_path / "devices")
+    return tmp_path / "devices", tmp_path / "manifest.yaml"
+
+
+def test_library_errors_are_command_errors():
+    error = ValidationFailed("broken", {"errors": 2})
+    assert isinstance(error, CommandError)
+    assert error.returncode == ExitCode.VALIDATION == 3
+    assert error_payload(error) == {
+        "error": {"code": "validation", "exit_code": 3, "message": "broken", "details": {"errors": 2}},
+    }
+
+
+def test_payload_for_plain_and_unexpected_errors():
+    assert error_payload(CommandError("old style", returncode=9))["error"] == {
+        "code": "failure", "exit_code": 9, "message": "old style",
+    }
+    assert error_payload(RuntimeError("boom"))["error"]["code"] == "internal"
+
+
+def test_verify_manifest_mismatch_is_validation(tree):
+    devices_path, manifest_path = tree
+    manifest = yaml.safe_load(manifest_path.read_text())
+    manifest["vendors"][0]["technologies"] = ["lorawan"]
+    manifest_path.write_text(yaml.safe_dump(manifest))
+    with pytest.raises(ValidationFailed):
+        call_command("verify_manifest", path=str(devices_path))
+
+
+def test_missing_manifest_is_not_found(tmp_path):
+    with pytest.raises(NotFound, match="Manifest not found"):
+        call_command("verify_manifest", path=str(tmp_path / "devices"))
+
+
+def test_rename_not_found_and_conflict(tree):
+    with pytest.raises(NotFound):
+        call_command("rename_vendor", "Missing Vendor", "Whatever")
+    with pytest.raises(Conflict):
+        call_command("rename_vendor", "Exit Vendor", "Other Vendor")
+
+
+def test_json_errors_on_stderr(tree, capsys):
+    with pytest.raises(SystemExit) as exc:
+        RenameCommand().run_from_argv(["manage.py", "rename_vendor", "Missing Vendor", "X", "--json-errors"])
+    assert exc.value.code == ExitCode.NOT_FOUND
+    payload = json.loads(capsys.readouterr().err)
+    assert payload["error"]["code"] == "not_found"
+    assert "Missing Vendor" in payload["error"]["message"]
+
+
+def test_json_errors_for_bad_arguments(capsys):
+    with pytest.raises(SystemExit) as exc:
+        LintCommand().run_from_argv(["manage.py", "lint_library", "--no-such-flag", "--json-errors"])
+    assert exc.value.code == ExitCode.USAGE
+    assert json.loads(capsys.readouterr().err)["error"]["code"] == UsageError.code
@@ -0,0 +1,67 @@
+"""Editing a device or one of its configs in an external editor."""
+
+import sys
+
+import pytest
+from django.core.management import call_command
+from django.core.management.base import CommandError
+
+from library.external_edit import EditError, apply_edit, document
+from library.models import DeviceHistory, ModbusConfig, RegisterDefinition, Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def device():
+    vendor = Vendor.objects.create(name="Edit Vendor", slug="edit-vendor")
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number="ED-1", name="Meter", device_type="power_meter", technology="modbus",
+    )
+    modbus = ModbusConfig.objects.create(device_type=device, function="holding", byte_order="big_endian")
+    RegisterDefinition.objects.create(modbus_config=modbus, field_name="energy", address=0, data_type="uint32")
+    return device
+
+
+def _editor(tmp_path, old, new):
+    """An editor command that replaces ``old`` with ``new`` in the file."""
+    script = tmp_path / "editor.py"
+    script.write_text(
+        "import pathlib, sys\n"
+        "path = pathlib.Path(sys.argv[1])\n"
+        f"path.write_text(path.read_text().replace({old!r}, {new!r}))\n"
+    )
+    return f"{sys.executable} {script}"
+
+
+def test_edit_section_in_editor(device, tmp_path):
+    assert document(device, "technology_config").startswith("technology: modbus\n")
+    editor = _editor(tmp_path, "address: 0", "address: 40")
+    call_command("edit_device", "ed-1", "--section", "technology_config", "--editor", editor)
+    assert device.modbus_config.register_definitions.get().address == 40
+    assert DeviceHistory.objects.filter(device=device).count() == 1
+
+
+def test_unchanged_document_saves_nothing(device, tmp_path, capsys):
+    call_command("edit_device", "ED-1", "--editor", _editor(tmp_path, "", ""))
+    assert "No changes to Edit Vendor ED-1" in capsys.readouterr().out
+    assert not DeviceHistory.objects.filter(device=device).exists()
+
+
+def test_invalid_edits_are_refused(device):
+    text = document(device)
+    with pytest.raises(EditError, match="model_number can't be changed"):
+        apply_edit(device, text.replace("model_number: ED-1", "model_number: ED-2"))
+    with pytest.raises(EditError, match="Not valid YAML") as excinfo:
+        apply_edit(device, "technology: [modbus", "technology_config")
+    assert excinfo.value.text == "technology: [modbus"
+    section = document(device, "technology_config")
+    with pytest.raises(EditError, match="address"):
+        apply_edit(device, section.replace("address: 0", "address: -1"), "technology_config")
+
+
+def test_command_reports_invalid_document(device, tmp_path):
+    with pytest.raises(CommandError, match="Not valid YAML"):
+        call_command("edit_device", "ED-1", "--editor", _editor(tmp_path, "name: Meter", "name: [Meter"))
+    device.refresh_from_db()
+    assert device.name == "Meter"
@@ -0,0 +1,109 @@
+"""Find and replace across devices: preview every hit, apply the picked ones."""
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.test import Client
+
+from library.models import DeviceHistory, ModbusConfig, RegisterDefinition, Vendor, VendorModel
+from library.replace import find_hits, replace_value
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def client():
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(username="replace-editor", password="x", role="editor"))
+    return client
+
+
+@pytest.fixture
+def meters():
+    meters = []
+    for vendor_name in ("Acme", "Zeta"):
+        vendor = Vendor.objects.create(name=vendor_name, slug=vendor_name.lower())
+        device = VendorModel.objects.create(
+            vendor=vendor, model_number="PM-1", name="Power meter", device_type="power_meter", technology="modbus",
+            description="Three phase powre meter.",
+        )
+        modbus = ModbusConfig.objects.create(device_type=device, function="input")
+        RegisterDefinition.objects.create(
+            modbus_config=modbus, field_name="active_power_total", field_unit="W", address=0, data_type="float32",
+            field_description="Total active powre",
+        )
+        RegisterDefinition.objects.create(
+            modbus_config=modbus, field_name="energy_total", field_unit="Wh", address=2, data_type="float32",
+        )
+        meters.append(device)
+    return meters
+
+
+def test_replace_value():
+    assert replace_value("Powre powre", "powre", "power") == "power power"
+    assert replace_value("Powre powre", "powre", "power", match_case=True) == "Powre power"
+    assert replace_value("Wh", "W", "kW", whole_value=True) is None
+    assert replace_value("w", "W", "kW", whole_value=True) == "kW"
+    assert replace_value("energy", "power", "x") is None
+
+
+def test_hits_by_target_and_vendor(meters):
+    hits = find_hits("powre", "power", ["description"])
+    assert [(h.device.vendor.name, h.where) for h in hits] == [
+        ("Acme", "description"),
+        ("Acme", "register 0 (active_power_total) description"),
+        ("Zeta", "description"),
+        ("Zeta", "register 0 (active_power_total) description"),
+    ]
+    assert hits[0].new == "Three phase power meter."
+
+    acme = Vendor.objects.get(slug="acme")
+    assert [h.old for h in find_hits("W", "kW", ["unit"], vendor=acme)] == ["W", "Wh"]
+    assert [h.old for h in find_hits("W", "kW", ["unit"], vendor=acme, whole_value=True)] == ["W"]
+    assert find_hits("powre", "power", ["field_name"]) == []
+
+
+def test_emptying_a_field_name_is_refused(meters):
+    [hit] = find_hits("energy_total", "", ["field_name"], vendor=meters[0].vendor)
+    assert hit.problem == "The field name can't be empty."
+
+
+def test_preview_lists_hits_without_changing_anything(client, meters):
+    response = client.get("/models/replace/", {"find": "powre", "replace": "power", "targets": ["description"]})
+    body = response.content.decode()
+    assert "4 hits" in body
+    assert body.count("data-replace-hit") == 4
+    assert not DeviceHistory.objects.exists()
+
+
+def test_apply_picked_hits(client, meters):
+    query = {"find": "powre", "replace": "power", "targets": ["description"], "vendor": "acme"}
+    hits = find_hits("powre", "power", ["description"], vendor=meters[0].vendor)
+    response = client.post("/models/replace/", {**query, "hits": [h.key for h in hits]})
+    assert response.status_code == 302
+    assert response.url.startswith("/models/replace/?find=powre")
+
+    acme, zeta = (VendorModel.objects.get(pk=m.pk) for m in meters)
+    assert acme.description == "Three phase power meter."
+    assert acme.modbus_config.register_definitions.get(address=0).field_description == "Total active power"
+    assert zeta.description == "Three phase powre meter."
+    [entry] = DeviceHistory.objects.filter(action=DeviceHistory.Action.UPDATED)
+    assert entry.device == acme
+
+
+def test_unpicked_hits_are_left_alone(client, meters):
+    [description, register] = find_hits("powre", "power", ["description"], vendor=meters[0].vendor)
+    client.post("/models/replace/", {
+        "find": "powre", "replace": "power", "targets": ["description"], "vendor": "acme", "hits": [register.key],
+    })
+    device = VendorModel.objects.get(pk=meters[0].pk)
+    assert device.description == "Three phase powre meter."
+    assert device.modbus_config.register_definitions.get(address=0).field_description == "Total active power"
+
+
+def test_name_and_description_of_one_register_together(client, meters):
+    client.post("/models/replace/", {
+        "find": "active", "replace": "real", "targets": ["field_name", "description"], "vendor": "acme",
+        "hits": [h.key for h in find_hits("active", "real", ["field_name", "description"], vendor=meters[0].vendor)],
+    })
+    register = RegisterDefinition.objects.get(modbus_config__device_type=meters[0], address=0)
+    assert (register.field_name, register.field_description) == ("real_power_total", "Total real powre")
@@ -0,0 +1,61 @@
+"""Syntax checks for embedded JavaScript codecs and the codec-syntax lint rule."""
+
+import pytest
+
+from library.js_check import check_script
+from library.lint import LintDevice, lint_devices
+
+CODEC = r"""
+// TTN v3 codec
+function decodeUplink(input) {
+  var b = input.bytes; /* two-byte
+  temperature */
+  var digits = /\d+[/]x/g, half = b[0] / 2 / 1;
+  var label = "a\"b" + 'c' + `t ${b.length > 1 ? `${b[1]}` : "{"} }`;
+  if (digits.test(label)) { return { data: { temperature: (b[0] << 8 | b[1]) / 10 } }; }
+  return { errors: ["short payload"] };
+}
+"""
+
+
+def test_valid_codec():
+    assert check_script(CODEC, "ttn_v3") == []
+    assert check_script("var decodeUplink = (input) => ({ data: {} });", "chirpstack") == []
+    assert check_script("function Decoder(bytes, port) { return {}; }", "ttn_v2") == []
+
+
+@pytest.mark.parametrize("expression", ["i++ / 2", "b[0]-- / 2", "(i)++ / 2", "++i / 2"])
+def test_division_after_increment(expression):
+    # Taken for the start of a regex, "/ 2" would be an unterminated one.
+    assert check_script(f"function decodeUplink(i) {{ var b = [1]; return {expression}; }}", "ttn_v3") == []
+
+
+@pytest.mark.parametrize("script, error", [
+    ("function decodeUplink(input) {\n  return {data: {}};\n", "line 1, column 30: '{' is never closed"),
+    ("function decodeUplink(input) {\n  var a = [1, 2);\n}\n",
+     "line 2, column 16: expected ']' to close '[' from line 2, found ')'"),
+    ("function decodeUplink(i) { return 1; }\n}\n", "line 2, column 1: unexpected '}'"),
+    ("function decodeUplink(i) {\n  var s = 'abc;\n}\n", "line 2, column 11: unterminated string"),
+    ("function decodeUplink(i) {\n  var s = `abc ${i}\n}\n", "line 2, column 11: unterminated template literal"),
+    ("function decodeUplink(i) {} /* x", "line 1, column 29: unterminated comment"),
+    ("var s = 'function decodeUplink(';", "no decodeUplink function, which ttn_v3 codecs must define"),
+])
+def test_syntax_errors(script, error):
+    assert [str(e) for e in check_script(script, "ttn_v3")] == [error]
+
+
+def test_lint_rule():
+    device = {
+        "vendor_name": "Acme", "model_number": "LS-1", "description": "Sensor",
+        "technology_config": {
+            "technology": "lorawan", "device_class": "A",
+            "payload_codec": {"format": "ttn_v2", "script": CODEC},
+        },
+        "processor_config": {"decoder_type": "js_codec", "field_mappings": [
+            {"source": "temperature", "target": "temperature"},
+        ]},
+    }
+    findings = [f for f in lint_devices([LintDevice(data=device)]) if f.rule == "codec-syntax"]
+    assert [(f.severity, f.path, f.message) for f in findings] == [
+        ("error", "technology_config.payload_codec.script", "no Decoder function, which ttn_v2 codecs must define"),
+    ]
@@ -0,0 +1,288 @@
+"""Library lint: rule registry, .sparklint.yaml config, DB and YAML sources."""
+
+import io
+
+import pytest
+import yaml
+from django.contrib.auth import get_user_model
+from django.core.management import call_command
+from django.core.management.base import CommandError
+from django.test import Client
+
+from library import lint
+from library.exporters import export_to_yaml
+from library.lint import (
+    LintConfig,
+    LintDevice,
+    device_statuses,
+    devices_from_database,
+    devices_from_yaml,
+    lint_devices,
+)
+from library.management.errors import UsageError
+from library.models import ModbusConfig, ProcessorConfig, RegisterDefinition, Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+
+def _device(**overrides):
+    device = {
+        "vendor_name": "Acme",
+        "model_number": "PM-1",
+        "name": "Power Meter",
+        "description": "Three-phase meter",
+        "technology_config": {
+            "technology": "modbus",
+            "function": "holding",
+            "byte_order": "big_endian",
+            "word_order": "high_first",
+            "register_definitions": [
+                {"field": {"name": "active_power", "unit": "W"}, "address": 0, "data_type": "int32"},
+            ],
+        },
+        "processor_config": {"field_mappings": [{"source": "active_power", "target": "power:active"}]},
+    }
+    device.update(overrides)
+    return LintDevice(data=device)
+
+
+def _rules(findings):
+    return {f.rule for f in findings}
+
+
+def _modbus_config(device):
+    modbus = ModbusConfig.objects.create(
+        device_type=device, function="holding", byte_order="big_endian", word_order="high_first",
+    )
+    RegisterDefinition.objects.create(modbus_config=modbus, field_name="active_power", address=0, data_type="int32")
+
+
+class TestRules:
+    def test_clean_device_has_no_findings(self):
+        assert lint_devices([_device()]) == []
+
+    def test_field_naming_flags_non_snake_case(self):
+        dev = _device()
+        dev.data["technology_config"]["register_definitions"][0]["field"]["name"] = "ActivePower"
+        findings = lint_devices([dev])
+        assert _rules(findings) == {"field-naming"}
+        assert findings[0].path == "technology_config.register_definitions[0].field.name"
+
+    def test_unit_whitelist(self):
+        dev = _device()
+        dev.data["technology_config"]["register_definitions"][0]["field"]["unit"] = "furlongs"
+        assert _rules(lint_devices([dev])) == {"unit-whitelist"}
+
+    def test_missing_description_and_empty_processor(self):
+        findings = lint_devices([_device(description="", processor_config={})])
+        assert _rules(findings) == {"missing-description", "empty-processor-config"}
+
+    def test_duplicate_technology(self):
+        hybrid = _device(additional_technology_configs=[
+            {"technology": "wmbus", "manufacturer_code": "KAM", "wmbus_device_type": 4},
+        ])
+        assert lint_devices([hybrid]) == []
+        findings = lint_devices([_device(additional_technology_configs=[{"technology": "modbus"}])])
+        assert [(f.rule, f.path) for f in findings] == [
+            ("duplicate-technology", "additional_technology_configs[0].technology"),
+        ]
+
+    def test_duplicate_model_number_is_normalized_per_vendor(self):
+        findings = lint_devices([_device(), _device(model_number="pm-1 "), _device(vendor_name="Other")])
+        assert [f.rule for f in findings] == ["duplicate-model-number"]
+        assert findings[0].severity == "error"
+
+
+class TestGuardrails:
+    def test_limits_are_configurable(self):
+        devices = [_device(model_number=f"PM-{i}") for i in range(3)]
+        for dev in devices:
+            dev.source, dev.source_bytes = "acme.yaml", 4096
+        config = LintConfig.from_dict({"rules": {
+            "max-devices-per-file": {"max": 2}, "max-file-size": {"max_kb": 2}, "max-registers": {"max": 0},
+        }})
+        findings = lint_devices(devices, config)
+        assert sorted(f.rule for f in findings if f.rule != "max-registers") == ["max-devices-per-file", "max-file-size"]
+        assert "split_vendor" in next(f for f in findings if f.rule == "max-devices-per-file").message
+        assert len([f for f in findings if f.rule == "max-registers"]) == 3
+        assert {f.severity for f in findings} == {"warning"}
+
+    def test_database_devices_have_no_file_limits(self):
+        config = LintConfig.from_dict({"rules": {"max-devices-per-file": {"max": 0}, "max-file-size": {"max_kb": 0}}})
+        assert lint_devices([_device()], config) == []
+
+
+class TestConfig:
+    def test_disable_rule(self):
+        config = LintConfig.from_dict({"rules": {"missing-description": False}})
+        assert lint_devices([_device(description="")], config) == []
+
+    def test_override_severity_and_options(self):
+        dev = _device()
+        dev.data["technology_config"]["register_definitions"][0]["field"]["unit"] = "GJ/h"
+        config = LintConfig.from_dict({"rules": {"unit-whitelist": {"severity": "error", "extra_units": ["GJ/h"]}}})
+        assert lint_devices([dev], config) == []
+
+        config = LintConfig.from_dict({"rules": {"unit-whitelist": {"severity": "error"}}})
+        assert [f.severity for f in lint_devices([dev], config)] == ["error"]
+
+    def test_canonical_field_rule_is_opt_in(self):
+        dev = _device()
+        register = dev.data["technology_config"]["register_definitions"][0]["field"]
+        register.update(name="power", unit="kW")
+        assert "canonical-field" not in _rules(lint_devices([dev]))
+
+        config = LintConfig.from_dict({"rules": {"canonical-field": True}})
+        [finding] = [f for f in lint_devices([dev], config) if f.rule == "canonical-field"]
+        assert "did you mean 'active_power'" in finding.message
+
+        register.update(name="active_power", unit="kWh")
+        assert "Unit 'kWh'" in lint_devices([dev], config)[0].message
+        config = LintConfig.from_dict({"rules": {"canonical-field": {"check_units": False}}})
+        assert lint_devices([dev], config) == []
+
+    def test_unit_conversion_rule_is_opt_in(self):
+        mapping = {"source": "energy_import", "target": "elec:total_energy"}
+        dev = _device(processor_config={"field_mappings": [mapping]})
+        dev.data["technology_config"]["register_definitions"][0]["field"].update(name="energy_import", unit="Wh")
+        assert lint_devices([dev]) == []
+
+        config = LintConfig.from_dict({"rules": {"unit-conversion": True}})
+        [finding] = lint_devices([dev], config)
+        assert "needs scale 0.001" in finding.message
+        dev.data["processor_config"]["field_mappings"][0]["scale"] = 0.001
+        assert lint_devices([dev], config) == []
+
+    def test_unknown_rule_rejected(self):
+        with pytest.raises(ValueError, match="no-such-rule"):
+            LintConfig.from_dict({"rules": {"no-such-rule": False}})
+
+    def test_load_from_file(self, tmp_path):
+        path = tmp_path / ".sparklint.yaml"
+        path.write_text(yaml.dump({"rules": {"field-naming": {"enabled": False}}}))
+        assert not LintConfig.load(path).is_enabled("field-naming")
+
+
+class TestPlugins:
+    PLUGIN = (
+        "from library.lint import rule\n"
+        "\n"
+        "@rule('acme-prefix', description='Acme models start with ACME-', severity='error')\n"
+        "def check(device, options):\n"
+        "    if not device.get('model_number', '').startswith('ACME-'):\n"
+        "        yield 'model_number', 'Model number must start with ACME-'\n"
+    )
+
+    @pytest.fixture(autouse=True)
+    def isolated_registry(self, monkeypatch):
+        monkeypatch.setattr(lint, "RULES", dict(lint.RULES))
+
+    def test_plugin_listed_in_config(self, tmp_path):
+        (tmp_path / "acme_rules.py").write_text(self.PLUGIN)
+        (tmp_path / ".sparklint.yaml").write_text(
+            yaml.dump({"plugins": ["acme_rules.py"], "rules": {"acme-prefix": {"severity": "warning"}}})
+        )
+        config = LintConfig.load(tmp_path / ".sparklint.yaml", load_plugins=True)
+        findings = lint_devices([_device()], config)
+        assert [(f.rule, f.severity) for f in findings] == [("acme-prefix", "warning")]
+
+    def test_config_plugins_are_not_run_unless_asked(self, tmp_path, settings):
+        (tmp_path / "acme_rules.py").write_text(self.PLUGIN + "open(__file__ + '.ran', 'w').close()\n")
+        (tmp_path / ".sparklint.yaml").write_text(
+            yaml.dump({"plugins": ["acme_rules.py"], "rules": {"acme-prefix": {"severity": "warning"}}})
+        )
+        config = LintConfig.load(tmp_path / ".sparklint.yaml")
+        assert _rules(lint_devices([_device()], config)) == set()
+        settings.SPARKLINT_CONFIG = tmp_path / ".sparklint.yaml"
+        LintConfig.load()
+        with pytest.raises(ValueError, match="named explicitly"):
+            LintConfig.load(load_plugins=True)
+        with pytest.raises(UsageError, match="--load-plugins needs --config"):
+            call_command("lint_library", "--load-plugins", stdout=io.StringIO())
+        assert not (tmp_path / "acme_rules.py.ran").exists()
+
+    def test_plugin_dir(self, tmp_path):
+        (tmp_path / "acme_rules.py").write_text(self.PLUGIN)
+        lint.load_plugin_dir(tmp_path)
+        assert _rules(lint_devices([_device()])) == {"acme-prefix"}
+
+    def test_missing_plugin_is_reported(self, tmp_path):
+        with pytest.raises(ValueError, match="not found"):
+            LintConfig.from_dict({"plugins": ["nope.py"]}, base_dir=tmp_path, load_plugins=True)
+
+
+class TestSources:
+    @pytest.fixture
+    def device(self):
+        vendor = Vendor.objects.create(name="Lint Vendor", slug="lint-vendor")
+        device = VendorModel.objects.create(
+            vendor=vendor, model_number="LV-1", name="Lint Device", device_type="power_meter", technology="modbus",
+        )
+        _modbus_config(device)
+        ProcessorConfig.objects.create(device_type=device)
+        return device
+
+    def test_database_source(self, device):
+        devices = devices_from_database(vendor_slug="lint-vendor")
+        assert [d.label for d in devices] == ["Lint Vendor LV-1"]
+        assert {"missing-description", "empty-processor-config"} <= _rules(lint_devices(devices))
+
+    def test_yaml_source_matches_database(self, tmp_path, device):
+        export_to_yaml(tmp_path / "devices")
+        devices = devices_from_yaml(tmp_path / "devices", tmp_path / "manifest.yaml")
+        labels = [d.label for d in devices]
+        assert "Lint Vendor LV-1" in labels
+        assert all(d.source.endswith(".yaml") for d in devices)
+
+    def test_unresolved_device_type_key(self, tmp_path, device):
+        devices_path = tmp_path / "devices"
+        devices_path.mkdir()
+        known, dangling = "6f1c0c1e-0000-4000-8000-000000000001", "6f1c0c1e-0000-4000-8000-00000000dead"
+        models = [
+            _device(model_number="K-1", device_type_key=known),
+            _device(model_number="K-2", device_type_key=dangling),
+        ]
+        (devices_path / "acme.yaml").write_text(yaml.safe_dump({"models": models}))
+        manifest = {"vendors": [{"name": "Acme", "file": "acme.yaml"}], "device_types": [{"key": known}]}
+        (tmp_path / "manifest.yaml").write_text(yaml.safe_dump(manifest))
+
+        findings = lint_devices(devices_from_yaml(devices_path, tmp_path / "manifest.yaml"))
+        unresolved = [f for f in findings if f.rule == "unresolved-reference"]
+        assert [(f.device, f.path, f.severity) for f in unresolved] == [("Acme K-2", "device_type_key", "error")]
+        assert "unresolved-reference" not in _rules(lint_devices(devices_from_database(vendor_slug="lint-vendor")))
+
+    def test_command_fails_on_errors(self, device):
+        VendorModel.objects.create(
+            vendor=device.vendor, model_number="lv-1", name="Dup", device_type="power_meter", technology="modbus",
+        )
+        with pytest.raises(CommandError, match="error"):
+            call_command("lint_library", "--vendor", "lint-vendor")
+
+    def test_device_statuses(self, device):
+        duplicate = VendorModel.objects.create(
+            vendor=device.vendor, model_number="lv-1", name="Dup", device_type="power_meter", technology="modbus",
+        )
+        _modbus_config(duplicate)
+        statuses = device_statuses([device, duplicate])
+        assert statuses[str(device.pk)]["status"] == "warning"
+        assert statuses[str(duplicate.pk)] == {"status": "error", "errors": 1, "warnings": 2}
+
+    def test_status_endpoint(self, device):
+        client = Client()
+        client.force_login(get_user_model().objects.create_user(username="lint-viewer", password="x"))
+        response = client.get("/models/lint-status/", {"id": [str(device.pk)]})
+        assert response.json()[str(device.pk)]["status"] == "warning"
+        assert client.get("/models/lint-status/", {"id": ["not-a-uuid"]}).status_code == 400
+
+    def test_status_endpoint_applies_the_configured_rules(self, device, tmp_path, settings):
+        client = Client()
+        client.force_login(get_user_model().objects.create_user(username="lint-viewer", password="x"))
+        settings.SPARKLINT_CONFIG = tmp_path / ".sparklint.yaml"
+        settings.SPARKLINT_CONFIG.write_text(yaml.dump({"rules": {rule: False for rule in lint.RULES}}))
+        response = client.get("/models/lint-status/", {"id": [str(device.pk)]})
+        assert response.json()[str(device.pk)]["status"] == "ok"
+
+        settings.SPARKLINT_CONFIG.write_text("rules: [missing-unit]\n")
+        response = client.get("/models/lint-status/", {"id": [str(device.pk)]})
+        assert response.status_code == 500
+        assert "'rules' must be a mapping" in response.json()["error"]
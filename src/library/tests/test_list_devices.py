@@ -0,0 +1,51 @@
+"""``list_devices`` filters and output formats."""
+
+import io
+import json
+
+import pytest
+import yaml
+from django.core.management import call_command
+
+from library.models import ControlConfig, Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def devices():
+    vendor = Vendor.objects.create(name="List Vendor", slug="list-vendor")
+    plug = VendorModel.objects.create(
+        vendor=vendor, model_number="LP-1", name="Plug", device_type="smart_plug", technology="lorawan",
+    )
+    ControlConfig.objects.create(device_type=plug, controllable=True)
+    VendorModel.objects.create(
+        vendor=vendor, model_number="LS-1", name="Sensor", device_type="environment_sensor", technology="lorawan",
+    )
+    VendorModel.objects.create(
+        vendor=vendor, model_number="LM-1", name="Meter", device_type="power_meter", technology="modbus",
+    )
+
+
+def _run(*args):
+    out = io.StringIO()
+    call_command("list_devices", "--vendor", "list-vendor", *args, stdout=out)
+    return out.getvalue()
+
+
+def test_json_with_filters(devices):
+    rows = json.loads(_run("--technology", "lorawan", "--controllable", "--format", "json"))
+    assert [r["model_number"] for r in rows] == ["LP-1"]
+    assert rows[0]["controllable"] is True
+
+
+def test_yaml_output(devices):
+    rows = yaml.safe_load(_run("--technology", "lorawan", "--format", "yaml"))
+    assert [r["model_number"] for r in rows] == ["LP-1", "LS-1"]
+
+
+def test_table_output(devices):
+    out = _run()
+    assert out.splitlines()[0].startswith("VENDOR")
+    assert "LM-1" in out
+    assert "3 devices" in out
@@ -0,0 +1,70 @@
+"""Recovery from a missing or partial manifest: import anyway, offer the rebuilt one."""
+
+import pytest
+import yaml
+from django.core.management import call_command
+
+from library.doctor import rebuild_manifest
+from library.exporters import export_to_yaml
+from library.importers import import_from_yaml
+from library.models import Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def tree(tmp_path):
+    for name, slug in (("Fork Vendor", "fork-vendor"), ("WIP Vendor", "wip-vendor")):
+        vendor = Vendor.objects.create(name=name, slug=slug)
+        VendorModel.objects.create(
+            vendor=vendor, model_number=f"{vendor.slug}-1", name="Meter", device_type="power_meter",
+            technology="modbus",
+        )
+    export_to_yaml(tmp_path / "devices")
+    Vendor.objects.all().delete()
+    return tmp_path / "devices", tmp_path / "manifest.yaml"
+
+
+def test_import_without_manifest_recovers_and_writes_on_request(tree, capsys):
+    devices, manifest_path = tree
+    manifest_path.unlink()
+    with pytest.raises(FileNotFoundError):
+        import_from_yaml(devices, manifest_path)
+
+    call_command("import_yaml", path=str(devices))
+    out = capsys.readouterr().out
+    assert "No manifest; vendor list rebuilt from devices/" in out
+    assert "--write-manifest" in out
+    assert not manifest_path.exists()
+    assert VendorModel.objects.count() == 2
+
+    call_command("import_yaml", path=str(devices), write_manifest=True)
+    manifest = yaml.safe_load(manifest_path.read_text())
+    assert [(v["name"], v["file"], v["technologies"]) for v in manifest["vendors"]] == [
+        ("Fork Vendor", "fork-vendor.yaml", ["modbus"]),
+        ("WIP Vendor", "wip-vendor.yaml", ["modbus"]),
+    ]
+    assert "recovery" not in import_from_yaml(devices, manifest_path, recover=True)
+
+
+def test_import_skips_entries_for_missing_files(tree):
+    devices, manifest_path = tree
+    (devices / "wip-vendor.yaml").unlink()
+
+    stats = import_from_yaml(devices, manifest_path, recover=True)
+    assert stats["recovery"] == ["Dropped vendor WIP Vendor: wip-vendor.yaml not found"]
+    assert list(Vendor.objects.values_list("name", flat=True)) == ["Fork Vendor"]
+    assert [v["name"] for v in stats["recovered_manifest"]["vendors"]] == ["Fork Vendor"]
+
+
+def test_verify_manifest_rebuild(tree):
+    devices, manifest_path = tree
+    manifest = yaml.safe_load(manifest_path.read_text())
+    manifest["vendors"] = manifest["vendors"][:1] + [{"name": "Gone", "file": "gone.yaml"}]
+    manifest_path.write_text(yaml.safe_dump(manifest))
+
+    call_command("verify_manifest", path=str(devices), rebuild=True)
+    rebuilt = yaml.safe_load(manifest_path.read_text())
+    assert [v["file"] for v in rebuilt["vendors"]] == ["fork-vendor.yaml", "wip-vendor.yaml"]
+    assert rebuilt["schema_version"] == manifest["schema_version"]
+    assert rebuild_manifest(devices, manifest_path) == []
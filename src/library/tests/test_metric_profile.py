@@ -0,0 +1,67 @@
+"""The structured metrics editor for a device type's profile."""
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.test import Client
+
+from library.metric_profile import ProfileError, ProfileMetric, load_profile, save_profile
+from library.models import DeviceType, DeviceTypeHistory, Metric, ProcessorConfig, Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def device_type():
+    Metric.objects.get_or_create(key="test:energy", defaults={"label": "Energy", "unit": "kWh", "aggregation": "delta"})
+    Metric.objects.get_or_create(key="test:volume", defaults={"label": "Volume", "unit": "m³"})
+    dt = DeviceType.objects.create(
+        code="profile_meter", label="Profile Meter", metrics=[{"metric": "test:energy", "tier": "primary"}],
+    )
+    vendor = Vendor.objects.create(name="Profile Vendor", slug="profile-vendor")
+    vm = VendorModel.objects.create(
+        vendor=vendor, model_number="PM-1", name="PM-1", device_type="power_meter", device_type_fk=dt,
+        technology="lorawan",
+    )
+    ProcessorConfig.objects.create(device_type=vm, field_mappings=[{"source": "e_kwh", "target": "test:energy"}])
+    return dt
+
+
+@pytest.fixture
+def client():
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(username="profile-admin", password="x", role="admin"))
+    return client
+
+
+def test_profile_rows_are_typed(device_type):
+    [entry] = load_profile(device_type)
+    assert (entry.metric, entry.tier, entry.unit) == ("test:energy", "primary", "kWh")
+    assert entry.aggregation == "Delta (last − first)"
+    assert entry.sources == ("e_kwh",)
+
+
+def test_save_profile_validates(device_type):
+    entries = load_profile(device_type)
+    with pytest.raises(ProfileError, match="already in the profile"):
+        save_profile(device_type, [*entries, ProfileMetric("test:energy")])
+    with pytest.raises(ProfileError, match="Not in the metric catalogue: test:nope"):
+        save_profile(device_type, [*entries, ProfileMetric("test:nope")])
+    with pytest.raises(ProfileError, match="tier must be one of"):
+        save_profile(device_type, [ProfileMetric("test:volume", tier="hidden")])
+
+
+def test_add_edit_delete(client, device_type):
+    url = f"/device-types/{device_type.pk}/metrics/"
+    assert client.post(url, {"metric": "test:volume", "tier": "secondary"}).status_code == 302
+    client.post(f"{url}1/edit/", {"metric": "test:volume", "tier": "diagnostic"})
+    device_type.refresh_from_db()
+    assert device_type.metrics == [
+        {"metric": "test:energy", "tier": "primary"}, {"metric": "test:volume", "tier": "diagnostic"},
+    ]
+    client.post(f"{url}0/delete/")
+    device_type.refresh_from_db()
+    assert device_type.metrics == [{"metric": "test:volume", "tier": "diagnostic"}]
+    assert DeviceTypeHistory.objects.filter(device_type=device_type).count() == 3
+
+    response = client.post(url, {"metric": "test:volume", "tier": "primary"})
+    assert "already in the profile" in response.content.decode()
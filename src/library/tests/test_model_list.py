@@ -0,0 +1,70 @@
+"""Model list filters — controllable, unpublished changes — and the active-filter chips."""
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.test import Client
+from django.urls import reverse
+
+from library.models import ControlConfig, Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def client():
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(
+        username="list-admin", password="x", is_staff=True, is_superuser=True, role="admin",
+    ))
+    return client
+
+
+@pytest.fixture
+def devices():
+    vendor = Vendor.objects.create(name="Filter Vendor", slug="filter-vendor")
+    relay = VendorModel.objects.create(
+        vendor=vendor, model_number="FR-1", name="Relay", device_type="smart_plug", technology="lorawan",
+    )
+    ControlConfig.objects.create(device_type=relay, controllable=True)
+    meter = VendorModel.objects.create(
+        vendor=vendor, model_number="FM-1", name="Meter", device_type="power_meter", technology="modbus",
+    )
+    return relay, meter
+
+
+def _listed(response):
+    return [m.model_number for m in response.context["models"]]
+
+
+def test_controllable_filter_and_sort(client, devices):
+    assert _listed(client.get("/models/", {"vendor": "filter-vendor", "controllable": "yes"})) == ["FR-1"]
+    assert _listed(client.get("/models/", {"vendor": "filter-vendor", "controllable": "no"})) == ["FM-1"]
+    response = client.get("/models/", {"vendor": "filter-vendor", "sort": "-technology"})
+    assert _listed(response) == ["FM-1", "FR-1"]
+
+
+def test_modified_only(client, devices):
+    client.post(reverse("library:version-create"))
+    added = VendorModel.objects.create(
+        vendor=devices[0].vendor, model_number="FN-1", name="New", device_type="power_meter", technology="modbus",
+    )
+    assert _listed(client.get("/models/", {"modified": "1"})) == [added.model_number]
+
+
+def test_active_filters_in_header(client, devices):
+    response = client.get("/models/", {"technology": "lorawan", "controllable": "yes", "sort": "name", "page": "1"})
+    filters = {f["label"]: f for f in response.context["active_filters"]}
+    assert {label: f["value"] for label, f in filters.items()} == {"Technology": "LoRaWAN", "Control": "Controllable"}
+    assert filters["Technology"]["remove_url"] == "?controllable=yes&sort=name"
+    assert response.content.decode().count("data-active-filter") == 2
+    assert client.get("/models/").context["active_filters"] == []
+
+
+def test_long_lists_are_paginated(client, devices):
+    Vendor.objects.bulk_create(Vendor(name=f"Paged Vendor {i:02}", slug=f"paged-vendor-{i:02}") for i in range(60))
+    response = client.get("/vendors/", {"page": 2})
+    assert len(response.context["vendors"]) == 11
+    content = response.content.decode()
+    assert "51–61 of 61" in content
+    assert 'data-page="first"' in content and 'data-page="next"' not in content
+    assert 'href="?page=1"' in content
@@ -0,0 +1,37 @@
+"""Numeric display filters used on detail pages."""
+
+from decimal import Decimal
+
+from django.test import override_settings
+
+from library.templatetags.number_format import fmt_number, fmt_plain, hex_addr
+
+
+def test_fmt_plain_avoids_scientific_notation():
+    assert fmt_plain(1e-05) == "0.00001"
+    assert fmt_plain(1.0) == "1"
+    assert fmt_plain(-0.5) == "-0.5"
+
+
+def test_fmt_number_pads_by_unit_and_groups_thousands():
+    assert fmt_number(Decimal("1234567.5"), "kWh") == "1\u202f234\u202f567.500"
+    assert fmt_number(Decimal("21"), "°C") == "21.0"
+
+
+def test_fmt_number_never_rounds_away_precision():
+    assert fmt_number(Decimal("0.05"), "°C") == "0.05"
+
+
+def test_fmt_number_unknown_unit_trims_zeros():
+    assert fmt_number(Decimal("5.000000"), "furlong") == "5"
+
+
+@override_settings(NUMBER_FORMAT={"thousands_separator": ",", "decimal_places_by_unit": {"W": 2}})
+def test_fmt_number_respects_settings():
+    assert fmt_number(12345, "W") == "12,345.00"
+
+
+def test_hex_addr():
+    assert hex_addr(10) == "0x000A"
+    assert hex_addr(40001, 6) == "0x009C41"
+    assert hex_addr("n/a") == "n/a"
@@ -0,0 +1,122 @@
+"""Changes since the published original: marked per field and register, reverted one at a time."""
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.test import Client
+
+from library.models import DeviceHistory, LoRaWANConfig, ModbusConfig, RegisterDefinition, Vendor, VendorModel
+from library.original import RevertError, device_changes, revert_field, revert_register
+from library.publish import publish_version
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def client():
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(username="revert-editor", password="x", role="editor"))
+    return client
+
+
+@pytest.fixture
+def meter():
+    vendor = Vendor.objects.create(name="Original Vendor", slug="original-vendor")
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number="OM-1", name="Meter", device_type="power_meter", technology="modbus",
+        description="Three phase meter.",
+    )
+    modbus = ModbusConfig.objects.create(device_type=device, function="holding")
+    RegisterDefinition.objects.create(modbus_config=modbus, field_name="voltage", field_unit="V", address=0,
+                                      data_type="float32")
+    RegisterDefinition.objects.create(modbus_config=modbus, field_name="current", field_unit="A", address=2,
+                                      data_type="float32")
+    publish_version(None)
+    return device
+
+
+def _edit(device):
+    device.name = "Meter (renamed)"
+    device.description = "Edited."
+    device.save()
+    registers = RegisterDefinition.objects.filter(modbus_config__device_type=device)
+    registers.filter(address=0).update(field_name="voltage_l1", scale=0.1)
+    registers.filter(address=2).delete()
+    RegisterDefinition.objects.create(modbus_config=device.modbus_config, field_name="energy", address=4,
+                                      data_type="uint32")
+
+
+def test_no_original_before_the_first_publish():
+    vendor = Vendor.objects.create(name="New Vendor", slug="new-vendor")
+    device = VendorModel.objects.create(vendor=vendor, model_number="N-1", name="New", technology="modbus")
+    assert device_changes(device) is None
+
+
+def test_changes_by_field_and_register(meter):
+    assert not device_changes(meter)
+    _edit(meter)
+    LoRaWANConfig.objects.create(device_type=meter, device_class="A")
+    changes = device_changes(meter)
+    assert changes.version == 1
+    assert sorted(changes.fields) == ["description", "lorawan_config", "name"]
+    assert changes.fields["name"].original == "Meter"
+    assert changes.fields["lorawan_config"].label == "LoRaWAN configuration"
+    assert {a: (r.change, r.fields) for a, r in changes.registers.items()} == {
+        0: ("modified", ["field_name", "scale"]),
+        2: ("removed", []),
+        4: ("added", []),
+    }
+
+
+def test_revert_one_field_keeps_the_others(meter):
+    _edit(meter)
+    revert_field(meter, "name")
+    meter.refresh_from_db()
+    assert (meter.name, meter.description) == ("Meter", "Edited.")
+    with pytest.raises(RevertError):
+        revert_field(meter, "name")
+
+
+def test_revert_registers(meter):
+    _edit(meter)
+    for address in (0, 2, 4):
+        revert_register(meter, address)
+    registers = RegisterDefinition.objects.filter(modbus_config__device_type=meter)
+    assert list(registers.values_list("address", "field_name", "scale")) == [(0, "voltage", 1.0), (2, "current", 1.0)]
+    assert device_changes(meter).fields.keys() == {"name", "description"}
+
+
+def test_revert_a_config_that_did_not_exist(meter):
+    LoRaWANConfig.objects.create(device_type=meter)
+    revert_field(meter, "lorawan_config")
+    assert not LoRaWANConfig.objects.filter(device_type=meter).exists()
+
+
+def test_revert_view_records_history_and_goes_back(client, meter):
+    _edit(meter)
+    edit_url = f"/models/{meter.pk}/edit/"
+    response = client.post(f"/models/{meter.pk}/revert/", {"field": "description", "next": edit_url})
+    assert response.url == edit_url
+    assert VendorModel.objects.get(pk=meter.pk).description == "Three phase meter."
+    assert DeviceHistory.objects.filter(device=meter, action=DeviceHistory.Action.UPDATED).count() == 1
+
+    response = client.post(f"/models/{meter.pk}/revert/", {"register": "4", "next": "https://example.com/"})
+    assert response.url == f"/models/{meter.pk}/"
+    assert not RegisterDefinition.objects.filter(modbus_config__device_type=meter, address=4).exists()
+
+
+def test_fields_without_a_column_are_not_reverted(client, meter):
+    meter.vendor = Vendor.objects.create(name="Other Vendor", slug="other-vendor")
+    meter.save()
+    assert not device_changes(meter).fields["vendor"].revertable
+    response = client.post(f"/models/{meter.pk}/revert/", {"field": "vendor"}, follow=True)
+    assert [str(m) for m in response.context["messages"]] == ["vendor has no change that can be reverted."]
+
+
+def test_pages_mark_what_changed(client, meter):
+    _edit(meter)
+    body = client.get(f"/models/{meter.pk}/edit/").content.decode()
+    assert 'data-modified="name"' in body
+    assert 'data-modified="model_number"' not in body
+    assert "Changed since v1" in client.get(f"/models/{meter.pk}/").content.decode()
+    table = client.get(f"/models/{meter.pk}/registers/").content.decode()
+    assert [table.count(f'data-register-change="{kind}"') for kind in ("modified", "added", "removed")] == [1, 1, 1]
@@ -0,0 +1,115 @@
+"""Declarative batch patches across devices (database and YAML tree)."""
+
+import io
+
+import pytest
+import yaml
+from django.core.management import call_command
+from django.core.management.base import CommandError
+
+from library.exporters import export_to_yaml
+from library.models import DeviceHistory, ModbusConfig, RegisterDefinition, Vendor, VendorModel
+from library.patching import PatchError, apply_to_device, apply_to_tree, device_matches, load_patches
+
+pytestmark = pytest.mark.django_db
+
+PATCH = {
+    "patches": [
+        {
+            "description": "kWh registers were exported in Wh",
+            "match": {"vendor": "patch-acme", "model_number": "PA-*"},
+            "registers": {"where": {"field.unit": "kWh"}, "multiply": {"scale": 0.1}},
+        },
+        {"match": {"technology": "modbus", "vendor": "patch-acme"}, "set": {"description": "Acme meter"}},
+    ]
+}
+
+
+@pytest.fixture
+def devices():
+    acme = Vendor.objects.create(name="Patch Acme", slug="patch-acme")
+    for model in ("PA-1", "PA-2"):
+        vm = VendorModel.objects.create(
+            vendor=acme, model_number=model, name=model, device_type="power_meter", technology="modbus",
+        )
+        modbus = ModbusConfig.objects.create(device_type=vm)
+        RegisterDefinition.objects.create(
+            modbus_config=modbus, field_name="energy", field_unit="kWh", address=0, data_type="uint32",
+        )
+        RegisterDefinition.objects.create(
+            modbus_config=modbus, field_name="voltage", field_unit="V", address=2, data_type="float32",
+        )
+    other = Vendor.objects.create(name="Patch Other", slug="patch-other")
+    VendorModel.objects.create(
+        vendor=other, model_number="PA-9", name="Other", device_type="power_meter", technology="modbus",
+    )
+
+
+@pytest.fixture
+def patch_file(tmp_path):
+    path = tmp_path / "patch.yaml"
+    path.write_text(yaml.dump(PATCH))
+    return path
+
+
+def test_matching_is_glob_and_case_insensitive():
+    device = {"vendor_name": "Patch Acme", "model_number": "PA-1", "technology_config": {"technology": "modbus"}}
+    assert device_matches(device, {"vendor": "Patch*", "model_number": "pa-?"})
+    assert device_matches(device, {"vendor": "patch-acme"})
+    assert not device_matches(device, {"technology": "lorawan"})
+
+
+def test_set_creates_nested_paths():
+    device = {"processor_config": {}}
+    changes = apply_to_device(device, {"set": {"processor_config.decoder_type": "acme_v2"}})
+    assert device["processor_config"]["decoder_type"] == "acme_v2"
+    assert changes == ["processor_config.decoder_type: None → 'acme_v2'"]
+
+
+def test_invalid_patch_file(tmp_path):
+    path = tmp_path / "bad.yaml"
+    path.write_text(yaml.dump({"patches": [{"match": {"colour": "red"}, "set": {"a": 1}}]}))
+    with pytest.raises(PatchError, match="colour"):
+        load_patches(path)
+
+
+def test_database_patch(devices, patch_file):
+    out = io.StringIO()
+    call_command("apply_patch", str(patch_file), stdout=out)
+    assert "2 device(s) changed" in out.getvalue()
+
+    energy = RegisterDefinition.objects.filter(modbus_config__device_type__model_number="PA-1", address=0).get()
+    assert energy.scale == pytest.approx(0.1)
+    voltage = RegisterDefinition.objects.filter(modbus_config__device_type__model_number="PA-1", address=2).get()
+    assert voltage.scale == 1.0
+    assert VendorModel.objects.get(model_number="PA-1").description == "Acme meter"
+    assert VendorModel.objects.get(model_number="PA-9").description == ""
+    assert DeviceHistory.objects.filter(action="updated").count() == 2
+
+
+def test_database_dry_run_saves_nothing(devices, patch_file):
+    out = io.StringIO()
+    call_command("apply_patch", str(patch_file), "--dry-run", stdout=out)
+    assert "+    scale: 0.1" in out.getvalue()
+    assert not VendorModel.objects.filter(description="Acme meter").exists()
+
+
+def test_tree_patch(devices, tmp_path):
+    export_to_yaml(tmp_path / "devices")
+    results = apply_to_tree(load_patches_from(PATCH, tmp_path), tmp_path / "devices", tmp_path / "manifest.yaml")
+    assert len(results) == 2
+    data = yaml.safe_load((tmp_path / "devices" / "patch-acme.yaml").read_text())
+    assert data["models"][0]["technology_config"]["register_definitions"][0]["scale"] == pytest.approx(0.1)
+
+
+def test_non_numeric_multiply_fails(devices, tmp_path):
+    path = tmp_path / "patch.yaml"
+    path.write_text(yaml.dump({"patches": [{"registers": {"multiply": {"field.name": 2}}}]}))
+    with pytest.raises(CommandError, match="not a number"):
+        call_command("apply_patch", str(path))
+
+
+def load_patches_from(doc, tmp_path):
+    path = tmp_path / "p.yaml"
+    path.write_text(yaml.dump(doc))
+    return load_patches(path)
@@ -0,0 +1,93 @@
+"""Live Problems panel of the device editor: findings attributed to fields and config pages."""
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.test import Client
+
+from library.forms import VendorModelForm
+from library.models import ModbusConfig, RegisterDefinition, Vendor, VendorModel
+from library.problems import editor_problems
+
+pytestmark = pytest.mark.django_db
+User = get_user_model()
+
+
+@pytest.fixture
+def device():
+    vendor = Vendor.objects.create(name="Problem Vendor", slug="problem-vendor")
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number="PB-1", name="Meter", device_type="power_meter", technology="modbus",
+        description="Meter",
+    )
+    modbus = ModbusConfig.objects.create(device_type=device, function="holding", byte_order="big_endian")
+    RegisterDefinition.objects.create(modbus_config=modbus, field_name="energy", address=0, data_type="uint32")
+    RegisterDefinition.objects.create(modbus_config=modbus, field_name="energy", address=2, data_type="uint16")
+    return device
+
+
+def _data(device, **overrides):
+    data = {
+        "vendor": device.vendor.pk, "model_number": device.model_number, "name": device.name,
+        "device_type": device.device_type, "technology": device.technology, "description": device.description,
+        "links": "[]",
+    }
+    data.update(overrides)
+    return data
+
+
+def test_problems_point_at_fields_and_config_pages(device):
+    form = VendorModelForm(data=_data(device, name="", description="See https://acme.example/pb-1.pdf"),
+                           instance=device)
+    form.is_valid()
+    problems = {(p.rule, p.path): p for p in editor_problems(form)}
+
+    assert problems["form", ""].field == "name"
+    assert problems["description-url", "description"].field == "description"
+    word_order = problems["technology-required", "technology_config.word_order"]
+    assert (word_order.severity, word_order.url) == ("error", f"/models/{device.pk}/modbus-config/edit/")
+    second = device.modbus_config.register_definitions.get(address=2)
+    duplicate = problems["duplicate-register-field", "technology_config.register_definitions[1]"]
+    assert duplicate.url == f"/registers/{second.pk}/edit/"
+    assert not any(p.rule == "link-unreachable" for p in problems.values())
+
+
+def test_endpoint_lints_without_saving(device):
+    client = Client()
+    client.force_login(User.objects.create_user(username="problem-editor", password="x", role="editor"))
+
+    response = client.post(f"/models/{device.pk}/problems/", _data(device, name="Renamed"))
+    assert response.status_code == 200
+    body = response.json()
+    assert (body["errors"], body["warnings"]) == (2, 1)
+    empty = next(p for p in body["problems"] if p["rule"] == "empty-processor-config")
+    assert empty["url"] == f"/models/{device.pk}/processor-config/edit/"
+    assert VendorModel.objects.get(pk=device.pk).name == "Meter"
+
+    # A new device is linted in the shape it would be created with.
+    response = client.post("/models/problems/", _data(device, model_number="PB-2", technology="wmbus"))
+    paths = {p["path"] for p in response.json()["problems"]}
+    assert {"technology_config.manufacturer_code", "technology_config.wmbus_device_type"} <= paths
+    assert not VendorModel.objects.filter(model_number="PB-2").exists()
+
+
+def test_endpoint_applies_the_configured_rules(device, tmp_path, settings):
+    client = Client()
+    client.force_login(User.objects.create_user(username="problem-editor", password="x", role="editor"))
+    settings.SPARKLINT_CONFIG = tmp_path / ".sparklint.yaml"
+    settings.SPARKLINT_CONFIG.write_text("rules:\n  duplicate-register-field: false\n")
+
+    response = client.post(f"/models/{device.pk}/problems/", _data(device))
+    assert "duplicate-register-field" not in {p["rule"] for p in response.json()["problems"]}
+
+    settings.SPARKLINT_CONFIG.write_text("rules: [duplicate-register-field]\n")
+    response = client.post(f"/models/{device.pk}/problems/", _data(device))
+    assert response.status_code == 500
+    assert "'rules' must be a mapping" in response.json()["error"]
+
+
+def test_editor_renders_the_panel(device):
+    client = Client()
+    client.force_login(User.objects.create_user(username="problem-viewer", password="x", role="editor"))
+    content = client.get(f"/models/{device.pk}/edit/").content.decode()
+    assert f'data-url="/models/{device.pk}/problems/"' in content
+    assert 'data-field="description"' in content
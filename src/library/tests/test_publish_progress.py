@@ -0,0 +1,75 @@
+"""Publishing as streamed progress: steps, cancellation and failure roll back."""
+
+import json
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.test import Client
+
+from library import publish
+from library.models import LibraryVersion, Vendor, VendorModel
+from library.publish import publish_steps, publish_version
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def admin():
+    return get_user_model().objects.create_user(username="publisher", password="x", role="admin")
+
+
+@pytest.fixture
+def models():
+    vendor = Vendor.objects.create(name="Progress Vendor", slug="progress-vendor")
+    return [
+        VendorModel.objects.create(
+            vendor=vendor, model_number=f"PV-{n}", name=f"Meter {n}", device_type="power_meter", technology="modbus",
+        )
+        for n in range(3)
+    ]
+
+
+def test_steps_count_models_then_metrics_and_device_types(admin, models):
+    steps = list(publish_steps(admin))
+    model_steps = [s for s in steps if s.message.startswith("Model ")]
+    assert [(s.done, s.total) for s in model_steps] == [(1, 3), (2, 3), (3, 3)]
+    assert any(s.message.startswith("Metric ") for s in steps)
+    assert steps[-1].version == LibraryVersion.objects.get(is_current=True)
+    assert steps[-1].message == f"Library version v{steps[-1].version.version} created."
+
+
+def test_stopping_half_way_publishes_nothing(admin, models):
+    first = publish_version(admin)
+    steps = publish_steps(admin)
+    for step in steps:
+        if step.done == 2:
+            break
+    steps.close()  # the browser cancelled
+    assert list(LibraryVersion.objects.values_list("version", "is_current")) == [(first.version, True)]
+
+
+def test_failure_half_way_publishes_nothing(admin, models, monkeypatch):
+    def broken(*args, **kwargs):
+        raise RuntimeError("disk full")
+        yield  # pragma: no cover
+
+    monkeypatch.setattr(publish, "_publish_entities", broken)
+    client = Client()
+    client.force_login(admin)
+    response = client.post("/versions/create/", HTTP_ACCEPT="application/x-ndjson")
+    events = [json.loads(line) for line in b"".join(response.streaming_content).decode().splitlines()]
+    assert events[-1] == {"error": "Publishing failed, nothing was published: disk full"}
+    assert not LibraryVersion.objects.exists()
+
+
+def test_streamed_publish(admin, models):
+    client = Client()
+    client.force_login(admin)
+    response = client.post("/versions/create/", HTTP_ACCEPT="application/x-ndjson")
+    assert response["Content-Type"] == "application/x-ndjson"
+    events = [json.loads(line) for line in b"".join(response.streaming_content).decode().splitlines()]
+    version = LibraryVersion.objects.get(is_current=True)
+    assert {"message": "Model 3/3: " + str(models[2]), "done": 3, "total": 3} in events
+    assert events[-1] == {
+        "done": True, "message": f"Library version v{version.version} created.", "url": f"/versions/{version.pk}/",
+    }
@@ -0,0 +1,104 @@
+"""jq-style library queries: the expression engine and the command."""
+
+import json
+
+import pytest
+from django.core.management import call_command
+from django.core.management.base import CommandError
+
+from library.exporters import export_to_yaml
+from library.models import Vendor, VendorModel, WMBusConfig
+from library.query import QueryError, library_document, run_query
+
+pytestmark = pytest.mark.django_db
+
+DOC = {
+    "models": [
+        {"vendor_name": "Kamstrup", "model_number": "M21", "technology_config": {"technology": "wmbus"}},
+        {
+            "vendor_name": "Acme",
+            "model_number": "PM1",
+            "technology_config": {"technology": "modbus", "register_definitions": [{"address": 1}, {"address": 2}]},
+        },
+    ]
+}
+
+
+@pytest.mark.parametrize(
+    ("expression", "expected"),
+    [
+        ('.models[] | select(.technology_config.technology == "wmbus") | .model_number', ["M21"]),
+        ('[.models[] | select(.vendor_name != "Acme")] | length', [1]),
+        (".models[0].model_number, .models[-1][\"vendor_name\"]", ["M21", "Acme"]),
+        (".models | map(.vendor_name) | sort", [["Acme", "Kamstrup"]]),
+        ('.models[] | select(.model_number | test("^P")) | .vendor_name | ascii_downcase', ["acme"]),
+        (".models[] | .technology_config.register_definitions // [] | length", [0, 2]),
+        (".models[1] | {model_number, count: (.technology_config.register_definitions | length)}",
+         [{"model_number": "PM1", "count": 2}]),
+        (".models[].missing.deeper", [None, None]),
+        ('.models[] | select((.vendor_name | startswith("K")) | not) | .model_number', ["PM1"]),
+        ('.models[0] | has("vendor_name") and (.model_number > "A")', [True]),
+    ],
+)
+def test_expressions(expression, expected):
+    assert run_query(expression, DOC) == expected
+
+
+@pytest.mark.parametrize(
+    ("expression", "message"),
+    [
+        (".models[", "end of expression"),
+        ("nope", "Unknown function"),
+        (".models[] | .model_number.x", "Cannot index string"),
+        ('"a" | .[]', "Cannot iterate"),
+    ],
+)
+def test_errors(expression, message):
+    with pytest.raises(QueryError, match=message):
+        run_query(expression, DOC)
+
+
+def test_optional_suppresses_errors():
+    assert run_query(".models[] | .model_number.x?", DOC) == []
+
+
+@pytest.fixture
+def library():
+    vendor = Vendor.objects.create(name="Query Vendor", slug="query-vendor")
+    wmbus = VendorModel.objects.create(
+        vendor=vendor, model_number="QW-1", name="QW-1", device_type="water_meter", technology="wmbus",
+    )
+    WMBusConfig.objects.create(device_type=wmbus, manufacturer_code="QVV")
+    VendorModel.objects.create(
+        vendor=vendor, model_number="QM-1", name="QM-1", device_type="power_meter", technology="modbus",
+    )
+
+
+def test_database_and_tree_documents_agree(library, tmp_path):
+    export_to_yaml(tmp_path / "devices")
+    expression = ".models[] | [.vendor_name, .model_number]"
+    from_db = run_query(expression, library_document())
+    from_tree = run_query(expression, library_document(tmp_path / "devices", tmp_path / "manifest.yaml"))
+    assert sorted(from_db) == sorted(from_tree) == [["Query Vendor", "QM-1"], ["Query Vendor", "QW-1"]]
+
+
+def test_device_types_alias(library, capsys):
+    call_command(
+        "query_library", '.device_types[] | select(.technology_config.technology=="wmbus") | .model_number', "-r",
+    )
+    assert capsys.readouterr().out.split() == ["QW-1"]
+
+
+def test_command_raw_output(library, capsys):
+    call_command("query_library", '.models[] | select(.technology_config.technology == "wmbus") | .model_number', "-r")
+    assert capsys.readouterr().out.split() == ["QW-1"]
+
+
+def test_command_compact_json(library, capsys):
+    call_command("query_library", "[.models[].model_number] | sort", "--compact")
+    assert json.loads(capsys.readouterr().out) == ["QM-1", "QW-1"]
+
+
+def test_command_invalid_query():
+    with pytest.raises(CommandError, match="Invalid query"):
+        call_command("query_library", ".models[")
@@ -0,0 +1,69 @@
+"""The "/" fuzzy finder: vendors, devices and register fields, best matches first; and the "g m" model jump."""
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.test import Client
+
+from library.models import ModbusConfig, RegisterDefinition, Vendor, VendorModel
+from library.search import fuzzy_score, jump_candidates, quick_search
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def meter():
+    vendor = Vendor.objects.create(name="Schneider Electric", slug="schneider-quick")
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number="PM5110", name="PowerLogic PM5110", device_type="power_meter", technology="modbus",
+    )
+    modbus = ModbusConfig.objects.create(device_type=device)
+    RegisterDefinition.objects.create(modbus_config=modbus, field_name="reactive_power", address=3, data_type="float32")
+    return device
+
+
+def test_fuzzy_score_prefers_contiguous_word_starts():
+    assert fuzzy_score("rctpwr", "reactive_power") is not None
+    assert fuzzy_score("pwx", "reactive_power") is None
+    assert fuzzy_score("power", "reactive_power") > fuzzy_score("rctpwr", "reactive_power")
+    assert fuzzy_score("pm", "PM5110") > fuzzy_score("pm", "Schneider PM5110")
+
+
+def test_quick_search_across_kinds(meter):
+    [device] = quick_search("schn pm51")
+    assert (device.kind, device.label, device.url) == ("device", "Schneider Electric PM5110", f"/models/{meter.pk}/")
+
+    [register] = quick_search("reactpow")
+    register_pk = meter.modbus_config.register_definitions.get().pk
+    assert register.kind == "register"
+    assert register.detail == "Register 3 · Schneider Electric PM5110"
+    assert register.url == f"/registers/{register_pk}/edit/"
+
+    assert [m.kind for m in quick_search("schneider")] == ["vendor", "device"]
+    assert quick_search("  ") == []
+
+
+def test_endpoint(meter):
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(username="quick-viewer", password="x"))
+    results = client.get("/search/", {"q": "pm5110"}).json()["results"]
+    assert [r["kind"] for r in results] == ["device"]
+    assert 'id="quickSearch"' in client.get("/models/").content.decode()
+
+
+def test_jump_prefers_exact_model_number(meter):
+    other = Vendor.objects.create(name="Other Vendor", slug="other-quick")
+    VendorModel.objects.create(
+        vendor=other, model_number="PM5110-X", name="Clone", device_type="power_meter", technology="modbus",
+    )
+    assert jump_candidates("pm5110") == [meter]
+    assert len(jump_candidates("pm51")) == 2
+    assert jump_candidates("clone")[0].vendor == other
+    assert jump_candidates(" ") == []
+
+
+def test_jump_endpoint(meter):
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(username="jump-viewer", password="x"))
+    assert client.get("/jump/", {"q": "PowerLogic"}).url == f"/models/{meter.pk}/"
+    assert client.get("/jump/", {"q": "nothing"}).url == "/models/?q=nothing"
+    assert client.get("/jump/").url == "/models/"
@@ -0,0 +1,77 @@
+"""Copying registers from one Modbus device and pasting them into another."""
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.test import Client
+
+from library.models import ModbusConfig, RegisterDefinition, Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+
+def _device(vendor, model_number, registers=()):
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number=model_number, name=model_number, device_type="power_meter", technology="modbus",
+    )
+    modbus = ModbusConfig.objects.create(device_type=device, function="holding")
+    for field_name, address, data_type in registers:
+        RegisterDefinition.objects.create(
+            modbus_config=modbus, field_name=field_name, address=address, data_type=data_type, field_unit="V",
+        )
+    return device
+
+
+@pytest.fixture
+def devices():
+    vendor = Vendor.objects.create(name="Clip Vendor", slug="clip-vendor")
+    source = _device(vendor, "CL-1", [
+        ("voltage_l1", 100, "float32"), ("voltage_l2", 102, "float32"), ("energy", 200, "uint32"),
+    ])
+    target = _device(vendor, "CL-2", [("voltage_l1", 0, "float32")])
+    return source, target
+
+
+@pytest.fixture
+def client():
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(username="clip-editor", password="x", role="editor"))
+    return client
+
+
+def _pks(device, *names):
+    return [str(pk) for pk in RegisterDefinition.objects.filter(
+        modbus_config__device_type=device, field_name__in=names,
+    ).values_list("pk", flat=True)]
+
+
+def test_copy_and_paste_with_offset(client, devices):
+    source, target = devices
+    client.post(f"/models/{source.pk}/registers/copy/", {"register": _pks(source, "voltage_l2", "energy")})
+    page = client.get(f"/models/{target.pk}/")
+    assert page.context["register_clipboard"].base == 102
+    assert f"/models/{target.pk}/registers/paste/" in page.content.decode()
+
+    response = client.post(f"/models/{target.pk}/registers/paste/", {"offset": "-100"})
+    assert response.status_code == 302
+    pasted = RegisterDefinition.objects.filter(modbus_config__device_type=target).exclude(field_name="voltage_l1")
+    assert list(pasted.values_list("field_name", "address", "data_type", "field_unit")) == [
+        ("voltage_l2", 2, "float32", "V"), ("energy", 100, "uint32", "V"),
+    ]
+
+    client.post("/undo/")
+    assert RegisterDefinition.objects.filter(modbus_config__device_type=target).count() == 1
+
+
+def test_paste_refuses_clashes(client, devices):
+    source, target = devices
+    client.post(f"/models/{source.pk}/registers/copy/", {"register": _pks(source, "voltage_l1", "energy")})
+    response = client.post(f"/models/{target.pk}/registers/paste/", {"offset": "0"})
+    assert "field voltage_l1 already exists" in response.content.decode()
+    assert RegisterDefinition.objects.filter(modbus_config__device_type=target).count() == 1
+
+
+def test_empty_clipboard(client, devices):
+    source, target = devices
+    assert "clipboard is empty" in client.get(f"/models/{target.pk}/registers/paste/").content.decode()
+    response = client.post(f"/models/{source.pk}/registers/copy/", follow=True)
+    assert "No registers selected" in response.content.decode()
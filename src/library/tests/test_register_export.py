@@ -0,0 +1,59 @@
+"""Register map CSV export — command and detail-page download."""
+
+import csv
+import io
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.core.management import call_command
+from django.core.management.base import CommandError
+from django.test import Client
+
+from library.models import ModbusConfig, RegisterDefinition, Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+User = get_user_model()
+
+
+@pytest.fixture
+def modbus_device():
+    vendor = Vendor.objects.create(name="CSV Vendor", slug="csv-vendor")
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number="CV-1", name="CSV Meter", device_type="power_meter", technology="modbus",
+    )
+    modbus = ModbusConfig.objects.create(device_type=device)
+    RegisterDefinition.objects.create(
+        modbus_config=modbus, field_name="voltage_l1", field_unit="V", address=10, data_type="float32",
+    )
+    RegisterDefinition.objects.create(
+        modbus_config=modbus, field_name="energy_total", field_unit="kWh", address=0, data_type="uint32", scale=0.01,
+    )
+    return device
+
+
+def test_command_writes_rows_in_address_order(modbus_device):
+    out = io.StringIO()
+    call_command("export_registers", "--vendor", "csv-vendor", "--model", "CV-1", stdout=out)
+
+    rows = list(csv.reader(io.StringIO(out.getvalue())))
+    assert rows[0] == ["address", "data_type", "scale", "offset", "field_name", "unit", "description"]
+    assert [r[4] for r in rows[1:]] == ["energy_total", "voltage_l1"]
+    assert rows[1][2] == "0.01"
+
+
+def test_command_rejects_unknown_device():
+    with pytest.raises(CommandError, match="not found"):
+        call_command("export_registers", "--vendor", "nope", "--model", "X")
+
+
+def test_detail_download(modbus_device):
+    user = User.objects.create_user(username="csv-viewer", password="x", role="viewer")
+    client = Client()
+    client.force_login(user)
+
+    response = client.get(f"/models/{modbus_device.pk}/registers/export/")
+    assert response.status_code == 200
+    assert response["Content-Type"].startswith("text/csv")
+    assert "csv-vendor-CV-1-registers.csv" in response["Content-Disposition"]
+    body = response.content.decode("utf-8-sig")
+    assert "voltage_l1,V" in body
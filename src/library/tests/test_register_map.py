@@ -0,0 +1,74 @@
+"""Register map analysis: overlaps by data type width, repeated field names, gaps."""
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.test import Client
+
+from library.lint import LintConfig, LintDevice, lint_devices
+from library.models import ModbusConfig, RegisterDefinition, Vendor, VendorModel
+from library.register_map import analyze_registers
+
+pytestmark = pytest.mark.django_db
+
+
+def _reg(name, address, data_type="uint16"):
+    return {"field": {"name": name, "unit": "V"}, "address": address, "data_type": data_type}
+
+
+REGISTERS = [
+    _reg("energy", 100, "float32"),
+    _reg("voltage", 101),  # inside energy's second word
+    _reg("current", 103),  # 102 unmapped
+    _reg("energy", 104, "uint32"),
+    _reg("frequency", 200),  # a separate block, not a gap
+]
+
+
+def test_analyze_registers():
+    assert [(i.kind, i.index, i.message) for i in analyze_registers(REGISTERS)] == [
+        ("overlap", 1, "voltage (uint16 at 101) overlaps energy (float32 at 100–101)"),
+        ("gap", 2, "1 unmapped register between energy (ends at 101) and current — a data type too narrow, "
+                   "or a missing field?"),
+        ("duplicate-field", 3, "Field name 'energy' is also used at address 100"),
+    ]
+    assert analyze_registers([_reg("a", 0, "int64"), _reg("b", 4), _reg("c", 5, "int32"), _reg("d", 7)]) == []
+    # Same address is duplicate-register-address's finding, not an overlap.
+    assert analyze_registers([_reg("a", 10), _reg("b", 10)]) == []
+
+
+def test_lint_rules():
+    device = {
+        "vendor_name": "Acme", "model_number": "X", "description": "x",
+        "technology_config": {
+            "technology": "modbus", "function": "holding", "byte_order": "big_endian", "word_order": "high_first",
+            "register_definitions": REGISTERS,
+        },
+        "processor_config": {"field_mappings": [{"source": "energy", "target": "elec:total_energy"}]},
+    }
+    findings = lint_devices([LintDevice(data=device)])
+    assert sorted((f.rule, f.severity, f.path) for f in findings) == [
+        ("duplicate-register-field", "error", "technology_config.register_definitions[3]"),
+        ("register-gap", "warning", "technology_config.register_definitions[2]"),
+        ("register-overlap", "error", "technology_config.register_definitions[1]"),
+    ]
+    config = LintConfig.from_dict({"rules": {"register-gap": {"max_gap": 0}}})
+    assert "register-gap" not in {f.rule for f in lint_devices([LintDevice(data=device)], config)}
+
+
+def test_device_page_marks_rows():
+    vendor = Vendor.objects.create(name="Map Vendor", slug="map-vendor")
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number="MV-1", name="Meter", device_type="power_meter", technology="modbus",
+    )
+    modbus = ModbusConfig.objects.create(device_type=device, function="holding")
+    for reg in REGISTERS:
+        RegisterDefinition.objects.create(
+            modbus_config=modbus, field_name=reg["field"]["name"], address=reg["address"], data_type=reg["data_type"],
+        )
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(username="map-viewer", password="x"))
+
+    response = client.get(f"/models/{device.pk}/")
+    rows = {reg.address: [issue.kind for issue in reg.issues] for reg in response.context["registers"]}
+    assert rows == {100: [], 101: ["overlap"], 103: ["gap"], 104: ["duplicate-field"], 200: []}
+    assert "3 register map issues" in response.content.decode()
@@ -0,0 +1,233 @@
+"""Register CSV import: parsing, per-row reconciliation and the review view."""
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.core.files.uploadedfile import SimpleUploadedFile
+from django.test import Client
+
+from library.models import DeviceHistory, ModbusConfig, RegisterDefinition, Vendor, VendorModel
+from library.lint import LintDevice, lint_devices
+from library.register_merge import apply_plan, find_duplicates, parse_register_csv, reconcile, resolve_duplicates
+
+pytestmark = pytest.mark.django_db
+User = get_user_model()
+
+HEADER = "address,data_type,scale,offset,field_name,unit\n"
+
+
+@pytest.fixture
+def modbus_device():
+    vendor = Vendor.objects.create(name="Merge Vendor", slug="merge-vendor")
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number="MV-1", name="Merge Meter", device_type="power_meter", technology="modbus",
+    )
+    modbus = ModbusConfig.objects.create(device_type=device)
+    RegisterDefinition.objects.create(
+        modbus_config=modbus, field_name="energy_total", field_unit="kWh", address=0, data_type="uint32",
+    )
+    RegisterDefinition.objects.create(
+        modbus_config=modbus, field_name="voltage_l1", field_unit="V", address=10, data_type="float32",
+    )
+    RegisterDefinition.objects.create(
+        modbus_config=modbus, field_name="current_l1", field_unit="A", address=20, data_type="float32",
+    )
+    return device
+
+
+CSV = HEADER + (
+    "0,uint32,1.0,0.0,energy_total,kWh\n"
+    "10,float32,0.1,0.0,voltage_l1,V\n"
+    "30,int16,1,0,power_factor,\n"
+)
+
+
+class TestParse:
+    def test_parses_export_layout_with_bom(self):
+        rows = parse_register_csv("\ufeff" + CSV)
+        assert [r["address"] for r in rows] == [0, 10, 30]
+        assert rows[1]["scale"] == 0.1
+        assert rows[2]["field_unit"] == ""
+
+    def test_reports_line_of_bad_row(self):
+        with pytest.raises(ValueError, match="Line 3: unknown data type"):
+            parse_register_csv(HEADER + "0,uint32,1,0,a,\n1,bogus,1,0,b,\n")
+
+    def test_rejects_duplicate_address(self):
+        with pytest.raises(ValueError, match="duplicate address 0"):
+            parse_register_csv(HEADER + "0,uint32,1,0,a,\n0,uint16,1,0,b,\n")
+
+    def test_missing_columns(self):
+        with pytest.raises(ValueError, match="missing column"):
+            parse_register_csv("address,name\n0,x\n")
+
+
+class TestReconcile:
+    def test_classifies_rows(self, modbus_device):
+        rows = reconcile(modbus_device.modbus_config.register_definitions.all(), parse_register_csv(CSV))
+        assert [(r.address, r.status) for r in rows] == [
+            (0, "unchanged"), (10, "changed"), (20, "removed"), (30, "new"),
+        ]
+        assert rows[1].changed_fields == ["scale"]
+        assert [r.default_accept for r in rows] == [False, True, False, True]
+
+    def test_description_column_is_optional(self, modbus_device):
+        modbus = modbus_device.modbus_config
+        modbus.register_definitions.filter(address=0).update(field_description="Import energy, all tariffs")
+
+        # Older CSVs without the column leave descriptions alone.
+        rows = reconcile(modbus.register_definitions.all(), parse_register_csv(CSV))
+        assert rows[0].status == "unchanged"
+
+        csv_text = "address,data_type,scale,offset,field_name,unit,description\n0,uint32,1.0,0.0,energy_total,kWh,\n"
+        rows = reconcile(modbus.register_definitions.all(), parse_register_csv(csv_text))
+        assert rows[0].changed_fields == ["field_description"]
+        apply_plan(modbus, rows, accepted={0})
+        assert modbus.register_definitions.get(address=0).field_description == ""
+
+    def test_apply_only_accepted_rows(self, modbus_device):
+        modbus = modbus_device.modbus_config
+        rows = reconcile(modbus.register_definitions.all(), parse_register_csv(CSV))
+        counts = apply_plan(modbus, rows, accepted={10, 20})
+        assert counts == {"created": 0, "updated": 1, "deleted": 1}
+        assert list(modbus.register_definitions.values_list("address", flat=True)) == [0, 10]
+        assert modbus.register_definitions.get(address=10).scale == 0.1
+
+
+class TestView:
+    @pytest.fixture
+    def client(self):
+        user = User.objects.create_user(username="merge-editor", password="x", role="editor")
+        client = Client()
+        client.force_login(user)
+        return client
+
+    def test_upload_review_apply(self, client, modbus_device):
+        url = f"/models/{modbus_device.pk}/registers/import/"
+        upload = SimpleUploadedFile("regs.csv", CSV.encode("utf-8"), content_type="text/csv")
+        response = client.post(url, {"csv_file": upload})
+        assert response.status_code == 200
+        assert [r.status for r in response.context["rows"]] == ["unchanged", "changed", "removed", "new"]
+
+        response = client.post(url, {"apply": "1", "accept": ["10", "30"]})
+        assert response.status_code == 302
+        addresses = RegisterDefinition.objects.filter(modbus_config__device_type=modbus_device)
+        assert sorted(addresses.values_list("address", flat=True)) == [0, 10, 20, 30]
+        assert DeviceHistory.objects.filter(device=modbus_device, action="updated").exists()
+
+    def test_invalid_csv_shows_error(self, client, modbus_device):
+        upload = SimpleUploadedFile("regs.csv", b"address,name\n0,x\n", content_type="text/csv")
+        response = client.post(f"/models/{modbus_device.pk}/registers/import/", {"csv_file": upload})
+        assert response.status_code == 200
+        assert "missing column" in response.content.decode()
+
+    def test_bad_accept_is_a_bad_request(self, client, modbus_device):
+        url = f"/models/{modbus_device.pk}/registers/import/"
+        upload = SimpleUploadedFile("regs.csv", CSV.encode("utf-8"), content_type="text/csv")
+        client.post(url, {"csv_file": upload})
+        response = client.post(url, {"apply": "1", "accept": ["10", "ten"]})
+        assert response.status_code == 400
+        assert modbus_device.modbus_config.register_definitions.get(address=10).scale == 1.0
+
+    def test_config_created_only_when_applied(self, client):
+        vendor = Vendor.objects.create(name="Bare Vendor", slug="bare-vendor")
+        device = VendorModel.objects.create(
+            vendor=vendor, model_number="BV-1", name="Bare Meter", device_type="power_meter", technology="modbus",
+        )
+        url = f"/models/{device.pk}/registers/import/"
+        upload = SimpleUploadedFile("regs.csv", CSV.encode("utf-8"), content_type="text/csv")
+        response = client.post(url, {"csv_file": upload})
+        assert [r.status for r in response.context["rows"]] == ["new", "new", "new"]
+        assert not ModbusConfig.objects.filter(device_type=device).exists()
+
+        client.post(url, {"apply": "1", "accept": ["0"]})
+        assert list(RegisterDefinition.objects.filter(modbus_config__device_type=device).values_list(
+            "address", flat=True)) == [0]
+        history = DeviceHistory.objects.get(device=device, action="updated")
+        assert "registers" in history.changes
+
+    def test_refused_for_other_technologies(self, client):
+        vendor = Vendor.objects.create(name="Radio Vendor", slug="radio-vendor")
+        device = VendorModel.objects.create(
+            vendor=vendor, model_number="RV-1", name="Radio Meter", device_type="power_meter", technology="lorawan",
+        )
+        url = f"/models/{device.pk}/registers/import/"
+        upload = SimpleUploadedFile("regs.csv", CSV.encode("utf-8"), content_type="text/csv")
+        response = client.post(url, {"csv_file": upload})
+        assert response.status_code == 302
+        assert not ModbusConfig.objects.filter(device_type=device).exists()
+        assert client.get(url).status_code == 302
+
+    def test_viewer_forbidden(self, modbus_device):
+        user = User.objects.create_user(username="merge-viewer", password="x", role="viewer")
+        client = Client()
+        client.force_login(user)
+        assert client.get(f"/models/{modbus_device.pk}/registers/import/").status_code == 403
+
+
+class TestDuplicates:
+    @pytest.fixture
+    def duplicated(self, modbus_device):
+        # Both sides of a merge conflict survived: same address, different metadata.
+        RegisterDefinition.objects.create(
+            modbus_config=modbus_device.modbus_config, field_name="voltage_l1_v2", field_unit="",
+            address=10, data_type="float32", display_name="Voltage L1",
+        )
+        return modbus_device
+
+    def _registers(self, device):
+        return RegisterDefinition.objects.filter(modbus_config__device_type=device)
+
+    def test_find_groups_by_address(self, duplicated):
+        groups = find_duplicates(self._registers(duplicated))
+        assert [g.address for g in groups] == [10]
+        assert groups[0].differing_fields == ["field_name", "field_unit", "display_name"]
+        assert groups[0].merged["field_name"] == "voltage_l1"
+        assert groups[0].merged["field_unit"] == "V"
+        assert groups[0].merged["display_name"] == "Voltage L1"
+
+    def test_merge_and_keep(self, duplicated):
+        group = find_duplicates(self._registers(duplicated))[0]
+        survivor = resolve_duplicates(group)
+        assert (survivor.field_name, survivor.field_unit, survivor.display_name) == ("voltage_l1", "V", "Voltage L1")
+        assert self._registers(duplicated).filter(address=10).count() == 1
+
+    def test_keep_one(self, duplicated):
+        group = find_duplicates(self._registers(duplicated))[0]
+        resolve_duplicates(group, keep=group.registers[1])
+        assert self._registers(duplicated).get(address=10).field_name == "voltage_l1_v2"
+
+    def test_lint_flags_duplicate_address(self):
+        device = {
+            "vendor_name": "Acme", "model_number": "X", "description": "x",
+            "technology_config": {
+                "technology": "modbus", "function": "holding", "byte_order": "big_endian", "word_order": "high_first",
+                "register_definitions": [
+                    {"field": {"name": "a", "unit": "V"}, "address": 10, "data_type": "uint16"},
+                    {"field": {"name": "b", "unit": "V"}, "address": 10, "data_type": "uint16"},
+                ],
+            },
+            "processor_config": {"field_mappings": [{"source": "a", "target": "voltage:l1"}]},
+        }
+        findings = lint_devices([LintDevice(data=device)])
+        assert [(f.rule, f.path) for f in findings] == [
+            ("duplicate-register-address", "technology_config.register_definitions[1].address"),
+        ]
+
+    def test_view_resolves_and_form_refuses_new_duplicate(self, duplicated):
+        user = User.objects.create_user(username="dup-editor", password="x", role="editor")
+        client = Client()
+        client.force_login(user)
+        detail = client.get(f"/models/{duplicated.pk}/")
+        assert len(detail.context["register_duplicates"]) == 1
+
+        url = f"/models/{duplicated.pk}/registers/duplicates/"
+        assert client.get(url).status_code == 200
+        assert client.post(url, {"resolve-10": "merge"}).status_code == 302
+        assert find_duplicates(self._registers(duplicated)) == []
+        assert DeviceHistory.objects.filter(device=duplicated, action="updated").exists()
+
+        response = client.post(f"/models/{duplicated.pk}/registers/create/", {
+            "field_name": "again", "address": 20, "data_type": "uint16", "scale": 1, "offset": 0,
+        })
+        assert response.status_code == 200
+        assert "already used by current_l1" in response.content.decode()
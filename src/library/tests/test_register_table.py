@@ -0,0 +1,78 @@
+"""The register table: all registers with their function code, edited one cell at a time."""
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.test import Client
+
+from library.models import DeviceHistory, ModbusConfig, RegisterDefinition, Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def registers():
+    vendor = Vendor.objects.create(name="Table Vendor", slug="table-vendor")
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number="TB-1", name="Meter", device_type="power_meter", technology="modbus",
+    )
+    modbus = ModbusConfig.objects.create(device_type=device, function="input")
+    return [
+        RegisterDefinition.objects.create(modbus_config=modbus, field_name="energy", address=0, data_type="uint32"),
+        RegisterDefinition.objects.create(modbus_config=modbus, field_name="power", address=2, data_type="int32"),
+    ]
+
+
+@pytest.fixture
+def client():
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(username="table-editor", password="x", role="editor"))
+    return client
+
+
+def test_table_lists_registers_with_function_code(client, registers):
+    device = registers[0].modbus_config.device_type
+    response = client.get(f"/models/{device.pk}/registers/")
+    assert response.context["function_code"] == 4
+    assert list(response.context["registers"]) == registers
+    assert response.content.decode().count('data-field="field_name"') == 2
+
+
+def test_cell_edit(client, registers):
+    energy = registers[0]
+    response = client.post(f"/registers/{energy.pk}/cell/", {"field": "scale", "value": "0.01"})
+    assert response.json()["value"] == 0.01
+    energy.refresh_from_db()
+    assert (energy.scale, energy.field_name, energy.data_type) == (0.01, "energy", "uint32")
+    assert DeviceHistory.objects.filter(device=energy.modbus_config.device_type).count() == 1
+
+    client.post("/undo/")
+    energy.refresh_from_db()
+    assert energy.scale == 1.0
+
+
+def test_cell_edit_is_validated(client, registers):
+    energy = registers[0]
+    response = client.post(f"/registers/{energy.pk}/cell/", {"field": "address", "value": "2"})
+    assert response.status_code == 400
+    assert "already used by power" in response.json()["error"]
+    assert client.post(f"/registers/{energy.pk}/cell/", {"field": "display_icon", "value": "x"}).status_code == 400
+    energy.refresh_from_db()
+    assert energy.address == 0
+
+
+def test_address_takes_hex_and_refuses_non_numbers(client, registers):
+    power = registers[1]
+    response = client.post(f"/registers/{power.pk}/cell/", {"field": "address", "value": "0x1A"})
+    assert response.json()["value"] == 26
+    for value, error in (("4a", "hex like 0x1A"), ("0xZZ", "hex like 0x1A"), ("-2", "greater than or equal to 0")):
+        response = client.post(f"/registers/{power.pk}/cell/", {"field": "address", "value": value})
+        assert response.status_code == 400
+        assert error in response.json()["error"]
+    power.refresh_from_db()
+    assert power.address == 26
+
+
+def test_register_form_address_is_a_text_input(client, registers):
+    device = registers[0].modbus_config.device_type
+    body = client.get(f"/models/{device.pk}/registers/create/").content.decode()
+    assert 'name="address"' in body and 'inputmode="numeric"' in body
@@ -0,0 +1,92 @@
+"""Rename/removal manifest between published library versions."""
+
+import io
+import json
+import zipfile
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.test import Client
+
+from library.history import record_history, snapshot_device
+from library.models import DeviceHistory, LibraryVersion, ModbusConfig, RegisterDefinition, Vendor, VendorModel
+from library.release_migrations import build_migrations
+
+pytestmark = pytest.mark.django_db
+User = get_user_model()
+
+
+@pytest.fixture
+def admin_client(db):
+    u = User.objects.create_user(
+        username="migrations-admin", password="x",
+        is_staff=True, is_superuser=True, role="admin",
+    )
+    c = Client()
+    c.force_login(u)
+    return c
+
+
+def _publish(client):
+    assert client.post("/versions/create/").status_code == 302
+    return LibraryVersion.objects.order_by("-version").first()
+
+
+def test_renames_and_removals(admin_client):
+    vendor = Vendor.objects.create(name="Mig Vendor", slug="mig-vendor")
+    meter = VendorModel.objects.create(
+        vendor=vendor, model_number="MV-1", name="Meter", device_type="power_meter", technology="modbus",
+    )
+    modbus = ModbusConfig.objects.create(device_type=meter)
+    reg = RegisterDefinition.objects.create(modbus_config=modbus, field_name="volt", address=0, data_type="float32")
+    doomed = VendorModel.objects.create(
+        vendor=vendor, model_number="MV-OLD", name="Old", device_type="power_meter", technology="modbus",
+    )
+    record_history(meter, DeviceHistory.Action.CREATED, None)
+    record_history(doomed, DeviceHistory.Action.CREATED, None)
+    _publish(admin_client)
+
+    prev = snapshot_device(meter)
+    meter.model_number = "MV-1A"
+    meter.save()
+    reg.field_name = "voltage"
+    reg.save()
+    record_history(meter, DeviceHistory.Action.UPDATED, None, prev)
+    doomed.delete()
+    v2 = _publish(admin_client)
+
+    doc = build_migrations(v2)
+
+    assert doc["to_version"] == v2.version
+    [renamed] = doc["devices"]["renamed"]
+    assert renamed["key"] == str(meter.key)
+    assert renamed["old"]["model_number"] == "MV-1"
+    assert renamed["new"]["model_number"] == "MV-1A"
+    assert [r["label"] for r in doc["devices"]["removed"]] == ["Mig Vendor MV-OLD"]
+    [field] = doc["fields"]["renamed"]
+    assert (field["old"], field["new"]) == ("volt", "voltage")
+
+    response = admin_client.get(f"/versions/{v2.pk}/export/?format=migrations")
+    assert response.status_code == 200
+    assert response.json()["devices"]["renamed"][0]["new"]["model_number"] == "MV-1A"
+
+
+def test_first_version_has_no_base(admin_client):
+    v1 = _publish(admin_client)
+    doc = build_migrations(v1)
+    assert doc["from_version"] is None
+    assert doc["devices"] == {"renamed": [], "removed": []}
+
+
+def test_release_bundle_includes_migrations(admin_client):
+    v1 = _publish(admin_client)
+
+    response = admin_client.get(f"/versions/{v1.pk}/export/?format=bundle")
+
+    assert response.status_code == 200
+    assert response["Content-Type"] == "application/zip"
+    with zipfile.ZipFile(io.BytesIO(response.content)) as bundle:
+        assert sorted(bundle.namelist()) == [
+            f"library-v{v1.version}.json", f"library-v{v1.version}.yaml", "migrations.json",
+        ]
+        assert json.loads(bundle.read("migrations.json"))["to_version"] == v1.version
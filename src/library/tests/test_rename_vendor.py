@@ -0,0 +1,87 @@
+"""Vendor rename across database and exported YAML tree."""
+
+import pytest
+import yaml
+from django.core.management import call_command
+from django.core.management.base import CommandError
+
+from library.exporters import export_to_yaml
+from library.importers import import_from_yaml
+from library.models import DeviceHistory, Vendor, VendorModel
+from library.rename import RenameError, rename_vendor_in_database, rename_vendor_in_tree
+from library.safe_write import TreeWriter
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def vendor():
+    acme = Vendor.objects.create(name="Rename Acme", slug="rename-acme")
+    for model in ("RA-1", "RA-2"):
+        VendorModel.objects.create(
+            vendor=acme, model_number=model, name=model, device_type="power_meter", technology="modbus",
+        )
+    return acme
+
+
+def test_tree_rename_rewrites_manifest_file_and_devices(vendor, tmp_path):
+    export_to_yaml(tmp_path / "devices")
+    result = rename_vendor_in_tree(
+        tmp_path / "devices", tmp_path / "manifest.yaml", "rename-acme", "Acme Metering", alias=True,
+    )
+    assert result == {"old_file": "rename-acme.yaml", "new_file": "acme-metering.yaml", "devices": 2}
+
+    assert not (tmp_path / "devices" / "rename-acme.yaml").exists()
+    assert (tmp_path / "devices" / "rename-acme.yaml.bak").exists()
+    data = yaml.safe_load((tmp_path / "devices" / "acme-metering.yaml").read_text())
+    assert {d["vendor_name"] for d in data["models"]} == {"Acme Metering"}
+    manifest = yaml.safe_load((tmp_path / "manifest.yaml").read_text())
+    entry = next(v for v in manifest["vendors"] if v["file"] == "acme-metering.yaml")
+    assert entry["name"] == "Acme Metering"
+    assert entry["aliases"] == ["Rename Acme"]
+
+
+def test_tree_dry_run_writes_nothing(vendor, tmp_path):
+    export_to_yaml(tmp_path / "devices")
+    before = (tmp_path / "manifest.yaml").read_text()
+    writer = TreeWriter(dry_run=True)
+    rename_vendor_in_tree(tmp_path / "devices", tmp_path / "manifest.yaml", "Rename Acme", "Acme Metering", writer=writer)
+    assert (tmp_path / "manifest.yaml").read_text() == before
+    assert (tmp_path / "devices" / "rename-acme.yaml").exists()
+    assert len(writer.changed) == 3
+
+
+def test_importing_aliased_tree_renames_vendor_in_place(vendor, tmp_path):
+    export_to_yaml(tmp_path / "devices")
+    rename_vendor_in_tree(tmp_path / "devices", tmp_path / "manifest.yaml", "rename-acme", "Acme Metering", alias=True)
+    device_pks = set(vendor.device_types.values_list("pk", flat=True))
+
+    stats = import_from_yaml(tmp_path / "devices", tmp_path / "manifest.yaml")
+
+    assert stats["vendors_created"] == 0
+    vendor.refresh_from_db()
+    assert (vendor.name, vendor.slug) == ("Acme Metering", "acme-metering")
+    assert set(vendor.device_types.values_list("pk", flat=True)) == device_pks
+
+
+def test_tree_rename_unknown_or_taken(vendor, tmp_path):
+    Vendor.objects.create(name="Rename Other", slug="rename-other")
+    export_to_yaml(tmp_path / "devices")
+    with pytest.raises(RenameError, match="not in manifest"):
+        rename_vendor_in_tree(tmp_path / "devices", tmp_path / "manifest.yaml", "nope", "Whatever")
+    with pytest.raises(RenameError, match="already exists"):
+        rename_vendor_in_tree(tmp_path / "devices", tmp_path / "manifest.yaml", "rename-acme", "Rename Other")
+
+
+def test_database_rename_records_history(vendor):
+    rename_vendor_in_database("rename-acme", "Acme Metering")
+    vendor.refresh_from_db()
+    assert vendor.slug == "acme-metering"
+    entries = DeviceHistory.objects.filter(device__vendor=vendor, action=DeviceHistory.Action.UPDATED)
+    assert entries.count() == 2
+    assert "vendor" in entries.first().changes
+
+
+def test_command_rejects_alias_without_path(vendor):
+    with pytest.raises(CommandError, match="--path"):
+        call_command("rename_vendor", "rename-acme", "Acme Metering", "--alias")
@@ -0,0 +1,145 @@
+"""Atomic writes with .bak backups and dry-run diffs for the YAML tree."""
+
+import io
+import json
+import re
+from unittest import mock
+
+import pytest
+import yaml
+from django.core.management import call_command
+
+from auditlog.models import AuditLog
+from library.management.errors import ValidationFailed
+from library.models import ModbusConfig, RegisterDefinition, Vendor, VendorModel
+from library.safe_write import TreeWriter, atomic_write
+
+
+def _meter(vendor, model_number, name):
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number=model_number, name=name, device_type="power_meter", technology="modbus",
+    )
+    modbus = ModbusConfig.objects.create(
+        device_type=device, function="holding", byte_order="big_endian", word_order="high_first",
+    )
+    RegisterDefinition.objects.create(modbus_config=modbus, field_name="energy", address=0, data_type="uint32")
+    return device
+
+
+def test_atomic_write_keeps_backup(tmp_path):
+    path = tmp_path / "acme.yaml"
+    path.write_text("old\n")
+    atomic_write(path, "new\n")
+    assert path.read_text() == "new\n"
+    assert (tmp_path / "acme.yaml.bak").read_text() == "old\n"
+    assert [p.name for p in tmp_path.iterdir() if p.name.endswith(".tmp")] == []
+
+
+def test_failed_write_leaves_original_intact(tmp_path):
+    path = tmp_path / "acme.yaml"
+    path.write_text("old\n")
+    with mock.patch("library.safe_write.os.replace", side_effect=OSError("disk full")):
+        with pytest.raises(OSError):
+            atomic_write(path, "new\n")
+    assert path.read_text() == "old\n"
+    assert sorted(p.name for p in tmp_path.iterdir()) == ["acme.yaml", "acme.yaml.bak"]
+
+
+def test_dry_run_records_diff_without_writing(tmp_path):
+    path = tmp_path / "acme.yaml"
+    path.write_text("a: 1\n")
+    writer = TreeWriter(dry_run=True)
+    assert writer.write(path, "a: 2\n")
+    assert path.read_text() == "a: 1\n"
+    assert "-a: 1" in writer.diffs[0] and "+a: 2" in writer.diffs[0]
+
+
+def test_unchanged_content_is_not_rewritten(tmp_path):
+    path = tmp_path / "acme.yaml"
+    path.write_text("a: 1\n")
+    writer = TreeWriter()
+    assert not writer.write(path, "a: 1\n")
+    assert writer.changed == []
+    assert not (tmp_path / "acme.yaml.bak").exists()
+
+
+@pytest.mark.django_db
+def test_export_dry_run(tmp_path):
+    vendor = Vendor.objects.create(name="Dry Vendor", slug="dry-vendor")
+    _meter(vendor, "DV-1", "Dry")
+    out = io.StringIO()
+    call_command("export_yaml", "--output-dir", str(tmp_path / "devices"), "--dry-run", stdout=out)
+    assert "+  model_number: DV-1" in out.getvalue()
+    assert not (tmp_path / "devices").exists()
+
+    call_command("export_yaml", "--output-dir", str(tmp_path / "devices"), stdout=io.StringIO())
+    data = yaml.safe_load((tmp_path / "devices" / "dry-vendor.yaml").read_text())
+    assert data["models"][0]["model_number"] == "DV-1"
+
+
+@pytest.mark.django_db
+def test_export_preview_writes_submission_without_touching_tree(tmp_path):
+    vendor = Vendor.objects.create(name="Preview Vendor", slug="preview-vendor")
+    _meter(vendor, "PV-1", "Preview")
+    devices = tmp_path / "repo" / "devices"
+    call_command("export_yaml", "--output-dir", str(devices), "--preview", str(tmp_path / "preview"),
+                 stdout=io.StringIO())
+    assert not devices.exists()
+
+    preview = tmp_path / "preview"
+    summary = json.loads((preview / "preview.json").read_text())
+    assert re.fullmatch(r"library/export-\d{8}-\d{6}-[0-9a-f]{7}", summary["branch"])
+    assert summary["title"] == "Update device library: preview-vendor"
+    assert sorted(summary["changed_files"]) == ["devices/preview-vendor.yaml", "manifest.yaml"]
+    assert "+++ b/devices/preview-vendor.yaml" in (preview / "changes.diff").read_text()
+    page = (preview / "changes.html").read_text()
+    assert '<a href="#file-0">devices/preview-vendor.yaml</a> (new)' in page
+    assert '<span style="color:#1a7f37;background:#e6ffec">+  model_number: PV-1</span>' in page
+
+    out = io.StringIO()
+    call_command("export_yaml", "--output-dir", str(devices), "--preview", "--force-color",
+                 stdout=out, stderr=io.StringIO())
+    assert "\033[1m=== devices/preview-vendor.yaml (new) ===\033[0m" in out.getvalue()
+    assert "\033[32m+  model_number: PV-1\033[0m" in out.getvalue()
+    assert "## Validation" in (preview / "pr.md").read_text()
+    committed = yaml.safe_load((preview / "tree" / "devices" / "preview-vendor.yaml").read_text())
+    assert committed["models"][0]["model_number"] == "PV-1"
+
+    # Exported for real, the same preview has nothing left to change.
+    call_command("export_yaml", "--output-dir", str(devices), "--no-backup", stdout=io.StringIO())
+    out = io.StringIO()
+    call_command("export_yaml", "--output-dir", str(devices), "--preview", stdout=out, stderr=io.StringIO())
+    assert "(no changes)" in out.getvalue()
+
+
+@pytest.mark.django_db
+def test_export_is_blocked_by_errors_in_changed_files(tmp_path, settings):
+    settings.USAGE_TRACKING = False
+    vendor = Vendor.objects.create(name="Gate Vendor", slug="gate-vendor")
+    _meter(vendor, "GV-1", "Complete")
+    VendorModel.objects.create(
+        vendor=vendor, model_number="GV-2", name="Empty", device_type="power_meter", technology="modbus",
+    )
+    devices = tmp_path / "devices"
+    out = io.StringIO()
+    with pytest.raises(ValidationFailed, match="4 validation error") as excinfo:
+        call_command("export_yaml", "--output-dir", str(devices), stdout=out)
+    assert not devices.exists()
+    assert "devices/gate-vendor.yaml\n  GV-2\n    technology-required [technology_config.function]" in out.getvalue()
+    assert list(excinfo.value.details["devices/gate-vendor.yaml"]) == ["GV-2"]
+
+    out = io.StringIO()
+    call_command("export_yaml", "--output-dir", str(devices), "--force", stdout=out)
+    assert "--force: exporting despite 4 error(s)" in out.getvalue()
+    assert len(yaml.safe_load((devices / "gate-vendor.yaml").read_text())["models"]) == 2
+    # Usage tracking is off, yet the override is on record.
+    entry = AuditLog.objects.get(category=AuditLog.Category.EXPORT)
+    assert entry.target_label == f"export_yaml {devices}"
+    assert entry.details["validation_errors_overridden"] == 4
+
+    # Errors in a file this export leaves alone don't block it.
+    other = Vendor.objects.create(name="Other Vendor", slug="other-vendor")
+    _meter(other, "OV-1", "Other")
+    call_command("export_yaml", "--output-dir", str(devices), stdout=io.StringIO())
+    assert (devices / "other-vendor.yaml").exists()
+    assert AuditLog.objects.filter(category=AuditLog.Category.EXPORT).count() == 1
@@ -0,0 +1,53 @@
+"""Device scaffolding — YAML tree append and database creation."""
+
+import pytest
+import yaml
+from django.core.management import call_command
+from django.core.management.base import CommandError
+
+from library.importers import import_from_yaml
+from library.models import DeviceHistory, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def tree(tmp_path):
+    (tmp_path / "devices").mkdir()
+    (tmp_path / "manifest.yaml").write_text(yaml.dump({"schema_version": 4, "vendors": []}))
+    return tmp_path
+
+
+def _create(*extra):
+    call_command(
+        "create_device", "--vendor", "Acme Scaffold", "--model", "PM-210",
+        "--technology", "modbus", "--type", "power_meter", *extra,
+    )
+
+
+def test_appends_to_new_vendor_file_and_manifest(tree):
+    _create("--path", str(tree / "devices"))
+
+    manifest = yaml.safe_load((tree / "manifest.yaml").read_text())
+    assert manifest["vendors"] == [{"name": "Acme Scaffold", "file": "acme-scaffold.yaml", "technologies": ["modbus"]}]
+    [device] = yaml.safe_load((tree / "devices" / "acme-scaffold.yaml").read_text())["models"]
+    assert device["model_number"] == "PM-210"
+    assert device["technology_config"] == {"technology": "modbus", "register_definitions": []}
+
+    # The skeleton is importable as-is.
+    stats = import_from_yaml(tree / "devices", tree / "manifest.yaml")
+    assert stats["errors"] == []
+    assert VendorModel.objects.filter(model_number="PM-210").exists()
+
+
+def test_refuses_duplicate_in_tree(tree):
+    _create("--path", str(tree / "devices"))
+    with pytest.raises(CommandError, match="already exists"):
+        _create("--path", str(tree / "devices"))
+
+
+def test_creates_in_database_with_history():
+    _create()
+    vm = VendorModel.objects.get(vendor__slug="acme-scaffold", model_number="PM-210")
+    assert vm.modbus_config.register_definitions.count() == 0
+    assert DeviceHistory.objects.filter(device=vm, action=DeviceHistory.Action.CREATED).exists()
@@ -0,0 +1,120 @@
+"""JSON Schema documents for the tree, the validator, and the schema lint rule."""
+
+import json
+
+import pytest
+import yaml
+from django.core.management import call_command
+
+from library.exporters import export_to_yaml
+from library.lint import LintDevice, lint_devices
+from library.management.errors import ValidationFailed
+from library.models import (
+    LoRaWANConfig, ModbusConfig, ProcessorConfig, RegisterDefinition, Vendor, VendorModel, WMBusConfig,
+)
+from library.schema import (
+    SCHEMA_DIR, SCHEMAS, _Validator, load_schema, validate_device, validate_manifest, validate_vendor_file,
+)
+from library.strict import DEVICE_KEYS, TECHNOLOGY_KEYS
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def tree(tmp_path):
+    vendor = Vendor.objects.create(name="Schema Vendor", slug="schema-vendor")
+    meter = VendorModel.objects.create(
+        vendor=vendor, model_number="SC-1", name="Meter", device_type="heat_meter", technology="modbus",
+        additional_technologies=["wmbus"],
+    )
+    modbus = ModbusConfig.objects.create(
+        device_type=meter, function="holding", byte_order="big_endian", word_order="high_first",
+    )
+    RegisterDefinition.objects.create(modbus_config=modbus, field_name="energy", address=0, data_type="uint32")
+    WMBusConfig.objects.create(device_type=meter, manufacturer_code="KAM", wmbus_device_type=4)
+    sensor = VendorModel.objects.create(
+        vendor=vendor, model_number="SC-2", name="Sensor", device_type="environment_sensor", technology="lorawan",
+    )
+    LoRaWANConfig.objects.create(device_type=sensor, device_class="A", downlink_f_port=10)
+    ProcessorConfig.objects.create(device_type=sensor)  # lorawan_field_map decoder
+    export_to_yaml(tmp_path / "devices")
+    return tmp_path / "devices", tmp_path / "manifest.yaml"
+
+
+def test_schemas_are_valid_json_and_match_the_known_keys():
+    for file_name in SCHEMAS.values():
+        assert load_schema(file_name)["$id"] == file_name
+    assert set(load_schema("device.schema.json")["properties"]) == DEVICE_KEYS
+    for technology, keys in TECHNOLOGY_KEYS.items():
+        schema = json.loads((SCHEMA_DIR / "technologies" / f"{technology}.schema.json").read_text())
+        assert set(schema["properties"]) == keys
+
+
+def test_exported_tree_is_valid(tree):
+    devices, manifest_path = tree
+    assert validate_manifest(yaml.safe_load(manifest_path.read_text())) == []
+    data = yaml.safe_load((devices / "schema-vendor.yaml").read_text())
+    assert validate_vendor_file(data) == []
+    call_command("lint_library", path=str(devices))
+
+
+def test_errors_carry_paths_per_technology():
+    device = {
+        "model_number": 7,
+        "technology_config": {"technology": "modbus", "register_definitions": [
+            {"field": {"name": "energy"}, "address": 70000, "data_type": "int8"},
+        ]},
+        "additional_technology_configs": [{"technology": "lorawan", "device_class": "D"}],
+        "alarm_config": {"mappings": [{"match": "leak", "severity": "fatal"}]},
+    }
+    assert [(e.path, e.keyword) for e in validate_device(device)] == [
+        ("model_number", "type"),
+        ("technology_config.register_definitions[0].address", "maximum"),
+        ("technology_config.register_definitions[0].data_type", "enum"),
+        ("additional_technology_configs[0].device_class", "enum"),
+        ("alarm_config.mappings[0].severity", "enum"),
+    ]
+    assert [e.path for e in validate_device({"technology_config": {}})] == [
+        "model_number", "technology_config.technology",
+    ]
+
+
+@pytest.mark.parametrize("schema, value, keyword", [
+    ({"enum": [1, "one"]}, True, "enum"),
+    ({"enum": [0]}, False, "enum"),
+    ({"const": False}, 0, "const"),
+    ({"const": [1]}, [True], "const"),
+    ({"uniqueItems": True}, [1, True], None),
+    ({"enum": [1]}, 1.0, None),
+])
+def test_enum_const_and_unique_items_tell_booleans_from_numbers(schema, value, keyword):
+    errors = []
+    _Validator(SCHEMAS["device"], errors).check(value, schema, "")
+    assert [e.keyword for e in errors] == ([keyword] if keyword else [])
+
+
+def test_lint_rule_leaves_unknown_keys_to_unknown_key():
+    device = {
+        "vendor_name": "Acme", "model_number": "X", "description": "x",
+        "technology_config": {"technology": "wmbus", "manufacturer_code": "KAM", "wmbus_device_type": 4,
+                              "encryption_required": "no"},
+        "processor_config": {"field_mappings": [{"source": "volume", "target": "water:total_volume"}]},
+        "descripton": "typo",
+    }
+    findings = lint_devices([LintDevice(data=device)])
+    assert [(f.rule, f.path) for f in findings] == [
+        ("schema", "technology_config.encryption_required"),
+        ("unknown-key", "descripton"),
+    ]
+
+
+def test_lint_library_checks_manifest_and_vendor_files(tree, capsys):
+    devices, manifest_path = tree
+    manifest = yaml.safe_load(manifest_path.read_text())
+    manifest["schema_version"] = "four"
+    manifest_path.write_text(yaml.safe_dump(manifest))
+
+    with pytest.raises(ValidationFailed):
+        call_command("lint_library", path=str(devices))
+    out = capsys.readouterr().out
+    assert "schema" in out and "manifest.yaml" in out and "[schema_version]" in out
@@ -0,0 +1,115 @@
+"""Schema migrations: ordered transforms and the migrate_schema command."""
+
+import pytest
+import yaml
+from django.core.management import call_command
+
+import devicelib
+from library.importers import import_from_yaml
+from library.management.errors import UsageError
+from library.models import DEFAULT_SCHEMA_VERSION, ProcessorConfig, VendorModel
+from library.schema_migrations import LATEST_SCHEMA_VERSION, MIGRATIONS, migrate_documents
+
+pytestmark = pytest.mark.django_db
+
+
+def _v2_tree(tmp_path, schema_version=2):
+    devices = tmp_path / "devices"
+    devices.mkdir()
+    manifest = {"version": "1.0.0", "vendors": [{"name": "Legacy", "file": "legacy.yaml"}]}
+    if schema_version is not None:
+        manifest["schema_version"] = schema_version
+    (tmp_path / "manifest.yaml").write_text(yaml.safe_dump(manifest))
+    (devices / "legacy.yaml").write_text(yaml.safe_dump({"device_types": [{
+        "vendor_name": "Legacy",
+        "model_number": "LG-1",
+        "name": "LG-1",
+        "device_type": "water_meter",
+        "technology_config": {"technology": "wmbus", "manufacturer_code": "AAA"},
+        "control_config": {"controllable": False, "capabilities": {"relay": True}},
+        "processor_config": {
+            "field_mappings": [{"source": "volume_m3", "target": "water:total_volume", "unit": "m³", "primary": True}],
+            "extra_field_mappings": [{"source": "battery_pct", "target": "device:battery", "unit": "ratio"}],
+        },
+    }]}))
+    return devices, tmp_path / "manifest.yaml"
+
+
+def test_every_schema_bump_has_a_migration():
+    assert LATEST_SCHEMA_VERSION == DEFAULT_SCHEMA_VERSION
+    assert [m.version for m in MIGRATIONS] == sorted({m.version for m in MIGRATIONS})
+
+
+def test_migrate_upgrades_tree_in_place(tmp_path, capsys):
+    devices, manifest_path = _v2_tree(tmp_path)
+
+    call_command("migrate_schema", path=str(devices))
+
+    manifest = yaml.safe_load(manifest_path.read_text())
+    assert manifest["schema_version"] == LATEST_SCHEMA_VERSION
+    assert manifest["device_types"] == []
+    assert {m["key"]: m["unit"] for m in manifest["metrics"]} == {"device:battery": "ratio", "water:total_volume": "m³"}
+    device = yaml.safe_load((devices / "legacy.yaml").read_text())["models"][0]
+    assert device["processor_config"] == {"field_mappings": [
+        {"source": "volume_m3", "target": "water:total_volume"},
+        {"source": "battery_pct", "target": "device:battery"},
+    ]}
+    assert device["control_config"] == {"controllable": False}
+    assert (devices / "legacy.yaml.bak").exists()
+
+    # Consumers of the current schema read the migrated tree directly …
+    library = devicelib.load(tmp_path)
+    assert library.schema_version == LATEST_SCHEMA_VERSION
+    assert [m.target for m in library.device("legacy", "LG-1").field_mappings] == [
+        "water:total_volume", "device:battery",
+    ]
+    # … the importer takes it strictly, and a second run changes nothing.
+    assert import_from_yaml(devices, manifest_path, strict=True)["errors"] == []
+    vm = VendorModel.objects.get(model_number="LG-1")
+    assert len(ProcessorConfig.objects.get(device_type=vm).field_mappings) == 2
+    content = manifest_path.read_text()
+    call_command("migrate_schema", path=str(devices))
+    assert manifest_path.read_text() == content
+    assert "already at schema_version" in capsys.readouterr().out
+
+
+def test_migrate_to_intermediate_version_and_dry_run(tmp_path):
+    devices, manifest_path = _v2_tree(tmp_path)
+    original = (devices / "legacy.yaml").read_text()
+
+    call_command("migrate_schema", path=str(devices), to=3, dry_run=True)
+    assert (devices / "legacy.yaml").read_text() == original
+
+    call_command("migrate_schema", path=str(devices), to=3)
+    assert yaml.safe_load(manifest_path.read_text())["schema_version"] == 3
+    device = yaml.safe_load((devices / "legacy.yaml").read_text())["models"][0]
+    assert "extra_field_mappings" in device["processor_config"]  # a v3 shape, untouched until v4
+
+
+def test_migrate_refuses_downgrades_and_unknown_versions(tmp_path):
+    devices, _ = _v2_tree(tmp_path, schema_version=None)
+    with pytest.raises(UsageError, match="no schema_version"):
+        call_command("migrate_schema", path=str(devices))
+    call_command("migrate_schema", path=str(devices), from_version=2)
+
+    with pytest.raises(UsageError, match="downgrading"):
+        call_command("migrate_schema", path=str(devices), to=3)
+    with pytest.raises(UsageError, match="No migration to schema_version"):
+        call_command("migrate_schema", path=str(devices), to=LATEST_SCHEMA_VERSION + 1)
+
+
+def test_steps_leave_migrated_documents_unchanged():
+    manifest = {"schema_version": 2, "device_types": [{"code": "heat_meter", "default_field_mappings": [
+        {"source": "e", "target": "heat:total_energy", "primary": True},
+        {"source": "t", "target": "heat:flow_temperature"},
+    ]}], "vendors": []}
+    result = migrate_documents(manifest, {})
+    assert [m.version for m in result.applied] == [3, 4]
+    assert manifest["device_types"][0]["metrics"] == [
+        {"metric": "heat:total_energy", "tier": "primary"},
+        {"metric": "heat:flow_temperature", "tier": "secondary"},
+    ]
+    snapshot = yaml.safe_dump(manifest)
+    for migration in MIGRATIONS:
+        assert migration.apply(manifest, {}) == []
+    assert yaml.safe_dump(manifest) == snapshot
@@ -0,0 +1,58 @@
+"""Full-text library search — database queryset, YAML tree, model list."""
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.test import Client
+
+from library.exporters import export_to_yaml
+from library.models import ModbusConfig, RegisterDefinition, Vendor, VendorModel
+from library.search import search_queryset, search_yaml
+
+pytestmark = pytest.mark.django_db
+User = get_user_model()
+
+
+@pytest.fixture
+def devices():
+    schneider = Vendor.objects.create(name="Schneider Electric", slug="schneider-electric")
+    pm = VendorModel.objects.create(
+        vendor=schneider, model_number="PM5110", name="PowerLogic PM5110",
+        device_type="power_meter", technology="modbus", description="Three-phase power meter",
+    )
+    modbus = ModbusConfig.objects.create(device_type=pm)
+    RegisterDefinition.objects.create(modbus_config=modbus, field_name="reactive_power", address=0, data_type="float32")
+    other = Vendor.objects.create(name="Acme", slug="acme-search")
+    VendorModel.objects.create(
+        vendor=other, model_number="WM-1", name="Water Meter", device_type="water_meter", technology="wmbus",
+    )
+    return pm
+
+
+def test_all_terms_must_match_across_fields(devices):
+    assert list(search_queryset(VendorModel.objects.all(), "schneider pm")) == [devices]
+    assert not search_queryset(VendorModel.objects.all(), "schneider water").exists()
+
+
+def test_register_field_names_are_searched(devices):
+    assert list(search_queryset(VendorModel.objects.all(), "reactive")) == [devices]
+
+
+def test_yaml_search_reports_file_and_line(tmp_path, devices):
+    export_to_yaml(tmp_path / "devices")
+    hits = search_yaml(tmp_path / "devices", tmp_path / "manifest.yaml", "schneider reactive", technology="modbus")
+
+    assert {h.field for h in hits} >= {"vendor_name", "field_name"}
+    field_hit = next(h for h in hits if h.field == "field_name")
+    assert field_hit.file == "schneider-electric.yaml"
+    lines = (tmp_path / "devices" / "schneider-electric.yaml").read_text().splitlines()
+    assert "reactive_power" in lines[field_hit.line - 1]
+
+    assert search_yaml(tmp_path / "devices", tmp_path / "manifest.yaml", "schneider", technology="wmbus") == []
+
+
+def test_model_list_filters_by_query(devices):
+    client = Client()
+    client.force_login(User.objects.create_user(username="searcher", password="x", role="viewer"))
+    response = client.get("/models/", {"q": "pm5110"})
+    assert response.status_code == 200
+    assert list(response.context["models"]) == [devices]
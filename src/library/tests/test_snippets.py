@@ -0,0 +1,131 @@
+"""Register snippets: the shipped library, insertion, the command and the picker view."""
+
+import io
+import json
+from pathlib import Path
+
+import pytest
+from django.conf import settings
+from django.contrib.auth import get_user_model
+from django.core.management import call_command
+from django.test import Client
+
+from devicelib import fields
+from library.management.errors import Conflict, ValidationFailed
+from library.models import DeviceHistory, ModbusConfig, RegisterDefinition, Vendor, VendorModel
+from library.snippets import SnippetError, insert_snippet, load_snippet, load_snippets
+
+pytestmark = pytest.mark.django_db
+User = get_user_model()
+
+SHIPPED = Path(settings.BASE_DIR).parent / "snippets"
+BLOCK = """\
+name: Test block
+register_definitions:
+- field: {name: voltage_l1, unit: V}
+  address: 0
+  data_type: float32
+- field: {name: current_l1, unit: A}
+  address: 2
+  data_type: float32
+"""
+
+
+@pytest.fixture
+def snippet_dir(tmp_path, settings):
+    settings.SNIPPETS_DIR = tmp_path
+    (tmp_path / "test-block.yaml").write_text(BLOCK)
+    return tmp_path
+
+
+@pytest.fixture
+def modbus_device():
+    vendor = Vendor.objects.create(name="Snippet Vendor", slug="snippet-vendor")
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number="SV-1", name="Snippet Meter", device_type="power_meter", technology="modbus",
+    )
+    modbus = ModbusConfig.objects.create(device_type=device)
+    RegisterDefinition.objects.create(
+        modbus_config=modbus, field_name="energy_import", field_unit="kWh", address=0, data_type="uint32",
+    )
+    return device
+
+
+def test_shipped_snippets_are_valid_and_canonical():
+    snippets = load_snippets(SHIPPED)
+    assert {s.key for s in snippets} >= {"three-phase-electricity", "heat-meter"}
+    for snippet in snippets:
+        for reg in snippet.registers:
+            assert fields.validate(reg["field"]["name"], reg["field"].get("unit", "")) == [], snippet.key
+
+
+def test_insert_shifts_addresses_and_records_history(snippet_dir, modbus_device):
+    created = insert_snippet(modbus_device, load_snippet("test-block"), 100)
+    assert [(r.field_name, r.address) for r in created] == [("voltage_l1", 100), ("current_l1", 102)]
+    assert DeviceHistory.objects.filter(device=modbus_device, action="updated").count() == 1
+
+
+def test_insert_is_refused_on_clashes(snippet_dir, modbus_device):
+    with pytest.raises(SnippetError, match="address 0 is used by energy_import"):
+        insert_snippet(modbus_device, load_snippet("test-block"), 0)
+    assert RegisterDefinition.objects.filter(modbus_config__device_type=modbus_device).count() == 1
+
+
+def test_invalid_snippet_lists_problems(snippet_dir):
+    (snippet_dir / "broken.yaml").write_text("name: Broken\nregister_definitions:\n- field: {nam: x}\n  address: -1\n")
+    with pytest.raises(SnippetError) as excinfo:
+        load_snippet("broken")
+    assert "did you mean 'name'" in str(excinfo.value)
+    assert "address must be a non-negative integer" in str(excinfo.value)
+
+
+class TestCommand:
+    def test_create_from_device_then_list(self, snippet_dir, modbus_device):
+        insert_snippet(modbus_device, load_snippet("test-block"), 10)
+        call_command(
+            "snippets", "create", "sv-phase", "--vendor", "snippet-vendor", "--model", "SV-1", "--start", "10",
+            stderr=io.StringIO(),
+        )
+        snippet = load_snippet("sv-phase")
+        assert [(r["field"]["name"], r["address"]) for r in snippet.registers] == [("voltage_l1", 0), ("current_l1", 2)]
+
+        out = io.StringIO()
+        call_command("snippets", "list", "--format", "json", stdout=out)
+        assert [row["key"] for row in json.loads(out.getvalue())] == ["sv-phase", "test-block"]
+
+        with pytest.raises(Conflict):
+            call_command("snippets", "create", "sv-phase", "--vendor", "snippet-vendor", "--model", "SV-1")
+
+    def test_check_fails_on_a_broken_file(self, snippet_dir):
+        call_command("snippets", "check", stdout=io.StringIO())
+        (snippet_dir / "broken.yaml").write_text("name: Broken\nregister_definitions: []\n")
+        with pytest.raises(ValidationFailed, match="1 of 2"):
+            call_command("snippets", "check", stdout=io.StringIO(), stderr=io.StringIO())
+
+
+class TestView:
+    @pytest.fixture
+    def client(self):
+        user = User.objects.create_user(username="snippet-editor", password="x", role="editor")
+        client = Client()
+        client.force_login(user)
+        return client
+
+    def test_picker_suggests_next_free_address(self, client, snippet_dir, modbus_device):
+        response = client.get(f"/models/{modbus_device.pk}/registers/snippets/")
+        assert response.status_code == 200
+        assert response.context["form"].initial["base_address"] == 2  # after the uint32 at 0
+
+    def test_insert_from_picker(self, client, snippet_dir, modbus_device):
+        response = client.post(
+            f"/models/{modbus_device.pk}/registers/snippets/", {"snippet": "test-block", "base_address": 20},
+        )
+        assert response.status_code == 302
+        assert RegisterDefinition.objects.filter(modbus_config__device_type=modbus_device, address=22).exists()
+
+    def test_clash_is_shown_on_the_form(self, client, snippet_dir, modbus_device):
+        response = client.post(
+            f"/models/{modbus_device.pk}/registers/snippets/", {"snippet": "test-block", "base_address": 0},
+        )
+        assert response.status_code == 200
+        assert "address 0 is used by energy_import" in response.content.decode()
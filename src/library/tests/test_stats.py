@@ -0,0 +1,54 @@
+"""Library coverage statistics."""
+
+import io
+import json
+
+import pytest
+from django.core.management import call_command
+
+from library.models import ControlConfig, ModbusConfig, ProcessorConfig, RegisterDefinition, Vendor, VendorModel
+from library.stats import library_stats
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def library():
+    acme = Vendor.objects.create(name="Stats Acme", slug="stats-acme")
+    meter = VendorModel.objects.create(
+        vendor=acme, model_number="SA-1", name="Meter", device_type="power_meter", technology="modbus",
+        description="Three-phase meter",
+    )
+    modbus = ModbusConfig.objects.create(device_type=meter)
+    for address in range(4):
+        RegisterDefinition.objects.create(
+            modbus_config=modbus, field_name=f"f{address}", address=address, data_type="uint16",
+        )
+    ProcessorConfig.objects.create(device_type=meter, field_mappings=[{"source": "f0", "target": "power:active"}])
+    ControlConfig.objects.create(device_type=meter, controllable=True, controls=[{"type": "relay"}])
+
+    VendorModel.objects.create(
+        vendor=acme, model_number="SA-2", name="Bare", device_type="power_meter", technology="modbus",
+    )
+    other = Vendor.objects.create(name="Stats Other", slug="stats-other")
+    VendorModel.objects.create(
+        vendor=other, model_number="SO-1", name="Sensor", device_type="environment_sensor", technology="lorawan",
+    )
+
+
+def test_library_stats(library):
+    stats = library_stats()
+    assert stats["devices"] == 3
+    assert stats["per_vendor"][0] == {"vendor": "Stats Acme", "count": 2}
+    assert {r["technology"]: r["count"] for r in stats["per_technology"]} == {"modbus": 2, "lorawan": 1}
+    assert stats["controllable"] == 1
+    assert stats["controllable_share"] == pytest.approx(1 / 3, abs=1e-4)
+    assert stats["missing_description"] == ["Stats Acme SA-2", "Stats Other SO-1"]
+    assert stats["missing_metrics"] == ["Stats Acme SA-2", "Stats Other SO-1"]
+    assert stats["avg_registers_per_modbus_device"] == 2.0
+
+
+def test_command_json(library):
+    out = io.StringIO()
+    call_command("stats", "--format", "json", stdout=out)
+    assert json.loads(out.getvalue())["devices"] == 3
@@ -0,0 +1,52 @@
+"""The status log: messages kept past the page that showed them, and --log-file."""
+
+import pytest
+from django.contrib import messages
+from django.contrib.auth import get_user_model
+from django.core.management import call_command
+from django.test import Client, RequestFactory
+
+from library import status_log
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def client():
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(username="log-editor", password="x", role="editor"))
+    return client
+
+
+def test_messages_stay_in_the_status_log(client):
+    client.get("/jump/", {"q": "nothing-like-this"})
+    [entry] = client.session[status_log.STATUS_LOG_KEY]
+    assert (entry["level"], entry["path"]) == ("warning", "/jump/")
+    assert "nothing-like-this" in entry["message"]
+
+    # Shown once at the top of the next page, then only in the log panel.
+    for _ in range(2):
+        body = client.get("/models/").content.decode()
+        assert 'id="statusLog"' in body
+        assert "nothing-like-this" in body
+
+
+def test_status_log_is_capped():
+    request = RequestFactory().get("/models/")
+    request.session = {}
+    for n in range(status_log.STATUS_LOG_LIMIT + 5):
+        status_log.record(request, messages.INFO, f"message {n}")
+    entries = status_log.entries(request)
+    assert len(entries) == status_log.STATUS_LOG_LIMIT
+    assert entries[0]["message"] == f"message {status_log.STATUS_LOG_LIMIT + 4}"
+
+
+def test_command_log_file(tmp_path):
+    log_file = tmp_path / "run.log"
+    call_command(
+        "create_device", "--vendor", "Log Vendor", "--model", "LV-1", "--technology", "modbus",
+        "--type", "power_meter", "--log-file", str(log_file),
+    )
+    lines = log_file.read_text().splitlines()
+    assert "manage.py create_device started" in lines[0]
+    assert "manage.py create_device finished" in lines[-1]
@@ -0,0 +1,99 @@
+"""Strict decoding: unknown keys in device definitions and technology configs."""
+
+import pytest
+import yaml
+from django.core.management import call_command
+from django.core.management.base import CommandError
+
+from library.exporters import export_to_yaml
+from library.importers import import_from_yaml
+from library.lint import LintDevice, lint_devices
+from library.models import Vendor, VendorModel
+from library.strict import UnknownKeyError, check_known_keys, unknown_keys
+
+pytestmark = pytest.mark.django_db
+
+
+def _device(**overrides):
+    device = {
+        "vendor_name": "Strict Vendor",
+        "model_number": "SV-1",
+        "name": "Meter",
+        "device_type": "power_meter",
+        "description": "Meter",
+        "technology_config": {
+            "technology": "modbus",
+            "function": "holding",
+            "byte_order": "big_endian",
+            "word_order": "high_first",
+            "register_definitions": [
+                {"field": {"name": "energy", "unit": "kWh"}, "address": 0, "data_type": "uint32",
+                 "display": {"precision": 2}},
+            ],
+        },
+        "processor_config": {"field_mappings": [{"source": "energy", "target": "energy:import"}]},
+    }
+    device.update(overrides)
+    return device
+
+
+def test_known_schema_passes():
+    assert unknown_keys(_device()) == []
+    check_known_keys(_device(control_config={"controllable": False, "capabilities": {}}))
+
+
+def test_typos_reported_with_suggestion():
+    device = _device(descripton="typo")
+    tech = device["technology_config"]
+    tech["registr_definitions"] = tech.pop("register_definitions")
+    found = dict(unknown_keys(device))
+    assert found["descripton"] == "Unknown key 'descripton' (did you mean 'description'?)"
+    assert "did you mean 'register_definitions'" in found["technology_config.registr_definitions"]
+
+
+def test_nested_register_and_per_technology_keys():
+    device = _device()
+    device["technology_config"]["register_definitions"][0]["field"]["units"] = "kWh"
+    assert [path for path, _ in unknown_keys(device)] == [
+        "technology_config.register_definitions[0].field.units",
+    ]
+    # A wM-Bus key on a LoRaWAN device is dead data too.
+    lorawan = _device(technology_config={"technology": "lorawan", "manufacturer_code": "ABC"})
+    assert [path for path, _ in unknown_keys(lorawan)] == ["technology_config.manufacturer_code"]
+    with pytest.raises(UnknownKeyError, match="manufacturer_code"):
+        check_known_keys(lorawan)
+
+
+def test_lint_rule_is_an_error_by_default():
+    findings = lint_devices([LintDevice(data=_device(descripton="x"))])
+    assert [(f.rule, f.severity, f.path) for f in findings] == [("unknown-key", "error", "descripton")]
+
+
+@pytest.fixture
+def tree(tmp_path):
+    vendor = Vendor.objects.create(name="Strict Vendor", slug="strict-vendor")
+    VendorModel.objects.create(
+        vendor=vendor, model_number="SV-1", name="Meter", device_type="power_meter", technology="modbus",
+    )
+    export_to_yaml(tmp_path / "devices")
+    path = tmp_path / "devices" / "strict-vendor.yaml"
+    data = yaml.safe_load(path.read_text())
+    data["models"][0]["descripton"] = "typo"
+    data["models"][0]["name"] = "Renamed"
+    path.write_text(yaml.safe_dump(data))
+    return tmp_path / "devices", tmp_path / "manifest.yaml"
+
+
+def test_import_ignores_unknown_keys_unless_strict(tree):
+    stats = import_from_yaml(*tree, strict=True)
+    assert "descripton" in stats["errors"][0]
+    assert VendorModel.objects.get(model_number="SV-1").name == "Meter"
+
+    stats = import_from_yaml(*tree)
+    assert stats["errors"] == []
+    assert VendorModel.objects.get(model_number="SV-1").name == "Renamed"
+
+
+def test_lint_library_fails_on_unknown_key(tree):
+    with pytest.raises(CommandError, match="Lint failed"):
+        call_command("lint_library", path=str(tree[0]))
@@ -0,0 +1,325 @@
+"""Submitting an export as a pull request, directly or through a fork."""
+
+import base64
+import io
+import urllib.error
+
+import pytest
+from django.core.management import call_command
+
+from library import submit as submit_module
+from library.export_preview import ExportPreview
+from library.management.errors import AuthFailure, ExitCode, NetworkFailure
+from library.models import Vendor, VendorModel
+from library.submit import (
+    GitHub,
+    SubmitError,
+    Unauthorized,
+    Unreachable,
+    blob_sha,
+    branch_files,
+    compare,
+    open_submissions,
+    submit,
+    update,
+)
+
+UPSTREAM = "hardwario/enerooo-spark-device-library"
+FORK = "vendor/enerooo-spark-device-library"
+BRANCH = "library/export-20261016-1200"
+
+
+def _pull(number=7, login="vendor", branch=BRANCH, **fields):
+    return {
+        "number": number, "state": "open", "title": "Update device library: acme", "user": {"login": login},
+        "html_url": f"https://github.com/{UPSTREAM}/pull/{number}",
+        "head": {"ref": branch, "sha": "branch-head", "repo": {"full_name": FORK}}, **fields,
+    }
+
+
+class FakeGitHub:
+    """``GitHub`` stand-in: the library repository and, optionally, the user's fork."""
+
+    def __init__(self, push=True, fork=None, pending=0, fork_name=FORK, pull=None, branch=None, upstream=None):
+        self.push = push
+        self.fork = fork
+        self.fork_name = fork_name  # what forking creates
+        self.pending = pending  # polls a new fork answers 404 to before it's ready
+        self.pull = pull or _pull()
+        self.branch = branch or {}  # files on the pull request's branch
+        self.upstream = upstream or {}  # files on the library's default branch
+        self.calls = []
+
+    def get(self, path):
+        self.calls.append(("GET", path))
+        if path == f"/repos/{UPSTREAM}":
+            return {"name": "enerooo-spark-device-library", "full_name": UPSTREAM, "default_branch": "main",
+                    "permissions": {"push": self.push}}
+        if path == "/user":
+            return {"login": "vendor"}
+        if path == "/repos/vendor/enerooo-spark-device-library":
+            if self.fork is None:
+                raise SubmitError(f"GET {path}: HTTP 404 Not Found", 404)
+            return self.fork
+        if "/git/ref/heads/" in path:
+            if not path.startswith(f"/repos/{UPSTREAM}/") and self.pending:
+                self.pending -= 1
+                raise SubmitError(f"GET {path}: HTTP 409 Git Repository is empty.", 409)
+            return {"object": {"sha": "head"}}
+        if "/git/commits/" in path:
+            return {"tree": {"sha": "base-tree"}}
+        if path.startswith(f"/repos/{UPSTREAM}/pulls?"):
+            return [self.pull, _pull(8, login="someone-else"), _pull(9, branch="fix-typo")]
+        if path == f"/repos/{UPSTREAM}/pulls/7":
+            return self.pull
+        if path.endswith("/git/trees/base-tree?recursive=1"):
+            files = self.upstream if path.startswith(f"/repos/{UPSTREAM}/") else self.branch
+            return {"tree": [{"path": "devices", "type": "tree", "sha": "d"}] + [
+                {"path": name, "type": "blob", "sha": blob_sha(content)} for name, content in files.items()
+            ]}
+        if "/git/blobs/" in path:
+            files = {**self.upstream, **self.branch}.values()
+            [content] = {c for c in files if blob_sha(c) == path.rsplit("/", 1)[1]}
+            return {"content": base64.b64encode(content.encode()).decode(), "encoding": "base64"}
+        raise AssertionError(path)
+
+    def post(self, path, body):
+        self.calls.append(("POST", path, body))
+        if path.endswith("/forks"):
+            return {"full_name": self.fork_name}
+        if path.endswith("/pulls"):
+            return {"number": 7, "html_url": f"https://github.com/{UPSTREAM}/pull/7"}
+        return {"sha": path.rsplit("/", 1)[1]}
+
+    def patch(self, path, body):
+        self.calls.append(("PATCH", path, body))
+        return {}
+
+    def posted(self, suffix):
+        return [(path, body) for method, path, *body in self.calls if method == "POST" and path.endswith(suffix)]
+
+
+class RefusingGitHub(FakeGitHub):
+    def post(self, path, body):
+        raise Unauthorized(f"POST {path}: HTTP 403 Resource not accessible by integration", 403)
+
+
+def _preview(tree=None):
+    # ``changes`` are against the local tree; submitting compares with the repository instead.
+    return ExportPreview(
+        branch="library/export-20261016-1200",
+        title="Device library export (no changes)",
+        changes=[],
+        checks=[],
+        findings=[],
+        stats={"vendors_exported": 1, "devices_exported": 1},
+        tree={"devices/acme.yaml": "vendor: acme\n"} if tree is None else tree,
+    )
+
+
+def test_with_push_access_the_branch_goes_to_the_library():
+    github = FakeGitHub()
+    submission = submit(_preview(), UPSTREAM, github)
+    assert (submission.repo, submission.forked, submission.number) == (UPSTREAM, False, 7)
+    assert ("GET", "/user") not in github.calls
+    [(path, [tree])] = github.posted("/git/trees")
+    assert path == f"/repos/{UPSTREAM}/git/trees"
+    assert tree == {"base_tree": "base-tree", "tree": [
+        {"path": "devices/acme.yaml", "mode": "100644", "type": "blob", "content": "vendor: acme\n"},
+    ]}
+    assert github.posted("/git/commits")[0][1][0]["parents"] == ["head"]
+    assert github.posted("/git/refs")[0][1][0] == {"ref": "refs/heads/library/export-20261016-1200", "sha": "commits"}
+    [(_, [pull])] = github.posted("/pulls")
+    assert (pull["head"], pull["base"]) == ("library/export-20261016-1200", "main")
+    assert "maintainer_can_modify" not in pull
+
+
+def test_without_push_access_an_existing_fork_is_reused():
+    fork = {"full_name": "vendor/enerooo-spark-device-library", "fork": True, "parent": {"full_name": UPSTREAM}}
+    github = FakeGitHub(push=False, fork=fork)
+    submission = submit(_preview(), UPSTREAM, github)
+    assert (submission.repo, submission.forked) == ("vendor/enerooo-spark-device-library", True)
+    assert not github.posted("/forks")
+    assert github.posted("/git/refs")[0][0] == "/repos/vendor/enerooo-spark-device-library/git/refs"
+    [(path, [pull])] = github.posted("/pulls")
+    assert path == f"/repos/{UPSTREAM}/pulls"
+    assert pull["head"] == "vendor:library/export-20261016-1200"
+    assert pull["maintainer_can_modify"] is True
+
+
+def test_without_a_fork_one_is_created_and_waited_for():
+    unrelated = {"full_name": "vendor/enerooo-spark-device-library", "fork": False}
+    github = FakeGitHub(push=False, fork=unrelated, pending=2, fork_name="vendor/enerooo-spark-device-library-1")
+    waits = []
+    submission = submit(_preview(), UPSTREAM, github, sleep=waits.append)
+    assert github.posted("/forks") == [(f"/repos/{UPSTREAM}/forks", [{}])]
+    assert waits == [submit_module.FORK_WAIT_SECONDS] * 2
+    assert (submission.repo, submission.forked) == ("vendor/enerooo-spark-device-library-1", True)
+
+    github = FakeGitHub(push=False, pending=submit_module.FORK_WAIT_TRIES)
+    with pytest.raises(SubmitError, match="is not ready yet"):
+        submit(_preview(), UPSTREAM, github, sleep=lambda seconds: None)
+    assert not github.posted("/git/trees")
+
+
+def test_changes_are_taken_against_the_library_repository():
+    upstream = {
+        "README.md": "readme\n", "manifest.yaml": "vendors: []\n",
+        "devices/acme.yaml": "vendor: acme\nmodels: []\n", "devices/gone.yaml": "vendor: gone\n",
+    }
+    github = FakeGitHub(upstream=upstream)
+    compared = compare(_preview(tree={"manifest.yaml": "vendors: []\n", "devices/acme.yaml": "vendor: acme\n"}),
+                       UPSTREAM, github)
+    assert [(c.path, c.status) for c in compared.changes] == [
+        ("devices/acme.yaml", "+0 −1"), ("devices/gone.yaml", "deleted"),
+    ]
+    assert compared.title == "Update device library: acme, gone"
+
+    submit(compared, UPSTREAM, github)
+    [(_, [tree])] = github.posted("/git/trees")
+    assert tree["tree"] == [
+        {"path": "devices/acme.yaml", "mode": "100644", "type": "blob", "content": "vendor: acme\n"},
+        {"path": "devices/gone.yaml", "mode": "100644", "type": "blob", "sha": None},
+    ]
+    [(_, [pull])] = github.posted("/pulls")
+    assert pull["title"] == "Update device library: acme, gone"
+    assert "- `devices/gone.yaml` (deleted)" in pull["body"]
+
+
+def test_nothing_to_submit_and_no_token(monkeypatch):
+    with pytest.raises(SubmitError, match=f"Nothing to submit: the export matches {UPSTREAM}"):
+        submit(_preview(), UPSTREAM, FakeGitHub(upstream={"devices/acme.yaml": "vendor: acme\n"}))
+    monkeypatch.setattr(submit_module, "github_token", lambda: None)
+    with pytest.raises(Unauthorized, match="needs a GitHub token"):
+        submit(_preview(), UPSTREAM)
+
+
+def test_open_submissions_are_the_users_export_pull_requests():
+    [submission] = open_submissions(UPSTREAM, FakeGitHub())
+    assert (submission.number, submission.repo, submission.branch, submission.forked) == (7, FORK, BRANCH, True)
+
+
+def test_branch_files_are_the_exported_tree():
+    branch = {"README.md": "readme\n", "manifest.yaml": "vendors: []\n", "devices/acme.yaml": "vendor: acme\n",
+              "devices/old/acme.yaml": "vendor: old\n"}
+    assert branch_files(7, UPSTREAM, github=FakeGitHub(branch=branch)) == {
+        "manifest.yaml": "vendors: []\n", "devices/acme.yaml": "vendor: acme\n",
+    }
+
+
+def test_update_pushes_only_what_differs_from_the_branch():
+    branch = {"manifest.yaml": "vendors: []\n", "devices/acme.yaml": "vendor: acme\n"}
+    github = FakeGitHub(branch=branch)
+    tree = {**branch, "devices/acme.yaml": "vendor: acme\nmodels: []\n", "devices/beta.yaml": "vendor: beta\n"}
+    submission = update(_preview(tree=tree), 7, UPSTREAM, github)
+    assert (submission.repo, submission.branch, submission.commit) == (FORK, BRANCH, "commits")
+    [(path, [posted])] = github.posted("/git/trees")
+    assert path == f"/repos/{FORK}/git/trees"
+    assert [entry["path"] for entry in posted["tree"]] == ["devices/acme.yaml", "devices/beta.yaml"]
+    [(_, [commit])] = github.posted("/git/commits")
+    assert commit["parents"] == ["branch-head"]
+    assert commit["message"].startswith("Update device library: acme, beta\n")
+    assert github.calls[-1] == ("PATCH", f"/repos/{FORK}/git/refs/heads/{BRANCH}", {"sha": "commits"})
+    assert not github.posted("/pulls")
+
+    github = FakeGitHub(branch=branch)
+    assert update(_preview(tree=branch), 7, UPSTREAM, github).commit == ""
+    assert not github.posted("/git/trees")
+
+
+@pytest.mark.parametrize("pull, message", [
+    (_pull(login="someone-else"), "opened by someone-else, not by you"),
+    (_pull(branch="fix-typo"), "its branch fix-typo doesn't start with library/export-"),
+])
+def test_only_own_export_pull_requests_are_updated(pull, message):
+    github = FakeGitHub(pull=pull)
+    with pytest.raises(SubmitError, match=message):
+        update(_preview(tree={"manifest.yaml": ""}), 7, UPSTREAM, github)
+    assert not github.posted("/git/trees")
+
+
+def test_closed_pull_requests_are_not_updated():
+    github = FakeGitHub(pull=_pull(state="closed", merged_at="2026-10-16T12:00:00Z"))
+    with pytest.raises(SubmitError, match="#7 is merged"):
+        update(_preview(tree={"manifest.yaml": ""}), 7, UPSTREAM, github)
+    github = FakeGitHub(pull=_pull(head={"ref": BRANCH, "sha": "branch-head", "repo": None}))
+    with pytest.raises(SubmitError, match="its fork was deleted"):
+        branch_files(7, UPSTREAM, github=github)
+
+
+@pytest.mark.django_db
+def test_export_yaml_submit(tmp_path, monkeypatch):
+    vendor = Vendor.objects.create(name="Submit Vendor", slug="submit-vendor")
+    VendorModel.objects.create(vendor=vendor, model_number="SV-1", name="Submitted", technology="modbus")
+    devices = tmp_path / "repo" / "devices"
+    github = FakeGitHub(push=False)
+    monkeypatch.setattr(submit_module, "github_token", lambda: "token")
+    monkeypatch.setattr(submit_module, "GitHub", lambda token: github)
+
+    out = io.StringIO()
+    call_command("export_yaml", "--output-dir", str(devices), "--submit", "--force", stdout=out)
+    assert "pushed library/export-" in out.getvalue()
+    assert f"Pull request #7 opened: https://github.com/{UPSTREAM}/pull/7" in out.getvalue()
+    assert not devices.exists()
+    [(_, [tree])] = github.posted("/git/trees")
+    assert sorted(entry["path"] for entry in tree["tree"]) == ["devices/submit-vendor.yaml", "manifest.yaml"]
+
+    # Exported as the library repository already has it: nothing to submit, whatever the local tree.
+    call_command("export_yaml", "--output-dir", str(devices), "--no-backup", "--force", stdout=io.StringIO())
+    exported = {str(path.relative_to(devices.parent)): path.read_text() for path in devices.parent.rglob("*.yaml")}
+    monkeypatch.setattr(submit_module, "GitHub", lambda token: FakeGitHub(upstream=exported))
+    out = io.StringIO()
+    call_command("export_yaml", "--output-dir", str(tmp_path / "elsewhere" / "devices"), "--submit", stdout=out)
+    assert f"Nothing to submit: the export matches {UPSTREAM}." in out.getvalue()
+
+    monkeypatch.setattr(submit_module, "GitHub", lambda token: RefusingGitHub())
+    with pytest.raises(AuthFailure, match="HTTP 403") as excinfo:
+        call_command("export_yaml", "--output-dir", str(devices), "--submit", "--force", stdout=io.StringIO())
+    assert excinfo.value.returncode == ExitCode.AUTH
+
+
+@pytest.mark.django_db
+def test_client_errors_tell_auth_from_network(monkeypatch):
+    def refuse(request, timeout):
+        raise urllib.error.HTTPError(request.full_url, 401, "Unauthorized", {}, io.BytesIO(b'{"message": "Bad creds"}'))
+
+    monkeypatch.setattr(submit_module.urllib.request, "urlopen", refuse)
+    with pytest.raises(Unauthorized, match="HTTP 401 Bad creds"):
+        GitHub("token").get("/user")
+
+    def unreachable(request, timeout):
+        raise urllib.error.URLError("Name or service not known")
+
+    monkeypatch.setattr(submit_module.urllib.request, "urlopen", unreachable)
+    with pytest.raises(Unreachable):
+        GitHub("token").get("/user")
+    monkeypatch.setattr(submit_module, "github_token", lambda: "token")
+    with pytest.raises(NetworkFailure) as excinfo:
+        call_command("export_yaml", "--list-submissions", stdout=io.StringIO())
+    assert excinfo.value.returncode == ExitCode.NETWORK
+
+
+@pytest.mark.django_db
+def test_export_yaml_resumes_a_submission(tmp_path, monkeypatch):
+    vendor = Vendor.objects.create(name="Acme", slug="acme")
+    VendorModel.objects.create(vendor=vendor, model_number="A-1", name="Acme meter", technology="modbus")
+    devices = tmp_path / "repo" / "devices"
+    github = FakeGitHub(branch={"manifest.yaml": "vendors: []\n", "devices/acme.yaml": "vendor: acme\n"})
+    monkeypatch.setattr(submit_module, "github_token", lambda: "token")
+    monkeypatch.setattr(submit_module, "GitHub", lambda token: github)
+
+    out = io.StringIO()
+    call_command("export_yaml", "--list-submissions", stdout=out)
+    assert out.getvalue() == f"#7  Update device library: acme  {BRANCH} (fork {FORK})  {github.pull['html_url']}\n"
+
+    out = io.StringIO()
+    call_command("export_yaml", "--output-dir", str(devices), "--checkout", "7", "--no-backup", stdout=out)
+    assert (devices / "acme.yaml").read_text() == "vendor: acme\n"
+    assert f"load them with import_yaml --path {devices}" in out.getvalue()
+
+    out = io.StringIO()
+    call_command("export_yaml", "--output-dir", str(devices), "--update", "7", "--force", stdout=out)
+    assert f"Pushed commits to {BRANCH} of {FORK}" in out.getvalue()
+    [(_, [tree])] = github.posted("/git/trees")
+    assert "devices/acme.yaml" in [entry["path"] for entry in tree["tree"]]
+    assert not github.posted("/pulls")
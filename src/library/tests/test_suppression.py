@@ -0,0 +1,85 @@
+"""Rule severities from .sparklint.yaml and per-device inline suppressions."""
+
+import pytest
+import yaml
+
+from library.exporters import export_to_yaml
+from library.forms import VendorModelForm
+from library.importers import import_from_yaml
+from library.lint import RULES, LintConfig, LintDevice, lint_devices
+from library.models import Vendor, VendorModel, WMBusConfig
+from library.schema import validate_device
+
+pytestmark = pytest.mark.django_db
+
+
+def _device(**overrides):
+    device = {
+        "vendor_name": "Acme", "model_number": "WM-1", "description": "",
+        "technology_config": {"technology": "wmbus", "manufacturer_code": "KAM", "wmbus_device_type": 7},
+        "processor_config": {"field_mappings": [{"source": "volume", "target": "water:total_volume"}]},
+    }
+    device.update(overrides)
+    return device
+
+
+def _rules(findings):
+    return [(f.rule, f.severity) for f in findings]
+
+
+def test_severity_shorthand():
+    device = LintDevice(data=_device())
+    assert _rules(lint_devices([device])) == [("missing-description", "warning")]
+    config = LintConfig.from_dict({"rules": {"missing-description": "error"}})
+    assert _rules(lint_devices([device], config)) == [("missing-description", "error")]
+    for ignored in ("ignore", {"severity": "ignore"}, False):
+        assert lint_devices([device], LintConfig.from_dict({"rules": {"missing-description": ignored}})) == []
+    with pytest.raises(ValueError, match="severity must be one of error, warning, ignore"):
+        LintConfig.from_dict({"rules": {"missing-description": "fatal"}})
+
+
+def test_inline_suppression():
+    device = _device(validation={"suppress": ["missing-description", "missing-descripton"]})
+    assert validate_device(device) == []
+    findings = lint_devices([LintDevice(data=device)])
+    assert [(f.rule, f.path, f.message) for f in findings] == [
+        ("unknown-suppression", "validation.suppress[1]",
+         "Unknown rule 'missing-descripton' (did you mean 'missing-description'?)"),
+    ]
+
+    # Library-scope findings are dropped for the suppressing device only.
+    quiet = LintDevice(data=_device(description="x", validation={"suppress": ["duplicate-model-number"]}))
+    loud = LintDevice(data=_device(description="x", model_number="wm-1"))
+    assert [f.rule for f in lint_devices([quiet, loud])] == ["duplicate-model-number"]
+    assert [f.rule for f in lint_devices([loud, quiet])] == []
+
+
+def test_suppressions_round_trip_and_editor(tmp_path):
+    vendor = Vendor.objects.create(name="Quiet Vendor", slug="quiet-vendor")
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number="QV-1", name="Quiet", device_type="water_meter", technology="wmbus",
+        suppressed_rules=["missing-description"],
+    )
+    WMBusConfig.objects.create(device_type=device, manufacturer_code="KAM", wmbus_device_type=7)
+
+    export_to_yaml(tmp_path / "devices")
+    exported = yaml.safe_load((tmp_path / "devices" / "quiet-vendor.yaml").read_text())["models"][0]
+    assert exported["validation"] == {"suppress": ["missing-description"]}
+    VendorModel.objects.all().delete()
+    import_from_yaml(tmp_path / "devices", tmp_path / "manifest.yaml")
+    device = VendorModel.objects.get(model_number="QV-1")
+    assert device.suppressed_rules == ["missing-description"]
+
+    form = VendorModelForm(data={
+        "vendor": vendor.pk, "model_number": "QV-1", "name": "Quiet", "device_type": "water_meter",
+        "technology": "wmbus", "description": "", "links": "[]", "suppressed_rules": ["field-naming", "no-such-rule"],
+    }, instance=device)
+    assert not form.is_valid()
+    assert "suppressed_rules" in form.errors
+
+
+def test_suppression_checklist_describes_rules():
+    html = str(VendorModelForm(initial={"suppressed_rules": ["missing-description"]})["suppressed_rules"])
+    assert 'value="missing-description" id="id_suppressed_rules_' in html
+    assert html.count(" checked") == 1
+    assert RULES["missing-description"].description in html
@@ -0,0 +1,32 @@
+"""Shared --path/--manifest handling across the tree commands."""
+
+import argparse
+
+import pytest
+from django.core.management.base import CommandError
+
+from library.management.tree import add_tree_arguments, tree_paths
+
+
+def _parse(*argv):
+    parser = argparse.ArgumentParser()
+    add_tree_arguments(parser, "devices dir")
+    return vars(parser.parse_args(argv))
+
+
+def test_database_mode_without_path():
+    assert tree_paths(_parse()) is None
+
+
+def test_manifest_defaults_next_to_devices(tmp_path):
+    (tmp_path / "manifest.yaml").write_text("vendors: []\n")
+    devices, manifest = tree_paths(_parse("--path", str(tmp_path / "devices")))
+    assert manifest == tmp_path / "manifest.yaml"
+    assert devices == tmp_path / "devices"
+
+
+def test_missing_manifest(tmp_path):
+    options = _parse("--path", str(tmp_path / "devices"))
+    with pytest.raises(CommandError, match="Manifest not found"):
+        tree_paths(options)
+    assert tree_paths(options, must_exist=False)[1] == tmp_path / "manifest.yaml"
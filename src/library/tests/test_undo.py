@@ -0,0 +1,125 @@
+"""Session undo/redo of device edits, register changes and deletions."""
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.test import Client
+
+from library.models import DeviceHistory, LibraryVersionDevice, ModbusConfig, RegisterDefinition, Vendor, VendorModel
+from library.publish import publish_version
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def device():
+    vendor = Vendor.objects.create(name="Undo Vendor", slug="undo-vendor")
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number="UD-1", name="Meter", device_type="power_meter", technology="modbus",
+        description="Meter",
+    )
+    modbus = ModbusConfig.objects.create(device_type=device, function="holding", byte_order="big_endian")
+    RegisterDefinition.objects.create(modbus_config=modbus, field_name="energy", address=0, data_type="uint32")
+    return device
+
+
+@pytest.fixture
+def client():
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(username="undo-editor", password="x", role="editor"))
+    return client
+
+
+def _edit(client, device, **changes):
+    data = {
+        "vendor": device.vendor.pk, "model_number": device.model_number, "name": device.name,
+        "device_type": device.device_type, "technology": device.technology, "description": device.description,
+        "links": "[]",
+    }
+    data.update(changes)
+    assert client.post(f"/models/{device.pk}/edit/", data).status_code == 302
+
+
+def test_undo_and_redo_field_edit(client, device):
+    _edit(client, device, name="Renamed")
+    assert client.get(f"/models/{device.pk}/").context["undo_label"] == "Edit Undo Vendor UD-1"
+
+    response = client.post("/undo/")
+    assert response.url == f"/models/{device.pk}/"
+    device.refresh_from_db()
+    assert device.name == "Meter"
+    assert DeviceHistory.objects.filter(device=device).count() == 2  # the edit and its undo
+
+    client.post("/redo/")
+    device.refresh_from_db()
+    assert device.name == "Renamed"
+    assert client.post("/redo/").status_code == 302  # nothing left: an error message, no change
+    device.refresh_from_db()
+    assert device.name == "Renamed"
+
+
+def test_undo_register_changes(client, device):
+    register = device.modbus_config.register_definitions.get()
+    client.post(f"/registers/{register.pk}/delete/")
+    client.post(f"/models/{device.pk}/registers/create/", {
+        "field_name": "power", "address": 10, "data_type": "int32", "scale": 1, "offset": 0,
+    })
+    assert [r.field_name for r in device.modbus_config.register_definitions.all()] == ["power"]
+
+    client.post("/undo/")
+    client.post("/undo/")
+    assert list(device.modbus_config.register_definitions.values_list("pk", "field_name")) == [
+        (register.pk, "energy"),
+    ]
+
+
+def test_undo_snippet_insertion(client, device, tmp_path, settings):
+    settings.SNIPPETS_DIR = tmp_path
+    (tmp_path / "pair.yaml").write_text(
+        "name: Pair\nregister_definitions:\n"
+        "- field: {name: voltage_l1, unit: V}\n  address: 0\n  data_type: float32\n"
+        "- field: {name: current_l1, unit: A}\n  address: 2\n  data_type: float32\n"
+    )
+    client.post(f"/models/{device.pk}/registers/snippets/", {"snippet": "pair", "base_address": 10})
+    assert device.modbus_config.register_definitions.count() == 3
+    assert client.get(f"/models/{device.pk}/").context["undo_label"] == "Insert snippet pair into Undo Vendor UD-1"
+
+    client.post("/undo/")
+    assert list(device.modbus_config.register_definitions.values_list("field_name", flat=True)) == ["energy"]
+
+
+def test_undo_device_deletion_restores_it_in_place(client, device):
+    client.post(f"/models/{device.pk}/delete/")
+    assert not VendorModel.objects.filter(pk=device.pk).exists()
+
+    assert client.post("/undo/").url == f"/models/{device.pk}/"
+    restored = VendorModel.objects.get(pk=device.pk)
+    assert (restored.key, restored.modbus_config.function) == (device.key, "holding")
+    assert restored.modbus_config.register_definitions.get().field_name == "energy"
+
+
+def test_undo_device_deletion_relinks_history_and_versions(client, device):
+    publish_version(None)
+    client.post(f"/models/{device.pk}/delete/")
+    assert not DeviceHistory.objects.filter(device_id=device.pk).exists()
+
+    client.post("/undo/")
+    assert list(DeviceHistory.objects.filter(device=device).order_by("version").values_list("version", "action")) == [
+        (1, "created"), (2, "deleted"), (3, "created"),
+    ]
+    # The published entry points at the device again: the next version sees it modified, not removed and re-added.
+    assert LibraryVersionDevice.objects.get(library_version__version=1).device_type_id == device.pk
+    publish_version(None)
+    assert list(LibraryVersionDevice.objects.filter(library_version__version=2).values_list(
+        "device_type_id", "device_version", "change_type")) == [(device.pk, 3, "modified")]
+
+
+def test_undo_refuses_when_the_device_changed_since(client, device):
+    _edit(client, device, name="Renamed")
+    other = Client()
+    other.force_login(get_user_model().objects.create_user(username="undo-other", password="x", role="editor"))
+    _edit(other, VendorModel.objects.get(pk=device.pk), description="Edited elsewhere")
+
+    response = client.post("/undo/", follow=True)
+    assert "has changed since" in response.content.decode()
+    device.refresh_from_db()
+    assert (device.name, device.description) == ("Renamed", "Edited elsewhere")
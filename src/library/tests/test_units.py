@@ -0,0 +1,59 @@
+"""Approved unit list: lint suggestions and the register editor's unit options."""
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.contrib.messages import get_messages
+from django.test import Client
+
+from library.lint import DEFAULT_UNITS, LintConfig, LintDevice, approved_units, lint_devices, suggest_unit
+from library.models import ModbusConfig, Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+
+def test_suggest_unit():
+    assert suggest_unit("m3", DEFAULT_UNITS) == "m³"
+    assert suggest_unit("KWH", DEFAULT_UNITS) == "kWh"
+    assert suggest_unit("degC", DEFAULT_UNITS) == "°C"
+    assert suggest_unit("furlongs", DEFAULT_UNITS) is None
+    # An alias only counts when its target is approved.
+    assert suggest_unit("m3", ["m3/h", "kWh"]) == "m3/h"
+
+
+def test_lint_message_suggests_closest_unit():
+    device = {
+        "vendor_name": "Acme", "model_number": "U-1", "description": "x",
+        "technology_config": {
+            "technology": "modbus", "function": "holding", "byte_order": "big_endian", "word_order": "high_first",
+            "register_definitions": [{"field": {"name": "volume", "unit": "m3"}, "address": 0, "data_type": "uint32"}],
+        },
+        "processor_config": {"field_mappings": [{"source": "volume", "target": "water:total_volume"}]},
+    }
+    [finding] = [f for f in lint_devices([LintDevice(data=device)]) if f.rule == "unit-whitelist"]
+    assert finding.message == "Unit 'm3' is not in the approved unit list (did you mean 'm³'?)"
+
+    config = LintConfig.from_dict({"rules": {"unit-whitelist": {"extra_units": ["m3", "kWh"]}}})
+    units = approved_units(config.options("unit-whitelist"))
+    assert units[-1] == "m3" and units.count("kWh") == 1
+    assert "unit-whitelist" not in {f.rule for f in lint_devices([LintDevice(data=device)], config)}
+
+
+def test_register_editor_offers_and_checks_units():
+    vendor = Vendor.objects.create(name="Unit Vendor", slug="unit-vendor")
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number="UV-1", name="Meter", device_type="water_meter", technology="modbus",
+    )
+    ModbusConfig.objects.create(device_type=device, function="holding")
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(username="unit-editor", password="x", role="editor"))
+
+    content = client.get(f"/models/{device.pk}/registers/create/").content.decode()
+    assert 'list="approved-units"' in content
+    assert '<option value="m³"></option>' in content
+
+    response = client.post(f"/models/{device.pk}/registers/create/", {
+        "field_name": "volume", "field_unit": "m3", "address": 0, "data_type": "uint32", "scale": 1, "offset": 0,
+    })
+    assert response.status_code == 302
+    warnings = [str(m) for m in get_messages(response.wsgi_request)]
+    assert "Unit 'm3' is not in the approved unit list (did you mean 'm³'?)" in warnings
@@ -33,7 +33,7 @@ from library.models import (
     Vendor,
     VendorModel,
 )
-from library.unpublished import unpublished_changes_summary
+from library.unpublished import changed_files, unpublished_changes_summary
 
 pytestmark = pytest.mark.django_db
 User = get_user_model()
@@ -226,3 +226,45 @@ class TestBannerRendering:
         response = admin_session_client.get(reverse("library:dashboard"))
         body = response.content.decode()
         assert "unpublished change" not in body
+
+    def test_sign_out_form_lists_unpublished_changes(self, admin_session_client):
+        body = admin_session_client.get(reverse("library:dashboard")).content.decode()
+        assert 'id="signOutForm" data-unpublished="' in body
+        assert 'id="unpublishedChanges"' in body
+
+    def test_sign_out_form_unguarded_after_publish(self, admin_session_client):
+        _publish(admin_session_client)
+        body = admin_session_client.get(reverse("library:dashboard")).content.decode()
+        assert 'id="signOutForm">' in body
+        assert 'id="unpublishedChanges"' not in body
+
+
+class TestChangedFiles:
+    def test_model_changes_grouped_by_vendor_file(self, admin_session_client):
+        edited = _make_vendor_model("files-a")
+        gone = _make_vendor_model("files-b")
+        _publish(admin_session_client)
+
+        prev = snapshot_device(edited)
+        edited.description = "edited"
+        edited.save()
+        record_history(edited, DeviceHistory.Action.UPDATED, user=None, previous_snapshot=prev)
+        added = VendorModel.objects.create(
+            vendor=edited.vendor, model_number="M-new", name="New", device_type="water_meter",
+            technology=VendorModel.Technology.WMBUS,
+        )
+        record_history(added, DeviceHistory.Action.CREATED, user=None)
+        record_history(gone, DeviceHistory.Action.DELETED, user=None)
+        gone.delete()
+
+        files = {f.file: f for f in changed_files()}
+        assert set(files) == {"files-a.yaml", "files-b.yaml"}
+        assert files["files-a.yaml"].counts == {"added": 1, "modified": 1}
+        [modified] = [m for m in files["files-a.yaml"].models if m.entity.change_type == "modified"]
+        assert modified.fields == ["description"]
+        assert (modified.published_version, modified.latest_version) == (1, 2)
+        assert files["files-b.yaml"].counts == {"removed": 1}
+
+        body = admin_session_client.get(reverse("library:changes")).content.decode()
+        assert body.count("data-changed-file") == 2
+        assert "?from=1&to=2" in body
@@ -0,0 +1,69 @@
+"""Usage report from device history and the (opt-in) command audit trail."""
+
+import io
+import json
+from datetime import timedelta
+
+import pytest
+from django.core.management import call_command
+from django.utils import timezone
+
+from auditlog.models import AuditLog
+from library.history import record_history
+from library.models import DeviceHistory, ModbusConfig, RegisterDefinition, Vendor, VendorModel
+from library.usage import usage_report
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def device():
+    vendor = Vendor.objects.create(name="Usage Vendor", slug="usage-vendor")
+    vm = VendorModel.objects.create(
+        vendor=vendor, model_number="UV-1", name="UV-1", device_type="power_meter", technology="modbus",
+    )
+    modbus = ModbusConfig.objects.create(
+        device_type=vm, function="holding", byte_order="big_endian", word_order="high_first",
+    )
+    RegisterDefinition.objects.create(modbus_config=modbus, field_name="energy", address=0, data_type="uint32")
+    record_history(vm, DeviceHistory.Action.CREATED, None)
+    record_history(vm, DeviceHistory.Action.UPDATED, None)
+    return vm
+
+
+def _window():
+    now = timezone.now()
+    return now - timedelta(days=1), now + timedelta(minutes=1)
+
+
+def test_edits_are_counted_per_device_and_vendor(device):
+    report = usage_report(*_window())
+    assert report["totals"]["edits"] == 2
+    assert report["devices"][0]["device"] == "Usage Vendor UV-1"
+    assert report["vendors"] == [{"vendor": "Usage Vendor", "edits": 2, "devices_edited": 1, "validations": 0}]
+
+
+def test_cli_runs_are_only_logged_when_opted_in(device, settings, tmp_path):
+    settings.USAGE_TRACKING = False
+    call_command("lint_library", stdout=io.StringIO())
+    assert not AuditLog.objects.filter(category=AuditLog.Category.VALIDATION).exists()
+
+    settings.USAGE_TRACKING = True
+    call_command("lint_library", stdout=io.StringIO())
+    call_command("export_json", "-o", str(tmp_path / "library.json"), stdout=io.StringIO())
+
+    report = usage_report(*_window())
+    assert report["totals"]["validation_runs"] == 1
+    assert report["totals"]["exports"] == 1
+    assert report["devices"][0]["validations"] == 1
+
+
+def test_period_excludes_older_activity(device):
+    DeviceHistory.objects.update(created=timezone.now() - timedelta(days=60))
+    assert usage_report(*_window())["totals"]["edits"] == 0
+
+
+def test_command_json(device):
+    out = io.StringIO()
+    call_command("usage_report", "--format", "json", stdout=out)
+    assert json.loads(out.getvalue())["totals"]["devices_edited"] == 1
@@ -0,0 +1,206 @@
+"""Splitting and merging vendor files, and moving devices between vendors."""
+
+import pytest
+import yaml
+from django.core.management import call_command
+from django.core.management.base import CommandError
+
+from library.exporters import export_to_yaml
+from library.importers import import_from_yaml
+from library.models import Vendor, VendorModel
+from library.vendor_files import VendorFileConflict, VendorFileError, merge_vendor_files, move_device, split_vendor_file
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def tree(tmp_path):
+    acme = Vendor.objects.create(name="Split Acme", slug="split-acme")
+    for model, device_type in (("SA-1", "power_meter"), ("SA-2", "power_meter"), ("SA-3", "heat_meter")):
+        VendorModel.objects.create(
+            vendor=acme, model_number=model, name=model, device_type=device_type, technology="modbus",
+        )
+    export_to_yaml(tmp_path / "devices")
+    return tmp_path / "devices", tmp_path / "manifest.yaml"
+
+
+def _manifest_files(manifest_path):
+    return [v["file"] for v in yaml.safe_load(manifest_path.read_text())["vendors"]]
+
+
+def test_split_by_device_type(tree):
+    devices_path, manifest_path = tree
+    files = split_vendor_file(devices_path, manifest_path, "split-acme", by="device_type")
+
+    assert files == {"split-acme-heat-meter.yaml": 1, "split-acme-power-meter.yaml": 2}
+    assert _manifest_files(manifest_path) == list(files)
+    assert not (devices_path / "split-acme.yaml").exists()
+
+
+def test_split_tree_imports_as_one_vendor(tree):
+    split_vendor_file(*tree, "Split Acme")
+    stats = import_from_yaml(*tree)
+    assert stats["vendors_created"] == 0
+    assert Vendor.objects.get(slug="split-acme").device_types.count() == 3
+
+
+def test_merge_round_trips_split(tree):
+    devices_path, manifest_path = tree
+    split_vendor_file(devices_path, manifest_path, "split-acme")
+
+    target, count = merge_vendor_files(
+        devices_path, manifest_path,
+        ["split-acme-heat-meter.yaml", "split-acme-power-meter.yaml"], into="split-acme.yaml",
+    )
+
+    assert (target, count) == ("split-acme.yaml", 3)
+    assert _manifest_files(manifest_path) == ["split-acme.yaml"]
+    assert not (devices_path / "split-acme-heat-meter.yaml").exists()
+
+
+def test_split_and_merge_keep_manifest_entry_keys(tree):
+    devices_path, manifest_path = tree
+    manifest = yaml.safe_load(manifest_path.read_text())
+    manifest["vendors"][0]["aliases"] = ["Acme Split"]
+    manifest_path.write_text(yaml.dump(manifest))
+
+    split_vendor_file(devices_path, manifest_path, "split-acme")
+    entries = yaml.safe_load(manifest_path.read_text())["vendors"]
+    assert [e["aliases"] for e in entries] == [["Acme Split"], ["Acme Split"]]
+    assert [e["technologies"] for e in entries] == [["modbus"], ["modbus"]]
+
+    merge_vendor_files(devices_path, manifest_path, [e["file"] for e in entries], into="split-acme.yaml")
+    (entry,) = yaml.safe_load(manifest_path.read_text())["vendors"]
+    assert entry["aliases"] == ["Acme Split"]
+    assert entry["file"] == "split-acme.yaml"
+
+
+def test_merge_rejects_duplicates_and_other_vendors(tree, tmp_path):
+    devices_path, manifest_path = tree
+    split_vendor_file(devices_path, manifest_path, "split-acme")
+    power = devices_path / "split-acme-power-meter.yaml"
+    data = yaml.safe_load(power.read_text())
+    data["models"][0]["model_number"] = "SA-3"
+    power.write_text(yaml.dump(data))
+    with pytest.raises(VendorFileError, match="defined in both"):
+        merge_vendor_files(devices_path, manifest_path, ["split-acme-heat-meter.yaml", power.name])
+
+    manifest = yaml.safe_load(manifest_path.read_text())
+    manifest["vendors"][0]["name"] = "Someone Else"
+    manifest_path.write_text(yaml.dump(manifest))
+    with pytest.raises(VendorFileError, match="different vendors"):
+        merge_vendor_files(devices_path, manifest_path, ["split-acme-heat-meter.yaml", power.name])
+
+
+def test_split_command_dry_run(tree, capsys):
+    devices_path, manifest_path = tree
+    call_command("split_vendor", "split-acme", "--path", str(devices_path), "--dry-run")
+    assert "split-acme-power-meter.yaml" in capsys.readouterr().out
+    assert _manifest_files(manifest_path) == ["split-acme.yaml"]
+
+
+def test_split_command_unknown_vendor(tree):
+    with pytest.raises(CommandError, match="not in manifest"):
+        call_command("split_vendor", "nope", "--path", str(tree[0]))
+
+
+WM_1 = """\
+- vendor_name: Split Acme
+  model_number: WM-1
+  technology_config:
+    technology: wmbus
+    manufacturer_code: ACM
+"""
+HAND_EDITED = """\
+# Maintained by hand; keep the flagship first.
+models:
+# The flagship
+- vendor_name: Split Acme
+  model_number: EM340
+  description: 'no'
+  technology_config: {technology: modbus}
+""" + WM_1 + """\
+- vendor_name: Split Acme
+  model_number: EM112
+  technology_config: {technology: modbus}
+"""
+
+
+def test_split_by_family_and_merge_keep_device_text(tmp_path):
+    devices_path, manifest_path = tmp_path / "devices", tmp_path / "manifest.yaml"
+    devices_path.mkdir()
+    (devices_path / "split-acme.yaml").write_text(HAND_EDITED)
+    manifest_path.write_text(yaml.dump({"vendors": [{"name": "Split Acme", "file": "split-acme.yaml"}]}))
+
+    files = split_vendor_file(devices_path, manifest_path, "split-acme.yaml", by="family")
+    assert files == {"split-acme-em.yaml": 2, "split-acme-wm.yaml": 1}
+    em = (devices_path / "split-acme-em.yaml").read_text()
+    assert em == HAND_EDITED.replace(WM_1, "")
+    assert (devices_path / "split-acme-wm.yaml").read_text().endswith("models:\n" + WM_1)
+
+    merge_vendor_files(devices_path, manifest_path, list(files), into="split-acme.yaml")
+    assert (devices_path / "split-acme.yaml").read_text() == HAND_EDITED.replace(WM_1, "") + WM_1
+
+
+OTHER = """\
+models:
+  - vendor_name: Other Co
+    model_number: X-1
+    technology_config: {technology: lorawan}
+"""
+
+
+def test_move_device_between_vendor_files(tmp_path):
+    devices_path, manifest_path = tmp_path / "devices", tmp_path / "manifest.yaml"
+    devices_path.mkdir()
+    (devices_path / "split-acme.yaml").write_text(HAND_EDITED)
+    (devices_path / "other-co.yaml").write_text(OTHER)
+    manifest_path.write_text(yaml.dump({"vendors": [
+        {"name": "Split Acme", "file": "split-acme.yaml", "technologies": ["modbus", "wmbus"]},
+        {"name": "Other Co", "file": "other-co.yaml", "technologies": ["lorawan"]},
+    ]}))
+
+    assert move_device(devices_path, manifest_path, "wm-1", "other-co") == ("split-acme.yaml", "other-co.yaml")
+    assert (devices_path / "split-acme.yaml").read_text() == HAND_EDITED.replace(WM_1, "")
+    moved = (devices_path / "other-co.yaml").read_text()
+    assert moved == OTHER + "".join("  " + line for line in WM_1.replace("Split Acme", "Other Co").splitlines(True))
+    technologies = {v["name"]: v["technologies"] for v in yaml.safe_load(manifest_path.read_text())["vendors"]}
+    assert technologies == {"Split Acme": ["modbus"], "Other Co": ["lorawan", "wmbus"]}
+
+    move_device(devices_path, manifest_path, "EM112", "Other Co", copy=True)
+    assert "EM112" in (devices_path / "split-acme.yaml").read_text()
+    with pytest.raises(VendorFileConflict, match="already has EM112"):
+        move_device(devices_path, manifest_path, "EM112", "other-co", from_vendor="split-acme")
+    with pytest.raises(VendorFileError, match="several vendors"):
+        move_device(devices_path, manifest_path, "EM112", "split-acme")
+
+
+def test_move_device_command_in_database(tree):
+    other = Vendor.objects.create(name="Other Co", slug="other-co")
+    call_command("move_device", "SA-1", "--to", "other-co")
+    assert VendorModel.objects.get(model_number="SA-1").vendor == other
+
+    call_command("move_device", "SA-3", "--to", "Other Co", "--copy")
+    assert sorted(VendorModel.objects.filter(model_number="SA-3").values_list("vendor__slug", flat=True)) == [
+        "other-co", "split-acme",
+    ]
+    with pytest.raises(CommandError, match="several vendors"):
+        call_command("move_device", "SA-3", "--to", "split-acme")
+
+
+def test_add_vendor_then_device(tree):
+    devices_path, manifest_path = tree
+    call_command("add_vendor", "New Meters", "--technology", "wmbus", "--path", str(devices_path))
+
+    assert yaml.safe_load((devices_path / "new-meters.yaml").read_text()) == {"models": []}
+    entry = yaml.safe_load(manifest_path.read_text())["vendors"][-1]
+    assert entry == {"name": "New Meters", "file": "new-meters.yaml", "technologies": ["wmbus"]}
+    call_command(
+        "create_device", "--vendor", "New Meters", "--model", "NM-1", "--technology", "wmbus",
+        "--type", "water_meter", "--path", str(devices_path),
+    )
+    assert [m["model_number"] for m in yaml.safe_load((devices_path / "new-meters.yaml").read_text())["models"]] == [
+        "NM-1"
+    ]
+    with pytest.raises(CommandError, match="already in the manifest"):
+        call_command("add_vendor", "new meters", "--path", str(devices_path))
@@ -0,0 +1,71 @@
+"""verify_manifest: manifest entries vs. vendor files and their technologies."""
+
+import pytest
+import yaml
+from django.core.management import call_command
+from django.core.management.base import CommandError
+
+from library.doctor import check_manifest, fix_technologies
+from library.exporters import export_to_yaml
+from library.models import Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def tree(tmp_path):
+    vendor = Vendor.objects.create(name="Verify Vendor", slug="verify-vendor")
+    for model, technology in (("VV-1", "modbus"), ("VV-2", "wmbus")):
+        VendorModel.objects.create(
+            vendor=vendor, model_number=model, name=model, device_type="power_meter", technology=technology,
+        )
+    export_to_yaml(tmp_path / "devices")
+    return tmp_path / "devices", tmp_path / "manifest.yaml"
+
+
+def _manifest(path):
+    return yaml.safe_load(path.read_text())
+
+
+def _status(checks):
+    return {c.name: c.status for c in checks}
+
+
+def test_export_writes_technologies(tree):
+    entry = _manifest(tree[1])["vendors"][0]
+    assert entry["technologies"] == ["modbus", "wmbus"]
+    assert set(_status(check_manifest(tree[0], _manifest(tree[1]))).values()) == {"ok"}
+
+
+def test_mismatch_missing_and_orphans(tree):
+    devices_path, manifest_path = tree
+    manifest = _manifest(manifest_path)
+    manifest["vendors"][0]["technologies"] = ["modbus"]
+    manifest["vendors"].append({"name": "Gone", "file": "gone.yaml"})
+    (devices_path / "stray.yaml").write_text("models: []\n")
+
+    statuses = _status(check_manifest(devices_path, manifest))
+    assert statuses == {"dangling-entries": "error", "orphaned-files": "warning", "technologies": "error"}
+
+
+def test_missing_list_is_a_warning_and_fixable(tree):
+    devices_path, manifest_path = tree
+    manifest = _manifest(manifest_path)
+    del manifest["vendors"][0]["technologies"]
+    manifest_path.write_text(yaml.dump(manifest))
+    assert _status(check_manifest(devices_path, manifest))["technologies"] == "warning"
+
+    assert fix_technologies(devices_path, manifest_path) == ["verify-vendor.yaml"]
+    assert _manifest(manifest_path)["vendors"][0]["technologies"] == ["modbus", "wmbus"]
+
+
+def test_command_fails_then_fix(tree):
+    devices_path, manifest_path = tree
+    manifest = _manifest(manifest_path)
+    manifest["vendors"][0]["technologies"] = ["lorawan"]
+    manifest_path.write_text(yaml.dump(manifest))
+
+    with pytest.raises(CommandError, match="1 check"):
+        call_command("verify_manifest", "--path", str(devices_path))
+    call_command("verify_manifest", "--path", str(devices_path), "--fix")
+    assert _manifest(manifest_path)["vendors"][0]["technologies"] == ["modbus", "wmbus"]
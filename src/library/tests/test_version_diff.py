@@ -0,0 +1,66 @@
+"""Library diff between exported trees and published versions."""
+
+import io
+import json
+
+import pytest
+from django.core.management import call_command
+
+from library.exporters import export_to_yaml
+from library.models import ModbusConfig, RegisterDefinition, Vendor, VendorModel
+from library.version_diff import compare_snapshot_maps, yaml_snapshot_map
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def meter():
+    vendor = Vendor.objects.create(name="Diff Vendor", slug="diff-vendor")
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number="DV-1", name="Diff Meter", device_type="power_meter", technology="modbus",
+    )
+    modbus = ModbusConfig.objects.create(device_type=device)
+    RegisterDefinition.objects.create(modbus_config=modbus, field_name="voltage", field_unit="V", address=0, data_type="float32")
+    return device
+
+
+def test_yaml_trees_report_register_level_changes(tmp_path, meter):
+    export_to_yaml(tmp_path / "old" / "devices")
+
+    reg = meter.modbus_config.register_definitions.get()
+    reg.scale = 0.1
+    reg.save()
+    RegisterDefinition.objects.create(
+        modbus_config=meter.modbus_config, field_name="current", field_unit="A", address=2, data_type="float32",
+    )
+    VendorModel.objects.create(
+        vendor=meter.vendor, model_number="DV-2", name="New Meter", device_type="power_meter", technology="modbus",
+    )
+    export_to_yaml(tmp_path / "new" / "devices")
+
+    result = compare_snapshot_maps(yaml_snapshot_map(tmp_path / "old"), yaml_snapshot_map(tmp_path / "new"))
+
+    assert [e["label"] for e in result["added"]] == ["Diff Vendor DV-2"]
+    assert result["removed"] == []
+    [modified] = result["modified"]
+    registers = modified["diff"]["registers"]
+    assert [r["field_name"] for r in registers["added"]] == ["current"]
+    assert registers["modified"][0]["new"]["scale"] == 0.1
+
+
+def test_identical_trees_are_unchanged(tmp_path, meter):
+    export_to_yaml(tmp_path / "a" / "devices")
+    export_to_yaml(tmp_path / "b" / "devices")
+    result = compare_snapshot_maps(yaml_snapshot_map(tmp_path / "a"), yaml_snapshot_map(tmp_path / "b/devices"))
+    assert result["modified"] == [] and result["added"] == [] and result["removed"] == []
+    assert result["unchanged_count"] >= 1
+
+
+def test_command_json_output(tmp_path, meter):
+    export_to_yaml(tmp_path / "a" / "devices")
+    meter.delete()
+    export_to_yaml(tmp_path / "b" / "devices")
+
+    out = io.StringIO()
+    call_command("diff_versions", str(tmp_path / "a"), str(tmp_path / "b"), "--format", "json", stdout=out)
+    assert json.loads(out.getvalue())["removed"] == ["Diff Vendor DV-1"]
@@ -0,0 +1,123 @@
+"""Interactive add-device wizard driven by scripted answers."""
+
+import io
+
+import pytest
+import yaml
+from django.contrib.auth import get_user_model
+from django.core.management import call_command
+
+from auditlog.models import AuditLog
+from library.models import DeviceHistory, VendorModel
+from library.scaffold import ScaffoldError, append_to_tree, skeleton_device
+from library.wizard import DeviceWizard, blocking_findings, save_to_database, validate
+
+pytestmark = pytest.mark.django_db
+
+
+def _wizard(answers):
+    answers = iter(answers)
+    said = []
+    return DeviceWizard(ask=lambda prompt: next(answers), say=said.append), said
+
+
+MODBUS_ANSWERS = [
+    "Acme", "PM-1", "modbus", "power_meter", "", "Three-phase meter",
+    "holding", "big_endian", "high_first",
+    "0", "energy_total", "kWh", "0.01", "", "uint32",
+    "0",                                   # duplicate address → re-asked
+    "x", "10", "voltage_l1", "V", "", "", "float32",
+    "",
+]
+
+
+def test_modbus_flow():
+    wizard, said = _wizard(MODBUS_ANSWERS)
+    device = wizard.run()
+    tech = device["technology_config"]
+    assert device["name"] == "PM-1"
+    assert tech["function"] == "holding"
+    assert [(r["address"], r["field"]["name"], r["scale"]) for r in tech["register_definitions"]] == [
+        (0, "energy_total", 0.01), (10, "voltage_l1", 1.0),
+    ]
+    assert any("already defined" in s for s in said)
+    assert any("Invalid value" in s for s in said)
+
+
+def test_invalid_choice_is_reasked():
+    wizard, said = _wizard([
+        "Acme", "WM-1", "zigbee", "wmbus", "water_meter", "", "Water meter",
+        "kam", "1b", "7", "y", "",
+    ])
+    device = wizard.run()
+    assert device["technology_config"]["manufacturer_code"] == "KAM"
+    assert device["technology_config"]["encryption_required"] is True
+    assert any("Choose one of" in s for s in said)
+
+
+def test_modbus_needs_a_register_and_a_function():
+    wizard, said = _wizard([
+        "Acme", "PM-2", "modbus", "power_meter", "", "Meter",
+        "", "holding", "", "",                 # function is required; byte/word order default
+        "", "0", "energy_total", "kWh", "", "", "uint32", "",
+    ])
+    tech = wizard.run()["technology_config"]
+    assert (tech["function"], tech["byte_order"], tech["word_order"]) == ("holding", "big_endian", "high_first")
+    assert len(tech["register_definitions"]) == 1
+    assert "  At least one register is required." in said
+
+
+def test_lorawan_decoder(tmp_path):
+    wizard, _ = _wizard(["Acme", "LS-1", "lorawan", "environment_sensor", "", "Sensor", "", "", "lorawan_field_map"])
+    device = wizard.run()
+    assert device["processor_config"]["decoder_type"] == "lorawan_field_map"
+    assert "payload_codec" not in device["technology_config"]
+
+    codec = tmp_path / "codec.js"
+    codec.write_text("function decodeUplink(input) { return {data: {}}; }\n")
+    wizard, said = _wizard([
+        "Acme", "LS-2", "lorawan", "environment_sensor", "", "Sensor", "C", "", "", "", str(tmp_path / "missing.js"),
+        str(codec),
+    ])
+    device = wizard.run()
+    assert device["technology_config"]["payload_codec"]["format"] == "ttn_v3"
+    assert device["technology_config"]["payload_codec"]["script"].startswith("function decodeUplink")
+    assert any("can't read" in s for s in said)
+
+
+def test_empty_technology_config_blocks_saving():
+    device = skeleton_device("Acme", "PM-3", "modbus", "power_meter")
+    device["description"] = "Meter"
+    assert {(f.path, f.message) for f in blocking_findings(validate(device))} == {
+        ("technology_config.function", "modbus devices must declare function"),
+        ("technology_config.byte_order", "modbus devices must declare byte_order"),
+        ("technology_config.word_order", "modbus devices must declare word_order"),
+        ("technology_config.register_definitions", "modbus devices must declare register_definitions"),
+    }
+
+
+def test_saves_to_database_and_tree(tmp_path):
+    device = _wizard(MODBUS_ANSWERS)[0].run()
+    assert not [f for f in validate(device) if f.severity == "error"]
+
+    vm = save_to_database(device)
+    assert vm.modbus_config.register_definitions.count() == 2
+    assert VendorModel.objects.get(model_number="PM-1").description == "Three-phase meter"
+    with pytest.raises(ScaffoldError):
+        save_to_database(device)
+
+    (tmp_path / "manifest.yaml").write_text(yaml.dump({"vendors": []}))
+    path = append_to_tree(tmp_path / "devices", tmp_path / "manifest.yaml", device)
+    assert yaml.safe_load(path.read_text())["models"][0]["model_number"] == "PM-1"
+
+
+def test_command_records_the_user(monkeypatch):
+    user = get_user_model().objects.create_user(username="wizard-editor", password="x", role="editor")
+    answers = iter([*MODBUS_ANSWERS, "y"])
+    monkeypatch.setattr("builtins.input", lambda prompt: next(answers))
+    call_command("add_device", "--user", "wizard-editor", stdout=io.StringIO())
+
+    device = VendorModel.objects.get(model_number="PM-1")
+    assert DeviceHistory.objects.get(device=device).user == user
+    entry = AuditLog.objects.get(category=AuditLog.Category.DEVICE)
+    assert (entry.user, entry.action, entry.target_id) == (user, "created", device.pk)
@@ -0,0 +1,49 @@
+"""The YAML config editor on the control config form."""
+
+import pytest
+from django.contrib.auth import get_user_model
+from django.test import Client
+
+from library.forms import ControlConfigForm
+from library.models import ControlConfig, Vendor, VendorModel
+
+pytestmark = pytest.mark.django_db
+
+CONTROLS = """\
+- id: relay
+  label: Relay
+  kind: toggle
+"""
+
+
+@pytest.fixture
+def device():
+    vendor = Vendor.objects.create(name="YAML Vendor", slug="yaml-vendor")
+    return VendorModel.objects.create(
+        vendor=vendor, model_number="YM-1", name="Relay", device_type="power_meter", technology="modbus",
+    )
+
+
+def test_yaml_and_json_are_accepted():
+    form = ControlConfigForm({"controllable": "on", "controls": CONTROLS})
+    assert form.is_valid(), form.errors
+    assert form.cleaned_data["controls"] == [{"id": "relay", "label": "Relay", "kind": "toggle"}]
+    form = ControlConfigForm({"controls": '[{"id": "relay"}]'})
+    assert form.is_valid(), form.errors
+    assert form.cleaned_data["controls"] == [{"id": "relay"}]
+    assert ControlConfigForm({"controls": ""}).is_valid()
+
+
+def test_parse_error_names_line_and_column():
+    form = ControlConfigForm({"controls": "- id: relay\n  label: [Relay\n"})
+    assert not form.is_valid()
+    assert form.errors["controls"][0].startswith("Line 3, column 1: ")
+
+
+def test_form_shows_controls_as_yaml(device):
+    ControlConfig.objects.create(device_type=device, controllable=True, controls=[{"id": "relay", "label": "Relay"}])
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(username="yaml-editor", password="x", role="editor"))
+    content = client.get(f"/models/{device.pk}/control-config/edit/").content.decode()
+    assert "- id: relay\n  label: Relay" in content
+    assert "data-yaml-editor" in content
@@ -0,0 +1,88 @@
+"""Canonical YAML formatting of the exported tree (``fmt_yaml``) and the per-device YAML preview."""
+
+import pytest
+import yaml
+from django.contrib.auth import get_user_model
+from django.core.management import call_command
+from django.core.management.base import CommandError
+from django.test import Client
+
+from library.exporters import export_device_yaml, export_to_yaml
+from library.models import LoRaWANConfig, Vendor, VendorModel
+from library.yaml_content import check_file
+from library.yaml_format import format_tree
+
+pytestmark = pytest.mark.django_db
+
+
+@pytest.fixture
+def tree(tmp_path):
+    vendor = Vendor.objects.create(name="Fmt Vendor", slug="fmt-vendor")
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number="FV-1", name="Fmt", device_type="environment_sensor", technology="lorawan",
+    )
+    LoRaWANConfig.objects.create(device_type=device, payload_codec="function decodeUplink(input) {\n  return {};\n}\n")
+    export_to_yaml(tmp_path / "devices")
+    return tmp_path
+
+
+def test_export_is_already_canonical(tree):
+    assert format_tree(tree / "devices", tree / "manifest.yaml", check=True) == []
+
+
+def test_device_preview_matches_the_export(tree):
+    device = VendorModel.objects.get(model_number="FV-1")
+    assert export_device_yaml(device) == (tree / "devices" / "fmt-vendor.yaml").read_text()
+
+    client = Client()
+    client.force_login(get_user_model().objects.create_user(username="yaml-viewer", password="x"))
+    body = client.get(f"/models/{device.pk}/yaml/").content.decode()
+    assert "model_number: FV-1" in body
+    assert "fmt-vendor.yaml" in body
+
+
+def test_codec_is_a_literal_block(tree):
+    text = (tree / "devices" / "fmt-vendor.yaml").read_text()
+    assert "script: |" in text
+
+
+def test_check_flags_and_fmt_fixes_key_order(tree):
+    path = tree / "devices" / "fmt-vendor.yaml"
+    data = yaml.safe_load(path.read_text())
+    data["models"][0] = dict(reversed(list(data["models"][0].items())))
+    path.write_text(yaml.dump(data, sort_keys=False))
+
+    with pytest.raises(CommandError):
+        call_command("fmt_yaml", "--path", str(tree / "devices"), "--check")
+
+    call_command("fmt_yaml", "--path", str(tree / "devices"))
+    assert format_tree(tree / "devices", tree / "manifest.yaml", check=True) == []
+    assert next(iter(yaml.safe_load(path.read_text())["models"][0])) == "vendor_name"
+
+
+def test_fmt_quotes_values_yaml_would_misread(tree):
+    path = tree / "devices" / "fmt-vendor.yaml"
+    text = path.read_text().replace("model_number: FV-1", "model_number: 1234")
+    path.write_text(text.replace("description: ''", "description: no", 1))
+    problems = check_file(path)
+    assert [p.message.split(":")[0] for p in problems] == ["model_number", "description"]
+    assert all(p.fixable for p in problems)
+
+    call_command("fmt_yaml", "--path", str(tree / "devices"))
+    device = yaml.safe_load(path.read_text())["models"][0]
+    assert (device["model_number"], device["description"]) == ("1234", "no")
+    assert check_file(path) == []
+
+
+@pytest.mark.parametrize("content,message", [
+    ("description: café".encode("latin-1"), "Not UTF-8 (byte 0xe9)"),
+    (b"description: a\x07b", "Control character U+0007"),
+])
+def test_fmt_refuses_unreadable_content(tree, content, message):
+    path = tree / "devices" / "fmt-vendor.yaml"
+    path.write_bytes(path.read_bytes().replace(b"description: ''", content, 1))
+    [problem] = check_file(path)
+    assert message in problem.message and not problem.fixable
+
+    with pytest.raises(CommandError, match=f"line {problem.line}, column {problem.column}"):
+        call_command("fmt_yaml", "--path", str(tree / "devices"))
@@ -1,11 +1,13 @@
 """End-to-end YAML export → import round trip (schema-v4)."""
 
+import json
+
 import pytest
 import yaml
 
-from library.exporters import export_to_yaml
+from library.exporters import export_to_json, export_to_yaml
 from library.importers import import_from_yaml
-from library.models import Metric, ProcessorConfig, Vendor, VendorModel
+from library.models import Metric, ModbusConfig, ProcessorConfig, RegisterDefinition, Vendor, VendorModel
 
 pytestmark = pytest.mark.django_db
 
@@ -244,3 +246,117 @@ def test_import_falls_back_to_code_when_key_missing(tmp_path, gas_meter_type):
 
     vm = VendorModel.objects.get(vendor__slug="fallback", model_number="FB-1")
     assert vm.device_type_fk_id == gas_meter_type.id
+
+
+def test_json_bundle_inlines_vendor_models(tmp_path, water_meter_type):
+    """The JSON bundle is the manifest with each vendor's models inline."""
+    vendor = Vendor.objects.create(name="Bundle Vendor", slug="bundle-vendor")
+    for model_number in ("BV-2", "BV-1"):
+        VendorModel.objects.create(
+            vendor=vendor,
+            model_number=model_number,
+            name=model_number,
+            device_type="water_meter",
+            device_type_fk=water_meter_type,
+            technology=VendorModel.Technology.WMBUS,
+        )
+
+    output = tmp_path / "library.json"
+    bundle, stats = export_to_json(output)
+
+    assert json.loads(output.read_text()) == bundle
+    assert bundle["format_version"] == 1
+    assert bundle["schema_version"] == 4
+    assert {"metrics", "device_types", "vendors"} <= bundle.keys()
+    entry = next(v for v in bundle["vendors"] if v["slug"] == "bundle-vendor")
+    assert "file" not in entry
+    assert [m["model_number"] for m in entry["models"]] == ["BV-1", "BV-2"]
+    assert stats["devices_exported"] >= 1
+
+
+def test_import_limited_to_selected_vendors(tmp_path):
+    """``vendors=`` imports only the selected manifest entries; the rest are
+    reported as skipped and their files need not exist."""
+    manifest = {
+        "schema_version": 4,
+        "vendors": [
+            {"name": "Picked", "file": "picked.yaml"},
+            {"name": "Elsewhere", "file": "elsewhere.yaml"},
+        ],
+    }
+    devices_dir = tmp_path / "devices"
+    devices_dir.mkdir(parents=True)
+    (tmp_path / "manifest.yaml").write_text(yaml.dump(manifest))
+    (devices_dir / "picked.yaml").write_text(
+        yaml.dump({
+            "models": [
+                {
+                    "vendor_name": "Picked",
+                    "model_number": "PK-1",
+                    "name": "PK-1",
+                    "device_type": "water_meter",
+                    "technology_config": {"technology": "wmbus", "manufacturer_code": "PCK"},
+                },
+            ],
+        }),
+    )
+
+    stats = import_from_yaml(devices_dir, tmp_path / "manifest.yaml", vendors=["picked"])
+
+    assert VendorModel.objects.filter(vendor__slug="picked", model_number="PK-1").exists()
+    assert not Vendor.objects.filter(slug="elsewhere").exists()
+    assert stats["vendors_skipped"] == ["Elsewhere"]
+    assert stats["errors"] == []
+
+
+def test_import_clears_selected_vendors_by_manifest_entry(tmp_path):
+    """``clear`` with ``vendors`` wipes the rows the selected entries import
+    into, whether the selection names the vendor, its file or an alias."""
+    manifest = {
+        "schema_version": 4,
+        "vendors": [
+            {"name": "Cleared Vendor", "file": "cv.yaml"},
+            {"name": "Renamed Vendor", "file": "renamed.yaml", "aliases": ["Old Vendor"]},
+            {"name": "Kept Vendor", "file": "kept.yaml"},
+        ],
+    }
+    devices_dir = tmp_path / "devices"
+    devices_dir.mkdir(parents=True)
+    (tmp_path / "manifest.yaml").write_text(yaml.dump(manifest))
+    for file in ("cv.yaml", "renamed.yaml"):
+        (devices_dir / file).write_text(yaml.dump({"models": []}))
+    for name in ("Cleared Vendor", "Old Vendor", "Kept Vendor"):
+        vendor = Vendor.objects.create(name=name, slug=name.lower().replace(" ", "-"))
+        VendorModel.objects.create(
+            vendor=vendor, model_number="CL-1", name="CL-1", device_type="water_meter", technology="wmbus",
+        )
+
+    import_from_yaml(devices_dir, tmp_path / "manifest.yaml", clear=True, vendors=["cv", "Renamed Vendor"])
+
+    assert not VendorModel.objects.exclude(vendor__slug="kept-vendor").exists()
+    assert not Vendor.objects.filter(slug="old-vendor").exists()
+    assert VendorModel.objects.filter(vendor__slug="kept-vendor", model_number="CL-1").exists()
+
+
+def test_register_field_description_round_trips(tmp_path):
+    vendor = Vendor.objects.create(name="Doc Vendor", slug="doc-vendor")
+    device = VendorModel.objects.create(
+        vendor=vendor, model_number="HM-1", name="Heat", device_type="heat_meter", technology="modbus",
+    )
+    modbus = ModbusConfig.objects.create(device_type=device)
+    RegisterDefinition.objects.create(
+        modbus_config=modbus, field_name="t3", field_unit="°C", address=4, data_type="int16",
+        field_description="T3 supply temperature",
+    )
+    RegisterDefinition.objects.create(modbus_config=modbus, field_name="t4", address=5, data_type="int16")
+
+    export_to_yaml(tmp_path / "devices")
+    data = yaml.safe_load((tmp_path / "devices" / "doc-vendor.yaml").read_text())
+    fields = [r["field"] for r in data["models"][0]["technology_config"]["register_definitions"]]
+    assert fields[0]["description"] == "T3 supply temperature"
+    assert "description" not in fields[1]  # omitted when empty
+
+    Vendor.objects.all().delete()
+    import_from_yaml(tmp_path / "devices", tmp_path / "manifest.yaml")
+    reg = RegisterDefinition.objects.get(field_name="t3")
+    assert reg.field_description == "T3 supply temperature"
@@ -0,0 +1,160 @@
+"""Session undo/redo for device edits.
+
+Every editor view that changes a device — its form, a technology,
+processor, control or alarm config, a register added, edited or removed,
+or the device deleted — pushes an operation onto the user's session::
+
+    {"label", "device": pk, "before": state, "after": state, "version": n}
+
+A *state* is the device with its configs and registers serialized by
+``django.core.serializers`` (``None`` for a device that doesn't exist),
+so restoring one brings back every column, primary keys included — a
+deleted device comes back at the same URL. The state also lists the
+history entries and published version entries pointing at the device
+(just their keys): deleting the device unlinks them, and restoring it
+links them back, so its history and version log carry on where they were. ``version`` is the device's
+latest ``DeviceHistory`` version after the operation (``None`` once
+deleted): an undo or redo only applies while the device is still where
+the operation left it, so it never overwrites an edit made since, in
+another tab or by another user. Restores are recorded in the device
+history like any other edit.
+
+The log lives in the session (``UNDO_LIMIT`` operations), so it is per
+user and per login, and gone after signing out.
+"""
+
+from __future__ import annotations
+
+import json
+
+from django.core import serializers
+from django.core.serializers.json import DjangoJSONEncoder
+from django.db import IntegrityError, transaction
+
+from .history import record_history, snapshot_device
+from .models import DeviceHistory, LibraryVersionDevice, VendorModel
+
+UNDO_LIMIT = 50
+UNDO_KEY = "undo_stack"
+REDO_KEY = "redo_stack"
+
+# One-to-one configs serialized with the device (registers ride along with
+# the Modbus config).
+CONFIGS = ("modbus_config", "lorawan_config", "wmbus_config", "control_config", "processor_config", "alarm_config")
+
+# Rows that outlive the device (SET_NULL) and their key to it.
+LINKS = ((DeviceHistory, "device"), (LibraryVersionDevice, "device_type"))
+
+
+class UndoError(Exception):
+    pass
+
+
+def capture(device: VendorModel) -> str:
+    """Serialized state of ``device`` — take it before changing the device."""
+    objects = [VendorModel.objects.get(pk=device.pk)]
+    for name in CONFIGS:
+        config = getattr(objects[0], name, None)
+        if config is None:
+            continue
+        objects.append(config)
+        if name == "modbus_config":
+            objects.extend(config.register_definitions.all())
+    state = serializers.serialize("python", objects)
+    for model, key in LINKS:
+        state.extend(serializers.serialize("python", model.objects.filter(**{key: device.pk}), fields=[key]))
+    return json.dumps(state, cls=DjangoJSONEncoder)
+
+
+def _latest_version(pk) -> int | None:
+    if not VendorModel.objects.filter(pk=pk).exists():
+        return None
+    return DeviceHistory.objects.filter(device_id=pk).order_by("-version").values_list("version", flat=True).first()
+
+
+def push(request, label: str, pk, before: str | None) -> None:
+    """Record that device ``pk`` went from the ``before`` state to its
+    current one (deleted, when it no longer exists). Clears the redo stack."""
+    device = VendorModel.objects.filter(pk=pk).first()
+    operation = {
+        "label": label,
+        "device": str(pk),
+        "before": before,
+        "after": capture(device) if device else None,
+        "version": _latest_version(pk),
+    }
+    stack = request.session.get(UNDO_KEY, [])
+    request.session[UNDO_KEY] = [*stack, operation][-UNDO_LIMIT:]
+    request.session[REDO_KEY] = []
+
+
+def peek(request) -> tuple[str | None, str | None]:
+    """Labels of the next undo and redo, for the header buttons."""
+    undo, redo = request.session.get(UNDO_KEY), request.session.get(REDO_KEY)
+    return (undo[-1]["label"] if undo else None), (redo[-1]["label"] if redo else None)
+
+
+def undo(request) -> dict:
+    """Revert the last operation; returns it (with the device's pk)."""
+    return _move(request, UNDO_KEY, REDO_KEY, restore="before")
+
+
+def redo(request) -> dict:
+    """Re-apply the last undone operation."""
+    return _move(request, REDO_KEY, UNDO_KEY, restore="after")
+
+
+def _move(request, source: str, target: str, restore: str) -> dict:
+    stack = list(request.session.get(source, []))
+    if not stack:
+        raise UndoError(f"Nothing to {'undo' if source == UNDO_KEY else 'redo'}.")
+    operation = stack.pop()
+    request.session[source] = stack
+    if _latest_version(operation["device"]) != operation["version"]:
+        raise UndoError(f"{operation['label']!r} can't be reverted: the device has changed since.")
+    try:
+        _restore(operation["device"], operation[restore], request.user)
+    except IntegrityError as e:  # e.g. the model number has been taken since
+        raise UndoError(f"{operation['label']!r} can't be reverted: {e}") from e
+    version = _latest_version(operation["device"])
+    # The restore is a new history version of the state the device's next
+    # operation on this stack ended in (undo) or starts from (redo).
+    for entry in reversed(stack):
+        if entry["device"] == operation["device"]:
+            entry["version"] = version
+            break
+    request.session[source] = stack
+    operation = {**operation, "version": version}
+    request.session[target] = [*request.session.get(target, []), operation][-UNDO_LIMIT:]
+    return operation
+
+
+@transaction.atomic
+def _restore(pk: str, state: str | None, user) -> None:
+    device = VendorModel.objects.filter(pk=pk).first()
+    if state is None:
+        if device is not None:
+            record_history(device, DeviceHistory.Action.DELETED, user)
+            device.delete()
+        return
+    old_snapshot = snapshot_device(device) if device else None
+    if device is not None:
+        # Configs absent from the state must go; the rest are overwritten.
+        for name in CONFIGS:
+            config = getattr(device, name, None)
+            if config is not None:
+                config.delete()
+    links = {model: [] for model, _ in LINKS}
+    for obj in serializers.deserialize("json", state):
+        if type(obj.object) in links:
+            links[type(obj.object)].append(obj.object.pk)
+        else:
+            obj.save()
+    # Before recording the restore, so its version follows the relinked history.
+    for model, key in LINKS:
+        model.objects.filter(pk__in=links[model], **{f"{key}__isnull": True}).update(**{key: pk})
+    device = VendorModel.objects.get(pk=pk)
+    if old_snapshot is None:
+        record_history(device, DeviceHistory.Action.CREATED, user)
+    else:
+        record_history(device, DeviceHistory.Action.UPDATED, user, old_snapshot)
@@ -13,16 +13,22 @@ version pinned by the current LibraryVersion — plus rows added since
 the last publish and rows pinned by the manifest but no longer in the
 database (unpublished removals). The result feeds the global banner
 (via the context processor) and the /versions/ landing page.
+
+``changed_files`` regroups the model changes by the vendor file they land
+in on export, with the fields each edit touched, for the /changes/ page.
 """
 
 from __future__ import annotations
 
+from collections import Counter
 from collections.abc import Callable
 from dataclasses import dataclass, field
 from typing import Any
 
 from django.db.models import Max
 
+from .history import diff_snapshots
+
 from .models import (
     DeviceHistory,
     DeviceType,
@@ -33,6 +39,7 @@ from .models import (
     LibraryVersionMetric,
     Metric,
     MetricHistory,
+    Vendor,
     VendorModel,
 )
 
@@ -206,3 +213,64 @@ def unpublished_changes_summary() -> UnpublishedChangesSummary:
         metrics=metrics,
         device_types=device_types,
     )
+
+
+@dataclass
+class ChangedModel:
+    entity: UnpublishedEntity
+    fields: list[str] = field(default_factory=list)  # touched since the published version
+    published_version: int | None = None
+    latest_version: int | None = None
+
+
+@dataclass
+class ChangedFile:
+    """A vendor file of the exported tree (``<slug>.yaml``) and its unpublished model changes."""
+
+    vendor: str
+    slug: str  # "" once the vendor itself is gone
+    models: list[ChangedModel] = field(default_factory=list)
+
+    @property
+    def file(self) -> str:
+        return f"{self.slug}.yaml" if self.slug else ""
+
+    @property
+    def counts(self) -> dict[str, int]:
+        return dict(Counter(m.entity.change_type for m in self.models))
+
+
+def changed_files(summary: UnpublishedChangesSummary | None = None) -> list[ChangedFile]:
+    """Unpublished model changes grouped by vendor file, vendors A–Z."""
+    summary = summary or unpublished_changes_summary()
+    current = LibraryVersion.objects.filter(is_current=True).first()
+    pinned = dict(current.device_changes.values_list("device_type_id", "device_version")) if current else {}
+    ids = [e.pk for e in summary.models if e.pk]
+    devices = {str(d.pk): d for d in VendorModel.objects.select_related("vendor").filter(pk__in=ids)}
+
+    files: dict[str, ChangedFile] = {}
+    for entity in summary.models:
+        device = devices.get(entity.pk)
+        if device is not None:
+            vendor, slug = device.vendor.name, device.vendor.slug
+        else:
+            vendor = _removed_vendor(entity.label)
+            slug = Vendor.objects.filter(name=vendor).values_list("slug", flat=True).first() or ""
+        changed = ChangedModel(entity)
+        if device is not None and entity.change_type == "modified":
+            published = pinned.get(device.pk)
+            history = dict(device.history.filter(version__gte=published).values_list("version", "snapshot"))
+            if history:
+                changed.published_version, changed.latest_version = published, max(history)
+                changed.fields = sorted(diff_snapshots(history.get(published), history[max(history)]))
+        files.setdefault(vendor, ChangedFile(vendor, slug)).models.append(changed)
+    return [files[name] for name in sorted(files, key=str.lower)]
+
+
+def _removed_vendor(label: str) -> str:
+    """Vendor of a deleted model, from its last history snapshot."""
+    entry = (
+        DeviceHistory.objects.filter(device__isnull=True, device_label=label)
+        .order_by("-created").values_list("snapshot", flat=True).first()
+    )
+    return (entry or {}).get("vendor") or "Unknown vendor"
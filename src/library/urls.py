@@ -9,6 +9,10 @@ app_name = "library"
 urlpatterns = [
     # Dashboard
     path("", views.DashboardView.as_view(), name="dashboard"),
+    path("search/", views.QuickSearchView.as_view(), name="quick-search"),
+    path("jump/", views.ModelJumpView.as_view(), name="model-jump"),
+    path("undo/", views.UndoView.as_view(), name="undo"),
+    path("redo/", views.RedoView.as_view(), name="redo"),
     # Vendors
     path("vendors/", views.VendorListView.as_view(), name="vendor-list"),
     path("vendors/create/", views.VendorCreateView.as_view(), name="vendor-create"),
@@ -28,16 +32,43 @@ urlpatterns = [
     path("device-types/<uuid:pk>/", views.DeviceTypeDetailView.as_view(), name="devicetype-detail"),
     path("device-types/<uuid:pk>/edit/", views.DeviceTypeUpdateView.as_view(), name="devicetype-edit"),
     path("device-types/<uuid:pk>/delete/", views.DeviceTypeDeleteView.as_view(), name="devicetype-delete"),
+    path("device-types/<uuid:pk>/metrics/", views.DeviceTypeMetricsView.as_view(), name="devicetype-metrics"),
+    path(
+        "device-types/<uuid:pk>/metrics/<int:index>/edit/",
+        views.DeviceTypeMetricEditView.as_view(),
+        name="devicetype-metric-edit",
+    ),
+    path(
+        "device-types/<uuid:pk>/metrics/<int:index>/delete/",
+        views.DeviceTypeMetricDeleteView.as_view(),
+        name="devicetype-metric-delete",
+    ),
     path("device-types/<uuid:pk>/history/<int:version>/", views.DeviceTypeHistorySnapshotView.as_view(), name="devicetype-history-snapshot"),
     path("device-types/<uuid:pk>/history/diff/", views.DeviceTypeHistoryDiffView.as_view(), name="devicetype-history-diff"),
     # Models
     path("models/", views.VendorModelListView.as_view(), name="model-list"),
     path("models/create/", views.VendorModelCreateView.as_view(), name="model-create"),
+    path("models/paste/", views.VendorModelPasteView.as_view(), name="model-paste"),
+    path("models/bulk/", views.VendorModelBulkView.as_view(), name="model-bulk"),
+    path("models/replace/", views.FindReplaceView.as_view(), name="find-replace"),
+    path("models/lint-status/", views.ModelLintStatusView.as_view(), name="model-lint-status"),
+    path("models/problems/", views.ModelProblemsView.as_view(), name="model-problems"),
     path("models/<uuid:pk>/", views.VendorModelDetailView.as_view(), name="model-detail"),
     path("models/<uuid:pk>/edit/", views.VendorModelUpdateView.as_view(), name="model-edit"),
+    path("models/<uuid:pk>/yaml/", views.VendorModelYAMLView.as_view(), name="model-yaml"),
+    path("models/<uuid:pk>/problems/", views.ModelProblemsView.as_view(), name="model-problems"),
+    path("models/<uuid:pk>/duplicate/", views.VendorModelDuplicateView.as_view(), name="model-duplicate"),
     path("models/<uuid:pk>/delete/", views.VendorModelDeleteView.as_view(), name="model-delete"),
+    path("models/<uuid:pk>/revert/", views.VendorModelRevertView.as_view(), name="model-revert"),
     path("models/<uuid:pk>/history/<int:version>/", views.DeviceHistorySnapshotView.as_view(), name="model-history-snapshot"),
     path("models/<uuid:pk>/history/diff/", views.DeviceHistoryDiffView.as_view(), name="model-history-diff"),
+    # Drafts (scratchpad devices, not part of the library)
+    path("drafts/", views.DraftListView.as_view(), name="draft-list"),
+    path("drafts/create/", views.DraftCreateView.as_view(), name="draft-create"),
+    path("drafts/<uuid:pk>/", views.DraftUpdateView.as_view(), name="draft-edit"),
+    path("drafts/<uuid:pk>/technology/", views.DraftTechnologyView.as_view(), name="draft-technology"),
+    path("drafts/<uuid:pk>/file/", views.DraftFileView.as_view(), name="draft-file"),
+    path("drafts/<uuid:pk>/delete/", views.DraftDeleteView.as_view(), name="draft-delete"),
     # wM-Bus Mapping Table
     path("wmbus-mappings/", views.WMBusMappingView.as_view(), name="wmbus-mappings"),
     # Modbus Config
@@ -52,6 +83,11 @@ urlpatterns = [
         views.ControlConfigUpdateView.as_view(),
         name="control-config-edit",
     ),
+    path(
+        "models/<uuid:device_pk>/control-config/controls/",
+        views.ControlEditorView.as_view(),
+        name="control-editor",
+    ),
     # wM-Bus Config
     path(
         "models/<uuid:device_pk>/wmbus-config/edit/",
@@ -64,6 +100,16 @@ urlpatterns = [
         views.LoRaWANConfigUpdateView.as_view(),
         name="lorawan-config-edit",
     ),
+    path(
+        "models/<uuid:device_pk>/lorawan-config/fetch-codec/",
+        views.LoRaWANCodecFetchView.as_view(),
+        name="lorawan-codec-fetch",
+    ),
+    path(
+        "models/<uuid:device_pk>/lorawan-config/codec/",
+        views.LoRaWANCodecView.as_view(),
+        name="lorawan-codec",
+    ),
     # Processor Config
     path(
         "models/<uuid:device_pk>/processor-config/edit/",
@@ -83,11 +129,46 @@ urlpatterns = [
         views.RegisterCreateView.as_view(),
         name="register-create",
     ),
+    path(
+        "models/<uuid:device_pk>/registers/export/",
+        views.RegisterExportView.as_view(),
+        name="register-export",
+    ),
+    path(
+        "models/<uuid:device_pk>/registers/import/",
+        views.RegisterImportView.as_view(),
+        name="register-import",
+    ),
+    path(
+        "models/<uuid:device_pk>/registers/duplicates/",
+        views.RegisterDuplicatesView.as_view(),
+        name="register-duplicates",
+    ),
+    path(
+        "models/<uuid:device_pk>/registers/snippets/",
+        views.RegisterSnippetView.as_view(),
+        name="register-snippets",
+    ),
+    path(
+        "models/<uuid:device_pk>/registers/copy/",
+        views.RegisterCopyView.as_view(),
+        name="register-copy",
+    ),
+    path(
+        "models/<uuid:device_pk>/registers/paste/",
+        views.RegisterPasteView.as_view(),
+        name="register-paste",
+    ),
     path(
         "registers/<uuid:pk>/edit/",
         views.RegisterUpdateView.as_view(),
         name="register-edit",
     ),
+    path(
+        "registers/<uuid:pk>/cell/",
+        views.RegisterCellView.as_view(),
+        name="register-cell",
+    ),
     path(
         "registers/<uuid:pk>/delete/",
         views.RegisterDeleteView.as_view(),
@@ -98,6 +179,7 @@ urlpatterns = [
     path("export/", views.ExportView.as_view(), name="export"),
     path("export/download/", views.ExportDownloadView.as_view(), name="export-download"),
     # Versions
+    path("changes/", views.UnpublishedChangesView.as_view(), name="changes"),
     path("versions/", views.VersionListView.as_view(), name="version-list"),
     path("versions/compare/", views.VersionCompareView.as_view(), name="version-compare"),
     path("versions/create/", views.VersionCreateView.as_view(), name="version-create"),
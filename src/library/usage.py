@@ -0,0 +1,74 @@
+"""Local usage report built from the audit trail.
+
+Summarises, for a period, which devices were edited (``DeviceHistory``),
+validated (``lint_library`` runs) and how often the library was exported
+or imported (``AuditLog``), so maintainers can see which vendors get the
+most attention. CLI lint/export runs only show up when
+``settings.USAGE_TRACKING`` is on; web edits, imports and exports are
+always audited. Nothing leaves the database — the report is printed
+locally. (The library has no probe step, so there is nothing to count
+for probing.)
+"""
+
+from __future__ import annotations
+
+from collections import defaultdict
+from datetime import datetime
+
+from auditlog.models import AuditLog
+
+from .models import DeviceHistory
+
+
+def usage_report(since: datetime, until: datetime, top: int | None = None) -> dict:
+    devices: dict[str, dict] = {}
+
+    def row(vendor: str, model: str) -> dict:
+        label = f"{vendor} {model}".strip()
+        return devices.setdefault(label, {
+            "device": label, "vendor": vendor or "—", "edits": 0, "editors": set(), "validations": 0,
+            "last_edited": None,
+        })
+
+    history = DeviceHistory.objects.filter(created__gte=since, created__lt=until).select_related("user")
+    for entry in history.order_by("created"):
+        snapshot = entry.snapshot or {}
+        r = row(snapshot.get("vendor") or "", snapshot.get("model_number") or entry.device_label)
+        r["edits"] += 1
+        if entry.user:
+            r["editors"].add(entry.user.username)
+        r["last_edited"] = entry.created.isoformat()
+
+    logs = AuditLog.objects.filter(created__gte=since, created__lt=until)
+    validation_runs = 0
+    for log in logs.filter(category=AuditLog.Category.VALIDATION):
+        validation_runs += 1
+        for vendor, model in (log.details or {}).get("devices", []):
+            row(vendor, model)["validations"] += 1
+
+    per_vendor: dict[str, dict] = defaultdict(lambda: {"edits": 0, "devices_edited": 0, "validations": 0})
+    for r in devices.values():
+        v = per_vendor[r["vendor"]]
+        v["edits"] += r["edits"]
+        v["devices_edited"] += 1 if r["edits"] else 0
+        v["validations"] += r["validations"]
+        r["editors"] = len(r["editors"])
+
+    def activity(item: dict) -> tuple:
+        return (-(item["edits"] + item["validations"]), item.get("device") or item.get("vendor"))
+
+    device_rows = sorted(devices.values(), key=activity)
+    vendor_rows = sorted(({"vendor": name, **counts} for name, counts in per_vendor.items()), key=activity)
+    return {
+        "since": since.isoformat(),
+        "until": until.isoformat(),
+        "totals": {
+            "edits": sum(r["edits"] for r in device_rows),
+            "devices_edited": sum(1 for r in device_rows if r["edits"]),
+            "validation_runs": validation_runs,
+            "exports": logs.filter(category=AuditLog.Category.EXPORT).count(),
+            "imports": logs.filter(category=AuditLog.Category.IMPORT).count(),
+        },
+        "vendors": vendor_rows,
+        "devices": device_rows[:top] if top else device_rows,
+    }
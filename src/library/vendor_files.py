@@ -0,0 +1,431 @@
+"""Split a vendor file into several, merge vendor files back together,
+move a device from one vendor's file to another's, or start a file for a
+new vendor.
+
+A manifest may list the same vendor more than once, each entry pointing at
+its own file — the importer resolves every entry to the same vendor row.
+That lets a vendor with hundreds of models be reviewed as one file per
+device type (or technology) without changing what gets imported.
+
+Both operations write through a ``safe_write.TreeWriter``: new vendor files
+first, then the manifest, and only then are superseded files removed, so
+an interrupted run never leaves the manifest pointing at a missing file.
+
+Devices move as text: each device's block in its source file — comments
+directly above it included — is copied byte for byte (re-indented only
+when the target lists devices at another indentation), so a
+reorganisation diff shows moved lines rather than reformatted ones. A
+file whose device list isn't a plain block sequence is re-serialized
+canonically instead. A device moved to another vendor keeps its text too,
+with only its ``vendor_name`` line rewritten.
+"""
+
+from __future__ import annotations
+
+import re
+from dataclasses import dataclass
+from pathlib import Path
+
+import yaml
+from django.utils.text import slugify
+
+from .safe_write import TreeWriter
+from .yaml_format import canonical_manifest, canonical_vendor_file, device_technologies, dump_yaml
+
+SPLIT_BY = ("device_type", "technology", "family")
+
+
+class VendorFileError(Exception):
+    pass
+
+
+class VendorFileNotFound(VendorFileError):
+    """The vendor or file isn't in the manifest / on disk."""
+
+
+class VendorFileConflict(VendorFileError):
+    """A target file already exists or a model is defined twice."""
+
+
+def model_family(model_number: str) -> str:
+    """The family a model number belongs to: its leading letters
+    (``EM340``, ``EM-112-DIN`` → ``EM``), else its first segment."""
+    model_number = (model_number or "").strip()
+    if match := re.match(r"[A-Za-z]+", model_number):
+        return match.group(0).upper()
+    return re.split(r"[-_ /.]", model_number, maxsplit=1)[0]
+
+
+def _split_key(device: dict, by: str) -> str:
+    if by == "technology":
+        return (device.get("technology_config") or {}).get("technology") or ""
+    if by == "family":
+        return model_family(str(device.get("model_number") or ""))
+    return device.get(by) or ""
+
+
+@dataclass
+class _Device:
+    data: dict
+    text: str | None  # the device's block in its source file
+    indent: int  # column of its "- "
+
+
+@dataclass
+class _SourceFile:
+    devices_key: str
+    devices: list[_Device]
+    header: str  # text before the first device, "models:" included
+    footer: str  # text after the device list
+
+
+def _line_indent(line: str) -> int:
+    return len(line) - len(line.lstrip(" "))
+
+
+def _blocks(text: str, devices_key: str, count: int) -> tuple[str, list[tuple[str, int]], str] | None:
+    """``(header, [(device text, indent)], footer)`` of a vendor file whose
+    device list is a block sequence, else None."""
+    try:
+        root = yaml.compose(text, Loader=yaml.SafeLoader)
+    except yaml.YAMLError:
+        return None
+    if not isinstance(root, yaml.MappingNode):
+        return None
+    seq = next((v for k, v in root.value if k.value == devices_key), None)
+    if not isinstance(seq, yaml.SequenceNode) or seq.flow_style or len(seq.value) != count or not count:
+        return None
+
+    lines = text.splitlines(keepends=True)
+    offsets = [0]
+    for line in lines:
+        offsets.append(offsets[-1] + len(line))
+    starts, indents = [], []
+    for item in seq.value:
+        line_no = item.start_mark.line
+        dash = lines[line_no]
+        if not dash.lstrip(" ").startswith("-"):
+            return None
+        indent = _line_indent(dash)
+        # Comments right above a device travel with it.
+        while line_no > 0 and lines[line_no - 1].lstrip(" ").startswith("#"):
+            if _line_indent(lines[line_no - 1]) != indent:
+                break
+            line_no -= 1
+        starts.append(line_no)
+        indents.append(indent)
+    if seq.end_mark.column != 0 and seq.end_mark.index != len(text):
+        return None
+    end = seq.end_mark.index if seq.end_mark.column == 0 else len(text)
+    bounds = [offsets[n] for n in starts] + [end]
+    blocks = []
+    for i, indent in enumerate(indents):
+        block = text[bounds[i]:bounds[i + 1]]
+        blocks.append((block if block.endswith("\n") else block + "\n", indent))
+    return text[:bounds[0]], blocks, text[end:]
+
+
+def _load(devices_path: Path, entry: dict) -> _SourceFile:
+    path = devices_path / entry["file"]
+    if not path.exists():
+        raise VendorFileNotFound(f"File not found: {path}")
+    text = path.read_text()
+    data = yaml.safe_load(text) or {}
+    devices_key = "models" if "models" in data else "device_types"
+    devices = list(data.get(devices_key) or [])
+    parsed = _blocks(text, devices_key, len(devices))
+    if parsed is None:
+        return _SourceFile(devices_key, [_Device(d, None, 0) for d in devices], f"{devices_key}:\n", "")
+    header, blocks, footer = parsed
+    return _SourceFile(
+        devices_key, [_Device(d, block, indent) for d, (block, indent) in zip(devices, blocks)], header, footer,
+    )
+
+
+def _reindent(block: str, by: int) -> str:
+    if by > 0:
+        return "".join(" " * by + line if line.strip() else line for line in block.splitlines(keepends=True))
+    return "".join(line[min(-by, _line_indent(line)):] for line in block.splitlines(keepends=True))
+
+
+def _find_entries(vendors: list[dict], vendor: str) -> list[dict]:
+    """Manifest entries of ``vendor`` — a name, slug or one of its files."""
+    slug = slugify(vendor)
+    return [v for v in vendors if v["file"] == Path(vendor).name] or [
+        v for v in vendors if slugify(v.get("name", "")) == slug or slugify(Path(v["file"]).stem) == slug
+    ]
+
+
+def _render(source: _SourceFile, devices: list[_Device]) -> str:
+    """The vendor file listing ``devices`` under ``source``'s header and
+    footer, devices copied verbatim where their text is known."""
+    expected = [d.data for d in devices]
+    if devices and all(d.text is not None for d in devices):
+        indent = next((d.indent for d in source.devices if d.text is not None), devices[0].indent)
+        body = "".join(_reindent(d.text, indent - d.indent) if d.indent != indent else d.text for d in devices)
+        if not source.footer:
+            body = body.rstrip("\n") + "\n"  # blank lines that separated devices don't end the file
+        content = source.header + body + source.footer
+        try:
+            if (yaml.safe_load(content) or {}).get(source.devices_key) == expected:
+                return content
+        except yaml.YAMLError:
+            pass
+    return dump_yaml(canonical_vendor_file({source.devices_key: expected}))
+
+
+def _shared_entry(entries: list[dict]) -> dict:
+    """The first of a vendor's manifest ``entries`` with the ``aliases`` of
+    all of them."""
+    entry = dict(entries[0])
+    aliases = [a for e in entries for a in e.get("aliases") or []]
+    if aliases:
+        entry["aliases"] = list(dict.fromkeys(aliases))
+    return entry
+
+
+def split_vendor_file(
+    devices_path: str | Path,
+    manifest_path: str | Path,
+    vendor: str,
+    by: str = "device_type",
+    writer: TreeWriter | None = None,
+) -> dict[str, int]:
+    """Split the vendor's file(s) into one file per ``by`` value.
+
+    ``vendor`` is the vendor's name or slug, or one of its files. Returns
+    ``{file name: device count}`` for the new files.
+    """
+    if by not in SPLIT_BY:
+        raise VendorFileError(f"Cannot split by {by!r} (choose from {', '.join(SPLIT_BY)})")
+    devices_path, manifest_path = Path(devices_path), Path(manifest_path)
+    writer = writer or TreeWriter()
+    manifest = yaml.safe_load(manifest_path.read_text()) or {}
+    vendors = manifest.get("vendors") or []
+
+    entries = _find_entries(vendors, vendor)
+    if not entries:
+        raise VendorFileNotFound(f"Vendor not in manifest: {vendor}")
+    name = entries[0]["name"]
+
+    groups: dict[str, list[_Device]] = {}
+    sources = [_load(devices_path, entry) for entry in entries]
+    for source in sources:
+        for device in source.devices:
+            groups.setdefault(_split_key(device.data, by), []).append(device)
+
+    base = slugify(name)
+    files = {f"{base}-{slugify(key) or 'other'}.yaml": devices for key, devices in sorted(groups.items())}
+    old_files = {e["file"] for e in entries}
+    clashes = [f for f in files if f not in old_files and (devices_path / f).exists()]
+    if clashes:
+        raise VendorFileConflict(f"File already exists: {devices_path / clashes[0]}")
+
+    for file_name, devices in files.items():
+        writer.write(devices_path / file_name, _render(sources[0], devices))
+
+    position = vendors.index(entries[0])
+    rest = [v for v in vendors if v not in entries]
+    # Every new entry keeps what the vendor's entries said (aliases included).
+    shared = _shared_entry(entries)
+    manifest["vendors"] = rest[:position] + [
+        {**shared, "file": f, "technologies": device_technologies([d.data for d in devices])}
+        for f, devices in files.items()
+    ] + rest[position:]
+    writer.write(manifest_path, dump_yaml(canonical_manifest(manifest)))
+
+    for file_name in sorted(old_files - set(files)):
+        writer.remove(devices_path / file_name)
+    return {f: len(devices) for f, devices in files.items()}
+
+
+def merge_vendor_files(
+    devices_path: str | Path,
+    manifest_path: str | Path,
+    files: list[str],
+    into: str | None = None,
+    writer: TreeWriter | None = None,
+) -> tuple[str, int]:
+    """Merge vendor ``files`` (all of one vendor) into a single file.
+
+    The result goes to ``into`` (default: the first file). Returns
+    ``(file name, device count)``.
+    """
+    devices_path, manifest_path = Path(devices_path), Path(manifest_path)
+    writer = writer or TreeWriter()
+    manifest = yaml.safe_load(manifest_path.read_text()) or {}
+    vendors = manifest.get("vendors") or []
+
+    names = [Path(f).name for f in files]
+    if len(set(names)) < 2:
+        raise VendorFileError("Need at least two different files to merge")
+    by_file = {v["file"]: v for v in vendors}
+    missing = [n for n in names if n not in by_file]
+    if missing:
+        raise VendorFileNotFound(f"Not in manifest: {', '.join(missing)}")
+    entries = [by_file[n] for n in names]
+    if len({slugify(e["name"]) for e in entries}) > 1:
+        raise VendorFileError(
+            f"Files belong to different vendors: {', '.join(sorted({e['name'] for e in entries}))}"
+        )
+
+    target = Path(into).name if into else names[0]
+    if target not in names and (devices_path / target).exists():
+        raise VendorFileConflict(f"File already exists: {devices_path / target}")
+
+    merged: list[_Device] = []
+    seen: dict[str, str] = {}
+    sources = {entry["file"]: _load(devices_path, entry) for entry in entries}
+    for entry in entries:
+        for device in sources[entry["file"]].devices:
+            model = str(device.data.get("model_number") or "").strip().lower()
+            if model in seen:
+                raise VendorFileConflict(
+                    f"{device.data.get('model_number')} is defined in both {seen[model]} and {entry['file']}"
+                )
+            seen[model] = entry["file"]
+            merged.append(device)
+
+    # The merged file keeps the target's header (comments, key) if it was one of the inputs.
+    writer.write(devices_path / target, _render(sources.get(target, sources[names[0]]), merged))
+
+    keep = entries[0]
+    keep.update(_shared_entry(entries), file=target)
+    if any("technologies" in e for e in entries):
+        keep["technologies"] = device_technologies([d.data for d in merged])
+    manifest["vendors"] = [v for v in vendors if v is keep or v not in entries]
+    writer.write(manifest_path, dump_yaml(canonical_manifest(manifest)))
+
+    for name in sorted(set(names) - {target}):
+        writer.remove(devices_path / name)
+    return target, len(merged)
+
+
+def _model_key(device: dict) -> str:
+    return str(device.get("model_number") or "").strip().lower()
+
+
+def _with_vendor_name(device: _Device, name: str) -> _Device:
+    """``device`` filed under vendor ``name``: only the ``vendor_name`` line
+    of its text changes (the text is dropped if it has none)."""
+    data = {**device.data, "vendor_name": name}
+    if device.text is None:
+        return _Device(data, None, device.indent)
+    line = yaml.safe_dump({"vendor_name": name}, allow_unicode=True, width=1000).rstrip("\n")
+    text, count = re.subn(
+        r"^(\s*(?:- )?)vendor_name:.*$", lambda m: m.group(1) + line, device.text, count=1, flags=re.MULTILINE,
+    )
+    return _Device(data, text if count else None, device.indent)
+
+
+def move_device(
+    devices_path: str | Path,
+    manifest_path: str | Path,
+    model_number: str,
+    to_vendor: str,
+    from_vendor: str | None = None,
+    copy: bool = False,
+    writer: TreeWriter | None = None,
+) -> tuple[str, str]:
+    """Move (or ``copy``) a device into another vendor's file.
+
+    ``to_vendor`` / ``from_vendor`` are vendor names or slugs, or one of
+    their files; the device goes to the target vendor's first file and
+    its ``vendor_name`` follows. ``from_vendor`` is only needed when the
+    model number exists under several vendors. Returns ``(source file,
+    target file)``.
+    """
+    devices_path, manifest_path = Path(devices_path), Path(manifest_path)
+    writer = writer or TreeWriter()
+    manifest = yaml.safe_load(manifest_path.read_text()) or {}
+    vendors = manifest.get("vendors") or []
+
+    targets = _find_entries(vendors, to_vendor)
+    if not targets:
+        raise VendorFileNotFound(f"Vendor not in manifest: {to_vendor}")
+    candidates = vendors
+    if from_vendor:
+        candidates = _find_entries(vendors, from_vendor)
+        if not candidates:
+            raise VendorFileNotFound(f"Vendor not in manifest: {from_vendor}")
+    sources = {entry["file"]: _load(devices_path, entry) for entry in {e["file"]: e for e in candidates}.values()}
+
+    model = model_number.strip().lower()
+    found = [
+        (file, i)
+        for file, source in sources.items()
+        for i, device in enumerate(source.devices)
+        if _model_key(device.data) == model
+    ]
+    if not found:
+        raise VendorFileNotFound(f"Device not found: {model_number}")
+    owners = {next(e["name"] for e in vendors if e["file"] == file) for file, _ in found}
+    if len(owners) > 1:
+        raise VendorFileError(
+            f"{model_number} exists under several vendors ({', '.join(sorted(owners))}); say which with from_vendor"
+        )
+    source_file, index = found[0]
+    target_name = targets[0]["name"]
+    if slugify(target_name) == slugify(owners.pop()):
+        raise VendorFileError(f"{model_number} is already filed under {target_name}")
+    for entry in targets:
+        if entry["file"] not in sources:
+            sources[entry["file"]] = _load(devices_path, entry)
+        if any(_model_key(d.data) == model for d in sources[entry["file"]].devices):
+            raise VendorFileConflict(f"{target_name} already has {model_number} (in {entry['file']})")
+
+    source, target_file = sources[source_file], targets[0]["file"]
+    target = sources[target_file]
+    changed = {target_file: [*target.devices, _with_vendor_name(source.devices[index], target_name)]}
+    if not copy:
+        changed[source_file] = source.devices[:index] + source.devices[index + 1:]
+    for file, devices in changed.items():
+        writer.write(devices_path / file, _render(sources[file], devices))
+
+    entries = [e for e in vendors if e["file"] in changed and "technologies" in e]
+    for entry in entries:
+        entry["technologies"] = device_technologies([d.data for d in changed[entry["file"]]])
+    if entries:
+        writer.write(manifest_path, dump_yaml(canonical_manifest(manifest)))
+    return source_file, target_file
+
+
+def add_vendor(
+    devices_path: str | Path,
+    manifest_path: str | Path,
+    name: str,
+    file: str | None = None,
+    technologies: list[str] | tuple[str, ...] = (),
+    writer: TreeWriter | None = None,
+) -> str:
+    """Create an empty vendor file for ``name`` and append its manifest
+    entry; returns the file name (default ``<slug>.yaml``).
+
+    ``technologies`` is recorded on the entry up front; adding devices
+    (``create_device --path``) keeps it in step with the file afterwards.
+    """
+    devices_path, manifest_path = Path(devices_path), Path(manifest_path)
+    writer = writer or TreeWriter()
+    name = name.strip()
+    if not slugify(name):
+        raise VendorFileError(f"Not a usable vendor name: {name!r}")
+    file = (file or "").strip() or f"{slugify(name)}.yaml"
+    if Path(file).name != file or not file.endswith((".yaml", ".yml")):
+        raise VendorFileError(f"Vendor file must be a .yaml file name without directories: {file}")
+
+    manifest = yaml.safe_load(manifest_path.read_text()) or {}
+    vendors = manifest.setdefault("vendors", [])
+    existing = [v for v in vendors if slugify(v.get("name", "")) == slugify(name)]
+    if existing:
+        raise VendorFileConflict(f"{existing[0]['name']} is already in the manifest ({existing[0]['file']})")
+    if any(v["file"] == file for v in vendors) or (devices_path / file).exists():
+        raise VendorFileConflict(f"{file} already exists")
+
+    entry = {"name": name, "file": file}
+    if technologies:
+        entry["technologies"] = sorted(set(technologies))
+    vendors.append(entry)
+    writer.write(devices_path / file, dump_yaml({"models": []}))
+    writer.write(manifest_path, dump_yaml(canonical_manifest(manifest)))
+    return file
+
@@ -0,0 +1,167 @@
+"""Compare two library states — published versions or exported YAML trees.
+
+Both sides are reduced to a map of ``{identity: {"label", "version",
+"snapshot"}}`` where ``snapshot`` has the ``DeviceHistory.snapshot`` shape,
+so the comparison reuses ``diff_snapshots`` (field-level changes plus
+per-register added/removed/modified by address) regardless of source.
+Published versions key devices by primary key; YAML trees have no stable
+ids and key by ``"<vendor> <model_number>"``.
+"""
+
+from __future__ import annotations
+
+from pathlib import Path
+
+import yaml
+
+from .history import diff_snapshots
+
+_CONFIG_KEYS = {
+    "modbus": ("modbus_config", ("function", "byte_order", "word_order")),
+    "lorawan": ("lorawan_config", ("device_class", "downlink_f_port")),
+    "wmbus": (
+        "wmbus_config",
+        (
+            "manufacturer_code",
+            "wmbus_version",
+            "wmbus_device_type",
+            "encryption_required",
+            "shared_encryption_key",
+            "wmbusmeters_driver",
+            "is_mvt_default",
+        ),
+    ),
+}
+
+
+def version_snapshot_map(lib_version) -> dict:
+    """Snapshot map for a published ``LibraryVersion`` (removed entries excluded)."""
+    from .models import DeviceHistory, LibraryVersionDevice
+
+    entries = lib_version.device_changes.exclude(change_type=LibraryVersionDevice.ChangeType.REMOVED)
+    result = {}
+    for entry in entries:
+        if not entry.device_type_id:
+            continue
+        snapshot = (
+            DeviceHistory.objects.filter(device_id=entry.device_type_id, version=entry.device_version)
+            .values_list("snapshot", flat=True)
+            .first()
+        )
+        if snapshot:
+            result[entry.device_type_id] = {
+                "label": entry.device_label,
+                "version": entry.device_version,
+                "snapshot": snapshot,
+            }
+    return result
+
+
+def schema_to_snapshot(device: dict) -> dict:
+    """Inverse of ``exporters.snapshot_to_schema`` for the fields a diff needs."""
+    tech = device.get("technology_config") or {}
+    technology = tech.get("technology", "")
+    snapshot = {
+        "vendor": device.get("vendor_name", ""),
+        "model_number": device.get("model_number", ""),
+        "name": device.get("name", ""),
+        "device_type": device.get("device_type", ""),
+        "technology": technology,
+        "description": device.get("description", "") or "",
+    }
+
+    if technology in _CONFIG_KEYS:
+        key, fields = _CONFIG_KEYS[technology]
+        snapshot[key] = {f: tech.get(f) for f in fields if f in tech}
+    if technology == "modbus":
+        snapshot["registers"] = sorted(
+            (
+                {
+                    "field_name": (r.get("field") or {}).get("name", ""),
+                    "field_unit": (r.get("field") or {}).get("unit", ""),
+                    **(
+                        {"field_description": r["field"]["description"]}
+                        if (r.get("field") or {}).get("description") else {}
+                    ),
+                    "address": r.get("address"),
+                    "data_type": r.get("data_type", "uint16"),
+                    "scale": r.get("scale", 1.0),
+                    "offset": r.get("offset", 0.0),
+                    **({"display": r["display"]} if r.get("display") else {}),
+                }
+                for r in tech.get("register_definitions") or []
+            ),
+            key=lambda r: r["address"] if r["address"] is not None else -1,
+        )
+    elif technology == "lorawan" and tech.get("payload_codec"):
+        codec = tech["payload_codec"]
+        snapshot["lorawan_config"]["codec_format"] = codec.get("format", "ttn_v3")
+        snapshot["lorawan_config"]["payload_codec"] = codec.get("script", "")
+
+    for key in ("control_config", "processor_config", "alarm_config"):
+        if device.get(key):
+            snapshot[key] = device[key]
+    return snapshot
+
+
+def yaml_snapshot_map(path: str | Path) -> dict:
+    """Snapshot map for an exported tree.
+
+    ``path`` may be the tree root (containing ``manifest.yaml`` and
+    ``devices/``) or the ``devices/`` directory itself.
+    """
+    path = Path(path)
+    if (path / "manifest.yaml").exists():
+        manifest_path, devices_path = path / "manifest.yaml", path / "devices"
+    else:
+        manifest_path, devices_path = path.parent / "manifest.yaml", path
+    if not manifest_path.exists():
+        raise FileNotFoundError(f"Manifest not found under {path}")
+
+    with open(manifest_path) as f:
+        manifest = yaml.safe_load(f) or {}
+
+    result = {}
+    for vendor_entry in manifest.get("vendors", []) or []:
+        file_path = devices_path / vendor_entry["file"]
+        if not file_path.exists():
+            continue
+        with open(file_path) as f:
+            data = yaml.safe_load(f) or {}
+        devices_key = "models" if "models" in data else "device_types"
+        for device in data.get(devices_key) or []:
+            device.setdefault("vendor_name", vendor_entry.get("name", ""))
+            label = f"{device['vendor_name']} {device.get('model_number', '')}"
+            result[label] = {"label": label, "version": None, "snapshot": schema_to_snapshot(device)}
+    return result
+
+
+def compare_snapshot_maps(from_map: dict, to_map: dict) -> dict:
+    """Classify devices as added / removed / modified between two maps.
+
+    Returns ``{"added": [...], "removed": [...], "modified": [...],
+    "unchanged_count": int}``; modified entries carry the
+    ``diff_snapshots`` result under ``diff`` and the new-side snapshot.
+    """
+    added, removed, modified = [], [], []
+    unchanged = 0
+
+    for ident in sorted(set(from_map) | set(to_map), key=lambda d: (from_map.get(d) or to_map.get(d))["label"]):
+        if ident not in from_map:
+            added.append(to_map[ident])
+        elif ident not in to_map:
+            removed.append(from_map[ident])
+        else:
+            diff = diff_snapshots(from_map[ident]["snapshot"], to_map[ident]["snapshot"])
+            if diff:
+                modified.append({
+                    "label": to_map[ident]["label"],
+                    "from_version": from_map[ident]["version"],
+                    "to_version": to_map[ident]["version"],
+                    "snapshot": to_map[ident]["snapshot"],
+                    "diff": diff,
+                })
+            else:
+                unchanged += 1
+
+    return {"added": added, "removed": removed, "modified": modified, "unchanged_count": unchanged}
@@ -1,33 +1,69 @@
 """Library views for the web UI."""
 
 import json
+import logging
+from dataclasses import replace
+from pathlib import Path
 
 import yaml
+from django.conf import settings
 from django.contrib import messages
 from django.contrib.auth.mixins import LoginRequiredMixin
-from django.db.models import Count, Max, OuterRef, Q, Subquery
-from django.http import HttpResponse
+from django.core.exceptions import ValidationError
+from django.db import IntegrityError, transaction
+from django.db.models import Count, OuterRef, Q, Subquery
+from django.forms.models import model_to_dict
+from django.http import Http404, HttpResponse, HttpResponseBadRequest, JsonResponse, StreamingHttpResponse
 from django.shortcuts import get_object_or_404, redirect
-from django.urls import reverse_lazy
+from django.urls import reverse, reverse_lazy
+from django.utils.functional import cached_property
+from django.utils.http import url_has_allowed_host_and_scheme, urlencode
 from django.views import View
-from django.views.generic import CreateView, DeleteView, DetailView, ListView, TemplateView, UpdateView
+from django.views.generic import CreateView, DeleteView, DetailView, FormView, ListView, TemplateView, UpdateView
 
 from auditlog.helpers import log_action
 from auditlog.models import AuditLog
 from core.models import User
 from core.permissions import RoleRequiredMixin
-
-from .exporters import export_to_yaml, snapshot_to_schema
+from devicelib import fields as canonical_fields
+from devicelib.readplan import REGISTER_WIDTHS
+from devicelib.technology import DECODERS
+
+from . import register_clipboard, undo
+from .codec_editor import codec_outputs, missing_sources, split_outputs
+from .codec_fetch import CodecFetchError, apply_fetched_codec, fetch_codec
+from .control_editor import build_control, control_rows, register_addresses
+from .device_clipboard import paste_device
+from .device_templates import TemplateError, load_template, load_templates, template_content
+from .doctor import check_manifest, write_manifest
+from .drafts import DraftError, file_draft, initial_content, parse_draft, set_technology_config
+from .duplicate import copy_configs
+from .exporters import export_device_yaml, export_registers_csv, export_to_yaml, snapshot_to_schema
 from .forms import (
     AlarmConfigForm,
     APIKeyForm,
+    BulkModelForm,
+    CodecFetchForm,
+    CodecOutputFormSet,
     ControlConfigForm,
+    ControlEditorForm,
+    ControlEntryFormSet,
+    DeviceDraftForm,
+    DevicePasteForm,
     DeviceTypeForm,
+    DraftFileForm,
+    FindReplaceForm,
+    LoRaWANCodecForm,
     LoRaWANConfigForm,
     MetricForm,
     ModbusConfigForm,
     ProcessorConfigForm,
+    ProfileMetricForm,
+    RegisterCSVImportForm,
     RegisterDefinitionForm,
+    RegisterPasteForm,
+    RegisterSnippetForm,
+    TechnologyConfigForm,
     VendorForm,
     VendorModelForm,
     WMBusConfigForm,
@@ -43,10 +79,13 @@ from .history import (
     snapshot_metric,
 )
 from .importers import import_from_yaml
+from .lint import LintConfig, device_statuses
+from .metric_profile import ProfileError, ProfileMetric, load_profile, save_profile
 from .models import (
     AlarmConfig,
     APIKey,
     ControlConfig,
+    DeviceDraft,
     DeviceHistory,
     DeviceType,
     DeviceTypeHistory,
@@ -65,6 +104,17 @@ from .models import (
     VendorModel,
     WMBusConfig,
 )
+from .original import RevertError, device_changes, revert_field, revert_register
+from .problems import editor_problems
+from .publish import publish_steps, publish_version
+from .register_map import analyze_registers
+from .register_merge import find_duplicates, resolve_duplicates
+from .search import jump_candidates, quick_search, search_queryset
+from .snippets import SnippetError, insert_snippet, load_snippets
+from .unpublished import changed_files, unpublished_changes_summary
+from .yaml_format import dump_yaml
+
+logger = logging.getLogger(__name__)
 
 # === Dashboard ===
 
@@ -88,12 +138,62 @@ class DashboardView(LoginRequiredMixin, TemplateView):
         return ctx
 
 
+class QuickSearchView(LoginRequiredMixin, View):
+    """Fuzzy matches for the ``/`` finder overlay (see ``base.html``)."""
+
+    def get(self, request):
+        matches = quick_search(request.GET.get("q", ""))
+        return JsonResponse({"results": [m.as_dict() for m in matches]})
+
+
+class ModelJumpView(LoginRequiredMixin, View):
+    """The ``g m`` prompt: open the one device matching a model number or
+    partial name, or the model list searched for it when several do."""
+
+    def get(self, request):
+        query = request.GET.get("q", "").strip()
+        if not query:
+            return redirect("library:model-list")
+        candidates = jump_candidates(query)
+        if len(candidates) == 1:
+            return redirect("library:model-detail", pk=candidates[0].pk)
+        if not candidates:
+            messages.warning(request, f'No model number or name matches "{query}".')
+        return redirect(f"{reverse_lazy('library:model-list')}?{urlencode({'q': query})}")
+
+
+class UndoView(RoleRequiredMixin, View):
+    """Undo (or, as ``RedoView``, redo) the session's last device edit; see
+    ``library.undo``. Lands on the device, or the list once it's gone."""
+
+    required_role = User.Role.EDITOR
+    action = staticmethod(undo.undo)
+    verb = "Undone"
+
+    def post(self, request):
+        try:
+            operation = self.action(request)
+        except undo.UndoError as e:
+            messages.error(request, str(e))
+            return redirect("library:model-list")
+        messages.success(request, f"{self.verb}: {operation['label']}")
+        if VendorModel.objects.filter(pk=operation["device"]).exists():
+            return redirect("library:model-detail", pk=operation["device"])
+        return redirect("library:model-list")
+
+
+class RedoView(UndoView):
+    action = staticmethod(undo.redo)
+    verb = "Redone"
+
+
 # === Vendors ===
 
 
 class VendorListView(LoginRequiredMixin, ListView):
     template_name = "library/vendor_list.html"
     context_object_name = "vendors"
+    paginate_by = 50
     ALLOWED_SORT_FIELDS = {"name", "slug", "modbus_count", "lorawan_count", "wmbus_count", "device_count"}
 
     def get_queryset(self):
@@ -462,6 +562,101 @@ class DeviceTypeDeleteView(RoleRequiredMixin, View):
         return redirect("library:devicetype-list")
 
 
+class DeviceTypeMetricsView(RoleRequiredMixin, TemplateView):
+    """The type's metrics profile as rows (``metric_profile.ProfileMetric``)
+    with the catalogue's label, unit and aggregation and the source fields
+    its models map; POST adds an entry."""
+
+    required_role = User.Role.ADMIN
+    template_name = "library/device_type/metrics.html"
+
+    @cached_property
+    def device_type(self):
+        return get_object_or_404(DeviceType, pk=self.kwargs["pk"])
+
+    def get_context_data(self, **kwargs):
+        ctx = super().get_context_data(**kwargs)
+        ctx["device_type"] = self.device_type
+        ctx["entries"] = load_profile(self.device_type)
+        ctx.setdefault("form", ProfileMetricForm())
+        return ctx
+
+    def post(self, request, pk):
+        form = ProfileMetricForm(request.POST)
+        if form.is_valid():
+            entries = load_profile(self.device_type)
+            entries.append(ProfileMetric(metric=form.cleaned_data["metric"], tier=form.cleaned_data["tier"]))
+            try:
+                save_profile(self.device_type, entries, request.user)
+            except ProfileError as e:
+                form.add_error(None, str(e))
+            else:
+                log_action(request, "updated", self.device_type, details={"metric_added": form.cleaned_data["metric"]})
+                messages.success(request, f"Added {form.cleaned_data['metric']} to {self.device_type.label}.")
+                return redirect("library:devicetype-metrics", pk=pk)
+        return self.render_to_response(self.get_context_data(form=form))
+
+
+class DeviceTypeMetricEditView(DeviceTypeMetricsView):
+    """Change one profile entry — its metric or its tier."""
+
+    template_name = "library/device_type/metric_form.html"
+
+    @cached_property
+    def entries(self):
+        return load_profile(self.device_type)
+
+    @cached_property
+    def entry(self):
+        index = self.kwargs["index"]
+        if not 0 <= index < len(self.entries):
+            raise Http404("No such profile entry")
+        return self.entries[index]
+
+    def get_context_data(self, **kwargs):
+        ctx = super().get_context_data(**kwargs)
+        ctx["entry"] = self.entry
+        if "form" not in kwargs:
+            ctx["form"] = ProfileMetricForm(initial={"metric": self.entry.metric, "tier": self.entry.tier})
+        return ctx
+
+    def post(self, request, pk, index):
+        form = ProfileMetricForm(request.POST)
+        if form.is_valid():
+            entries = list(self.entries)
+            entries[index] = replace(self.entry, metric=form.cleaned_data["metric"], tier=form.cleaned_data["tier"])
+            if entries == self.entries:
+                return redirect("library:devicetype-metrics", pk=pk)
+            try:
+                save_profile(self.device_type, entries, request.user)
+            except ProfileError as e:
+                form.add_error(None, str(e))
+            else:
+                log_action(request, "updated", self.device_type, details={"metric_changed": self.entry.metric})
+                messages.success(request, f"Updated {form.cleaned_data['metric']} on {self.device_type.label}.")
+                return redirect("library:devicetype-metrics", pk=pk)
+        return self.render_to_response(self.get_context_data(form=form))
+
+
+class DeviceTypeMetricDeleteView(RoleRequiredMixin, View):
+    required_role = User.Role.ADMIN
+
+    def post(self, request, pk, index):
+        device_type = get_object_or_404(DeviceType, pk=pk)
+        entries = load_profile(device_type)
+        if not 0 <= index < len(entries):
+            raise Http404("No such profile entry")
+        removed = entries.pop(index)
+        try:
+            save_profile(device_type, entries, request.user)
+        except ProfileError as e:
+            messages.error(request, f"Cannot remove {removed.metric}: {e}")
+            return redirect("library:devicetype-metrics", pk=pk)
+        log_action(request, "updated", device_type, details={"metric_removed": removed.metric})
+        messages.success(request, f"Removed {removed.metric} from {device_type.label}.")
+        return redirect("library:devicetype-metrics", pk=pk)
+
+
 # === Devices ===
 
 
@@ -489,13 +684,24 @@ class VendorModelListView(LoginRequiredMixin, ListView):
         vendor = self.request.GET.get("vendor")
         technology = self.request.GET.get("technology")
         device_type = self.request.GET.get("device_type")
+        controllable = self.request.GET.get("controllable")
+        query = self.request.GET.get("q", "").strip()
 
+        if query:
+            qs = search_queryset(qs, query)
         if vendor:
             qs = qs.filter(vendor__slug=vendor)
         if technology:
             qs = qs.filter(technology=technology)
         if device_type:
             qs = qs.filter(device_type=device_type)
+        if controllable == "yes":
+            qs = qs.filter(control_config__controllable=True)
+        elif controllable == "no":
+            qs = qs.exclude(control_config__controllable=True)
+        if self.request.GET.get("modified"):
+            # Added or edited since the current library version was published.
+            qs = qs.filter(pk__in=[e.pk for e in unpublished_changes_summary().models if e.pk])
 
         sort = self.request.GET.get("sort", "vendor")
         descending = sort.startswith("-")
@@ -512,8 +718,83 @@ class VendorModelListView(LoginRequiredMixin, ListView):
         ctx["device_type_choices"] = VendorModel.DeviceCategory.choices
         ctx["total_count"] = VendorModel.objects.count()
         ctx["filtered_count"] = self.get_queryset().count()
+        ctx["active_filters"] = self._active_filters()
+        if self.request.user.is_editor:
+            ctx["bulk_form"] = BulkModelForm()
         return ctx
 
+    def _active_filters(self) -> list[dict]:
+        """``{label, value, remove_url}`` per filter narrowing the list, for
+        the chips in the page header."""
+        params = self.request.GET
+        shown = {
+            "q": ("Search", lambda v: v),
+            "vendor": ("Vendor", lambda v: Vendor.objects.filter(slug=v).values_list("name", flat=True).first() or v),
+            "technology": ("Technology", lambda v: dict(VendorModel.Technology.choices).get(v, v)),
+            "device_type": ("Type", lambda v: dict(VendorModel.DeviceCategory.choices).get(v, v)),
+            "controllable": ("Control", {"yes": "Controllable", "no": "Read-only"}.get),
+            "modified": ("Unpublished changes", lambda v: "only"),
+        }
+        filters = []
+        for key, (label, display) in shown.items():
+            value = params.get(key, "").strip()
+            if not value or display(value) is None:
+                continue
+            remaining = params.copy()
+            remaining.pop(key)
+            remaining.pop("page", None)
+            filters.append({"label": label, "value": display(value), "remove_url": f"?{remaining.urlencode()}"})
+        return filters
+
+
+def _lint_config() -> tuple[LintConfig | None, JsonResponse | None]:
+    """The rule set ``lint_library`` applies (``settings.SPARKLINT_CONFIG``),
+    or a JSON error response when that file is malformed."""
+    try:
+        return LintConfig.load(), None
+    except ValueError as e:
+        return None, JsonResponse({"error": f"{settings.SPARKLINT_CONFIG}: {e}"}, status=500)
+
+
+class ModelLintStatusView(LoginRequiredMixin, View):
+    """Lint status per device for the model list, fetched after the page
+    renders so a slow rule set doesn't hold up the list."""
+
+    MAX_DEVICES = 100
+
+    def get(self, request):
+        ids = request.GET.getlist("id")[: self.MAX_DEVICES]
+        config, error = _lint_config()
+        if error:
+            return error
+        devices = VendorModel.objects.filter(pk__in=ids).select_related("vendor", "device_type_fk")
+        try:
+            statuses = device_statuses(devices, config)
+        except ValidationError:
+            return JsonResponse({"error": "invalid device id"}, status=400)
+        return JsonResponse(statuses)
+
+
+class ModelProblemsView(RoleRequiredMixin, View):
+    """Problems panel of the device editor: lint the posted form without
+    saving it, attributing each problem to a form field or config page."""
+
+    required_role = User.Role.EDITOR
+
+    def post(self, request, pk=None):
+        instance = get_object_or_404(VendorModel, pk=pk) if pk else None
+        form = VendorModelForm(data=request.POST, instance=instance)
+        form.is_valid()
+        config, error = _lint_config()
+        if error:
+            return error
+        problems = editor_problems(form, config)
+        return JsonResponse({
+            "errors": sum(p.severity == "error" for p in problems),
+            "warnings": sum(p.severity == "warning" for p in problems),
+            "problems": [p.as_dict() for p in problems],
+        })
+
 
 class VendorModelDetailView(LoginRequiredMixin, DetailView):
     template_name = "library/devicetype_detail.html"
@@ -565,11 +846,26 @@ class VendorModelDetailView(LoginRequiredMixin, DetailView):
         except Exception:
             ctx["alarm_config"] = None
 
-        # Registers
+        # Registers, each with its overlap / duplicate-name / gap issues
         if ctx["modbus_config"]:
-            ctx["registers"] = ctx["modbus_config"].register_definitions.all()
+            ctx["registers"] = list(ctx["modbus_config"].register_definitions.all())
         else:
             ctx["registers"] = []
+        ctx["register_duplicates"] = find_duplicates(ctx["registers"])
+        for reg in ctx["registers"]:
+            reg.issues = []
+        issues = analyze_registers([
+            {"field": {"name": reg.field_name}, "address": reg.address, "data_type": reg.data_type}
+            for reg in ctx["registers"]
+        ])
+        for issue in issues:
+            ctx["registers"][issue.index].issues.append(issue)
+        ctx["register_issues"] = issues
+
+        ctx["register_clipboard"] = register_clipboard.clipboard(self.request)
+
+        # Fields changed since the published version, each revertable on its own
+        ctx["changes"] = device_changes(device)
 
         # History
         ctx["history"] = device.history.select_related("user").all()[:20]
@@ -577,6 +873,26 @@ class VendorModelDetailView(LoginRequiredMixin, DetailView):
         return ctx
 
 
+class VendorModelYAMLView(LoginRequiredMixin, DetailView):
+    """Read-only view of the device as the YAML export serializes it
+    (``?raw=1``: the bare text, for copying to the clipboard)."""
+
+    template_name = "library/model_yaml.html"
+    model = VendorModel
+    context_object_name = "device"
+
+    def render_to_response(self, context, **response_kwargs):
+        if self.request.GET.get("raw"):
+            return HttpResponse(context["yaml"], content_type="text/yaml; charset=utf-8")
+        return super().render_to_response(context, **response_kwargs)
+
+    def get_context_data(self, **kwargs):
+        ctx = super().get_context_data(**kwargs)
+        ctx["yaml"] = export_device_yaml(self.object)
+        ctx["file"] = f"{self.object.vendor.slug}.yaml"
+        return ctx
+
+
 class VendorModelCreateView(RoleRequiredMixin, CreateView):
     required_role = User.Role.EDITOR
     model = VendorModel
@@ -593,6 +909,70 @@ class VendorModelCreateView(RoleRequiredMixin, CreateView):
         return reverse_lazy("library:model-detail", kwargs={"pk": self.object.pk})
 
 
+class VendorModelPasteView(RoleRequiredMixin, FormView):
+    """Create a model from device YAML pasted from the clipboard."""
+
+    required_role = User.Role.EDITOR
+    form_class = DevicePasteForm
+    template_name = "library/model_paste.html"
+
+    def form_valid(self, form):
+        data = form.cleaned_data
+        try:
+            device, findings = paste_device(data["content"], data["vendor"], data["model_number"].strip())
+        except DraftError as e:
+            form.add_error(None, str(e))
+            return self.form_invalid(form)
+        log_action(self.request, "created", device, details="Pasted as YAML")
+        undo.push(self.request, f"Paste {device}", device.pk, None)
+        messages.success(self.request, f"Model \"{device}\" created from the pasted YAML.")
+        for finding in findings:
+            messages.warning(self.request, f"{finding.path or finding.rule}: {finding.message}")
+        return redirect("library:model-detail", pk=device.pk)
+
+
+class VendorModelDuplicateView(VendorModelCreateView):
+    """Create a device from a copy of an existing one.
+
+    The form starts from the source's fields with the model number blank;
+    on save the new device gets its own copies of the source's configs and
+    registers (``library.duplicate``)."""
+
+    @cached_property
+    def source(self):
+        return get_object_or_404(VendorModel, pk=self.kwargs["pk"])
+
+    def get_initial(self):
+        source = self.source
+        return {
+            "vendor": source.vendor_id,
+            "name": source.name,
+            "device_type": source.device_type,
+            "device_type_fk": source.device_type_fk_id,
+            "technology": source.technology,
+            "additional_technologies": source.additional_technologies,
+            "description": source.description,
+            "links": source.links,
+            "suppressed_rules": source.suppressed_rules,
+        }
+
+    def get_context_data(self, **kwargs):
+        return super().get_context_data(duplicate_of=self.source, **kwargs)
+
+    @transaction.atomic
+    def form_valid(self, form):
+        self.object = form.save()
+        registers = copy_configs(self.source, self.object)
+        record_history(self.object, DeviceHistory.Action.CREATED, self.request.user)
+        log_action(self.request, "created", self.object)
+        undo.push(self.request, f"Duplicate {self.source} as {self.object.model_number}", self.object.pk, None)
+        messages.success(
+            self.request,
+            f"Model \"{self.object}\" created from {self.source} with {registers} register{'s' * (registers != 1)}.",
+        )
+        return redirect(self.get_success_url())
+
+
 class VendorModelUpdateView(RoleRequiredMixin, UpdateView):
     required_role = User.Role.EDITOR
     model = VendorModel
@@ -602,31 +982,206 @@ class VendorModelUpdateView(RoleRequiredMixin, UpdateView):
     def get_object(self, queryset=None):
         obj = super().get_object(queryset)
         self._old_snapshot = snapshot_device(obj)
+        self._undo_state = undo.capture(obj)
         return obj
 
+    def get_context_data(self, **kwargs):
+        ctx = super().get_context_data(**kwargs)
+        ctx["changes"] = device_changes(self.object)
+        return ctx
+
     def form_valid(self, form):
         response = super().form_valid(form)
         record_history(self.object, DeviceHistory.Action.UPDATED, self.request.user, self._old_snapshot)
         log_action(self.request, "updated", self.object)
+        undo.push(self.request, f"Edit {self.object}", self.object.pk, self._undo_state)
         return response
 
     def get_success_url(self):
         return reverse_lazy("library:model-detail", kwargs={"pk": self.object.pk})
 
 
+class VendorModelRevertView(RoleRequiredMixin, View):
+    """Put one field (``field``, a snapshot key) or one register
+    (``register``, its address) back as last published, leaving the
+    model's other edits alone (``library.original``)."""
+
+    required_role = User.Role.EDITOR
+
+    def post(self, request, pk):
+        device = get_object_or_404(VendorModel, pk=pk)
+        old_snapshot = snapshot_device(device)
+        undo_state = undo.capture(device)
+        register = request.POST.get("register", "")
+        try:
+            with transaction.atomic():
+                if register.isdigit():
+                    label = f"Register {revert_register(device, int(register)).address}"
+                else:
+                    label = revert_field(device, request.POST.get("field", "")).label
+        except RevertError as e:
+            messages.error(request, str(e))
+            return self._back(request, device)
+        except IntegrityError as e:  # e.g. the published model number has been taken since
+            messages.error(request, f"Can't revert: {e}")
+            return self._back(request, device)
+
+        device = VendorModel.objects.get(pk=device.pk)
+        record_history(device, DeviceHistory.Action.UPDATED, request.user, old_snapshot)
+        log_action(request, "updated", device, details=f"{label} reverted to the published version")
+        undo.push(request, f"Revert {label} of {device}", device.pk, undo_state)
+        messages.success(request, f"{label} reverted to the published version.")
+        return self._back(request, device)
+
+    @staticmethod
+    def _back(request, device):
+        back = request.POST.get("next", "")
+        if url_has_allowed_host_and_scheme(back, allowed_hosts={request.get_host()}):
+            return redirect(back)
+        return redirect("library:model-detail", pk=device.pk)
+
+
 class VendorModelDeleteView(RoleRequiredMixin, View):
     required_role = User.Role.EDITOR
 
     def post(self, request, pk):
         device = get_object_or_404(VendorModel, pk=pk)
         name = f"{device.vendor.name} {device.model_number}"
+        undo_state = undo.capture(device)
         record_history(device, DeviceHistory.Action.DELETED, request.user)
         log_action(request, "deleted", device)
         device.delete()
+        undo.push(request, f"Delete {name}", pk, undo_state)
         messages.success(request, f"Model \"{name}\" has been deleted.")
         return redirect("library:model-list")
 
 
+class VendorModelBulkView(RoleRequiredMixin, View):
+    """Apply one action to the models marked in the model list.
+
+    The first post shows what will change; the action runs only when the
+    confirmation page posts back with ``confirm``. Each model gets its own
+    history entry and undo operation, as if edited one by one.
+    """
+
+    required_role = User.Role.EDITOR
+    template_name = "library/model_bulk.html"
+
+    def post(self, request):
+        from django.shortcuts import render
+
+        back = reverse_lazy("library:model-list")
+        if request.POST.get("query"):
+            back = f"{back}?{request.POST['query']}"
+        form = BulkModelForm(request.POST)
+        if not form.is_valid():
+            for errors in form.errors.values():
+                for error in errors:
+                    messages.error(request, error)
+            return redirect(back)
+        if "confirm" not in request.POST:
+            return render(request, self.template_name, {"form": form, "models": form.cleaned_data["models"]})
+
+        action = form.cleaned_data["action"]
+        devices = list(form.cleaned_data["models"])
+        with transaction.atomic():
+            for device in devices:
+                self._apply(request, device, action, form.cleaned_data)
+        label = dict(BulkModelForm.ACTIONS)[action]
+        messages.success(request, f"{label}: {len(devices)} model{'s' * (len(devices) != 1)}.")
+        return redirect(back)
+
+    def _apply(self, request, device, action, data):
+        undo_state = undo.capture(device)
+        if action == "delete":
+            record_history(device, DeviceHistory.Action.DELETED, request.user)
+            log_action(request, "deleted", device)
+            pk, name = device.pk, str(device)
+            device.delete()
+            undo.push(request, f"Delete {name}", pk, undo_state)
+            return
+
+        old_snapshot = snapshot_device(device)
+        rules = list(device.suppressed_rules or [])
+        if action == "device_type" and device.device_type_fk != data["device_type_fk"]:
+            device.device_type_fk = data["device_type_fk"]
+        elif action == "suppress" and data["rule"] not in rules:
+            device.suppressed_rules = [*rules, data["rule"]]
+        elif action == "unsuppress" and data["rule"] in rules:
+            device.suppressed_rules = [r for r in rules if r != data["rule"]]
+        else:
+            return
+        device.save()
+        record_history(device, DeviceHistory.Action.UPDATED, request.user, old_snapshot)
+        log_action(request, "updated", device)
+        undo.push(request, f"Edit {device}", device.pk, undo_state)
+
+
+class FindReplaceView(RoleRequiredMixin, View):
+    """Find and replace field names, units or descriptions (``library.replace``).
+
+    A search lists every hit with what it would become; only the hits
+    left ticked are applied when the preview is posted back. Each device
+    changed gets one history entry and one undo operation, as with the
+    bulk actions.
+    """
+
+    required_role = User.Role.EDITOR
+    template_name = "library/find_replace.html"
+
+    def get(self, request):
+        from django.shortcuts import render
+
+        if "find" in request.GET:
+            form = FindReplaceForm(request.GET)
+        else:
+            form = FindReplaceForm(initial={"vendor": request.GET.get("vendor")})
+        hits = form.hits() if form.is_bound and form.is_valid() else None
+        return render(request, self.template_name, {"form": form, "hits": hits})
+
+    def post(self, request):
+        form = FindReplaceForm(request.POST)
+        if not form.is_valid():
+            for errors in form.errors.values():
+                for error in errors:
+                    messages.error(request, error)
+            return redirect("library:find-replace")
+        picked = set(request.POST.getlist("hits"))
+        by_device = {}
+        for hit in form.hits():
+            if hit.key in picked and not hit.problem:
+                by_device.setdefault(hit.device.pk, []).append(hit)
+        with transaction.atomic():
+            for hits in by_device.values():
+                self._apply(request, hits)
+        count = sum(len(hits) for hits in by_device.values())
+        if count:
+            messages.success(
+                request,
+                f"Replaced {count} occurrence{'s' * (count != 1)} of \"{form.cleaned_data['find']}\" "
+                f"in {len(by_device)} model{'s' * (len(by_device) != 1)}.",
+            )
+        else:
+            messages.info(request, "Nothing was replaced.")
+        query = {k: v for k, v in request.POST.lists() if k not in ("csrfmiddlewaretoken", "hits")}
+        return redirect(f"{reverse('library:find-replace')}?{urlencode(query, doseq=True)}")
+
+    def _apply(self, request, hits):
+        device = next((hit.obj for hit in hits if isinstance(hit.obj, VendorModel)), hits[0].device)
+        undo_state = undo.capture(device)
+        old_snapshot = snapshot_device(device)
+        # Hits on the same row (a register's name and description) share one instance.
+        objects = {}
+        for hit in hits:
+            obj = objects.setdefault(hit.key.rsplit(":", 1)[0], hit.obj)
+            setattr(obj, hit.attr, hit.new)
+        for obj in objects.values():
+            obj.save()
+        record_history(device, DeviceHistory.Action.UPDATED, request.user, old_snapshot)
+        log_action(request, "updated", device, details=f"Find and replace: {len(hits)} change{'s' * (len(hits) != 1)}")
+        undo.push(request, f"Replace in {device}", device.pk, undo_state)
+
+
 # === Modbus Config ===
 
 
@@ -640,6 +1195,7 @@ class ModbusConfigUpdateView(RoleRequiredMixin, UpdateView):
         device = get_object_or_404(VendorModel, pk=self.kwargs["device_pk"])
         self._device = device
         self._old_snapshot = snapshot_device(device)
+        self._undo_state = undo.capture(device)
         obj, _ = ModbusConfig.objects.get_or_create(device_type=device)
         return obj
 
@@ -654,6 +1210,7 @@ class ModbusConfigUpdateView(RoleRequiredMixin, UpdateView):
         device = VendorModel.objects.get(pk=self._device.pk)
         record_history(device, DeviceHistory.Action.UPDATED, self.request.user, self._old_snapshot)
         log_action(self.request, "updated", form.instance, details=f"Modbus config updated on {self._device}")
+        undo.push(self.request, f"Edit Modbus config of {device}", device.pk, self._undo_state)
         return response
 
     def get_success_url(self):
@@ -673,6 +1230,7 @@ class ControlConfigUpdateView(RoleRequiredMixin, UpdateView):
         device = get_object_or_404(VendorModel, pk=self.kwargs["device_pk"])
         self._device = device
         self._old_snapshot = snapshot_device(device)
+        self._undo_state = undo.capture(device)
         obj, _ = ControlConfig.objects.get_or_create(device_type=device)
         return obj
 
@@ -689,12 +1247,90 @@ class ControlConfigUpdateView(RoleRequiredMixin, UpdateView):
         device = VendorModel.objects.get(pk=self._device.pk)
         record_history(device, DeviceHistory.Action.UPDATED, self.request.user, self._old_snapshot)
         log_action(self.request, "updated", form.instance, details=f"Control config updated on {self._device}")
+        undo.push(self.request, f"Edit control config of {device}", device.pk, self._undo_state)
         return response
 
     def get_success_url(self):
         return reverse_lazy("library:model-detail", kwargs={"pk": self._device.pk})
 
 
+class ControlEditorView(RoleRequiredMixin, View):
+    """The structured control editor (``library.control_editor``): relays,
+    setpoints and commands as typed rows, checked against the register map
+    and the feedback metrics' ranges before they are saved."""
+
+    required_role = User.Role.EDITOR
+    template_name = "library/control_editor.html"
+
+    def get(self, request, device_pk):
+        device = get_object_or_404(VendorModel, pk=device_pk)
+        config, _ = ControlConfig.objects.get_or_create(device_type=device)
+        rows, kept = control_rows(config.controls, device.technology)
+        form = ControlEditorForm(initial={"controllable": config.controllable})
+        entries = self._formset(device, kept, initial=rows)
+        return self._render(request, device, config, form, entries, kept)
+
+    def post(self, request, device_pk):
+        device = get_object_or_404(VendorModel, pk=device_pk)
+        old_snapshot = snapshot_device(device)
+        undo_state = undo.capture(device)
+        config, _ = ControlConfig.objects.get_or_create(device_type=device)
+        _, kept = control_rows(config.controls, device.technology)
+        form = ControlEditorForm(request.POST)
+        entries = self._formset(device, kept, data=request.POST)
+        if not (form.is_valid() and entries.is_valid()):
+            return self._render(request, device, config, form, entries, kept)
+
+        config.controllable = form.cleaned_data["controllable"]
+        config.controls = [
+            build_control(row, device.technology) for row in entries.cleaned_data if row.get("id")
+        ] + kept
+        try:
+            config.full_clean()
+        except ValidationError as e:
+            for error in e.messages:
+                messages.error(request, error)
+            return self._render(request, device, config, form, entries, kept)
+        config.save()
+
+        device = VendorModel.objects.get(pk=device.pk)
+        record_history(device, DeviceHistory.Action.UPDATED, request.user, old_snapshot)
+        log_action(request, "updated", config, details=f"Controls updated on {device}")
+        undo.push(request, f"Edit controls of {device}", device.pk, undo_state)
+        return redirect("library:model-detail", pk=device.pk)
+
+    @staticmethod
+    def _formset(device, kept, **kwargs):
+        limits = {
+            metric["key"]: tuple(None if v is None else float(v) for v in (metric["min_value"], metric["max_value"]))
+            for metric in Metric.objects.values("key", "min_value", "max_value")
+        }
+        return ControlEntryFormSet(
+            prefix="controls",
+            kept_ids=[entry["id"] for entry in kept if isinstance(entry, dict) and entry.get("id")],
+            form_kwargs={
+                "technology": device.technology,
+                "addresses": register_addresses(device),
+                "limits": limits,
+            },
+            **kwargs,
+        )
+
+    def _render(self, request, device, config, form, entries, kept):
+        from django.shortcuts import render
+
+        return render(request, self.template_name, {
+            "device": device,
+            "object": config,
+            "form": form,
+            "entries": entries,
+            "kept": kept,
+            "modbus": device.technology == VendorModel.Technology.MODBUS,
+            "metrics": Metric.objects.values("key", "label", "unit", "kind").order_by("-kind", "key"),
+            "registers": RegisterDefinition.objects.filter(modbus_config__device_type=device).order_by("address"),
+        })
+
+
 # === wM-Bus Config ===
 
 
@@ -708,6 +1344,7 @@ class WMBusConfigUpdateView(RoleRequiredMixin, UpdateView):
         device = get_object_or_404(VendorModel, pk=self.kwargs["device_pk"])
         self._device = device
         self._old_snapshot = snapshot_device(device)
+        self._undo_state = undo.capture(device)
         obj, _ = WMBusConfig.objects.get_or_create(device_type=device)
         return obj
 
@@ -727,6 +1364,7 @@ class WMBusConfigUpdateView(RoleRequiredMixin, UpdateView):
         device = VendorModel.objects.get(pk=self._device.pk)
         record_history(device, DeviceHistory.Action.UPDATED, self.request.user, self._old_snapshot)
         log_action(self.request, "updated", form.instance, details=f"wM-Bus config updated on {self._device}")
+        undo.push(self.request, f"Edit wM-Bus config of {device}", device.pk, self._undo_state)
         return response
 
     def get_success_url(self):
@@ -746,6 +1384,7 @@ class LoRaWANConfigUpdateView(RoleRequiredMixin, UpdateView):
         device = get_object_or_404(VendorModel, pk=self.kwargs["device_pk"])
         self._device = device
         self._old_snapshot = snapshot_device(device)
+        self._undo_state = undo.capture(device)
         obj, _ = LoRaWANConfig.objects.get_or_create(device_type=device)
         return obj
 
@@ -759,12 +1398,140 @@ class LoRaWANConfigUpdateView(RoleRequiredMixin, UpdateView):
         device = VendorModel.objects.get(pk=self._device.pk)
         record_history(device, DeviceHistory.Action.UPDATED, self.request.user, self._old_snapshot)
         log_action(self.request, "updated", form.instance, details=f"LoRaWAN config updated on {self._device}")
+        undo.push(self.request, f"Edit LoRaWAN config of {device}", device.pk, self._undo_state)
         return response
 
     def get_success_url(self):
         return reverse_lazy("library:model-detail", kwargs={"pk": self._device.pk})
 
 
+class LoRaWANCodecView(RoleRequiredMixin, View):
+    """The codec editor of a LoRaWAN device (``library.codec_editor``):
+    decoder source or field map, downlink fPort, and the outputs the
+    decoder is expected to produce — saved together as one edit."""
+
+    required_role = User.Role.EDITOR
+    template_name = "library/lorawan_codec.html"
+
+    def get(self, request, device_pk):
+        device = get_object_or_404(VendorModel, pk=device_pk)
+        config, _ = LoRaWANConfig.objects.get_or_create(device_type=device)
+        decoder = ProcessorConfig.DecoderType
+        form = LoRaWANCodecForm(initial={
+            "decoder": (decoder.JS_CODEC if config.payload_codec else decoder.LORAWAN_FIELD_MAP).value,
+            "codec_format": config.codec_format or LoRaWANConfig.CodecFormat.TTN_V3,
+            "payload_codec": config.payload_codec,
+            "downlink_f_port": config.downlink_f_port,
+        })
+        processor = ProcessorConfig.objects.filter(device_type=device).first()
+        outputs = CodecOutputFormSet(initial=codec_outputs(processor), prefix="outputs")
+        return self._render(request, device, config, form, outputs)
+
+    def post(self, request, device_pk):
+        device = get_object_or_404(VendorModel, pk=device_pk)
+        old_snapshot = snapshot_device(device)
+        undo_state = undo.capture(device)
+        config, _ = LoRaWANConfig.objects.get_or_create(device_type=device)
+        form = LoRaWANCodecForm(request.POST)
+        outputs = CodecOutputFormSet(request.POST, prefix="outputs")
+        if not (form.is_valid() and outputs.is_valid()):
+            return self._render(request, device, config, form, outputs)
+
+        data = form.cleaned_data
+        config.codec_format = data["codec_format"]
+        config.downlink_f_port = data["downlink_f_port"]
+        if data["decoder"] == ProcessorConfig.DecoderType.JS_CODEC:
+            config.payload_codec = data["payload_codec"]
+        else:
+            # No codec, so no provenance of one either.
+            config.payload_codec = config.codec_source_url = config.codec_source_sha256 = ""
+            config.codec_fetched_at = None
+        config.save()
+
+        rows = [row for row in outputs.cleaned_data if row.get("source")]
+        processor = ProcessorConfig.objects.filter(device_type=device).first()
+        if rows or processor:
+            processor = processor or ProcessorConfig(device_type=device)
+            processor.field_mappings, processor.extra_mappings = split_outputs(rows, self._declared(device))
+            processor.save()
+
+        device = VendorModel.objects.get(pk=device.pk)
+        record_history(device, DeviceHistory.Action.UPDATED, request.user, old_snapshot)
+        log_action(request, "updated", config, details=f"LoRaWAN codec updated on {device}")
+        undo.push(request, f"Edit codec of {device}", device.pk, undo_state)
+        for error in form.script_errors():
+            messages.warning(request, f"Codec {error}")
+        if config.payload_codec:
+            missing = missing_sources(config.payload_codec, rows)
+            if missing:
+                messages.warning(request, f"Not mentioned in the codec: {', '.join(missing)}")
+        return redirect("library:model-detail", pk=device.pk)
+
+    @staticmethod
+    def _declared(device) -> set[str]:
+        """Metric keys the device type declares (its field mappings' targets)."""
+        if not device.device_type_fk_id:
+            return set()
+        return {m["metric"] for m in device.device_type_fk.metrics or [] if isinstance(m, dict) and m.get("metric")}
+
+    def _render(self, request, device, config, form, outputs):
+        from django.shortcuts import render
+
+        declared = self._declared(device)
+        metrics = [
+            {**metric, "declared": metric["key"] in declared}
+            for metric in Metric.objects.values("key", "label", "unit").order_by("key")
+        ]
+        return render(request, self.template_name, {
+            "device": device,
+            "object": config,
+            "form": form,
+            "outputs": outputs,
+            "metrics": metrics,
+            "fetch_form": CodecFetchForm(
+                initial={"url": config.codec_source_url, "codec_format": config.codec_format},
+            ),
+        })
+
+
+class LoRaWANCodecFetchView(RoleRequiredMixin, View):
+    """Download a vendor-published codec into the LoRaWAN config, recording
+    the source URL, SHA-256 and fetch time alongside the script."""
+
+    required_role = User.Role.EDITOR
+
+    def post(self, request, device_pk):
+        device = get_object_or_404(VendorModel, pk=device_pk)
+        if VendorModel.Technology.LORAWAN not in device.technologies:
+            messages.error(request, f"{device} is not a LoRaWAN device; it has no codec to fetch.")
+            return redirect("library:model-detail", pk=device.pk)
+        form = CodecFetchForm(request.POST)
+        if not form.is_valid():
+            for errors in form.errors.values():
+                for error in errors:
+                    messages.error(request, error)
+            return redirect("library:lorawan-codec", device_pk=device.pk)
+        try:
+            fetched = fetch_codec(form.cleaned_data["url"])
+        except CodecFetchError as e:
+            messages.error(request, str(e))
+            return redirect("library:lorawan-codec", device_pk=device.pk)
+
+        old_snapshot = snapshot_device(device)
+        undo_state = undo.capture(device)
+        config, _ = LoRaWANConfig.objects.get_or_create(device_type=device)
+        apply_fetched_codec(config, fetched, form.cleaned_data["codec_format"])
+        device = VendorModel.objects.get(pk=device.pk)
+        record_history(device, DeviceHistory.Action.UPDATED, request.user, old_snapshot)
+        log_action(
+            request, "updated", config,
+            details=f"LoRaWAN codec fetched for {device} from {fetched.url} (sha256 {fetched.sha256})",
+        )
+        undo.push(request, f"Fetch codec of {device}", device.pk, undo_state)
+        messages.success(request, f"Fetched codec from {fetched.url} ({len(fetched.script)} characters).")
+        return redirect("library:lorawan-codec", device_pk=device.pk)
+
+
 # === Processor Config ===
 
 
@@ -778,6 +1545,7 @@ class ProcessorConfigUpdateView(RoleRequiredMixin, UpdateView):
         device = get_object_or_404(VendorModel, pk=self.kwargs["device_pk"])
         self._device = device
         self._old_snapshot = snapshot_device(device)
+        self._undo_state = undo.capture(device)
         obj, _ = ProcessorConfig.objects.get_or_create(device_type=device)
         return obj
 
@@ -796,6 +1564,7 @@ class ProcessorConfigUpdateView(RoleRequiredMixin, UpdateView):
         device = VendorModel.objects.get(pk=self._device.pk)
         record_history(device, DeviceHistory.Action.UPDATED, self.request.user, self._old_snapshot)
         log_action(self.request, "updated", form.instance, details=f"Processor config updated on {self._device}")
+        undo.push(self.request, f"Edit processor config of {device}", device.pk, self._undo_state)
         return response
 
     def get_success_url(self):
@@ -815,6 +1584,7 @@ class AlarmConfigUpdateView(RoleRequiredMixin, UpdateView):
         device = get_object_or_404(VendorModel, pk=self.kwargs["device_pk"])
         self._device = device
         self._old_snapshot = snapshot_device(device)
+        self._undo_state = undo.capture(device)
         obj, _ = AlarmConfig.objects.get_or_create(device_type=device)
         return obj
 
@@ -828,6 +1598,7 @@ class AlarmConfigUpdateView(RoleRequiredMixin, UpdateView):
         device = VendorModel.objects.get(pk=self._device.pk)
         record_history(device, DeviceHistory.Action.UPDATED, self.request.user, self._old_snapshot)
         log_action(self.request, "updated", form.instance, details=f"Alarm config updated on {self._device}")
+        undo.push(self.request, f"Edit alarm config of {device}", device.pk, self._undo_state)
         return response
 
     def get_success_url(self):
@@ -837,11 +1608,68 @@ class AlarmConfigUpdateView(RoleRequiredMixin, UpdateView):
 # === Registers ===
 
 
+# Columns of the register table that can be edited in place.
+REGISTER_TABLE_FIELDS = ("address", "data_type", "field_name", "field_unit", "scale", "offset")
+MODBUS_FUNCTION_CODES = {ModbusConfig.Function.HOLDING: 3, ModbusConfig.Function.INPUT: 4}
+
+
 class RegisterListView(LoginRequiredMixin, ListView):
-    """Redirect to device detail (registers shown inline)."""
+    """All registers of a device as a table: arrow keys move between
+    cells, Enter edits one in place (``RegisterCellView``)."""
 
-    def get(self, request, device_pk):
-        return redirect("library:model-detail", pk=device_pk)
+    template_name = "library/register_table.html"
+    context_object_name = "registers"
+
+    def get_queryset(self):
+        self.device = get_object_or_404(VendorModel.objects.select_related("vendor"), pk=self.kwargs["device_pk"])
+        return RegisterDefinition.objects.filter(modbus_config__device_type=self.device)
+
+    def get_context_data(self, **kwargs):
+        ctx = super().get_context_data(**kwargs)
+        modbus_config = ModbusConfig.objects.filter(device_type=self.device).first()
+        ctx["device"] = self.device
+        ctx["function_code"] = MODBUS_FUNCTION_CODES.get(modbus_config.function) if modbus_config else None
+        ctx["data_types"] = RegisterDefinition.DataType.values
+        ctx["changes"] = changes = device_changes(self.device)
+        for reg in ctx["registers"]:
+            reg.change = changes.registers.get(reg.address) if changes else None
+        return ctx
+
+
+class RegisterCellView(RoleRequiredMixin, View):
+    """Save one cell of the register table: ``field`` and ``value``.
+
+    The register goes through ``RegisterDefinitionForm`` with its other
+    fields unchanged, so a cell edit is validated, recorded and undoable
+    like an edit on the register form."""
+
+    required_role = User.Role.EDITOR
+
+    def post(self, request, pk):
+        register = get_object_or_404(RegisterDefinition.objects.select_related("modbus_config__device_type"), pk=pk)
+        field = request.POST.get("field")
+        if field not in REGISTER_TABLE_FIELDS:
+            return JsonResponse({"error": f"{field!r} can't be edited here"}, status=400)
+        device = register.modbus_config.device_type
+        data = {
+            name: "" if value is None else value
+            for name, value in model_to_dict(register, fields=RegisterDefinitionForm.Meta.fields).items()
+        }
+        data[field] = request.POST.get("value", "").strip()
+        old_snapshot, undo_state = snapshot_device(device), undo.capture(device)
+        form = RegisterDefinitionForm(data, instance=register, device=device)
+        if not form.is_valid():
+            errors = form.errors.get(field) or [e for errs in form.errors.values() for e in errs]
+            return JsonResponse({"error": " ".join(errors)}, status=400)
+        if form.has_changed():
+            form.save()
+            record_history(device, DeviceHistory.Action.UPDATED, request.user, old_snapshot)
+            log_action(request, "updated", register, details=f"Register {field} updated on {device}")
+            undo.push(request, f"Edit register {register.field_name} of {device}", device.pk, undo_state)
+        return JsonResponse({
+            "value": getattr(register, field),
+            "warnings": [*form.unit_warnings(), *form.canonical_warnings()],
+        })
 
 
 class RegisterCreateView(RoleRequiredMixin, CreateView):
@@ -853,11 +1681,18 @@ class RegisterCreateView(RoleRequiredMixin, CreateView):
     def get_context_data(self, **kwargs):
         ctx = super().get_context_data(**kwargs)
         ctx["device"] = get_object_or_404(VendorModel, pk=self.kwargs["device_pk"])
+        ctx["canonical_fields"] = canonical_fields.FIELDS.values()
         return ctx
 
+    def get_form_kwargs(self):
+        kwargs = super().get_form_kwargs()
+        kwargs["device"] = get_object_or_404(VendorModel, pk=self.kwargs["device_pk"])
+        return kwargs
+
     def form_valid(self, form):
         device = get_object_or_404(VendorModel, pk=self.kwargs["device_pk"])
         old_snapshot = snapshot_device(device)
+        undo_state = undo.capture(device)
         # Ensure modbus config exists
         from .models import ModbusConfig
 
@@ -866,6 +1701,9 @@ class RegisterCreateView(RoleRequiredMixin, CreateView):
         response = super().form_valid(form)
         record_history(device, DeviceHistory.Action.UPDATED, self.request.user, old_snapshot)
         log_action(self.request, "created", form.instance, details=f"Register added to {device}")
+        undo.push(self.request, f"Add register {form.instance.field_name} to {device}", device.pk, undo_state)
+        for warning in [*form.unit_warnings(), *form.canonical_warnings()]:
+            messages.warning(self.request, warning)
         return response
 
     def get_success_url(self):
@@ -883,17 +1721,30 @@ class RegisterUpdateView(RoleRequiredMixin, UpdateView):
         device = obj.modbus_config.device_type
         self._device = device
         self._old_snapshot = snapshot_device(device)
+        self._undo_state = undo.capture(device)
         return obj
 
+    def get_form_kwargs(self):
+        kwargs = super().get_form_kwargs()
+        kwargs["device"] = self._device
+        return kwargs
+
     def get_context_data(self, **kwargs):
         ctx = super().get_context_data(**kwargs)
         ctx["device"] = self.object.modbus_config.device_type
+        ctx["canonical_fields"] = canonical_fields.FIELDS.values()
         return ctx
 
     def form_valid(self, form):
         response = super().form_valid(form)
         record_history(self._device, DeviceHistory.Action.UPDATED, self.request.user, self._old_snapshot)
         log_action(self.request, "updated", form.instance, details=f"Register updated on {self._device}")
+        undo.push(
+            self.request, f"Edit register {form.instance.field_name} of {self._device}", self._device.pk,
+            self._undo_state,
+        )
+        for warning in [*form.unit_warnings(), *form.canonical_warnings()]:
+            messages.warning(self.request, warning)
         return response
 
     def get_success_url(self):
@@ -909,10 +1760,264 @@ class RegisterDeleteView(RoleRequiredMixin, DeleteView):
         self.object = self.get_object()
         device = self.object.modbus_config.device_type
         old_snapshot = snapshot_device(device)
+        undo_state = undo.capture(device)
         reg_name = self.object.field_name
         self.object.delete()
         record_history(device, DeviceHistory.Action.UPDATED, request.user, old_snapshot)
         log_action(request, "deleted", self.object, details=f"Register '{reg_name}' removed from {device}")
+        undo.push(request, f"Delete register {reg_name} of {device}", device.pk, undo_state)
+        return redirect("library:model-detail", pk=device.pk)
+
+
+class RegisterExportView(LoginRequiredMixin, View):
+    """Download a Modbus device's register map as CSV."""
+
+    def get(self, request, device_pk):
+        device = get_object_or_404(VendorModel.objects.select_related("vendor"), pk=device_pk)
+        response = HttpResponse(content_type="text/csv; charset=utf-8")
+        # BOM so Excel picks up UTF-8 unit symbols (°C, m³) when opened directly.
+        response.write("\ufeff")
+        export_registers_csv(device, response)
+        filename = f"{device.vendor.slug}-{device.model_number}-registers.csv".replace(" ", "_")
+        response["Content-Disposition"] = f'attachment; filename="{filename}"'
+        return response
+
+
+class RegisterImportView(RoleRequiredMixin, View):
+    """Merge a CSV register map into a Modbus device, row by row.
+
+    Step 1 (upload) parses the CSV and parks the rows in the session;
+    step 2 (apply) shows the reconciliation against the existing registers
+    and applies only the rows the editor ticked.
+    """
+
+    required_role = User.Role.EDITOR
+    template_name = "library/register_import.html"
+
+    def _session_key(self, device):
+        return f"register_import:{device.pk}"
+
+    def _render(self, request, device, form=None, rows=None):
+        from django.shortcuts import render
+
+        return render(request, self.template_name, {"device": device, "form": form, "rows": rows})
+
+    def _modbus_device(self, request, device_pk):
+        device = get_object_or_404(VendorModel, pk=device_pk)
+        if VendorModel.Technology.MODBUS not in device.technologies:
+            messages.error(request, f"{device} is not a Modbus device; it has no registers to import.")
+            return device, redirect("library:model-detail", pk=device.pk)
+        return device, None
+
+    def get(self, request, device_pk):
+        device, refused = self._modbus_device(request, device_pk)
+        if refused:
+            return refused
+        return self._render(request, device, form=RegisterCSVImportForm())
+
+    def post(self, request, device_pk):
+        from .register_merge import RegisterRow, apply_plan, parse_register_csv, reconcile
+
+        device, refused = self._modbus_device(request, device_pk)
+        if refused:
+            return refused
+        # The config is created only when an import is applied, not for a look at the plan.
+        existing = RegisterDefinition.objects.filter(modbus_config__device_type=device)
+
+        if "apply" not in request.POST:
+            form = RegisterCSVImportForm(request.POST, request.FILES)
+            if not form.is_valid():
+                return self._render(request, device, form=form)
+            try:
+                incoming = parse_register_csv(form.cleaned_data["csv_file"].read().decode("utf-8"))
+            except (UnicodeDecodeError, ValueError) as e:
+                form.add_error("csv_file", str(e))
+                return self._render(request, device, form=form)
+            request.session[self._session_key(device)] = incoming
+            rows = reconcile(existing, incoming)
+            return self._render(request, device, rows=rows)
+
+        try:
+            accepted = {int(a) for a in request.POST.getlist("accept")}
+        except ValueError:
+            return HttpResponseBadRequest("accept must list register addresses")
+        incoming = request.session.pop(self._session_key(device), None)
+        if incoming is None:
+            messages.error(request, "Import session expired — upload the CSV again.")
+            return redirect("library:register-import", device_pk=device.pk)
+
+        rows: list[RegisterRow] = reconcile(existing, incoming)
+        old_snapshot = snapshot_device(device)
+        undo_state = undo.capture(device)
+        counts = {"created": 0, "updated": 0, "deleted": 0}
+        if any(row.address in accepted and row.status != "unchanged" for row in rows):
+            modbus_config, _ = ModbusConfig.objects.get_or_create(device_type=device)
+            counts = apply_plan(modbus_config, rows, accepted)
+        if any(counts.values()):
+            device = VendorModel.objects.get(pk=device.pk)
+            record_history(device, DeviceHistory.Action.UPDATED, request.user, old_snapshot)
+            undo.push(request, f"Import registers into {device}", device.pk, undo_state)
+            log_action(
+                request, "updated", device,
+                details=f"Register import: {counts['created']} added, {counts['updated']} updated, "
+                        f"{counts['deleted']} removed",
+            )
+        messages.success(
+            request,
+            f"Registers merged: {counts['created']} added, {counts['updated']} updated, {counts['deleted']} removed.",
+        )
+        return redirect("library:model-detail", pk=device.pk)
+
+
+class RegisterSnippetView(RoleRequiredMixin, View):
+    """Insert a register snippet (``snippets/*.yaml``) into a Modbus device."""
+
+    required_role = User.Role.EDITOR
+    template_name = "library/register_snippets.html"
+
+    def _render(self, request, device, snippets, form):
+        from django.shortcuts import render
+
+        registers = RegisterDefinition.objects.filter(modbus_config__device_type=device)
+        next_address = max((r.address + REGISTER_WIDTHS.get(r.data_type, 1) for r in registers), default=0)
+        if not form.is_bound:
+            form.initial.setdefault("base_address", next_address)
+        return render(request, self.template_name, {"device": device, "snippets": snippets, "form": form})
+
+    def _snippets(self, request):
+        try:
+            return load_snippets()
+        except SnippetError as e:
+            messages.error(request, str(e))
+            return []
+
+    def get(self, request, device_pk):
+        device = get_object_or_404(VendorModel, pk=device_pk)
+        snippets = self._snippets(request)
+        form = RegisterSnippetForm(snippets=snippets, initial={"snippet": request.GET.get("snippet")})
+        return self._render(request, device, snippets, form)
+
+    def post(self, request, device_pk):
+        device = get_object_or_404(VendorModel, pk=device_pk)
+        snippets = self._snippets(request)
+        form = RegisterSnippetForm(request.POST, snippets=snippets)
+        if not form.is_valid():
+            return self._render(request, device, snippets, form)
+        snippet = next(s for s in snippets if s.key == form.cleaned_data["snippet"])
+        undo_state = undo.capture(device)
+        try:
+            created = insert_snippet(device, snippet, form.cleaned_data["base_address"], request.user)
+        except SnippetError as e:
+            form.add_error(None, str(e))
+            return self._render(request, device, snippets, form)
+        log_action(
+            request, "updated", device,
+            details=f"Snippet {snippet.key}: {len(created)} registers at {form.cleaned_data['base_address']}",
+        )
+        undo.push(request, f"Insert snippet {snippet.key} into {device}", device.pk, undo_state)
+        messages.success(request, f"Added {len(created)} registers from {snippet.name}.")
+        return redirect("library:model-detail", pk=device.pk)
+
+
+class RegisterCopyView(RoleRequiredMixin, View):
+    """Put the selected registers of a device on the register clipboard."""
+
+    required_role = User.Role.EDITOR
+
+    def post(self, request, device_pk):
+        device = get_object_or_404(VendorModel, pk=device_pk)
+        registers = RegisterDefinition.objects.filter(
+            modbus_config__device_type=device, pk__in=request.POST.getlist("register"),
+        )
+        try:
+            copied = register_clipboard.yank(request, device, registers)
+        except SnippetError as e:
+            messages.error(request, str(e))
+        else:
+            count = len(copied.snippet.registers)
+            messages.success(request, f"Copied {count} register{'s' * (count != 1)} — paste them on another device.")
+        return redirect("library:model-detail", pk=device.pk)
+
+
+class RegisterPasteView(RoleRequiredMixin, View):
+    """Paste the register clipboard into a device, shifted by an offset."""
+
+    required_role = User.Role.EDITOR
+    template_name = "library/register_paste.html"
+
+    def _render(self, request, device, clipboard, form):
+        from django.shortcuts import render
+
+        return render(request, self.template_name, {"device": device, "clipboard": clipboard, "form": form})
+
+    def get(self, request, device_pk):
+        device = get_object_or_404(VendorModel, pk=device_pk)
+        return self._render(request, device, register_clipboard.clipboard(request), RegisterPasteForm())
+
+    def post(self, request, device_pk):
+        device = get_object_or_404(VendorModel, pk=device_pk)
+        clipboard = register_clipboard.clipboard(request)
+        form = RegisterPasteForm(request.POST)
+        if not form.is_valid() or clipboard is None:
+            return self._render(request, device, clipboard, form)
+        undo_state = undo.capture(device)
+        try:
+            created = register_clipboard.paste(request, device, form.cleaned_data["offset"], request.user)
+        except SnippetError as e:
+            form.add_error(None, str(e))
+            return self._render(request, device, clipboard, form)
+        log_action(request, "updated", device, details=f"Pasted {len(created)} registers from {clipboard.source}")
+        undo.push(request, f"Paste registers into {device}", device.pk, undo_state)
+        messages.success(request, f"Pasted {len(created)} registers from {clipboard.source}.")
+        return redirect("library:model-detail", pk=device.pk)
+
+
+class RegisterDuplicatesView(RoleRequiredMixin, View):
+    """Side-by-side review of registers sharing an address on one device.
+
+    Per address the editor keeps one register, merges them (the oldest
+    register with blanks filled from the others) or leaves the group for
+    later.
+    """
+
+    required_role = User.Role.EDITOR
+    template_name = "library/register_duplicates.html"
+
+    def _groups(self, device):
+        registers = RegisterDefinition.objects.filter(modbus_config__device_type=device)
+        return find_duplicates(registers)
+
+    def get(self, request, device_pk):
+        from django.shortcuts import render
+
+        device = get_object_or_404(VendorModel, pk=device_pk)
+        groups = self._groups(device)
+        if not groups:
+            messages.info(request, "No duplicate register addresses.")
+            return redirect("library:model-detail", pk=device.pk)
+        return render(request, self.template_name, {"device": device, "groups": groups})
+
+    def post(self, request, device_pk):
+        device = get_object_or_404(VendorModel, pk=device_pk)
+        old_snapshot = snapshot_device(device)
+        undo_state = undo.capture(device)
+        resolved = 0
+        with transaction.atomic():
+            for group in self._groups(device):
+                choice = request.POST.get(f"resolve-{group.address}", "")
+                if choice == "merge":
+                    resolve_duplicates(group)
+                else:
+                    keep = next((r for r in group.registers if str(r.pk) == choice), None)
+                    if keep is None:
+                        continue
+                    resolve_duplicates(group, keep=keep)
+                resolved += 1
+        if resolved:
+            record_history(device, DeviceHistory.Action.UPDATED, request.user, old_snapshot)
+            log_action(request, "updated", device, details=f"Resolved {resolved} duplicate register address(es)")
+            undo.push(request, f"Resolve duplicate registers of {device}", device.pk, undo_state)
+            messages.success(request, f"Resolved {resolved} duplicate register address(es).")
         return redirect("library:model-detail", pk=device.pk)
 
 
@@ -1067,6 +2172,7 @@ class DeviceHistorySnapshotView(LoginRequiredMixin, TemplateView):
         ctx["entry"] = entry
         ctx["snapshot"] = snapshot
         ctx["technology"] = snapshot.get("technology", "")
+        ctx["technologies"] = [ctx["technology"], *snapshot.get("additional_technologies", [])]
         ctx["modbus_config"] = snapshot.get("modbus_config")
         ctx["registers"] = snapshot.get("registers", [])
         ctx["lorawan_config"] = snapshot.get("lorawan_config")
@@ -1115,12 +2221,22 @@ class ImportView(RoleRequiredMixin, TemplateView):
     def post(self, request):
         form = YAMLImportForm(request.POST)
         if form.is_valid():
+            manifest_warnings = _manifest_warnings(
+                form.cleaned_data["devices_path"], form.cleaned_data["manifest_path"],
+            )
             try:
                 stats = import_from_yaml(
                     devices_path=form.cleaned_data["devices_path"],
                     manifest_path=form.cleaned_data["manifest_path"],
                     clear=form.cleaned_data["clear_existing"],
+                    recover=True,
                 )
+                if stats.get("recovery"):
+                    # The recovery notes say the same as the manifest check.
+                    manifest_warnings = []
+                    if form.cleaned_data["write_manifest"]:
+                        write_manifest(form.cleaned_data["manifest_path"], stats["recovered_manifest"])
+                        stats["manifest_written"] = True
                 log_action(
                     request,
                     "imported",
@@ -1134,13 +2250,25 @@ class ImportView(RoleRequiredMixin, TemplateView):
                         "errors": len(stats.get("errors", [])),
                     },
                 )
-                return self.render_to_response(self.get_context_data(form=form, stats=stats))
+                return self.render_to_response(self.get_context_data(
+                    form=form, stats=stats, manifest_warnings=manifest_warnings,
+                ))
             except Exception as e:
                 messages.error(request, f"Import failed: {e}")
+            return self.render_to_response(self.get_context_data(form=form, manifest_warnings=manifest_warnings))
 
         return self.render_to_response(self.get_context_data(form=form))
 
 
+def _manifest_warnings(devices_path: str, manifest_path: str) -> list:
+    """Failed ``verify_manifest`` checks for the tree being imported."""
+    try:
+        manifest = yaml.safe_load(Path(manifest_path).read_text()) or {}
+        return [c for c in check_manifest(devices_path, manifest) if c.status != "ok"]
+    except (OSError, yaml.YAMLError, AttributeError):
+        return []
+
+
 class ExportView(RoleRequiredMixin, TemplateView):
     required_role = User.Role.ADMIN
     template_name = "library/export.html"
@@ -1182,6 +2310,21 @@ class VersionListView(LoginRequiredMixin, ListView):
     queryset = LibraryVersion.objects.all()
 
 
+class UnpublishedChangesView(LoginRequiredMixin, TemplateView):
+    """Everything changed since the current version, model changes grouped
+    by the vendor file they are exported to."""
+
+    template_name = "library/changes.html"
+
+    def get_context_data(self, **kwargs):
+        ctx = super().get_context_data(**kwargs)
+        summary = unpublished_changes_summary()
+        ctx["files"] = changed_files(summary)
+        ctx["metrics"] = summary.metrics
+        ctx["device_types"] = summary.device_types
+        return ctx
+
+
 class VersionDetailView(LoginRequiredMixin, DetailView):
     template_name = "library/version_detail.html"
     model = LibraryVersion
@@ -1248,289 +2391,76 @@ class VersionCompareView(LoginRequiredMixin, TemplateView):
         ctx["from_version"] = from_version
         ctx["to_version"] = to_version
 
-        # Build snapshot lookups for each version: {device_type_id: (label, snapshot)}
-        def _build_snapshot_map(lib_version):
-            entries = lib_version.device_changes.exclude(
-                change_type=LibraryVersionDevice.ChangeType.REMOVED,
-            )
-            result = {}
-            for entry in entries:
-                if not entry.device_type_id:
-                    continue
-                snapshot = (
-                    DeviceHistory.objects.filter(
-                        device_id=entry.device_type_id,
-                        version=entry.device_version,
-                    )
-                    .values_list("snapshot", flat=True)
-                    .first()
-                )
-                if snapshot:
-                    result[entry.device_type_id] = {
-                        "label": entry.device_label,
-                        "version": entry.device_version,
-                        "snapshot": snapshot,
-                    }
-            return result
-
-        from_map = _build_snapshot_map(from_version)
-        to_map = _build_snapshot_map(to_version)
-
-        # Also collect removed entries (no device_type_id) by label
-        from_removed = {
-            e.device_label
-            for e in from_version.device_changes.filter(
-                change_type=LibraryVersionDevice.ChangeType.REMOVED,
-            )
-        }
-        to_removed = {
-            e.device_label
-            for e in to_version.device_changes.filter(
-                change_type=LibraryVersionDevice.ChangeType.REMOVED,
-            )
-        }
-
-        from .history import diff_snapshots
-
-        all_device_ids = set(from_map) | set(to_map)
-        added = []
-        removed = []
-        modified = []
-        unchanged = 0
-
-        for device_id in sorted(all_device_ids, key=lambda d: (from_map.get(d) or to_map.get(d))["label"]):
-            in_from = device_id in from_map
-            in_to = device_id in to_map
+        from .version_diff import compare_snapshot_maps, version_snapshot_map
 
-            if in_to and not in_from:
-                added.append(to_map[device_id])
-            elif in_from and not in_to:
-                removed.append(from_map[device_id])
-            else:
-                old_snap = from_map[device_id]["snapshot"]
-                new_snap = to_map[device_id]["snapshot"]
-                diff = diff_snapshots(old_snap, new_snap)
-                if diff:
-                    modified.append({
-                        "label": to_map[device_id]["label"],
-                        "from_version": from_map[device_id]["version"],
-                        "to_version": to_map[device_id]["version"],
-                        "diff": diff,
-                    })
-                else:
-                    unchanged += 1
-
-        ctx["added"] = added
-        ctx["removed"] = removed
-        ctx["modified"] = modified
-        ctx["unchanged_count"] = unchanged
+        ctx.update(compare_snapshot_maps(version_snapshot_map(from_version), version_snapshot_map(to_version)))
         ctx["versions"] = LibraryVersion.objects.values_list("version", flat=True).order_by("version")
 
         return ctx
 
 
 class VersionCreateView(RoleRequiredMixin, View):
-    required_role = User.Role.ADMIN
+    """Publish the next library version (``library.publish``).
 
-    def post(self, request):
-        # Auto-compute next version number
-        max_version = LibraryVersion.objects.aggregate(v=Max("version"))["v"] or 0
-        new_version = max_version + 1
-
-        # Backfill: ensure every VendorModel has at least one DeviceHistory entry
-        devices_without_history = VendorModel.objects.filter(history__isnull=True)
-        for device in devices_without_history:
-            record_history(device, DeviceHistory.Action.CREATED, user=None)
-
-        # Mark previous current version as not current
-        LibraryVersion.objects.filter(is_current=True).update(is_current=False)
-
-        # Create the new library version
-        lib_version = LibraryVersion.objects.create(
-            version=new_version,
-            published_by=request.user,
-            is_current=True,
-        )
-
-        # Build previous version's manifest for comparison
-        prev_version = (
-            LibraryVersion.objects.filter(version__lt=new_version).order_by("-version").first()
-        )
-        prev_manifest = {}
-        if prev_version:
-            for entry in prev_version.device_changes.all():
-                if entry.device_type_id and entry.change_type != LibraryVersionDevice.ChangeType.REMOVED:
-                    prev_manifest[entry.device_type_id] = entry.device_version
-
-        # Snapshot all current devices
-        current_device_ids = set()
-        for device in VendorModel.objects.select_related("vendor"):
-            current_device_ids.add(device.pk)
-            # Get latest DeviceHistory version for this device
-            latest_version = (
-                DeviceHistory.objects.filter(device=device)
-                .order_by("-version")
-                .values_list("version", flat=True)
-                .first()
-            ) or 1
-
-            # Determine change_type
-            if device.pk in prev_manifest:
-                if prev_manifest[device.pk] == latest_version:
-                    change_type = LibraryVersionDevice.ChangeType.UNCHANGED
-                else:
-                    change_type = LibraryVersionDevice.ChangeType.MODIFIED
-            else:
-                change_type = LibraryVersionDevice.ChangeType.ADDED
-
-            LibraryVersionDevice.objects.create(
-                library_version=lib_version,
-                device_type=device,
-                device_version=latest_version,
-                device_label=str(device),
-                change_type=change_type,
-            )
+    Asked for ``application/x-ndjson`` (the publish dialog), the response
+    streams the steps as JSON lines — ``{"message", "done", "total"}``,
+    then ``{"done": true, "url", "message"}`` or ``{"error"}`` — for a
+    progress bar. Closing the connection cancels the publish; it is one
+    transaction, so nothing is published then."""
 
-        # Detect removed devices (in previous but not in current)
-        if prev_version:
-            for prev_device_id, prev_device_version in prev_manifest.items():
-                if prev_device_id not in current_device_ids:
-                    # Get label from previous manifest entry
-                    prev_entry = prev_version.device_changes.filter(device_type_id=prev_device_id).first()
-                    label = prev_entry.device_label if prev_entry else "Deleted device"
-                    LibraryVersionDevice.objects.create(
-                        library_version=lib_version,
-                        device_type=None,
-                        device_version=prev_device_version,
-                        device_label=label,
-                        change_type=LibraryVersionDevice.ChangeType.REMOVED,
-                    )
-
-        # -----------------------------------------------------------------
-        # L1 Metric + L2 DeviceType manifest entries — same publish flow
-        # as VendorModel above, applied to the two other versioned entity
-        # types. Without these, retrieve(version=N) would have to fall
-        # back to ``Metric.objects.all()`` and serve the *current* state
-        # rather than the v=N snapshot — see ``LibraryContentViewSet``.
-        # -----------------------------------------------------------------
-        self._publish_entities(
-            lib_version,
-            prev_version,
-            Metric, MetricHistory,
-            LibraryVersionMetric,
-            link_attr="metric",
-            label_attr="key",
-            version_attr="metric_version",
-            label_field="metric_key",
-            prev_relation="metric_changes",
-        )
-        self._publish_entities(
-            lib_version,
-            prev_version,
-            DeviceType, DeviceTypeHistory,
-            LibraryVersionDeviceType,
-            link_attr="device_type",
-            label_attr="code",
-            version_attr="device_type_version",
-            label_field="device_type_code",
-            prev_relation="device_type_changes",
-        )
+    required_role = User.Role.ADMIN
 
+    def post(self, request):
+        if request.headers.get("Accept") == "application/x-ndjson":
+            response = StreamingHttpResponse(self._stream(request), content_type="application/x-ndjson")
+            response["Cache-Control"] = "no-cache"
+            response["X-Accel-Buffering"] = "no"  # don't let a proxy hold the progress back
+            return response
+        lib_version = publish_version(request.user)
         log_action(request, "created", lib_version)
-        messages.success(request, f"Library version v{new_version} created.")
+        messages.success(request, f"Library version v{lib_version.version} created.")
         return redirect("library:version-detail", pk=lib_version.pk)
 
-    def _publish_entities(
-        self,
-        lib_version,
-        prev_version,
-        entity_model,
-        history_model,
-        link_model,
-        *,
-        link_attr,         # FK name on link_model ("metric" / "device_type")
-        label_attr,        # field on entity_model used as label ("key" / "code")
-        version_attr,      # version field on link_model ("metric_version" / …)
-        label_field,       # label field on link_model ("metric_key" / "device_type_code")
-        prev_relation,     # reverse FK on LibraryVersion ("metric_changes" / …)
-    ):
-        """Generic publish step for an L1/L2 entity that mirrors the
-        VendorModel block above. Factored out to keep the two new
-        entity types from duplicating ~30 lines each."""
-        # Backfill: any entity row without a history entry gets a v1
-        # CREATED snapshot so the publish flow can reference it.
-        record_fn = (
-            record_metric_history if entity_model is Metric else record_device_type_history
-        )
-        action_created = (
-            MetricHistory.Action.CREATED
-            if entity_model is Metric
-            else DeviceTypeHistory.Action.CREATED
-        )
-        for ent in entity_model.objects.filter(history__isnull=True):
-            record_fn(ent, action_created, user=None)
-
-        # Previous manifest: which entity → which history version was pinned
-        prev_manifest: dict = {}
-        if prev_version:
-            for entry in getattr(prev_version, prev_relation).all():
-                fk_id = getattr(entry, f"{link_attr}_id")
-                if fk_id and entry.change_type != link_model.ChangeType.REMOVED:
-                    prev_manifest[fk_id] = getattr(entry, version_attr)
-
-        current_ids: set = set()
-        for ent in entity_model.objects.all():
-            current_ids.add(ent.pk)
-            latest_version = (
-                history_model.objects.filter(**{link_attr: ent})
-                .order_by("-version")
-                .values_list("version", flat=True)
-                .first()
-            ) or 1
-
-            if ent.pk in prev_manifest:
-                change_type = (
-                    link_model.ChangeType.UNCHANGED
-                    if prev_manifest[ent.pk] == latest_version
-                    else link_model.ChangeType.MODIFIED
-                )
-            else:
-                change_type = link_model.ChangeType.ADDED
-
-            link_model.objects.create(
-                library_version=lib_version,
-                **{link_attr: ent},
-                **{version_attr: latest_version},
-                **{label_field: getattr(ent, label_attr)},
-                change_type=change_type,
-            )
+    def _stream(self, request):
+        steps = publish_steps(request.user)
+        step = None
+        try:
+            for step in steps:
+                yield json.dumps(step.as_dict()) + "\n"
+        except Exception as e:  # noqa: BLE001 — reported to the dialog; the transaction is rolled back
+            logger.exception("Publishing failed")
+            yield json.dumps({"error": f"Publishing failed, nothing was published: {e}"}) + "\n"
+            return
+        finally:
+            steps.close()
+        log_action(request, "created", step.version)
+        yield json.dumps({
+            "done": True,
+            "message": step.message,
+            "url": reverse("library:version-detail", kwargs={"pk": step.version.pk}),
+        }) + "\n"
 
-        if prev_version:
-            for prev_id, prev_ver in prev_manifest.items():
-                if prev_id in current_ids:
-                    continue
-                prev_entry = (
-                    getattr(prev_version, prev_relation)
-                    .filter(**{f"{link_attr}_id": prev_id})
-                    .first()
-                )
-                label = getattr(prev_entry, label_field) if prev_entry else f"Deleted {label_attr}"
-                link_model.objects.create(
-                    library_version=lib_version,
-                    **{link_attr: None},
-                    **{version_attr: prev_ver},
-                    **{label_field: label},
-                    change_type=link_model.ChangeType.REMOVED,
-                )
+
+def _migrations_json(lib_version) -> str:
+    from .release_migrations import build_migrations
+
+    return json.dumps(build_migrations(lib_version), indent=2, ensure_ascii=False)
 
 
 class VersionExportView(LoginRequiredMixin, View):
-    """Export a library version as JSON or YAML download."""
+    """Export a library version as JSON or YAML download, its
+    migrations.json, or all three as a release bundle (ZIP)."""
 
     def get(self, request, pk):
         lib_version = get_object_or_404(LibraryVersion, pk=pk)
         fmt = request.GET.get("format", "json")
 
+        if fmt == "migrations":
+            content = _migrations_json(lib_version)
+            response = HttpResponse(content, content_type="application/json")
+            response["Content-Disposition"] = f'attachment; filename="library-v{lib_version.version}-migrations.json"'
+            return response
+
         entries = lib_version.device_changes.select_related("device_type").exclude(
             change_type=LibraryVersionDevice.ChangeType.REMOVED,
         )
@@ -1575,8 +2505,23 @@ class VersionExportView(LoginRequiredMixin, View):
             "vendors": vendor_list,
         }
 
-        if fmt == "yaml":
-            content = yaml.dump(document, default_flow_style=False, sort_keys=False, allow_unicode=True)
+        if fmt == "bundle":
+            import zipfile
+            from io import BytesIO
+
+            files = {
+                f"library-v{lib_version.version}.json": json.dumps(document, indent=2, ensure_ascii=False),
+                f"library-v{lib_version.version}.yaml": dump_yaml(document),
+                "migrations.json": _migrations_json(lib_version),
+            }
+            buf = BytesIO()
+            with zipfile.ZipFile(buf, "w", zipfile.ZIP_DEFLATED) as zf:
+                for name, content in files.items():
+                    zf.writestr(name, content)
+            response = HttpResponse(buf.getvalue(), content_type="application/zip")
+            response["Content-Disposition"] = f'attachment; filename="library-v{lib_version.version}.zip"'
+        elif fmt == "yaml":
+            content = dump_yaml(document)
             response = HttpResponse(content, content_type="application/x-yaml")
             response["Content-Disposition"] = f'attachment; filename="library-v{lib_version.version}.yaml"'
         else:
@@ -1723,6 +2668,173 @@ class WMBusMappingView(LoginRequiredMixin, ListView):
         return ctx
 
 
+# === Drafts (scratchpad) ===
+
+
+class DraftListView(LoginRequiredMixin, ListView):
+    template_name = "library/draft_list.html"
+    context_object_name = "drafts"
+
+    def get_queryset(self):
+        return DeviceDraft.objects.select_related("created_by")
+
+
+class DraftCreateView(RoleRequiredMixin, CreateView):
+    required_role = User.Role.EDITOR
+    model = DeviceDraft
+    form_class = DeviceDraftForm
+    template_name = "library/draft_form.html"
+
+    def get_initial(self):
+        key = self.request.GET.get("template")
+        if key:
+            try:
+                template = load_template(key)
+            except TemplateError as e:
+                messages.error(self.request, str(e))
+            else:
+                return {"title": template.name, "content": template_content(template)}
+        technology = self.request.GET.get("technology", "modbus")
+        if technology not in VendorModel.Technology.values:
+            technology = "modbus"
+        return {"content": initial_content(technology)}
+
+    def get_context_data(self, **kwargs):
+        ctx = super().get_context_data(**kwargs)
+        try:
+            ctx["device_templates"] = load_templates()
+        except TemplateError as e:
+            messages.error(self.request, f"Device templates: {e}")
+        ctx["current_template"] = self.request.GET.get("template", "")
+        return ctx
+
+    def form_valid(self, form):
+        form.instance.created_by = self.request.user
+        response = super().form_valid(form)
+        log_action(self.request, "created", self.object)
+        messages.success(self.request, f"Draft '{self.object.title}' created.")
+        return response
+
+    def get_success_url(self):
+        return reverse_lazy("library:draft-edit", kwargs={"pk": self.object.pk})
+
+
+class DraftUpdateView(RoleRequiredMixin, UpdateView):
+    required_role = User.Role.EDITOR
+    model = DeviceDraft
+    form_class = DeviceDraftForm
+    template_name = "library/draft_form.html"
+
+    def get_context_data(self, **kwargs):
+        ctx = super().get_context_data(**kwargs)
+        ctx["file_form"] = DraftFileForm()
+        return ctx
+
+    def form_valid(self, form):
+        response = super().form_valid(form)
+        messages.success(self.request, f"Draft '{self.object.title}' saved.")
+        return response
+
+    def get_success_url(self):
+        return reverse_lazy("library:draft-edit", kwargs={"pk": self.object.pk})
+
+
+class DraftTechnologyView(RoleRequiredMixin, FormView):
+    """Edit a draft's ``technology_config`` through the form generated from
+    its typed struct instead of the raw YAML."""
+
+    required_role = User.Role.EDITOR
+    form_class = TechnologyConfigForm
+    template_name = "library/draft_technology.html"
+
+    @cached_property
+    def draft(self):
+        return get_object_or_404(DeviceDraft, pk=self.kwargs["pk"])
+
+    @cached_property
+    def config(self):
+        """The draft's ``technology_config`` when the form covers it, else None."""
+        try:
+            config = parse_draft(self.draft.content).get("technology_config") or {}
+        except DraftError:
+            return None
+        if not isinstance(config, dict):
+            return None
+        if not config.get("technology"):
+            # Starting a config: ?technology= picks the struct.
+            config = {**config, "technology": self.request.GET.get("technology", "")}
+        technology = config["technology"]
+        return config if isinstance(technology, str) and technology in DECODERS else None
+
+    def get(self, request, *args, **kwargs):
+        if self.config is None:
+            return self._unsupported()
+        return super().get(request, *args, **kwargs)
+
+    def post(self, request, *args, **kwargs):
+        if self.config is None:
+            return self._unsupported()
+        return super().post(request, *args, **kwargs)
+
+    def _unsupported(self):
+        messages.info(
+            self.request,
+            f"The form covers {', '.join(DECODERS)} technology configs that parse; edit this one in the YAML.",
+        )
+        return redirect("library:draft-edit", pk=self.draft.pk)
+
+    def get_form_kwargs(self):
+        return {**super().get_form_kwargs(), "config": self.config}
+
+    def get_context_data(self, **kwargs):
+        ctx = super().get_context_data(**kwargs)
+        ctx["draft"] = self.draft
+        return ctx
+
+    def form_valid(self, form):
+        set_technology_config(self.draft, form.technology_config)
+        messages.success(self.request, f"Technology config of draft '{self.draft.title}' saved.")
+        return redirect("library:draft-edit", pk=self.draft.pk)
+
+
+class DraftFileView(RoleRequiredMixin, View):
+    """File a draft under a vendor, turning it into a regular model."""
+
+    required_role = User.Role.EDITOR
+
+    def post(self, request, pk):
+        draft = get_object_or_404(DeviceDraft, pk=pk)
+        form = DraftFileForm(request.POST)
+        if not form.is_valid():
+            messages.error(request, "Choose a vendor and a model number to file the draft.")
+            return redirect("library:draft-edit", pk=pk)
+
+        title = draft.title
+        try:
+            device, findings = file_draft(draft, form.cleaned_data["vendor"], form.cleaned_data["model_number"])
+        except DraftError as e:
+            messages.error(request, f"Cannot file draft: {e}")
+            return redirect("library:draft-edit", pk=pk)
+
+        log_action(request, "created", device, details={"from_draft": title})
+        messages.success(request, f"Draft '{title}' filed as {device}.")
+        for finding in findings:
+            messages.warning(request, f"{finding.path or finding.rule}: {finding.message}")
+        return redirect("library:model-detail", pk=device.pk)
+
+
+class DraftDeleteView(RoleRequiredMixin, View):
+    required_role = User.Role.EDITOR
+
+    def post(self, request, pk):
+        draft = get_object_or_404(DeviceDraft, pk=pk)
+        title = draft.title
+        log_action(request, "deleted", draft)
+        draft.delete()
+        messages.success(request, f"Draft '{title}' has been deleted.")
+        return redirect("library:draft-list")
+
+
 # === Gateway Assignments ===
 
 
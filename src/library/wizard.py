@@ -0,0 +1,197 @@
+"""Step-by-step questionnaire that builds a complete device definition.
+
+For one-off contributors who shouldn't need to learn the YAML schema:
+``DeviceWizard`` asks vendor → model → technology → technology-specific
+questions and returns a schema-v4 device dict (the ``skeleton_device``
+shape, filled in). Prompting goes through an injectable ``ask`` callable
+so the ``add_device`` command can use ``input()`` and tests can feed
+scripted answers.
+"""
+
+from __future__ import annotations
+
+from collections.abc import Callable
+from pathlib import Path
+
+from django.utils.text import slugify
+
+from .importers import import_device
+from .lint import LintDevice, lint_devices
+from .models import LoRaWANConfig, ModbusConfig, RegisterDefinition, Vendor, VendorModel
+from .scaffold import ScaffoldError, skeleton_device
+
+
+class DeviceWizard:
+    def __init__(self, ask: Callable[[str], str], say: Callable[[str], None] = print):
+        self._ask = ask
+        self.say = say
+
+    def ask(self, question: str, default: str = "", choices=None, required: bool = False, parse=None):
+        """Ask until the answer is valid; returns the parsed value."""
+        hint = f" [{'/'.join(choices)}]" if choices else ""
+        suffix = f" ({default})" if default else ""
+        while True:
+            answer = self._ask(f"{question}{hint}{suffix}: ").strip() or default
+            if not answer and required:
+                self.say("  A value is required.")
+                continue
+            if choices and answer and answer not in choices:
+                self.say(f"  Choose one of: {', '.join(choices)}")
+                continue
+            if parse and answer:
+                try:
+                    return parse(answer)
+                except ValueError as e:
+                    self.say(f"  Invalid value: {e}")
+                    continue
+            return answer
+
+    def confirm(self, question: str, default: bool = False) -> bool:
+        answer = self.ask(question, default="y" if default else "n", choices=["y", "n"])
+        return answer == "y"
+
+    def run(self) -> dict:
+        vendor = self.ask("Vendor name", required=True)
+        model = self.ask("Model number", required=True)
+        technology = self.ask(
+            "Technology", choices=[t.value for t in VendorModel.Technology], required=True,
+        )
+        device_type = self.ask(
+            "Device category", choices=[c.value for c in VendorModel.DeviceCategory], required=True,
+        )
+        device = skeleton_device(
+            vendor_name=vendor,
+            model_number=model,
+            technology=technology,
+            device_type=device_type,
+            name=self.ask("Display name", default=model),
+        )
+        device["description"] = self.ask("Short description", required=True)
+
+        tech = device["technology_config"]
+        if technology == VendorModel.Technology.MODBUS:
+            self._modbus(tech)
+        elif technology == VendorModel.Technology.LORAWAN:
+            self._lorawan(tech, device["processor_config"])
+        elif technology == VendorModel.Technology.WMBUS:
+            self._wmbus(tech)
+        return device
+
+    def _modbus(self, tech: dict):
+        # Connection parameters are required (``technology-required``); the
+        # byte and word order default to the Modbus convention.
+        tech["function"] = self.ask(
+            "Register function", choices=[c.value for c in ModbusConfig.Function], required=True,
+        )
+        tech["byte_order"] = self.ask(
+            "Byte order", default="big_endian", choices=[c.value for c in ModbusConfig.ByteOrder],
+        )
+        tech["word_order"] = self.ask(
+            "Word order", default="high_first", choices=[c.value for c in ModbusConfig.WordOrder],
+        )
+
+        self.say("Registers — leave the address empty to finish.")
+        seen = set()
+        while True:
+            address = self.ask("  Address", parse=int)
+            if address == "" and not seen:
+                self.say("  At least one register is required.")
+                continue
+            if address == "":
+                break
+            if address in seen:
+                self.say(f"  Address {address} is already defined.")
+                continue
+            seen.add(address)
+            tech["register_definitions"].append({
+                "field": {
+                    "name": self.ask("  Field name (snake_case)", required=True),
+                    "unit": self.ask("  Unit"),
+                },
+                "scale": self.ask("  Scale", default="1", parse=float),
+                "offset": self.ask("  Offset", default="0", parse=float),
+                "address": address,
+                "data_type": self.ask(
+                    "  Data type", default="uint16", choices=[c.value for c in RegisterDefinition.DataType],
+                ),
+            })
+
+    def _lorawan(self, tech: dict, processor: dict):
+        tech["device_class"] = self.ask(
+            "Device class", default="A", choices=[c.value for c in LoRaWANConfig.DeviceClass],
+        )
+        port = self.ask("Downlink FPort (empty if none)", parse=_fport)
+        if port != "":
+            tech["downlink_f_port"] = port
+        decoder = self.ask("Decoder", default="js_codec", choices=["js_codec", "lorawan_field_map"])
+        processor["decoder_type"] = decoder
+        if decoder == "js_codec":
+            tech["payload_codec"] = {
+                "format": self.ask(
+                    "Codec format", default="ttn_v3", choices=[c.value for c in LoRaWANConfig.CodecFormat],
+                ),
+                "script": self.ask("Codec script file", required=True, parse=_codec_script),
+            }
+
+    def _wmbus(self, tech: dict):
+        tech["manufacturer_code"] = self.ask("Manufacturer code (3 letters, e.g. KAM)", required=True, parse=_mfct)
+        tech["wmbus_version"] = self.ask("Version byte (hex, e.g. 1b)")
+        tech["wmbus_device_type"] = self.ask("wM-Bus device type (decimal)", required=True, parse=_wmbus_device_type)
+        tech["encryption_required"] = self.confirm("Encryption required?")
+        tech["wmbusmeters_driver"] = self.ask("wmbusmeters driver (empty for auto)")
+
+
+def _fport(value: str) -> int:
+    port = int(value)
+    if not 1 <= port <= 223:
+        raise ValueError("FPort must be between 1 and 223")
+    return port
+
+
+def _wmbus_device_type(value: str) -> int:
+    device_type = int(value)
+    if not 0 <= device_type <= 255:
+        raise ValueError("device type must be between 0 and 255")
+    return device_type
+
+
+def _codec_script(value: str) -> str:
+    try:
+        script = Path(value).expanduser().read_text()
+    except (OSError, UnicodeDecodeError) as e:
+        raise ValueError(f"can't read {value}: {e}") from e
+    if not script.strip():
+        raise ValueError(f"{value} is empty")
+    return script
+
+
+def _mfct(value: str) -> str:
+    if len(value) != 3 or not value.isalpha():
+        raise ValueError("expected three letters")
+    return value.upper()
+
+
+# Errors of these rules mean the definition is unusable; it isn't saved.
+BLOCKING_RULES = ("schema", "technology-required", "codec-syntax")
+
+
+def validate(device: dict):
+    """Lint findings for the finished device (default rule set, the
+    ``schema``, ``technology-required`` and ``codec-syntax`` rules included)."""
+    return lint_devices([LintDevice(data=device)])
+
+
+def blocking_findings(findings) -> list:
+    return [f for f in findings if f.rule in BLOCKING_RULES and f.severity == "error"]
+
+
+def save_to_database(device: dict, user=None) -> VendorModel:
+    """Create the device (vendor included) through the YAML importer path,
+    its history entry recorded under ``user``."""
+    vendor, _ = Vendor.objects.get_or_create(
+        slug=slugify(device["vendor_name"]),
+        defaults={"name": device["vendor_name"]},
+    )
+    if VendorModel.objects.filter(vendor=vendor, model_number__iexact=device["model_number"]).exists():
+        raise ScaffoldError(f"{vendor.name} {device['model_number']} already exists")
+    return import_device(vendor, device, user)
@@ -0,0 +1,162 @@
+"""Checks for YAML content that parses, but not the way the author meant.
+
+Hand-edited device files trip over a few YAML corners that otherwise only
+surface as confusing parser errors or wrong values downstream:
+
+- bytes that aren't UTF-8 (files saved in a legacy code page);
+- control characters, which the YAML reader rejects as "unacceptable
+  character" far from where a human would look;
+- plain scalars YAML 1.1 resolves to something other than a string —
+  ``description: no`` is ``False``, ``wmbus_version: 1.10`` is ``1.1``,
+  ``join_eui_default: 0000000000000000`` is ``0``.
+
+The last kind is only a problem under keys the schema defines as strings
+(``STRING_KEYS``), and there it is fixable: ``load_preserving_strings``
+keeps the text as written, and ``dump_yaml`` then quotes it — this is how
+``fmt_yaml`` repairs such files. Encoding and control-character problems
+need a human.
+"""
+
+from __future__ import annotations
+
+import re
+from dataclasses import dataclass
+from pathlib import Path
+
+import yaml
+
+STRING_KEYS = frozenset({
+    "vendor_name", "model_number", "name", "description", "device_type", "device_type_key", "unit",
+    "version", "wmbus_version", "lorawan_version", "lorawan_phy_version", "manufacturer_code",
+    "frequency_plan_id", "join_eui_default", "shared_encryption_key", "wmbusmeters_driver",
+    "decoder_type", "source", "target", "icon", "category", "label", "code", "key", "file",
+})
+STR_TAG = "tag:yaml.org,2002:str"
+RESOLVED_AS = {
+    "tag:yaml.org,2002:bool": "a boolean",
+    "tag:yaml.org,2002:int": "an integer",
+    "tag:yaml.org,2002:float": "a number",
+    "tag:yaml.org,2002:timestamp": "a date",
+}
+VERSION_LIKE = re.compile(r"^\d+(\.\d+)+$")
+# What the YAML reader refuses: C0 controls other than tab / LF / CR, DEL,
+# and the C1 range except NEL.
+CONTROL_CHARS = re.compile(r"[\x00-\x08\x0b\x0c\x0e-\x1f\x7f-\x84\x86-\x9f]")
+
+
+class YAMLContentError(ValueError):
+    def __init__(self, path, problems: list[ContentProblem]):
+        super().__init__(f"{path}: " + "; ".join(str(p) for p in problems))
+        self.path = path
+        self.problems = problems
+
+
+@dataclass(frozen=True)
+class ContentProblem:
+    line: int  # 1-based
+    column: int  # 1-based
+    message: str
+    fixable: bool = False  # fmt_yaml repairs it
+
+    def __str__(self) -> str:
+        return f"line {self.line}, column {self.column}: {self.message}"
+
+
+def _position(text: str, offset: int) -> tuple[int, int]:
+    line = text.count("\n", 0, offset) + 1
+    return line, offset - (text.rfind("\n", 0, offset) + 1) + 1
+
+
+def decode(raw: bytes) -> tuple[str | None, list[ContentProblem]]:
+    """``raw`` as text plus the encoding / control-character problems.
+
+    The text is ``None`` when ``raw`` isn't UTF-8.
+    """
+    try:
+        text = raw.decode("utf-8-sig")
+    except UnicodeDecodeError as e:
+        before = raw[:e.start].decode("utf-8", "replace")
+        line, column = _position(before, len(before))
+        message = f"Not UTF-8 (byte 0x{raw[e.start]:02x}); re-save the file as UTF-8"
+        return None, [ContentProblem(line, column, message)]
+    problems = [
+        ContentProblem(*_position(text, m.start()), f"Control character U+{ord(m.group()):04X}; remove it")
+        for m in CONTROL_CHARS.finditer(text)
+    ]
+    return text, problems
+
+
+def _ambiguous_nodes(node, key: str | None = None):
+    """``(key, scalar node)`` for plain scalars under ``STRING_KEYS`` that
+    don't resolve to a string."""
+    if isinstance(node, yaml.MappingNode):
+        for key_node, value_node in node.value:
+            yield from _ambiguous_nodes(value_node, key_node.value if isinstance(key_node, yaml.ScalarNode) else None)
+    elif isinstance(node, yaml.SequenceNode):
+        for item in node.value:
+            yield from _ambiguous_nodes(item, key)
+    elif (
+        isinstance(node, yaml.ScalarNode)
+        and key in STRING_KEYS
+        and node.style is None
+        and node.tag in RESOLVED_AS
+    ):
+        yield key, node
+
+
+def _compose(text: str):
+    loader = yaml.SafeLoader(text)
+    try:
+        return loader, loader.get_single_node()
+    except yaml.YAMLError:
+        loader.dispose()
+        return None, None
+
+
+def ambiguous_scalars(text: str) -> list[ContentProblem]:
+    """Plain scalars under string keys YAML would read as another type."""
+    loader, root = _compose(text)
+    if root is None:
+        return []
+    problems = []
+    for key, node in _ambiguous_nodes(root):
+        value = loader.construct_object(node)
+        kind = "version-like string" if VERSION_LIKE.match(node.value) else "string"
+        problems.append(ContentProblem(
+            node.start_mark.line + 1, node.start_mark.column + 1,
+            f"{key}: unquoted {kind} {node.value!r} is read as {RESOLVED_AS[node.tag]} ({value!r}); quote it",
+            fixable=True,
+        ))
+    loader.dispose()
+    return problems
+
+
+def load_preserving_strings(text: str):
+    """``yaml.safe_load``, except ambiguous scalars under string keys stay
+    the text that was written (``no`` → ``"no"``, ``1.10`` → ``"1.10"``)."""
+    loader, root = _compose(text)
+    if root is None:
+        return yaml.safe_load(text)  # re-raises the parse error
+    for _, node in _ambiguous_nodes(root):
+        node.tag = STR_TAG
+    try:
+        return loader.construct_document(root)
+    finally:
+        loader.dispose()
+
+
+def check_file(path: str | Path) -> list[ContentProblem]:
+    """Every content problem in the YAML file at ``path``."""
+    text, problems = decode(Path(path).read_bytes())
+    if text is None or problems:
+        return problems  # the YAML reader would reject the file anyway
+    return ambiguous_scalars(text)
+
+
+def read_yaml(path: str | Path):
+    """Load ``path`` with strings preserved; ``YAMLContentError`` when the
+    file has problems ``fmt_yaml`` cannot fix."""
+    text, problems = decode(Path(path).read_bytes())
+    if text is None or problems:
+        raise YAMLContentError(path, problems)
+    return load_preserving_strings(text)
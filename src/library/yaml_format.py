@@ -0,0 +1,161 @@
+"""Canonical YAML serialization for the exported device tree.
+
+Every writer of the tree — ``export_to_yaml``, scaffolding, the ``fmt_yaml``
+command — goes through ``dump_yaml`` so hand edits and tool output settle
+on one style: known keys in a fixed order (unknown keys keep their
+relative order after them), block style, and multi-line strings such as
+JS codecs as ``|`` literal blocks instead of escaped one-liners.
+
+``format_tree`` reads files through ``yaml_content.read_yaml``, so values
+YAML would misread (``description: no``, ``wmbus_version: 1.10``) come
+out quoted as the strings they were meant to be.
+"""
+
+from __future__ import annotations
+
+from pathlib import Path
+
+import yaml
+
+from .safe_write import TreeWriter
+from .yaml_content import read_yaml
+
+MANIFEST_KEY_ORDER = ("version", "schema_version", "metrics", "device_types", "vendors")
+DEVICE_KEY_ORDER = (
+    "vendor_name",
+    "model_number",
+    "name",
+    "device_type",
+    "description",
+    "technology_config",
+    "additional_technology_configs",
+    "control_config",
+    "processor_config",
+    "device_type_key",
+    "alarm_config",
+    "links",
+    "validation",
+)
+TECH_KEY_ORDER = ("technology",)  # remaining keys as-is, register_definitions last
+REGISTER_KEY_ORDER = ("field", "scale", "offset", "address", "data_type", "display")
+FIELD_KEY_ORDER = ("name", "unit", "description")
+LINK_KEY_ORDER = ("kind", "url", "title")
+
+
+class _CanonicalDumper(yaml.SafeDumper):
+    pass
+
+
+def _represent_str(dumper, value):
+    # Plain ``str`` for TextChoices members and other str subclasses, which
+    # the default representer would tag as Python objects.
+    value = str.__str__(value)
+    if "\n" in value:
+        return dumper.represent_scalar("tag:yaml.org,2002:str", value, style="|")
+    return dumper.represent_scalar("tag:yaml.org,2002:str", value)
+
+
+_CanonicalDumper.add_representer(str, _represent_str)
+_CanonicalDumper.add_multi_representer(str, _represent_str)
+
+
+def dump_yaml(data, stream=None):
+    """Serialize ``data`` in the canonical style (returns a str when no stream)."""
+    return yaml.dump(
+        data,
+        stream,
+        Dumper=_CanonicalDumper,
+        default_flow_style=False,
+        sort_keys=False,
+        allow_unicode=True,
+    )
+
+
+def _ordered(data: dict, order: tuple[str, ...], last: tuple[str, ...] = ()) -> dict:
+    out = {k: data[k] for k in order if k in data}
+    out.update({k: v for k, v in data.items() if k not in out and k not in last})
+    out.update({k: data[k] for k in last if k in data})
+    return out
+
+
+def _canonical_tech(tech: dict) -> dict:
+    tech = _ordered(tech, TECH_KEY_ORDER, last=("register_definitions",))
+    regs = tech.get("register_definitions")
+    if isinstance(regs, list):
+        tech["register_definitions"] = [
+            _canonical_register(r) if isinstance(r, dict) else r for r in regs
+        ]
+    return tech
+
+
+def canonical_device(device: dict) -> dict:
+    device = _ordered(device, DEVICE_KEY_ORDER)
+    tech = device.get("technology_config")
+    if isinstance(tech, dict):
+        device["technology_config"] = _canonical_tech(tech)
+    additional = device.get("additional_technology_configs")
+    if isinstance(additional, list):
+        device["additional_technology_configs"] = [
+            _canonical_tech(t) if isinstance(t, dict) else t for t in additional
+        ]
+    links = device.get("links")
+    if isinstance(links, list):
+        device["links"] = [_ordered(link, LINK_KEY_ORDER) if isinstance(link, dict) else link for link in links]
+    return device
+
+
+def _canonical_register(reg: dict) -> dict:
+    reg = _ordered(reg, REGISTER_KEY_ORDER)
+    if isinstance(reg.get("field"), dict):
+        reg["field"] = _ordered(reg["field"], FIELD_KEY_ORDER)
+    return reg
+
+
+def canonical_vendor_file(data: dict) -> dict:
+    devices_key = "models" if "models" in data else "device_types"
+    out = dict(data)
+    if isinstance(out.get(devices_key), list):
+        out[devices_key] = [canonical_device(d) if isinstance(d, dict) else d for d in out[devices_key]]
+    return out
+
+
+def device_technologies(devices: list[dict]) -> list[str]:
+    """Sorted technologies used by ``devices`` — the manifest entry's
+    ``technologies`` list for a vendor file."""
+    return sorted({
+        tech for d in devices if isinstance(d, dict)
+        for config in [d.get("technology_config"), *(d.get("additional_technology_configs") or [])]
+        if isinstance(config, dict) and (tech := config.get("technology"))
+    })
+
+
+def canonical_manifest(data: dict) -> dict:
+    return _ordered(data, MANIFEST_KEY_ORDER)
+
+
+def format_tree(
+    devices_path: str | Path, manifest_path: str | Path, check: bool = False, writer: TreeWriter | None = None,
+) -> list[Path]:
+    """Rewrite the manifest and every vendor file it lists canonically.
+
+    Returns the files whose content differs from canonical form. With
+    ``check`` nothing is written — CI fails on a non-empty result.
+    ``writer`` is a ``safe_write.TreeWriter`` (e.g. a dry-run one whose
+    ``diffs`` the caller prints). A file that isn't UTF-8 or contains
+    control characters raises ``yaml_content.YAMLContentError``.
+    """
+    devices_path = Path(devices_path)
+    manifest_path = Path(manifest_path)
+    writer = writer or TreeWriter(dry_run=check)
+
+    def _process(path: Path, canonicalize) -> dict:
+        data = read_yaml(path) or {}
+        writer.write(path, dump_yaml(canonicalize(data)))
+        return data
+
+    manifest = _process(manifest_path, canonical_manifest)
+    for vendor_entry in manifest.get("vendors", []) or []:
+        file_path = devices_path / vendor_entry["file"]
+        if file_path.exists():
+            _process(file_path, canonical_vendor_file)
+    return writer.changed